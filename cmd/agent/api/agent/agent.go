@@ -54,7 +54,13 @@ func SetupHandlers(r *mux.Router) *mux.Router {
 	r.HandleFunc("/stop", stopAgent).Methods("POST")
 	r.HandleFunc("/status", getStatus).Methods("GET")
 	r.HandleFunc("/stream-logs", streamLogs).Methods("POST")
+	r.HandleFunc("/logs/pause", pauseLogsFile).Methods("POST")
+	r.HandleFunc("/logs/resume", resumeLogsFile).Methods("POST")
 	r.HandleFunc("/dogstatsd-stats", getDogstatsdStats).Methods("GET")
+	r.HandleFunc("/snmp-traps/conflicts", getSNMPTrapsConflicts).Methods("GET")
+	r.HandleFunc("/snmp-traps/reload", reloadSNMPTraps).Methods("POST")
+	r.HandleFunc("/snmp-traps/inject", injectSNMPTrap).Methods("POST")
+	r.HandleFunc("/snmp-traps/resolve", resolveSNMPTrapOIDs).Methods("GET")
 	r.HandleFunc("/status/formatted", getFormattedStatus).Methods("GET")
 	r.HandleFunc("/status/health", getHealth).Methods("GET")
 	r.HandleFunc("/{component}/status", componentStatusGetterHandler).Methods("GET")
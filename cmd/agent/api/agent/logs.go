@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/pkg/logs"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// logsFileRequest is the body of the requests pausing or resuming the tailing of a file.
+type logsFileRequest struct {
+	Path string `json:"path"`
+}
+
+func pauseLogsFile(w http.ResponseWriter, r *http.Request) {
+	setLogsFilePaused(w, r, true)
+}
+
+func resumeLogsFile(w http.ResponseWriter, r *http.Request) {
+	setLogsFilePaused(w, r, false)
+}
+
+func setLogsFilePaused(w http.ResponseWriter, r *http.Request, pause bool) {
+	log.Infof("Got a request to pause (%t) the tailing of a file.", pause)
+	w.Header().Set("Content-Type", "application/json")
+
+	var request logsFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Path == "" {
+		body, _ := json.Marshal(map[string]string{"error": "the path of the file is missing"})
+		http.Error(w, string(body), 400)
+		return
+	}
+
+	if !logs.IsAgentRunning() {
+		body, _ := json.Marshal(map[string]string{
+			"error":      "the logs agent is not running",
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	var err error
+	if pause {
+		err = logs.PauseFile(request.Path)
+	} else {
+		err = logs.ResumeFile(request.Path)
+	}
+	if err != nil {
+		log.Errorf("Error pausing (%t) the tailing of %s: %s", pause, request.Path, err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 404)
+		return
+	}
+	w.Write([]byte(`{}`))
+}
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
+	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+func getSNMPTrapsConflicts(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request for the conflicts of the SNMP traps DB.")
+	w.Header().Set("Content-Type", "application/json")
+
+	conflicts, err := traps.GetOIDConflicts()
+	if err != nil {
+		body, _ := json.Marshal(map[string]string{
+			"error":      err.Error(),
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	body, err := json.Marshal(conflicts)
+	if err != nil {
+		log.Errorf("Error marshalling the conflicts of the SNMP traps DB: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+	w.Write(body)
+}
+
+func reloadSNMPTraps(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request to reload the SNMP traps listeners.")
+	w.Header().Set("Content-Type", "application/json")
+
+	if !traps.IsRunning() {
+		body, _ := json.Marshal(map[string]string{
+			"error":      "the SNMP traps server is not running",
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	err := readSNMPTrapsConfig()
+	if err == nil {
+		err = traps.ReloadServer()
+	}
+	if err != nil {
+		log.Errorf("Error reloading the SNMP traps listeners: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+	w.Write([]byte(`{}`))
+}
+
+func injectSNMPTrap(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request to inject an SNMP trap.")
+	w.Header().Set("Content-Type", "application/json")
+
+	if !traps.IsRunning() {
+		body, _ := json.Marshal(map[string]string{
+			"error":      "the SNMP traps server is not running",
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	var trap traps.InjectedTrap
+	if err := json.NewDecoder(r.Body).Decode(&trap); err != nil {
+		body, _ := json.Marshal(map[string]string{"error": fmt.Sprintf("invalid trap: %v", err)})
+		http.Error(w, string(body), 400)
+		return
+	}
+	result, err := traps.InjectTrap(trap)
+	if err != nil {
+		log.Errorf("Error injecting an SNMP trap: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 400)
+		return
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		log.Errorf("Error marshalling the injected SNMP trap: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+	w.Write(body)
+}
+
+func resolveSNMPTrapOIDs(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request to resolve SNMP trap OIDs.")
+	w.Header().Set("Content-Type", "application/json")
+
+	if !traps.IsRunning() {
+		body, _ := json.Marshal(map[string]string{
+			"error":      "the SNMP traps server is not running",
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	query := r.URL.Query()
+	resolved, err := traps.ResolveOIDs(query.Get("namespace"), query.Get("trap_oid"), query["variable"])
+	if err != nil {
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 400)
+		return
+	}
+
+	body, err := json.Marshal(resolved)
+	if err != nil {
+		log.Errorf("Error marshalling the resolved SNMP trap OIDs: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+	w.Write(body)
+}
+
+// readSNMPTrapsConfig reads the snmp_traps_config section of the configuration file again, with
+// its secrets, the rest of the configuration is unchanged.
+func readSNMPTrapsConfig() error {
+	path := config.Datadog.ConfigFileUsed()
+	fileConfig := config.NewConfig("datadog", "DD", strings.NewReplacer(".", "_"))
+	fileConfig.SetConfigFile(path)
+	if err := fileConfig.ReadInConfig(); err != nil {
+		return err
+	}
+	trapsConfig, err := yaml.Marshal(fileConfig.Get("snmp_traps_config"))
+	if err != nil {
+		return err
+	}
+	if trapsConfig, err = secrets.Decrypt(trapsConfig, filepath.Base(path)); err != nil {
+		return err
+	}
+	var decrypted interface{}
+	if err := yaml.Unmarshal(trapsConfig, &decrypted); err != nil {
+		return err
+	}
+	config.Datadog.Set("snmp_traps_config", decrypted)
+	return nil
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	AgentCmd.AddCommand(logsFilesCmd)
+	logsFilesCmd.AddCommand(logsFilesPauseCmd)
+	logsFilesCmd.AddCommand(logsFilesResumeCmd)
+}
+
+var logsFilesCmd = &cobra.Command{
+	Use:   "logs-files",
+	Short: "Control the tailing of the log files by the running agent",
+	Long:  ``,
+}
+
+var logsFilesPauseCmd = &cobra.Command{
+	Use:   "pause <path>",
+	Short: "Pause the tailing of a log file",
+	Long: `Stop reading a log file tailed by the running agent, e.g. a noisy file during an
+incident, until it is resumed or the agent restarts. The offset of the file is kept, the
+logs written meanwhile are collected once it is resumed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupConfig(); err != nil {
+			return err
+		}
+
+		if err := requestLogsFile("pause", args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("The tailing of %s has been paused.\n", args[0])
+		return nil
+	},
+}
+
+var logsFilesResumeCmd = &cobra.Command{
+	Use:   "resume <path>",
+	Short: "Resume the tailing of a paused log file",
+	Long:  ``,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupConfig(); err != nil {
+			return err
+		}
+
+		if err := requestLogsFile("resume", args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("The tailing of %s has been resumed.\n", args[0])
+		return nil
+	},
+}
+
+func requestLogsFile(endpoint string, path string) error {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return err
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/logs/%s", ipcAddress, config.Datadog.GetInt("cmd_port"), endpoint)
+
+	body, err := json.Marshal(map[string]string{"path": path})
+	if err != nil {
+		return err
+	}
+	r, err := util.DoPost(c, urlstr, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			return errors.New(e)
+		}
+		return fmt.Errorf("could not reach agent: %v\nMake sure the agent is running before requesting the logs agent", err)
+	}
+	return nil
+}
@@ -404,7 +404,10 @@ func StartAgent() error {
 
 	// Start SNMP trap server
 	if traps.IsEnabled() {
-		if config.Datadog.GetBool("logs_enabled") {
+		if config.Datadog.GetBool("logs_enabled") || !traps.IsLogsOutputEnabled() {
+			if sender, err := demux.GetDefaultSender(); err == nil {
+				traps.SetEventSender(sender)
+			}
 			err = traps.StartServer(hostname)
 			if err != nil {
 				log.Errorf("Failed to start snmp-traps server: %s", err)
@@ -412,7 +415,7 @@ func StartAgent() error {
 		} else {
 			log.Warn(
 				"snmp-traps server did not start, as log collection is disabled. " +
-					"Please enable log collection to collect and forward traps.",
+					"Please enable log collection to collect and forward traps as logs.",
 			)
 		}
 	}
@@ -0,0 +1,312 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	AgentCmd.AddCommand(snmpTrapsCmd)
+	snmpTrapsCmd.AddCommand(snmpTrapsConflictsCmd)
+	snmpTrapsConflictsCmd.Flags().BoolVarP(&jsonStatus, "json", "j", false, "print out raw json")
+	snmpTrapsConflictsCmd.Flags().BoolVarP(&prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
+	snmpTrapsCmd.AddCommand(snmpTrapsReloadCmd)
+	snmpTrapsCmd.AddCommand(snmpTrapsInjectCmd)
+	snmpTrapsInjectCmd.Flags().StringVarP(&injectedTrap.Source, "source", "s", "", "IP address of the device sending the trap (default 127.0.0.1)")
+	snmpTrapsInjectCmd.Flags().StringVarP(&injectedTrap.Namespace, "namespace", "n", "", "device namespace of the trap (default the namespace of the traps server)")
+	snmpTrapsInjectCmd.Flags().Uint32VarP(&injectedTrap.Uptime, "uptime", "u", 0, "uptime of the device in hundredths of seconds")
+	snmpTrapsInjectCmd.Flags().StringArrayVarP(&injectedVariables, "var", "v", nil, "variable of the trap, as OID=type:value (ex: 1.3.6.1.2.1.2.2.1.1=integer:2)")
+	snmpTrapsCmd.AddCommand(snmpTrapsResolveCmd)
+	snmpTrapsResolveCmd.Flags().StringVarP(&resolvedNamespace, "namespace", "n", "", "device namespace whose traps DB resolves the OIDs (default the namespace of the traps server)")
+	snmpTrapsResolveCmd.Flags().BoolVarP(&jsonStatus, "json", "j", false, "print out raw json")
+	snmpTrapsResolveCmd.Flags().BoolVarP(&prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
+}
+
+var (
+	injectedTrap      traps.InjectedTrap
+	injectedVariables []string
+	resolvedNamespace string
+)
+
+var snmpTrapsCmd = &cobra.Command{
+	Use:   "snmp-traps",
+	Short: "Inspect the SNMP traps server of the running agent",
+	Long:  ``,
+}
+
+var snmpTrapsConflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "Print the trap and variable OIDs defined by several files of the traps DB",
+	Long: `Print the trap and variable OIDs defined by several files of the traps DB,
+the MIB files and the traps DB bundles, and the file whose definition is used.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupConfig(); err != nil {
+			return err
+		}
+
+		r, err := requestSNMPTraps("conflicts", nil)
+		if err != nil {
+			return err
+		}
+		if prettyPrintJSON {
+			var prettyJSON bytes.Buffer
+			json.Indent(&prettyJSON, r, "", "  ") //nolint:errcheck
+			fmt.Println(prettyJSON.String())
+			return nil
+		}
+		if jsonStatus {
+			fmt.Println(string(r))
+			return nil
+		}
+
+		var conflicts []traps.OIDConflict
+		if err := json.Unmarshal(r, &conflicts); err != nil {
+			return fmt.Errorf("could not decode the conflicts of the traps DB: %v", err)
+		}
+		if len(conflicts) == 0 {
+			fmt.Println("No OID is defined by several files of the traps DB.")
+			return nil
+		}
+		for _, conflict := range conflicts {
+			if conflict.Namespace != "" {
+				fmt.Printf("%s %s is defined in namespace %s by:\n", conflict.Kind, conflict.OID, conflict.Namespace)
+			} else {
+				fmt.Printf("%s %s is defined by:\n", conflict.Kind, conflict.OID)
+			}
+			used := make(map[string]bool, len(conflict.Winners))
+			for _, file := range conflict.Winners {
+				used[file] = true
+			}
+			for _, file := range conflict.Files {
+				if used[file] {
+					fmt.Printf("  - %s (used)\n", file)
+				} else {
+					fmt.Printf("  - %s\n", file)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var snmpTrapsReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the configuration of the SNMP traps listeners",
+	Long: `Read the snmp_traps_config section of the configuration file again and replace the
+listeners of the running agent without dropping the traps being received. The ports,
+credentials, namespaces and tags of the listeners are reloaded, the other options
+require a restart of the agent.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupConfig(); err != nil {
+			return err
+		}
+
+		if _, err := requestSNMPTraps("reload", bytes.NewReader(nil)); err != nil {
+			return err
+		}
+		fmt.Println("The SNMP traps listeners have been reloaded.")
+		return nil
+	},
+}
+
+var snmpTrapsInjectCmd = &cobra.Command{
+	Use:   "inject <trap OID>",
+	Short: "Inject a synthetic SNMP trap into the traps server of the running agent",
+	Long: `Inject a synthetic SNMPv2 trap into the traps server of the running agent, which
+resolves, formats and forwards it as the traps received from the devices, and print the
+forwarded trap. It validates the traps DB files and the logs pipelines without sending
+traps from the devices. The types of the variables are integer, string, oid, ip_address,
+counter32, gauge32, timeticks and counter64.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupConfig(); err != nil {
+			return err
+		}
+
+		injectedTrap.TrapOID = args[0]
+		for _, variable := range injectedVariables {
+			parsed, err := parseInjectedVariable(variable)
+			if err != nil {
+				return err
+			}
+			injectedTrap.Variables = append(injectedTrap.Variables, parsed)
+		}
+		body, err := json.Marshal(injectedTrap)
+		if err != nil {
+			return err
+		}
+		r, err := requestSNMPTraps("inject", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		var prettyJSON bytes.Buffer
+		json.Indent(&prettyJSON, r, "", "  ") //nolint:errcheck
+		fmt.Println(prettyJSON.String())
+		return nil
+	},
+}
+
+var snmpTrapsResolveCmd = &cobra.Command{
+	Use:   "resolve <trap OID> [variable OID...]",
+	Short: "Resolve a trap OID and the OIDs of its variables with the traps DB of the running agent",
+	Long: `Resolve a trap OID and the OIDs of variables of the trap with the traps DB of the
+running agent, as the traps received by the agent, and print their names, their
+enumerated values and the files defining them. It validates the traps DB files
+without waiting for a trap.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupConfig(); err != nil {
+			return err
+		}
+
+		query := url.Values{"trap_oid": {args[0]}, "variable": args[1:]}
+		if resolvedNamespace != "" {
+			query.Set("namespace", resolvedNamespace)
+		}
+		r, err := requestSNMPTraps("resolve?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		if prettyPrintJSON {
+			var prettyJSON bytes.Buffer
+			json.Indent(&prettyJSON, r, "", "  ") //nolint:errcheck
+			fmt.Println(prettyJSON.String())
+			return nil
+		}
+		if jsonStatus {
+			fmt.Println(string(r))
+			return nil
+		}
+
+		var resolved traps.ResolvedTrap
+		if err := json.Unmarshal(r, &resolved); err != nil {
+			return fmt.Errorf("could not decode the resolved OIDs: %v", err)
+		}
+		printResolvedTrap(resolved)
+		return nil
+	},
+}
+
+// printResolvedTrap prints the metadata of a trap and of its variables.
+func printResolvedTrap(trap traps.ResolvedTrap) {
+	if trap.Error != "" {
+		fmt.Printf("trap %s (namespace %s): %s\n", trap.OID, trap.Namespace, trap.Error)
+	} else {
+		fmt.Printf("trap %s (namespace %s): %s::%s\n", trap.OID, trap.Namespace, trap.MIB, trap.Name)
+		fmt.Printf("  file: %s\n", trap.SourceFile)
+		if trap.Description != "" {
+			fmt.Printf("  description: %s\n", trap.Description)
+		}
+	}
+	for _, variable := range trap.Variables {
+		if variable.Error != "" {
+			fmt.Printf("variable %s: %s\n", variable.OID, variable.Error)
+			continue
+		}
+		fmt.Printf("variable %s: %s::%s\n", variable.OID, variable.MIB, variable.Name)
+		fmt.Printf("  file: %s\n", variable.SourceFile)
+		if variable.Index != "" {
+			fmt.Printf("  index: %s\n", variable.Index)
+			for _, name := range sortedKeys(variable.IndexComponents) {
+				fmt.Printf("    %s: %v\n", name, variable.IndexComponents[name])
+			}
+		}
+		if variable.Format != "" {
+			fmt.Printf("  format: %s\n", variable.Format)
+		}
+		printLabels("enum", variable.Enumeration)
+		printLabels("bits", variable.Bits)
+	}
+}
+
+// printLabels prints the labels of the values of a variable, sorted by value.
+func printLabels(title string, labels map[int]string) {
+	if len(labels) == 0 {
+		return
+	}
+	values := make([]int, 0, len(labels))
+	for value := range labels {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+	fmt.Printf("  %s:\n", title)
+	for _, value := range values {
+		fmt.Printf("    %d: %s\n", value, labels[value])
+	}
+}
+
+// sortedKeys returns the keys of a map in alphabetical order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseInjectedVariable parses a variable of an injected trap given as OID=type:value.
+func parseInjectedVariable(variable string) (traps.InjectedVariable, error) {
+	oid, typedValue, ok := cutString(variable, "=")
+	if !ok {
+		return traps.InjectedVariable{}, fmt.Errorf("invalid variable %q, expected OID=type:value", variable)
+	}
+	valueType, value, ok := cutString(typedValue, ":")
+	if !ok {
+		return traps.InjectedVariable{}, fmt.Errorf("invalid variable %q, expected OID=type:value", variable)
+	}
+	return traps.InjectedVariable{OID: oid, Type: valueType, Value: value}, nil
+}
+
+// cutString slices s around the first instance of sep.
+func cutString(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// requestSNMPTraps queries an endpoint of the SNMP traps server of the running agent, with a
+// POST request when there is a body.
+func requestSNMPTraps(endpoint string, body io.Reader) ([]byte, error) {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return nil, err
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/snmp-traps/%s", ipcAddress, config.Datadog.GetInt("cmd_port"), endpoint)
+
+	var r []byte
+	if body != nil {
+		r, err = util.DoPost(c, urlstr, "application/json", body)
+	} else {
+		r, err = util.DoGet(c, urlstr, util.LeaveConnectionOpen)
+	}
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			return nil, errors.New(e)
+		}
+		return nil, fmt.Errorf("could not reach agent: %v\nMake sure the agent is running before requesting the SNMP traps server", err)
+	}
+	return r, nil
+}
@@ -126,6 +126,14 @@ func StopServer() {
 func validateToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.String()
+		if isFederationPath(r.URL.Path) {
+			// The runners of the other clusters can be authenticated by the
+			// token of their cluster instead of the DCA token
+			if cluster := util.GetFederatedCluster(r); cluster != "" {
+				next.ServeHTTP(w, r.WithContext(util.WithFederatedCluster(r.Context(), cluster)))
+				return
+			}
+		}
 		var isValid bool
 		if !isExternalPath(path) {
 			if err := util.Validate(w, r); err == nil {
@@ -141,6 +149,13 @@ func validateToken(next http.Handler) http.Handler {
 	})
 }
 
+// isFederationPath returns whether the path is an endpoint used by the cluster
+// check runners of the other clusters with the cluster checks federation.
+func isFederationPath(path string) bool {
+	return (strings.HasPrefix(path, "/api/v1/clusterchecks/status/") || strings.HasPrefix(path, "/api/v1/clusterchecks/configs/")) &&
+		len(strings.Split(path, "/")) == 6
+}
+
 // isExternal returns whether the path is an endpoint used by Node Agents.
 func isExternalPath(path string) bool {
 	return strings.HasPrefix(path, "/api/v1/metadata/") && len(strings.Split(path, "/")) == 7 || // support for agents < 6.5.0
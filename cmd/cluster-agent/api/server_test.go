@@ -79,3 +79,72 @@ func TestValidateTokenMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTokenMiddlewareFederation(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("cluster_agent.auth_token", "abc123")
+	mockConfig.Set("cluster_checks.federation.cluster_tokens", map[string]string{"satellite": "def456"})
+	defer mockConfig.Set("cluster_checks.federation.cluster_tokens", map[string]string{})
+	util.InitDCAAuthToken()
+
+	tests := []struct {
+		path, authToken    string
+		expectedStatusCode int
+		expectedCluster    string
+	}{
+		{
+			"/api/v1/clusterchecks/configs/node1?cluster=satellite",
+			"def456",
+			http.StatusOK,
+			"satellite",
+		},
+		{
+			"/api/v1/clusterchecks/status/node1",
+			"def456",
+			http.StatusOK,
+			"satellite",
+		},
+		{
+			"/api/v1/clusterchecks/configs/node1",
+			"abc123",
+			http.StatusOK,
+			"",
+		},
+		{
+			"/api/v1/clusterchecks/dispatching",
+			"def456",
+			http.StatusForbidden,
+			"",
+		},
+		{
+			"/api/v1/clusterchecks/configs/node1",
+			"imposter",
+			http.StatusForbidden,
+			"",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d", i), func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.path, nil)
+			require.NoError(t, err)
+
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tt.authToken))
+
+			rr := httptest.NewRecorder()
+
+			var cluster string
+			nopHandler := func(w http.ResponseWriter, r *http.Request) {
+				cluster = util.FederatedCluster(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}
+
+			handler := validateToken(http.HandlerFunc(nopHandler))
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedCluster, cluster)
+		})
+	}
+}
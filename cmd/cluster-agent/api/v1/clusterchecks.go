@@ -13,9 +13,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 
+	"github.com/DataDog/datadog-agent/pkg/api/util"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
 	cctypes "github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
@@ -29,6 +31,14 @@ func installClusterCheckEndpoints(r *mux.Router, sc clusteragent.ServerContext)
 	r.HandleFunc("/clusterchecks/status/{identifier}", postCheckStatus(sc)).Methods("POST")
 	r.HandleFunc("/clusterchecks/configs/{identifier}", getCheckConfigs(sc)).Methods("GET")
 	r.HandleFunc("/clusterchecks/rebalance", postRebalanceChecks(sc)).Methods("POST")
+	r.HandleFunc("/clusterchecks/pin", postPinCheck(sc)).Methods("POST")
+	r.HandleFunc("/clusterchecks/isolate", postIsolateCheck(sc)).Methods("POST")
+	r.HandleFunc("/clusterchecks/drain/{identifier}", drainNode(sc, true)).Methods("POST")
+	r.HandleFunc("/clusterchecks/undrain/{identifier}", drainNode(sc, false)).Methods("POST")
+	r.HandleFunc("/clusterchecks/drain/{identifier}", getDrainStatus(sc)).Methods("GET")
+	r.HandleFunc("/clusterchecks/dispatching", getDispatchingState(sc)).Methods("GET")
+	r.HandleFunc("/clusterchecks/state", getLeaderState(sc)).Methods("GET")
+	r.HandleFunc("/clusterchecks/dryrun", postDryRun(sc)).Methods("POST")
 	r.HandleFunc("/clusterchecks", getState(sc)).Methods("GET")
 }
 
@@ -55,6 +65,10 @@ func postCheckStatus(sc clusteragent.ServerContext) func(w http.ResponseWriter,
 			return
 		}
 
+		if !validateFederatedCluster(w, r, status.Cluster, "postCheckStatus") {
+			return
+		}
+
 		clientIP, err := validateClientIP(r.Header.Get(dcautil.RealIPHeader))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -86,7 +100,12 @@ func getCheckConfigs(sc clusteragent.ServerContext) func(w http.ResponseWriter,
 
 		vars := mux.Vars(r)
 		identifier := vars["identifier"]
-		response, err := sc.ClusterCheckHandler.GetConfigs(identifier)
+		cluster := r.URL.Query().Get("cluster")
+		if !validateFederatedCluster(w, r, cluster, "getCheckConfigs") {
+			return
+		}
+
+		response, err := sc.ClusterCheckHandler.GetConfigs(identifier, cluster)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			incrementRequestMetric("getCheckConfigs", http.StatusInternalServerError)
@@ -119,6 +138,194 @@ func postRebalanceChecks(sc clusteragent.ServerContext) func(w http.ResponseWrit
 	}
 }
 
+// postPinCheck requests that a cluster check be pinned to a node, or unpinned
+func postPinCheck(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ClusterCheckHandler == nil {
+		return clusterChecksDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shouldHandle(w, r, sc.ClusterCheckHandler, "postPinCheck") {
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var request cctypes.PinRequest
+		err := decoder.Decode(&request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			incrementRequestMetric("postPinCheck", http.StatusBadRequest)
+			return
+		}
+
+		response, err := sc.ClusterCheckHandler.PinClusterCheck(request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			incrementRequestMetric("postPinCheck", http.StatusBadRequest)
+			return
+		}
+
+		writeJSONResponse(w, response, "postPinCheck")
+	}
+}
+
+// postIsolateCheck requests that a cluster check be isolated on a dedicated
+// node for a time, or released
+func postIsolateCheck(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ClusterCheckHandler == nil {
+		return clusterChecksDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shouldHandle(w, r, sc.ClusterCheckHandler, "postIsolateCheck") {
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var request cctypes.IsolateRequest
+		err := decoder.Decode(&request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			incrementRequestMetric("postIsolateCheck", http.StatusBadRequest)
+			return
+		}
+
+		response, err := sc.ClusterCheckHandler.IsolateClusterCheck(request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			incrementRequestMetric("postIsolateCheck", http.StatusBadRequest)
+			return
+		}
+
+		writeJSONResponse(w, response, "postIsolateCheck")
+	}
+}
+
+// postDryRun returns the placement the dispatcher would produce with some
+// nodes added and removed, without applying it
+func postDryRun(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ClusterCheckHandler == nil {
+		return clusterChecksDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shouldHandle(w, r, sc.ClusterCheckHandler, "postDryRun") {
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var request cctypes.DryRunRequest
+		err := decoder.Decode(&request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			incrementRequestMetric("postDryRun", http.StatusBadRequest)
+			return
+		}
+
+		response, err := sc.ClusterCheckHandler.DryRunDispatching(request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			incrementRequestMetric("postDryRun", http.StatusBadRequest)
+			return
+		}
+
+		writeJSONResponse(w, response, "postDryRun")
+	}
+}
+
+// drainNode requests that a node be drained, or not drained anymore
+func drainNode(sc clusteragent.ServerContext, draining bool) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ClusterCheckHandler == nil {
+		return clusterChecksDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shouldHandle(w, r, sc.ClusterCheckHandler, "drainNode") {
+			return
+		}
+
+		vars := mux.Vars(r)
+		identifier := vars["identifier"]
+		response, err := sc.ClusterCheckHandler.DrainNode(identifier, draining)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			incrementRequestMetric("drainNode", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, response, "drainNode")
+	}
+}
+
+// getDrainStatus returns the progress of the drain of a node
+func getDrainStatus(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ClusterCheckHandler == nil {
+		return clusterChecksDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shouldHandle(w, r, sc.ClusterCheckHandler, "getDrainStatus") {
+			return
+		}
+
+		vars := mux.Vars(r)
+		identifier := vars["identifier"]
+		response, err := sc.ClusterCheckHandler.GetDrainStatus(identifier)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			incrementRequestMetric("getDrainStatus", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, response, "getDrainStatus")
+	}
+}
+
+// getDispatchingState is used by the followers to replicate the dispatching
+// state of the leader
+func getDispatchingState(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ClusterCheckHandler == nil {
+		return clusterChecksDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shouldHandle(w, r, sc.ClusterCheckHandler, "getDispatchingState") {
+			return
+		}
+
+		response, err := sc.ClusterCheckHandler.GetDispatchingState()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			incrementRequestMetric("getDispatchingState", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, response, "getDispatchingState")
+	}
+}
+
+// getLeaderState is used by the followers to serve the state of the leader
+func getLeaderState(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ClusterCheckHandler == nil {
+		return clusterChecksDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shouldHandle(w, r, sc.ClusterCheckHandler, "getLeaderState") {
+			return
+		}
+
+		response, err := sc.ClusterCheckHandler.GetState()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			incrementRequestMetric("getLeaderState", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, response, "getLeaderState")
+	}
+}
+
 // getState is used by the clustercheck config
 func getState(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
 	if sc.ClusterCheckHandler == nil {
@@ -186,6 +393,20 @@ func clusterChecksDisabledHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Cluster-checks are not enabled"))
 }
 
+// validateFederatedCluster rejects the requests authenticated by the federation
+// token of a cluster that report another cluster
+func validateFederatedCluster(w http.ResponseWriter, r *http.Request, cluster, handler string) bool {
+	// the clusters of the federation tokens are lowercased by the configuration
+	federated := util.FederatedCluster(r.Context())
+	if federated == "" || strings.EqualFold(federated, cluster) {
+		return true
+	}
+
+	http.Error(w, fmt.Sprintf("the token of cluster %q cannot be used by cluster %q", federated, cluster), http.StatusForbidden)
+	incrementRequestMetric(handler, http.StatusForbidden)
+	return false
+}
+
 // validateClientIP validates the http client IP retrieved from the request's header.
 // Empty IPs are considered valid for backward compatibility with old clc runner versions
 // that don't set the realIPHeader header field.
@@ -9,9 +9,65 @@
 package v1
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/api/util"
 )
 
+func Test_validateFederatedCluster(t *testing.T) {
+	tests := []struct {
+		name       string
+		federated  string
+		cluster    string
+		want       bool
+		wantStatus int
+	}{
+		{
+			name:       "authenticated by the cluster agent token",
+			federated:  "",
+			cluster:    "satellite",
+			want:       true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "same cluster",
+			federated:  "satellite",
+			cluster:    "satellite",
+			want:       true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "mixed-case cluster",
+			federated:  "satellite-eu",
+			cluster:    "Satellite-EU",
+			want:       true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "other cluster",
+			federated:  "satellite",
+			cluster:    "imposter",
+			want:       false,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/clusterchecks/configs/node1", nil)
+			req = req.WithContext(util.WithFederatedCluster(context.Background(), tt.federated))
+			rr := httptest.NewRecorder()
+
+			assert.Equal(t, tt.want, validateFederatedCluster(rr, req, tt.cluster, "getCheckConfigs"))
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
+
 func Test_validateClientIP(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -279,10 +279,12 @@ func start(cmd *cobra.Command, args []string) error {
 	// start the autoconfig, this will immediately run any configured check
 	common.StartAutoConfig()
 
+	var clusterCheckHandler *clusterchecks.Handler
 	if config.Datadog.GetBool("cluster_checks.enabled") {
 		// Start the cluster check Autodiscovery
-		clusterCheckHandler, err := setupClusterCheck(mainCtx)
+		handler, err := setupClusterCheck(mainCtx)
 		if err == nil {
+			clusterCheckHandler = handler
 			api.ModifyAPIRouter(func(r *mux.Router) {
 				dcav1.InstallChecksEndpoints(r, clusteragent.ServerContext{ClusterCheckHandler: clusterCheckHandler})
 			})
@@ -370,6 +372,11 @@ func start(cmd *cobra.Command, args []string) error {
 		log.Warnf("Some components were unhealthy: %v", health.Unhealthy)
 	}
 
+	// Hand the cluster checks dispatching over to the next leader
+	if clusterCheckHandler != nil {
+		clusterCheckHandler.Handover()
+	}
+
 	// Cancel the main context to stop components
 	mainCtxCancel()
 
@@ -13,6 +13,10 @@ import "github.com/DataDog/datadog-agent/cmd/cluster-agent/commands"
 func init() {
 	clusterChecksCmd := commands.GetClusterChecksCobraCmd(&flagNoColor, &confPath, loggerName)
 	clusterChecksCmd.AddCommand(commands.RebalanceClusterChecksCobraCmd(&flagNoColor, &confPath, loggerName))
+	clusterChecksCmd.AddCommand(commands.PinClusterCheckCobraCmd(&flagNoColor, &confPath, loggerName))
+	clusterChecksCmd.AddCommand(commands.IsolateClusterCheckCobraCmd(&flagNoColor, &confPath, loggerName))
+	clusterChecksCmd.AddCommand(commands.DrainClusterChecksCobraCmd(&flagNoColor, &confPath, loggerName))
+	clusterChecksCmd.AddCommand(commands.DryRunClusterChecksCobraCmd(&flagNoColor, &confPath, loggerName))
 
 	ClusterAgentCmd.AddCommand(clusterChecksCmd)
 }
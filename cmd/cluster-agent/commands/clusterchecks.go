@@ -12,6 +12,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/pkg/api/util"
@@ -24,7 +27,14 @@ import (
 )
 
 var (
-	checkName string
+	checkName         string
+	isolateTTL        time.Duration
+	isolateRelease    bool
+	drainCancel       bool
+	drainStatus       bool
+	dryRunAddNodes    []string
+	dryRunRemoveNodes []string
+	dryRunRebalance   bool
 )
 
 func GetClusterChecksCobraCmd(flagNoColor *bool, confPath *string, loggerName config.LoggerName) *cobra.Command {
@@ -131,3 +141,337 @@ func rebalanceChecks() error {
 
 	return nil
 }
+
+func PinClusterCheckCobraCmd(flagNoColor *bool, confPath *string, loggerName config.LoggerName) *cobra.Command {
+	clusterChecksCmd := &cobra.Command{
+		Use:   "pin <check ID> [node name]",
+		Short: "Pins a cluster check to a node, or unpins it if no node name is given",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if *flagNoColor {
+				color.NoColor = true
+			}
+
+			// we'll search for a config file named `datadog-cluster.yaml`
+			config.Datadog.SetConfigName("datadog-cluster")
+			err := common.SetupConfig(*confPath)
+			if err != nil {
+				return fmt.Errorf("unable to set up global cluster agent configuration: %v", err)
+			}
+
+			err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+			if err != nil {
+				fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+				return err
+			}
+
+			request := types.PinRequest{CheckID: args[0]}
+			if len(args) == 2 {
+				request.NodeName = args[1]
+			}
+			return pinCheck(request)
+		},
+	}
+
+	return clusterChecksCmd
+}
+
+func pinCheck(request types.PinRequest) error {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	urlstr := fmt.Sprintf("https://localhost:%v/api/v1/clusterchecks/pin", config.Datadog.GetInt("cluster_agent.cmd_port"))
+
+	// Set session token
+	err := util.SetAuthToken()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	r, err := util.DoPost(c, urlstr, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		if len(r) > 0 {
+			return fmt.Errorf("could not pin check %s: %s", request.CheckID, bytes.TrimSpace(r))
+		}
+		return fmt.Errorf("could not reach agent: %v", err)
+	}
+
+	if request.NodeName == "" {
+		fmt.Printf("Cluster check %s unpinned successfully\n", request.CheckID)
+	} else {
+		fmt.Printf("Cluster check %s pinned to node %s successfully\n", request.CheckID, request.NodeName)
+	}
+	return nil
+}
+
+func IsolateClusterCheckCobraCmd(flagNoColor *bool, confPath *string, loggerName config.LoggerName) *cobra.Command {
+	clusterChecksCmd := &cobra.Command{
+		Use:   "isolate <check ID> [node name]",
+		Short: "Moves a cluster check to a dedicated node for a time, evicting the other checks from it",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if *flagNoColor {
+				color.NoColor = true
+			}
+
+			// we'll search for a config file named `datadog-cluster.yaml`
+			config.Datadog.SetConfigName("datadog-cluster")
+			err := common.SetupConfig(*confPath)
+			if err != nil {
+				return fmt.Errorf("unable to set up global cluster agent configuration: %v", err)
+			}
+
+			err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+			if err != nil {
+				fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+				return err
+			}
+
+			request := types.IsolateRequest{
+				CheckID: args[0],
+				TTL:     int64(isolateTTL.Seconds()),
+				Release: isolateRelease,
+			}
+			if len(args) == 2 {
+				request.NodeName = args[1]
+			}
+			return isolateCheck(request)
+		},
+	}
+	clusterChecksCmd.Flags().DurationVarP(&isolateTTL, "ttl", "", 0, "time before the check is released, cluster_checks.isolation_ttl by default")
+	clusterChecksCmd.Flags().BoolVarP(&isolateRelease, "release", "", false, "release the isolated check")
+
+	return clusterChecksCmd
+}
+
+func isolateCheck(request types.IsolateRequest) error {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	urlstr := fmt.Sprintf("https://localhost:%v/api/v1/clusterchecks/isolate", config.Datadog.GetInt("cluster_agent.cmd_port"))
+
+	// Set session token
+	err := util.SetAuthToken()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	r, err := util.DoPost(c, urlstr, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		if len(r) > 0 {
+			return fmt.Errorf("could not isolate check %s: %s", request.CheckID, bytes.TrimSpace(r))
+		}
+		return fmt.Errorf("could not reach agent: %v", err)
+	}
+
+	var response types.IsolateResponse
+	if err = json.Unmarshal(r, &response); err != nil {
+		return err
+	}
+
+	if !response.Isolated {
+		fmt.Printf("Cluster check %s released from node %s successfully\n", response.CheckID, response.NodeName)
+	} else {
+		fmt.Printf("Cluster check %s isolated on node %s until %s\n", response.CheckID, response.NodeName, time.Unix(response.Until, 0).Format(time.RFC3339))
+	}
+	return nil
+}
+
+func DrainClusterChecksCobraCmd(flagNoColor *bool, confPath *string, loggerName config.LoggerName) *cobra.Command {
+	clusterChecksCmd := &cobra.Command{
+		Use:   "drain <node name>",
+		Short: "Moves the cluster checks out of a node before scaling it down",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if *flagNoColor {
+				color.NoColor = true
+			}
+
+			// we'll search for a config file named `datadog-cluster.yaml`
+			config.Datadog.SetConfigName("datadog-cluster")
+			err := common.SetupConfig(*confPath)
+			if err != nil {
+				return fmt.Errorf("unable to set up global cluster agent configuration: %v", err)
+			}
+
+			err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+			if err != nil {
+				fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+				return err
+			}
+
+			return drainNode(args[0])
+		},
+	}
+	clusterChecksCmd.Flags().BoolVarP(&drainCancel, "cancel", "", false, "stop draining the node")
+	clusterChecksCmd.Flags().BoolVarP(&drainStatus, "status", "", false, "only print the progress of the drain")
+
+	return clusterChecksCmd
+}
+
+func drainNode(nodeName string) error {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	endpoint := "drain"
+	if drainCancel {
+		endpoint = "undrain"
+	}
+	urlstr := fmt.Sprintf("https://localhost:%v/api/v1/clusterchecks/%s/%s", config.Datadog.GetInt("cluster_agent.cmd_port"), endpoint, nodeName)
+
+	// Set session token
+	err := util.SetAuthToken()
+	if err != nil {
+		return err
+	}
+
+	var r []byte
+	if drainStatus {
+		r, err = util.DoGet(c, urlstr, util.LeaveConnectionOpen)
+	} else {
+		r, err = util.DoPost(c, urlstr, "application/json", bytes.NewBuffer([]byte{}))
+	}
+	if err != nil {
+		if len(r) > 0 {
+			return fmt.Errorf("could not drain node %s: %s", nodeName, bytes.TrimSpace(r))
+		}
+		return fmt.Errorf("could not reach agent: %v", err)
+	}
+
+	var status types.DrainResponse
+	if err = json.Unmarshal(r, &status); err != nil {
+		return err
+	}
+
+	switch {
+	case !status.Draining:
+		fmt.Printf("Node %s is not draining\n", status.NodeName)
+	case status.Complete:
+		fmt.Printf("Node %s is drained\n", status.NodeName)
+	default:
+		fmt.Printf("Node %s is draining, %d cluster checks left to move\n", status.NodeName, status.Remaining)
+	}
+	return nil
+}
+
+func DryRunClusterChecksCobraCmd(flagNoColor *bool, confPath *string, loggerName config.LoggerName) *cobra.Command {
+	clusterChecksCmd := &cobra.Command{
+		Use:   "dryrun",
+		Short: "Previews the cluster checks dispatching with nodes added or removed, without applying it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if *flagNoColor {
+				color.NoColor = true
+			}
+
+			// we'll search for a config file named `datadog-cluster.yaml`
+			config.Datadog.SetConfigName("datadog-cluster")
+			err := common.SetupConfig(*confPath)
+			if err != nil {
+				return fmt.Errorf("unable to set up global cluster agent configuration: %v", err)
+			}
+
+			err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+			if err != nil {
+				fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+				return err
+			}
+
+			request := types.DryRunRequest{
+				RemoveNodes: dryRunRemoveNodes,
+				Rebalance:   dryRunRebalance,
+			}
+			for _, node := range dryRunAddNodes {
+				dryRunNode, err := parseDryRunNode(node)
+				if err != nil {
+					return err
+				}
+				request.AddNodes = append(request.AddNodes, dryRunNode)
+			}
+			return dryRun(request)
+		},
+	}
+	clusterChecksCmd.Flags().StringArrayVarP(&dryRunAddNodes, "add-node", "", nil, "node to add, as name[:zone[:capacity]]")
+	clusterChecksCmd.Flags().StringArrayVarP(&dryRunRemoveNodes, "remove-node", "", nil, "name of a node to remove")
+	clusterChecksCmd.Flags().BoolVarP(&dryRunRebalance, "rebalance", "", false, "preview the rebalancing too")
+
+	return clusterChecksCmd
+}
+
+// parseDryRunNode parses a node given as name[:zone[:capacity]]
+func parseDryRunNode(value string) (types.DryRunNode, error) {
+	parts := strings.SplitN(value, ":", 3)
+	node := types.DryRunNode{Name: parts[0]}
+	if len(parts) > 1 {
+		node.Zone = parts[1]
+	}
+	if len(parts) > 2 {
+		capacity, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return node, fmt.Errorf("invalid capacity for node %s: %v", node.Name, err)
+		}
+		node.Capacity = capacity
+	}
+	return node, nil
+}
+
+func dryRun(request types.DryRunRequest) error {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	urlstr := fmt.Sprintf("https://localhost:%v/api/v1/clusterchecks/dryrun", config.Datadog.GetInt("cluster_agent.cmd_port"))
+
+	// Set session token
+	err := util.SetAuthToken()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	r, err := util.DoPost(c, urlstr, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		if len(r) > 0 {
+			return fmt.Errorf("could not preview the dispatching: %s", bytes.TrimSpace(r))
+		}
+		return fmt.Errorf("could not reach agent: %v", err)
+	}
+
+	var response types.DryRunResponse
+	if err = json.Unmarshal(r, &response); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d cluster checks would move\n", len(response.Moves))
+	for _, move := range response.Moves {
+		switch {
+		case move.DestNodeName == "":
+			fmt.Printf("Check %s (%s) on node %s could not be dispatched\n", move.Name, move.Digest, move.SourceNodeName)
+		case move.SourceNodeName == "":
+			fmt.Printf("Check %s (%s) would be dispatched to node %s\n", move.Name, move.Digest, move.DestNodeName)
+		default:
+			fmt.Printf("Check %s (%s) would move from node %s to %s\n", move.Name, move.Digest, move.SourceNodeName, move.DestNodeName)
+		}
+	}
+	for _, check := range response.Rebalancing {
+		fmt.Printf("Rebalancing would move check %s with weight %d from node %s to %s\n",
+			check.CheckID, check.CheckWeight, check.SourceNodeName, check.DestNodeName)
+	}
+
+	fmt.Println("")
+	for _, node := range response.Nodes {
+		fmt.Printf("Node %s: %d cluster checks\n", node.Name, len(node.Configs))
+	}
+	if len(response.Dangling) > 0 {
+		fmt.Printf("%d cluster checks would be unassigned\n", len(response.Dangling))
+	}
+	return nil
+}
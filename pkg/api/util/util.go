@@ -6,13 +6,20 @@
 package util
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/api/security"
+	"github.com/DataDog/datadog-agent/pkg/config"
 )
 
+// federatedClusterKey is the context key of the cluster authenticated by a
+// federation token
+type federatedClusterKey struct{}
+
 var (
 	token    string
 	dcaToken string
@@ -125,6 +132,37 @@ func ValidateDCARequest(w http.ResponseWriter, r *http.Request) error {
 	return err
 }
 
+// GetFederatedCluster returns the cluster whose federation token, set in
+// cluster_checks.federation.cluster_tokens, authenticates a request of a cluster
+// check runner of another cluster, or an empty string. As the keys of the
+// configuration, the name of the cluster is lowercased.
+func GetFederatedCluster(r *http.Request) string {
+	tok := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(tok) != 2 || tok[0] != "Bearer" || tok[1] == "" {
+		return ""
+	}
+
+	for cluster, clusterToken := range config.Datadog.GetStringMapString("cluster_checks.federation.cluster_tokens") {
+		if clusterToken != "" && subtle.ConstantTimeCompare([]byte(tok[1]), []byte(clusterToken)) == 1 {
+			return cluster
+		}
+	}
+	return ""
+}
+
+// WithFederatedCluster returns a context holding the cluster authenticated by
+// the federation token of a request
+func WithFederatedCluster(ctx context.Context, cluster string) context.Context {
+	return context.WithValue(ctx, federatedClusterKey{}, cluster)
+}
+
+// FederatedCluster returns the cluster authenticated by the federation token
+// of a request, or an empty string if it was authenticated by the DCA token
+func FederatedCluster(ctx context.Context) string {
+	cluster, _ := ctx.Value(federatedClusterKey{}).(string)
+	return cluster
+}
+
 // IsForbidden returns whether the cluster check runner server is allowed to listen on a given ip
 // The function is a non-secure helper to help avoiding setting an IP that's too permissive.
 // The function doesn't guarantee any security feature
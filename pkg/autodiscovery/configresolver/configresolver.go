@@ -71,6 +71,10 @@ func Resolve(tpl integration.Config, svc listeners.Service) (integration.Config,
 		Source:          tpl.Source,
 		MetricsExcluded: svc.HasFilter(containers.MetricsFilter),
 		LogsExcluded:    svc.HasFilter(containers.LogsFilter),
+		Topology:        tpl.Topology,
+		PinnedNode:      tpl.PinnedNode,
+		RunnerPool:      tpl.RunnerPool,
+		Cost:            tpl.Cost,
 	}
 	copy(resolvedConfig.InitConfig, tpl.InitConfig)
 	copy(resolvedConfig.Instances, tpl.Instances)
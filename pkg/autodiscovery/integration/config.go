@@ -96,6 +96,22 @@ type Config struct {
 	// LogsExcluded is whether logs collection is disabled (set by container
 	// listeners only)
 	LogsExcluded bool `json:"logs_excluded"` // (include in digest: false)
+
+	// Topology is the topology constraint of a cluster check, used by the
+	// cluster-agent to select the node it is dispatched to (optional)
+	Topology TopologyConstraint `json:"topology"` // (include in digest: true)
+
+	// PinnedNode is the name of the node a cluster check is pinned to: the
+	// cluster-agent always dispatches it to this node (optional)
+	PinnedNode string `json:"pinned_node"` // (include in digest: false)
+
+	// RunnerPool is the pool of runners a cluster check is dispatched to,
+	// overriding the pool of its check name (optional)
+	RunnerPool string `json:"runner_pool"` // (include in digest: false)
+
+	// Cost is the dispatching cost of each instance of a cluster check,
+	// weighted by the cost function of the cluster-agent (optional)
+	Cost int `json:"cost"` // (include in digest: true)
 }
 
 // CommonInstanceConfig holds the reserved fields for the yaml instance data
@@ -131,6 +147,22 @@ func (k KubeNamespacedName) IsEmpty() bool {
 	return k.Name == "" && k.Namespace == ""
 }
 
+// TopologyConstraint restricts the nodes a cluster check can be dispatched to,
+// based on the zone label reported by the node-agents.
+type TopologyConstraint struct {
+	// Zone is the zone of the monitored endpoint: the check runs on a node of
+	// this zone when one is available
+	Zone string `yaml:"zone,omitempty" json:"zone,omitempty"`
+	// Spread dispatches the configurations of the check across the zones,
+	// it is ignored when Zone is set
+	Spread bool `yaml:"spread,omitempty" json:"spread,omitempty"`
+}
+
+// IsEmpty returns true if the TopologyConstraint is empty
+func (t TopologyConstraint) IsEmpty() bool {
+	return t.Zone == "" && !t.Spread
+}
+
 // Equal determines whether the passed config is the same
 func (c *Config) Equal(cfg *Config) bool {
 	if cfg == nil {
@@ -391,6 +423,15 @@ func (c *Config) Digest() string {
 	h.Write([]byte(c.LogsConfig))                                  //nolint:errcheck
 	h.Write([]byte(c.ServiceID))                                   //nolint:errcheck
 	h.Write([]byte(strconv.FormatBool(c.IgnoreAutodiscoveryTags))) //nolint:errcheck
+	if !c.Topology.IsEmpty() {
+		// the configurations are dispatched again when their topology constraint changes
+		h.Write([]byte(c.Topology.Zone))                       //nolint:errcheck
+		h.Write([]byte(strconv.FormatBool(c.Topology.Spread))) //nolint:errcheck
+	}
+	if c.Cost != 0 {
+		// the configurations are dispatched again when their cost changes
+		h.Write([]byte(strconv.Itoa(c.Cost))) //nolint:errcheck
+	}
 
 	return strconv.FormatUint(h.Sum64(), 16)
 }
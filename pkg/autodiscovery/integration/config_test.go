@@ -236,6 +236,31 @@ func TestDigest(t *testing.T) {
 
 	// assert the ClusterCheck field is not taken into account
 	assert.NotEqual(t, simpleConfig.Digest(), simpleIngoreADTagsConfig.Digest())
+
+	zonedConfig := &Config{
+		Name:       "foo",
+		InitConfig: Data(""),
+		Topology:   TopologyConstraint{Zone: "zone-a"},
+	}
+	spreadConfig := &Config{
+		Name:       "foo",
+		InitConfig: Data(""),
+		Topology:   TopologyConstraint{Spread: true},
+	}
+
+	// assert a topology change produces different hashes
+	assert.NotEqual(t, simpleConfig.Digest(), zonedConfig.Digest())
+	assert.NotEqual(t, simpleConfig.Digest(), spreadConfig.Digest())
+	assert.NotEqual(t, zonedConfig.Digest(), spreadConfig.Digest())
+
+	costlyConfig := &Config{
+		Name:       "foo",
+		InitConfig: Data(""),
+		Cost:       500,
+	}
+
+	// assert a cost change produces different hashes
+	assert.NotEqual(t, simpleConfig.Digest(), costlyConfig.Digest())
 }
 
 func TestGetNameForInstance(t *testing.T) {
@@ -7,18 +7,28 @@ package providers
 
 import (
 	"context"
+	"runtime"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers/names"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner/expvars"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/clustername"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/hostinfo"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
-const defaultGraceDuration = 60 * time.Second
+const (
+	defaultGraceDuration = 60 * time.Second
+	// labelsRefreshInterval is the interval at which the labels reported to
+	// the cluster-agent are refreshed, as the node labels can change
+	labelsRefreshInterval = 5 * time.Minute
+)
 
 // ClusterChecksConfigProvider implements the ConfigProvider interface
 // for the cluster check feature.
@@ -28,6 +38,11 @@ type ClusterChecksConfigProvider struct {
 	heartbeat      time.Time
 	lastChange     int64
 	identifier     string
+	labels         map[string]string
+	labelsRefresh  time.Time
+	capacity       float64
+	cluster        string
+	checkIDs       map[check.ID]struct{} // IDs of the checks dispatched by the cluster-agent
 	flushedConfigs bool
 }
 
@@ -60,6 +75,9 @@ func NewClusterChecksConfigProvider(providerConfig *config.ConfigurationProvider
 		c.graceDuration = time.Duration(providerConfig.GraceTimeSeconds) * time.Second
 	}
 
+	c.capacity = getRunnerCapacity()
+	c.cluster = getRunnerCluster()
+
 	// Register in the cluster agent as soon as possible
 	c.IsUpToDate(context.TODO()) //nolint:errcheck
 
@@ -93,8 +111,17 @@ func (c *ClusterChecksConfigProvider) IsUpToDate(ctx context.Context) (bool, err
 		}
 	}
 
+	if time.Since(c.labelsRefresh) >= labelsRefreshInterval {
+		c.labels = getRunnerLabels()
+		c.labelsRefresh = time.Now()
+	}
+
 	status := types.NodeStatus{
-		LastChange: c.lastChange,
+		LastChange:     c.lastChange,
+		Labels:         c.labels,
+		Capacity:       c.capacity,
+		Cluster:        c.cluster,
+		CheckDurations: c.getCheckDurations(),
 	}
 
 	reply, err := c.dcaClient.PostClusterCheckStatus(ctx, c.identifier, status)
@@ -126,7 +153,7 @@ func (c *ClusterChecksConfigProvider) Collect(ctx context.Context) ([]integratio
 		}
 	}
 
-	reply, err := c.dcaClient.GetClusterCheckConfigs(ctx, c.identifier)
+	reply, err := c.dcaClient.GetClusterCheckConfigs(ctx, c.identifier, c.cluster)
 	if err != nil {
 		if !c.flushedConfigs {
 			// On first error after grace period, mask the error once
@@ -140,9 +167,93 @@ func (c *ClusterChecksConfigProvider) Collect(ctx context.Context) ([]integratio
 	c.flushedConfigs = false
 	c.lastChange = reply.LastChange
 	log.Tracef("Storing last change %d", c.lastChange)
+
+	c.checkIDs = make(map[check.ID]struct{})
+	for _, conf := range reply.Configs {
+		for _, instance := range conf.Instances {
+			c.checkIDs[check.BuildID(conf.Name, instance, conf.InitConfig)] = struct{}{}
+		}
+	}
 	return reply.Configs, nil
 }
 
+// getCheckDurations returns the histograms of the execution durations of the
+// recent runs of the checks dispatched by the cluster-agent, for its
+// latency-aware rebalancing
+func (c *ClusterChecksConfigProvider) getCheckDurations() map[string]types.DurationHistogram {
+	if len(c.checkIDs) == 0 {
+		return nil
+	}
+
+	durations := make(map[string]types.DurationHistogram)
+	for _, checks := range expvars.GetCheckStats() {
+		for id, stats := range checks {
+			if _, found := c.checkIDs[id]; !found {
+				continue
+			}
+			durations[string(id)] = types.NewDurationHistogram(stats.RecentExecutionTimes())
+		}
+	}
+	return durations
+}
+
+// getRunnerLabels returns the labels reported to the cluster-agent for its
+// topology-aware and pool dispatching: the zone and pool labels of the
+// Kubernetes node, overridden by the ones set in clc_runner_labels. The other
+// labels are not used by the cluster-agent, so they are not reported.
+func getRunnerLabels() map[string]string {
+	reported := make(map[string]struct{})
+	for _, key := range []string{"cluster_checks.topology_zone_label", "cluster_checks.runner_pool_label"} {
+		if name := config.Datadog.GetString(key); name != "" {
+			reported[name] = struct{}{}
+		}
+	}
+
+	labels := make(map[string]string)
+	if config.IsKubernetes() {
+		nodeLabels, err := hostinfo.GetNodeLabels(context.TODO())
+		if err != nil {
+			log.Debugf("Cannot get node labels, they will not be reported to the cluster-agent: %s", err)
+		}
+		for name, value := range nodeLabels {
+			if _, found := reported[name]; found {
+				labels[name] = value
+			}
+		}
+	}
+	for name, value := range config.Datadog.GetStringMapString("clc_runner_labels") {
+		if _, found := reported[name]; found {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// getRunnerCapacity returns the capacity weight reported to the cluster-agent,
+// which dispatches proportionally less checks to the agents with a lower
+// capacity: the clc_runner_capacity option if set, or else the number of check
+// runners, or else the number of CPUs when the check runners are dynamic.
+func getRunnerCapacity() float64 {
+	if capacity := config.Datadog.GetFloat64("clc_runner_capacity"); capacity > 0 {
+		return capacity
+	}
+	if workers := config.Datadog.GetInt("check_runners"); workers > 0 {
+		return float64(workers)
+	}
+	return float64(runtime.NumCPU())
+}
+
+// getRunnerCluster returns the cluster reported to the cluster-agent, which
+// only dispatches the federated checks to the runners of other clusters: the
+// clc_runner_cluster_name option if set, or else the detected cluster name.
+func getRunnerCluster() string {
+	if cluster := config.Datadog.GetString("clc_runner_cluster_name"); cluster != "" {
+		return cluster
+	}
+	hostname, _ := util.GetHostname(context.TODO())
+	return clustername.GetClusterName(context.TODO(), hostname)
+}
+
 func init() {
 	RegisterProvider(names.ClusterChecksRegisterName, NewClusterChecksConfigProvider)
 }
@@ -34,8 +34,12 @@ type configFormat struct {
 	MetricConfig            interface{}                        `yaml:"jmx_metrics"`
 	LogsConfig              interface{}                        `yaml:"logs"`
 	Instances               []integration.RawMap
-	DockerImages            []string `yaml:"docker_images"`             // Only imported for deprecation warning
-	IgnoreAutodiscoveryTags bool     `yaml:"ignore_autodiscovery_tags"` // Use to ignore tags coming from autodiscovery
+	DockerImages            []string                       `yaml:"docker_images"`             // Only imported for deprecation warning
+	IgnoreAutodiscoveryTags bool                           `yaml:"ignore_autodiscovery_tags"` // Use to ignore tags coming from autodiscovery
+	Topology                integration.TopologyConstraint `yaml:"topology"`                  // Topology constraint of cluster checks
+	PinnedNode              string                         `yaml:"pinned_node"`               // Node cluster checks are pinned to
+	RunnerPool              string                         `yaml:"runner_pool"`               // Pool of runners cluster checks are dispatched to
+	Cost                    int                            `yaml:"cost"`                      // Dispatching cost of cluster checks
 }
 
 type configPkg struct {
@@ -397,6 +401,12 @@ func GetIntegrationConfigFromFile(name, fpath string) (integration.Config, error
 	// Copy ignore_autodiscovery_tags parameter
 	conf.IgnoreAutodiscoveryTags = cf.IgnoreAutodiscoveryTags
 
+	// Copy the cluster check topology constraint, pinned node, runner pool and cost
+	conf.Topology = cf.Topology
+	conf.PinnedNode = cf.PinnedNode
+	conf.RunnerPool = cf.RunnerPool
+	conf.Cost = cf.Cost
+
 	// DockerImages entry was found: we ignore it if no ADIdentifiers has been found
 	if len(cf.DockerImages) > 0 && len(cf.ADIdentifiers) == 0 {
 		return conf, errors.New("the 'docker_images' section is deprecated, please use 'ad_identifiers' instead")
@@ -8,9 +8,21 @@
 
 package providers
 
-import "strings"
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
 
-const ignoreADTagsAnnotationSuffix = "ignore_autodiscovery_tags"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+const (
+	ignoreADTagsAnnotationSuffix = "ignore_autodiscovery_tags"
+	topologyAnnotationSuffix     = "topology"
+	pinnedNodeAnnotationSuffix   = "pinned_node"
+	runnerPoolAnnotationSuffix   = "runner_pool"
+	costAnnotationSuffix         = "cost"
+)
 
 // ignoreADTagsFromAnnotations returns whether the check should have autodiscovery tags from the service (e.g kube_namespace)
 // based on the value of the annotation ad.datadoghq.com/ignore_autodiscovery_tags
@@ -20,3 +32,38 @@ func ignoreADTagsFromAnnotations(annotations map[string]string, prefix string) b
 	}
 	return strings.ToLower(annotations[prefix+ignoreADTagsAnnotationSuffix]) == "true"
 }
+
+// topologyFromAnnotations returns the topology constraint of the cluster checks of a service
+// based on the JSON value of the annotation ad.datadoghq.com/service.topology
+func topologyFromAnnotations(annotations map[string]string, prefix string) (integration.TopologyConstraint, error) {
+	var topology integration.TopologyConstraint
+	value, found := annotations[prefix+topologyAnnotationSuffix]
+	if !found {
+		return topology, nil
+	}
+	err := json.Unmarshal([]byte(value), &topology)
+	return topology, err
+}
+
+// pinnedNodeFromAnnotations returns the node the cluster checks of a service are pinned to
+// based on the value of the annotation ad.datadoghq.com/service.pinned_node
+func pinnedNodeFromAnnotations(annotations map[string]string, prefix string) string {
+	return annotations[prefix+pinnedNodeAnnotationSuffix]
+}
+
+// runnerPoolFromAnnotations returns the pool of runners the cluster checks of a service are
+// dispatched to based on the value of the annotation ad.datadoghq.com/service.runner_pool
+func runnerPoolFromAnnotations(annotations map[string]string, prefix string) string {
+	return annotations[prefix+runnerPoolAnnotationSuffix]
+}
+
+// costFromAnnotations returns the dispatching cost of the cluster checks of a service
+// based on the integer value of the annotation ad.datadoghq.com/service.cost, or
+// ad.datadoghq.com/endpoints.cost for the endpoints checks
+func costFromAnnotations(annotations map[string]string, prefix string) (int, error) {
+	value, found := annotations[prefix+costAnnotationSuffix]
+	if !found {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -218,6 +218,10 @@ func parseServiceAnnotationsForEndpoints(services []*v1.Service) []configInfo {
 			log.Errorf("Cannot parse endpoint template for service %s/%s: %s", svc.Namespace, svc.Name, err)
 		}
 		ignoreADTags := ignoreADTagsFromAnnotations(svc.GetAnnotations(), kubeEndpointAnnotationPrefix)
+		cost, err := costFromAnnotations(svc.GetAnnotations(), kubeEndpointAnnotationPrefix)
+		if err != nil {
+			log.Errorf("Cannot parse dispatching cost for service %s/%s: %s", svc.Namespace, svc.Name, err)
+		}
 		var resolveMode endpointResolveMode
 		if value, found := svc.Annotations[kubeEndpointAnnotationPrefix+kubeEndpointResolvePath]; found {
 			resolveMode = endpointResolveMode(value)
@@ -225,6 +229,7 @@ func parseServiceAnnotationsForEndpoints(services []*v1.Service) []configInfo {
 		for i := range endptConf {
 			endptConf[i].Source = "kube_endpoints:" + endpointsID
 			endptConf[i].IgnoreAutodiscoveryTags = ignoreADTags
+			endptConf[i].Cost = cost
 			configsInfo = append(configsInfo, configInfo{
 				tpl:         endptConf[i],
 				namespace:   svc.Namespace,
@@ -278,6 +283,7 @@ func generateConfigs(tpl integration.Config, resolveMode endpointResolveMode, ke
 				Provider:                tpl.Provider,
 				Source:                  tpl.Source,
 				IgnoreAutodiscoveryTags: tpl.IgnoreAutodiscoveryTags,
+				Cost:                    tpl.Cost,
 			}
 
 			if resolveFunc != nil {
@@ -157,11 +157,25 @@ func parseServiceAnnotations(services []*v1.Service) ([]integration.Config, erro
 			log.Errorf("Cannot parse service template for service %s/%s: %s", svc.Namespace, svc.Name, err)
 		}
 		ignoreADTags := ignoreADTagsFromAnnotations(svc.GetAnnotations(), kubeServiceAnnotationPrefix)
+		topology, err := topologyFromAnnotations(svc.GetAnnotations(), kubeServiceAnnotationPrefix)
+		if err != nil {
+			log.Errorf("Cannot parse topology constraint for service %s/%s: %s", svc.Namespace, svc.Name, err)
+		}
+		pinnedNode := pinnedNodeFromAnnotations(svc.GetAnnotations(), kubeServiceAnnotationPrefix)
+		runnerPool := runnerPoolFromAnnotations(svc.GetAnnotations(), kubeServiceAnnotationPrefix)
+		cost, err := costFromAnnotations(svc.GetAnnotations(), kubeServiceAnnotationPrefix)
+		if err != nil {
+			log.Errorf("Cannot parse dispatching cost for service %s/%s: %s", svc.Namespace, svc.Name, err)
+		}
 		// All configurations are cluster checks
 		for i := range svcConf {
 			svcConf[i].ClusterCheck = true
 			svcConf[i].Source = "kube_services:" + serviceID
 			svcConf[i].IgnoreAutodiscoveryTags = ignoreADTags
+			svcConf[i].Topology = topology
+			svcConf[i].PinnedNode = pinnedNode
+			svcConf[i].RunnerPool = runnerPool
+			svcConf[i].Cost = cost
 		}
 		configs = append(configs, svcConf...)
 	}
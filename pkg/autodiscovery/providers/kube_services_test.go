@@ -85,6 +85,114 @@ func TestParseKubeServiceAnnotations(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "topology constraint",
+			service: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					UID: types.UID("test"),
+					Annotations: map[string]string{
+						"ad.datadoghq.com/service.check_names":  "[\"http_check\"]",
+						"ad.datadoghq.com/service.init_configs": "[{}]",
+						"ad.datadoghq.com/service.instances":    "[{\"name\": \"My service\", \"url\": \"http://%%host%%\", \"timeout\": 1}]",
+						"ad.datadoghq.com/service.topology":     "{\"zone\": \"us-east-1a\"}",
+					},
+					Name:      "svc",
+					Namespace: "ns",
+				},
+			},
+			expectedOut: []integration.Config{
+				{
+					Name:          "http_check",
+					ADIdentifiers: []string{"kube_service://ns/svc"},
+					InitConfig:    integration.Data("{}"),
+					Instances:     []integration.Data{integration.Data("{\"name\":\"My service\",\"timeout\":1,\"url\":\"http://%%host%%\"}")},
+					ClusterCheck:  true,
+					Source:        "kube_services:kube_service://ns/svc",
+					Topology:      integration.TopologyConstraint{Zone: "us-east-1a"},
+				},
+			},
+		},
+		{
+			name: "pinned node",
+			service: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					UID: types.UID("test"),
+					Annotations: map[string]string{
+						"ad.datadoghq.com/service.check_names":  "[\"http_check\"]",
+						"ad.datadoghq.com/service.init_configs": "[{}]",
+						"ad.datadoghq.com/service.instances":    "[{\"name\": \"My service\", \"url\": \"http://%%host%%\", \"timeout\": 1}]",
+						"ad.datadoghq.com/service.pinned_node":  "clc-runner-vpn",
+					},
+					Name:      "svc",
+					Namespace: "ns",
+				},
+			},
+			expectedOut: []integration.Config{
+				{
+					Name:          "http_check",
+					ADIdentifiers: []string{"kube_service://ns/svc"},
+					InitConfig:    integration.Data("{}"),
+					Instances:     []integration.Data{integration.Data("{\"name\":\"My service\",\"timeout\":1,\"url\":\"http://%%host%%\"}")},
+					ClusterCheck:  true,
+					Source:        "kube_services:kube_service://ns/svc",
+					PinnedNode:    "clc-runner-vpn",
+				},
+			},
+		},
+		{
+			name: "runner pool",
+			service: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					UID: types.UID("test"),
+					Annotations: map[string]string{
+						"ad.datadoghq.com/service.check_names":  "[\"oracle\"]",
+						"ad.datadoghq.com/service.init_configs": "[{}]",
+						"ad.datadoghq.com/service.instances":    "[{\"server\": \"%%host%%\"}]",
+						"ad.datadoghq.com/service.runner_pool":  "database",
+					},
+					Name:      "svc",
+					Namespace: "ns",
+				},
+			},
+			expectedOut: []integration.Config{
+				{
+					Name:          "oracle",
+					ADIdentifiers: []string{"kube_service://ns/svc"},
+					InitConfig:    integration.Data("{}"),
+					Instances:     []integration.Data{integration.Data("{\"server\":\"%%host%%\"}")},
+					ClusterCheck:  true,
+					Source:        "kube_services:kube_service://ns/svc",
+					RunnerPool:    "database",
+				},
+			},
+		},
+		{
+			name: "cost",
+			service: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					UID: types.UID("test"),
+					Annotations: map[string]string{
+						"ad.datadoghq.com/service.check_names":  "[\"snmp\"]",
+						"ad.datadoghq.com/service.init_configs": "[{}]",
+						"ad.datadoghq.com/service.instances":    "[{\"ip_address\": \"%%host%%\"}]",
+						"ad.datadoghq.com/service.cost":         "500",
+					},
+					Name:      "svc",
+					Namespace: "ns",
+				},
+			},
+			expectedOut: []integration.Config{
+				{
+					Name:          "snmp",
+					ADIdentifiers: []string{"kube_service://ns/svc"},
+					InitConfig:    integration.Data("{}"),
+					Instances:     []integration.Data{integration.Data("{\"ip_address\":\"%%host%%\"}")},
+					ClusterCheck:  true,
+					Source:        "kube_services:kube_service://ns/svc",
+					Cost:          500,
+				},
+			},
+		},
 	} {
 		t.Run(fmt.Sprintf(tc.name), func(t *testing.T) {
 			cfgs, _ := parseServiceAnnotations([]*v1.Service{tc.service})
@@ -13,6 +13,8 @@ import (
 	"net/http"
 
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 const notReadyReason = "Startup in progress"
@@ -36,24 +38,63 @@ func (h *Handler) ShouldHandle() (int, string) {
 	}
 }
 
-// GetState returns the state of the dispatching, for the clusterchecks cmd
+// GetState returns the state of the dispatching, for the clusterchecks cmd.
+// Followers serve a read-only copy of the state of the leader.
 func (h *Handler) GetState() (types.StateResponse, error) {
 	h.m.RLock()
-	defer h.m.RUnlock()
+	currentState := h.state
+	h.m.RUnlock()
 
-	switch h.state {
+	switch currentState {
 	case leader:
 		return h.dispatcher.getState()
 	case follower:
-		return types.StateResponse{NotRunning: "currently follower"}, nil
+		return h.getLeaderState(), nil
 	default:
 		return types.StateResponse{NotRunning: notReadyReason}, nil
 	}
 }
 
-// GetConfigs returns configurations dispatched to a given agent
-func (h *Handler) GetConfigs(identifier string) (types.ConfigResponse, error) {
-	configs, lastChange, err := h.dispatcher.getClusterCheckConfigs(identifier)
+// getLeaderState fetches the state of the dispatching of the leader, or
+// returns the last one fetched if the leader cannot be reached
+func (h *Handler) getLeaderState() types.StateResponse {
+	if h.leaderStateClient == nil {
+		return types.StateResponse{NotRunning: "currently follower"}
+	}
+
+	h.m.RLock()
+	leaderAddr := fmt.Sprintf("%s:%d", h.leaderIP, h.port)
+	h.m.RUnlock()
+
+	state, err := h.leaderStateClient.GetState(leaderAddr)
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if err != nil {
+		log.Debugf("Cannot fetch the dispatching state of the leader %s: %v", leaderAddr, err)
+		if h.lastLeaderState == nil {
+			return types.StateResponse{NotRunning: fmt.Sprintf("currently follower, cannot reach the leader %s: %v", leaderAddr, err)}
+		}
+		return *h.lastLeaderState
+	}
+	if state.NotRunning == "" {
+		state.LeaderAddress = leaderAddr
+		state.Timestamp = timestampNow()
+		h.lastLeaderState = &state
+	}
+	return state
+}
+
+// GetDispatchingState returns a snapshot of the dispatching state of the
+// leader, replicated by the followers
+func (h *Handler) GetDispatchingState() (types.DispatchingState, error) {
+	return h.dispatcher.getSnapshot(), nil
+}
+
+// GetConfigs returns configurations dispatched to a given agent of a cluster
+func (h *Handler) GetConfigs(identifier, cluster string) (types.ConfigResponse, error) {
+	configs, lastChange, err := h.dispatcher.getClusterCheckConfigs(h.dispatcher.nodeKey(identifier, cluster))
 	response := types.ConfigResponse{
 		Configs:    configs,
 		LastChange: lastChange,
@@ -63,7 +104,7 @@ func (h *Handler) GetConfigs(identifier string) (types.ConfigResponse, error) {
 
 // PostStatus handles status reports from the node agents
 func (h *Handler) PostStatus(identifier, clientIP string, status types.NodeStatus) (types.StatusResponse, error) {
-	upToDate, err := h.dispatcher.processNodeStatus(identifier, clientIP, status)
+	upToDate, err := h.dispatcher.processNodeStatus(h.dispatcher.nodeKey(identifier, status.Cluster), clientIP, status)
 	response := types.StatusResponse{
 		IsUpToDate: upToDate,
 	}
@@ -111,3 +152,48 @@ func (h *Handler) RebalanceClusterChecks() ([]types.RebalanceResponse, error) {
 
 	return response, nil
 }
+
+// PinClusterCheck pins the configuration of a check to a node, bypassing the
+// dispatching and the rebalancing, or unpins it if the node name is empty
+func (h *Handler) PinClusterCheck(request types.PinRequest) (types.PinRequest, error) {
+	if request.CheckID == "" {
+		return request, fmt.Errorf("the check ID is required")
+	}
+	if request.NodeName == "" {
+		return request, h.dispatcher.unpinCheck(request.CheckID)
+	}
+	return request, h.dispatcher.pin(request.CheckID, request.NodeName)
+}
+
+// IsolateClusterCheck moves the configuration of a check to a dedicated node
+// for a time, evicting the other configurations from it, or releases it
+func (h *Handler) IsolateClusterCheck(request types.IsolateRequest) (types.IsolateResponse, error) {
+	if request.CheckID == "" {
+		return types.IsolateResponse{}, fmt.Errorf("the check ID is required")
+	}
+	if request.Release {
+		return h.dispatcher.releaseCheck(request.CheckID)
+	}
+	ttl := request.TTL
+	if ttl <= 0 {
+		ttl = config.Datadog.GetInt64("cluster_checks.isolation_ttl")
+	}
+	return h.dispatcher.isolate(request.CheckID, request.NodeName, ttl)
+}
+
+// DrainNode marks a node as draining, or stops draining it: no check is
+// dispatched to a draining node and its checks are moved to the other nodes
+func (h *Handler) DrainNode(nodeName string, draining bool) (types.DrainResponse, error) {
+	return h.dispatcher.drain(nodeName, draining)
+}
+
+// DryRunDispatching returns the placement the dispatcher would produce with
+// the given nodes added and removed, without applying it
+func (h *Handler) DryRunDispatching(request types.DryRunRequest) (types.DryRunResponse, error) {
+	return h.dispatcher.dryRun(request)
+}
+
+// GetDrainStatus returns the progress of the drain of a node
+func (h *Handler) GetDrainStatus(nodeName string) (types.DrainResponse, error) {
+	return h.dispatcher.drainStatus(nodeName)
+}
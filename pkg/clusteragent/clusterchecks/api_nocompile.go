@@ -44,3 +44,6 @@ func (h *Handler) Run(_ context.Context) error {
 func GetStats() (*types.Stats, error) {
 	return nil, ErrNotCompiled
 }
+
+// Handover not implemented
+func (h *Handler) Handover() {}
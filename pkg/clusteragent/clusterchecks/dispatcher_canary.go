@@ -0,0 +1,283 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	le "github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// replacedConfigGraceSeconds is how long a removed configuration is kept
+// running, waiting for a new version of it to be scheduled. Autodiscovery
+// unschedules the previous version right before scheduling the new one.
+const replacedConfigGraceSeconds = 5
+
+// replacedConfig is a configuration removed by autodiscovery, kept running
+// in case a new version of it is scheduled
+type replacedConfig struct {
+	digest    string
+	removedAt int64
+}
+
+// configChange is a new version of a configuration, replacing a previous one
+type configChange struct {
+	config    integration.Config
+	oldDigest string
+}
+
+// canaryRollout holds the changed configurations of a check: the new versions
+// dispatched first to canaries, and the ones held back until the canaries
+// report enough successful runs, the previous versions running meanwhile.
+type canaryRollout struct {
+	canaries map[string]struct{}     // Digests of the new versions dispatched as canaries
+	pending  map[string]configChange // Changes held back, by digest of the new version
+	failing  bool                    // Whether a canary failed on its last run
+}
+
+func newCanaryRollout() *canaryRollout {
+	return &canaryRollout{
+		canaries: make(map[string]struct{}),
+		pending:  make(map[string]configChange),
+	}
+}
+
+// canaryEnabled returns whether the changed configurations are rolled out
+// with canaries
+func (d *dispatcher) canaryEnabled() bool {
+	return d.canaryCount > 0
+}
+
+// configIdentity identifies the versions of a configuration: the configuration
+// of a check from a given source, for a given service.
+func configIdentity(config integration.Config) string {
+	return config.Name + "|" + config.Source + "|" + config.ServiceID
+}
+
+// holdRemoval keeps a removed configuration running until the next
+// processRollouts, in case a new version of it is scheduled right after.
+// It returns false if the configuration is not dispatched, for it to be
+// removed right away.
+func (d *dispatcher) holdRemoval(config integration.Config) bool {
+	digest := config.Digest()
+	identity := configIdentity(config)
+
+	d.store.Lock()
+	runningDigest := digest
+	if rollout, found := d.store.rollouts[config.Name]; found {
+		delete(rollout.canaries, digest)
+		if change, found := rollout.pending[digest]; found {
+			// The new version was held back, the previous one is still running
+			delete(rollout.pending, digest)
+			runningDigest = change.oldDigest
+		}
+	}
+	if _, found := d.store.digestToConfig[runningDigest]; !found {
+		d.store.Unlock()
+		return false
+	}
+	previous, evicted := d.store.replacedConfigs[identity]
+	d.store.replacedConfigs[identity] = replacedConfig{digest: runningDigest, removedAt: timestampNow()}
+	d.store.Unlock()
+
+	if evicted && previous.digest != runningDigest {
+		d.removeConfig(previous.digest)
+		d.unpin(previous.digest)
+	}
+	return true
+}
+
+// addVersion dispatches a configuration scheduled by autodiscovery. If it
+// replaces a configuration removed right before, the new version is
+// dispatched as a canary of its check, or held back if the check has enough
+// canaries already.
+func (d *dispatcher) addVersion(config integration.Config) {
+	digest := config.Digest()
+	identity := configIdentity(config)
+
+	d.store.Lock()
+	replaced, found := d.store.replacedConfigs[identity]
+	if !found {
+		d.store.Unlock()
+		d.add(config)
+		return
+	}
+	delete(d.store.replacedConfigs, identity)
+	if replaced.digest == digest {
+		// Rescheduled unchanged, keep it running
+		d.store.Unlock()
+		return
+	}
+
+	rollout, found := d.store.rollouts[config.Name]
+	if !found {
+		rollout = newCanaryRollout()
+		d.store.rollouts[config.Name] = rollout
+	}
+	change := configChange{config: config, oldDigest: replaced.digest}
+	canary := len(rollout.canaries) < d.canaryCount
+	if canary {
+		rollout.canaries[digest] = struct{}{}
+	} else {
+		rollout.pending[digest] = change
+	}
+	d.store.Unlock()
+
+	if canary {
+		log.Infof("Dispatching the new version %s of configuration %s:%s as a canary", digest, config.Name, replaced.digest)
+		d.applyChange(change)
+		return
+	}
+	log.Infof("Holding back the new version %s of configuration %s:%s until the canaries of %s succeed", digest, config.Name, replaced.digest, config.Name)
+}
+
+// applyChange replaces the previous version of a configuration with the new one
+func (d *dispatcher) applyChange(change configChange) {
+	d.removeConfig(change.oldDigest)
+	d.unpin(change.oldDigest)
+	d.add(change.config)
+}
+
+// processRollouts removes the configurations no new version replaced, and
+// rolls out the changes of the checks whose canaries succeeded.
+func (d *dispatcher) processRollouts() {
+	if !d.canaryEnabled() {
+		return
+	}
+
+	d.store.Lock()
+	removed := []string{}
+	for identity, replaced := range d.store.replacedConfigs {
+		if timestampNow()-replaced.removedAt >= replacedConfigGraceSeconds {
+			removed = append(removed, replaced.digest)
+			delete(d.store.replacedConfigs, identity)
+		}
+	}
+	d.store.Unlock()
+
+	for _, digest := range removed {
+		log.Debugf("Removing configuration %s, not replaced by a new version", digest)
+		d.removeConfig(digest)
+		d.unpin(digest)
+	}
+
+	if d.hasCanaries() {
+		// Collect the runs of the canaries, the runner stats are only
+		// collected for the rebalancing otherwise
+		d.updateRunnersStats()
+	}
+
+	for _, change := range d.promoteRollouts() {
+		d.applyChange(change)
+	}
+}
+
+// hasCanaries returns whether configurations are dispatched as canaries
+func (d *dispatcher) hasCanaries() bool {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	for _, rollout := range d.store.rollouts {
+		if len(rollout.canaries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// promoteRollouts returns the changes to apply: all the changes of the checks
+// whose canaries succeeded, and new canaries for the checks whose canaries
+// were removed.
+func (d *dispatcher) promoteRollouts() []configChange {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	changes := []configChange{}
+	held := 0
+	for name, rollout := range d.store.rollouts {
+		if len(rollout.canaries) == 0 && len(rollout.pending) == 0 {
+			delete(d.store.rollouts, name)
+			continue
+		}
+
+		succeeded, failing := len(rollout.canaries) > 0, false
+		for digest := range rollout.canaries {
+			canarySucceeded, canaryFailing := d.canaryRuns(digest)
+			succeeded = succeeded && canarySucceeded
+			failing = failing || canaryFailing
+		}
+
+		if failing != rollout.failing {
+			if failing {
+				log.Warnf("A canary of check %s is failing, holding back the new version of %d configurations", name, len(rollout.pending))
+			} else {
+				log.Infof("The canaries of check %s are not failing anymore", name)
+			}
+			rollout.failing = failing
+		}
+
+		if succeeded {
+			if len(rollout.pending) > 0 {
+				log.Infof("The canaries of check %s succeeded, rolling out the new version of %d configurations", name, len(rollout.pending))
+			}
+			for _, change := range rollout.pending {
+				changes = append(changes, change)
+			}
+			delete(d.store.rollouts, name)
+			continue
+		}
+
+		// Replace the removed canaries
+		for digest, change := range rollout.pending {
+			if len(rollout.canaries) >= d.canaryCount {
+				break
+			}
+			delete(rollout.pending, digest)
+			rollout.canaries[digest] = struct{}{}
+			changes = append(changes, change)
+		}
+		held += len(rollout.pending)
+	}
+
+	heldConfigs.Set(float64(held), le.JoinLeaderValue)
+	return changes
+}
+
+// canaryRuns returns whether all the instances of a canary configuration
+// reported enough successful runs, and whether one of them failed on its
+// last run.
+// The store lock must be held by the caller.
+func (d *dispatcher) canaryRuns(digest string) (bool, bool) {
+	config, found := d.store.digestToConfig[digest]
+	if !found {
+		return false, false
+	}
+	node, found := d.store.getNodeStore(d.store.digestToNode[digest])
+	if !found {
+		return false, false
+	}
+
+	succeeded, failing := true, false
+	for _, instance := range config.Instances {
+		stats, err := node.GetRunnerStats(string(check.BuildID(config.Name, instance, config.InitConfig)))
+		if err != nil {
+			// Not run yet
+			succeeded = false
+			continue
+		}
+		if stats.LastExecFailed {
+			failing = true
+		}
+		if stats.LastExecFailed || stats.TotalRuns-stats.TotalErrors < d.canarySuccessfulRuns {
+			succeeded = false
+		}
+	}
+	return succeeded, failing
+}
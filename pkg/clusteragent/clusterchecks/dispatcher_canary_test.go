@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func generateVersionedIntegration(service, version int) (integration.Config, string) {
+	config, checkID := generatePinnedIntegration("http_check", fmt.Sprintf("url: http://%d\ntimeout: %d", service, version), "")
+	config.Source = fmt.Sprintf("kube_services:%d", service)
+	return config, checkID
+}
+
+func TestCanaryRollout(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.canaryCount = 1
+	dispatcher.canarySuccessfulRuns = 2
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	oldVersions := make([]integration.Config, 3)
+	newVersions := make([]integration.Config, 3)
+	newIDs := make([]string, 3)
+	for i := range oldVersions {
+		oldVersions[i], _ = generateVersionedIntegration(i, 1)
+		newVersions[i], newIDs[i] = generateVersionedIntegration(i, 2)
+		dispatcher.addVersion(oldVersions[i])
+	}
+	assert.Len(t, dispatcher.store.digestToConfig, 3)
+	assert.Empty(t, dispatcher.store.rollouts)
+
+	// The first new version is dispatched as a canary, the other ones are held back
+	for i := range oldVersions {
+		dispatcher.remove(oldVersions[i])
+		dispatcher.addVersion(newVersions[i])
+	}
+	assert.Len(t, dispatcher.store.digestToConfig, 3)
+	assert.Contains(t, dispatcher.store.digestToConfig, newVersions[0].Digest())
+	assert.Contains(t, dispatcher.store.digestToConfig, oldVersions[1].Digest())
+	assert.Contains(t, dispatcher.store.digestToConfig, oldVersions[2].Digest())
+	assert.Empty(t, dispatcher.store.replacedConfigs)
+
+	// Not enough successful runs yet
+	canaryNode, _ := dispatcher.store.getNodeStore(dispatcher.store.digestToNode[newVersions[0].Digest()])
+	dispatcher.processRollouts()
+	assert.Contains(t, dispatcher.store.digestToConfig, oldVersions[1].Digest())
+	canaryNode.AddRunnerStats(newIDs[0], types.CLCRunnerStats{TotalRuns: 2, TotalErrors: 1})
+	dispatcher.processRollouts()
+	assert.Contains(t, dispatcher.store.digestToConfig, oldVersions[1].Digest())
+
+	// A failing canary holds back the rollout
+	canaryNode.AddRunnerStats(newIDs[0], types.CLCRunnerStats{TotalRuns: 3, TotalErrors: 2, LastExecFailed: true})
+	dispatcher.processRollouts()
+	assert.Contains(t, dispatcher.store.digestToConfig, oldVersions[1].Digest())
+	assert.True(t, dispatcher.store.rollouts["http_check"].failing)
+
+	// The other new versions are rolled out once the canary succeeded
+	canaryNode.AddRunnerStats(newIDs[0], types.CLCRunnerStats{TotalRuns: 4, TotalErrors: 2})
+	dispatcher.processRollouts()
+	assert.Len(t, dispatcher.store.digestToConfig, 3)
+	for _, config := range newVersions {
+		assert.Contains(t, dispatcher.store.digestToConfig, config.Digest())
+	}
+	assert.Empty(t, dispatcher.store.rollouts)
+
+	// Unchanged configurations keep running
+	dispatcher.remove(newVersions[1])
+	dispatcher.addVersion(newVersions[1])
+	assert.Contains(t, dispatcher.store.digestToConfig, newVersions[1].Digest())
+	assert.Empty(t, dispatcher.store.replacedConfigs)
+	assert.Empty(t, dispatcher.store.rollouts)
+
+	// Configurations not replaced by a new version are removed after a grace period
+	dispatcher.remove(newVersions[2])
+	assert.Contains(t, dispatcher.store.digestToConfig, newVersions[2].Digest())
+	for identity, replaced := range dispatcher.store.replacedConfigs {
+		replaced.removedAt -= replacedConfigGraceSeconds
+		dispatcher.store.replacedConfigs[identity] = replaced
+	}
+	dispatcher.processRollouts()
+	assert.NotContains(t, dispatcher.store.digestToConfig, newVersions[2].Digest())
+	assert.Len(t, dispatcher.store.digestToConfig, 2)
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -28,13 +28,22 @@ func (d *dispatcher) getState() (types.StateResponse, error) {
 	defer d.store.RUnlock()
 
 	response := types.StateResponse{
-		Warmup:   !d.store.active,
-		Dangling: makeConfigArray(d.store.danglingConfigs),
+		Warmup:      !d.store.active,
+		Dangling:    d.makePinnedConfigArray(d.store.danglingConfigs),
+		Unscheduled: makeConfigArray(d.store.unscheduled),
 	}
 	for _, node := range d.store.nodes {
 		n := types.StateNodeResponse{
-			Name:    node.name,
-			Configs: makeConfigArray(node.digestToConfig),
+			Name:     node.name,
+			Zone:     node.zone(d.zoneLabel),
+			Pool:     node.pool(d.poolLabel),
+			Cluster:  d.remoteCluster(node),
+			Capacity: node.capacity(),
+			Cost:     node.GetWeightedBusyness(d.costFunc),
+			Draining: d.store.isDraining(node.name),
+			Excluded: d.store.isExcluded(node.name),
+			Isolated: d.store.isIsolating(node.name),
+			Configs:  d.makePinnedConfigArray(node.digestToConfig),
 		}
 		response.Nodes = append(response.Nodes, n)
 	}
@@ -48,14 +57,14 @@ func (d *dispatcher) addConfig(config integration.Config, targetNodeName string)
 
 	// Register config
 	digest := config.Digest()
-	d.store.digestToConfig[digest] = config
-	for _, instance := range config.Instances {
-		d.store.idToDigest[check.BuildID(config.Name, instance, config.InitConfig)] = digest
-	}
+	d.store.registerConfig(config)
+	d.store.removeUnscheduled(digest)
 
 	// No target node specified: store in danglingConfigs
 	if targetNodeName == "" {
-		danglingConfigs.Inc(le.JoinLeaderValue)
+		if !d.store.dryRun {
+			danglingConfigs.Inc(le.JoinLeaderValue)
+		}
 		d.store.danglingConfigs[digest] = config
 		return
 	}
@@ -68,6 +77,7 @@ func (d *dispatcher) addConfig(config integration.Config, targetNodeName string)
 	targetNode.addConfig(config)
 	targetNode.Unlock()
 	d.store.digestToNode[digest] = targetNodeName
+	d.store.observeScheduled(digest)
 
 	// Remove config from previous node if found
 	// We double-check the config actually changed nodes, to
@@ -88,6 +98,8 @@ func (d *dispatcher) removeConfig(digest string) {
 	delete(d.store.digestToNode, digest)
 	delete(d.store.digestToConfig, digest)
 	delete(d.store.danglingConfigs, digest)
+	delete(d.store.pendingSince, digest)
+	d.store.removeUnscheduled(digest)
 
 	for k, v := range d.store.idToDigest {
 		if v == digest {
@@ -124,7 +136,9 @@ func (d *dispatcher) retrieveAndClearDangling() []integration.Config {
 	defer d.store.Unlock()
 	configs := makeConfigArray(d.store.danglingConfigs)
 	d.store.clearDangling()
-	danglingConfigs.Set(0, le.JoinLeaderValue)
+	if !d.store.dryRun {
+		danglingConfigs.Set(0, le.JoinLeaderValue)
+	}
 	return configs
 }
 
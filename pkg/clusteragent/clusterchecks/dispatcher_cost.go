@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// costFunction returns the weight of a check on the node running it, from
+// its runner stats. The busyness of a node is the sum of the weights of its
+// checks.
+type costFunction func(stats types.CLCRunnerStats) int
+
+// costWeights holds the weights of the runner stats of a check in its cost
+type costWeights struct {
+	executionTime float64
+	metricSamples float64
+	instance      float64
+	configCost    float64
+}
+
+// defaultCostWeights are the weights of the default cost function
+var defaultCostWeights = costWeights{
+	executionTime: checkExecutionTimeWeight,
+	metricSamples: checkMetricSamplesWeight,
+	configCost:    1,
+}
+
+// costWeightsFromConfig returns the weights of the cost function set in the
+// cluster_checks.cost_function configuration
+func costWeightsFromConfig() costWeights {
+	return costWeights{
+		executionTime: config.Datadog.GetFloat64("cluster_checks.cost_function.execution_time_weight"),
+		metricSamples: config.Datadog.GetFloat64("cluster_checks.cost_function.metric_samples_weight"),
+		instance:      config.Datadog.GetFloat64("cluster_checks.cost_function.instance_weight"),
+		configCost:    config.Datadog.GetFloat64("cluster_checks.cost_function.config_cost_weight"),
+	}
+}
+
+// cost is the cost function weighting the average execution time, the metric
+// samples, the instance itself and the cost declared by the configuration of a
+// check. A failing check costs nothing.
+func (w costWeights) cost(s types.CLCRunnerStats) int {
+	if s.LastExecFailed {
+		return 0
+	}
+	return int(w.executionTime*float64(s.AverageExecutionTime) +
+		w.metricSamples*float64(s.MetricSamples) +
+		w.instance +
+		w.configCost*float64(s.ConfigCost))
+}
+
+// costApplied returns whether the nodes are weighted with the cost function,
+// which requires the runner stats collected by the advanced dispatching or
+// reported for the latency aware rebalancing
+func (d *dispatcher) costApplied() bool {
+	return d.advancedDispatching || d.latencyAware
+}
+
+// addConfigCosts keeps the cost declared by a configuration for each of its checks
+func (s *nodeStore) addConfigCosts(config integration.Config) {
+	if config.Cost == 0 {
+		return
+	}
+	for _, instance := range config.Instances {
+		s.checkCosts[string(check.BuildID(config.Name, instance, config.InitConfig))] = config.Cost
+	}
+}
+
+// removeConfigCosts forgets the cost declared by a configuration
+func (s *nodeStore) removeConfigCosts(config integration.Config) {
+	for _, instance := range config.Instances {
+		delete(s.checkCosts, string(check.BuildID(config.Name, instance, config.InitConfig)))
+	}
+}
+
+// applyConfigCosts sets the cost declared by the configurations of the checks
+// of the node in their runner stats
+func (s *nodeStore) applyConfigCosts(checkStats types.CLCRunnersStats) {
+	for id, cost := range s.checkCosts {
+		if stats, found := checkStats[id]; found {
+			stats.ConfigCost = cost
+			checkStats[id] = stats
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestCostFunction(t *testing.T) {
+	stats := types.CLCRunnerStats{AverageExecutionTime: 100, MetricSamples: 50, ConfigCost: 30}
+
+	// The default cost function matches the historical busyness function
+	dispatcher := newDispatcher()
+	assert.Equal(t, 90, busynessFunc(types.CLCRunnerStats{AverageExecutionTime: 100, MetricSamples: 50}))
+	assert.Equal(t, 120, dispatcher.costFunc(stats))
+	assert.Equal(t, 0, dispatcher.costFunc(types.CLCRunnerStats{AverageExecutionTime: 100, LastExecFailed: true}))
+
+	weights := costWeights{executionTime: 1, instance: 10, configCost: 2}
+	assert.Equal(t, 170, weights.cost(stats))
+
+	// The cost declared by the configurations is applied to the stats of their checks
+	dispatcher.costFunc = costWeights{instance: 1, configCost: 1}.cost
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	expensive, expensiveID := generatePinnedIntegration("snmp", "ip_address: 10.1.0.1", "")
+	expensive.Cost = 100
+	cheap, cheapID := generatePinnedIntegration("http_check", "url: http://1", "")
+	dispatcher.add(expensive)
+	dispatcher.add(cheap)
+
+	node := dispatcher.store.nodes["nodeA"]
+	checkStats := types.CLCRunnersStats{
+		expensiveID: {IsClusterCheck: true},
+		cheapID:     {IsClusterCheck: true},
+	}
+	node.applyConfigCosts(checkStats)
+	assert.Equal(t, 100, checkStats[expensiveID].ConfigCost)
+	assert.Equal(t, 0, checkStats[cheapID].ConfigCost)
+	assert.Equal(t, 102, calculateBusyness(checkStats, dispatcher.costFunc))
+
+	for id, stats := range checkStats {
+		node.AddRunnerStats(id, stats)
+	}
+	assert.Equal(t, 102, node.GetWeightedBusyness(dispatcher.costFunc))
+	checkID, weight, err := node.GetMostWeightedClusterCheck(dispatcher.costFunc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expensiveID, checkID)
+	assert.Equal(t, 101, weight)
+
+	// The costs are forgotten with their configuration
+	dispatcher.remove(expensive)
+	assert.Empty(t, node.checkCosts)
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// drainState is the state of the drain of a node
+type drainState struct {
+	drained bool // Whether all the configurations were moved out of the node
+}
+
+// isDraining returns whether a node is draining
+// The store lock must be held by the caller.
+func (s *clusterStore) isDraining(nodeName string) bool {
+	_, found := s.drainingNodes[nodeName]
+	return found
+}
+
+// setDraining marks a node as draining, or not draining anymore. The state is
+// kept by node name, so that a node stays draining when it expires and
+// registers again.
+// The store lock must be held by the caller.
+func (s *clusterStore) setDraining(nodeName string, draining bool) {
+	if !draining {
+		delete(s.drainingNodes, nodeName)
+		return
+	}
+	if _, found := s.drainingNodes[nodeName]; !found {
+		s.drainingNodes[nodeName] = &drainState{}
+	}
+}
+
+// drain marks a node as draining, or not draining anymore. No configuration
+// is dispatched to a draining node, and its configurations are gradually moved
+// to the other nodes by migrateDrainingNodes, but the ones pinned to it. A
+// node which is not reporting anymore can still stop draining.
+func (d *dispatcher) drain(nodeName string, draining bool) (types.DrainResponse, error) {
+	d.store.Lock()
+	_, found := d.store.getNodeStore(nodeName)
+	if nodeName == "" || !found && (draining || !d.store.isDraining(nodeName)) {
+		d.store.Unlock()
+		return types.DrainResponse{}, fmt.Errorf("node %s is not reporting to the cluster-agent", nodeName)
+	}
+	if d.store.isIsolating(nodeName) {
+		d.store.Unlock()
+		return types.DrainResponse{}, fmt.Errorf("node %s is dedicated to an isolated check", nodeName)
+	}
+	d.store.setDraining(nodeName, draining)
+	d.store.Unlock()
+
+	if draining {
+		log.Infof("Draining node %s", nodeName)
+	} else {
+		log.Infof("Stopped draining node %s", nodeName)
+	}
+	if !found {
+		return types.DrainResponse{NodeName: nodeName}, nil
+	}
+	return d.drainStatus(nodeName)
+}
+
+// drainStatus returns the progress of the drain of a node. The configurations
+// of a draining node which is not reporting are dispatched to the other nodes.
+func (d *dispatcher) drainStatus(nodeName string) (types.DrainResponse, error) {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	draining := d.store.isDraining(nodeName)
+	node, found := d.store.getNodeStore(nodeName)
+	if nodeName == "" || !found && !draining {
+		return types.DrainResponse{}, fmt.Errorf("node %s is not reporting to the cluster-agent", nodeName)
+	}
+	remaining := 0
+	if found {
+		remaining = len(d.drainableConfigs(node))
+	}
+	return types.DrainResponse{
+		NodeName:  nodeName,
+		Draining:  draining,
+		Remaining: remaining,
+		Complete:  draining && remaining == 0,
+	}, nil
+}
+
+// drainableConfigs returns the configurations moved out of a node when it is
+// drained: all of them but the ones pinned to it.
+// The store lock must be held by the caller.
+func (d *dispatcher) drainableConfigs(node *nodeStore) []integration.Config {
+	node.RLock()
+	defer node.RUnlock()
+
+	configs := []integration.Config{}
+	for digest, config := range node.digestToConfig {
+		if d.store.pinnedNode(digest, config) != node.name {
+			configs = append(configs, config)
+		}
+	}
+	return configs
+}
+
+// migrateDrainingNodes moves up to drainBatchSize configurations out of each
+// draining node, to the nodes they would be dispatched to.
+func (d *dispatcher) migrateDrainingNodes() {
+	d.store.RLock()
+	toMove := make(map[string][]integration.Config)
+	for nodeName := range d.store.drainingNodes {
+		node, found := d.store.getNodeStore(nodeName)
+		if !found {
+			continue
+		}
+		configs := d.drainableConfigs(node)
+		if d.drainBatchSize > 0 && len(configs) > d.drainBatchSize {
+			configs = configs[:d.drainBatchSize]
+		}
+		toMove[nodeName] = configs
+	}
+	d.store.RUnlock()
+
+	for nodeName, configs := range toMove {
+		for _, config := range configs {
+			if !d.isDispatchedTo(config.Digest(), nodeName) {
+				// Removed or moved since listed
+				continue
+			}
+			target := d.getNodeToDispatch(config)
+			if target == "" {
+				log.Warnf("No available node to move %s:%s out of draining node %s, will retry later", config.Name, config.Digest(), nodeName)
+				break
+			}
+			log.Infof("Moving configuration %s:%s out of draining node %s to node %s", config.Name, config.Digest(), nodeName, target)
+			d.addConfig(config, target)
+		}
+	}
+
+	d.store.Lock()
+	defer d.store.Unlock()
+	for nodeName, state := range d.store.drainingNodes {
+		node, found := d.store.getNodeStore(nodeName)
+		drained := found && len(d.drainableConfigs(node)) == 0
+		if drained && !state.drained {
+			log.Infof("Node %s is drained", nodeName)
+		}
+		state.drained = drained
+	}
+}
+
+// isDispatchedTo returns whether a configuration is dispatched to a node
+func (d *dispatcher) isDispatchedTo(digest, nodeName string) bool {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	return d.store.digestToNode[digest] == nodeName
+}
+
+// filterDraining removes the draining nodes from the destination nodes of the
+// rebalancing.
+func (d *dispatcher) filterDraining(diffMap map[string]int) map[string]int {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	filtered := make(map[string]int, len(diffMap))
+	for nodeName, diff := range diffMap {
+		if d.store.isDraining(nodeName) {
+			continue
+		}
+		filtered[nodeName] = diff
+	}
+	return filtered
+}
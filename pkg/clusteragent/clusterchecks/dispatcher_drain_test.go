@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestDrain(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.drainBatchSize = 2
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	for i := 0; i < 3; i++ {
+		config, _ := generatePinnedIntegration("http_check", fmt.Sprintf("url: http://%d", i), "")
+		dispatcher.addConfig(config, "nodeA")
+	}
+	pinned, _ := generatePinnedIntegration("pinned", "url: http://pinned", "nodeA")
+	dispatcher.addConfig(pinned, "nodeA")
+
+	_, err := dispatcher.drain("nodeC", true)
+	assert.Error(t, err)
+
+	status, err := dispatcher.drain("nodeA", true)
+	require.NoError(t, err)
+	assert.Equal(t, types.DrainResponse{NodeName: "nodeA", Draining: true, Remaining: 3}, status)
+
+	// No config is dispatched to a draining node, even the least busy one
+	dispatcher.drain("nodeB", true)
+	assert.Equal(t, "", dispatcher.getLeastBusyNode())
+	dispatcher.drain("nodeB", false)
+	assert.Equal(t, "nodeB", dispatcher.getLeastBusyNode())
+
+	// Configs are moved by batches, but the pinned ones
+	dispatcher.migrateDrainingNodes()
+	status, _ = dispatcher.drainStatus("nodeA")
+	assert.Equal(t, 1, status.Remaining)
+	assert.False(t, status.Complete)
+
+	dispatcher.migrateDrainingNodes()
+	status, _ = dispatcher.drainStatus("nodeA")
+	assert.Equal(t, types.DrainResponse{NodeName: "nodeA", Draining: true, Remaining: 0, Complete: true}, status)
+	assert.Equal(t, "nodeA", dispatcher.store.digestToNode[pinned.Digest()])
+	assert.Len(t, dispatcher.store.nodes["nodeB"].digestToConfig, 3)
+
+	// Draining nodes are not rebalancing destinations
+	filtered := dispatcher.filterDraining(map[string]int{"nodeA": -10, "nodeB": 10})
+	assert.Equal(t, map[string]int{"nodeB": 10}, filtered)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestDrainExpiredNode(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	config, _ := generatePinnedIntegration("http_check", "url: http://one", "")
+	dispatcher.addConfig(config, "nodeA")
+
+	_, err := dispatcher.drain("nodeA", true)
+	require.NoError(t, err)
+	dispatcher.migrateDrainingNodes()
+	assert.True(t, dispatcher.store.drainingNodes["nodeA"].drained)
+
+	// The node is still draining once it expired and registered again
+	dispatcher.store.nodes["nodeA"].heartbeat = 0
+	dispatcher.expireNodes()
+	status, err := dispatcher.drainStatus("nodeA")
+	require.NoError(t, err)
+	assert.Equal(t, types.DrainResponse{NodeName: "nodeA", Draining: true, Remaining: 0, Complete: true}, status)
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	assert.Equal(t, "nodeB", dispatcher.getLeastBusyNode())
+
+	// The drain of a node which is not reporting can be stopped
+	delete(dispatcher.store.nodes, "nodeA")
+	_, err = dispatcher.drain("nodeA", false)
+	require.NoError(t, err)
+	_, err = dispatcher.drainStatus("nodeA")
+	assert.Error(t, err)
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+// dryRun returns the placement the dispatcher would produce if the given nodes
+// were added and removed, including the rebalancing moves if requested. It is
+// computed on a copy of the store, nothing is applied.
+func (d *dispatcher) dryRun(request types.DryRunRequest) (types.DryRunResponse, error) {
+	sim := d.copyForDryRun()
+
+	sim.store.Lock()
+	for _, node := range request.AddNodes {
+		if node.Name == "" {
+			sim.store.Unlock()
+			return types.DryRunResponse{}, fmt.Errorf("the name of the added nodes is required")
+		}
+		nodeStore := sim.store.getOrCreateNodeStore(node.Name, "")
+		nodeStore.heartbeat = timestampNow()
+		nodeStore.lastStatus = types.NodeStatus{Capacity: node.Capacity}
+		nodeStore.lastStatus.Labels = map[string]string{}
+		if sim.zoneLabel != "" && node.Zone != "" {
+			nodeStore.lastStatus.Labels[sim.zoneLabel] = node.Zone
+		}
+		if sim.poolLabel != "" && node.Pool != "" {
+			nodeStore.lastStatus.Labels[sim.poolLabel] = node.Pool
+		}
+	}
+	for _, nodeName := range request.RemoveNodes {
+		nodeStore, found := sim.store.getNodeStore(nodeName)
+		if !found || nodeName == "" {
+			sim.store.Unlock()
+			return types.DryRunResponse{}, fmt.Errorf("node %s is not reporting to the cluster-agent", nodeName)
+		}
+		// The configurations of the removed nodes are re-dispatched like the
+		// ones of the expired nodes
+		for digest, config := range nodeStore.digestToConfig {
+			delete(sim.store.digestToNode, digest)
+			sim.store.danglingConfigs[digest] = config
+		}
+		delete(sim.store.nodes, nodeName)
+	}
+	sim.store.Unlock()
+
+	for _, config := range sim.retrieveAndClearDangling() {
+		sim.addConfig(config, sim.getNodeToDispatch(config))
+	}
+
+	response := types.DryRunResponse{}
+	if request.Rebalance {
+		response.Rebalancing = sim.rebalance()
+	}
+
+	state, err := sim.getState()
+	if err != nil {
+		return response, err
+	}
+	response.Nodes = state.Nodes
+	sort.Slice(response.Nodes, func(i, j int) bool { return response.Nodes[i].Name < response.Nodes[j].Name })
+	response.Dangling = state.Dangling
+	response.Moves = d.diffPlacement(sim)
+	return response, nil
+}
+
+// diffPlacement returns the configurations dispatched to a different node in
+// the dry-run dispatcher than in the dispatcher
+func (d *dispatcher) diffPlacement(sim *dispatcher) []types.DryRunMove {
+	d.store.RLock()
+	defer d.store.RUnlock()
+	sim.store.RLock()
+	defer sim.store.RUnlock()
+
+	moves := []types.DryRunMove{}
+	for digest, config := range sim.store.digestToConfig {
+		source, dest := d.store.digestToNode[digest], sim.store.digestToNode[digest]
+		if source == dest {
+			continue
+		}
+		moves = append(moves, types.DryRunMove{
+			Name:           config.Name,
+			Digest:         digest,
+			SourceNodeName: source,
+			DestNodeName:   dest,
+		})
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].Digest < moves[j].Digest })
+	return moves
+}
+
+// copyForDryRun returns a dispatcher with the same settings and a copy of the
+// store, not reporting metrics nor collecting the runner stats
+func (d *dispatcher) copyForDryRun() *dispatcher {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	store := newClusterStore()
+	store.active = d.store.active
+	store.dryRun = true
+	copyConfigMap(store.digestToConfig, d.store.digestToConfig)
+	copyConfigMap(store.danglingConfigs, d.store.danglingConfigs)
+	copyConfigMap(store.unscheduled, d.store.unscheduled)
+	for digest, nodeName := range d.store.digestToNode {
+		store.digestToNode[digest] = nodeName
+	}
+	for id, digest := range d.store.idToDigest {
+		store.idToDigest[id] = digest
+	}
+	for digest, nodeName := range d.store.pinnedNodes {
+		store.pinnedNodes[digest] = nodeName
+	}
+	for digest, nodeName := range d.store.restoredNodes {
+		store.restoredNodes[digest] = nodeName
+	}
+	for nodeName, node := range d.store.nodes {
+		store.nodes[nodeName] = node.copyForDryRun()
+	}
+	for nodeName, state := range d.store.drainingNodes {
+		drain := *state
+		store.drainingNodes[nodeName] = &drain
+	}
+	for nodeName, excludedUntil := range d.store.excludedNodes {
+		store.excludedNodes[nodeName] = excludedUntil
+	}
+	for checkID, movedAt := range d.store.movedChecks {
+		store.movedChecks[checkID] = movedAt
+	}
+
+	return &dispatcher{
+		store:                 store,
+		nodeExpirationSeconds: d.nodeExpirationSeconds,
+		extraTags:             d.extraTags,
+		advancedDispatching:   d.advancedDispatching,
+		latencyAware:          d.latencyAware,
+		zoneLabel:             d.zoneLabel,
+		poolLabel:             d.poolLabel,
+		checkPools:            d.checkPools,
+		drainBatchSize:        d.drainBatchSize,
+		maxConfigsPerRunner:   d.maxConfigsPerRunner,
+		maxWorkersPerRunner:   d.maxWorkersPerRunner,
+		canaryCount:           d.canaryCount,
+		canarySuccessfulRuns:  d.canarySuccessfulRuns,
+		costFunc:              d.costFunc,
+		extender:              d.extender,
+		extenderIgnorable:     d.extenderIgnorable,
+		exclusionSeconds:      d.exclusionSeconds,
+		maxFailureRate:        d.maxFailureRate,
+		maxFlaps:              d.maxFlaps,
+		flapWindowSeconds:     d.flapWindowSeconds,
+		minImbalancePercent:   d.minImbalancePercent,
+		maxRebalanceMoves:     d.maxRebalanceMoves,
+		moveCooldownSeconds:   d.moveCooldownSeconds,
+		federationEnabled:     d.federationEnabled,
+		localCluster:          d.localCluster,
+		federatedChecks:       d.federatedChecks,
+	}
+}
+
+// copyForDryRun returns a copy of the node store, not reporting metrics
+// The nodeStore handles thread safety for this method
+func (s *nodeStore) copyForDryRun() *nodeStore {
+	s.RLock()
+	defer s.RUnlock()
+
+	node := newNodeStore(s.name, s.clientIP)
+	node.heartbeat = s.heartbeat
+	node.lastStatus = s.lastStatus
+	node.lastConfigChange = s.lastConfigChange
+	node.busyness = s.busyness
+	node.dryRun = true
+	copyConfigMap(node.digestToConfig, s.digestToConfig)
+	for id, stats := range s.clcRunnerStats {
+		node.clcRunnerStats[id] = stats
+	}
+	for id, cost := range s.checkCosts {
+		node.checkCosts[id] = cost
+	}
+	for id, interval := range s.checkIntervals {
+		node.checkIntervals[id] = interval
+	}
+	return node
+}
+
+func copyConfigMap(dest, src map[string]integration.Config) {
+	for digest, config := range src {
+		dest[digest] = config
+	}
+}
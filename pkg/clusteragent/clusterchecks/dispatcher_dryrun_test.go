@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestDryRun(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	for i := 0; i < 4; i++ {
+		config, _ := generatePinnedIntegration("http_check", fmt.Sprintf("url: http://%d", i), "")
+		dispatcher.addConfig(config, "nodeA")
+	}
+	pinned, _ := generatePinnedIntegration("pinned", "url: http://pinned", "nodeA")
+	dispatcher.addConfig(pinned, "nodeA")
+
+	// Unknown nodes cannot be removed
+	_, err := dispatcher.dryRun(types.DryRunRequest{RemoveNodes: []string{"nodeC"}})
+	assert.Error(t, err)
+
+	// Removing nodeA moves its configs to the other nodes, but the pinned one
+	response, err := dispatcher.dryRun(types.DryRunRequest{
+		AddNodes:    []types.DryRunNode{{Name: "nodeC"}},
+		RemoveNodes: []string{"nodeA"},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Nodes, 2)
+	assert.Equal(t, "nodeB", response.Nodes[0].Name)
+	assert.Equal(t, "nodeC", response.Nodes[1].Name)
+	assert.Len(t, response.Nodes[0].Configs, 2)
+	assert.Len(t, response.Nodes[1].Configs, 2)
+	require.Len(t, response.Dangling, 1)
+	assert.Equal(t, "pinned", response.Dangling[0].Name)
+	assert.Len(t, response.Moves, 5)
+	for _, move := range response.Moves {
+		assert.Equal(t, "nodeA", move.SourceNodeName)
+		if move.Name == "pinned" {
+			assert.Equal(t, "", move.DestNodeName)
+		}
+	}
+
+	// Nothing is applied
+	assert.Len(t, dispatcher.store.nodes, 2)
+	assert.Len(t, dispatcher.store.nodes["nodeA"].digestToConfig, 5)
+	assert.Empty(t, dispatcher.store.danglingConfigs)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestDryRunLatencyAware(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.latencyAware = true
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	dispatcher.addConfig(generateIntegration("A"), "nodeB")
+	dispatcher.store.nodes["nodeA"].busyness = 100
+	dispatcher.store.nodes["nodeB"].busyness = 10
+
+	// The copy dispatches on the busyness like the dispatcher, not on the config count
+	dryRun := dispatcher.copyForDryRun()
+	assert.True(t, dryRun.latencyAware)
+	dryRun.store.RLock()
+	assert.Equal(t, "nodeB", dryRun.leastBusyNode(dryRun.store.nodes))
+	dryRun.store.RUnlock()
+
+	requireNotLocked(t, dispatcher.store)
+}
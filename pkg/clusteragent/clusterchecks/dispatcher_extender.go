@@ -0,0 +1,302 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// PlacementExtender lets an external system veto or reorder the candidate
+// nodes of the cluster checks, like the Kubernetes scheduler extenders
+type PlacementExtender interface {
+	// Filter returns the names of the nodes allowed to run a configuration
+	// among the candidate ones, by order of preference
+	Filter(args types.ExtenderArgs) (types.ExtenderResult, error)
+}
+
+// registeredExtender is the placement extender used when no webhook is configured
+var registeredExtender PlacementExtender
+
+// RegisterPlacementExtender sets the placement extender consulted by the
+// dispatcher when the cluster_checks.extender.url webhook is not configured.
+// It must be called before the cluster checks handler is created.
+func RegisterPlacementExtender(extender PlacementExtender) {
+	registeredExtender = extender
+}
+
+// errExtenderUnavailable is returned without calling the webhook while its
+// circuit breaker is open
+var errExtenderUnavailable = errors.New("the placement extender failed repeatedly, not calling it until the cooldown elapses")
+
+// webhookExtender is a placement extender calling a webhook. After
+// failureThreshold consecutive failures, the webhook is not called for
+// failureCooldown, so that the dispatching is not slowed down by a timeout on
+// every placement.
+type webhookExtender struct {
+	url              string
+	authToken        string
+	client           *http.Client
+	failureThreshold int
+	failureCooldown  time.Duration
+	m                sync.Mutex // Below fields protected by the mutex
+	failures         int
+	openUntil        time.Time
+}
+
+// newExtenderFromConfig returns the placement extender set in the
+// cluster_checks.extender configuration, or the registered one
+func newExtenderFromConfig() PlacementExtender {
+	url := config.Datadog.GetString("cluster_checks.extender.url")
+	if url == "" {
+		return registeredExtender
+	}
+
+	tlsConfig, err := extenderTLSConfig()
+	if err != nil {
+		log.Errorf("Cannot configure the TLS client of the placement extender, it will be disabled: %v", err)
+		return nil
+	}
+	return &webhookExtender{
+		url:       url,
+		authToken: config.Datadog.GetString("cluster_checks.extender.auth_token"),
+		client: &http.Client{
+			Timeout:   time.Duration(config.Datadog.GetInt("cluster_checks.extender.timeout")) * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		failureThreshold: config.Datadog.GetInt("cluster_checks.extender.failure_threshold"),
+		failureCooldown:  time.Duration(config.Datadog.GetInt("cluster_checks.extender.failure_cooldown")) * time.Second,
+	}
+}
+
+// extenderTLSConfig returns the TLS configuration of the webhook client: the
+// CA verifying the webhook and the client certificate, if set
+func extenderTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if caFile := config.Datadog.GetString("cluster_checks.extender.tls_ca_file"); caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+	}
+
+	certFile := config.Datadog.GetString("cluster_checks.extender.tls_cert_file")
+	keyFile := config.Datadog.GetString("cluster_checks.extender.tls_key_file")
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Filter implements the PlacementExtender interface by posting the candidate
+// nodes to the webhook, unless its circuit breaker is open
+func (e *webhookExtender) Filter(args types.ExtenderArgs) (types.ExtenderResult, error) {
+	if e.isOpen() {
+		return types.ExtenderResult{}, errExtenderUnavailable
+	}
+
+	result, err := e.post(args)
+	e.recordResult(err)
+	return result, err
+}
+
+// isOpen returns whether the webhook is not called as it failed repeatedly
+func (e *webhookExtender) isOpen() bool {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return time.Now().Before(e.openUntil)
+}
+
+// recordResult counts the consecutive failures of the webhook, and opens the
+// circuit breaker once they reach the threshold
+func (e *webhookExtender) recordResult(err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if err == nil {
+		e.failures = 0
+		return
+	}
+	e.failures++
+	if e.failureThreshold > 0 && e.failures >= e.failureThreshold {
+		log.Warnf("The placement extender failed %d times in a row, not calling it for %s: %v", e.failures, e.failureCooldown, err)
+		e.failures = 0
+		e.openUntil = time.Now().Add(e.failureCooldown)
+	}
+}
+
+// post posts the candidate nodes to the webhook and decodes its answer
+func (e *webhookExtender) post(args types.ExtenderArgs) (types.ExtenderResult, error) {
+	var result types.ExtenderResult
+
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return result, err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(payload))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.authToken)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("unexpected status code %d from the placement extender", resp.StatusCode)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, err
+	}
+	if result.Error != "" {
+		return result, errors.New(result.Error)
+	}
+	return result, nil
+}
+
+// extenderNodes returns the nodes a configuration can be dispatched to among
+// the given ones, for the placement extender: the preferred node first, then
+// the other ones by name.
+// The store lock must be held by the caller.
+func (d *dispatcher) extenderNodes(nodes map[string]*nodeStore, preferred string) []types.ExtenderNode {
+	candidates := []types.ExtenderNode{}
+	for name, node := range nodes {
+		if !d.isAvailable(name, node) {
+			continue
+		}
+		node.RLock()
+		candidates = append(candidates, types.ExtenderNode{
+			Name:     name,
+			Labels:   node.lastStatus.Labels,
+			Capacity: node.lastStatus.Capacity,
+			Busyness: node.busyness,
+			Configs:  len(node.digestToConfig),
+		})
+		node.RUnlock()
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if (candidates[i].Name == preferred) != (candidates[j].Name == preferred) {
+			return candidates[i].Name == preferred
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates
+}
+
+// extenderArgs returns the request sent to the placement extender for a
+// configuration
+func extenderArgs(config integration.Config, candidates []types.ExtenderNode) types.ExtenderArgs {
+	return types.ExtenderArgs{
+		Config: types.ExtenderConfig{
+			Name:       config.Name,
+			Digest:     config.Digest(),
+			Source:     config.Source,
+			ServiceID:  config.ServiceID,
+			RunnerPool: config.RunnerPool,
+		},
+		Nodes: candidates,
+	}
+}
+
+// extendPlacement asks the placement extender to filter and order the
+// candidate nodes of a configuration, and returns the first one it allows,
+// or an empty string if it vetoes all of them. When the extender fails, the
+// node preferred by the dispatcher is returned if the extender is ignorable.
+func (d *dispatcher) extendPlacement(config integration.Config, preferred string, candidates []types.ExtenderNode) string {
+	if len(candidates) == 0 {
+		return preferred
+	}
+
+	result, err := d.extender.Filter(extenderArgs(config, candidates))
+	if err != nil {
+		if d.extenderIgnorable {
+			log.Warnf("Cannot consult the placement extender for %s:%s, dispatching it to node %s: %v", config.Name, config.Digest(), preferred, err)
+			return preferred
+		}
+		log.Warnf("Cannot consult the placement extender for %s:%s, will retry later: %v", config.Name, config.Digest(), err)
+		return ""
+	}
+
+	allowed := make(map[string]struct{}, len(candidates))
+	for _, node := range candidates {
+		allowed[node.Name] = struct{}{}
+	}
+	for _, name := range result.Nodes {
+		if _, found := allowed[name]; found {
+			return name
+		}
+	}
+	log.Infof("The placement extender vetoed all the nodes for %s:%s, will retry later", config.Name, config.Digest())
+	return ""
+}
+
+// filterByExtender removes the nodes vetoed by the placement extender from the
+// destination nodes of the rebalancing of a check.
+func (d *dispatcher) filterByExtender(diffMap map[string]int, checkID string) map[string]int {
+	if d.extender == nil || len(diffMap) == 0 {
+		return diffMap
+	}
+	config, _ := d.getConfigAndDigest(checkID)
+
+	d.store.RLock()
+	nodes := make(map[string]*nodeStore, len(diffMap))
+	for nodeName := range diffMap {
+		if node, found := d.store.getNodeStore(nodeName); found {
+			nodes[nodeName] = node
+		}
+	}
+	candidates := d.extenderNodes(nodes, "")
+	d.store.RUnlock()
+
+	result, err := d.extender.Filter(extenderArgs(config, candidates))
+	if err != nil {
+		if d.extenderIgnorable {
+			log.Debugf("Cannot consult the placement extender for check %s, ignoring it: %v", checkID, err)
+			return diffMap
+		}
+		log.Debugf("Cannot consult the placement extender for check %s, it will not move: %v", checkID, err)
+		return map[string]int{}
+	}
+
+	filtered := make(map[string]int, len(result.Nodes))
+	for _, nodeName := range result.Nodes {
+		if diff, found := diffMap[nodeName]; found {
+			filtered[nodeName] = diff
+		}
+	}
+	return filtered
+}
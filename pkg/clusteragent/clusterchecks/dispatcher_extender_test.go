@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+type fakeExtender struct {
+	nodes []string
+	err   error
+	args  []types.ExtenderArgs
+}
+
+func (e *fakeExtender) Filter(args types.ExtenderArgs) (types.ExtenderResult, error) {
+	e.args = append(e.args, args)
+	return types.ExtenderResult{Nodes: e.nodes}, e.err
+}
+
+func TestPlacementExtender(t *testing.T) {
+	extender := &fakeExtender{}
+	dispatcher := newDispatcher()
+	dispatcher.extender = extender
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{Labels: map[string]string{"team": "network"}})
+	busy, _ := generatePinnedIntegration("http_check", "url: http://0", "nodeB")
+	dispatcher.add(busy)
+
+	// The extender reorders the candidate nodes, the dispatcher's choice first
+	extender.nodes = []string{"unknown", "nodeB", "nodeA"}
+	config, _ := generatePinnedIntegration("http_check", "url: http://1", "")
+	dispatcher.add(config)
+	assert.Contains(t, dispatcher.store.nodes["nodeB"].digestToConfig, config.Digest())
+	require.Len(t, extender.args, 1)
+	assert.Equal(t, config.Digest(), extender.args[0].Config.Digest)
+	require.Len(t, extender.args[0].Nodes, 2)
+	assert.Equal(t, "nodeA", extender.args[0].Nodes[0].Name)
+	assert.Equal(t, "network", extender.args[0].Nodes[1].Labels["team"])
+
+	// Configurations whose nodes are all vetoed are kept dangling
+	extender.nodes = []string{}
+	vetoed, _ := generatePinnedIntegration("http_check", "url: http://2", "")
+	dispatcher.add(vetoed)
+	assert.Contains(t, dispatcher.store.danglingConfigs, vetoed.Digest())
+
+	// The dispatcher's choice is kept when an ignorable extender fails
+	extender.err = errors.New("unavailable")
+	dispatcher.extenderIgnorable = true
+	ignored, _ := generatePinnedIntegration("http_check", "url: http://3", "")
+	dispatcher.add(ignored)
+	assert.Contains(t, dispatcher.store.nodes["nodeA"].digestToConfig, ignored.Digest())
+	dispatcher.extenderIgnorable = false
+	failed, _ := generatePinnedIntegration("http_check", "url: http://4", "")
+	dispatcher.add(failed)
+	assert.Contains(t, dispatcher.store.danglingConfigs, failed.Digest())
+
+	// Pinned configurations are not submitted to the extender
+	extender.args = nil
+	pinned, _ := generatePinnedIntegration("http_check", "url: http://5", "nodeA")
+	dispatcher.add(pinned)
+	assert.Contains(t, dispatcher.store.nodes["nodeA"].digestToConfig, pinned.Digest())
+	assert.Empty(t, extender.args)
+
+	// The vetoed nodes are not rebalancing destinations
+	extender.err = nil
+	extender.nodes = []string{"nodeB"}
+	_, checkID := generatePinnedIntegration("http_check", "url: http://1", "")
+	assert.Equal(t, map[string]int{"nodeB": 5}, dispatcher.filterByExtender(map[string]int{"nodeA": 10, "nodeB": 5}, checkID))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestWebhookExtender(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args types.ExtenderArgs
+		if err := json.NewDecoder(r.Body).Decode(&args); err != nil || len(args.Nodes) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if args.Config.Name == "vetoed" {
+			json.NewEncoder(w).Encode(types.ExtenderResult{Error: "vetoed by policy"})
+			return
+		}
+		json.NewEncoder(w).Encode(types.ExtenderResult{Nodes: []string{args.Nodes[len(args.Nodes)-1].Name}})
+	}))
+	defer server.Close()
+
+	extender := &webhookExtender{url: server.URL, client: server.Client()}
+	result, err := extender.Filter(types.ExtenderArgs{Nodes: []types.ExtenderNode{{Name: "nodeA"}, {Name: "nodeB"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nodeB"}, result.Nodes)
+
+	_, err = extender.Filter(types.ExtenderArgs{Config: types.ExtenderConfig{Name: "vetoed"}, Nodes: []types.ExtenderNode{{Name: "nodeA"}}})
+	assert.EqualError(t, err, "vetoed by policy")
+
+	_, err = extender.Filter(types.ExtenderArgs{})
+	assert.Error(t, err)
+}
+
+func TestWebhookExtenderCircuitBreaker(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	extender := &webhookExtender{url: server.URL, client: server.Client(), failureThreshold: 2, failureCooldown: time.Hour}
+	args := types.ExtenderArgs{Nodes: []types.ExtenderNode{{Name: "nodeA"}}}
+
+	// The webhook is not called anymore once it failed repeatedly
+	for i := 0; i < 4; i++ {
+		_, err := extender.Filter(args)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, calls)
+	_, err := extender.Filter(args)
+	assert.Equal(t, errExtenderUnavailable, err)
+
+	// It is called again once the cooldown elapsed
+	extender.m.Lock()
+	extender.openUntil = time.Now()
+	extender.m.Unlock()
+	extender.Filter(args) //nolint:errcheck
+	assert.Equal(t, 3, calls)
+}
+
+func TestWebhookExtenderTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(types.ExtenderResult{Nodes: []string{"nodeA"}})
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, ca, 0644))
+
+	mockConfig := config.Mock()
+	mockConfig.Set("cluster_checks.extender.url", server.URL)
+	mockConfig.Set("cluster_checks.extender.tls_ca_file", caFile)
+	mockConfig.Set("cluster_checks.extender.auth_token", "secret")
+	defer mockConfig.Set("cluster_checks.extender.url", "")
+
+	extender := newExtenderFromConfig()
+	require.NotNil(t, extender)
+	result, err := extender.Filter(types.ExtenderArgs{Nodes: []types.ExtenderNode{{Name: "nodeA"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nodeA"}, result.Nodes)
+
+	// The extender is disabled when its CA cannot be loaded
+	mockConfig.Set("cluster_checks.extender.tls_ca_file", filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Nil(t, newExtenderFromConfig())
+}
@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+// remoteNodeSeparator separates the cluster and the name of the runners of
+// the other clusters in the names of their nodes
+const remoteNodeSeparator = "/"
+
+// cluster returns the Kubernetes cluster reported by the node
+// The nodeStore handles thread safety for this method
+func (s *nodeStore) cluster() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastStatus.Cluster
+}
+
+// remoteCluster returns the cluster of a runner registered from another
+// cluster with the federation, or an empty string for the local runners.
+// The runners not reporting their cluster are local.
+func (d *dispatcher) remoteCluster(node *nodeStore) string {
+	if !d.federationEnabled {
+		return ""
+	}
+	if cluster := node.cluster(); cluster != "" && cluster != d.localCluster {
+		return cluster
+	}
+	return ""
+}
+
+// nodeKey returns the name of the node of a runner in the store: the runners of
+// the other clusters are prefixed with their cluster, so that they do not
+// collide with the runners of the same name in the other clusters.
+func (d *dispatcher) nodeKey(nodeName, cluster string) string {
+	if !d.federationEnabled || cluster == "" || cluster == d.localCluster {
+		return nodeName
+	}
+	return cluster + remoteNodeSeparator + nodeName
+}
+
+// isFederated returns whether a configuration can be dispatched to the runners
+// of the other clusters
+func (d *dispatcher) isFederated(config integration.Config) bool {
+	_, found := d.federatedChecks[config.Name]
+	return found
+}
+
+// federationNodes returns the nodes a configuration can be dispatched to among
+// the given ones: the runners of the other clusters only run the federated
+// configurations, which can run on any runner.
+// The store lock must be held by the caller.
+func (d *dispatcher) federationNodes(config integration.Config, nodes map[string]*nodeStore) map[string]*nodeStore {
+	if !d.federationEnabled || d.isFederated(config) {
+		return nodes
+	}
+
+	local := make(map[string]*nodeStore, len(nodes))
+	for name, node := range nodes {
+		if d.remoteCluster(node) == "" {
+			local[name] = node
+		}
+	}
+	return local
+}
+
+// filterByFederation restricts the destination nodes of a check being
+// rebalanced to the local nodes, unless its configuration is federated.
+func (d *dispatcher) filterByFederation(diffMap map[string]int, checkID string) map[string]int {
+	if !d.federationEnabled {
+		return diffMap
+	}
+	config, _ := d.getConfigAndDigest(checkID)
+	if d.isFederated(config) {
+		return diffMap
+	}
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	filtered := make(map[string]int, len(diffMap))
+	for nodeName, diff := range diffMap {
+		if node, found := d.store.getNodeStore(nodeName); found && d.remoteCluster(node) == "" {
+			filtered[nodeName] = diff
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestFederation(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.federationEnabled = true
+	dispatcher.localCluster = "central"
+	dispatcher.federatedChecks = map[string]struct{}{"http_check": {}}
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{Cluster: "central"})
+	dispatcher.processNodeStatus("nodeB", "10.1.0.1", types.NodeStatus{Cluster: "satellite"})
+	dispatcher.processNodeStatus("nodeC", "10.0.0.2", types.NodeStatus{})
+
+	// The runners of the other clusters only run the federated configurations
+	for _, instance := range []string{"ip_address: 10.2.0.1", "ip_address: 10.2.0.2", "ip_address: 10.2.0.3"} {
+		config, _ := generatePinnedIntegration("snmp", instance, "")
+		dispatcher.add(config)
+	}
+	assert.Empty(t, dispatcher.store.nodes["nodeB"].digestToConfig)
+	federated, checkID := generatePinnedIntegration("http_check", "url: http://1", "")
+	dispatcher.add(federated)
+	assert.Contains(t, dispatcher.store.nodes["nodeB"].digestToConfig, federated.Digest())
+
+	// Only the federated checks are rebalanced to them
+	diffMap := map[string]int{"nodeA": 10, "nodeB": -10, "nodeC": 0}
+	assert.Equal(t, diffMap, dispatcher.filterByFederation(diffMap, checkID))
+	_, snmpID := generatePinnedIntegration("snmp", "ip_address: 10.2.0.1", "")
+	assert.Equal(t, map[string]int{"nodeA": 10, "nodeC": 0}, dispatcher.filterByFederation(diffMap, snmpID))
+
+	state, _ := dispatcher.getState()
+	for _, node := range state.Nodes {
+		if node.Name == "nodeB" {
+			assert.Equal(t, "satellite", node.Cluster)
+		} else {
+			assert.Empty(t, node.Cluster)
+		}
+	}
+
+	// Without the federation, all the runners are local
+	dispatcher.federationEnabled = false
+	assert.Equal(t, diffMap, dispatcher.filterByFederation(diffMap, snmpID))
+	assert.Empty(t, dispatcher.remoteCluster(dispatcher.store.nodes["nodeB"]))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestFederationNodeKey(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.federationEnabled = true
+	dispatcher.localCluster = "central"
+	dispatcher.federatedChecks = map[string]struct{}{"http_check": {}}
+
+	// The runners of the other clusters do not collide with the local ones
+	assert.Equal(t, "node1", dispatcher.nodeKey("node1", ""))
+	assert.Equal(t, "node1", dispatcher.nodeKey("node1", "central"))
+	assert.Equal(t, "satellite/node1", dispatcher.nodeKey("node1", "satellite"))
+
+	h := &Handler{dispatcher: dispatcher}
+	_, err := h.PostStatus("node1", "10.0.0.1", types.NodeStatus{Cluster: "central"})
+	assert.NoError(t, err)
+	_, err = h.PostStatus("node1", "10.1.0.1", types.NodeStatus{Cluster: "satellite"})
+	assert.NoError(t, err)
+	assert.Len(t, dispatcher.store.nodes, 2)
+	assert.Equal(t, "10.0.0.1", dispatcher.store.nodes["node1"].clientIP)
+	assert.Equal(t, "satellite", dispatcher.remoteCluster(dispatcher.store.nodes["satellite/node1"]))
+
+	federated, _ := generatePinnedIntegration("http_check", "url: http://1", "satellite/node1")
+	dispatcher.add(federated)
+	response, err := h.GetConfigs("node1", "satellite")
+	assert.NoError(t, err)
+	assert.Len(t, response.Configs, 1)
+	response, err = h.GetConfigs("node1", "central")
+	assert.NoError(t, err)
+	assert.Empty(t, response.Configs)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestFederationMisconfigured(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("cluster_checks.federation.enabled", true)
+	defer mockConfig.Set("cluster_checks.federation.enabled", false)
+
+	// Without the name of the local cluster, every runner would be remote
+	dispatcher := newDispatcher()
+	assert.False(t, dispatcher.federationEnabled)
+
+	mockConfig.Set("cluster_checks.federation.cluster_name", "central")
+	defer mockConfig.Set("cluster_checks.federation.cluster_name", "")
+	dispatcher = newDispatcher()
+	assert.True(t, dispatcher.federationEnabled)
+	assert.Equal(t, "central", dispatcher.localCluster)
+}
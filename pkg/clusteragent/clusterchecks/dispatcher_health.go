@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	le "github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// minChecksForFailureRate is the minimum number of cluster checks with stats
+// on a node to consider its failure rate, so that a single failing check does
+// not exclude its node
+const minChecksForFailureRate = 3
+
+// getEventSender returns the sender of the exclusion events, replaced in tests
+var getEventSender = aggregator.GetDefaultSender
+
+// excludeUnhealthyEnabled returns whether the unhealthy nodes are excluded
+func (d *dispatcher) excludeUnhealthyEnabled() bool {
+	return d.exclusionSeconds > 0
+}
+
+// isExcluded returns whether a node is excluded from the new placements
+// The store lock must be held by the caller.
+func (s *clusterStore) isExcluded(nodeName string) bool {
+	_, found := s.excludedNodes[nodeName]
+	return found
+}
+
+// recordExpiration keeps the expiration of a node to detect its flapping
+// The store lock must be held by the caller.
+func (d *dispatcher) recordExpiration(nodeName string) {
+	if !d.excludeUnhealthyEnabled() {
+		return
+	}
+	d.store.nodeExpirations[nodeName] = append(d.store.nodeExpirations[nodeName], timestampNow())
+}
+
+// failureRate returns the ratio of the cluster checks of the node whose last
+// run failed, 0 if too few of them have stats.
+// The nodeStore handles thread safety for this method
+func (s *nodeStore) failureRate() float64 {
+	s.RLock()
+	defer s.RUnlock()
+
+	checks, failing := 0, 0
+	for _, stats := range s.clcRunnerStats {
+		if !stats.IsClusterCheck {
+			continue
+		}
+		checks++
+		if stats.LastExecFailed {
+			failing++
+		}
+	}
+	if checks < minChecksForFailureRate {
+		return 0
+	}
+	return float64(failing) / float64(checks)
+}
+
+// unhealthyReason returns why a node is unhealthy, or an empty string if it
+// is healthy. Old expirations are forgotten.
+// The store lock must be held by the caller.
+func (d *dispatcher) unhealthyReason(nodeName string, now int64) string {
+	var flaps []int64
+	for _, expiration := range d.store.nodeExpirations[nodeName] {
+		if expiration > now-d.flapWindowSeconds {
+			flaps = append(flaps, expiration)
+		}
+	}
+	if len(flaps) == 0 {
+		delete(d.store.nodeExpirations, nodeName)
+	} else {
+		d.store.nodeExpirations[nodeName] = flaps
+	}
+	if d.maxFlaps > 0 && len(flaps) >= d.maxFlaps {
+		return fmt.Sprintf("the node expired %d times in the last %d seconds", len(flaps), d.flapWindowSeconds)
+	}
+
+	node, found := d.store.getNodeStore(nodeName)
+	if !found {
+		return ""
+	}
+	if rate := node.failureRate(); d.maxFailureRate > 0 && rate >= d.maxFailureRate {
+		return fmt.Sprintf("%.0f%% of its cluster checks are failing", rate*100)
+	}
+	return ""
+}
+
+// admittedNodes returns the number of nodes new configurations can be
+// dispatched to, neither draining nor excluded.
+// The store lock must be held by the caller.
+func (d *dispatcher) admittedNodes() int {
+	admitted := 0
+	for name := range d.store.nodes {
+		if name != "" && !d.store.isDraining(name) && !d.store.isExcluded(name) {
+			admitted++
+		}
+	}
+	return admitted
+}
+
+// processUnhealthyNodes excludes the unhealthy nodes from the new placements
+// for the exclusion duration, and re-admits the excluded nodes that recovered
+// once it elapsed. The last admitted node is never excluded.
+func (d *dispatcher) processUnhealthyNodes() {
+	if !d.excludeUnhealthyEnabled() {
+		return
+	}
+
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	now := timestampNow()
+	names := make(map[string]struct{}, len(d.store.nodes)+len(d.store.excludedNodes))
+	for name := range d.store.nodes {
+		names[name] = struct{}{}
+	}
+	for name := range d.store.excludedNodes {
+		names[name] = struct{}{}
+	}
+	delete(names, "")
+
+	for name := range names {
+		reason := d.unhealthyReason(name, now)
+		excludedUntil, excluded := d.store.excludedNodes[name]
+		switch {
+		case excluded && now < excludedUntil:
+			continue
+		case excluded && reason == "":
+			delete(d.store.excludedNodes, name)
+			log.Infof("Re-admitting node %s to the cluster checks dispatching", name)
+			d.sendExclusionEvent(name, false, "the node recovered")
+		case excluded:
+			d.store.excludedNodes[name] = now + d.exclusionSeconds
+			log.Debugf("Keeping node %s excluded from the cluster checks dispatching: %s", name, reason)
+		case reason == "":
+			continue
+		case d.admittedNodes() <= 1:
+			log.Warnf("Node %s is unhealthy but is the last node available to the cluster checks: %s", name, reason)
+		default:
+			d.store.excludedNodes[name] = now + d.exclusionSeconds
+			log.Warnf("Excluding node %s from the cluster checks dispatching for %d seconds: %s", name, d.exclusionSeconds, reason)
+			d.sendExclusionEvent(name, true, reason)
+		}
+	}
+
+	if !d.store.dryRun {
+		excludedNodes.Set(float64(len(d.store.excludedNodes)), le.JoinLeaderValue)
+	}
+}
+
+// filterExcluded removes the excluded nodes from the destination nodes of the
+// rebalancing.
+func (d *dispatcher) filterExcluded(diffMap map[string]int) map[string]int {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	if len(d.store.excludedNodes) == 0 {
+		return diffMap
+	}
+	filtered := make(map[string]int, len(diffMap))
+	for nodeName, diff := range diffMap {
+		if d.store.isExcluded(nodeName) {
+			continue
+		}
+		filtered[nodeName] = diff
+	}
+	return filtered
+}
+
+// sendExclusionEvent sends an event on the exclusion or re-admission of a node
+func (d *dispatcher) sendExclusionEvent(nodeName string, excluded bool, reason string) {
+	if d.store.dryRun {
+		return
+	}
+	sender, err := getEventSender()
+	if err != nil {
+		log.Debugf("Cannot send the exclusion event of node %s: %v", nodeName, err)
+		return
+	}
+
+	event := metrics.Event{
+		Title:          fmt.Sprintf("Node %s re-admitted to the cluster checks dispatching", nodeName),
+		Text:           fmt.Sprintf("The cluster checks can be dispatched to node %s again: %s.", nodeName, reason),
+		Priority:       metrics.EventPriorityNormal,
+		AlertType:      metrics.EventAlertTypeSuccess,
+		SourceTypeName: "datadog-cluster-agent",
+		EventType:      "cluster_checks",
+		AggregationKey: "cluster_checks_node:" + nodeName,
+		Tags:           append([]string{"node:" + nodeName}, d.extraTags...),
+	}
+	if excluded {
+		event.Title = fmt.Sprintf("Node %s excluded from the cluster checks dispatching", nodeName)
+		event.Text = fmt.Sprintf("No new cluster check is dispatched to node %s for %d seconds: %s.", nodeName, d.exclusionSeconds, reason)
+		event.AlertType = metrics.EventAlertTypeWarning
+	}
+	sender.Event(event)
+}
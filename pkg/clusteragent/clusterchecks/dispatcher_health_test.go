@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestUnhealthyNodes(t *testing.T) {
+	sender := new(mocksender.MockSender)
+	sender.On("Event", mock.AnythingOfType("metrics.Event")).Return()
+	getEventSender = func() (aggregator.Sender, error) { return sender, nil }
+	defer func() { getEventSender = aggregator.GetDefaultSender }()
+
+	dispatcher := newDispatcher()
+	dispatcher.exclusionSeconds = 300
+	dispatcher.maxFailureRate = 0.5
+	dispatcher.maxFlaps = 2
+	dispatcher.flapWindowSeconds = 600
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	// Nodes whose cluster checks are failing are excluded from the new placements
+	for i := 0; i < 3; i++ {
+		dispatcher.store.nodes["nodeA"].AddRunnerStats(fmt.Sprintf("http_check:%d", i), types.CLCRunnerStats{IsClusterCheck: true, LastExecFailed: i > 0})
+	}
+	dispatcher.processUnhealthyNodes()
+	assert.Contains(t, dispatcher.store.excludedNodes, "nodeA")
+	for i := 0; i < 3; i++ {
+		config, _ := generatePinnedIntegration("http_check", fmt.Sprintf("url: http://%d", i), "")
+		dispatcher.add(config)
+	}
+	assert.Empty(t, dispatcher.store.nodes["nodeA"].digestToConfig)
+	assert.Len(t, dispatcher.store.nodes["nodeB"].digestToConfig, 3)
+	assert.Equal(t, map[string]int{"nodeB": 0}, dispatcher.filterExcluded(map[string]int{"nodeA": -10, "nodeB": 0}))
+	sender.AssertCalled(t, "Event", mock.MatchedBy(func(e metrics.Event) bool {
+		return e.AlertType == metrics.EventAlertTypeWarning && e.AggregationKey == "cluster_checks_node:nodeA"
+	}))
+
+	// The last node available is never excluded
+	for i := 0; i < 3; i++ {
+		dispatcher.store.nodes["nodeB"].AddRunnerStats(fmt.Sprintf("http_check:%d", i), types.CLCRunnerStats{IsClusterCheck: true, LastExecFailed: true})
+	}
+	dispatcher.processUnhealthyNodes()
+	assert.NotContains(t, dispatcher.store.excludedNodes, "nodeB")
+	for i := 0; i < 3; i++ {
+		dispatcher.store.nodes["nodeB"].AddRunnerStats(fmt.Sprintf("http_check:%d", i), types.CLCRunnerStats{IsClusterCheck: true})
+	}
+
+	// Excluded nodes stay excluded while unhealthy, and are re-admitted once recovered
+	dispatcher.store.excludedNodes["nodeA"] = timestampNow() - 1
+	dispatcher.processUnhealthyNodes()
+	assert.Greater(t, dispatcher.store.excludedNodes["nodeA"], timestampNow())
+	dispatcher.store.nodes["nodeA"].AddRunnerStats("http_check:1", types.CLCRunnerStats{IsClusterCheck: true})
+	dispatcher.store.nodes["nodeA"].AddRunnerStats("http_check:2", types.CLCRunnerStats{IsClusterCheck: true})
+	dispatcher.store.excludedNodes["nodeA"] = timestampNow() - 1
+	dispatcher.processUnhealthyNodes()
+	assert.NotContains(t, dispatcher.store.excludedNodes, "nodeA")
+	sender.AssertNumberOfCalls(t, "Event", 2)
+
+	// Flapping nodes are excluded too
+	dispatcher.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{})
+	dispatcher.store.nodeExpirations["nodeC"] = []int64{timestampNow() - 1000, timestampNow() - 100}
+	dispatcher.processUnhealthyNodes()
+	assert.NotContains(t, dispatcher.store.excludedNodes, "nodeC")
+	assert.Len(t, dispatcher.store.nodeExpirations["nodeC"], 1)
+	dispatcher.store.Lock()
+	dispatcher.recordExpiration("nodeC")
+	dispatcher.store.Unlock()
+	dispatcher.processUnhealthyNodes()
+	assert.Contains(t, dispatcher.store.excludedNodes, "nodeC")
+
+	state, _ := dispatcher.getState()
+	for _, node := range state.Nodes {
+		assert.Equal(t, node.Name == "nodeC", node.Excluded)
+	}
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+// imbalanceThreshold returns the busyness above the average from which the
+// checks of a node are rebalanced, so that slight oscillations of the
+// busyness values do not move checks back and forth.
+func (d *dispatcher) imbalanceThreshold(avg int) int {
+	if d.minImbalancePercent <= 0 || avg <= 0 {
+		return 0
+	}
+	return int(float64(avg) * d.minImbalancePercent / 100)
+}
+
+// moveBudgetReached returns whether a rebalancing moved as many checks as
+// allowed.
+func (d *dispatcher) moveBudgetReached(moved int) bool {
+	return d.maxRebalanceMoves > 0 && moved >= d.maxRebalanceMoves
+}
+
+// recordMove starts the cool-down of a check moved by the rebalancing
+func (d *dispatcher) recordMove(checkID string) {
+	if d.moveCooldownSeconds <= 0 {
+		return
+	}
+
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	d.store.movedChecks[checkID] = timestampNow()
+}
+
+// forgetMoves ends the cool-down of the checks moved long enough ago
+func (d *dispatcher) forgetMoves() {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	threshold := timestampNow() - d.moveCooldownSeconds
+	for checkID, movedAt := range d.store.movedChecks {
+		if movedAt <= threshold {
+			delete(d.store.movedChecks, checkID)
+		}
+	}
+}
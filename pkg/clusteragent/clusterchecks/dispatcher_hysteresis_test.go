@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestRebalanceHysteresis(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.store.active = true
+	dispatcher.store.nodes["A"] = newNodeStore("A", "")
+	dispatcher.store.nodes["B"] = newNodeStore("B", "")
+	for i := 0; i < 6; i++ {
+		dispatcher.store.nodes["A"].clcRunnerStats[fmt.Sprintf("checkA%d", i)] = types.CLCRunnerStats{AverageExecutionTime: 100, IsClusterCheck: true}
+	}
+	for i := 0; i < 4; i++ {
+		dispatcher.store.nodes["B"].clcRunnerStats[fmt.Sprintf("checkB%d", i)] = types.CLCRunnerStats{AverageExecutionTime: 100, IsClusterCheck: true}
+	}
+
+	// Imbalances below the minimum are tolerated
+	assert.Equal(t, 0, dispatcher.imbalanceThreshold(200))
+	dispatcher.minImbalancePercent = 200
+	assert.Equal(t, 400, dispatcher.imbalanceThreshold(200))
+	assert.Empty(t, dispatcher.rebalance())
+	dispatcher.minImbalancePercent = 0
+
+	// The number of checks moved per rebalancing is limited
+	dispatcher.maxRebalanceMoves = 1
+	dispatcher.moveCooldownSeconds = 3600
+	moves := dispatcher.rebalance()
+	require.Len(t, moves, 1)
+	assert.Equal(t, "A", moves[0].SourceNodeName)
+	assert.Len(t, dispatcher.store.nodes["A"].clcRunnerStats, 5)
+
+	// The moved checks cool down before moving again
+	movedID := moves[0].CheckID
+	assert.Contains(t, dispatcher.store.movedChecks, movedID)
+	for i := 0; i < 10; i++ {
+		checkID, _, err := dispatcher.pickCheckToMove("B")
+		assert.NoError(t, err)
+		assert.NotEqual(t, movedID, checkID)
+	}
+	dispatcher.store.movedChecks[movedID] = timestampNow() - 3600
+	dispatcher.forgetMoves()
+	assert.Empty(t, dispatcher.store.movedChecks)
+
+	requireNotLocked(t, dispatcher.store)
+}
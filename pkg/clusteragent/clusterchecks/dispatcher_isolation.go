@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	le "github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// isolation is the isolation of the configuration of a check on a dedicated
+// node, until a timestamp
+type isolation struct {
+	checkID     string
+	nodeName    string
+	until       int64
+	previousPin string // Node the configuration was pinned to with the API before, if any
+	wasDraining bool   // Whether the node was draining before
+}
+
+// isolatedNode returns the node a configuration is isolated on, or an empty
+// string if it is not isolated
+// The store lock must be held by the caller.
+func (s *clusterStore) isolatedNode(digest string) string {
+	if isolated, found := s.isolations[digest]; found {
+		return isolated.nodeName
+	}
+	return ""
+}
+
+// isolatedNode returns the node a configuration is isolated on, as the store
+// method does
+func (d *dispatcher) isolatedNode(digest string) string {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	return d.store.isolatedNode(digest)
+}
+
+// isIsolating returns whether a node is dedicated to an isolated configuration
+// The store lock must be held by the caller.
+func (s *clusterStore) isIsolating(nodeName string) bool {
+	for _, isolated := range s.isolations {
+		if isolated.nodeName == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// isolate moves the configuration of a check to a dedicated node for a time:
+// the configuration is pinned to the node, which is drained of its other
+// configurations, but the ones pinned to it. If no node is given, the least
+// busy one is dedicated. Isolating an isolated configuration again extends
+// its isolation.
+func (d *dispatcher) isolate(checkID, nodeName string, ttlSeconds int64) (types.IsolateResponse, error) {
+	config, digest := d.getConfigAndDigest(checkID)
+	if digest == "" {
+		return types.IsolateResponse{}, fmt.Errorf("check %s is not dispatched by the cluster-agent", checkID)
+	}
+
+	d.store.Lock()
+	if isolated, found := d.store.isolations[digest]; found {
+		defer d.store.Unlock()
+		if nodeName != "" && nodeName != isolated.nodeName {
+			return types.IsolateResponse{}, fmt.Errorf("check %s is already isolated on node %s", checkID, isolated.nodeName)
+		}
+		isolated.until = timestampNow() + ttlSeconds
+		log.Infof("Extending the isolation of configuration %s:%s on node %s", config.Name, digest, isolated.nodeName)
+		return isolated.response(), nil
+	}
+
+	if nodeName == "" {
+		nodeName = d.leastBusyNode(d.federationNodes(config, d.store.nodes))
+	}
+	if _, found := d.store.getNodeStore(nodeName); !found || nodeName == "" {
+		d.store.Unlock()
+		return types.IsolateResponse{}, fmt.Errorf("node %s is not reporting to the cluster-agent", nodeName)
+	}
+	if d.store.isIsolating(nodeName) {
+		d.store.Unlock()
+		return types.IsolateResponse{}, fmt.Errorf("node %s is already dedicated to an isolated check", nodeName)
+	}
+	if d.admittedNodes() < 2 {
+		d.store.Unlock()
+		return types.IsolateResponse{}, fmt.Errorf("no other node to move the checks of node %s to", nodeName)
+	}
+
+	isolated := &isolation{
+		checkID:     checkID,
+		nodeName:    nodeName,
+		until:       timestampNow() + ttlSeconds,
+		previousPin: d.store.pinnedNodes[digest],
+		wasDraining: d.store.isDraining(nodeName),
+	}
+	d.store.isolations[digest] = isolated
+	d.store.pinnedNodes[digest] = nodeName
+	d.store.setDraining(nodeName, true)
+	if _, found := d.store.danglingConfigs[digest]; found {
+		delete(d.store.danglingConfigs, digest)
+		danglingConfigs.Dec(le.JoinLeaderValue)
+	}
+	d.store.Unlock()
+
+	log.Infof("Isolating configuration %s:%s on node %s for %d seconds", config.Name, digest, nodeName, ttlSeconds)
+	d.addConfig(config, nodeName)
+	return isolated.response(), nil
+}
+
+// releaseCheck ends the isolation of the configuration of a check
+func (d *dispatcher) releaseCheck(checkID string) (types.IsolateResponse, error) {
+	config, digest := d.getConfigAndDigest(checkID)
+	if digest == "" {
+		return types.IsolateResponse{}, fmt.Errorf("check %s is not dispatched by the cluster-agent", checkID)
+	}
+
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	isolated, found := d.store.isolations[digest]
+	if !found {
+		return types.IsolateResponse{}, fmt.Errorf("check %s is not isolated", checkID)
+	}
+	d.store.release(digest)
+	log.Infof("Released the isolation of configuration %s:%s on node %s", config.Name, digest, isolated.nodeName)
+	return types.IsolateResponse{CheckID: checkID, NodeName: isolated.nodeName}, nil
+}
+
+// release ends the isolation of a configuration, if any: its pin and the
+// drain of its node are restored. The configuration stays on the node until
+// it is rebalanced.
+// The store lock must be held by the caller.
+func (s *clusterStore) release(digest string) {
+	isolated, found := s.isolations[digest]
+	if !found {
+		return
+	}
+	delete(s.isolations, digest)
+	if isolated.previousPin != "" {
+		s.pinnedNodes[digest] = isolated.previousPin
+	} else {
+		delete(s.pinnedNodes, digest)
+	}
+	s.setDraining(isolated.nodeName, isolated.wasDraining)
+}
+
+// processIsolations releases the isolations whose TTL elapsed
+func (d *dispatcher) processIsolations() {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	now := timestampNow()
+	for digest, isolated := range d.store.isolations {
+		if now < isolated.until {
+			continue
+		}
+		d.store.release(digest)
+		log.Infof("The isolation of check %s on node %s expired", isolated.checkID, isolated.nodeName)
+	}
+}
+
+// response returns the API response describing the isolation
+func (i *isolation) response() types.IsolateResponse {
+	return types.IsolateResponse{
+		CheckID:  i.checkID,
+		NodeName: i.nodeName,
+		Until:    i.until,
+		Isolated: true,
+	}
+}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestIsolation(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+
+	noisy, noisyID := generatePinnedIntegration("noisy", "url: http://noisy", "")
+	dispatcher.add(noisy)
+	for i := 0; i < 3; i++ {
+		config, _ := generatePinnedIntegration("http_check", fmt.Sprintf("url: http://%d", i), "")
+		dispatcher.add(config)
+	}
+	pinned, _ := generatePinnedIntegration("pinned", "url: http://pinned", "nodeA")
+	dispatcher.add(pinned)
+
+	// A check cannot be isolated without another node for the evicted checks
+	_, err := dispatcher.isolate(noisyID, "nodeA", 60)
+	assert.Error(t, err)
+	_, err = dispatcher.isolate("unknown", "nodeA", 60)
+	assert.Error(t, err)
+
+	// The isolated check is moved to its node, which is drained of the other checks
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	response, err := dispatcher.isolate(noisyID, "nodeB", 60)
+	require.NoError(t, err)
+	assert.Equal(t, "nodeB", response.NodeName)
+	assert.True(t, response.Isolated)
+	assert.Equal(t, "nodeB", dispatcher.store.digestToNode[noisy.Digest()])
+	other, _ := generatePinnedIntegration("http_check", "url: http://other", "")
+	dispatcher.add(other)
+	assert.Equal(t, "nodeA", dispatcher.store.digestToNode[other.Digest()])
+	state, _ := dispatcher.getState()
+	for _, node := range state.Nodes {
+		assert.Equal(t, node.Name == "nodeB", node.Isolated)
+	}
+
+	// Isolated checks and their nodes are not pinned nor drained with the API
+	assert.Error(t, dispatcher.pin(noisyID, "nodeA"))
+	assert.Error(t, dispatcher.unpinCheck(noisyID))
+	_, err = dispatcher.drain("nodeB", false)
+	assert.Error(t, err)
+	_, err = dispatcher.isolate(noisyID, "nodeA", 60)
+	assert.Error(t, err)
+
+	// Isolating again extends the isolation, which is released after its TTL
+	response, err = dispatcher.isolate(noisyID, "", 120)
+	require.NoError(t, err)
+	assert.Greater(t, response.Until, timestampNow()+60)
+	dispatcher.processIsolations()
+	assert.Contains(t, dispatcher.store.isolations, noisy.Digest())
+	dispatcher.store.isolations[noisy.Digest()].until = timestampNow()
+	dispatcher.processIsolations()
+	assert.Empty(t, dispatcher.store.isolations)
+	assert.Empty(t, dispatcher.store.pinnedNodes)
+	assert.False(t, dispatcher.store.isDraining("nodeB"))
+
+	// Isolations are released with the API, restoring the previous pins
+	require.NoError(t, dispatcher.pin(noisyID, "nodeA"))
+	_, err = dispatcher.isolate(noisyID, "", 60)
+	require.NoError(t, err)
+	assert.Equal(t, "nodeB", dispatcher.store.digestToNode[noisy.Digest()])
+	response, err = dispatcher.releaseCheck(noisyID)
+	require.NoError(t, err)
+	assert.False(t, response.Isolated)
+	assert.Equal(t, "nodeA", dispatcher.store.pinnedNodes[noisy.Digest()])
+	_, err = dispatcher.releaseCheck(noisyID)
+	assert.Error(t, err)
+
+	// Removed configurations are released too
+	_, err = dispatcher.isolate(noisyID, "", 60)
+	require.NoError(t, err)
+	dispatcher.remove(noisy)
+	assert.Empty(t, dispatcher.store.isolations)
+	assert.False(t, dispatcher.store.isDraining("nodeB"))
+
+	requireNotLocked(t, dispatcher.store)
+}
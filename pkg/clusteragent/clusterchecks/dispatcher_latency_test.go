@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestDurationHistogram(t *testing.T) {
+	histogram := types.NewDurationHistogram([]int64{5, 10, 80, 60000})
+	assert.Equal(t, types.DurationHistogram{2, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1}, histogram)
+	assert.Equal(t, (5+5+75+30000)/4.0, histogram.Mean())
+	assert.Equal(t, 0.0, types.NewDurationHistogram(nil).Mean())
+}
+
+func TestLatencyAwareDispatching(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.latencyAware = true
+
+	config, checkID := generatePinnedIntegration("http_check", "url: http://slow", "")
+	dispatcher.addConfig(config, "nodeA")
+
+	// The durations reported by the runners feed their busyness
+	slow := types.NodeStatus{CheckDurations: map[string]types.DurationHistogram{
+		checkID: types.NewDurationHistogram([]int64{4000, 5000}),
+	}}
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", slow)
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{CheckDurations: map[string]types.DurationHistogram{}})
+
+	stats, err := dispatcher.store.nodes["nodeA"].GetRunnerStats(checkID)
+	require.NoError(t, err)
+	assert.Equal(t, 3750, stats.AverageExecutionTime)
+	assert.True(t, stats.IsClusterCheck)
+	assert.Equal(t, int(checkExecutionTimeWeight*3750), dispatcher.store.nodes["nodeA"].busyness)
+
+	// Configs are dispatched to the node with the lowest run cost
+	assert.Equal(t, "nodeB", dispatcher.getLeastBusyNode())
+
+	// The computed cost of the nodes is exposed
+	state, err := dispatcher.getState()
+	require.NoError(t, err)
+	for _, node := range state.Nodes {
+		if node.Name == "nodeA" {
+			assert.Equal(t, int(checkExecutionTimeWeight*3750), node.Cost)
+		}
+	}
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -33,6 +34,30 @@ type dispatcher struct {
 	extraTags             []string
 	clcRunnersClient      clusteragent.CLCRunnerClientInterface
 	advancedDispatching   bool
+	latencyAware          bool
+	zoneLabel             string
+	poolLabel             string
+	checkPools            map[string]string
+	drainBatchSize        int
+	maxConfigsPerRunner   int
+	maxWorkersPerRunner   float64
+	canaryCount           int
+	canarySuccessfulRuns  int
+	costFunc              costFunction
+	exclusionSeconds      int64
+	maxFailureRate        float64
+	maxFlaps              int
+	flapWindowSeconds     int64
+	extender              PlacementExtender
+	extenderIgnorable     bool
+	minImbalancePercent   float64
+	maxRebalanceMoves     int
+	moveCooldownSeconds   int64
+	federationEnabled     bool
+	localCluster          string
+	federatedChecks       map[string]struct{}
+	stateStore            stateStore
+	persistedState        types.DispatchingState
 }
 
 func newDispatcher() *dispatcher {
@@ -40,7 +65,20 @@ func newDispatcher() *dispatcher {
 		store: newClusterStore(),
 	}
 	d.nodeExpirationSeconds = config.Datadog.GetInt64("cluster_checks.node_expiration_timeout")
+	d.zoneLabel = config.Datadog.GetString("cluster_checks.topology_zone_label")
+	d.poolLabel = config.Datadog.GetString("cluster_checks.runner_pool_label")
+	d.checkPools = config.Datadog.GetStringMapString("cluster_checks.runner_pools")
+	d.drainBatchSize = config.Datadog.GetInt("cluster_checks.drain_batch_size")
+	d.maxConfigsPerRunner = config.Datadog.GetInt("cluster_checks.max_configs_per_runner")
+	d.maxWorkersPerRunner = config.Datadog.GetFloat64("cluster_checks.max_workers_per_runner")
 	d.extraTags = config.Datadog.GetStringSlice("cluster_checks.extra_tags")
+	costWeights := costWeightsFromConfig()
+	d.costFunc = costWeights.cost
+	d.extender = newExtenderFromConfig()
+	d.extenderIgnorable = config.Datadog.GetBool("cluster_checks.extender.ignorable")
+	d.minImbalancePercent = config.Datadog.GetFloat64("cluster_checks.rebalancing.min_imbalance_percent")
+	d.maxRebalanceMoves = config.Datadog.GetInt("cluster_checks.rebalancing.max_moves")
+	d.moveCooldownSeconds = config.Datadog.GetInt64("cluster_checks.rebalancing.move_cooldown")
 
 	hostname, _ := util.GetHostname(context.TODO())
 	clusterTagValue := clustername.GetClusterName(context.TODO(), hostname)
@@ -53,16 +91,69 @@ func newDispatcher() *dispatcher {
 		d.extraTags = append(d.extraTags, fmt.Sprintf("kube_cluster_name:%s", clusterTagValue))
 	}
 
+	d.federationEnabled = config.Datadog.GetBool("cluster_checks.federation.enabled")
+	d.localCluster = config.Datadog.GetString("cluster_checks.federation.cluster_name")
+	if d.localCluster == "" {
+		d.localCluster = clusterTagValue
+	}
+	if d.federationEnabled && d.localCluster == "" {
+		// All the runners reporting a cluster would be considered remote
+		log.Error("The cluster checks federation is misconfigured: the name of the local cluster is unknown, set cluster_checks.federation.cluster_name. The federation will be disabled")
+		d.federationEnabled = false
+	}
+	d.federatedChecks = make(map[string]struct{})
+	for _, name := range config.Datadog.GetStringSlice("cluster_checks.federation.check_names") {
+		d.federatedChecks[name] = struct{}{}
+	}
+
+	if config.Datadog.GetBool("cluster_checks.persist_state") {
+		var err error
+		d.stateStore, err = newStateStore()
+		if err != nil {
+			log.Warnf("Cannot create the cluster checks state store, the dispatching state will not be persisted: %v", err)
+		}
+	}
+
 	d.advancedDispatching = config.Datadog.GetBool("cluster_checks.advanced_dispatching_enabled")
-	if !d.advancedDispatching {
-		return d
+	if d.advancedDispatching {
+		var err error
+		d.clcRunnersClient, err = clusteragent.GetCLCRunnerClient()
+		if err != nil {
+			log.Warnf("Cannot create CLC runners client, advanced dispatching will be disabled: %v", err)
+			d.advancedDispatching = false
+		}
+	}
+
+	d.latencyAware = config.Datadog.GetBool("cluster_checks.latency_aware_rebalancing")
+
+	if d.maxWorkersPerRunner > 0 && !d.advancedDispatching && !d.latencyAware {
+		// The workers are estimated from the execution times of the checks
+		log.Warn("The cluster_checks.max_workers_per_runner quota requires the advanced dispatching or the latency aware rebalancing, it will be disabled")
+		d.maxWorkersPerRunner = 0
+	}
+	if !d.costApplied() && costWeights != defaultCostWeights {
+		// The cost is computed from the runner stats only
+		log.Warn("The cluster_checks.cost_function requires the advanced dispatching or the latency aware rebalancing, it will be ignored")
 	}
 
-	var err error
-	d.clcRunnersClient, err = clusteragent.GetCLCRunnerClient()
-	if err != nil {
-		log.Warnf("Cannot create CLC runners client, advanced dispatching will be disabled: %v", err)
-		d.advancedDispatching = false
+	if config.Datadog.GetBool("cluster_checks.canary_rollout.enabled") {
+		if d.advancedDispatching {
+			// The runs of the canaries are collected from the CLC runners
+			d.canaryCount = config.Datadog.GetInt("cluster_checks.canary_rollout.canaries")
+			d.canarySuccessfulRuns = config.Datadog.GetInt("cluster_checks.canary_rollout.successful_runs")
+		} else {
+			log.Warn("The canary rollout of the cluster checks requires the advanced dispatching, it will be disabled")
+		}
+	}
+
+	if config.Datadog.GetBool("cluster_checks.unhealthy_runners.enabled") {
+		d.exclusionSeconds = config.Datadog.GetInt64("cluster_checks.unhealthy_runners.exclusion_duration")
+		d.maxFlaps = config.Datadog.GetInt("cluster_checks.unhealthy_runners.max_flaps")
+		d.flapWindowSeconds = config.Datadog.GetInt64("cluster_checks.unhealthy_runners.flap_window")
+		if d.advancedDispatching {
+			// The failures of the checks are collected from the CLC runners
+			d.maxFailureRate = config.Datadog.GetFloat64("cluster_checks.unhealthy_runners.max_failure_rate")
+		}
 	}
 	return d
 }
@@ -93,6 +184,11 @@ func (d *dispatcher) Schedule(configs []integration.Config) {
 			log.Warnf("Cannot patch configuration %s: %s", c.Digest(), err)
 			continue
 		}
+		d.startScheduling(patched.Digest())
+		if d.canaryEnabled() {
+			d.addVersion(patched)
+			continue
+		}
 		d.add(patched)
 	}
 }
@@ -131,7 +227,18 @@ func (d *dispatcher) reschedule(configs []integration.Config) {
 
 // add stores and delegates a given configuration
 func (d *dispatcher) add(config integration.Config) {
-	target := d.getLeastBusyNode()
+	defer d.observeDispatch(time.Now())
+
+	if config.Cost != 0 && !d.costApplied() {
+		log.Warnf("The cost of %s:%s requires the advanced dispatching or the latency aware rebalancing, it will be ignored", config.Name, config.Digest())
+	}
+
+	target := d.getNodeToDispatch(config)
+	if target == "" && d.quotaReached(config) {
+		log.Warnf("All nodes reached their quota, %s:%s is unscheduled until one is available", config.Name, config.Digest())
+		d.addUnscheduled(config)
+		return
+	}
 	if target == "" {
 		// If no node is found, store it in the danglingConfigs map for retrying later.
 		log.Warnf("No available node to dispatch %s:%s on, will retry later", config.Name, config.Digest())
@@ -145,8 +252,13 @@ func (d *dispatcher) add(config integration.Config) {
 // remove deletes a given configuration
 func (d *dispatcher) remove(config integration.Config) {
 	digest := config.Digest()
+	if d.canaryEnabled() && d.holdRemoval(config) {
+		// Kept running until a new version replaces it
+		return
+	}
 	log.Debugf("Removing configuration %s:%s", config.Name, digest)
 	d.removeConfig(digest)
+	d.unpin(digest)
 }
 
 // reset empties the store and resets all states
@@ -154,6 +266,7 @@ func (d *dispatcher) reset() {
 	d.store.Lock()
 	defer d.store.Unlock()
 	d.store.reset()
+	d.persistedState = types.DispatchingState{}
 }
 
 // run is the main management goroutine for the dispatcher
@@ -187,6 +300,24 @@ func (d *dispatcher) run(ctx context.Context) {
 				danglingConfs := d.retrieveAndClearDangling()
 				d.reschedule(danglingConfs)
 			}
+
+			// Retry the configs unscheduled because of the node quotas
+			d.reschedule(d.retrieveAndClearUnscheduled())
+
+			// Move configs out of draining nodes
+			d.migrateDrainingNodes()
+
+			// Release the isolated configs whose TTL elapsed
+			d.processIsolations()
+
+			// Exclude the unhealthy nodes and re-admit the recovered ones
+			d.processUnhealthyNodes()
+
+			// Roll out the changed configs whose canaries succeeded
+			d.processRollouts()
+
+			// Save the dispatching state for the next leader
+			d.persistState()
 		case <-runnerStatsTicker.C:
 			// Collect stats with an exponential backoff 2 - 5 - 10 minutes
 			if runnerStatsMinutes == firstRunnerStatsMinutes {
@@ -198,7 +329,7 @@ func (d *dispatcher) run(ctx context.Context) {
 			}
 
 			// Rebalance if needed
-			if d.advancedDispatching {
+			if d.advancedDispatching || d.latencyAware {
 				// Rebalance checks distribution
 				d.rebalance()
 			}
@@ -15,12 +15,17 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	le "github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 const defaultBusynessValue int = -1
 
+// defaultCapacity is the capacity the load of the nodes is weighted against,
+// the default number of check runners
+const defaultCapacity = float64(config.DefaultNumWorkers)
+
 // getClusterCheckConfigs returns configurations dispatched to a given node
 func (d *dispatcher) getClusterCheckConfigs(nodeName string) ([]integration.Config, int64, error) {
 	d.store.RLock()
@@ -53,6 +58,17 @@ func (d *dispatcher) processNodeStatus(nodeName, clientIP string, status types.N
 	node.lastStatus = status
 	node.heartbeat = timestampNow()
 
+	if d.latencyAware && status.CheckDurations != nil {
+		if !d.advancedDispatching {
+			// The durations are the only stats without advanced dispatching
+			node.clcRunnerStats = types.CLCRunnersStats{}
+		}
+		applyCheckDurations(node.clcRunnerStats, status.CheckDurations)
+		node.applyConfigCosts(node.clcRunnerStats)
+		node.busyness = calculateBusyness(node.clcRunnerStats, d.costFunc)
+		busyness.Set(float64(node.busyness), node.name, le.JoinLeaderValue)
+	}
+
 	if node.lastConfigChange == status.LastChange {
 		// Node-agent is up to date
 		return true, nil
@@ -70,39 +86,58 @@ func (d *dispatcher) processNodeStatus(nodeName, clientIP string, status types.N
 }
 
 // getLeastBusyNode returns the name of the node that is assigned
-// the lowest number of checks. In case of equality, one is chosen
-// randomly, based on map iterations being randomized.
+// the lowest number of checks, weighted by the capacity of the nodes.
+// In case of equality, one is chosen randomly, based on map iterations
+// being randomized.
 func (d *dispatcher) getLeastBusyNode() string {
-	var leastBusyNode string
-	minCheckCount := int(-1)
-	minBusyness := int(-1)
-
 	d.store.RLock()
 	defer d.store.RUnlock()
 
-	for name, store := range d.store.nodes {
-		if name == "" {
+	return d.leastBusyNode(d.store.nodes)
+}
+
+// leastBusyNode returns the name of the least busy node among the given
+// nodes, as getLeastBusyNode does. Draining nodes, excluded unhealthy nodes
+// and nodes that reached their quota are ignored.
+// The store lock must be held by the caller.
+func (d *dispatcher) leastBusyNode(nodes map[string]*nodeStore) string {
+	var leastBusyNode string
+	minCheckCount := float64(-1)
+	minBusyness := float64(-1)
+
+	for name, store := range nodes {
+		if !d.isAvailable(name, store) {
 			continue
 		}
-		if d.advancedDispatching && store.busyness > defaultBusynessValue {
+		factor := store.capacityFactor()
+		if (d.advancedDispatching || d.latencyAware) && store.busyness > defaultBusynessValue {
 			// dispatching based on clc runners stats
-			// only when advancedDispatching is true and
-			// started collecting busyness values
-			if minBusyness == -1 || store.busyness < minBusyness {
+			// only when advancedDispatching or latencyAware is true
+			// and started collecting busyness values
+			busyness := float64(store.busyness) * factor
+			if minBusyness == -1 || busyness < minBusyness {
 				leastBusyNode = name
-				minBusyness = store.busyness
+				minBusyness = busyness
 			}
 		} else {
 			// count-based round robin dispatching
-			if minCheckCount == -1 || len(store.digestToConfig) < minCheckCount {
+			checkCount := float64(len(store.digestToConfig)) * factor
+			if minCheckCount == -1 || checkCount < minCheckCount {
 				leastBusyNode = name
-				minCheckCount = len(store.digestToConfig)
+				minCheckCount = checkCount
 			}
 		}
 	}
 	return leastBusyNode
 }
 
+// isAvailable returns whether new configurations can be dispatched to a node:
+// it is neither draining, excluded because it is unhealthy, nor over its quota.
+// The store lock must be held by the caller.
+func (d *dispatcher) isAvailable(name string, node *nodeStore) bool {
+	return name != "" && !d.store.isDraining(name) && !d.store.isExcluded(name) && d.hasQuota(node)
+}
+
 // expireNodes iterates over nodes and removes the ones that have not
 // reported for more than the expiration duration. The configurations
 // dispatched to these nodes will be moved to the danglingConfigs map.
@@ -120,6 +155,7 @@ func (d *dispatcher) expireNodes() {
 			if name != "" {
 				// Don't report on the dummy "" host for unscheduled configs
 				log.Infof("Expiring out node %s, last status report %d seconds ago", name, timestampNow()-node.heartbeat)
+				d.recordExpiration(name)
 			}
 			for digest, config := range node.digestToConfig {
 				delete(d.store.digestToNode, digest)
@@ -159,6 +195,12 @@ func (d *dispatcher) updateRunnersStats() {
 	d.store.Lock()
 	defer d.store.Unlock()
 	for name, node := range d.store.nodes {
+		if cluster := d.remoteCluster(node); cluster != "" {
+			// The IPs of the runners of the other clusters are not routable
+			log.Tracef("Not collecting the CLC Runner stats of node %s of cluster %s", name, cluster)
+			continue
+		}
+
 		node.RLock()
 		ip := node.clientIP
 		node.RUnlock()
@@ -181,9 +223,13 @@ func (d *dispatcher) updateRunnersStats() {
 				stats[id] = checkStats
 			}
 		}
+		if d.latencyAware {
+			applyCheckDurations(stats, node.lastStatus.CheckDurations)
+		}
+		node.applyConfigCosts(stats)
 		node.clcRunnerStats = stats
 		log.Tracef("Updated CLC Runner stats on node: %s, node IP: %s, stats: %v", name, node.clientIP, stats)
-		node.busyness = calculateBusyness(stats)
+		node.busyness = calculateBusyness(stats, d.costFunc)
 		log.Debugf("Updated busyness on node: %s, node IP: %s, busyness value: %d", name, node.clientIP, node.busyness)
 		busyness.Set(float64(node.busyness), node.name, le.JoinLeaderValue)
 		node.Unlock()
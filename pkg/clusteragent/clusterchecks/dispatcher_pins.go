@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	le "github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// pin pins the configuration of a check to a node and dispatches it there
+// right away. Pinned configurations are not moved by the rebalancing.
+func (d *dispatcher) pin(checkID, nodeName string) error {
+	config, digest := d.getConfigAndDigest(checkID)
+	if digest == "" {
+		return fmt.Errorf("check %s is not dispatched by the cluster-agent", checkID)
+	}
+
+	d.store.Lock()
+	if isolatedNode := d.store.isolatedNode(digest); isolatedNode != "" {
+		d.store.Unlock()
+		return fmt.Errorf("check %s is isolated on node %s", checkID, isolatedNode)
+	}
+	if _, found := d.store.getNodeStore(nodeName); !found || nodeName == "" {
+		d.store.Unlock()
+		return fmt.Errorf("node %s is not reporting to the cluster-agent", nodeName)
+	}
+	d.store.pinnedNodes[digest] = nodeName
+	if _, found := d.store.danglingConfigs[digest]; found {
+		delete(d.store.danglingConfigs, digest)
+		danglingConfigs.Dec(le.JoinLeaderValue)
+	}
+	d.store.Unlock()
+
+	log.Infof("Pinning configuration %s:%s to node %s", config.Name, digest, nodeName)
+	d.addConfig(config, nodeName)
+	return nil
+}
+
+// unpinCheck removes the pin set with the API on the configuration of a check.
+// The configuration stays on its node until it is rebalanced, or is moved to
+// the node set in its configuration, if any.
+func (d *dispatcher) unpinCheck(checkID string) error {
+	config, digest := d.getConfigAndDigest(checkID)
+	if digest == "" {
+		return fmt.Errorf("check %s is not dispatched by the cluster-agent", checkID)
+	}
+	if isolatedNode := d.isolatedNode(digest); isolatedNode != "" {
+		return fmt.Errorf("check %s is isolated on node %s", checkID, isolatedNode)
+	}
+
+	d.unpin(digest)
+	log.Infof("Unpinned configuration %s:%s", config.Name, digest)
+
+	if config.PinnedNode != "" {
+		d.add(config)
+	}
+	return nil
+}
+
+// unpin removes the pin set with the API on a configuration, if any, and
+// ends its isolation
+func (d *dispatcher) unpin(digest string) {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	d.store.release(digest)
+	delete(d.store.pinnedNodes, digest)
+}
+
+// makePinnedConfigArray returns the configurations as makeConfigArray does,
+// with the node they are pinned to with the API set as their PinnedNode.
+// The store lock must be held by the caller.
+func (d *dispatcher) makePinnedConfigArray(configMap map[string]integration.Config) []integration.Config {
+	configSlice := make([]integration.Config, 0, len(configMap))
+	for digest, c := range configMap {
+		c.PinnedNode = d.store.pinnedNode(digest, c)
+		configSlice = append(configSlice, c)
+	}
+	return configSlice
+}
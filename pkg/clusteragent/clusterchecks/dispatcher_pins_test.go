@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+func generatePinnedIntegration(name, instance, nodeName string) (integration.Config, string) {
+	config := generateIntegration(name)
+	config.Instances = []integration.Data{integration.Data(instance)}
+	config.PinnedNode = nodeName
+	return config, string(check.BuildID(config.Name, config.Instances[0], config.InitConfig))
+}
+
+func TestPinnedNodeInConfig(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	// nodeA is the least busy node, but the config is pinned to nodeB
+	dispatcher.addConfig(generateIntegration("A"), "nodeB")
+	config, _ := generatePinnedIntegration("pinned", "url: http://one", "nodeB")
+	assert.Equal(t, "nodeB", dispatcher.getNodeToDispatch(config))
+
+	// The config is kept dangling until its node reports
+	config.PinnedNode = "nodeC"
+	dispatcher.add(config)
+	assert.Contains(t, dispatcher.store.danglingConfigs, config.Digest())
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestPinWithAPI(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	config, id := generatePinnedIntegration("http_check", "url: http://one", "")
+	dispatcher.addConfig(config, "nodeA")
+
+	// Unknown checks and nodes are rejected
+	assert.Error(t, dispatcher.pin("unknown", "nodeB"))
+	assert.Error(t, dispatcher.pin(id, "nodeC"))
+
+	// The config is moved to its node right away
+	require.NoError(t, dispatcher.pin(id, "nodeB"))
+	assert.Equal(t, "nodeB", dispatcher.store.digestToNode[config.Digest()])
+	assert.Equal(t, "nodeB", dispatcher.getNodeToDispatch(config))
+	state, err := dispatcher.getState()
+	require.NoError(t, err)
+	for _, node := range state.Nodes {
+		if node.Name == "nodeB" {
+			require.Len(t, node.Configs, 1)
+			assert.Equal(t, "nodeB", node.Configs[0].PinnedNode)
+		}
+	}
+
+	// The pin set with the API takes precedence over the config
+	config.PinnedNode = "nodeA"
+	assert.Equal(t, "nodeB", dispatcher.getNodeToDispatch(config))
+
+	// Unpinning keeps the config on its node
+	require.NoError(t, dispatcher.unpinCheck(id))
+	assert.Equal(t, "nodeB", dispatcher.store.digestToNode[config.Digest()])
+	assert.Empty(t, dispatcher.store.pinnedNodes)
+
+	// Removing the config removes its pin
+	require.NoError(t, dispatcher.pin(id, "nodeB"))
+	dispatcher.remove(config)
+	assert.Empty(t, dispatcher.store.pinnedNodes)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestRebalanceSkipsPinned(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+
+	heavy, heavyID := generatePinnedIntegration("heavy", "url: http://one", "nodeA")
+	light, lightID := generatePinnedIntegration("light", "url: http://two", "")
+	dispatcher.addConfig(heavy, "nodeA")
+	dispatcher.addConfig(light, "nodeA")
+	dispatcher.store.nodes["nodeA"].clcRunnerStats = types.CLCRunnersStats{
+		heavyID: {AverageExecutionTime: 1000, MetricSamples: 100, IsClusterCheck: true},
+		lightID: {AverageExecutionTime: 100, MetricSamples: 10, IsClusterCheck: true},
+	}
+
+	// The heavier check is pinned, the lighter one is moved instead
+	checkID, _, err := dispatcher.pickCheckToMove("nodeA")
+	require.NoError(t, err)
+	assert.Equal(t, lightID, checkID)
+
+	requireNotLocked(t, dispatcher.store)
+}
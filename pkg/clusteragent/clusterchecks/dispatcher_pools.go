@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+// configPool returns the pool of runners a configuration is dispatched to:
+// the one set in the configuration, or else the one of its check name, or
+// else the empty pool of the runners not reporting any.
+func (d *dispatcher) configPool(config integration.Config) string {
+	if d.poolLabel == "" {
+		return ""
+	}
+	if config.RunnerPool != "" {
+		return config.RunnerPool
+	}
+	return d.checkPools[config.Name]
+}
+
+// poolNodes returns the nodes of a pool of runners. The pools are strict:
+// the configurations of a pool stay dangling until a runner of the pool
+// reports, and the runners of a pool only run the configurations of their
+// pool.
+// The store lock must be held by the caller.
+func (d *dispatcher) poolNodes(pool string) map[string]*nodeStore {
+	if d.poolLabel == "" {
+		return d.store.nodes
+	}
+
+	nodes := make(map[string]*nodeStore)
+	for name, node := range d.store.nodes {
+		if node.pool(d.poolLabel) == pool {
+			nodes[name] = node
+		}
+	}
+	return nodes
+}
+
+// filterByPool restricts the destination nodes of a check being rebalanced to
+// the nodes of its runner pool.
+func (d *dispatcher) filterByPool(diffMap map[string]int, checkID string) map[string]int {
+	if d.poolLabel == "" {
+		return diffMap
+	}
+	config, _ := d.getConfigAndDigest(checkID)
+	pool := d.configPool(config)
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	filtered := make(map[string]int)
+	for nodeName, diff := range diffMap {
+		if node, found := d.store.getNodeStore(nodeName); found && node.pool(d.poolLabel) == pool {
+			filtered[nodeName] = diff
+		}
+	}
+	return filtered
+}
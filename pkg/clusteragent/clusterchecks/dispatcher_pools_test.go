@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestRunnerPools(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.poolLabel = "runner_pool"
+	dispatcher.checkPools = map[string]string{"snmp": "network"}
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{Labels: map[string]string{"runner_pool": "network"}})
+
+	// Configs go to the runners of their pool, by check name or set in the config
+	for i := 0; i < 3; i++ {
+		config, _ := generatePinnedIntegration("http_check", fmt.Sprintf("url: http://%d", i), "")
+		dispatcher.add(config)
+		snmp, _ := generatePinnedIntegration("snmp", fmt.Sprintf("ip_address: 10.1.0.%d", i), "")
+		dispatcher.add(snmp)
+	}
+	oracle, oracleID := generatePinnedIntegration("oracle", "server: db", "")
+	oracle.RunnerPool = "database"
+	dispatcher.add(oracle)
+
+	assert.Len(t, dispatcher.store.nodes["nodeA"].digestToConfig, 3)
+	for _, config := range dispatcher.store.nodes["nodeA"].digestToConfig {
+		assert.Equal(t, "http_check", config.Name)
+	}
+	assert.Len(t, dispatcher.store.nodes["nodeB"].digestToConfig, 3)
+	for _, config := range dispatcher.store.nodes["nodeB"].digestToConfig {
+		assert.Equal(t, "snmp", config.Name)
+	}
+
+	// Configs stay dangling until a runner of their pool reports
+	assert.Contains(t, dispatcher.store.danglingConfigs, oracle.Digest())
+	dispatcher.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{Labels: map[string]string{"runner_pool": "database"}})
+	dispatcher.reschedule(dispatcher.retrieveAndClearDangling())
+	assert.Equal(t, "nodeC", dispatcher.store.digestToNode[oracle.Digest()])
+
+	// Checks are only rebalanced within their pool
+	filtered := dispatcher.filterByPool(map[string]int{"nodeA": -10, "nodeB": -20, "nodeC": 0}, oracleID)
+	assert.Equal(t, map[string]int{"nodeC": 0}, filtered)
+
+	state, _ := dispatcher.getState()
+	for _, node := range state.Nodes {
+		if node.Name == "nodeB" {
+			assert.Equal(t, "network", node.Pool)
+		}
+	}
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/check/defaults"
+	le "github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection/metrics"
+)
+
+// quotasEnabled returns whether the number of configurations or the
+// estimated workers per node are limited
+func (d *dispatcher) quotasEnabled() bool {
+	return d.maxConfigsPerRunner > 0 || d.maxWorkersPerRunner > 0
+}
+
+// hasQuota returns whether a configuration can be dispatched to a node
+// without exceeding the maximum number of configurations or estimated
+// workers per node.
+// The store lock must be held by the caller.
+func (d *dispatcher) hasQuota(node *nodeStore) bool {
+	if d.maxConfigsPerRunner > 0 && len(node.digestToConfig) >= d.maxConfigsPerRunner {
+		return false
+	}
+	if d.maxWorkersPerRunner > 0 && node.estimatedWorkers() >= d.maxWorkersPerRunner {
+		return false
+	}
+	return true
+}
+
+// estimatedWorkers returns the number of check workers kept busy by the checks
+// running on the node, estimated from their average execution time over their
+// interval. It is 0 until the runner stats are collected.
+// The nodeStore handles thread safety for this method
+func (s *nodeStore) estimatedWorkers() float64 {
+	s.RLock()
+	defer s.RUnlock()
+
+	workers := float64(0)
+	for id, stats := range s.clcRunnerStats {
+		interval, found := s.checkIntervals[id]
+		if !found {
+			interval = defaults.DefaultCheckInterval
+		}
+		workers += float64(stats.AverageExecutionTime) / float64(interval.Milliseconds())
+	}
+	return workers
+}
+
+// addCheckIntervals keeps the interval set by a configuration for each of its
+// checks, when it is not the default one
+func (s *nodeStore) addCheckIntervals(config integration.Config) {
+	for _, instance := range config.Instances {
+		commonOptions := integration.CommonInstanceConfig{}
+		if err := yaml.Unmarshal(instance, &commonOptions); err != nil || commonOptions.MinCollectionInterval <= 0 {
+			continue
+		}
+		id := string(check.BuildID(config.Name, instance, config.InitConfig))
+		s.checkIntervals[id] = time.Duration(commonOptions.MinCollectionInterval) * time.Second
+	}
+}
+
+// removeCheckIntervals forgets the intervals set by a configuration
+func (s *nodeStore) removeCheckIntervals(config integration.Config) {
+	for _, instance := range config.Instances {
+		delete(s.checkIntervals, string(check.BuildID(config.Name, instance, config.InitConfig)))
+	}
+}
+
+// quotaReached returns whether a configuration cannot be dispatched because
+// all its candidate nodes reached their quota, rather than because none of
+// them is reporting or available. Pinned configurations are never subject to
+// the quotas.
+func (d *dispatcher) quotaReached(config integration.Config) bool {
+	if !d.quotasEnabled() {
+		return false
+	}
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	if d.store.pinnedNode(config.Digest(), config) != "" {
+		return false
+	}
+	reached := false
+	for name, node := range d.candidateNodes(config) {
+		if name == "" || d.store.isDraining(name) || d.store.isExcluded(name) {
+			continue
+		}
+		if d.hasQuota(node) {
+			return false
+		}
+		reached = true
+	}
+	return reached
+}
+
+// addUnscheduled registers a configuration without dispatching it, until a
+// node is under its quota. A configuration already dispatched keeps running
+// on its node.
+func (d *dispatcher) addUnscheduled(config integration.Config) {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	digest := config.Digest()
+	d.store.registerConfig(config)
+	if d.store.digestToNode[digest] != "" {
+		return
+	}
+	d.store.unscheduled[digest] = config
+	if !d.store.dryRun {
+		unscheduledConfigs.Set(float64(len(d.store.unscheduled)), le.JoinLeaderValue)
+	}
+}
+
+// removeUnscheduled forgets an unscheduled configuration, if it is one
+// The store lock must be held by the caller.
+func (s *clusterStore) removeUnscheduled(digest string) {
+	if _, found := s.unscheduled[digest]; !found {
+		return
+	}
+	delete(s.unscheduled, digest)
+	if !s.dryRun {
+		unscheduledConfigs.Set(float64(len(s.unscheduled)), le.JoinLeaderValue)
+	}
+}
+
+// retrieveAndClearUnscheduled extracts the unscheduled configs from the store
+func (d *dispatcher) retrieveAndClearUnscheduled() []integration.Config {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	configs := makeConfigArray(d.store.unscheduled)
+	d.store.unscheduled = make(map[string]integration.Config)
+	if !d.store.dryRun {
+		unscheduledConfigs.Set(0, le.JoinLeaderValue)
+	}
+	return configs
+}
+
+// filterQuota removes the nodes that reached their quota from the
+// destination nodes of the rebalancing.
+func (d *dispatcher) filterQuota(diffMap map[string]int) map[string]int {
+	if !d.quotasEnabled() {
+		return diffMap
+	}
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	filtered := make(map[string]int, len(diffMap))
+	for nodeName, diff := range diffMap {
+		if node, found := d.store.getNodeStore(nodeName); found && !d.hasQuota(node) {
+			continue
+		}
+		filtered[nodeName] = diff
+	}
+	return filtered
+}
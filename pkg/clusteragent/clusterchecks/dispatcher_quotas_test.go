@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestQuotas(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.maxConfigsPerRunner = 2
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	for i := 0; i < 5; i++ {
+		config, _ := generatePinnedIntegration("http_check", fmt.Sprintf("url: http://%d", i), "")
+		dispatcher.add(config)
+	}
+
+	// The configs over the quota of the nodes are unscheduled, not dangling
+	assert.Len(t, dispatcher.store.nodes["nodeA"].digestToConfig, 2)
+	assert.Len(t, dispatcher.store.nodes["nodeB"].digestToConfig, 2)
+	assert.Len(t, dispatcher.store.unscheduled, 1)
+	assert.Empty(t, dispatcher.store.danglingConfigs)
+	state, err := dispatcher.getState()
+	require.NoError(t, err)
+	assert.Len(t, state.Unscheduled, 1)
+	assert.Equal(t, 1, dispatcher.getStats().UnscheduledConfigs)
+
+	// Pinned configs are not subject to the quotas
+	pinned, _ := generatePinnedIntegration("pinned", "url: http://pinned", "nodeA")
+	dispatcher.add(pinned)
+	assert.Len(t, dispatcher.store.nodes["nodeA"].digestToConfig, 3)
+
+	// The unscheduled configs are dispatched once a node is available
+	dispatcher.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{})
+	dispatcher.reschedule(dispatcher.retrieveAndClearUnscheduled())
+	assert.Len(t, dispatcher.store.nodes["nodeC"].digestToConfig, 1)
+	assert.Empty(t, dispatcher.store.unscheduled)
+
+	// Nodes whose checks keep enough workers busy reached their quota too
+	dispatcher.maxConfigsPerRunner = 0
+	dispatcher.maxWorkersPerRunner = 1
+	dispatcher.store.nodes["nodeC"].AddRunnerStats("http_check:1", types.CLCRunnerStats{AverageExecutionTime: 15000})
+	assert.False(t, dispatcher.hasQuota(dispatcher.store.nodes["nodeC"]))
+	assert.True(t, dispatcher.hasQuota(dispatcher.store.nodes["nodeB"]))
+	filtered := dispatcher.filterQuota(map[string]int{"nodeB": -10, "nodeC": -20})
+	assert.Equal(t, map[string]int{"nodeB": -10}, filtered)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestQuotaReachedCandidateNodes(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.maxConfigsPerRunner = 1
+	dispatcher.poolLabel = "pool"
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{Labels: map[string]string{"pool": "db"}})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{})
+
+	// nodeA is full, but the configs of the default pool can still run on nodeB and nodeC
+	db, _ := generatePinnedIntegration("postgres", "url: db", "")
+	db.RunnerPool = "db"
+	dispatcher.add(db)
+	config, _ := generatePinnedIntegration("http_check", "url: http://1", "")
+	assert.False(t, dispatcher.quotaReached(config))
+	dispatcher.add(config)
+	assert.False(t, dispatcher.quotaReached(config))
+
+	// The configs of a pool without runner are dangling
+	orphan, _ := generatePinnedIntegration("redis", "url: redis", "")
+	orphan.RunnerPool = "cache"
+	assert.False(t, dispatcher.quotaReached(orphan))
+	dispatcher.add(orphan)
+	assert.Empty(t, dispatcher.store.unscheduled)
+	assert.Contains(t, dispatcher.store.danglingConfigs, orphan.Digest())
+
+	// The configs are unscheduled once all their candidates are full
+	db2, _ := generatePinnedIntegration("postgres", "url: db2", "")
+	db2.RunnerPool = "db"
+	assert.True(t, dispatcher.quotaReached(db2))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestEstimatedWorkersInterval(t *testing.T) {
+	node := newNodeStore("nodeA", "10.0.0.1")
+	config, id := generatePinnedIntegration("http_check", "min_collection_interval: 60", "")
+	node.addConfig(config)
+	node.AddRunnerStats(id, types.CLCRunnerStats{AverageExecutionTime: 15000})
+	node.AddRunnerStats("default:1", types.CLCRunnerStats{AverageExecutionTime: 15000})
+
+	// 15s every minute, and 15s every default 15s interval
+	assert.InDelta(t, 1.25, node.estimatedWorkers(), 0.001)
+
+	node.removeConfig(config.Digest())
+	assert.InDelta(t, 2, node.estimatedWorkers(), 0.001)
+}
@@ -53,7 +53,7 @@ func (d *dispatcher) calculateAvg() (int, error) {
 	defer d.store.RUnlock()
 
 	for _, node := range d.store.nodes {
-		busyness = node.GetBusyness(busynessFunc)
+		busyness = node.GetWeightedBusyness(d.costFunc)
 		length++
 	}
 
@@ -66,7 +66,8 @@ func (d *dispatcher) calculateAvg() (int, error) {
 
 // getDiffAndWeights creates a map that contains the difference between
 // the busyness on each node and the total average busyness, and a Weights
-// struct containing nodes and their busyness values
+// struct containing nodes and their busyness values. Busyness values are
+// weighted by the capacity of the nodes.
 func (d *dispatcher) getDiffAndWeights(avg int) (map[string]int, Weights) {
 	diffMap := make(map[string]int)
 	weights := Weights{}
@@ -75,7 +76,7 @@ func (d *dispatcher) getDiffAndWeights(avg int) (map[string]int, Weights) {
 	defer d.store.RUnlock()
 
 	for nodeName, node := range d.store.nodes {
-		busyness := node.GetBusyness(busynessFunc)
+		busyness := node.GetWeightedBusyness(d.costFunc)
 		diffMap[nodeName] = busyness - avg
 		weights = append(weights, Weight{
 			nodeName: nodeName,
@@ -94,7 +95,7 @@ func (d *dispatcher) updateDiff(avg int) map[string]int {
 	defer d.store.RUnlock()
 
 	for nodeName, node := range d.store.nodes {
-		busyness := node.GetBusyness(busynessFunc)
+		busyness := node.GetWeightedBusyness(d.costFunc)
 		diffMap[nodeName] = busyness - avg
 	}
 
@@ -105,9 +106,15 @@ func (d *dispatcher) updateDiff(avg int) map[string]int {
 // A check Xi running on a node N is chosen to move to another node if it satisfies the following
 // Weight(Xi) >  Weight(Xj) (for each j != i, 0 <= j < len(weights))
 // where Weight(X) is the busyness value caused by running the check X.
+// The checks whose configuration is pinned to a node, and the checks moved
+// during their cool-down, are never moved.
 func (d *dispatcher) pickCheckToMove(nodeName string) (string, int, error) {
 	d.store.RLock()
 	node, found := d.store.getNodeStore(nodeName)
+	excluded := d.store.pinnedCheckIDs()
+	for checkID := range d.store.movedChecks {
+		excluded[checkID] = struct{}{}
+	}
 	d.store.RUnlock()
 
 	if !found {
@@ -115,7 +122,7 @@ func (d *dispatcher) pickCheckToMove(nodeName string) (string, int, error) {
 		return "", -1, fmt.Errorf("node %s not found in store", nodeName)
 	}
 
-	return node.GetMostWeightedClusterCheck(busynessFunc)
+	return node.GetMostWeightedClusterCheck(d.costFunc, excluded)
 }
 
 // pickNode select the most appropriate node to receive a specific check.
@@ -140,6 +147,18 @@ func pickNode(diffMap map[string]int, sourceNode string) string {
 	return pickedNode
 }
 
+// getCapacityFactor returns the capacity factor of a node, 1 if it is unknown
+func (d *dispatcher) getCapacityFactor(nodeName string) float64 {
+	d.store.RLock()
+	node, found := d.store.getNodeStore(nodeName)
+	d.store.RUnlock()
+
+	if !found {
+		return 1
+	}
+	return node.capacityFactor()
+}
+
 // moveCheck moves a check by its ID from a node to another
 func (d *dispatcher) moveCheck(src, dest, checkID string) error {
 	log.Debugf("Moving %s from %s to %s", checkID, src, dest)
@@ -168,6 +187,7 @@ func (d *dispatcher) moveCheck(src, dest, checkID string) error {
 
 	d.removeConfig(digest)
 	d.addConfig(config, dest)
+	d.recordMove(checkID)
 
 	log.Debugf("Check %s moved from %s to %s", checkID, src, dest)
 
@@ -182,7 +202,9 @@ func (d *dispatcher) rebalance() []types.RebalanceResponse {
 
 	start := time.Now()
 	defer func() {
-		rebalancingDuration.Set(time.Since(start).Seconds(), le.JoinLeaderValue)
+		if !d.store.dryRun {
+			rebalancingDuration.Set(time.Since(start).Seconds(), le.JoinLeaderValue)
+		}
 	}()
 
 	log.Trace("Trying to rebalance cluster checks distribution if needed")
@@ -191,13 +213,19 @@ func (d *dispatcher) rebalance() []types.RebalanceResponse {
 		log.Debugf("Cannot rebalance checks: %v", err)
 		return nil
 	}
+	d.forgetMoves()
 
 	checksMoved := []types.RebalanceResponse{}
 	diffMap, weights := d.getDiffAndWeights(totalAvg)
 	sort.Sort(weights)
+	threshold := d.imbalanceThreshold(totalAvg)
 
 	for _, nodeWeight := range weights {
-		for diffMap[nodeWeight.nodeName] > 0 {
+		for diffMap[nodeWeight.nodeName] > threshold {
+			if d.moveBudgetReached(len(checksMoved)) {
+				log.Debugf("Moved %d checks, the maximum per rebalancing", len(checksMoved))
+				break
+			}
 			// try to move checks from a node only of the node busyness is above the average
 			sourceNodeName := nodeWeight.nodeName
 			checkID, checkWeight, err := d.pickCheckToMove(sourceNodeName)
@@ -206,24 +234,34 @@ func (d *dispatcher) rebalance() []types.RebalanceResponse {
 				break
 			}
 
-			destNodeName := pickNode(diffMap, sourceNodeName)
+			candidates := d.filterByFederation(d.filterByPool(d.filterQuota(d.filterExcluded(d.filterDraining(diffMap))), checkID), checkID)
+			destNodeName := pickNode(d.filterByExtender(d.filterByTopology(candidates, sourceNodeName, checkID), checkID), sourceNodeName)
+			if destNodeName == "" {
+				log.Debugf("Cannot pick a node to move check %s from node %s to", checkID, sourceNodeName)
+				break
+			}
 			sourceDiff := diffMap[sourceNodeName]
 			destDiff := diffMap[destNodeName]
+			destWeight := int(float64(checkWeight) * d.getCapacityFactor(destNodeName))
 
 			// move a check to a new node only if it keeps the
 			// busyness of the new node lower than the original
 			// node's busyness multiplied by the tolerationMargin
 			// value the toleration margin is used to lean towards
 			// stability over perfectly optimal balance
-			if destDiff+checkWeight < int(float64(sourceDiff)*tolerationMargin) {
-				rebalancingDecisions.Inc(le.JoinLeaderValue)
+			if destDiff+destWeight < int(float64(sourceDiff)*tolerationMargin) {
+				if !d.store.dryRun {
+					rebalancingDecisions.Inc(le.JoinLeaderValue)
+				}
 				err = d.moveCheck(sourceNodeName, destNodeName, checkID)
 				if err != nil {
 					log.Debugf("Cannot move check %s: %v", checkID, err)
 					continue
 				}
 
-				successfulRebalancing.Inc(le.JoinLeaderValue)
+				if !d.store.dryRun {
+					successfulRebalancing.Inc(le.JoinLeaderValue)
+				}
 				log.Tracef("Check %s with weight %d moved, total avg: %d, source diff: %d, dest diff: %d",
 					checkID, checkWeight, totalAvg, sourceDiff, destDiff)
 				// diffMap needs to be updated on every check moved
@@ -242,5 +280,8 @@ func (d *dispatcher) rebalance() []types.RebalanceResponse {
 		}
 	}
 
+	if !d.store.dryRun {
+		rebalancingMoves.Observe(float64(len(checksMoved)), le.JoinLeaderValue)
+	}
 	return checksMoved
 }
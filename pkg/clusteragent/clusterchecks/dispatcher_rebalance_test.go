@@ -1383,6 +1383,36 @@ func TestRebalance(t *testing.T) {
 	}
 }
 
+func TestRebalanceCapacity(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.store.active = true
+
+	// nodeB runs more checks than nodeA, but has twice its capacity
+	stats := func(prefix string, count int) types.CLCRunnersStats {
+		s := types.CLCRunnersStats{}
+		for i := 0; i < count; i++ {
+			s[fmt.Sprintf("%s%d", prefix, i)] = types.CLCRunnerStats{AverageExecutionTime: 100, IsClusterCheck: true}
+		}
+		return s
+	}
+	dispatcher.store.nodes["A"] = newNodeStore("A", "")
+	dispatcher.store.nodes["A"].clcRunnerStats = stats("checkA", 3)
+	dispatcher.store.nodes["B"] = newNodeStore("B", "")
+	dispatcher.store.nodes["B"].clcRunnerStats = stats("checkB", 4)
+	dispatcher.store.nodes["B"].lastStatus.Capacity = 2 * defaultCapacity
+
+	moves := dispatcher.rebalance()
+
+	// A check is moved to the bigger node
+	assert.Len(t, moves, 1)
+	assert.Equal(t, "A", moves[0].SourceNodeName)
+	assert.Equal(t, "B", moves[0].DestNodeName)
+	assert.Len(t, dispatcher.store.nodes["A"].clcRunnerStats, 2)
+	assert.Len(t, dispatcher.store.nodes["B"].clcRunnerStats, 5)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
 func TestMoveCheck(t *testing.T) {
 	type checkInfo struct {
 		config integration.Config
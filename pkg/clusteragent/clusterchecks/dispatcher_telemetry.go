@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"time"
+
+	le "github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection/metrics"
+)
+
+// startScheduling records when a new configuration was scheduled, to report
+// its time to schedule once it is dispatched to a node
+func (d *dispatcher) startScheduling(digest string) {
+	if d.store.dryRun {
+		return
+	}
+
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	if _, dispatched := d.store.digestToNode[digest]; dispatched {
+		return
+	}
+	if _, found := d.store.pendingSince[digest]; !found {
+		d.store.pendingSince[digest] = time.Now()
+	}
+}
+
+// observeScheduled reports the time to schedule of a configuration dispatched
+// to a node, if it was pending
+// The store lock must be held by the caller.
+func (s *clusterStore) observeScheduled(digest string) {
+	since, found := s.pendingSince[digest]
+	if !found {
+		return
+	}
+	delete(s.pendingSince, digest)
+	timeToSchedule.Observe(time.Since(since).Seconds(), le.JoinLeaderValue)
+}
+
+// observeDispatch reports the duration of the dispatching of a configuration
+func (d *dispatcher) observeDispatch(start time.Time) {
+	if d.store.dryRun {
+		return
+	}
+	dispatchDuration.Observe(time.Since(start).Seconds(), le.JoinLeaderValue)
+}
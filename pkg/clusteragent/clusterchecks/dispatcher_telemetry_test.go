@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func TestSchedulingTelemetry(t *testing.T) {
+	dispatcher := newDispatcher()
+
+	// New configs are pending until they are dispatched to a node
+	config, _ := generatePinnedIntegration("http_check", "url: http://1", "")
+	config.ClusterCheck = true
+	dispatcher.Schedule([]integration.Config{config})
+	assert.Len(t, dispatcher.store.danglingConfigs, 1)
+	assert.Len(t, dispatcher.store.pendingSince, 1)
+
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.reschedule(dispatcher.retrieveAndClearDangling())
+	assert.Len(t, dispatcher.store.nodes["nodeA"].digestToConfig, 1)
+	assert.Empty(t, dispatcher.store.pendingSince)
+
+	// Configs already dispatched are not pending again
+	dispatcher.Schedule([]integration.Config{config})
+	assert.Empty(t, dispatcher.store.pendingSince)
+
+	// Configs removed before their dispatching are forgotten
+	other, _ := generatePinnedIntegration("http_check", "url: http://2", "nodeB")
+	other.ClusterCheck = true
+	dispatcher.Schedule([]integration.Config{other})
+	assert.Len(t, dispatcher.store.pendingSince, 1)
+	dispatcher.Unschedule([]integration.Config{other})
+	assert.Empty(t, dispatcher.store.pendingSince)
+
+	requireNotLocked(t, dispatcher.store)
+}
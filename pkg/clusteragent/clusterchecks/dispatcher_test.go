@@ -262,6 +262,40 @@ func TestGetLeastBusyNode(t *testing.T) {
 	requireNotLocked(t, dispatcher.store)
 }
 
+func TestGetLeastBusyNodeCapacity(t *testing.T) {
+	dispatcher := newDispatcher()
+
+	// node2 has twice the default capacity, node1 does not report any
+	dispatcher.processNodeStatus("node1", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("node2", "10.0.0.2", types.NodeStatus{Capacity: 2 * defaultCapacity})
+
+	// 2 configs on node1, 3 on node2
+	dispatcher.addConfig(generateIntegration("A"), "node1")
+	dispatcher.addConfig(generateIntegration("B"), "node1")
+	dispatcher.addConfig(generateIntegration("C"), "node2")
+	dispatcher.addConfig(generateIntegration("D"), "node2")
+	dispatcher.addConfig(generateIntegration("E"), "node2")
+	assert.Equal(t, "node2", dispatcher.getLeastBusyNode())
+
+	// Busyness values are weighted as well
+	dispatcher.advancedDispatching = true
+	dispatcher.store.nodes["node1"].busyness = 100
+	dispatcher.store.nodes["node2"].busyness = 150
+	assert.Equal(t, "node2", dispatcher.getLeastBusyNode())
+	dispatcher.store.nodes["node2"].busyness = 250
+	assert.Equal(t, "node1", dispatcher.getLeastBusyNode())
+
+	state, err := dispatcher.getState()
+	assert.NoError(t, err)
+	for _, node := range state.Nodes {
+		if node.Name == "node2" {
+			assert.Equal(t, 2*defaultCapacity, node.Capacity)
+		}
+	}
+
+	requireNotLocked(t, dispatcher.store)
+}
+
 func TestExpireNodes(t *testing.T) {
 	dispatcher := newDispatcher()
 
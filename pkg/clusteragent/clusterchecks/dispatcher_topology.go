@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// getNodeToDispatch returns the name of the node a configuration is dispatched
+// to: the node it is pinned to, or else the node it was dispatched to by the
+// previous leader, or else the least busy node among the nodes of its runner
+// pool satisfying its topology constraint.
+func (d *dispatcher) getNodeToDispatch(config integration.Config) string {
+	nodeName, candidates := d.selectNode(config)
+	if candidates == nil {
+		return nodeName
+	}
+	// The placement extender is consulted without holding the store lock
+	return d.extendPlacement(config, nodeName, candidates)
+}
+
+// selectNode returns the node a configuration should be dispatched to, and
+// the candidate nodes to submit to the placement extender, if any.
+func (d *dispatcher) selectNode(config integration.Config) (string, []types.ExtenderNode) {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	if nodeName := d.store.pinnedNode(config.Digest(), config); nodeName != "" {
+		if _, found := d.store.getNodeStore(nodeName); !found {
+			// Pinned configurations are kept dangling until their node reports
+			log.Debugf("Node %s pinned for %s:%s is not reporting", nodeName, config.Name, config.Digest())
+			return "", nil
+		}
+		return nodeName, nil
+	}
+
+	pool := d.configPool(config)
+	if nodeName, found := d.store.restoredNodes[config.Digest()]; found {
+		if node, found := d.store.getNodeStore(nodeName); found && !d.store.isDraining(nodeName) && node.pool(d.poolLabel) == pool {
+			return nodeName, nil
+		}
+	}
+
+	nodes := d.candidateNodes(config)
+	nodeName := d.leastBusyNode(nodes)
+	if d.extender == nil {
+		return nodeName, nil
+	}
+	return nodeName, d.extenderNodes(nodes, nodeName)
+}
+
+// candidateNodes returns the nodes a configuration can be dispatched to: the
+// nodes of its runner pool, local unless it is federated, satisfying its
+// topology constraint.
+// The store lock must be held by the caller.
+func (d *dispatcher) candidateNodes(config integration.Config) map[string]*nodeStore {
+	nodes := d.federationNodes(config, d.poolNodes(d.configPool(config)))
+	if !config.Topology.IsEmpty() {
+		nodes = d.topologyCandidates(config, nodes)
+	}
+	return nodes
+}
+
+// topologyCandidates returns the nodes satisfying the topology constraint of a
+// configuration among the given nodes. The constraint is best-effort: when no
+// node is reporting in the requested zone, or in any zone when spreading, all
+// nodes are returned so that the check still runs.
+// The store lock must be held by the caller.
+func (d *dispatcher) topologyCandidates(config integration.Config, nodes map[string]*nodeStore) map[string]*nodeStore {
+	zones := d.nodesByZone(nodes)
+
+	if config.Topology.Zone != "" {
+		if zoneNodes, found := zones[config.Topology.Zone]; found {
+			return zoneNodes
+		}
+		log.Debugf("No node reporting in zone %s, dispatching %s:%s outside of it", config.Topology.Zone, config.Name, config.Digest())
+		return nodes
+	}
+
+	// Spread: select the zones running the fewest configurations of the check
+	candidates := make(map[string]*nodeStore)
+	minCount := -1
+	for _, zoneNodes := range zones {
+		count := 0
+		for _, node := range zoneNodes {
+			for _, c := range node.digestToConfig {
+				if c.Name == config.Name {
+					count++
+				}
+			}
+		}
+		if minCount == -1 || count < minCount {
+			minCount = count
+			candidates = make(map[string]*nodeStore)
+		}
+		if count == minCount {
+			for name, node := range zoneNodes {
+				candidates[name] = node
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		log.Debugf("No node reporting a zone, dispatching %s:%s without spreading it", config.Name, config.Digest())
+		return nodes
+	}
+	return candidates
+}
+
+// nodesByZone groups the given nodes by the zone they report. The nodes not
+// reporting any zone and the draining nodes are ignored.
+// The store lock must be held by the caller.
+func (d *dispatcher) nodesByZone(nodes map[string]*nodeStore) map[string]map[string]*nodeStore {
+	zones := make(map[string]map[string]*nodeStore)
+	for name, node := range nodes {
+		if name == "" || d.store.isDraining(name) {
+			continue
+		}
+		zone := node.zone(d.zoneLabel)
+		if zone == "" {
+			continue
+		}
+		if zones[zone] == nil {
+			zones[zone] = make(map[string]*nodeStore)
+		}
+		zones[zone][name] = node
+	}
+	return zones
+}
+
+// filterByTopology restricts the destination nodes of a check being rebalanced
+// to the zone of its source node when its configuration has a topology
+// constraint, so that rebalancing does not break it.
+func (d *dispatcher) filterByTopology(diffMap map[string]int, sourceNodeName, checkID string) map[string]int {
+	config, _ := d.getConfigAndDigest(checkID)
+	if config.Topology.IsEmpty() {
+		return diffMap
+	}
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	zoneOf := func(nodeName string) string {
+		node, found := d.store.getNodeStore(nodeName)
+		if !found {
+			return ""
+		}
+		return node.zone(d.zoneLabel)
+	}
+
+	sourceZone := zoneOf(sourceNodeName)
+	filtered := make(map[string]int)
+	for nodeName, diff := range diffMap {
+		if zoneOf(nodeName) == sourceZone {
+			filtered[nodeName] = diff
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+const zoneLabel = "topology.kubernetes.io/zone"
+
+func zoneStatus(zone string) types.NodeStatus {
+	return types.NodeStatus{Labels: map[string]string{zoneLabel: zone}}
+}
+
+func generateTopologyIntegration(name string, topology integration.TopologyConstraint) integration.Config {
+	config := generateIntegration(name)
+	config.Topology = topology
+	return config
+}
+
+func TestGetNodeToDispatchZone(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", zoneStatus("zone-a"))
+	dispatcher.processNodeStatus("nodeB1", "10.0.0.2", zoneStatus("zone-b"))
+	dispatcher.processNodeStatus("nodeB2", "10.0.0.3", zoneStatus("zone-b"))
+
+	// nodeA is the least busy node, but not in the zone
+	dispatcher.addConfig(generateIntegration("A"), "nodeB1")
+	dispatcher.addConfig(generateIntegration("B"), "nodeB2")
+	dispatcher.addConfig(generateIntegration("C"), "nodeB2")
+	assert.Equal(t, "nodeA", dispatcher.getNodeToDispatch(generateIntegration("D")))
+	assert.Equal(t, "nodeB1", dispatcher.getNodeToDispatch(generateTopologyIntegration("D", integration.TopologyConstraint{Zone: "zone-b"})))
+
+	// No node in the zone, fallback on the least busy node
+	assert.Equal(t, "nodeA", dispatcher.getNodeToDispatch(generateTopologyIntegration("D", integration.TopologyConstraint{Zone: "zone-c"})))
+
+	// The zone takes precedence over the spread
+	assert.Equal(t, "nodeB1", dispatcher.getNodeToDispatch(generateTopologyIntegration("D", integration.TopologyConstraint{Zone: "zone-b", Spread: true})))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestGetNodeToDispatchSpread(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA1", "10.0.0.1", zoneStatus("zone-a"))
+	dispatcher.processNodeStatus("nodeA2", "10.0.0.2", zoneStatus("zone-a"))
+	dispatcher.processNodeStatus("nodeB", "10.0.0.3", zoneStatus("zone-b"))
+	spread := integration.TopologyConstraint{Spread: true}
+
+	// Spread the configurations of the check across the zones
+	for _, url := range []string{"http://one", "http://two"} {
+		config := generateTopologyIntegration("http_check", spread)
+		config.Instances = []integration.Data{integration.Data("url: " + url)}
+		dispatcher.add(config)
+	}
+	zones := map[string]int{}
+	for _, name := range []string{"nodeA1", "nodeA2", "nodeB"} {
+		configs, _, err := dispatcher.getClusterCheckConfigs(name)
+		assert.NoError(t, err)
+		zones[dispatcher.store.nodes[name].zone(zoneLabel)] += len(configs)
+	}
+	assert.Equal(t, map[string]int{"zone-a": 1, "zone-b": 1}, zones)
+
+	// Only the configurations of the same check are counted
+	dispatcher.addConfig(generateIntegration("other1"), "nodeB")
+	dispatcher.addConfig(generateIntegration("other2"), "nodeB")
+	dispatcher.addConfig(generateTopologyIntegration("redisdb", spread), "nodeA1")
+	assert.Equal(t, "nodeB", dispatcher.getNodeToDispatch(generateTopologyIntegration("redisdb", spread)))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestGetNodeToDispatchSpreadWithoutZone(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", zoneStatus("zone-a"))
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", zoneStatus("zone-b"))
+	dispatcher.processNodeStatus("nodeNoZone", "10.0.0.3", types.NodeStatus{})
+	spread := integration.TopologyConstraint{Spread: true}
+
+	// The nodes not reporting a zone are not a zone of their own
+	for node, host := range map[string]string{"nodeA": "one", "nodeB": "two"} {
+		config := generateTopologyIntegration("redisdb", spread)
+		config.Instances = []integration.Data{integration.Data("host: " + host)}
+		dispatcher.addConfig(config, node)
+	}
+	dispatcher.addConfig(generateIntegration("other"), "nodeA")
+	assert.Equal(t, "nodeB", dispatcher.getNodeToDispatch(generateTopologyIntegration("redisdb", spread)))
+
+	// They are used when no node reports a zone
+	dispatcher = newDispatcher()
+	dispatcher.processNodeStatus("nodeNoZone", "10.0.0.3", types.NodeStatus{})
+	assert.Equal(t, "nodeNoZone", dispatcher.getNodeToDispatch(generateTopologyIntegration("redisdb", spread)))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestRebalanceTopology(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.store.active = true
+	dispatcher.processNodeStatus("nodeA1", "10.0.0.1", zoneStatus("zone-a"))
+	dispatcher.processNodeStatus("nodeA2", "10.0.0.2", zoneStatus("zone-a"))
+	dispatcher.processNodeStatus("nodeB", "10.0.0.3", zoneStatus("zone-b"))
+
+	config := generateTopologyIntegration("zoned", integration.TopologyConstraint{Zone: "zone-a"})
+	config.Instances = []integration.Data{integration.Data("")}
+	id := check.BuildID(config.Name, config.Instances[0], config.InitConfig)
+	dispatcher.addConfig(config, "nodeA1")
+
+	// nodeB is the least busy node, but the check is kept in its zone
+	filtered := dispatcher.filterByTopology(map[string]int{"nodeA1": 50, "nodeA2": 0, "nodeB": -50}, "nodeA1", string(id))
+	assert.Equal(t, map[string]int{"nodeA1": 50, "nodeA2": 0}, filtered)
+	assert.Equal(t, "nodeA2", pickNode(filtered, "nodeA1"))
+
+	// Unconstrained checks can move to any zone
+	unfiltered := dispatcher.filterByTopology(map[string]int{"nodeA1": 50, "nodeA2": 0, "nodeB": -50}, "nodeA1", "unknown")
+	assert.Equal(t, "nodeB", pickNode(unfiltered, "nodeA1"))
+
+	requireNotLocked(t, dispatcher.store)
+}
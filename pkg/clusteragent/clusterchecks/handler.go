@@ -11,6 +11,8 @@ package clusterchecks
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -19,11 +21,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 const (
-	schedulerName = "clusterchecks"
+	schedulerName           = "clusterchecks"
+	defaultLeaderStatusFreq = 5 * time.Second
 )
 
 type state int
@@ -46,13 +50,21 @@ type Handler struct {
 	autoconfig           pluggableAutoConfig
 	dispatcher           *dispatcher
 	leaderStatusFreq     time.Duration
+	leaderStatusJitter   float64
 	warmupDuration       time.Duration
 	leaderStatusCallback types.LeaderIPCallback
+	leaderChangeChan     <-chan struct{}
+	releaseLeadership    func()
+	leaderStateClient    clusteragent.LeaderStateClientInterface
+	followerSyncFreq     time.Duration
 	leadershipChan       chan state
 	m                    sync.RWMutex // Below fields protected by the mutex
 	state                state
 	leaderIP             string
 	port                 int
+	replicatedState      *types.DispatchingState
+	replicatedAt         int64
+	lastLeaderState      *types.StateResponse
 }
 
 // NewHandler returns a populated Handler
@@ -62,20 +74,39 @@ func NewHandler(ac pluggableAutoConfig) (*Handler, error) {
 		return nil, errors.New("empty autoconfig object")
 	}
 	h := &Handler{
-		autoconfig:       ac,
-		leaderStatusFreq: 5 * time.Second,
-		warmupDuration:   config.Datadog.GetDuration("cluster_checks.warmup_duration") * time.Second,
-		leadershipChan:   make(chan state, 1),
-		dispatcher:       newDispatcher(),
-		port:             config.Datadog.GetInt("cluster_agent.cmd_port"),
+		autoconfig:         ac,
+		leaderStatusFreq:   config.Datadog.GetDuration("cluster_checks.leader_status_frequency") * time.Second,
+		leaderStatusJitter: config.Datadog.GetFloat64("cluster_checks.leader_status_jitter"),
+		warmupDuration:     config.Datadog.GetDuration("cluster_checks.warmup_duration") * time.Second,
+		leadershipChan:     make(chan state, 1),
+		dispatcher:         newDispatcher(),
+		port:               config.Datadog.GetInt("cluster_agent.cmd_port"),
+	}
+	if h.leaderStatusFreq <= 0 {
+		h.leaderStatusFreq = defaultLeaderStatusFreq
 	}
 
 	if config.Datadog.GetBool("leader_election") {
-		callback, err := getLeaderIPCallback()
+		backend, err := getLeaderElectionBackend()
 		if err != nil {
 			return nil, err
 		}
-		h.leaderStatusCallback = callback
+		backend.Start()
+		h.leaderStatusCallback = backend.GetLeaderIP
+		if watcher, ok := backend.(leaderElectionWatcher); ok {
+			h.leaderChangeChan = watcher.WatchLeader()
+		}
+
+		if config.Datadog.GetBool("cluster_checks.handover_on_shutdown") {
+			h.releaseLeadership = backend.ReleaseLeadership
+		}
+
+		h.followerSyncFreq = config.Datadog.GetDuration("cluster_checks.follower_sync_interval") * time.Second
+		h.leaderStateClient, err = clusteragent.GetLeaderStateClient()
+		if err != nil {
+			log.Warnf("Cannot create the leader state client, the followers will not replicate nor serve the dispatching state: %v", err)
+			h.leaderStateClient = nil
+		}
 	}
 
 	// Cache a pointer to the handler for the agent status command
@@ -110,8 +141,14 @@ func (h *Handler) Run(ctx context.Context) {
 			}
 		}
 
-		// Leading, start warmup
-		log.Infof("Becoming leader, waiting %s for node-agents to report", h.warmupDuration)
+		// Leading, start warmup unless the state of the previous leader is restored
+		warmupDuration := h.warmupDuration
+		if h.restoreState() {
+			log.Info("Becoming leader with the state of the previous leader, skipping warmup")
+			warmupDuration = 0
+		} else {
+			log.Infof("Becoming leader, waiting %s for node-agents to report", h.warmupDuration)
+		}
 		select {
 		case <-ctx.Done():
 			return
@@ -119,7 +156,7 @@ func (h *Handler) Run(ctx context.Context) {
 			if newState != leader {
 				continue
 			}
-		case <-time.After(h.warmupDuration):
+		case <-time.After(warmupDuration):
 			break
 		}
 
@@ -154,6 +191,9 @@ func (h *Handler) runDispatch(ctx context.Context) {
 	// Register our scheduler and ask for a config replay
 	h.autoconfig.AddScheduler(schedulerName, h.dispatcher, true)
 
+	// The configs restored from the previous leader but not replayed were removed
+	h.dispatcher.pruneRestoredNodes()
+
 	// Run dispatcher loop - blocking until context is cancelled
 	h.dispatcher.run(ctx)
 
@@ -162,6 +202,72 @@ func (h *Handler) runDispatch(ctx context.Context) {
 	h.autoconfig.RemoveScheduler(schedulerName)
 }
 
+// restoreState restores the state handed over by the previous leader, or else
+// the state replicated from it as a follower. It returns true if the nodes are
+// restored and the warmup can be skipped.
+func (h *Handler) restoreState() bool {
+	if h.dispatcher.restoreState() {
+		return true
+	}
+
+	h.m.Lock()
+	replicated, replicatedAt := h.replicatedState, h.replicatedAt
+	h.replicatedState = nil
+	h.m.Unlock()
+
+	if replicated == nil {
+		return false
+	}
+	return h.dispatcher.applyState(*replicated, replicatedAt)
+}
+
+// replicateLeaderState pulls the dispatching state of the leader, for a
+// follower to restore it when it becomes leader.
+func (h *Handler) replicateLeaderState() {
+	h.m.RLock()
+	isFollower := h.state == follower
+	leaderAddr := fmt.Sprintf("%s:%d", h.leaderIP, h.port)
+	h.m.RUnlock()
+	if !isFollower {
+		return
+	}
+
+	state, err := h.leaderStateClient.GetDispatchingState(leaderAddr)
+	if err != nil {
+		log.Debugf("Cannot replicate the dispatching state of the leader %s: %v", leaderAddr, err)
+		return
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.replicatedState = &state
+	h.replicatedAt = timestampNow()
+}
+
+// Handover hands the dispatching over to the next leader when the cluster-agent
+// shuts down: the leader saves a snapshot of the dispatching state for the next
+// leader to restore it without warmup, then releases the leadership instead of
+// waiting for the lease to expire.
+func (h *Handler) Handover() {
+	if h.releaseLeadership == nil {
+		return
+	}
+
+	h.m.RLock()
+	isLeader := h.state == leader
+	h.m.RUnlock()
+	if !isLeader {
+		return
+	}
+
+	if err := h.dispatcher.saveHandoverState(); err != nil {
+		log.Warnf("Cannot save the dispatching state for the next leader: %v", err)
+	}
+	log.Info("Releasing the leadership to hand the cluster checks dispatching over")
+	h.releaseLeadership()
+}
+
 func (h *Handler) leaderWatch(ctx context.Context) {
 	err := h.updateLeaderIP()
 	if err != nil {
@@ -171,24 +277,51 @@ func (h *Handler) leaderWatch(ctx context.Context) {
 	healthProbe := health.RegisterLiveness("clusterchecks-leadership")
 	defer health.Deregister(healthProbe) //nolint:errcheck
 
-	watchTicker := time.NewTicker(h.leaderStatusFreq)
-	defer watchTicker.Stop()
+	watchTimer := time.NewTimer(h.nextLeaderStatusPoll())
+	defer watchTimer.Stop()
+
+	// Replicate the dispatching state of the leader while following
+	var syncTickerChan <-chan time.Time
+	if h.leaderStateClient != nil && h.followerSyncFreq > 0 {
+		syncTicker := time.NewTicker(h.followerSyncFreq)
+		defer syncTicker.Stop()
+		syncTickerChan = syncTicker.C
+	}
 
 	for {
 		select {
 		case <-healthProbe.C:
 			// This goroutine might hang if the leader election engine blocks
-		case <-watchTicker.C:
+		case <-watchTimer.C:
 			err := h.updateLeaderIP()
 			if err != nil {
 				log.Warnf("Could not refresh leadership status: %s", err)
 			}
+			watchTimer.Reset(h.nextLeaderStatusPoll())
+		case <-h.leaderChangeChan:
+			// Nil if the leader election backend does not notify the leader changes
+			err := h.updateLeaderIP()
+			if err != nil {
+				log.Warnf("Could not refresh leadership status: %s", err)
+			}
+		case <-syncTickerChan:
+			h.replicateLeaderState()
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// nextLeaderStatusPoll returns the delay before the next leadership status
+// poll: leaderStatusFreq plus a random jitter of up to leaderStatusJitter
+// times leaderStatusFreq, for the cluster-agents not to poll in sync.
+func (h *Handler) nextLeaderStatusPoll() time.Duration {
+	if h.leaderStatusJitter <= 0 {
+		return h.leaderStatusFreq
+	}
+	return h.leaderStatusFreq + time.Duration(rand.Float64()*h.leaderStatusJitter*float64(h.leaderStatusFreq))
+}
+
 // updateLeaderIP queries the leader election engine and updates
 // the leader IP accordlingly. In case of leadership statuschange,
 // a state type is sent on leadershipChan.
@@ -220,7 +220,7 @@ func TestHandlerRun(t *testing.T) {
 	h.dispatcher.Schedule([]integration.Config{testConfig})
 	testutil.AssertTrueBeforeTimeout(t, 10*time.Millisecond, 1*time.Second, func() bool {
 		// Found one configuration for node dummy
-		configs, err := h.GetConfigs("dummy")
+		configs, err := h.GetConfigs("dummy", "")
 		return err == nil && len(configs.Configs) == 1
 	})
 	testutil.AssertTrueBeforeTimeout(t, 10*time.Millisecond, 1*time.Second, func() bool {
@@ -291,3 +291,164 @@ func TestHandlerRun(t *testing.T) {
 		return ac.AssertNumberOfCalls(dummyT, "RemoveScheduler", 2)
 	})
 }
+
+func TestHandover(t *testing.T) {
+	store := &memoryStateStore{}
+	released := 0
+	h := &Handler{
+		dispatcher:        newDispatcher(),
+		releaseLeadership: func() { released++ },
+	}
+	h.dispatcher.stateStore = store
+	h.dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	config := generateIntegration("unit_test")
+	h.dispatcher.add(config)
+
+	// Followers have nothing to hand over
+	h.state = follower
+	h.Handover()
+	assert.Equal(t, 0, released)
+
+	h.state = leader
+	h.Handover()
+	assert.Equal(t, 1, released)
+	assert.NotZero(t, store.state.HandoverTime)
+	assert.Contains(t, store.state.Nodes, "nodeA")
+
+	// The snapshot is not overwritten afterwards
+	h.dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	h.dispatcher.persistState()
+	assert.Equal(t, 1, store.saves)
+
+	// The next leader restores the nodes and skips the warmup
+	next := newDispatcher()
+	next.stateStore = store
+	assert.True(t, next.restoreState())
+	assert.Equal(t, "nodeA", next.getNodeToDispatch(config))
+
+	// Stale snapshots are not handovers
+	store.state.HandoverTime -= next.nodeExpirationSeconds
+	stale := newDispatcher()
+	stale.stateStore = store
+	assert.False(t, stale.restoreState())
+	assert.Empty(t, stale.store.nodes)
+}
+
+type fakeLeaderStateClient struct {
+	state         types.DispatchingState
+	stateResponse types.StateResponse
+	err           error
+	addrs         []string
+}
+
+func (c *fakeLeaderStateClient) GetDispatchingState(leaderAddr string) (types.DispatchingState, error) {
+	c.addrs = append(c.addrs, leaderAddr)
+	return c.state, nil
+}
+
+func (c *fakeLeaderStateClient) GetState(leaderAddr string) (types.StateResponse, error) {
+	c.addrs = append(c.addrs, leaderAddr)
+	return c.stateResponse, c.err
+}
+
+func TestReplicateLeaderState(t *testing.T) {
+	leaderDispatcher := newDispatcher()
+	leaderDispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	config := generateIntegration("unit_test")
+	leaderDispatcher.add(config)
+
+	client := &fakeLeaderStateClient{state: leaderDispatcher.getSnapshot()}
+	h := &Handler{
+		dispatcher:        newDispatcher(),
+		leaderStateClient: client,
+		port:              5005,
+	}
+
+	// Only followers replicate the state of the leader
+	h.state = leader
+	h.replicateLeaderState()
+	assert.Empty(t, client.addrs)
+
+	h.state = follower
+	h.leaderIP = "10.0.0.10"
+	h.replicateLeaderState()
+	assert.Equal(t, []string{"10.0.0.10:5005"}, client.addrs)
+
+	// The promoted follower restores the nodes and skips the warmup
+	assert.True(t, h.restoreState())
+	assert.Equal(t, "nodeA", h.dispatcher.getNodeToDispatch(config))
+	assert.Nil(t, h.replicatedState)
+
+	// Stale replicated states are not enough to skip the warmup
+	h.replicateLeaderState()
+	h.replicatedAt -= h.dispatcher.nodeExpirationSeconds
+	h.dispatcher.reset()
+	assert.False(t, h.restoreState())
+	assert.Empty(t, h.dispatcher.store.nodes)
+	assert.Equal(t, "nodeA", h.dispatcher.store.restoredNodes[config.Digest()])
+
+	requireNotLocked(t, h.dispatcher.store)
+}
+
+func TestLeaderWatchNotification(t *testing.T) {
+	le := &fakeLeaderEngine{}
+	le.set("1.2.3.4", nil)
+	changes := make(chan struct{}, 1)
+
+	h := &Handler{
+		leaderStatusFreq:     time.Hour,
+		leaderStatusJitter:   0.2,
+		leadershipChan:       make(chan state, 1),
+		leaderStatusCallback: le.get,
+		leaderChangeChan:     changes,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.leaderWatch(ctx)
+	h.assertLeadershipMessage(t, follower)
+
+	// Leader changes are propagated without waiting for the next poll
+	le.set("", nil)
+	changes <- struct{}{}
+	h.assertLeadershipMessage(t, leader)
+
+	// Polls are delayed by up to the jitter
+	for i := 0; i < 10; i++ {
+		delay := h.nextLeaderStatusPoll()
+		assert.GreaterOrEqual(t, delay, time.Hour)
+		assert.LessOrEqual(t, delay, time.Hour+12*time.Minute)
+	}
+}
+
+func TestFollowerGetState(t *testing.T) {
+	client := &fakeLeaderStateClient{err: errors.New("unreachable")}
+	h := &Handler{
+		dispatcher:        newDispatcher(),
+		leaderStateClient: client,
+		state:             follower,
+		leaderIP:          "10.0.0.10",
+		port:              5005,
+	}
+
+	// Nothing to serve until the leader is reached
+	state, err := h.GetState()
+	assert.NoError(t, err)
+	assert.Contains(t, state.NotRunning, "cannot reach the leader")
+
+	// The state of the leader is served read-only
+	client.err = nil
+	client.stateResponse = types.StateResponse{Nodes: []types.StateNodeResponse{{Name: "nodeA"}}}
+	state, err = h.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, "", state.NotRunning)
+	assert.Equal(t, "10.0.0.10:5005", state.LeaderAddress)
+	assert.NotZero(t, state.Timestamp)
+	assert.Len(t, state.Nodes, 1)
+
+	// The last state fetched is served when the leader cannot be reached
+	client.err = errors.New("unreachable")
+	stale, err := h.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state, stale)
+	assert.Equal(t, []string{"10.0.0.10:5005", "10.0.0.10:5005", "10.0.0.10:5005"}, client.addrs)
+}
@@ -37,22 +37,32 @@ func timestampNow() int64 {
 	return time.Now().Unix()
 }
 
-// calculateBusyness returns the busyness value of a node
-func calculateBusyness(checkStats types.CLCRunnersStats) int {
+// calculateBusyness returns the busyness value of a node, weighting its
+// checks with the given cost function
+func calculateBusyness(checkStats types.CLCRunnersStats, costFunc costFunction) int {
 	busyness := 0
 	for _, stats := range checkStats {
-		busyness += busynessFunc(stats)
+		busyness += costFunc(stats)
 	}
 	return busyness
 }
 
-// busynessFunc returns the weight of a check
+// busynessFunc returns the weight of a check with the default cost function
 func busynessFunc(s types.CLCRunnerStats) int {
-	if s.LastExecFailed {
-		// The check is failing, its weight is 0
-		return 0
+	return defaultCostWeights.cost(s)
+}
+
+// applyCheckDurations sets the average execution time of the checks to the
+// mean of the durations of their recent runs reported by the runner, fresher
+// than the ones collected from the CLC runners API. All the checks reported are
+// cluster checks.
+func applyCheckDurations(checkStats types.CLCRunnersStats, durations map[string]types.DurationHistogram) {
+	for id, histogram := range durations {
+		stats := checkStats[id]
+		stats.AverageExecutionTime = int(histogram.Mean())
+		stats.IsClusterCheck = true
+		checkStats[id] = stats
 	}
-	return int(checkExecutionTimeWeight*float64(s.AverageExecutionTime) + checkMetricSamplesWeight*float64(s.MetricSamples))
 }
 
 // orderedKeys sorts the keys of a map and return them in a slice
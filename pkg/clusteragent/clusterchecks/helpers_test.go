@@ -95,7 +95,7 @@ func Test_calculateBusyness(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := calculateBusyness(tt.stats); got != tt.want {
+			if got := calculateBusyness(tt.stats, busynessFunc); got != tt.want {
 				t.Errorf("calculateBusyness() = %v, want %v", got, tt.want)
 			}
 		})
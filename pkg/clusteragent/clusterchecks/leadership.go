@@ -0,0 +1,295 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const defaultLeaderElectionBackend = "kubernetes"
+
+// leaderElectionBackend elects the cluster-agent dispatching the cluster
+// checks among the cluster-agents
+type leaderElectionBackend interface {
+	// Start starts campaigning for the leadership in the background
+	Start()
+	// GetLeaderIP returns the IP of the leader, or an empty string if the
+	// local cluster-agent is the leader
+	GetLeaderIP() (string, error)
+	// ReleaseLeadership stops campaigning and releases the leadership if held
+	ReleaseLeadership()
+}
+
+// leaderElectionWatcher is implemented by the backends notifying the leader
+// changes, for the Handler to refresh the leader right away instead of
+// waiting for the next poll
+type leaderElectionWatcher interface {
+	WatchLeader() <-chan struct{}
+}
+
+// leaderNotifier implements leaderElectionWatcher, coalescing the
+// notifications the Handler did not process yet
+type leaderNotifier struct {
+	c chan struct{}
+}
+
+func newLeaderNotifier() leaderNotifier {
+	return leaderNotifier{c: make(chan struct{}, 1)}
+}
+
+// notify notifies a leader change without blocking
+func (n leaderNotifier) notify() {
+	select {
+	case n.c <- struct{}{}:
+	default:
+	}
+}
+
+// WatchLeader implements the leaderElectionWatcher interface
+func (n leaderNotifier) WatchLeader() <-chan struct{} {
+	return n.c
+}
+
+type leaderElectionBackendFactory func() (leaderElectionBackend, error)
+
+// leaderElectionBackends holds the backends compiled in, by name
+var leaderElectionBackends = make(map[string]leaderElectionBackendFactory)
+
+// registerLeaderElectionBackend registers a backend selectable with the
+// cluster_checks.leader_election.backend option
+func registerLeaderElectionBackend(name string, factory leaderElectionBackendFactory) {
+	leaderElectionBackends[name] = factory
+}
+
+// getLeaderElectionBackend returns the backend selected in the configuration
+func getLeaderElectionBackend() (leaderElectionBackend, error) {
+	name := config.Datadog.GetString("cluster_checks.leader_election.backend")
+	if name == "" {
+		name = defaultLeaderElectionBackend
+	}
+
+	factory, found := leaderElectionBackends[name]
+	if !found {
+		available := make([]string, 0, len(leaderElectionBackends))
+		for backend := range leaderElectionBackends {
+			available = append(available, backend)
+		}
+		sort.Strings(available)
+		return nil, fmt.Errorf("leader election backend %q is not compiled in, available backends: %v", name, available)
+	}
+
+	log.Infof("Using the %s leader election backend for the cluster checks", name)
+	return factory()
+}
+
+// getAdvertisedAddress returns the IP the leader advertises to the other
+// cluster-agents with the lock based backends: the configured one, or else
+// the first non-loopback IP of the host.
+func getAdvertisedAddress() (string, error) {
+	if address := config.Datadog.GetString("cluster_checks.leader_election.advertise_address"); address != "" {
+		return address, nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", errors.New("cannot find the IP to advertise, set cluster_checks.leader_election.advertise_address")
+}
+
+// getCandidateIdentity returns an identity unique to the local cluster-agent,
+// as several candidates can share the advertised IP (same host, host network)
+func getCandidateIdentity() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix)), nil
+}
+
+// lockHolder is the value stored in the leaderLock by its holder
+type lockHolder struct {
+	Identity string `json:"identity"`
+	Address  string `json:"address"`
+}
+
+// encode returns the value to store in the leaderLock
+func (h lockHolder) encode() string {
+	value, _ := json.Marshal(h)
+	return string(value)
+}
+
+// decodeLockHolder parses the value of a leaderLock. The bare IPs stored by
+// the previous versions are used as both identity and address.
+func decodeLockHolder(value string) lockHolder {
+	var holder lockHolder
+	if err := json.Unmarshal([]byte(value), &holder); err != nil || holder.Identity == "" {
+		return lockHolder{Identity: value, Address: value}
+	}
+	return holder
+}
+
+// leaderLock is a lock held by the leader, expiring after a TTL unless renewed
+type leaderLock interface {
+	// tryLock acquires or renews the lock for the given value, and returns
+	// the value of its holder
+	tryLock(value string) (string, error)
+	// unlock releases the lock if it is held by the given value
+	unlock(value string) error
+}
+
+// lockBackend is a leaderElectionBackend campaigning by periodically trying
+// to acquire a leaderLock, identified by a unique identity and advertising
+// its IP to the followers
+type lockBackend struct {
+	leaderNotifier
+	lock   leaderLock
+	self   lockHolder
+	period time.Duration
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	m      sync.RWMutex // Below fields protected by the mutex
+	holder lockHolder
+	err    error
+}
+
+// newLockBackend returns a lockBackend, renewing the lock every quarter of
+// the leader lease duration
+func newLockBackend(lock leaderLock) (*lockBackend, error) {
+	address, err := getAdvertisedAddress()
+	if err != nil {
+		return nil, err
+	}
+	identity, err := getCandidateIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	return &lockBackend{
+		leaderNotifier: newLeaderNotifier(),
+		lock:           lock,
+		self:           lockHolder{Identity: identity, Address: address},
+		period:         getLeaderLeaseDuration() / 4,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+		err:            errors.New("leader election not started yet"),
+	}, nil
+}
+
+// Start implements the leaderElectionBackend interface
+func (b *lockBackend) Start() {
+	b.once.Do(func() {
+		go b.run()
+	})
+}
+
+func (b *lockBackend) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.period)
+	defer ticker.Stop()
+
+	for {
+		b.campaign()
+
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign tries to acquire the lock once and stores its holder
+func (b *lockBackend) campaign() {
+	value, err := b.lock.tryLock(b.self.encode())
+	if err == nil && value == "" {
+		err = errors.New("no leader elected yet")
+	}
+
+	var holder lockHolder
+	if err == nil {
+		holder = decodeLockHolder(value)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if err == nil && holder != b.holder {
+		log.Infof("New cluster checks leader %q at %s", holder.Identity, holder.Address)
+		b.notify()
+	}
+	b.holder, b.err = holder, err
+}
+
+// GetLeaderIP implements the leaderElectionBackend interface
+func (b *lockBackend) GetLeaderIP() (string, error) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	if b.err != nil {
+		return "", b.err
+	}
+	if b.holder.Identity == b.self.Identity {
+		return "", nil
+	}
+	return b.holder.Address, nil
+}
+
+// ReleaseLeadership implements the leaderElectionBackend interface
+func (b *lockBackend) ReleaseLeadership() {
+	started := true
+	b.once.Do(func() { started = false })
+	if !started {
+		return
+	}
+
+	close(b.stop)
+	<-b.done
+
+	if err := b.lock.unlock(b.self.encode()); err != nil {
+		log.Warnf("Cannot release the cluster checks leadership: %v", err)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.holder, b.err = lockHolder{}, errors.New("leader election stopped")
+}
+
+// getLeaderLeaseDuration returns the duration after which the lock of a
+// leader that stopped renewing it expires
+func getLeaderLeaseDuration() time.Duration {
+	if duration := config.Datadog.GetInt("leader_lease_duration"); duration > 0 {
+		return time.Duration(duration) * time.Second
+	}
+	return 60 * time.Second
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks && consul
+// +build clusterchecks,consul
+
+package clusterchecks
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+
+	consul "github.com/hashicorp/consul/api"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func init() {
+	registerLeaderElectionBackend("consul", newConsulLeaderElectionBackend)
+}
+
+// consulLeaderLock is a leaderLock stored in a Consul key acquired with a
+// session expiring after the leader lease duration
+type consulLeaderLock struct {
+	client  *consul.Client
+	key     string
+	ttl     string
+	m       sync.Mutex
+	session string
+}
+
+func newConsulLeaderElectionBackend() (leaderElectionBackend, error) {
+	consulURL, err := url.Parse(config.Datadog.GetString("cluster_checks.leader_election.consul_url"))
+	if err != nil {
+		return nil, err
+	}
+
+	clientCfg := consul.DefaultConfig()
+	clientCfg.Address = consulURL.Host
+	clientCfg.Scheme = consulURL.Scheme
+	clientCfg.Token = config.Datadog.GetString("cluster_checks.leader_election.consul_token")
+
+	cli, err := consul.NewClient(clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to instantiate the consul client: %s", err)
+	}
+
+	return newLockBackend(&consulLeaderLock{
+		client: cli,
+		key:    config.Datadog.GetString("cluster_checks.leader_election.lock_name"),
+		ttl:    getLeaderLeaseDuration().String(),
+	})
+}
+
+// tryLock renews the session, creating it if needed, and acquires the key
+// with it. The key is released when the session expires.
+func (l *consulLeaderLock) tryLock(value string) (string, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if err := l.renewSession(); err != nil {
+		return "", err
+	}
+
+	acquired, _, err := l.client.KV().Acquire(&consul.KVPair{
+		Key:     l.key,
+		Value:   []byte(value),
+		Session: l.session,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	if acquired {
+		return value, nil
+	}
+
+	pair, _, err := l.client.KV().Get(l.key, nil)
+	if err != nil {
+		return "", err
+	}
+	if pair == nil || pair.Session == "" {
+		// Released since the acquisition attempt
+		return "", nil
+	}
+	return string(pair.Value), nil
+}
+
+// renewSession renews the session, or creates a new one if it expired
+func (l *consulLeaderLock) renewSession() error {
+	if l.session != "" {
+		entry, _, err := l.client.Session().Renew(l.session, nil)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			return nil
+		}
+	}
+
+	session, _, err := l.client.Session().Create(&consul.SessionEntry{
+		Name:     l.key,
+		TTL:      l.ttl,
+		Behavior: consul.SessionBehaviorRelease,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	l.session = session
+	return nil
+}
+
+// unlock releases the key and destroys the session
+func (l *consulLeaderLock) unlock(value string) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.session == "" {
+		return nil
+	}
+
+	_, _, err := l.client.KV().Release(&consul.KVPair{Key: l.key, Value: []byte(value), Session: l.session}, nil)
+	if err != nil {
+		return err
+	}
+	_, err = l.client.Session().Destroy(l.session, nil)
+	l.session = ""
+	return err
+}
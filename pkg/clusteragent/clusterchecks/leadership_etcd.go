@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks && etcd
+// +build clusterchecks,etcd
+
+package clusterchecks
+
+import (
+	"fmt"
+	"time"
+
+	"go.etcd.io/etcd/client/v2"
+	"golang.org/x/net/context"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+func init() {
+	registerLeaderElectionBackend("etcd", newEtcdLeaderElectionBackend)
+}
+
+// etcdLeaderLock is a leaderLock stored in an etcd key expiring after the
+// leader lease duration
+type etcdLeaderLock struct {
+	keys client.KeysAPI
+	key  string
+	ttl  time.Duration
+}
+
+func newEtcdLeaderElectionBackend() (leaderElectionBackend, error) {
+	clientCfg := client.Config{
+		Endpoints:               []string{config.Datadog.GetString("cluster_checks.leader_election.etcd_url")},
+		Transport:               client.DefaultTransport,
+		HeaderTimeoutPerRequest: time.Second,
+	}
+	username := config.Datadog.GetString("cluster_checks.leader_election.etcd_username")
+	password := config.Datadog.GetString("cluster_checks.leader_election.etcd_password")
+	if len(username) > 0 && len(password) > 0 {
+		log.Info("Using provided etcd credentials: username ", username)
+		clientCfg.Username = username
+		clientCfg.Password = password
+	}
+
+	cl, err := client.New(clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to instantiate the etcd client: %s", err)
+	}
+
+	return newLockBackend(&etcdLeaderLock{
+		keys: client.NewKeysAPI(cl),
+		key:  "/" + config.Datadog.GetString("cluster_checks.leader_election.lock_name"),
+		ttl:  getLeaderLeaseDuration(),
+	})
+}
+
+// tryLock creates the key if it does not exist, or refreshes its TTL if it
+// holds the value
+func (l *etcdLeaderLock) tryLock(value string) (string, error) {
+	ctx := context.Background()
+
+	_, err := l.keys.Set(ctx, l.key, value, &client.SetOptions{PrevExist: client.PrevNoExist, TTL: l.ttl})
+	if err == nil {
+		return value, nil
+	}
+	if !isEtcdError(err, client.ErrorCodeNodeExist) {
+		return "", err
+	}
+
+	resp, err := l.keys.Get(ctx, l.key, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.Node.Value != value {
+		return resp.Node.Value, nil
+	}
+
+	_, err = l.keys.Set(ctx, l.key, value, &client.SetOptions{PrevValue: value, TTL: l.ttl})
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// unlock deletes the key if it holds the value
+func (l *etcdLeaderLock) unlock(value string) error {
+	_, err := l.keys.Delete(context.Background(), l.key, &client.DeleteOptions{PrevValue: value})
+	return err
+}
+
+func isEtcdError(err error, code int) bool {
+	etcdErr, ok := err.(client.Error)
+	return ok && etcdErr.Code == code
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks && !windows
+// +build clusterchecks,!windows
+
+package clusterchecks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func init() {
+	registerLeaderElectionBackend("file", newFileLeaderElectionBackend)
+}
+
+// fileLeaderLock is a leaderLock relying on an advisory lock on a file shared
+// by the cluster-agents, on bare-metal hosts for instance. The lock is
+// released by the OS when the leader exits, and a sibling file holds the
+// value of the leader, replaced atomically for the followers never to read
+// a partial one.
+type fileLeaderLock struct {
+	path string
+	m    sync.Mutex
+	file *os.File // Open while the lock is held
+}
+
+func newFileLeaderElectionBackend() (leaderElectionBackend, error) {
+	return newLockBackend(&fileLeaderLock{
+		path: config.Datadog.GetString("cluster_checks.leader_election.lock_file"),
+	})
+}
+
+// holderPath returns the path of the file holding the value of the leader
+func (l *fileLeaderLock) holderPath() string {
+	return l.path + ".holder"
+}
+
+// tryLock takes the lock without blocking and writes the value in the
+// holder file, or reads the value of the holder if it is taken
+func (l *fileLeaderLock) tryLock(value string) (string, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.file != nil {
+		return value, nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if err != syscall.EWOULDBLOCK {
+			return "", err
+		}
+		holder, err := ioutil.ReadFile(l.holderPath())
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return strings.TrimSpace(string(holder)), err
+	}
+
+	if err := writeLockHolder(l.holderPath(), value); err != nil {
+		file.Close()
+		return "", err
+	}
+	l.file = file
+	return value, nil
+}
+
+// writeLockHolder replaces the holder file with the value, writing a
+// temporary file renamed over it
+func writeLockHolder(path, value string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	if _, err := tmp.WriteString(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// unlock removes the holder file and releases the lock
+func (l *fileLeaderLock) unlock(value string) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	os.Remove(l.holderPath()) //nolint:errcheck
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
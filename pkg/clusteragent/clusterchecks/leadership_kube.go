@@ -9,17 +9,180 @@
 package clusterchecks
 
 import (
-	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	ld "k8s.io/client-go/tools/leaderelection"
+	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/common"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
-func getLeaderIPCallback() (types.LeaderIPCallback, error) {
+func init() {
+	registerLeaderElectionBackend("kubernetes", newKubeLeaderElectionBackend)
+	registerLeaderElectionBackend("kubernetes_lease", newLeaseLeaderElectionBackend)
+}
+
+// kubeLeaderElectionBackend relies on the leader election engine of the
+// cluster-agent, shared with the other features
+type kubeLeaderElectionBackend struct {
+	engine *leaderelection.LeaderEngine
+}
+
+func newKubeLeaderElectionBackend() (leaderElectionBackend, error) {
 	engine, err := leaderelection.GetLeaderEngine()
 	if err != nil {
 		return nil, err
 	}
+	return &kubeLeaderElectionBackend{engine: engine}, nil
+}
 
-	engine.StartLeaderElectionRun()
+// Start implements the leaderElectionBackend interface
+func (b *kubeLeaderElectionBackend) Start() {
+	b.engine.StartLeaderElectionRun()
+}
+
+// GetLeaderIP implements the leaderElectionBackend interface
+func (b *kubeLeaderElectionBackend) GetLeaderIP() (string, error) {
+	return b.engine.GetLeaderIP()
+}
 
-	return engine.GetLeaderIP, nil
+// ReleaseLeadership implements the leaderElectionBackend interface
+func (b *kubeLeaderElectionBackend) ReleaseLeadership() {
+	b.engine.ReleaseLeadership()
+}
+
+// WatchLeader implements the leaderElectionWatcher interface
+func (b *kubeLeaderElectionBackend) WatchLeader() <-chan struct{} {
+	return b.engine.SubscribeLeaderChanges()
+}
+
+// leaseLeaderElectionBackend elects the cluster checks leader with a
+// dedicated Lease object, the holder identity being the encoded lockHolder of
+// the candidate, as several candidates can share the advertised IP
+type leaseLeaderElectionBackend struct {
+	leaderNotifier
+	elector  *ld.LeaderElector
+	identity string
+	once     sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newLeaseLeaderElectionBackend() (leaderElectionBackend, error) {
+	address, err := getAdvertisedAddress()
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := getCandidateIdentity()
+	if err != nil {
+		return nil, err
+	}
+	identity := lockHolder{Identity: candidate, Address: address}.encode()
+
+	apiClient, err := apiserver.GetAPIClient()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := rl.New(
+		rl.LeasesResourceLock,
+		common.GetResourcesNamespace(),
+		config.Datadog.GetString("cluster_checks.leader_election.lock_name"),
+		apiClient.Cl.CoreV1(),
+		apiClient.Cl.CoordinationV1(),
+		rl.ResourceLockConfig{Identity: identity},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	notifier := newLeaderNotifier()
+	leaseDuration := getLeaderLeaseDuration()
+	elector, err := ld.NewLeaderElector(ld.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: leaseDuration / 2,
+		RetryPeriod:   leaseDuration / 4,
+		Callbacks: ld.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				log.Infof("Started leading the cluster checks as %q", identity)
+				notifier.notify()
+			},
+			OnStoppedLeading: func() {
+				log.Infof("Stopped leading the cluster checks as %q", identity)
+				notifier.notify()
+			},
+			OnNewLeader: func(holder string) {
+				log.Infof("New cluster checks leader %q", holder)
+				notifier.notify()
+			},
+		},
+		ReleaseOnCancel: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &leaseLeaderElectionBackend{
+		leaderNotifier: notifier,
+		elector:        elector,
+		identity:       identity,
+		done:           make(chan struct{}),
+	}, nil
+}
+
+// Start implements the leaderElectionBackend interface
+func (b *leaseLeaderElectionBackend) Start() {
+	b.once.Do(func() {
+		var ctx context.Context
+		ctx, b.cancel = context.WithCancel(context.Background())
+		go b.run(ctx)
+	})
+}
+
+// run campaigns again when the leadership is lost, until cancelled
+func (b *leaseLeaderElectionBackend) run(ctx context.Context) {
+	defer close(b.done)
+	for {
+		b.elector.Run(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// GetLeaderIP implements the leaderElectionBackend interface
+func (b *leaseLeaderElectionBackend) GetLeaderIP() (string, error) {
+	if b.elector.IsLeader() {
+		return "", nil
+	}
+	leader := b.elector.GetLeader()
+	if leader == "" {
+		return "", errors.New("no leader elected yet")
+	}
+	return decodeLockHolder(leader).Address, nil
+}
+
+// ReleaseLeadership implements the leaderElectionBackend interface
+func (b *leaseLeaderElectionBackend) ReleaseLeadership() {
+	b.once.Do(func() {})
+	if b.cancel == nil {
+		return
+	}
+
+	b.cancel()
+	select {
+	case <-b.done:
+	case <-time.After(5 * time.Second):
+		log.Warnf("Leader election still running for %q, the leadership might not be released", b.identity)
+	}
 }
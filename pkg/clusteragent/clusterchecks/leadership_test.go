@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks && !windows
+// +build clusterchecks,!windows
+
+package clusterchecks
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestGetLeaderElectionBackend(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("cluster_checks.leader_election.backend", "unknown")
+	_, err := getLeaderElectionBackend()
+	assert.Error(t, err)
+
+	mockConfig.Set("cluster_checks.leader_election.backend", "file")
+	mockConfig.Set("cluster_checks.leader_election.advertise_address", "10.0.0.1")
+	backend, err := getLeaderElectionBackend()
+	require.NoError(t, err)
+	assert.IsType(t, &lockBackend{}, backend)
+}
+
+func TestFileLeaderElection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	first := &lockBackend{lock: &fileLeaderLock{path: path}, self: lockHolder{Identity: "first", Address: "10.0.0.1"}}
+	second := &lockBackend{lock: &fileLeaderLock{path: path}, self: lockHolder{Identity: "second", Address: "10.0.0.2"}}
+
+	// The first candidate takes the lock, the second one follows it
+	first.campaign()
+	second.campaign()
+	ip, err := first.GetLeaderIP()
+	require.NoError(t, err)
+	assert.Equal(t, "", ip)
+	ip, err = second.GetLeaderIP()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip)
+
+	// Renewing keeps the leadership
+	first.campaign()
+	ip, _ = first.GetLeaderIP()
+	assert.Equal(t, "", ip)
+
+	// The second candidate takes over once the lock is released
+	require.NoError(t, first.lock.unlock(first.self.encode()))
+	second.campaign()
+	first.campaign()
+	ip, _ = second.GetLeaderIP()
+	assert.Equal(t, "", ip)
+	ip, _ = first.GetLeaderIP()
+	assert.Equal(t, "10.0.0.2", ip)
+}
+
+func TestFileLeaderElectionSameAddress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	first := &lockBackend{lock: &fileLeaderLock{path: path}, self: lockHolder{Identity: "first", Address: "10.0.0.1"}}
+	second := &lockBackend{lock: &fileLeaderLock{path: path}, self: lockHolder{Identity: "second", Address: "10.0.0.1"}}
+
+	// Only one of the candidates sharing the address is leader
+	first.campaign()
+	second.campaign()
+	ip, err := first.GetLeaderIP()
+	require.NoError(t, err)
+	assert.Equal(t, "", ip)
+	ip, err = second.GetLeaderIP()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip)
+}
+
+func TestLockBackendRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	backend := &lockBackend{
+		lock:   &fileLeaderLock{path: path},
+		self:   lockHolder{Identity: "first", Address: "10.0.0.1"},
+		period: time.Hour,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	backend.Start()
+	require.Eventually(t, func() bool {
+		ip, err := backend.GetLeaderIP()
+		return err == nil && ip == ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The released leader does not report itself as leader anymore
+	backend.ReleaseLeadership()
+	_, err := backend.GetLeaderIP()
+	assert.Error(t, err)
+	assert.NoFileExists(t, path+".holder")
+}
+
+func TestDecodeLockHolder(t *testing.T) {
+	holder := lockHolder{Identity: "host-1-abcd", Address: "10.0.0.1"}
+	assert.Equal(t, holder, decodeLockHolder(holder.encode()))
+
+	// Bare IPs written by the previous versions
+	assert.Equal(t, lockHolder{Identity: "10.0.0.2", Address: "10.0.0.2"}, decodeLockHolder("10.0.0.2"))
+}
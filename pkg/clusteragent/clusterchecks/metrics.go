@@ -20,6 +20,15 @@ var (
 	danglingConfigs = telemetry.NewGaugeWithOpts("cluster_checks", "configs_dangling",
 		[]string{le.JoinLeaderLabel}, "Number of check configurations not dispatched.",
 		telemetry.Options{NoDoubleUnderscoreSep: true})
+	heldConfigs = telemetry.NewGaugeWithOpts("cluster_checks", "configs_held",
+		[]string{le.JoinLeaderLabel}, "Number of new versions of check configurations held back until their canaries succeed.",
+		telemetry.Options{NoDoubleUnderscoreSep: true})
+	unscheduledConfigs = telemetry.NewGaugeWithOpts("cluster_checks", "configs_unscheduled",
+		[]string{le.JoinLeaderLabel}, "Number of check configurations not dispatched because all nodes reached their quota.",
+		telemetry.Options{NoDoubleUnderscoreSep: true})
+	excludedNodes = telemetry.NewGaugeWithOpts("cluster_checks", "nodes_excluded",
+		[]string{le.JoinLeaderLabel}, "Number of unhealthy nodes excluded from the new placements.",
+		telemetry.Options{NoDoubleUnderscoreSep: true})
 	dispatchedConfigs = telemetry.NewGaugeWithOpts("cluster_checks", "configs_dispatched",
 		[]string{"node", le.JoinLeaderLabel}, "Number of check configurations dispatched, by node.",
 		telemetry.Options{NoDoubleUnderscoreSep: true})
@@ -29,6 +38,18 @@ var (
 	successfulRebalancing = telemetry.NewCounterWithOpts("cluster_checks", "successful_rebalancing_moves",
 		[]string{le.JoinLeaderLabel}, "Total number of successful check rebalancing decisions",
 		telemetry.Options{NoDoubleUnderscoreSep: true})
+	rebalancingMoves = telemetry.NewHistogramWithOpts("cluster_checks", "rebalancing_configs_moved",
+		[]string{le.JoinLeaderLabel}, "Distribution of the number of checks moved per rebalancing",
+		[]float64{0, 1, 2, 5, 10, 20, 50, 100},
+		telemetry.Options{NoDoubleUnderscoreSep: true})
+	dispatchDuration = telemetry.NewHistogramWithOpts("cluster_checks", "dispatch_duration_seconds",
+		[]string{le.JoinLeaderLabel}, "Distribution of the duration of the dispatching of a check configuration",
+		[]float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1},
+		telemetry.Options{NoDoubleUnderscoreSep: true})
+	timeToSchedule = telemetry.NewHistogramWithOpts("cluster_checks", "time_to_schedule_seconds",
+		[]string{le.JoinLeaderLabel}, "Distribution of the time between the scheduling of a new check configuration and its dispatching to a node",
+		[]float64{0.1, 1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+		telemetry.Options{NoDoubleUnderscoreSep: true})
 	rebalancingDuration = telemetry.NewGaugeWithOpts("cluster_checks", "rebalancing_duration_seconds",
 		[]string{le.JoinLeaderLabel}, "Duration of the check rebalancing algorithm last execution",
 		telemetry.Options{NoDoubleUnderscoreSep: true})
@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// stateStore persists the dispatching state of the leader, so that a newly
+// elected leader restores the assignments of the configurations instead of
+// re-dispatching them all, which would restart the checks.
+type stateStore interface {
+	load() (types.DispatchingState, error)
+	save(state types.DispatchingState) error
+}
+
+// restoreState loads the persisted dispatching state and restores it, see
+// applyState. It returns true when the previous leader handed the dispatching
+// over less than the node expiration timeout ago: the warmup can be skipped.
+func (d *dispatcher) restoreState() bool {
+	if d.stateStore == nil {
+		return false
+	}
+
+	state, err := d.stateStore.load()
+	if err != nil {
+		log.Warnf("Cannot restore the cluster checks dispatching state, re-dispatching all configurations: %v", err)
+		return false
+	}
+
+	d.persistedState = state
+	return d.applyState(state, state.HandoverTime)
+}
+
+// applyState restores a dispatching state: the configurations are dispatched
+// to the node they were assigned to if it reports, and the pins, the drains
+// and the isolations set with the API are restored. When the state was taken at the given timestamp less than
+// the node expiration timeout ago, its nodes are restored too and applyState
+// returns true.
+func (d *dispatcher) applyState(state types.DispatchingState, timestamp int64) bool {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	for digest, nodeName := range state.Assignments {
+		d.store.restoredNodes[digest] = nodeName
+	}
+	for digest, nodeName := range state.PinnedNodes {
+		d.store.pinnedNodes[digest] = nodeName
+	}
+	for _, nodeName := range state.DrainingNodes {
+		d.store.setDraining(nodeName, true)
+	}
+	for digest, isolated := range state.Isolations {
+		// Their pin and drain are restored with the others, for the release
+		// to restore the previous ones
+		d.store.isolations[digest] = &isolation{
+			checkID:     isolated.CheckID,
+			nodeName:    isolated.NodeName,
+			until:       isolated.Until,
+			previousPin: isolated.PreviousPin,
+			wasDraining: isolated.WasDraining,
+		}
+	}
+	log.Infof("Restored the dispatching state of %d cluster check configurations", len(state.Assignments))
+
+	if timestamp == 0 || timestampNow()-timestamp >= d.nodeExpirationSeconds || len(state.Nodes) == 0 {
+		return false
+	}
+	for nodeName, nodeState := range state.Nodes {
+		node := d.store.getOrCreateNodeStore(nodeName, nodeState.ClientIP)
+		node.Lock()
+		if node.heartbeat < nodeState.Heartbeat {
+			node.heartbeat = nodeState.Heartbeat
+		}
+		node.Unlock()
+	}
+	log.Infof("Restored %d nodes of the previous leader", len(state.Nodes))
+	return true
+}
+
+// pruneRestoredNodes forgets the restored assignments of the configurations
+// which were not scheduled by the replay of the configurations, as they were
+// removed during the failover, so that they are not persisted forever.
+func (d *dispatcher) pruneRestoredNodes() {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	pruned := 0
+	for digest := range d.store.restoredNodes {
+		if _, found := d.store.digestToConfig[digest]; !found {
+			delete(d.store.restoredNodes, digest)
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		log.Infof("Pruned the restored dispatching state of %d removed cluster check configurations", pruned)
+	}
+}
+
+// saveHandoverState saves a snapshot of the dispatching state including the
+// nodes, for the next leader to restore it without waiting for the nodes to
+// report. The state is not persisted anymore afterwards.
+func (d *dispatcher) saveHandoverState() error {
+	if d.stateStore == nil {
+		return nil
+	}
+
+	state := d.getSnapshot()
+	state.HandoverTime = timestampNow()
+
+	d.store.Lock()
+	d.store.handedOver = true
+	d.store.Unlock()
+
+	return d.stateStore.save(state)
+}
+
+// getSnapshot returns the dispatching state including the nodes, for another
+// cluster-agent to restore it without waiting for the nodes to report.
+func (d *dispatcher) getSnapshot() types.DispatchingState {
+	state := d.getDispatchingState()
+	state.Nodes = make(map[string]types.NodeState)
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	for nodeName, node := range d.store.nodes {
+		if nodeName == "" {
+			continue
+		}
+		node.RLock()
+		state.Nodes[nodeName] = types.NodeState{ClientIP: node.clientIP, Heartbeat: node.heartbeat}
+		node.RUnlock()
+	}
+	return state
+}
+
+// persistState saves the dispatching state if it changed since it was last saved
+func (d *dispatcher) persistState() {
+	if d.stateStore == nil {
+		return
+	}
+
+	d.store.RLock()
+	handedOver := d.store.handedOver
+	d.store.RUnlock()
+	if handedOver {
+		// Keep the snapshot saved for the next leader
+		return
+	}
+
+	state := d.getDispatchingState()
+	if reflect.DeepEqual(state, d.persistedState) {
+		return
+	}
+
+	if err := d.stateStore.save(state); err != nil {
+		log.Warnf("Cannot persist the cluster checks dispatching state: %v", err)
+		return
+	}
+	d.persistedState = state
+}
+
+// getDispatchingState returns the state to persist. The restored assignments
+// of the configurations not dispatched yet are kept, as long as their node
+// is reporting.
+func (d *dispatcher) getDispatchingState() types.DispatchingState {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	state := types.DispatchingState{
+		Assignments: make(map[string]string, len(d.store.digestToNode)),
+		PinnedNodes: make(map[string]string, len(d.store.pinnedNodes)),
+	}
+	for digest, nodeName := range d.store.restoredNodes {
+		if _, found := d.store.getNodeStore(nodeName); found {
+			state.Assignments[digest] = nodeName
+		}
+	}
+	for digest, nodeName := range d.store.digestToNode {
+		state.Assignments[digest] = nodeName
+	}
+	for digest, nodeName := range d.store.pinnedNodes {
+		state.PinnedNodes[digest] = nodeName
+	}
+	for nodeName := range d.store.drainingNodes {
+		state.DrainingNodes = append(state.DrainingNodes, nodeName)
+	}
+	sort.Strings(state.DrainingNodes)
+	if len(d.store.isolations) > 0 {
+		state.Isolations = make(map[string]types.IsolationState, len(d.store.isolations))
+	}
+	for digest, isolated := range d.store.isolations {
+		state.Isolations[digest] = types.IsolationState{
+			CheckID:     isolated.checkID,
+			NodeName:    isolated.nodeName,
+			Until:       isolated.until,
+			PreviousPin: isolated.previousPin,
+			WasDraining: isolated.wasDraining,
+		}
+	}
+	return state
+}
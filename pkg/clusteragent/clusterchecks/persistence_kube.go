@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks && kubeapiserver
+// +build clusterchecks,kubeapiserver
+
+package clusterchecks
+
+import (
+	"context"
+	"encoding/json"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/common"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const stateConfigMapKey = "state"
+
+// configMapStateStore persists the dispatching state in a ConfigMap
+type configMapStateStore struct {
+	namespace string
+	name      string
+	client    corev1.CoreV1Interface
+}
+
+func newStateStore() (stateStore, error) {
+	client, err := apiserver.GetAPIClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &configMapStateStore{
+		namespace: common.GetResourcesNamespace(),
+		name:      config.Datadog.GetString("cluster_checks.state_configmap_name"),
+		client:    client.Cl.CoreV1(),
+	}, nil
+}
+
+// load returns the state stored in the ConfigMap, an empty one if it does not exist
+func (s *configMapStateStore) load() (types.DispatchingState, error) {
+	state := types.DispatchingState{}
+
+	cm, err := s.client.ConfigMaps(s.namespace).Get(context.TODO(), s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		log.Debugf("The configmap %s does not exist, no dispatching state to restore", s.name)
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	value, found := cm.Data[stateConfigMapKey]
+	if !found {
+		return state, nil
+	}
+	err = json.Unmarshal([]byte(value), &state)
+	return state, err
+}
+
+// save stores the state in the ConfigMap, which is created if it does not exist
+func (s *configMapStateStore) save(state types.DispatchingState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	cm, err := s.client.ConfigMaps(s.namespace).Get(context.TODO(), s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		log.Infof("The configmap %s does not exist, trying to create it", s.name)
+		_, err = s.client.ConfigMaps(s.namespace).Create(context.TODO(), &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{stateConfigMapKey: string(value)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[stateConfigMapKey] = string(value)
+	_, err = s.client.ConfigMaps(s.namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return err
+}
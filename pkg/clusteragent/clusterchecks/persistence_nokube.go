@@ -10,10 +10,8 @@ package clusterchecks
 
 import (
 	"errors"
-
-	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
 )
 
-func getLeaderIPCallback() (types.LeaderIPCallback, error) {
-	return nil, errors.New("No leader election engine compiled in")
+func newStateStore() (stateStore, error) {
+	return nil, errors.New("No state store compiled in")
 }
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+// memoryStateStore is a stateStore keeping the state in memory for tests
+type memoryStateStore struct {
+	state types.DispatchingState
+	saves int
+}
+
+func (s *memoryStateStore) load() (types.DispatchingState, error) {
+	return s.state, nil
+}
+
+func (s *memoryStateStore) save(state types.DispatchingState) error {
+	s.state = state
+	s.saves++
+	return nil
+}
+
+func TestPersistAndRestoreState(t *testing.T) {
+	store := &memoryStateStore{}
+
+	// The leader dispatches and persists the configs
+	leader := newDispatcher()
+	leader.stateStore = store
+	leader.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	leader.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	configA, _ := generatePinnedIntegration("http_check", "url: http://a", "")
+	configB, idB := generatePinnedIntegration("http_check", "url: http://b", "")
+	leader.addConfig(configA, "nodeA")
+	leader.addConfig(configB, "nodeA")
+	assert.NoError(t, leader.pin(idB, "nodeB"))
+
+	leader.persistState()
+	leader.persistState()
+	assert.Equal(t, 1, store.saves)
+	assert.Equal(t, types.DispatchingState{
+		Assignments: map[string]string{configA.Digest(): "nodeA", configB.Digest(): "nodeB"},
+		PinnedNodes: map[string]string{configB.Digest(): "nodeB"},
+	}, store.state)
+
+	// The new leader restores the assignments, nodeA being the busiest node
+	newLeader := newDispatcher()
+	newLeader.stateStore = store
+	newLeader.restoreState()
+	newLeader.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	newLeader.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	newLeader.addConfig(generateIntegration("other1"), "nodeA")
+	newLeader.addConfig(generateIntegration("other2"), "nodeA")
+	newLeader.add(configA)
+	assert.Equal(t, "nodeA", newLeader.store.digestToNode[configA.Digest()])
+
+	// The restored state is kept until the configs are dispatched
+	newLeader.persistState()
+	assert.Equal(t, "nodeB", store.state.Assignments[configB.Digest()])
+	newLeader.add(configB)
+	assert.Equal(t, "nodeB", newLeader.store.digestToNode[configB.Digest()])
+	assert.Empty(t, newLeader.store.restoredNodes)
+
+	// The configs removed during the failover are pruned once the configs are replayed
+	newLeader.store.Lock()
+	newLeader.store.restoredNodes["removed"] = "nodeA"
+	newLeader.store.Unlock()
+	newLeader.pruneRestoredNodes()
+	assert.Empty(t, newLeader.store.restoredNodes)
+	newLeader.persistState()
+	assert.NotContains(t, store.state.Assignments, "removed")
+
+	// Once dispatched, the configs are dispatched as usual
+	newLeader.removeConfig(configA.Digest())
+	newLeader.add(configA)
+	assert.Equal(t, "nodeB", newLeader.store.digestToNode[configA.Digest()])
+
+	requireNotLocked(t, newLeader.store)
+}
+
+func TestPersistAndRestoreDrainingNodes(t *testing.T) {
+	store := &memoryStateStore{}
+
+	leader := newDispatcher()
+	leader.stateStore = store
+	leader.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	leader.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	_, err := leader.drain("nodeB", true)
+	assert.NoError(t, err)
+	_, err = leader.drain("nodeA", true)
+	assert.NoError(t, err)
+	leader.persistState()
+	assert.Equal(t, []string{"nodeA", "nodeB"}, store.state.DrainingNodes)
+
+	// The new leader keeps draining the nodes, even before they report
+	newLeader := newDispatcher()
+	newLeader.stateStore = store
+	newLeader.restoreState()
+	newLeader.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	newLeader.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{})
+	assert.Equal(t, "nodeC", newLeader.getLeastBusyNode())
+	status, err := newLeader.drainStatus("nodeB")
+	assert.NoError(t, err)
+	assert.True(t, status.Draining)
+
+	requireNotLocked(t, newLeader.store)
+}
+
+func TestPersistAndRestoreIsolations(t *testing.T) {
+	store := &memoryStateStore{}
+
+	leader := newDispatcher()
+	leader.stateStore = store
+	leader.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	leader.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	noisy, noisyID := generatePinnedIntegration("noisy", "url: http://noisy", "")
+	leader.add(noisy)
+	_, err := leader.isolate(noisyID, "nodeB", 60)
+	require.NoError(t, err)
+	leader.persistState()
+	require.Len(t, store.state.Isolations, 1)
+	assert.Equal(t, "nodeB", store.state.Isolations[noisy.Digest()].NodeName)
+
+	// The new leader keeps the isolation until its TTL elapses
+	newLeader := newDispatcher()
+	newLeader.stateStore = store
+	newLeader.restoreState()
+	newLeader.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	newLeader.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	assert.Equal(t, "nodeB", newLeader.isolatedNode(noisy.Digest()))
+	newLeader.processIsolations()
+	assert.Equal(t, "nodeB", newLeader.isolatedNode(noisy.Digest()))
+
+	// Once released, the pin and the drain of the isolation are reverted
+	newLeader.store.Lock()
+	newLeader.store.isolations[noisy.Digest()].until = timestampNow() - 1
+	newLeader.store.Unlock()
+	newLeader.processIsolations()
+	assert.Equal(t, "", newLeader.isolatedNode(noisy.Digest()))
+	newLeader.store.RLock()
+	assert.Empty(t, newLeader.store.pinnedNodes)
+	assert.False(t, newLeader.store.isDraining("nodeB"))
+	newLeader.store.RUnlock()
+	newLeader.persistState()
+	assert.Empty(t, store.state.Isolations)
+
+	requireNotLocked(t, newLeader.store)
+}
@@ -62,11 +62,12 @@ func (d *dispatcher) getStats() *types.Stats {
 		checkNames[m.Name] = struct{}{}
 	}
 	return &types.Stats{
-		Active:          d.store.active,
-		NodeCount:       len(d.store.nodes),
-		ActiveConfigs:   len(d.store.digestToNode),
-		DanglingConfigs: len(d.store.danglingConfigs),
-		TotalConfigs:    len(d.store.digestToConfig),
-		CheckNames:      checkNames,
+		Active:             d.store.active,
+		NodeCount:          len(d.store.nodes),
+		ActiveConfigs:      len(d.store.digestToNode),
+		DanglingConfigs:    len(d.store.danglingConfigs),
+		UnscheduledConfigs: len(d.store.unscheduled),
+		TotalConfigs:       len(d.store.digestToConfig),
+		CheckNames:         checkNames,
 	}
 }
@@ -11,6 +11,7 @@ package clusterchecks
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
@@ -29,8 +30,21 @@ type clusterStore struct {
 	digestToNode     map[string]string                        // Node running a config
 	nodes            map[string]*nodeStore                    // All nodes known to the cluster-agent
 	danglingConfigs  map[string]integration.Config            // Configs we could not dispatch to any node
+	unscheduled      map[string]integration.Config            // Configs not dispatched because all nodes reached their quota
 	endpointsConfigs map[string]map[string]integration.Config // Endpoints configs to be consumed by node agents
 	idToDigest       map[check.ID]string                      // link check IDs to check configs
+	pinnedNodes      map[string]string                        // Nodes configs are pinned to with the API
+	restoredNodes    map[string]string                        // Nodes configs were dispatched to by the previous leader
+	replacedConfigs  map[string]replacedConfig                // Removed configs kept running until a new version replaces them, by identity
+	rollouts         map[string]*canaryRollout                // Canary rollouts of the changed configs, by check name
+	excludedNodes    map[string]int64                         // Unhealthy nodes excluded from the new placements, until a timestamp
+	nodeExpirations  map[string][]int64                       // Recent expiration timestamps of the nodes, to detect their flapping
+	pendingSince     map[string]time.Time                     // When the new configs not dispatched to a node yet were scheduled
+	isolations       map[string]*isolation                    // Configs isolated on a dedicated node with the API
+	drainingNodes    map[string]*drainState                   // Nodes drained with the API, by name, kept when they expire
+	movedChecks      map[string]int64                         // When the checks were last moved by the rebalancing, by check ID
+	handedOver       bool                                     // Whether the dispatching was handed over to the next leader
+	dryRun           bool                                     // Whether the store is a copy for a dry-run, not reporting metrics
 }
 
 func newClusterStore() *clusterStore {
@@ -46,8 +60,20 @@ func (s *clusterStore) reset() {
 	s.digestToNode = make(map[string]string)
 	s.nodes = make(map[string]*nodeStore)
 	s.danglingConfigs = make(map[string]integration.Config)
+	s.unscheduled = make(map[string]integration.Config)
 	s.endpointsConfigs = make(map[string]map[string]integration.Config)
 	s.idToDigest = make(map[check.ID]string)
+	s.pinnedNodes = make(map[string]string)
+	s.restoredNodes = make(map[string]string)
+	s.replacedConfigs = make(map[string]replacedConfig)
+	s.rollouts = make(map[string]*canaryRollout)
+	s.excludedNodes = make(map[string]int64)
+	s.nodeExpirations = make(map[string][]int64)
+	s.pendingSince = make(map[string]time.Time)
+	s.isolations = make(map[string]*isolation)
+	s.drainingNodes = make(map[string]*drainState)
+	s.movedChecks = make(map[string]int64)
+	s.handedOver = false
 }
 
 // getNodeStore retrieves the store struct for a given node name, if it exists
@@ -68,11 +94,45 @@ func (s *clusterStore) getOrCreateNodeStore(nodeName, clientIP string) *nodeStor
 		return node
 	}
 	node = newNodeStore(nodeName, clientIP)
-	nodeAgents.Inc(le.JoinLeaderValue)
+	node.dryRun = s.dryRun
+	if !s.dryRun {
+		nodeAgents.Inc(le.JoinLeaderValue)
+	}
 	s.nodes[nodeName] = node
 	return node
 }
 
+// registerConfig stores a configuration and the IDs of its checks
+func (s *clusterStore) registerConfig(config integration.Config) {
+	digest := config.Digest()
+	s.digestToConfig[digest] = config
+	delete(s.restoredNodes, digest)
+	for _, instance := range config.Instances {
+		s.idToDigest[check.BuildID(config.Name, instance, config.InitConfig)] = digest
+	}
+}
+
+// pinnedNode returns the node a configuration is pinned to, or an empty string
+// if it is not pinned. The pins set with the API take precedence over the node
+// set in the configuration.
+func (s *clusterStore) pinnedNode(digest string, config integration.Config) string {
+	if nodeName, found := s.pinnedNodes[digest]; found {
+		return nodeName
+	}
+	return config.PinnedNode
+}
+
+// pinnedCheckIDs returns the IDs of the checks whose configuration is pinned
+func (s *clusterStore) pinnedCheckIDs() map[string]struct{} {
+	ids := make(map[string]struct{})
+	for id, digest := range s.idToDigest {
+		if s.pinnedNode(digest, s.digestToConfig[digest]) != "" {
+			ids[string(id)] = struct{}{}
+		}
+	}
+	return ids
+}
+
 // clearDangling resets the danglingConfigs map to a new empty one
 func (s *clusterStore) clearDangling() {
 	s.danglingConfigs = make(map[string]integration.Config)
@@ -89,7 +149,10 @@ type nodeStore struct {
 	digestToConfig   map[string]integration.Config
 	clientIP         string
 	clcRunnerStats   types.CLCRunnersStats
+	checkCosts       map[string]int           // costs declared by the configurations of the checks
+	checkIntervals   map[string]time.Duration // intervals of the checks, when not the default one
 	busyness         int
+	dryRun           bool
 }
 
 func newNodeStore(name, clientIP string) *nodeStore {
@@ -98,25 +161,76 @@ func newNodeStore(name, clientIP string) *nodeStore {
 		clientIP:       clientIP,
 		digestToConfig: make(map[string]integration.Config),
 		clcRunnerStats: types.CLCRunnersStats{},
+		checkCosts:     make(map[string]int),
+		checkIntervals: make(map[string]time.Duration),
 		busyness:       defaultBusynessValue,
 	}
 }
 
+// capacity returns the capacity reported by the node, 0 if it does not report any
+// The nodeStore handles thread safety for this method
+func (s *nodeStore) capacity() float64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastStatus.Capacity
+}
+
+// capacityFactor returns the factor applied to the load of the node to compare
+// it with the other nodes: the ratio of the default capacity to the capacity
+// reported by the node, 1 when it does not report any.
+func (s *nodeStore) capacityFactor() float64 {
+	capacity := s.capacity()
+	if capacity <= 0 {
+		return 1
+	}
+	return defaultCapacity / capacity
+}
+
+// GetWeightedBusyness calculates busyness of the node, weighted by its capacity
+// The nodeStore handles thread safety for this public method
+func (s *nodeStore) GetWeightedBusyness(busynessFunc func(stats types.CLCRunnerStats) int) int {
+	return int(float64(s.GetBusyness(busynessFunc)) * s.capacityFactor())
+}
+
+// zone returns the zone of the node, as reported in the given label
+// The nodeStore handles thread safety for this method
+func (s *nodeStore) zone(label string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastStatus.Labels[label]
+}
+
+// pool returns the pool of runners of the node, as reported in the given label
+// The nodeStore handles thread safety for this method
+func (s *nodeStore) pool(label string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastStatus.Labels[label]
+}
+
 func (s *nodeStore) addConfig(config integration.Config) {
 	s.lastConfigChange = timestampNow()
 	s.digestToConfig[config.Digest()] = config
-	dispatchedConfigs.Inc(s.name, le.JoinLeaderValue)
+	s.addConfigCosts(config)
+	s.addCheckIntervals(config)
+	if !s.dryRun {
+		dispatchedConfigs.Inc(s.name, le.JoinLeaderValue)
+	}
 }
 
 func (s *nodeStore) removeConfig(digest string) {
-	_, found := s.digestToConfig[digest]
+	config, found := s.digestToConfig[digest]
 	if !found {
 		log.Debugf("unknown digest %s, skipping", digest)
 		return
 	}
 	s.lastConfigChange = timestampNow()
 	delete(s.digestToConfig, digest)
-	dispatchedConfigs.Dec(s.name, le.JoinLeaderValue)
+	s.removeConfigCosts(config)
+	s.removeCheckIntervals(config)
+	if !s.dryRun {
+		dispatchedConfigs.Dec(s.name, le.JoinLeaderValue)
+	}
 }
 
 // AddRunnerStats stores runner stats for a check
@@ -164,9 +278,10 @@ func (s *nodeStore) GetBusyness(busynessFunc func(stats types.CLCRunnerStats) in
 	return busyness
 }
 
-// GetMostWeightedClusterCheck returns the Cluster Check with the most weight on the node
+// GetMostWeightedClusterCheck returns the Cluster Check with the most weight on the node,
+// ignoring the excluded check IDs
 // The nodeStore handles thread safety for this public method
-func (s *nodeStore) GetMostWeightedClusterCheck(busynessFunc func(stats types.CLCRunnerStats) int) (string, int, error) {
+func (s *nodeStore) GetMostWeightedClusterCheck(busynessFunc func(stats types.CLCRunnerStats) int, excluded map[string]struct{}) (string, int, error) {
 	s.RLock()
 	defer s.RUnlock()
 	if len(s.clcRunnerStats) == 0 {
@@ -177,6 +292,9 @@ func (s *nodeStore) GetMostWeightedClusterCheck(busynessFunc func(stats types.CL
 	checkID := ""
 	checkWeight := 0
 	for id, stats := range s.clcRunnerStats {
+		if _, found := excluded[id]; found {
+			continue
+		}
 		busyness := busynessFunc(stats)
 		if (busyness > checkWeight || firstItr) && stats.IsClusterCheck {
 			// Only consider Cluster Checks
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package types
+
+// DurationHistogramBounds are the upper bounds in milliseconds of the buckets
+// of a DurationHistogram, an extra bucket holding the longer runs
+var DurationHistogramBounds = []int64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// DurationHistogram counts the recent runs of a check in each bucket of
+// DurationHistogramBounds, as reported by the runners in their status
+type DurationHistogram []int
+
+// NewDurationHistogram returns the histogram of execution durations in milliseconds
+func NewDurationHistogram(durations []int64) DurationHistogram {
+	histogram := make(DurationHistogram, len(DurationHistogramBounds)+1)
+	for _, duration := range durations {
+		bucket := len(DurationHistogramBounds)
+		for i, bound := range DurationHistogramBounds {
+			if duration <= bound {
+				bucket = i
+				break
+			}
+		}
+		histogram[bucket]++
+	}
+	return histogram
+}
+
+// Mean returns the mean execution duration in milliseconds, estimated with the
+// middle of the buckets, the runs longer than the last bound counting for it.
+// It returns 0 if no run is counted.
+func (h DurationHistogram) Mean() float64 {
+	total, runs := 0.0, 0
+	lower := int64(0)
+	for i, count := range h {
+		value := float64(DurationHistogramBounds[len(DurationHistogramBounds)-1])
+		if i < len(DurationHistogramBounds) {
+			value = float64(lower+DurationHistogramBounds[i]) / 2
+			lower = DurationHistogramBounds[i]
+		}
+		total += value * float64(count)
+		runs += count
+	}
+	if runs == 0 {
+		return 0
+	}
+	return total / float64(runs)
+}
@@ -11,7 +11,12 @@ import (
 
 // NodeStatus holds the status report from the node-agent
 type NodeStatus struct {
-	LastChange int64 `json:"last_change"`
+	LastChange int64             `json:"last_change"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Capacity   float64           `json:"capacity,omitempty"`
+	Cluster    string            `json:"cluster,omitempty"` // Kubernetes cluster of the runner, for the federation
+	// Execution durations of the recent runs of the cluster checks, by check ID
+	CheckDurations map[string]DurationHistogram `json:"check_durations,omitempty"`
 }
 
 // StatusResponse holds the DCA response for a status report
@@ -31,6 +36,134 @@ type RebalanceResponse struct {
 	DestDiff     int    `json:"dest_diff"`
 }
 
+// PinRequest holds a request to pin a check configuration to a node,
+// or to unpin it if the node name is empty
+type PinRequest struct {
+	CheckID  string `json:"check_id"`
+	NodeName string `json:"node_name"`
+}
+
+// IsolateRequest holds a request to isolate a check configuration on a
+// dedicated node for a time, or to release it
+type IsolateRequest struct {
+	CheckID  string `json:"check_id"`
+	NodeName string `json:"node_name"` // The least busy node if empty
+	TTL      int64  `json:"ttl"`       // In seconds, cluster_checks.isolation_ttl if 0
+	Release  bool   `json:"release"`
+}
+
+// IsolateResponse holds the DCA response for an isolate request
+type IsolateResponse struct {
+	CheckID  string `json:"check_id"`
+	NodeName string `json:"node_name"`
+	Until    int64  `json:"until"` // Timestamp the isolation is released at
+	Isolated bool   `json:"isolated"`
+}
+
+// ExtenderArgs holds the request sent to the placement extender: the
+// configuration to dispatch and its candidate nodes, the one preferred by the
+// dispatcher first
+type ExtenderArgs struct {
+	Config ExtenderConfig `json:"config"`
+	Nodes  []ExtenderNode `json:"nodes"`
+}
+
+// ExtenderConfig describes a check configuration to the placement extender
+type ExtenderConfig struct {
+	Name       string `json:"name"`
+	Digest     string `json:"digest"`
+	Source     string `json:"source"`
+	ServiceID  string `json:"service_id,omitempty"`
+	RunnerPool string `json:"runner_pool,omitempty"`
+}
+
+// ExtenderNode describes a candidate node to the placement extender
+type ExtenderNode struct {
+	Name     string            `json:"name"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Capacity float64           `json:"capacity,omitempty"`
+	Busyness int               `json:"busyness"`
+	Configs  int               `json:"configs"` // Number of configurations dispatched to the node
+}
+
+// ExtenderResult holds the response of the placement extender: the names of
+// the nodes allowed to run the configuration, by order of preference
+type ExtenderResult struct {
+	Nodes []string `json:"nodes"`
+	Error string   `json:"error,omitempty"`
+}
+
+// DrainResponse holds the DCA response for a drain request or query
+type DrainResponse struct {
+	NodeName string `json:"node_name"`
+	Draining bool   `json:"draining"`
+	// Number of configurations left to move out of the node, pinned ones excluded
+	Remaining int  `json:"remaining"`
+	Complete  bool `json:"complete"`
+}
+
+// DryRunRequest holds the changes of the node set to preview the dispatching of
+type DryRunRequest struct {
+	AddNodes    []DryRunNode `json:"add_nodes"`
+	RemoveNodes []string     `json:"remove_nodes"`
+	Rebalance   bool         `json:"rebalance"` // Whether to preview the rebalancing too
+}
+
+// DryRunNode is a node added by a DryRunRequest
+type DryRunNode struct {
+	Name     string  `json:"name"`
+	Zone     string  `json:"zone,omitempty"`
+	Pool     string  `json:"pool,omitempty"`
+	Capacity float64 `json:"capacity,omitempty"`
+}
+
+// DryRunResponse holds the placement the dispatcher would produce for a
+// DryRunRequest, without applying it
+type DryRunResponse struct {
+	Nodes       []StateNodeResponse  `json:"nodes"`
+	Dangling    []integration.Config `json:"dangling"`
+	Moves       []DryRunMove         `json:"moves"`       // Configurations changing node
+	Rebalancing []RebalanceResponse  `json:"rebalancing"` // Moves decided by the rebalancing
+}
+
+// DryRunMove is a configuration changing node in a DryRunResponse, the
+// destination node being empty if it cannot be dispatched
+type DryRunMove struct {
+	Name           string `json:"name"`
+	Digest         string `json:"digest"`
+	SourceNodeName string `json:"source_node_name"`
+	DestNodeName   string `json:"dest_node_name"`
+}
+
+// DispatchingState holds the dispatching state persisted by the leader, for
+// the next leader to restore it
+type DispatchingState struct {
+	Assignments map[string]string `json:"assignments"`            // Node running each config, by digest
+	PinnedNodes map[string]string `json:"pinned_nodes,omitempty"` // Nodes configs are pinned to with the API, by digest
+	// Nodes drained with the API, sorted by name
+	DrainingNodes []string `json:"draining_nodes,omitempty"`
+	// Configs isolated on a dedicated node with the API, by digest
+	Isolations map[string]IsolationState `json:"isolations,omitempty"`
+	// Set when the leader hands over the dispatching as it shuts down
+	Nodes        map[string]NodeState `json:"nodes,omitempty"`         // Nodes reporting, by name
+	HandoverTime int64                `json:"handover_time,omitempty"` // Timestamp of the handover
+}
+
+// IsolationState holds the isolation of a config in a DispatchingState
+type IsolationState struct {
+	CheckID     string `json:"check_id"`
+	NodeName    string `json:"node_name"`
+	Until       int64  `json:"until"`
+	PreviousPin string `json:"previous_pin,omitempty"`
+	WasDraining bool   `json:"was_draining,omitempty"`
+}
+
+// NodeState holds the state of a node in a DispatchingState
+type NodeState struct {
+	ClientIP  string `json:"client_ip"`
+	Heartbeat int64  `json:"heartbeat"`
+}
+
 // ConfigResponse holds the DCA response for a config query
 type ConfigResponse struct {
 	LastChange int64                `json:"last_change"`
@@ -39,16 +172,27 @@ type ConfigResponse struct {
 
 // StateResponse holds the DCA response for a dispatching state query
 type StateResponse struct {
-	NotRunning string               `json:"not_running"` // Reason why not running, empty if leading
-	Warmup     bool                 `json:"warmup"`
-	Nodes      []StateNodeResponse  `json:"nodes"`
-	Dangling   []integration.Config `json:"dangling"`
+	NotRunning    string               `json:"not_running"` // Reason why not running, empty if leading
+	Warmup        bool                 `json:"warmup"`
+	Nodes         []StateNodeResponse  `json:"nodes"`
+	Dangling      []integration.Config `json:"dangling"`
+	Unscheduled   []integration.Config `json:"unscheduled,omitempty"`    // Not dispatched because all nodes reached their quota
+	LeaderAddress string               `json:"leader_address,omitempty"` // Set when a follower serves the state of the leader
+	Timestamp     int64                `json:"timestamp,omitempty"`      // When a follower fetched the state of the leader
 }
 
 // StateNodeResponse is a chunk of StateResponse
 type StateNodeResponse struct {
-	Name    string               `json:"name"`
-	Configs []integration.Config `json:"configs"`
+	Name     string               `json:"name"`
+	Zone     string               `json:"zone,omitempty"`
+	Pool     string               `json:"pool,omitempty"`
+	Cluster  string               `json:"cluster,omitempty"` // Set for the runners of other clusters, with the federation
+	Capacity float64              `json:"capacity,omitempty"`
+	Cost     int                  `json:"cost,omitempty"` // Busyness weighted by the capacity, as compared by the rebalancing
+	Draining bool                 `json:"draining,omitempty"`
+	Excluded bool                 `json:"excluded,omitempty"` // Unhealthy node excluded from the new placements
+	Isolated bool                 `json:"isolated,omitempty"` // Node dedicated to an isolated check
+	Configs  []integration.Config `json:"configs"`
 }
 
 // Stats holds statistics for the agent status command
@@ -58,13 +202,14 @@ type Stats struct {
 	LeaderIP string
 
 	// Leading
-	Leader          bool
-	Active          bool
-	NodeCount       int
-	ActiveConfigs   int
-	DanglingConfigs int
-	TotalConfigs    int
-	CheckNames      map[string]struct{}
+	Leader             bool
+	Active             bool
+	NodeCount          int
+	ActiveConfigs      int
+	DanglingConfigs    int
+	UnscheduledConfigs int
+	TotalConfigs       int
+	CheckNames         map[string]struct{}
 }
 
 // LeaderIPCallback describes the leader-election method we
@@ -78,6 +223,9 @@ type CLCRunnersStats map[string]CLCRunnerStats
 type CLCRunnerStats struct {
 	AverageExecutionTime int  `json:"AverageExecutionTime"`
 	MetricSamples        int  `json:"MetricSamples"`
+	TotalRuns            int  `json:"TotalRuns"`
+	TotalErrors          int  `json:"TotalErrors"`
 	IsClusterCheck       bool `json:"IsClusterCheck"`
 	LastExecFailed       bool `json:"LastExecFailed"`
+	ConfigCost           int  `json:"-"` // set by the dispatcher from the check configuration
 }
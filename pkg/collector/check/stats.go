@@ -198,6 +198,21 @@ func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats S
 	}
 }
 
+// RecentExecutionTimes returns the durations in milliseconds of the recent runs
+// held in the ExecutionTimes buffer, in no particular order
+func (cs *Stats) RecentExecutionTimes() []int64 {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+
+	ringSize := cs.TotalRuns
+	if ringSize > uint64(len(cs.ExecutionTimes)) {
+		ringSize = uint64(len(cs.ExecutionTimes))
+	}
+	times := make([]int64, ringSize)
+	copy(times, cs.ExecutionTimes[:ringSize])
+	return times
+}
+
 type aggStats struct {
 	EventPlatformEvents       map[string]interface{}
 	EventPlatformEventsErrors map[string]interface{}
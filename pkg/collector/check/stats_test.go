@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -104,6 +105,19 @@ func TestNewStatsStateTelemetryInitializedWhenGloballyEnabled(t *testing.T) {
 	)
 }
 
+func TestRecentExecutionTimes(t *testing.T) {
+	stats := NewStats(newMockCheck())
+	assert.Empty(t, stats.RecentExecutionTimes())
+
+	for i := 1; i <= 40; i++ {
+		stats.Add(time.Duration(i)*time.Millisecond, nil, nil, NewSenderStats())
+	}
+	times := stats.RecentExecutionTimes()
+	assert.Len(t, times, 32)
+	assert.Contains(t, times, int64(40))
+	assert.NotContains(t, times, int64(8))
+}
+
 func TestTranslateEventPlatformEventTypes(t *testing.T) {
 	original := map[string]interface{}{
 		"EventPlatformEvents": map[string]interface{}{
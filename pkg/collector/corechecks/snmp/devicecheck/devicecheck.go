@@ -16,6 +16,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metadata/externalhost"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/snmp/devices"
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -104,6 +105,7 @@ func (d *DeviceCheck) Run(collectionTime time.Time) error {
 	if values != nil {
 		d.sender.ReportMetrics(d.config.Metrics, values, tags)
 	}
+	d.register(tags)
 
 	if d.config.CollectDeviceMetadata {
 		if deviceReachable {
@@ -126,6 +128,27 @@ func (d *DeviceCheck) Run(collectionTime time.Time) error {
 	return checkErr
 }
 
+// register registers the device with the tags of its metrics, so that its traps are tagged
+// like its metrics.
+func (d *DeviceCheck) register(tags []string) {
+	device := devices.Device{
+		Namespace: d.config.Namespace,
+		IPAddress: d.config.IPAddress,
+		Profile:   d.config.Profile,
+		// the instance tags are added to the metrics by the sender
+		Tags: append(common.CopyStrings(tags), d.config.InstanceTags...),
+	}
+	if d.config.ProfileDef != nil {
+		device.Vendor = d.config.ProfileDef.Device.Vendor
+	}
+	devices.Register(device)
+}
+
+// Unregister unregisters the device when it is no longer monitored.
+func (d *DeviceCheck) Unregister() {
+	devices.Unregister(d.config.Namespace, d.config.IPAddress)
+}
+
 func (d *DeviceCheck) setDeviceHostExternalTags() {
 	deviceHostname, err := d.GetDeviceHostname()
 	if deviceHostname == "" || err != nil {
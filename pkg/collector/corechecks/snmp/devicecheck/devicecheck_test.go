@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/snmp/devices"
 	"github.com/DataDog/datadog-agent/pkg/version"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/checkconfig"
@@ -296,6 +297,17 @@ profiles:
 
 	assert.Len(t, deviceCk.config.Metrics, len(firstRunMetrics))
 	assert.Len(t, deviceCk.config.MetricTags, len(firstRunMetricsTags))
+
+	// the device is registered with the tags of its metrics, to tag its traps like them
+	device, ok := devices.Get("default", "1.2.3.4")
+	assert.True(t, ok)
+	assert.Equal(t, "f5-big-ip", device.Profile)
+	assert.Equal(t, "f5", device.Vendor)
+	assert.Subset(t, device.Tags, snmpTags)
+
+	deviceCk.Unregister()
+	_, ok = devices.Get("default", "1.2.3.4")
+	assert.False(t, ok)
 }
 
 func TestDeviceCheck_Hostname(t *testing.T) {
@@ -253,6 +253,7 @@ func (d *Discovery) deleteDevice(deviceDigest checkconfig.DeviceDigest, subnet *
 		}
 
 		if d.config.DiscoveryAllowedFailures != -1 && failure >= d.config.DiscoveryAllowedFailures {
+			d.discoveredDevices[deviceDigest].deviceCheck.Unregister()
 			delete(d.discoveredDevices, deviceDigest)
 			delete(subnet.devices, deviceDigest)
 			delete(subnet.deviceFailures, deviceDigest)
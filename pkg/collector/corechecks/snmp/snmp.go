@@ -155,6 +155,13 @@ func (c *Check) Configure(rawInstance integration.Data, rawInitConfig integratio
 
 // Cancel is called when check is unscheduled
 func (c *Check) Cancel() {
+	if c.config.IsDiscovery() {
+		for _, deviceCk := range c.discovery.GetDiscoveredDeviceConfigs() {
+			deviceCk.Unregister()
+		}
+	} else if c.singleDeviceCk != nil {
+		c.singleDeviceCk.Unregister()
+	}
 	c.discovery.Stop()
 }
 
@@ -808,6 +808,10 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_sample_size", 500)
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_match_timeout", 30) // Seconds
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_match_threshold", 0.48)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_redetection_window", 0) // Disabled by default
+	// Detect the encoding of files which don't have an `encoding` configured, using
+	// their Byte Order Mark or the distribution of null bytes in their first few KB.
+	config.BindEnvAndSetDefault("logs_config.auto_encoding_detection", true)
 
 	// If true, the agent looks for container logs in the location used by podman, rather
 	// than docker.  This is a temporary configuration parameter to support podman logs until
@@ -815,6 +819,12 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.use_podman_logs", false)
 
 	config.BindEnvAndSetDefault("logs_config.auditor_ttl", DefaultAuditorTTL) // in hours
+	// Durability of the registry holding the offsets of the tailers: it is written on disk every
+	// registry_flush_period seconds, and after registry_flush_messages messages if not 0. With
+	// registry_fsync, each write is synced on disk and replaces the registry file atomically.
+	config.BindEnvAndSetDefault("logs_config.registry_flush_period", 1.0)
+	config.BindEnvAndSetDefault("logs_config.registry_flush_messages", 0)
+	config.BindEnvAndSetDefault("logs_config.registry_fsync", false)
 	// Timeout in milliseonds used when performing agreggation operations,
 	// including multi-line log processing rules and chunked line reaggregation.
 	// It may be useful to increase it when logs writing is slowed down, that
@@ -822,6 +832,40 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.aggregation_timeout", 1000)
 	// Time in seconds
 	config.BindEnvAndSetDefault("logs_config.file_scan_period", 10.0)
+	// Maximum number of nested directories matched by a ** recursive wildcard in the path of a
+	// file log configuration, walked at each scan. 0 means no limit.
+	config.BindEnvAndSetDefault("logs_config.recursive_wildcard_max_depth", 8)
+	// Time in seconds a file tailer keeps reading its file after it has been deleted, so that
+	// the last logs written in short-lived files are collected.
+	config.BindEnvAndSetDefault("logs_config.deleted_file_grace_period", 0)
+	// Time in seconds a file tailer of a one_shot source must read no new data at the end of its
+	// file before the file is read to completion, so that a file still being written is not closed.
+	config.BindEnvAndSetDefault("logs_config.one_shot_completion_window", 5.0)
+	// Maximum time in seconds waited by a file tailer before reading again its file while it
+	// has no new data. The time waited starts at one second and doubles while the file is idle.
+	config.BindEnvAndSetDefault("logs_config.file_tailer_max_sleep_duration", 5.0)
+	// Order in which the files matching wildcard paths are selected when the open_files_limit is reached:
+	// by_name (reverse lexicographical order), by_modification_time (most recent first) or by_size (largest first).
+	config.BindEnvAndSetDefault("logs_config.file_wildcard_selection_mode", "by_name")
+	// Number of bytes a file tailer reads in a turn when the tailers take turns to read their files,
+	// so that the busiest files do not starve the others. The tailers read independently when 0.
+	config.BindEnvAndSetDefault("logs_config.file_read_budget", 0)
+	// Path of the optional sidecar file listing tags, separated by commas or new lines, added to
+	// the logs of a file, such as "{path}.ddtags". The {path}, {dir} and {filename} placeholders
+	// are replaced with the path, the directory and the name of the file. The sidecar files are
+	// ignored when empty, which is the default.
+	config.BindEnvAndSetDefault("logs_config.sidecar_tags_pattern", "")
+	// Time in seconds after which a file tailer which has not read its growing file, or has been
+	// blocked on its outputs, is reported as stuck on the status page and in the telemetry. The
+	// tailers waiting for their full decoders are not stuck. The tailers are not watched when 0.
+	// The stuck tailers are replaced by new ones, reading their files from their last
+	// acknowledged offsets, when restart_stuck_tailers is true.
+	config.BindEnvAndSetDefault("logs_config.stuck_tailer_timeout", 0)
+	config.BindEnvAndSetDefault("logs_config.restart_stuck_tailers", false)
+	// When true, the file tailers read their files with io_uring on linux 5.6 and later, batching
+	// the reads of all the tailers in single system calls. The tailers fall back to read system
+	// calls on older kernels.
+	config.BindEnvAndSetDefault("logs_config.file_io_uring", false)
 
 	// The cardinality of tags to send for checks and dogstatsd respectively.
 	// Choices are: low, orchestrator, high.
@@ -867,10 +911,64 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("cluster_checks.extra_tags", []string{})
 	config.BindEnvAndSetDefault("cluster_checks.advanced_dispatching_enabled", false)
 	config.BindEnvAndSetDefault("cluster_checks.clc_runners_port", 5005)
+	config.BindEnvAndSetDefault("cluster_checks.latency_aware_rebalancing", false)
+	config.BindEnvAndSetDefault("cluster_checks.topology_zone_label", "topology.kubernetes.io/zone")
+	config.BindEnvAndSetDefault("cluster_checks.drain_batch_size", 5)
+	config.BindEnvAndSetDefault("cluster_checks.runner_pool_label", "runner_pool")
+	config.BindEnvAndSetDefault("cluster_checks.runner_pools", map[string]string{})
+	config.BindEnvAndSetDefault("cluster_checks.max_configs_per_runner", 0)
+	config.BindEnvAndSetDefault("cluster_checks.max_workers_per_runner", 0.0)
+	config.BindEnvAndSetDefault("cluster_checks.persist_state", false)
+	config.BindEnvAndSetDefault("cluster_checks.state_configmap_name", "datadog-cluster-checks-state")
+	config.BindEnvAndSetDefault("cluster_checks.handover_on_shutdown", false)
+	config.BindEnvAndSetDefault("cluster_checks.follower_sync_interval", 10) // value in seconds
+	config.BindEnvAndSetDefault("cluster_checks.leader_status_frequency", 5) // value in seconds
+	config.BindEnvAndSetDefault("cluster_checks.leader_status_jitter", 0.2)
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.backend", "kubernetes")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.advertise_address", "")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.lock_name", "datadog-cluster-checks-leader")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.lock_file", "/var/run/datadog/cluster-checks-leader.lock")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.etcd_url", "http://127.0.0.1:2379")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.etcd_username", "")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.etcd_password", "")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.consul_url", "http://127.0.0.1:8500")
+	config.BindEnvAndSetDefault("cluster_checks.leader_election.consul_token", "")
+	config.BindEnvAndSetDefault("cluster_checks.canary_rollout.enabled", false)
+	config.BindEnvAndSetDefault("cluster_checks.canary_rollout.canaries", 1)
+	config.BindEnvAndSetDefault("cluster_checks.canary_rollout.successful_runs", 3)
+	config.BindEnvAndSetDefault("cluster_checks.cost_function.execution_time_weight", 0.8)
+	config.BindEnvAndSetDefault("cluster_checks.cost_function.metric_samples_weight", 0.2)
+	config.BindEnvAndSetDefault("cluster_checks.cost_function.instance_weight", 0.0)
+	config.BindEnvAndSetDefault("cluster_checks.cost_function.config_cost_weight", 1.0)
+	config.BindEnvAndSetDefault("cluster_checks.unhealthy_runners.enabled", false)
+	config.BindEnvAndSetDefault("cluster_checks.unhealthy_runners.exclusion_duration", 300)
+	config.BindEnvAndSetDefault("cluster_checks.unhealthy_runners.max_failure_rate", 0.5)
+	config.BindEnvAndSetDefault("cluster_checks.unhealthy_runners.max_flaps", 3)
+	config.BindEnvAndSetDefault("cluster_checks.unhealthy_runners.flap_window", 600)
+	config.BindEnvAndSetDefault("cluster_checks.isolation_ttl", 3600)
+	config.BindEnvAndSetDefault("cluster_checks.extender.url", "")
+	config.BindEnvAndSetDefault("cluster_checks.extender.timeout", 2)
+	config.BindEnvAndSetDefault("cluster_checks.extender.ignorable", true)
+	config.BindEnvAndSetDefault("cluster_checks.extender.auth_token", "")
+	config.BindEnvAndSetDefault("cluster_checks.extender.tls_ca_file", "")
+	config.BindEnvAndSetDefault("cluster_checks.extender.tls_cert_file", "")
+	config.BindEnvAndSetDefault("cluster_checks.extender.tls_key_file", "")
+	config.BindEnvAndSetDefault("cluster_checks.extender.failure_threshold", 3)
+	config.BindEnvAndSetDefault("cluster_checks.extender.failure_cooldown", 30)
+	config.BindEnvAndSetDefault("cluster_checks.rebalancing.min_imbalance_percent", 0.0)
+	config.BindEnvAndSetDefault("cluster_checks.rebalancing.max_moves", 0)
+	config.BindEnvAndSetDefault("cluster_checks.rebalancing.move_cooldown", 0)
+	config.BindEnvAndSetDefault("cluster_checks.federation.enabled", false)
+	config.BindEnvAndSetDefault("cluster_checks.federation.check_names", []string{})
+	config.BindEnvAndSetDefault("cluster_checks.federation.cluster_name", "")
+	config.BindEnvAndSetDefault("cluster_checks.federation.cluster_tokens", map[string]string{})
 	// Cluster check runner
 	config.BindEnvAndSetDefault("clc_runner_enabled", false)
 	config.BindEnvAndSetDefault("clc_runner_id", "")
 	config.BindEnvAndSetDefault("clc_runner_host", "") // must be set using the Kubernetes downward API
+	config.BindEnvAndSetDefault("clc_runner_labels", map[string]string{})
+	config.BindEnvAndSetDefault("clc_runner_capacity", 0.0)
+	config.BindEnvAndSetDefault("clc_runner_cluster_name", "")
 	config.BindEnvAndSetDefault("clc_runner_port", 5005)
 	config.BindEnvAndSetDefault("clc_runner_server_write_timeout", 15)
 	config.BindEnvAndSetDefault("clc_runner_server_readheader_timeout", 10)
@@ -11,6 +11,7 @@ import (
 	"io"
 	"sort"
 	"text/tabwriter"
+	"time"
 
 	"github.com/fatih/color"
 
@@ -63,6 +64,12 @@ func GetClusterChecks(w io.Writer, checkName string) error {
 		return nil
 	}
 
+	// Print the origin of the state when served by a follower
+	if cr.LeaderAddress != "" {
+		fmt.Fprintln(w, fmt.Sprintf("=== %s state of the leader %s, as of %s ===", color.YellowString("Read-only"), cr.LeaderAddress, time.Unix(cr.Timestamp, 0).Format(time.RFC3339)))
+		fmt.Fprintln(w, "")
+	}
+
 	// Print warmup message
 	if cr.Warmup {
 		fmt.Fprintln(w, fmt.Sprintf("=== %s in progress ===", color.BlueString("Warmup")))
@@ -79,6 +86,15 @@ func GetClusterChecks(w io.Writer, checkName string) error {
 		fmt.Fprintln(w, "")
 	}
 
+	// Print the configs left unscheduled by the node quotas
+	if len(cr.Unscheduled) > 0 {
+		fmt.Fprintln(w, fmt.Sprintf("=== %s configurations, all nodes reached their quota ===", color.RedString("Unscheduled")))
+		for _, c := range cr.Unscheduled {
+			PrintConfig(w, c, checkName)
+		}
+		fmt.Fprintln(w, "")
+	}
+
 	// Print summary of agents
 	if len(cr.Nodes) == 0 {
 		fmt.Fprintln(w, fmt.Sprintf("=== %s agent reporting ===", color.RedString("Zero")))
@@ -87,10 +103,37 @@ func GetClusterChecks(w io.Writer, checkName string) error {
 	}
 	fmt.Fprintln(w, fmt.Sprintf("=== %d agents reporting ===", len(cr.Nodes)))
 	sort.Slice(cr.Nodes, func(i, j int) bool { return cr.Nodes[i].Name < cr.Nodes[j].Name })
+	withZones := false
+	for i, n := range cr.Nodes {
+		if n.Zone != "" {
+			withZones = true
+		}
+		if n.Pool != "" {
+			cr.Nodes[i].Name += fmt.Sprintf(" (pool %s)", n.Pool)
+		}
+		if n.Cluster != "" {
+			cr.Nodes[i].Name += fmt.Sprintf(" (cluster %s)", n.Cluster)
+		}
+		if n.Isolated {
+			cr.Nodes[i].Name += " (isolated)"
+		} else if n.Draining {
+			cr.Nodes[i].Name += " (draining)"
+		}
+		if n.Excluded {
+			cr.Nodes[i].Name += " (excluded)"
+		}
+	}
 	table := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(table, "\nName\tRunning checks")
-	for _, n := range cr.Nodes {
-		fmt.Fprintf(table, "%s\t%d\n", n.Name, len(n.Configs))
+	if withZones {
+		fmt.Fprintln(table, "\nName\tZone\tRunning checks\tCost")
+		for _, n := range cr.Nodes {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%d\n", n.Name, n.Zone, len(n.Configs), n.Cost)
+		}
+	} else {
+		fmt.Fprintln(table, "\nName\tRunning checks\tCost")
+		for _, n := range cr.Nodes {
+			fmt.Fprintf(table, "%s\t%d\t%d\n", n.Name, len(n.Configs), n.Cost)
+		}
 	}
 	table.Flush()
 
@@ -144,6 +144,15 @@ func PrintConfig(w io.Writer, c integration.Config, checkName string) {
 		}
 		printContainerExclusionRulesInfo(w, &c)
 	}
+	if c.PinnedNode != "" {
+		fmt.Fprintln(w, fmt.Sprintf("%s: %s", color.BlueString("Pinned to node"), color.CyanString(c.PinnedNode)))
+	}
+	if c.RunnerPool != "" {
+		fmt.Fprintln(w, fmt.Sprintf("%s: %s", color.BlueString("Runner pool"), color.CyanString(c.RunnerPool)))
+	}
+	if c.Cost != 0 {
+		fmt.Fprintln(w, fmt.Sprintf("%s: %s", color.BlueString("Dispatching cost"), color.CyanString("%d", c.Cost)))
+	}
 	if c.NodeName != "" {
 		state := fmt.Sprintf("dispatched to %s", c.NodeName)
 		fmt.Fprintln(w, fmt.Sprintf("%s: %s", color.BlueString("State"), color.CyanString(state)))
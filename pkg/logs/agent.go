@@ -42,6 +42,9 @@ type Agent struct {
 	inputs                    []restart.Restartable
 	health                    *health.Handle
 	diagnosticMessageReceiver *diagnostic.BufferedMessageReceiver
+	// fileLauncher is the launcher of the file tailers, which can be paused at runtime,
+	// nil in a serverless environment
+	fileLauncher *filelauncher.Launcher
 }
 
 // NewAgent returns a new Logs Agent
@@ -52,7 +55,12 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	// We pass the health handle to the auditor because it's the end of the pipeline and the most
 	// critical part. Arguably it could also be plugged to the destination.
 	auditorTTL := time.Duration(coreConfig.Datadog.GetInt("logs_config.auditor_ttl")) * time.Hour
-	auditor := auditor.New(coreConfig.Datadog.GetString("logs_config.run_path"), auditor.DefaultRegistryFilename, auditorTTL, health)
+	auditorFlushPolicy := auditor.FlushPolicy{
+		Period:   time.Duration(coreConfig.Datadog.GetFloat64("logs_config.registry_flush_period") * float64(time.Second)),
+		Messages: coreConfig.Datadog.GetInt("logs_config.registry_flush_messages"),
+		Fsync:    coreConfig.Datadog.GetBool("logs_config.registry_fsync"),
+	}
+	auditor := auditor.NewWithFlushPolicy(coreConfig.Datadog.GetString("logs_config.run_path"), auditor.DefaultRegistryFilename, auditorTTL, health, auditorFlushPolicy)
 	destinationsCtx := client.NewDestinationsContext()
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver()
 
@@ -88,10 +96,15 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 
 	validatePodContainerID := coreConfig.Datadog.GetBool("logs_config.validate_pod_container_id")
 
+	fileLauncher := filelauncher.NewLauncher(sources, coreConfig.Datadog.GetInt("logs_config.open_files_limit"), pipelineProvider, auditor,
+		filelauncher.DefaultSleepDuration, validatePodContainerID, time.Duration(coreConfig.Datadog.GetFloat64("logs_config.file_scan_period")*float64(time.Second)),
+		coreConfig.Datadog.GetString("logs_config.file_wildcard_selection_mode"), coreConfig.Datadog.GetInt("logs_config.file_read_budget"))
+	fileLauncher.WatchStuckTailers(time.Duration(coreConfig.Datadog.GetFloat64("logs_config.stuck_tailer_timeout")*float64(time.Second)),
+		coreConfig.Datadog.GetBool("logs_config.restart_stuck_tailers"))
+
 	// setup the inputs
 	inputs := []restart.Restartable{
-		filelauncher.NewLauncher(sources, coreConfig.Datadog.GetInt("logs_config.open_files_limit"), pipelineProvider, auditor,
-			filelauncher.DefaultSleepDuration, validatePodContainerID, time.Duration(coreConfig.Datadog.GetFloat64("logs_config.file_scan_period")*float64(time.Second))),
+		fileLauncher,
 		listener.NewLauncher(sources, coreConfig.Datadog.GetInt("logs_config.frame_size"), pipelineProvider),
 		journald.NewLauncher(sources, pipelineProvider, auditor),
 		windowsevent.NewLauncher(sources, pipelineProvider),
@@ -110,6 +123,7 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 		inputs:                    inputs,
 		health:                    health,
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		fileLauncher:              fileLauncher,
 	}
 }
 
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,13 +28,39 @@ const DefaultRegistryFilename = "registry.json"
 const defaultFlushPeriod = 1 * time.Second
 const defaultCleanupPeriod = 300 * time.Second
 
+// multiLinePatternTTL is the time after which a persisted multiline pattern is not used anymore,
+// so that the pattern of a file is detected again when its content changes.
+const multiLinePatternTTL = 24 * time.Hour
+
+// filePrefix is the prefix of the identifiers of the files in the registry, followed by their path.
+const filePrefix = "file:"
+
 // latest version of the API used by the auditor to retrieve the registry from disk.
 const registryAPIVersion = 2
 
+// FlushPolicy defines when the registry is written on disk, trading throughput
+// against the number of messages sent again or lost after a crash of the agent.
+type FlushPolicy struct {
+	// Period is the time between two writes of the registry.
+	Period time.Duration
+	// Messages is the number of messages handled after which the registry is written
+	// before the end of the period, 0 disables it.
+	Messages int
+	// Fsync syncs the registry on disk at each write, the registry file is replaced
+	// atomically so that it is never left partially written.
+	Fsync bool
+}
+
+// DefaultFlushPolicy writes the registry every second, without syncing it on disk.
+var DefaultFlushPolicy = FlushPolicy{Period: defaultFlushPeriod}
+
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetMultiLinePattern(identifier string) string
+	GetFingerprint(identifier string) string
+	IsCompleted(identifier string) bool
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -43,6 +70,14 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	// MultiLinePattern is the multiline pattern auto-detected for the content of the identifier, if any
+	MultiLinePattern string `json:",omitempty"`
+	// MultiLinePatternDetected is the unix time at which the multiline pattern was first persisted
+	MultiLinePatternDetected int64 `json:",omitempty"`
+	// Completed is true once the file of the identifier has been read to completion
+	Completed bool `json:",omitempty"`
+	// Fingerprint is the checksum of the first bytes of the file of the identifier, if any
+	Fingerprint string `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -69,15 +104,25 @@ type RegistryAuditor struct {
 	registryPath  string
 	registryMutex sync.Mutex
 	entryTTL      time.Duration
+	flushPolicy   FlushPolicy
 	done          chan struct{}
 }
 
 // New returns an initialized Auditor
 func New(runPath string, filename string, ttl time.Duration, health *health.Handle) *RegistryAuditor {
+	return NewWithFlushPolicy(runPath, filename, ttl, health, DefaultFlushPolicy)
+}
+
+// NewWithFlushPolicy returns an initialized Auditor writing its registry on disk according to the flush policy
+func NewWithFlushPolicy(runPath string, filename string, ttl time.Duration, health *health.Handle, flushPolicy FlushPolicy) *RegistryAuditor {
+	if flushPolicy.Period <= 0 {
+		flushPolicy.Period = defaultFlushPeriod
+	}
 	return &RegistryAuditor{
 		health:       health,
 		registryPath: filepath.Join(runPath, filename),
 		entryTTL:     ttl,
+		flushPolicy:  flushPolicy,
 	}
 }
 
@@ -86,6 +131,7 @@ func (a *RegistryAuditor) Start() {
 	a.createChannels()
 	a.registry = a.recoverRegistry()
 	a.cleanupRegistry()
+	a.migrateRegistry()
 	go a.run()
 }
 
@@ -149,10 +195,46 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetMultiLinePattern returns the last multiline pattern detected for a given identifier,
+// returns an empty string if it does not exist or if it was detected too long ago.
+func (a *RegistryAuditor) GetMultiLinePattern(identifier string) string {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return ""
+	}
+	if time.Unix(entry.MultiLinePatternDetected, 0).Before(time.Now().Add(-multiLinePatternTTL)) {
+		return ""
+	}
+	return entry.MultiLinePattern
+}
+
+// GetFingerprint returns the fingerprint of the file of a given identifier,
+// returns an empty string if it does not exist.
+func (a *RegistryAuditor) GetFingerprint(identifier string) string {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return ""
+	}
+	return entry.Fingerprint
+}
+
+// IsCompleted returns true if the file of a given identifier has been read to completion,
+// false if it does not exist.
+func (a *RegistryAuditor) IsCompleted(identifier string) bool {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return false
+	}
+	return entry.Completed
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
-	flushTicker := time.NewTicker(defaultFlushPeriod)
+	flushTicker := time.NewTicker(a.flushPolicy.Period)
 	defer func() {
 		// clean the context
 		cleanUpTicker.Stop()
@@ -161,6 +243,22 @@ func (a *RegistryAuditor) run() {
 	}()
 
 	var fileError sync.Once
+	flush := func() {
+		// saves current registry into disk
+		err := a.flushRegistry()
+		if err != nil {
+			if os.IsPermission(err) || os.IsNotExist(err) {
+				fileError.Do(func() {
+					log.Warn(err)
+				})
+			} else {
+				log.Warn(err)
+			}
+		}
+	}
+
+	// number of messages handled since the last write of the registry
+	messages := 0
 	for {
 		select {
 		case <-a.health.C:
@@ -171,23 +269,27 @@ func (a *RegistryAuditor) run() {
 			}
 			// update the registry with new entry
 			for _, msg := range payload.Messages {
-				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
+				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.MultiLinePattern, msg.IngestionTimestamp)
+				if msg.Origin.Identifier != "" && msg.Origin.InodeIdentifier != "" {
+					a.updateRegistry(msg.Origin.InodeIdentifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.MultiLinePattern, msg.IngestionTimestamp)
+					a.fingerprintRegistry(msg.Origin.InodeIdentifier, msg.Origin.Fingerprint)
+				}
+				if msg.Origin.Completed && msg.Origin.Identifier != "" && msg.Origin.InodeIdentifier != "" {
+					// the completion belongs to the file, not to its path which can be reused
+					a.completeRegistry(msg.Origin.InodeIdentifier)
+				}
+			}
+			messages += len(payload.Messages)
+			if a.flushPolicy.Messages > 0 && messages >= a.flushPolicy.Messages {
+				flush()
+				messages = 0
 			}
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
 			a.cleanupRegistry()
 		case <-flushTicker.C:
-			// saves current registry into disk
-			err := a.flushRegistry()
-			if err != nil {
-				if os.IsPermission(err) || os.IsNotExist(err) {
-					fileError.Do(func() {
-						log.Warn(err)
-					})
-				} else {
-					log.Warn(err)
-				}
-			}
+			flush()
+			messages = 0
 		}
 	}
 }
@@ -223,8 +325,38 @@ func (a *RegistryAuditor) cleanupRegistry() {
 	}
 }
 
-// updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
+// migrateRegistry adds the device and inode identifiers of the files having only an entry
+// identified by their path, written by a previous version of the agent.
+func (a *RegistryAuditor) migrateRegistry() {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	migrated := 0
+	for identifier, entry := range a.registry {
+		if !strings.HasPrefix(identifier, filePrefix) {
+			continue
+		}
+		info, err := os.Stat(strings.TrimPrefix(identifier, filePrefix))
+		if err != nil {
+			continue
+		}
+		inodeIdentifier := InodeIdentifier(info)
+		if inodeIdentifier == "" {
+			continue
+		}
+		if _, exists := a.registry[inodeIdentifier]; exists {
+			continue
+		}
+		migratedEntry := *entry
+		a.registry[inodeIdentifier] = &migratedEntry
+		migrated++
+	}
+	if migrated > 0 {
+		log.Infof("Added the device and inode identifiers of %d files to the registry", migrated)
+	}
+}
+
+// updateRegistry updates the registry entry matching identifier with new the offset, multiline pattern and timestamp
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, multiLinePattern string, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -236,17 +368,47 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 
 	// Don't update the registry with a value older than the current one
 	// This can happen when dual shipping and 2 destinations are sending the same payload successfully
+	now := time.Now().UTC()
+	multiLinePatternDetected := now.Unix()
 	if v, ok := a.registry[identifier]; ok {
 		if v.IngestionTimestamp > ingestionTimestamp {
 			return
 		}
+		// a pattern recovered after a restart keeps the time of its detection, to expire
+		if v.MultiLinePattern == multiLinePattern {
+			multiLinePatternDetected = v.MultiLinePatternDetected
+		}
+	}
+	if multiLinePattern == "" {
+		multiLinePatternDetected = 0
 	}
 
 	a.registry[identifier] = &RegistryEntry{
-		LastUpdated:        time.Now().UTC(),
-		Offset:             offset,
-		TailingMode:        tailingMode,
-		IngestionTimestamp: ingestionTimestamp,
+		LastUpdated:              now,
+		Offset:                   offset,
+		TailingMode:              tailingMode,
+		IngestionTimestamp:       ingestionTimestamp,
+		MultiLinePattern:         multiLinePattern,
+		MultiLinePatternDetected: multiLinePatternDetected,
+	}
+}
+
+// fingerprintRegistry records the fingerprint of the file of the identifier.
+func (a *RegistryAuditor) fingerprintRegistry(identifier string, fingerprint string) {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	if entry, exists := a.registry[identifier]; exists {
+		entry.Fingerprint = fingerprint
+	}
+}
+
+// completeRegistry records that the file of the identifier has been read to completion, until
+// the entry is updated again.
+func (a *RegistryAuditor) completeRegistry(identifier string) {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	if entry, exists := a.registry[identifier]; exists {
+		entry.Completed = true
 	}
 }
 
@@ -268,9 +430,33 @@ func (a *RegistryAuditor) flushRegistry() error {
 	if err != nil {
 		return err
 	}
+	if a.flushPolicy.Fsync {
+		return writeFileSync(a.registryPath, mr, 0644)
+	}
 	return ioutil.WriteFile(a.registryPath, mr, 0644)
 }
 
+// writeFileSync writes data to a temporary file synced on disk, then renames it to path,
+// so that the file at path is either the previous one or the new one, even after a crash.
+func writeFileSync(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
 // marshalRegistry marshals a registry
 func (a *RegistryAuditor) marshalRegistry(registry map[string]RegistryEntry) ([]byte, error) {
 	r := JSONRegistry{
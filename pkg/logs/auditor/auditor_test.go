@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 var testpath = "testpath"
@@ -62,11 +64,11 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", "", 0)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 1)
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", "", 1)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
@@ -103,6 +105,55 @@ func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForOffset() {
 	suite.Equal("", offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversMultiLinePattern() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", `^\d{4}-\d{2}-\d{2}`, 0)
+	suite.Equal(`^\d{4}-\d{2}-\d{2}`, suite.a.GetMultiLinePattern(suite.source.Config.Path))
+	suite.Nil(suite.a.flushRegistry())
+
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.Equal(`^\d{4}-\d{2}-\d{2}`, suite.a.GetMultiLinePattern(suite.source.Config.Path))
+	suite.Equal("", suite.a.GetMultiLinePattern("anotherpath"))
+
+	// the pattern is forgotten when it is not detected anymore
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "end", "", 1)
+	suite.Equal("", suite.a.GetMultiLinePattern(suite.source.Config.Path))
+}
+
+func (suite *AuditorTestSuite) TestAuditorExpiresMultiLinePattern() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", `^\d{4}-\d{2}-\d{2}`, 0)
+	detected := time.Now().Add(-multiLinePatternTTL - time.Minute).Unix()
+	suite.a.registry[suite.source.Config.Path].MultiLinePatternDetected = detected
+
+	// the same pattern keeps the time of its detection, and is not used anymore
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "end", `^\d{4}-\d{2}-\d{2}`, 1)
+	suite.Equal(detected, suite.a.registry[suite.source.Config.Path].MultiLinePatternDetected)
+	suite.Equal("", suite.a.GetMultiLinePattern(suite.source.Config.Path))
+
+	// a pattern detected again is used
+	suite.a.updateRegistry(suite.source.Config.Path, "44", "end", "", 2)
+	suite.a.updateRegistry(suite.source.Config.Path, "45", "end", `^\d{4}-\d{2}-\d{2}`, 3)
+	suite.Equal(`^\d{4}-\d{2}-\d{2}`, suite.a.GetMultiLinePattern(suite.source.Config.Path))
+}
+
+func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversCompletion() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", "", 0)
+	suite.False(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.a.completeRegistry(suite.source.Config.Path)
+	suite.True(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.Nil(suite.a.flushRegistry())
+
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.True(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.False(suite.a.IsCompleted("anotherpath"))
+
+	// the completion is forgotten when the file is read again
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "end", "", 1)
+	suite.False(suite.a.IsCompleted(suite.source.Config.Path))
+}
+
 func (suite *AuditorTestSuite) TestAuditorCleansupRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
@@ -123,6 +174,54 @@ func (suite *AuditorTestSuite) TestAuditorCleansupRegistry() {
 	suite.Equal("43", suite.a.registry[otherpath].Offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistryWithFsync() {
+	suite.a.flushPolicy.Fsync = true
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		TailingMode: "end",
+	}
+	suite.Nil(suite.a.flushRegistry())
+	r, err := ioutil.ReadFile(suite.testPath)
+	suite.Nil(err)
+	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0}}}", string(r))
+	_, err = os.Stat(suite.testPath + ".tmp")
+	suite.True(os.IsNotExist(err))
+
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
+}
+
+func (suite *AuditorTestSuite) TestAuditorFlushesRegistryAfterMessages() {
+	suite.a.flushPolicy = FlushPolicy{Period: time.Hour, Messages: 2}
+	suite.a.Start()
+	defer suite.a.Stop()
+
+	payload := &message.Payload{Messages: []*message.Message{newMessage(suite.source, "42")}}
+	suite.a.Channel() <- payload
+	// the registry is not written before the end of the period after a single message
+	time.Sleep(50 * time.Millisecond)
+	r, err := ioutil.ReadFile(suite.testPath)
+	suite.Nil(err)
+	suite.NotContains(string(r), "\"Offset\":\"42\"")
+
+	payload = &message.Payload{Messages: []*message.Message{newMessage(suite.source, "43")}}
+	suite.a.Channel() <- payload
+	suite.Eventually(func() bool {
+		r, err := ioutil.ReadFile(suite.testPath)
+		return err == nil && strings.Contains(string(r), "\"Offset\":\"43\"")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func newMessage(source *config.LogSource, offset string) *message.Message {
+	origin := message.NewOrigin(source)
+	origin.Identifier = source.Config.Path
+	origin.Offset = offset
+	return message.NewMessage(nil, origin, "", time.Now().UnixNano())
+}
+
 func TestScannerTestSuite(t *testing.T) {
 	suite.Run(t, new(AuditorTestSuite))
 }
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"fmt"
+	"hash/crc64"
+	"io"
+	"os"
+)
+
+// FingerprintSize is the number of bytes at the beginning of a file used to fingerprint it.
+const FingerprintSize = 1024
+
+var fingerprintTable = crc64.MakeTable(crc64.ECMA)
+
+// Fingerprint returns a checksum of the first bytes of the file at path, to tell it apart
+// from a new file reusing its device and inode numbers. It returns an empty string when the
+// file is too short to be fingerprinted, or when it is not identified by inodeIdentifier.
+func Fingerprint(path string, inodeIdentifier string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || InodeIdentifier(info) != inodeIdentifier {
+		return ""
+	}
+	buf := make([]byte, FingerprintSize)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("crc64:%016x", crc64.Checksum(buf, fingerprintTable))
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package auditor
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// InodeIdentifier returns the registry identifier of a file built from its device and inode
+// numbers, which does not change when the file is moved or accessed from another path.
+// It returns an empty string if they are not available.
+func InodeIdentifier(info os.FileInfo) string {
+	if info == nil {
+		return ""
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("inode:%d:%d", stat.Dev, stat.Ino)
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package auditor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+)
+
+func TestInodeIdentifierSurvivesRename(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "tests")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "file.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	identifier := InodeIdentifier(info)
+	assert.Regexp(t, `^inode:\d+:\d+$`, identifier)
+
+	renamedPath := filepath.Join(testDir, "renamed.log")
+	require.NoError(t, os.Rename(path, renamedPath))
+	info, err = os.Stat(renamedPath)
+	require.NoError(t, err)
+	assert.Equal(t, identifier, InodeIdentifier(info))
+
+	assert.Equal(t, "", InodeIdentifier(nil))
+}
+
+func TestFingerprint(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "tests")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "file.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	// the file is too short to be fingerprinted
+	assert.Equal(t, "", Fingerprint(path, InodeIdentifier(info)))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Repeat("hello\n", 1000)), 0644))
+	fingerprint := Fingerprint(path, InodeIdentifier(info))
+	assert.Regexp(t, `^crc64:[0-9a-f]{16}$`, fingerprint)
+	assert.Equal(t, "", Fingerprint(path, "inode:0:0"))
+
+	// only the beginning of the file is fingerprinted
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("world\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, fingerprint, Fingerprint(path, InodeIdentifier(info)))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Repeat("world\n", 1000)), 0644))
+	assert.NotEqual(t, fingerprint, Fingerprint(path, InodeIdentifier(info)))
+}
+
+func TestAuditorMigratesPathIdentifiers(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "tests")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "file.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	a := New(testDir, DefaultRegistryFilename, time.Hour, health.RegisterLiveness("fake"))
+	a.registry = map[string]*RegistryEntry{
+		"file:" + path: {LastUpdated: time.Now().UTC(), Offset: "6", TailingMode: "end"},
+		"file:" + filepath.Join(testDir, "missing.log"): {LastUpdated: time.Now().UTC(), Offset: "42"},
+		"journald:": {LastUpdated: time.Now().UTC(), Offset: "cursor"},
+	}
+	require.NoError(t, a.flushRegistry())
+
+	a.Start()
+	defer a.Stop()
+	assert.Equal(t, "6", a.GetOffset(InodeIdentifier(info)))
+	assert.Equal(t, "end", a.GetTailingMode(InodeIdentifier(info)))
+	// the path entries are kept
+	assert.Equal(t, "6", a.GetOffset("file:"+path))
+	assert.Len(t, a.readOnlyRegistryCopy(), 4)
+}
@@ -7,8 +7,11 @@ package mock
 
 // Registry does nothing
 type Registry struct {
-	offset      string
-	tailingMode string
+	offset           string
+	tailingMode      string
+	multiLinePattern string
+	fingerprint      string
+	completed        bool
 }
 
 // NewRegistry returns a new registry.
@@ -35,3 +38,33 @@ func (r *Registry) GetTailingMode(identifier string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetMultiLinePattern returns the multiline pattern.
+func (r *Registry) GetMultiLinePattern(identifier string) string {
+	return r.multiLinePattern
+}
+
+// SetMultiLinePattern sets the multiline pattern.
+func (r *Registry) SetMultiLinePattern(multiLinePattern string) {
+	r.multiLinePattern = multiLinePattern
+}
+
+// GetFingerprint returns the fingerprint.
+func (r *Registry) GetFingerprint(identifier string) string {
+	return r.fingerprint
+}
+
+// SetFingerprint sets the fingerprint.
+func (r *Registry) SetFingerprint(fingerprint string) {
+	r.fingerprint = fingerprint
+}
+
+// IsCompleted returns whether the file has been read to completion.
+func (r *Registry) IsCompleted(identifier string) bool {
+	return r.completed
+}
+
+// SetCompleted sets whether the file has been read to completion.
+func (r *Registry) SetCompleted(completed bool) {
+	r.completed = completed
+}
@@ -32,6 +32,15 @@ func (a *NullAuditor) GetOffset(identifier string) string { return "" }
 // GetTailingMode returns an empty string.
 func (a *NullAuditor) GetTailingMode(identifier string) string { return "" }
 
+// GetMultiLinePattern returns an empty string.
+func (a *NullAuditor) GetMultiLinePattern(identifier string) string { return "" }
+
+// GetFingerprint returns an empty string.
+func (a *NullAuditor) GetFingerprint(identifier string) string { return "" }
+
+// IsCompleted returns false.
+func (a *NullAuditor) IsCompleted(identifier string) bool { return false }
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
@@ -74,7 +74,7 @@ func ContainerCollectAllSource() *LogSource {
 
 // SNMPTrapsSource returs a source to forward SNMP traps as logs.
 func SNMPTrapsSource() *LogSource {
-	if traps.IsEnabled() && traps.IsRunning() {
+	if traps.IsEnabled() && traps.IsRunning() && traps.IsLogsOutputEnabled() {
 		// source to forward SNMP traps as logs.
 		return NewLogSource(SnmpTraps, &LogsConfig{
 			Type:    SnmpTrapsType,
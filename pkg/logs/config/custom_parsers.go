@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	customParsersMutex sync.RWMutex
+	customParsers      = make(map[string]struct{})
+)
+
+// RegisterCustomParser records the name of a custom parser, which the sources can select with
+// their parser option. The parsers themselves are registered with decoder.RegisterParser.
+func RegisterCustomParser(name string) {
+	customParsersMutex.Lock()
+	defer customParsersMutex.Unlock()
+	customParsers[name] = struct{}{}
+}
+
+// UnregisterCustomParser forgets the name of a custom parser.
+func UnregisterCustomParser(name string) {
+	customParsersMutex.Lock()
+	defer customParsersMutex.Unlock()
+	delete(customParsers, name)
+}
+
+// CustomParsers returns the sorted names of the custom parsers.
+func CustomParsers() []string {
+	customParsersMutex.RLock()
+	defer customParsersMutex.RUnlock()
+	names := make([]string, 0, len(customParsers))
+	for name := range customParsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *LogsConfig) validateParser() error {
+	if c.Parser == "" {
+		return nil
+	}
+	customParsersMutex.RLock()
+	_, exists := customParsers[c.Parser]
+	customParsersMutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("unknown parser: %s, registered parsers are [%s]", c.Parser, strings.Join(CustomParsers(), ", "))
+	}
+	return nil
+}
@@ -7,7 +7,9 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 )
@@ -27,8 +29,47 @@ const (
 	UTF16BE string = "utf-16-be"
 	// UTF16LE for UTF-16 Little Endian encoding
 	UTF16LE string = "utf-16-le"
+	// UTF32BE for UTF-32 Big endian encoding
+	UTF32BE string = "utf-32-be"
+	// UTF32LE for UTF-32 Little Endian encoding
+	UTF32LE string = "utf-32-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+	// EUCJP for EUC-JP (Japanese) encoding
+	EUCJP string = "euc-jp"
+	// GB18030 for GB18030 (Chinese) encoding
+	GB18030 string = "gb18030"
+)
+
+// Framings of the data of the sources made of length-prefixed binary records, the lines
+// separated by new lines being the default framing.
+const (
+	// LengthPrefixedVarint for records prefixed with their length as an unsigned varint, e.g. delimited protobuf
+	LengthPrefixedVarint string = "length_prefixed_varint"
+	// LengthPrefixedUint32BE for records prefixed with their length as a big-endian 32-bit unsigned integer
+	LengthPrefixedUint32BE string = "length_prefixed_uint32_be"
+	// LengthPrefixedUint32LE for records prefixed with their length as a little-endian 32-bit unsigned integer
+	LengthPrefixedUint32LE string = "length_prefixed_uint32_le"
+)
+
+// Handlings of the lines longer than the content limit, which are truncated by default.
+const (
+	// LongLinesTruncate splits the long lines into messages flagged as truncated
+	LongLinesTruncate string = "truncate"
+	// LongLinesChunk splits the long lines into chunks tagged with a correlation ID and a
+	// sequence number, to be reassembled by the backend or a downstream processor
+	LongLinesChunk string = "chunk"
+)
+
+// Handlings of the carriage returns of the lines, the carriage returns being preserved by default.
+const (
+	// CarriageReturnPreserve leaves the carriage returns in the content of the lines
+	CarriageReturnPreserve string = "preserve"
+	// CarriageReturnStrip removes the carriage returns preceding the line feeds, e.g. of CRLF files
+	CarriageReturnStrip string = "strip"
+	// CarriageReturnTerminator ends the lines at the carriage returns as well, a CRLF sequence
+	// ending a single line, e.g. for old Mac or embedded devices logs
+	CarriageReturnTerminator string = "terminator"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -43,6 +84,14 @@ type LogsConfig struct {
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
+	// PathTagsPattern is a regular expression matched against the path of the files, its named
+	// capture groups are added as tags instead of the filename and dirname tags.
+	PathTagsPattern string `mapstructure:"path_tags_pattern" json:"path_tags_pattern"` // File
+	// PathTagsRegex is PathTagsPattern compiled when the config is validated.
+	PathTagsRegex *regexp.Regexp `mapstructure:"-" json:"-"` // File
+	// CloseTimeout is the number of seconds a tailer keeps reading a file after it has been
+	// rotated, it overrides logs_config.close_timeout when set.
+	CloseTimeout int `mapstructure:"close_timeout" json:"close_timeout"` // File
 
 	IncludeUnits  []string `mapstructure:"include_units" json:"include_units"`   // Journald
 	ExcludeUnits  []string `mapstructure:"exclude_units" json:"exclude_units"`   // Journald
@@ -68,9 +117,76 @@ type LogsConfig struct {
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
 
+	// StripANSISequences removes ANSI escape sequences (colors, cursor movements...) from the content.
+	StripANSISequences bool `mapstructure:"strip_ansi_sequences" json:"strip_ansi_sequences"`
+	// JSONAttributes promotes attributes of JSON logs to the status and the tags of the messages.
+	JSONAttributes *JSONAttributes `mapstructure:"json_attributes" json:"json_attributes"`
+	// Framing defines how the data is broken into messages, the lines are separated by new lines
+	// when it is empty, otherwise each length-prefixed record is a message with a binary content.
+	Framing string `mapstructure:"framing" json:"framing"`
+	// CarriageReturn defines how the carriage returns are handled when breaking the data into
+	// lines separated by single-byte line feeds, they are preserved when it is empty.
+	CarriageReturn string `mapstructure:"carriage_return" json:"carriage_return"`
+	// LongLines defines how the lines longer than the content limit are split into messages,
+	// they are truncated when it is empty.
+	LongLines string `mapstructure:"long_lines" json:"long_lines"`
+	// OneShot makes the tailers of the files of the source stop once they have read them to
+	// completion, rather than waiting for new data, for files which are not written anymore.
+	OneShot bool `mapstructure:"one_shot" json:"one_shot"`
+	// MinSeverity is the least severe status of the logs collected, the logs with a less severe
+	// status, e.g. detected from their JSON attributes, are dropped.
+	MinSeverity string `mapstructure:"min_severity" json:"min_severity"`
+	// RateLimit limits the number of lines or bytes per second decoded by each tailer of the source.
+	RateLimit *RateLimit `mapstructure:"rate_limit" json:"rate_limit"`
+	// Timestamp parses the timestamp of the logs from their content, to use it as the time of the
+	// messages instead of the time they are collected at.
+	Timestamp *TimestampParsing `mapstructure:"timestamp" json:"timestamp"`
+	// Parser is the name of the custom parser of the lines of the source, registered with
+	// decoder.RegisterParser.
+	Parser string `mapstructure:"parser" json:"parser"`
+
 	AutoMultiLine               *bool   `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
 	AutoMultiLineSampleSize     int     `mapstructure:"auto_multi_line_sample_size" json:"auto_multi_line_sample_size"`
 	AutoMultiLineMatchThreshold float64 `mapstructure:"auto_multi_line_match_threshold" json:"auto_multi_line_match_threshold"`
+	// AutoMultiLineRedetectionWindow is the number of consecutive lines not matching the detected pattern
+	// after which the multiline auto detection starts over.
+	AutoMultiLineRedetectionWindow int `mapstructure:"auto_multi_line_redetection_window" json:"auto_multi_line_redetection_window"`
+}
+
+// JSONAttributes defines the attributes of JSON logs promoted to the status and the tags of
+// the messages. Nested attributes are referred to with a dot-separated path, e.g. "log.level".
+type JSONAttributes struct {
+	// Status is the attribute holding the level of the log, mapped to a status.
+	Status string `mapstructure:"status" json:"status"`
+	// Tags are the attributes added as tags, using the attribute as tag name.
+	Tags []string `mapstructure:"tags" json:"tags"`
+}
+
+// TimestampParsing defines how the timestamp of the logs is parsed from their content.
+type TimestampParsing struct {
+	// Pattern is a regular expression matching the timestamp in the content, its first capture
+	// group holding the timestamp if any. The timestamp is at the start of the content when empty.
+	Pattern string `mapstructure:"pattern" json:"pattern"`
+	// Layouts are the Go time layouts tried in order to parse the timestamp, e.g. "2006-01-02 15:04:05".
+	Layouts []string `mapstructure:"layouts" json:"layouts"`
+	// Timezone is the IANA name of the time zone of the timestamps without zone, UTC by default.
+	Timezone string `mapstructure:"timezone" json:"timezone"`
+}
+
+// Rate limit policies
+const (
+	// RateLimitBlock holds the tailer until the rate limit allows new messages (default).
+	RateLimitBlock = "block"
+	// RateLimitDrop drops the messages exceeding the rate limit.
+	RateLimitDrop = "drop"
+)
+
+// RateLimit defines the maximum throughput of a tailer, and what to do with the
+// messages exceeding it. A zero limit is not enforced.
+type RateLimit struct {
+	LinesPerSecond int    `mapstructure:"lines_per_second" json:"lines_per_second"`
+	BytesPerSecond int    `mapstructure:"bytes_per_second" json:"bytes_per_second"`
+	Policy         string `mapstructure:"policy" json:"policy"`
 }
 
 // TailingMode type
@@ -130,18 +246,128 @@ func (c *LogsConfig) Validate() error {
 		if err != nil {
 			return err
 		}
+		err = c.validatePathTagsPattern()
+		if err != nil {
+			return err
+		}
+		if c.CloseTimeout < 0 {
+			return fmt.Errorf("close_timeout must not be negative")
+		}
 	case c.Type == TCPType && c.Port == 0:
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
 	}
-	err := ValidateProcessingRules(c.ProcessingRules)
+	err := c.validateRateLimit()
+	if err != nil {
+		return err
+	}
+	err = c.validateMinSeverity()
+	if err != nil {
+		return err
+	}
+	err = c.validateFraming()
+	if err != nil {
+		return err
+	}
+	err = c.validateCarriageReturn()
+	if err != nil {
+		return err
+	}
+	err = c.validateLongLines()
+	if err != nil {
+		return err
+	}
+	err = c.validateTimestamp()
+	if err != nil {
+		return err
+	}
+	err = c.validateParser()
+	if err != nil {
+		return err
+	}
+	err = ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
 		return err
 	}
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+// severities are the statuses of the logs, from the most to the least severe.
+var severities = []string{"emergency", "alert", "critical", "error", "warn", "notice", "info", "debug"}
+
+func (c *LogsConfig) validateMinSeverity() error {
+	if c.MinSeverity == "" {
+		return nil
+	}
+	for _, severity := range severities {
+		if c.MinSeverity == severity {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid min_severity: %s, supported values are %s", c.MinSeverity, strings.Join(severities, ", "))
+}
+
+func (c *LogsConfig) validateFraming() error {
+	switch c.Framing {
+	case "", LengthPrefixedVarint, LengthPrefixedUint32BE, LengthPrefixedUint32LE:
+		return nil
+	default:
+		return fmt.Errorf("invalid framing: %s", c.Framing)
+	}
+}
+
+func (c *LogsConfig) validateCarriageReturn() error {
+	switch c.CarriageReturn {
+	case "", CarriageReturnPreserve, CarriageReturnStrip, CarriageReturnTerminator:
+		return nil
+	default:
+		return fmt.Errorf("invalid carriage return handling: %s", c.CarriageReturn)
+	}
+}
+
+func (c *LogsConfig) validateLongLines() error {
+	switch c.LongLines {
+	case "", LongLinesTruncate, LongLinesChunk:
+		return nil
+	default:
+		return fmt.Errorf("invalid long lines handling: %s", c.LongLines)
+	}
+}
+
+func (c *LogsConfig) validateTimestamp() error {
+	if c.Timestamp == nil {
+		return nil
+	}
+	if len(c.Timestamp.Layouts) == 0 {
+		return fmt.Errorf("timestamp parsing must have at least one layout")
+	}
+	if c.Timestamp.Pattern != "" {
+		if _, err := regexp.Compile(c.Timestamp.Pattern); err != nil {
+			return fmt.Errorf("invalid timestamp pattern %s: %v", c.Timestamp.Pattern, err)
+		}
+	}
+	if _, err := time.LoadLocation(c.Timestamp.Timezone); err != nil {
+		return fmt.Errorf("invalid timestamp timezone %s: %v", c.Timestamp.Timezone, err)
+	}
+	return nil
+}
+
+func (c *LogsConfig) validateRateLimit() error {
+	if c.RateLimit == nil {
+		return nil
+	}
+	if c.RateLimit.LinesPerSecond < 0 || c.RateLimit.BytesPerSecond < 0 {
+		return fmt.Errorf("rate limits must not be negative")
+	}
+	switch c.RateLimit.Policy {
+	case "", RateLimitBlock, RateLimitDrop:
+		return nil
+	default:
+		return fmt.Errorf("invalid rate limit policy '%v', must be '%v' or '%v'", c.RateLimit.Policy, RateLimitBlock, RateLimitDrop)
+	}
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
@@ -153,6 +379,23 @@ func (c *LogsConfig) validateTailingMode() error {
 	return nil
 }
 
+func (c *LogsConfig) validatePathTagsPattern() error {
+	if c.PathTagsPattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(c.PathTagsPattern)
+	if err != nil {
+		return fmt.Errorf("invalid path tags pattern %s for %v: %v", c.PathTagsPattern, c.Path, err)
+	}
+	for _, name := range re.SubexpNames()[1:] {
+		if name != "" {
+			c.PathTagsRegex = re
+			return nil
+		}
+	}
+	return fmt.Errorf("path tags pattern %s for %v must have at least one named capture group", c.PathTagsPattern, c.Path)
+}
+
 // AutoMultiLineEnabled determines whether auto multi line detection is enabled for this config,
 // considering both the agent-wide logs_config.auto_multi_line_detection and any config for this
 // particular log source.
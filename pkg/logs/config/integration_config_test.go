@@ -19,11 +19,21 @@ import (
 func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 	validConfigs := []*LogsConfig{
 		{Type: FileType, Path: "/var/log/foo.log"},
+		{Type: FileType, Path: "/var/log/*/*.log", PathTagsPattern: `/var/log/(?P<app>[^/]+)/.*\.log`},
+		{Type: FileType, Path: "/var/log/foo.log", CloseTimeout: 120},
+		{Type: FileType, Path: "/var/log/foo.log", MinSeverity: "warn"},
+		{Type: FileType, Path: "/var/log/foo.log", Framing: LengthPrefixedVarint},
+		{Type: FileType, Path: "/var/log/foo.log", CarriageReturn: CarriageReturnTerminator},
+		{Type: FileType, Path: "/var/log/foo.log", LongLines: LongLinesChunk},
+		{Type: FileType, Path: "/var/log/foo.log", Timestamp: &TimestampParsing{Layouts: []string{"2006-01-02 15:04:05"}}},
+		{Type: FileType, Path: "/var/log/foo.log", Timestamp: &TimestampParsing{Pattern: `time=(\S+)`, Layouts: []string{"2006-01-02T15:04:05Z07:00"}, Timezone: "Europe/Paris"}},
 		{Type: TCPType, Port: 1234},
 		{Type: UDPType, Port: 5678},
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: SnmpTrapsType},
+		{Type: FileType, Path: "/var/log/foo.log", RateLimit: &RateLimit{LinesPerSecond: 100}},
+		{Type: TCPType, Port: 1234, RateLimit: &RateLimit{BytesPerSecond: 1024, Policy: RateLimitDrop}},
 	}
 
 	for _, config := range validConfigs {
@@ -32,10 +42,27 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 	}
 }
 
+func TestValidateShouldCompilePathTagsPattern(t *testing.T) {
+	config := &LogsConfig{Type: FileType, Path: "/var/log/*/*.log", PathTagsPattern: `/var/log/(?P<app>[^/]+)/.*\.log`}
+	assert.Nil(t, config.Validate())
+	assert.NotNil(t, config.PathTagsRegex)
+	assert.Equal(t, []string{"/var/log/foo/bar.log", "foo"}, config.PathTagsRegex.FindStringSubmatch("/var/log/foo/bar.log"))
+}
+
 func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 	invalidConfigs := []*LogsConfig{
 		{},
 		{Type: FileType},
+		{Type: FileType, Path: "/var/log/*/*.log", PathTagsPattern: `/var/log/(?P<app>[^/]+/.*\.log`},
+		{Type: FileType, Path: "/var/log/*/*.log", PathTagsPattern: `/var/log/([^/]+)/.*\.log`},
+		{Type: FileType, Path: "/var/log/foo.log", CloseTimeout: -1},
+		{Type: FileType, Path: "/var/log/foo.log", MinSeverity: "warning"},
+		{Type: FileType, Path: "/var/log/foo.log", Framing: "length_prefixed"},
+		{Type: FileType, Path: "/var/log/foo.log", CarriageReturn: "crlf"},
+		{Type: FileType, Path: "/var/log/foo.log", LongLines: "split"},
+		{Type: FileType, Path: "/var/log/foo.log", Timestamp: &TimestampParsing{}},
+		{Type: FileType, Path: "/var/log/foo.log", Timestamp: &TimestampParsing{Pattern: `time=(\S+`, Layouts: []string{"2006-01-02 15:04:05"}}},
+		{Type: FileType, Path: "/var/log/foo.log", Timestamp: &TimestampParsing{Layouts: []string{"2006-01-02 15:04:05"}, Timezone: "Mars/Olympus"}},
 		{Type: TCPType},
 		{Type: UDPType},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
@@ -45,6 +72,8 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Pattern: ".*"}}},
+		{Type: FileType, Path: "/var/log/foo.log", RateLimit: &RateLimit{LinesPerSecond: -1}},
+		{Type: FileType, Path: "/var/log/foo.log", RateLimit: &RateLimit{LinesPerSecond: 100, Policy: "sample"}},
 	}
 
 	for _, config := range invalidConfigs {
@@ -8,6 +8,9 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Processing rule types
@@ -16,6 +19,7 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	Sample         = "sample"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,6 +29,10 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// KeepOneIn and KeepPercentage define the lines kept by a sampling rule, either one line
+	// out of KeepOneIn lines, or a random KeepPercentage percent of the lines.
+	KeepOneIn      int     `mapstructure:"keep_one_in" json:"keep_one_in"`
+	KeepPercentage float64 `mapstructure:"keep_percentage" json:"keep_percentage"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
@@ -34,7 +42,8 @@ type ProcessingRule struct {
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, optional for sampling rules
+// - for sampling rules, either a number of lines or a percentage of lines to keep
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,7 +51,7 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, Sample:
 			break
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
@@ -50,17 +59,100 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 			return fmt.Errorf("type %s is not supported for processing rule `%s`", rule.Type, rule.Name)
 		}
 
-		if rule.Pattern == "" {
+		if rule.Type == Sample {
+			if err := validateSampling(rule); err != nil {
+				return err
+			}
+		}
+
+		// a sampling rule without pattern samples all the lines
+		if rule.Pattern == "" && rule.Type != Sample {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+
+		if rule.Type == MaskSequences {
+			warnUnknownCaptureGroups(rule, re)
+		}
 	}
 	return nil
 }
 
+// validateSampling returns an error if the lines kept by a sampling rule are not properly defined.
+func validateSampling(rule *ProcessingRule) error {
+	switch {
+	case rule.KeepOneIn != 0 && rule.KeepPercentage != 0:
+		return fmt.Errorf("keep_one_in and keep_percentage are mutually exclusive for processing rule: %s", rule.Name)
+	case rule.KeepOneIn < 0:
+		return fmt.Errorf("keep_one_in must be positive for processing rule: %s", rule.Name)
+	case rule.KeepPercentage < 0 || rule.KeepPercentage > 100:
+		return fmt.Errorf("keep_percentage must be between 0 and 100 for processing rule: %s", rule.Name)
+	case rule.KeepOneIn == 0 && rule.KeepPercentage == 0:
+		return fmt.Errorf("keep_one_in or keep_percentage must be set for processing rule: %s", rule.Name)
+	}
+	return nil
+}
+
+// warnUnknownCaptureGroups warns about the references of the placeholder of a masking rule
+// to capture groups the pattern does not declare, which are replaced by an empty string.
+// The rule is kept as is, as such placeholders were accepted by the previous versions.
+func warnUnknownCaptureGroups(rule *ProcessingRule, re *regexp.Regexp) {
+	for _, group := range placeholderGroups(rule.ReplacePlaceholder) {
+		if !hasCaptureGroup(re, group) {
+			log.Warnf("placeholder %s refers to an unknown capture group `%s` for processing rule: %s, it is replaced by an empty string, use $$ for a literal $ or ${%s} to separate a group from the text that follows", rule.ReplacePlaceholder, group, rule.Name, group)
+		}
+	}
+}
+
+// placeholderGroups returns the names of the capture groups referred to in a placeholder,
+// with the $name or ${name} syntax of regexp.Expand, where name is either a number or the
+// name of a named capture group. A $ sign is written $$.
+func placeholderGroups(placeholder string) []string {
+	var groups []string
+	for i := 0; i < len(placeholder); i++ {
+		if placeholder[i] != '$' || i+1 == len(placeholder) {
+			continue
+		}
+		i++
+		if placeholder[i] == '$' {
+			continue
+		}
+		brace := placeholder[i] == '{'
+		if brace {
+			i++
+		}
+		start := i
+		for i < len(placeholder) && isGroupNameChar(placeholder[i]) {
+			i++
+		}
+		if i == start || (brace && (i == len(placeholder) || placeholder[i] != '}')) {
+			// malformed references are kept as raw text
+			i = start - 1
+			continue
+		}
+		groups = append(groups, placeholder[start:i])
+		if !brace {
+			i--
+		}
+	}
+	return groups
+}
+
+func isGroupNameChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// hasCaptureGroup returns true if the group, a number or a name, is a capture group of the regex.
+func hasCaptureGroup(re *regexp.Regexp, group string) bool {
+	if index, err := strconv.Atoi(group); err == nil {
+		return index >= 0 && index <= re.NumSubexp()
+	}
+	return re.SubexpIndex(group) != -1
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
@@ -69,7 +161,7 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, Sample:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
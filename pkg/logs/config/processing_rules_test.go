@@ -34,3 +34,53 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateShouldSucceedWithCaptureGroupsInPlaceholder(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "[masked]", Pattern: "password=\\S+"},
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "${1}[masked]", Pattern: "(password=)\\S+"},
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "$key=[masked]", Pattern: "(?P<key>password|token)=\\S+"},
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "${key}_masked $$", Pattern: "(?P<key>password|token)=\\S+"},
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "$0 ${", Pattern: "\\S+"},
+	}
+	for _, rule := range validRules {
+		assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.ReplacePlaceholder)
+	}
+}
+
+func TestValidateShouldKeepUnknownCaptureGroupsInPlaceholder(t *testing.T) {
+	// such placeholders were accepted by the previous versions, they are only reported
+	rules := []*ProcessingRule{
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "${2}[masked]", Pattern: "(password=)\\S+"},
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "$1masked", Pattern: "(password=)\\S+"},
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "${value}", Pattern: "(?P<key>password|token)=\\S+"},
+		{Type: MaskSequences, Name: "mask", ReplacePlaceholder: "$word", Pattern: "secret"},
+	}
+	for _, rule := range rules {
+		assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.ReplacePlaceholder)
+	}
+	assert.Equal(t, []string{"2"}, placeholderGroups("${2}[masked]"))
+	assert.Equal(t, []string{"1masked"}, placeholderGroups("$1masked"))
+	assert.Empty(t, placeholderGroups("$$word ${"))
+}
+
+func TestValidateSamplingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Type: Sample, Name: "sample", KeepOneIn: 10},
+		{Type: Sample, Name: "sample", KeepPercentage: 12.5, Pattern: "DEBUG"},
+	}
+	for _, rule := range validRules {
+		assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}))
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Type: Sample, Name: "sample"},
+		{Type: Sample, Name: "sample", KeepOneIn: 10, KeepPercentage: 10},
+		{Type: Sample, Name: "sample", KeepOneIn: -1},
+		{Type: Sample, Name: "sample", KeepPercentage: 150},
+		{Type: Sample, Name: "sample", KeepOneIn: 10, Pattern: "(?=abf)"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}))
+	}
+}
@@ -113,6 +113,16 @@ func (s *LogSource) RegisterInfo(i InfoProvider) {
 	s.info[i.InfoKey()] = i
 }
 
+// UnregisterInfo removes some info from the status page, unless another
+// instance has been registered with the same key since
+func (s *LogSource) UnregisterInfo(i InfoProvider) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.info[i.InfoKey()] == i {
+		delete(s.info, i.InfoKey())
+	}
+}
+
 // GetInfo gets an InfoProvider instance by the key
 func (s *LogSource) GetInfo(key string) InfoProvider {
 	s.lock.Lock()
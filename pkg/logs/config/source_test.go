@@ -28,6 +28,23 @@ func (s *LogSourceSuite) TestInputs() {
 
 }
 
+func (s *LogSourceSuite) TestInfo() {
+	s.source = NewLogSource("", nil)
+	info := NewCountInfo("foo")
+	info.Add(1)
+	s.source.RegisterInfo(info)
+	s.Equal(map[string][]string{"foo": {"1"}}, s.source.GetInfoStatus())
+
+	// a newer instance with the same key is not unregistered by the previous one
+	newInfo := NewCountInfo("foo")
+	s.source.RegisterInfo(newInfo)
+	s.source.UnregisterInfo(info)
+	s.Equal(newInfo, s.source.GetInfo("foo"))
+
+	s.source.UnregisterInfo(newInfo)
+	s.Nil(s.source.GetInfo("foo"))
+}
+
 func TestTrackerSuite(t *testing.T) {
 	suite.Run(t, new(LogSourceSuite))
 }
@@ -8,6 +8,7 @@ package config
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
 type status int
@@ -16,6 +17,7 @@ const (
 	isPending status = iota
 	isSuccess
 	isError
+	isRetrying
 )
 
 // LogStatus tracks errors and success.
@@ -49,6 +51,15 @@ func (s *LogStatus) Error(err error) {
 	s.err = fmt.Sprintf("Error: %s", err.Error())
 }
 
+// Retrying records the given error, which is expected to be transient, and that the
+// source is tried again after the given delay.
+func (s *LogStatus) Retrying(err error, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = isRetrying
+	s.err = fmt.Sprintf("Retrying in %v: %s", delay, err.Error())
+}
+
 // IsPending returns whether the current status is not yet determined.
 func (s *LogStatus) IsPending() bool {
 	s.mu.Lock()
@@ -70,6 +81,13 @@ func (s *LogStatus) IsError() bool {
 	return s.status == isError
 }
 
+// IsRetrying returns whether the current status is a transient error.
+func (s *LogStatus) IsRetrying() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status == isRetrying
+}
+
 // GetError returns the error.
 func (s *LogStatus) GetError() string {
 	s.mu.Lock()
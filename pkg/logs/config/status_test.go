@@ -8,6 +8,7 @@ package config
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -43,6 +44,20 @@ func (s *LogStatusSuite) TestError() {
 	s.Equal("Error: bar", s.status.GetError())
 }
 
+func (s *LogStatusSuite) TestRetrying() {
+	s.status = NewLogStatus()
+	s.status.Retrying(errors.New("bar"), 2*time.Second)
+	s.False(s.status.IsPending())
+	s.False(s.status.IsSuccess())
+	s.False(s.status.IsError())
+	s.True(s.status.IsRetrying())
+	s.Equal("Retrying in 2s: bar", s.status.GetError())
+
+	s.status.Success()
+	s.False(s.status.IsRetrying())
+	s.Equal("", s.status.GetError())
+}
+
 func TestLogStatusSuite(t *testing.T) {
 	suite.Run(t, new(LogStatusSuite))
 }
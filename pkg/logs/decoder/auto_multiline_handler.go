@@ -45,12 +45,14 @@ func (d *DetectedPattern) Get() *regexp.Regexp {
 
 // AutoMultilineHandler can attempts to detect a known/commob pattern (a timestamp) in the logs
 // and will switch to a MultiLine handler if one is detected and the thresholds are met.
+// When a redetection window is set, the detection starts over once the detected pattern
+// has not matched any of the lines in the window, e.g. when the format of the logs has
+// changed after the application has been redeployed.
 type AutoMultilineHandler struct {
 	multiLineHandler  *MultiLineHandler
 	singleLineHandler *SingleLineHandler
 	inputChan         chan *Message
 	outputChan        chan *Message
-	isRunning         bool
 	linesToAssess     int
 	linesTested       int
 	lineLimit         int
@@ -59,8 +61,11 @@ type AutoMultilineHandler struct {
 	processsingFunc   func(message *Message)
 	flushTimeout      time.Duration
 	source            *config.LogSource
+	matchTimeout      time.Duration
 	timeoutTimer      *time.Timer
 	detectedPattern   *DetectedPattern
+	redetectionWindow int
+	linesSinceMatch   int
 }
 
 // NewAutoMultilineHandler returns a new AutoMultilineHandler.
@@ -74,6 +79,7 @@ func NewAutoMultilineHandler(
 	source *config.LogSource,
 	additionalPatterns []*regexp.Regexp,
 	detectedPattern *DetectedPattern,
+	redetectionWindow int,
 ) *AutoMultilineHandler {
 
 	// Put the user patterns at the beginning of the list so we prioritize them if there is a conflicting match.
@@ -87,22 +93,24 @@ func NewAutoMultilineHandler(
 		}
 	}
 	h := &AutoMultilineHandler{
-		inputChan:       inputChan,
-		outputChan:      outputChan,
-		isRunning:       true,
-		lineLimit:       lineLimit,
-		matchThreshold:  matchThreshold,
-		scoredMatches:   scoredMatches,
-		linesToAssess:   linesToAssess,
-		flushTimeout:    flushTimeout,
-		source:          source,
-		timeoutTimer:    time.NewTimer(matchTimeout),
-		detectedPattern: detectedPattern,
+		inputChan:         inputChan,
+		outputChan:        outputChan,
+		lineLimit:         lineLimit,
+		matchThreshold:    matchThreshold,
+		scoredMatches:     scoredMatches,
+		linesToAssess:     linesToAssess,
+		flushTimeout:      flushTimeout,
+		source:            source,
+		matchTimeout:      matchTimeout,
+		timeoutTimer:      time.NewTimer(matchTimeout),
+		detectedPattern:   detectedPattern,
+		redetectionWindow: redetectionWindow,
 	}
 
 	// This single-line handler is never started. Instead, we call its `process`
 	// method directly.  So, it does not need an input channel.
 	h.singleLineHandler = NewSingleLineHandler(nil, outputChan, lineLimit)
+	h.singleLineHandler.chunks = newChunker(source.Config)
 	h.processsingFunc = h.processAndTry
 
 	return h
@@ -118,18 +126,42 @@ func (h *AutoMultilineHandler) Start() {
 	go h.run()
 }
 
-// run consumes new lines and processes them.
+// run consumes new lines and processes them, and makes sure the content buffered by the
+// multi-line handler is sent when it stayed for too long in the buffer.
 func (h *AutoMultilineHandler) run() {
-	for {
-		if !h.isRunning {
-			return
+	flushTimer := time.NewTimer(h.flushTimeout)
+	defer func() {
+		flushTimer.Stop()
+		// make sure the content stored in the buffer gets sent,
+		// this can happen when the stop is called in between two timer ticks.
+		if h.multiLineHandler != nil {
+			h.multiLineHandler.sendBuffer()
 		}
-		line, isOpen := <-h.inputChan
-		if !isOpen {
-			close(h.outputChan)
-			return
+		close(h.outputChan)
+	}()
+	for {
+		select {
+		case line, isOpen := <-h.inputChan:
+			if !isOpen {
+				// inputChan has been closed, no more lines are expected
+				return
+			}
+			// process the new line and restart the timeout
+			if !flushTimer.Stop() {
+				select {
+				case <-flushTimer.C:
+				default:
+				}
+			}
+			h.processsingFunc(line)
+			flushTimer.Reset(h.flushTimeout)
+		case <-flushTimer.C:
+			// no line has been collected since a while,
+			// the content is supposed to be complete.
+			if h.multiLineHandler != nil {
+				h.multiLineHandler.sendBuffer()
+			}
 		}
-		h.processsingFunc(line)
 	}
 }
 
@@ -137,22 +169,25 @@ func (h *AutoMultilineHandler) processAndTry(message *Message) {
 	// Process message before anything else
 	h.singleLineHandler.process(message)
 
+	// Every candidate pattern matching the line is scored, so that a more specific pattern
+	// is not penalized by a more generic one which comes first.
+	shouldSort := false
 	for i, scoredPattern := range h.scoredMatches {
-		match := scoredPattern.regexp.Match(message.Content)
-		if match {
+		if scoredPattern.regexp.Match(message.Content) {
 			scoredPattern.score++
-
-			// By keeping the scored matches sorted, the best match always comes first. Since we expect one timestamp to match overwhelmingly
-			// it should match most often causing few re-sorts.
-			if i != 0 {
-				sort.Slice(h.scoredMatches, func(i, j int) bool {
-					return h.scoredMatches[i].score > h.scoredMatches[j].score
-				})
-			}
-			break
+			shouldSort = shouldSort || i != 0
 		}
 	}
 
+	// By keeping the scored matches sorted, the best match always comes first. Since we expect one timestamp to match overwhelmingly
+	// it should match most often causing few re-sorts. The sort is stable so that, on a tie, the user patterns and then the
+	// patterns listed first are prioritized.
+	if shouldSort {
+		sort.SliceStable(h.scoredMatches, func(i, j int) bool {
+			return h.scoredMatches[i].score > h.scoredMatches[j].score
+		})
+	}
+
 	timeout := false
 	select {
 	case <-h.timeoutTimer.C:
@@ -183,14 +218,56 @@ func (h *AutoMultilineHandler) processAndTry(message *Message) {
 }
 
 func (h *AutoMultilineHandler) switchToMultilineHandler(r *regexp.Regexp) {
-	h.isRunning = false
 	h.singleLineHandler = nil
 
-	// Build and start a multiline-handler
-	h.multiLineHandler = NewMultiLineHandler(h.inputChan, h.outputChan, r, h.flushTimeout, h.lineLimit)
-	h.multiLineHandler.Start()
+	// This multi-line handler is never started. Instead, we call its `process` method directly
+	// and its buffer is flushed from the AutoMultilineHandler read loop.
+	h.multiLineHandler = NewMultiLineHandler(nil, h.outputChan, r, h.flushTimeout, h.lineLimit)
+	h.multiLineHandler.chunks = newChunker(h.source.Config)
+	h.linesSinceMatch = 0
+	h.processsingFunc = h.processMultiLine
+}
+
+// processMultiLine aggregates the lines using the detected pattern, and starts the detection over
+// when the pattern has not been matched by any of the lines of the redetection window.
+func (h *AutoMultilineHandler) processMultiLine(message *Message) {
+	h.multiLineHandler.process(message)
 
-	// At this point control is handed over to the multiline handler and the AutoMultilineHandler read loop has stopped.
+	if h.redetectionWindow <= 0 {
+		return
+	}
+	if h.multiLineHandler.newContentRe.Match(message.Content) {
+		h.linesSinceMatch = 0
+		return
+	}
+	h.linesSinceMatch++
+	if h.linesSinceMatch >= h.redetectionWindow {
+		log.Infof("Pattern %v did not match the last %d lines - restarting multiline auto detection", h.multiLineHandler.newContentRe.String(), h.linesSinceMatch)
+		h.multiLineHandler.sendBuffer()
+		h.switchToDetection()
+	}
+}
+
+// switchToDetection resets the state of the detection and processes the next lines as single
+// lines until a pattern is detected again.
+func (h *AutoMultilineHandler) switchToDetection() {
+	h.multiLineHandler = nil
+	h.singleLineHandler = NewSingleLineHandler(nil, h.outputChan, h.lineLimit)
+	h.singleLineHandler.chunks = newChunker(h.source.Config)
+	h.detectedPattern.Set(nil)
+
+	h.linesTested = 0
+	for _, scoredPattern := range h.scoredMatches {
+		scoredPattern.score = 0
+	}
+	if !h.timeoutTimer.Stop() {
+		select {
+		case <-h.timeoutTimer.C:
+		default:
+		}
+	}
+	h.timeoutTimer.Reset(h.matchTimeout)
+	h.processsingFunc = h.processAndTry
 }
 
 // Originally referenced from https://github.com/egnyte/ax/blob/master/pkg/heuristic/timestamp.go
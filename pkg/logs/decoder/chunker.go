@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// Attribute and tags of the chunks of the messages too long to be sent at once, the correlation
+// ID is an attribute rather than a tag as it is unique to each message.
+const (
+	chunkIDAttribute       = "chunk_id"
+	chunkSequenceTagPrefix = "chunk_seq:"
+	chunkLastTag           = "chunk_last:true"
+)
+
+// chunker marks the chunks of a message too long to be sent at once with a correlation ID shared
+// by all the chunks of the message and their sequence number, the last chunk being tagged as
+// such, so that the message can be reassembled by the backend or a downstream processor.
+type chunker struct {
+	id       string
+	sequence int
+}
+
+// newChunker returns a chunker if the long lines of the source must be sent in chunks,
+// nil if they must be truncated.
+func newChunker(c *config.LogsConfig) *chunker {
+	if c.LongLines != config.LongLinesChunk {
+		return nil
+	}
+	return &chunker{}
+}
+
+// inProgress returns true if some chunks of the current message have been sent already.
+func (c *chunker) inProgress() bool {
+	return c.id != ""
+}
+
+// next marks the message as the next chunk of the current message, last is true for its last chunk.
+func (c *chunker) next(msg *Message, last bool) {
+	if c.id == "" {
+		c.id = newChunkID()
+	}
+	if msg.Attributes == nil {
+		msg.Attributes = make(map[string]string)
+	}
+	msg.Attributes[chunkIDAttribute] = c.id
+	msg.Tags = append(msg.Tags, chunkSequenceTagPrefix+strconv.Itoa(c.sequence))
+	c.sequence++
+	if last {
+		msg.Tags = append(msg.Tags, chunkLastTag)
+		c.id = ""
+		c.sequence = 0
+	}
+}
+
+// newChunkID returns a random correlation ID.
+func newChunkID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestChunker(t *testing.T) {
+	assert.Nil(t, newChunker(&config.LogsConfig{}))
+	assert.Nil(t, newChunker(&config.LogsConfig{LongLines: config.LongLinesTruncate}))
+
+	c := newChunker(&config.LogsConfig{LongLines: config.LongLinesChunk})
+	assert.False(t, c.inProgress())
+	first := getDummyMessage("first")
+	c.next(first, false)
+	assert.True(t, c.inProgress())
+	assert.NotEmpty(t, first.Attributes[chunkIDAttribute])
+	assert.Equal(t, []string{chunkSequenceTagPrefix + "0"}, first.Tags)
+	last := getDummyMessage("last")
+	c.next(last, true)
+	assert.Equal(t, first.Attributes, last.Attributes)
+	assert.Equal(t, []string{chunkSequenceTagPrefix + "1", chunkLastTag}, last.Tags)
+	assert.False(t, c.inProgress())
+
+	// the chunks of the next message have another correlation ID
+	next := getDummyMessage("next")
+	c.next(next, false)
+	assert.NotEqual(t, first.Attributes[chunkIDAttribute], next.Attributes[chunkIDAttribute])
+	assert.Equal(t, []string{chunkSequenceTagPrefix + "0"}, next.Tags)
+}
+
+func TestSingleLineHandlerSendsChunks(t *testing.T) {
+	inputChan, outputChan := lineHandlerChans()
+	h := NewSingleLineHandler(inputChan, outputChan, 10)
+	h.chunks = &chunker{}
+	h.Start()
+	defer close(inputChan)
+
+	// the line breaker breaks the long lines at the limit
+	inputChan <- getDummyMessage(" 123456789")
+	inputChan <- getDummyMessage("abcdefghij")
+	inputChan <- getDummyMessageWithLF("klm ")
+	inputChan <- getDummyMessageWithLF(" short ")
+
+	output := <-outputChan
+	assert.Equal(t, "123456789", string(output.Content))
+	id := output.Attributes[chunkIDAttribute]
+	assert.NotEmpty(t, id)
+	assert.Equal(t, []string{"chunk_seq:0"}, output.Tags)
+	output = <-outputChan
+	assert.Equal(t, "abcdefghij", string(output.Content))
+	assert.Equal(t, map[string]string{chunkIDAttribute: id}, output.Attributes)
+	assert.Equal(t, []string{"chunk_seq:1"}, output.Tags)
+	output = <-outputChan
+	assert.Equal(t, "klm", string(output.Content))
+	assert.Equal(t, map[string]string{chunkIDAttribute: id}, output.Attributes)
+	assert.Equal(t, []string{"chunk_seq:2", "chunk_last:true"}, output.Tags)
+	assert.Equal(t, len("klm ")+1, output.RawDataLen)
+
+	output = <-outputChan
+	assert.Equal(t, "short", string(output.Content))
+	assert.Empty(t, output.Attributes)
+	assert.Empty(t, output.Tags)
+}
+
+func TestMultiLineHandlerSendsChunks(t *testing.T) {
+	inputChan, outputChan := lineHandlerChans()
+	h := NewMultiLineHandler(inputChan, outputChan, regexp.MustCompile(`^\d+`), time.Hour, 20)
+	h.chunks = &chunker{}
+	h.Start()
+
+	inputChan <- getDummyMessageWithLF("1 Exception")
+	inputChan <- getDummyMessageWithLF("  at foo.bar()")
+	inputChan <- getDummyMessageWithLF("  at foo.baz()")
+	inputChan <- getDummyMessageWithLF("2 ok")
+	close(inputChan)
+
+	output := <-outputChan
+	assert.Equal(t, `1 Exception\n  at foo.bar()`, string(output.Content))
+	id := output.Attributes[chunkIDAttribute]
+	assert.Len(t, id, 16)
+	assert.Equal(t, []string{"chunk_seq:0"}, output.Tags)
+	assert.Equal(t, len("1 Exception\n  at foo.bar()\n"), output.RawDataLen)
+
+	// the chunks are separated like the lines of the message
+	output = <-outputChan
+	assert.Equal(t, `\n  at foo.baz()`, string(output.Content))
+	assert.Equal(t, map[string]string{chunkIDAttribute: id}, output.Attributes)
+	assert.Equal(t, []string{"chunk_seq:1", "chunk_last:true"}, output.Tags)
+
+	output = <-outputChan
+	assert.Equal(t, "2 ok", string(output.Content))
+	assert.Empty(t, output.Attributes)
+	assert.Empty(t, output.Tags)
+}
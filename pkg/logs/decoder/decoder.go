@@ -13,6 +13,7 @@ import (
 	dd_conf "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/ansi"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -53,6 +54,14 @@ type Message struct {
 	RawDataLen         int
 	Timestamp          string
 	IngestionTimestamp int64
+	// Tags are extracted from the content of the message, if any.
+	Tags []string
+	// Attributes are sent next to the content of the message, if any.
+	Attributes map[string]string
+	// EventTimestamp is the time of the event parsed from the content of the message, if any.
+	EventTimestamp time.Time
+	// Masked is true once the masking rules of the source are applied to the content.
+	Masked bool
 }
 
 // NewMessage returns a new output.
@@ -80,18 +89,37 @@ func NewMessage(content []byte, status string, rawDataLen int, timestamp string)
 //
 // LineParser.run() takes data from its input channel, invokes the parser to convert it to
 // parsers.Message, converts that to decoder.Message, and passes that to the next actor via
-// lineHandler.Handle, which internally uses a channel.
+// lineHandler.Handle, which internally uses a channel. The parser is the one built by the
+// factory registered with RegisterParser under the name set in the parser option of the
+// source, if any.
 //
 // LineHandler.run() takes data from its input channel, processes it as necessary (as single
 // lines, multiple lines, or auto-detecting the two), and sends the result to its output
 // channel, which is the same channel as decoder.OutputChan.
+//
+// When timestamp parsing is configured on the source, a TimestampParser actor is inserted
+// after the LineHandler, and its output channel becomes decoder.OutputChan.
+// When sampling rules are configured on the source, a Sampler actor is inserted after them,
+// and its output channel becomes decoder.OutputChan.
+// When JSON attributes are configured on the source, a JSONAttributesExtractor actor is
+// inserted after them, and its output channel becomes decoder.OutputChan.
+// When a minimum severity is configured on the source, a SeverityFilter actor is inserted
+// after them, once the status of the messages is known, and its output channel becomes decoder.OutputChan.
+// When a rate limit is configured on the source, a RateLimiter actor is inserted last,
+// and its output channel becomes decoder.OutputChan.
 type Decoder struct {
 	InputChan  chan *Input
 	OutputChan chan *Message
 
-	lineBreaker *LineBreaker
-	lineParser  LineParser
-	lineHandler LineHandler
+	lineBreaker             *LineBreaker
+	lineParser              LineParser
+	lineHandler             LineHandler
+	timestampParser         *TimestampParser
+	sampler                 *Sampler
+	masker                  *Masker
+	jsonAttributesExtractor *JSONAttributesExtractor
+	severityFilter          *SeverityFilter
+	rateLimiter             *RateLimiter
 
 	// The decoder holds on to an instace of DetectedPattern which is a thread safe container used to
 	// pass a multiline pattern up from the line handler in order to surface it to the tailer.
@@ -113,8 +141,67 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parsers.Parse
 	lineLimit := defaultContentLenLimit
 	detectedPattern := &DetectedPattern{}
 
-	// construct the lineBreaker actor, wrapping the matcher
-	lineBreaker := NewLineBreaker(inputChan, brokenLineChan, matcher, lineLimit)
+	// the optional actors following the lineHandler are chained backward from the decoder
+	// output channel: lineHandler -> masker -> timestampParser -> sampler -> jsonAttributesExtractor -> severityFilter -> rateLimiter -> outputChan
+	lineHandlerOut := outputChan
+	var rateLimiter *RateLimiter
+	if source.Config.RateLimit != nil {
+		rateLimiterIn := make(chan *Message)
+		rateLimiter = NewRateLimiter(rateLimiterIn, lineHandlerOut, source.Name, source.Config.RateLimit)
+		lineHandlerOut = rateLimiterIn
+	}
+	var severityFilter *SeverityFilter
+	if source.Config.MinSeverity != "" {
+		severityFilterIn := make(chan *Message)
+		severityFilter = NewSeverityFilter(severityFilterIn, lineHandlerOut, source.Name, source.Config.MinSeverity)
+		lineHandlerOut = severityFilterIn
+	}
+	var jsonAttributesExtractor *JSONAttributesExtractor
+	if source.Config.JSONAttributes != nil {
+		jsonAttributesExtractorIn := make(chan *Message)
+		jsonAttributesExtractor = NewJSONAttributesExtractor(jsonAttributesExtractorIn, lineHandlerOut, source.Config.JSONAttributes)
+		lineHandlerOut = jsonAttributesExtractorIn
+	}
+	var sampler *Sampler
+	if rules := samplingRules(source.Config.ProcessingRules); len(rules) > 0 {
+		samplerIn := make(chan *Message)
+		sampler = NewSampler(samplerIn, lineHandlerOut, source.Name, rules)
+		lineHandlerOut = samplerIn
+	}
+	var timestampParser *TimestampParser
+	if source.Config.Timestamp != nil {
+		timestampParserIn := make(chan *Message)
+		timestampParser = NewTimestampParser(timestampParserIn, lineHandlerOut, source.Config.Timestamp)
+		lineHandlerOut = timestampParserIn
+	}
+	var masker *Masker
+	if rules := maskingRules(source.Config.ProcessingRules); len(rules) > 0 {
+		maskerIn := make(chan *Message)
+		masker = NewMasker(maskerIn, lineHandlerOut, rules)
+		lineHandlerOut = maskerIn
+	}
+
+	// construct the lineBreaker actor, wrapping the matcher, or breaking length-prefixed records
+	lengthPrefix := lengthPrefixForFraming(source.Config.Framing)
+	var lineBreaker *LineBreaker
+	if lengthPrefix != nil {
+		lineBreaker = NewLengthPrefixedLineBreaker(inputChan, brokenLineChan, lengthPrefix, lineLimit)
+	} else {
+		lineBreaker = NewLineBreaker(inputChan, brokenLineChan, matcher, lineLimit)
+		if _, isNewLine := matcher.(*NewLineMatcher); isNewLine {
+			// the carriage returns of multi-byte encodings are not handled
+			lineBreaker.carriageReturn = source.Config.CarriageReturn
+		}
+		// the last line of a file read to completion may not be terminated
+		lineBreaker.flushOnClose = source.Config.OneShot
+	}
+
+	// the parser selected by the source handles its custom log format
+	parser = catalogParser(source, parser)
+
+	if source.Config.StripANSISequences {
+		parser = ansi.NewStripper(parser)
+	}
 
 	// construct the lineParser actor, wrapping the parser
 	var lineParser LineParser
@@ -126,9 +213,14 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parsers.Parse
 
 	// construct the lineHandler actor
 	var lineHandler LineHandler
+	if lengthPrefix != nil {
+		// records are not lines, they are neither trimmed nor aggregated
+		lineHandler = NewRecordHandler(lineParserOut, lineHandlerOut)
+	}
 	for _, rule := range source.Config.ProcessingRules {
-		if rule.Type == config.MultiLine {
-			lh := NewMultiLineHandler(lineParserOut, outputChan, rule.Regex, config.AggregationTimeout(), lineLimit)
+		if rule.Type == config.MultiLine && lengthPrefix == nil {
+			lh := NewMultiLineHandler(lineParserOut, lineHandlerOut, rule.Regex, config.AggregationTimeout(), lineLimit)
+			lh.chunks = newChunker(source.Config)
 
 			// Since a single source can have multiple file tailers - each with their own decoder instance,
 			// Make sure we keep track of the multiline match count info from all of the decoders so the
@@ -147,22 +239,44 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parsers.Parse
 		if source.Config.AutoMultiLineEnabled() {
 			log.Infof("Auto multi line log detection enabled")
 
-			if multiLinePattern != nil {
+			redetectionWindow := autoMultiLineRedetectionWindow(source)
+			if multiLinePattern != nil && redetectionWindow > 0 {
+				log.Info("Found a previously detected pattern - using multiline auto detection with this pattern")
+
+				// Save the pattern again for the next rotation
+				detectedPattern.Set(multiLinePattern)
+
+				// Keep auto detection around, so that the pattern can still be detected again if it stops matching
+				h := buildAutoMultilineHandlerFromConfig(lineParserOut, lineHandlerOut, lineLimit, source, detectedPattern)
+				h.switchToMultilineHandler(multiLinePattern)
+				lineHandler = h
+			} else if multiLinePattern != nil {
 				log.Info("Found a previously detected pattern - using multiline handler")
 
 				// Save the pattern again for the next rotation
 				detectedPattern.Set(multiLinePattern)
 
-				lineHandler = NewMultiLineHandler(lineParserOut, outputChan, multiLinePattern, config.AggregationTimeout(), lineLimit)
+				lh := NewMultiLineHandler(lineParserOut, lineHandlerOut, multiLinePattern, config.AggregationTimeout(), lineLimit)
+				lh.chunks = newChunker(source.Config)
+				lineHandler = lh
 			} else {
-				lineHandler = buildAutoMultilineHandlerFromConfig(lineParserOut, outputChan, lineLimit, source, detectedPattern)
+				lineHandler = buildAutoMultilineHandlerFromConfig(lineParserOut, lineHandlerOut, lineLimit, source, detectedPattern)
 			}
 		} else {
-			lineHandler = NewSingleLineHandler(lineParserOut, outputChan, lineLimit)
+			lh := NewSingleLineHandler(lineParserOut, lineHandlerOut, lineLimit)
+			lh.chunks = newChunker(source.Config)
+			lineHandler = lh
 		}
 	}
 
-	return New(inputChan, outputChan, lineBreaker, lineParser, lineHandler, detectedPattern)
+	d := New(inputChan, outputChan, lineBreaker, lineParser, lineHandler, detectedPattern)
+	d.timestampParser = timestampParser
+	d.sampler = sampler
+	d.masker = masker
+	d.jsonAttributesExtractor = jsonAttributesExtractor
+	d.severityFilter = severityFilter
+	d.rateLimiter = rateLimiter
+	return d
 }
 
 func buildAutoMultilineHandlerFromConfig(inputChan chan *Message, outputChan chan *Message, lineLimit int, source *config.LogSource, detectedPattern *DetectedPattern) *AutoMultilineHandler {
@@ -195,7 +309,18 @@ func buildAutoMultilineHandlerFromConfig(inputChan chan *Message, outputChan cha
 		config.AggregationTimeout(),
 		source,
 		additionalPatternsCompiled,
-		detectedPattern)
+		detectedPattern,
+		autoMultiLineRedetectionWindow(source))
+}
+
+// autoMultiLineRedetectionWindow returns the number of consecutive lines not matching a detected
+// pattern after which the multiline auto detection starts over, 0 means it never does.
+func autoMultiLineRedetectionWindow(source *config.LogSource) int {
+	redetectionWindow := source.Config.AutoMultiLineRedetectionWindow
+	if redetectionWindow <= 0 {
+		redetectionWindow = dd_conf.Datadog.GetInt("logs_config.auto_multi_line_default_redetection_window")
+	}
+	return redetectionWindow
 }
 
 // New returns an initialized Decoder
@@ -215,6 +340,24 @@ func (d *Decoder) Start() {
 	d.lineBreaker.Start()
 	d.lineParser.Start()
 	d.lineHandler.Start()
+	if d.masker != nil {
+		d.masker.Start()
+	}
+	if d.timestampParser != nil {
+		d.timestampParser.Start()
+	}
+	if d.sampler != nil {
+		d.sampler.Start()
+	}
+	if d.jsonAttributesExtractor != nil {
+		d.jsonAttributesExtractor.Start()
+	}
+	if d.severityFilter != nil {
+		d.severityFilter.Start()
+	}
+	if d.rateLimiter != nil {
+		d.rateLimiter.Start()
+	}
 }
 
 // Stop stops the Decoder
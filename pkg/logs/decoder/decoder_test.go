@@ -6,6 +6,7 @@
 package decoder
 
 import (
+	"encoding/base64"
 	"regexp"
 	"testing"
 
@@ -332,3 +333,143 @@ func TestDecoderWithMultilineKubernetes(t *testing.T) {
 	assert.Equal(t, message.StatusError, output.Status)
 	assert.Equal(t, "2019-06-06T16:35:55.930852913Z", output.Timestamp)
 }
+
+func TestDecoderWithMultiByteEncodings(t *testing.T) {
+	tests := []struct {
+		encoding string
+		input    []byte
+		expected string
+	}{
+		// "日本\n" in EUC-JP
+		{config.EUCJP, []byte{0xc6, 0xfc, 0xcb, 0xdc, '\n'}, "日本"},
+		// "中文\n" in GB18030
+		{config.GB18030, []byte{0xd6, 0xd0, 0xce, 0xc4, '\n'}, "中文"},
+		// "\u0080\n" in GB18030, using a four-byte sequence
+		{config.GB18030, []byte{0x81, 0x30, 0x81, 0x30, '\n'}, "\u0080"},
+		// "hi\n" in UTF-32 LE and BE
+		{config.UTF32LE, []byte{'h', 0x0, 0x0, 0x0, 'i', 0x0, 0x0, 0x0, '\n', 0x0, 0x0, 0x0}, "hi"},
+		{config.UTF32BE, []byte{0x0, 0x0, 0x0, 'h', 0x0, 0x0, 0x0, 'i', 0x0, 0x0, 0x0, '\n'}, "hi"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.encoding, func(t *testing.T) {
+			source := config.NewLogSource("config", &config.LogsConfig{Encoding: test.encoding})
+			d := NewDecoderFromSource(source)
+			d.Start()
+
+			d.InputChan <- NewInput(test.input)
+
+			output := <-d.OutputChan
+			assert.Equal(t, test.expected, string(output.Content))
+			assert.Equal(t, len(test.input), output.RawDataLen)
+
+			d.Stop()
+		})
+	}
+}
+
+func TestDecoderWithANSISequencesStripping(t *testing.T) {
+	input := []byte("\x1b[31mERROR\x1b[0m something failed\n")
+
+	source := config.NewLogSource("config", &config.LogsConfig{})
+	d := InitializeDecoder(source, noop.New())
+	d.Start()
+	d.InputChan <- NewInput(input)
+	output := <-d.OutputChan
+	assert.Equal(t, "\x1b[31mERROR\x1b[0m something failed", string(output.Content))
+	d.Stop()
+
+	source = config.NewLogSource("config", &config.LogsConfig{StripANSISequences: true})
+	d = InitializeDecoder(source, noop.New())
+	d.Start()
+	d.InputChan <- NewInput(input)
+	output = <-d.OutputChan
+	assert.Equal(t, "ERROR something failed", string(output.Content))
+	// the raw data length still accounts for the stripped sequences
+	assert.Equal(t, len(input), output.RawDataLen)
+	d.Stop()
+}
+
+func TestDecoderWithPartialKubernetesLines(t *testing.T) {
+	var output *Message
+	var line []byte
+	var lineLen int
+
+	c := &config.LogsConfig{
+		ProcessingRules: []*config.ProcessingRule{
+			{
+				Type:  config.MultiLine,
+				Regex: regexp.MustCompile("1234"),
+			},
+		},
+	}
+	d := InitializeDecoder(config.NewLogSource("", c), kubernetes.New())
+	d.Start()
+	defer d.Stop()
+
+	// a line split in two partial lines, reassembled before the multiline handling
+	line = []byte("2019-06-06T16:35:55.930852911Z stdout P 12\n")
+	lineLen = len(line)
+	d.InputChan <- NewInput(line)
+
+	line = []byte("2019-06-06T16:35:55.930852912Z stdout F 34 hello\n")
+	lineLen += len(line)
+	d.InputChan <- NewInput(line)
+
+	// a partial line interleaved with a line from another stream
+	line = []byte("2019-06-06T16:35:55.930852913Z stdout P 1234 pending\n")
+	d.InputChan <- NewInput(line)
+
+	line = []byte("2019-06-06T16:35:55.930852914Z stderr F 1234 error\n")
+	d.InputChan <- NewInput(line)
+
+	output = <-d.OutputChan
+	assert.Equal(t, []byte("1234 hello"), output.Content)
+	assert.Equal(t, lineLen, output.RawDataLen)
+	assert.Equal(t, message.StatusInfo, output.Status)
+
+	output = <-d.OutputChan
+	assert.Equal(t, []byte("1234 pending"), output.Content)
+	assert.Equal(t, message.StatusInfo, output.Status)
+
+	output = <-d.OutputChan
+	assert.Equal(t, []byte("1234 error"), output.Content)
+	assert.Equal(t, message.StatusError, output.Status)
+}
+
+func TestDecoderWithLengthPrefixedFraming(t *testing.T) {
+	source := config.NewLogSource("config", &config.LogsConfig{Framing: config.LengthPrefixedUint32LE})
+	d := NewDecoderFromSource(source)
+	d.Start()
+	defer d.Stop()
+
+	// the records are neither trimmed nor broken at new lines
+	record := []byte(" binary\n\x00record ")
+	d.InputChan <- NewInput(append([]byte{byte(len(record)), 0, 0, 0}, record...))
+
+	// their binary content is encoded in base64
+	output := <-d.OutputChan
+	assert.Equal(t, base64.StdEncoding.EncodeToString(record), string(output.Content))
+	assert.Equal(t, len(record)+4, output.RawDataLen)
+}
+
+func TestDecoderWithCarriageReturnTerminator(t *testing.T) {
+	source := config.NewLogSource("config", &config.LogsConfig{CarriageReturn: config.CarriageReturnTerminator})
+	d := NewDecoderFromSource(source)
+	d.Start()
+	defer d.Stop()
+
+	d.InputChan <- NewInput([]byte("hello\rworld\r\n"))
+
+	output := <-d.OutputChan
+	assert.Equal(t, []byte("hello"), output.Content)
+	assert.Equal(t, len("hello\r"), output.RawDataLen)
+
+	output = <-d.OutputChan
+	assert.Equal(t, []byte("world"), output.Content)
+	assert.Equal(t, len("world\r"), output.RawDataLen)
+
+	// the carriage returns of multi-byte encodings are not handled
+	source = config.NewLogSource("config", &config.LogsConfig{CarriageReturn: config.CarriageReturnTerminator, Encoding: config.UTF16LE})
+	assert.Equal(t, "", NewDecoderFromSource(source).lineBreaker.carriageReturn)
+}
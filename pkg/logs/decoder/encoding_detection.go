@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// EncodingDetectionSampleSize is the number of bytes read from the beginning of a file
+// to detect its encoding.
+const EncodingDetectionSampleSize = 4096
+
+const (
+	// minimum ratio of null bytes expected at the positions holding the high-order
+	// bytes of a code unit, for mostly-ASCII UTF-16 and UTF-32 content.
+	nullBytesMinRatio = 0.7
+	// maximum ratio of null bytes tolerated at the positions holding the low-order
+	// byte of a code unit.
+	nullBytesMaxRatio = 0.1
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16leBOM = []byte{0xFF, 0xFE}
+	utf16beBOM = []byte{0xFE, 0xFF}
+	utf32leBOM = []byte{0xFF, 0xFE, 0x00, 0x00}
+	utf32beBOM = []byte{0x00, 0x00, 0xFE, 0xFF}
+)
+
+// DetectEncoding returns the encoding of the given sample, taken from the beginning
+// of a file, as one of the encodings supported in the logs configuration.
+// A Byte Order Mark is used when present, otherwise the distribution of null
+// bytes in the sample is used to recognize UTF-16 and UTF-32 content.
+// An empty string is returned when the sample looks like UTF-8 (or plain ASCII),
+// or when the encoding can not be determined.
+func DetectEncoding(sample []byte) string {
+	// UTF-32 BOMs must be checked first as the UTF-32LE BOM starts with the UTF-16LE one.
+	switch {
+	case bytes.HasPrefix(sample, utf32leBOM):
+		return config.UTF32LE
+	case bytes.HasPrefix(sample, utf32beBOM):
+		return config.UTF32BE
+	case bytes.HasPrefix(sample, utf16leBOM):
+		return config.UTF16LE
+	case bytes.HasPrefix(sample, utf16beBOM):
+		return config.UTF16BE
+	case bytes.HasPrefix(sample, utf8BOM):
+		return ""
+	}
+
+	// null bytes never appear in UTF-8 text, but they are frequent in UTF-16 and UTF-32
+	// when most of the characters are in the ASCII range.
+	if bytes.IndexByte(sample, 0x00) == -1 {
+		return ""
+	}
+
+	if len(sample) >= 8 {
+		ratios := nullBytesRatios(sample, 4)
+		if ratios[0] <= nullBytesMaxRatio && ratios[1] >= nullBytesMinRatio && ratios[2] >= nullBytesMinRatio && ratios[3] >= nullBytesMinRatio {
+			return config.UTF32LE
+		}
+		if ratios[0] >= nullBytesMinRatio && ratios[1] >= nullBytesMinRatio && ratios[2] >= nullBytesMinRatio && ratios[3] <= nullBytesMaxRatio {
+			return config.UTF32BE
+		}
+	}
+
+	if len(sample) >= 4 {
+		ratios := nullBytesRatios(sample, 2)
+		if ratios[0] <= nullBytesMaxRatio && ratios[1] >= nullBytesMinRatio {
+			return config.UTF16LE
+		}
+		if ratios[0] >= nullBytesMinRatio && ratios[1] <= nullBytesMaxRatio {
+			return config.UTF16BE
+		}
+	}
+
+	return ""
+}
+
+// nullBytesRatios returns, for each position within a code unit of the given width,
+// the ratio of null bytes found at that position in the sample.
+// Trailing bytes not making a complete code unit are ignored.
+func nullBytesRatios(sample []byte, width int) []float64 {
+	units := len(sample) / width
+	counts := make([]int, width)
+	for i := 0; i < units*width; i++ {
+		if sample[i] == 0x00 {
+			counts[i%width]++
+		}
+	}
+	ratios := make([]float64, width)
+	for i, count := range counts {
+		ratios[i] = float64(count) / float64(units)
+	}
+	return ratios
+}
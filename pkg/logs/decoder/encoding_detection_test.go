@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	text := "2021-12-01 10:00:00 INFO hello world\n2021-12-01 10:00:01 INFO bonjour\n"
+
+	utf16le, _ := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(text))
+	utf16be, _ := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(text))
+	utf32le, _ := utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM).NewEncoder().Bytes([]byte(text))
+	utf32be, _ := utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM).NewEncoder().Bytes([]byte(text))
+
+	tests := []struct {
+		name     string
+		sample   []byte
+		expected string
+	}{
+		{"empty", []byte{}, ""},
+		{"utf-8", []byte(text), ""},
+		{"utf-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, text...), ""},
+		{"utf-16-le with BOM", []byte{0xFF, 0xFE, 'h', 0x0}, config.UTF16LE},
+		{"utf-16-be with BOM", []byte{0xFE, 0xFF, 0x0, 'h'}, config.UTF16BE},
+		{"utf-32-le with BOM", []byte{0xFF, 0xFE, 0x0, 0x0, 'h', 0x0, 0x0, 0x0}, config.UTF32LE},
+		{"utf-32-be with BOM", []byte{0x0, 0x0, 0xFE, 0xFF, 0x0, 0x0, 0x0, 'h'}, config.UTF32BE},
+		{"utf-16-le", utf16le, config.UTF16LE},
+		{"utf-16-be", utf16be, config.UTF16BE},
+		{"utf-32-le", utf32le, config.UTF32LE},
+		{"utf-32-be", utf32be, config.UTF32BE},
+		{"truncated utf-16-le", utf16le[:len(utf16le)-1], config.UTF16LE},
+		{"binary", []byte{0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, DetectEncoding(test.sample))
+		})
+	}
+}
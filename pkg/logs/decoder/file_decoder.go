@@ -24,6 +24,13 @@ func NewDecoderFromSource(source *config.LogSource) *Decoder {
 
 // NewDecoderFromSourceWithPattern creates a new decoder from a log source with a multiline pattern
 func NewDecoderFromSourceWithPattern(source *config.LogSource, multiLinePattern *regexp.Regexp) *Decoder {
+	return NewDecoderFromSourceWithEncoding(source, source.Config.Encoding, multiLinePattern)
+}
+
+// NewDecoderFromSourceWithEncoding creates a new decoder from a log source with a multiline pattern,
+// decoding its content with the given encoding rather than the one from the source configuration.
+// This is used when the encoding of a file has been detected from its content.
+func NewDecoderFromSourceWithEncoding(source *config.LogSource, encoding string, multiLinePattern *regexp.Regexp) *Decoder {
 
 	// TODO: remove those checks and add to source a reference to a tagProvider and a lineParser.
 	var lineParser parsers.Parser
@@ -41,18 +48,34 @@ func NewDecoderFromSourceWithPattern(source *config.LogSource, multiLinePattern
 		}
 		matcher = &NewLineMatcher{}
 	default:
-		switch source.Config.Encoding {
+		switch encoding {
 		case config.UTF16BE:
 			lineParser = encodedtext.New(encodedtext.UTF16BE)
 			matcher = NewBytesSequenceMatcher(Utf16beEOL, 2)
 		case config.UTF16LE:
 			lineParser = encodedtext.New(encodedtext.UTF16LE)
 			matcher = NewBytesSequenceMatcher(Utf16leEOL, 2)
+		case config.UTF32BE:
+			lineParser = encodedtext.New(encodedtext.UTF32BE)
+			matcher = NewBytesSequenceMatcher(Utf32beEOL, 4)
+		case config.UTF32LE:
+			lineParser = encodedtext.New(encodedtext.UTF32LE)
+			matcher = NewBytesSequenceMatcher(Utf32leEOL, 4)
 		case config.SHIFTJIS:
 			lineParser = encodedtext.New(encodedtext.SHIFTJIS)
 			// No special handling required for the newline matcher since Shift JIS does not use
 			// newline characters (0x0a) as the second byte of a multibyte sequence.
 			matcher = &NewLineMatcher{}
+		case config.EUCJP:
+			lineParser = encodedtext.New(encodedtext.EUCJP)
+			// EUC-JP multibyte sequences only use bytes in the 0x8e-0xfe range, so a newline
+			// character (0x0a) is always a line separator.
+			matcher = &NewLineMatcher{}
+		case config.GB18030:
+			lineParser = encodedtext.New(encodedtext.GB18030)
+			// GB18030 trailing bytes are either in the 0x30-0x39 or in the 0x40-0xfe range, so a
+			// newline character (0x0a) can never be part of a multibyte sequence.
+			matcher = &NewLineMatcher{}
 		default:
 			lineParser = noop.New()
 			matcher = &NewLineMatcher{}
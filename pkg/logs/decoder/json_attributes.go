@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// levelStatusMapping maps the usual values of the level attributes of JSON logs to statuses.
+var levelStatusMapping = map[string]string{
+	"emerg":         message.StatusEmergency,
+	"emergency":     message.StatusEmergency,
+	"panic":         message.StatusEmergency,
+	"alert":         message.StatusAlert,
+	"crit":          message.StatusCritical,
+	"critical":      message.StatusCritical,
+	"fatal":         message.StatusCritical,
+	"err":           message.StatusError,
+	"error":         message.StatusError,
+	"warn":          message.StatusWarning,
+	"warning":       message.StatusWarning,
+	"notice":        message.StatusNotice,
+	"info":          message.StatusInfo,
+	"information":   message.StatusInfo,
+	"informational": message.StatusInfo,
+	"debug":         message.StatusDebug,
+	"trace":         message.StatusDebug,
+	"verbose":       message.StatusDebug,
+}
+
+// JSONAttributesExtractor implements an actor which parses the messages that are JSON
+// objects and promotes some of their attributes to the status and the tags of the messages.
+// Messages which are not JSON objects are forwarded unchanged.
+//
+// After Start(), the actor runs until its input channel is closed.
+// After all inputs are processed, the actor closes its output channel.
+type JSONAttributesExtractor struct {
+	inputChan     chan *Message
+	outputChan    chan *Message
+	statusPath    []string
+	tagAttributes []string
+	tagPaths      [][]string
+}
+
+// NewJSONAttributesExtractor returns a new JSONAttributesExtractor.
+func NewJSONAttributesExtractor(inputChan chan *Message, outputChan chan *Message, attributes *config.JSONAttributes) *JSONAttributesExtractor {
+	e := &JSONAttributesExtractor{
+		inputChan:     inputChan,
+		outputChan:    outputChan,
+		tagAttributes: attributes.Tags,
+	}
+	if attributes.Status != "" {
+		e.statusPath = strings.Split(attributes.Status, ".")
+	}
+	for _, attribute := range attributes.Tags {
+		e.tagPaths = append(e.tagPaths, strings.Split(attribute, "."))
+	}
+	return e
+}
+
+// Start starts the extractor.
+func (e *JSONAttributesExtractor) Start() {
+	go e.run()
+}
+
+// run consumes new messages and processes them.
+func (e *JSONAttributesExtractor) run() {
+	for msg := range e.inputChan {
+		e.process(msg)
+		e.outputChan <- msg
+	}
+	close(e.outputChan)
+}
+
+// process sets the status and the tags of the message from its attributes.
+func (e *JSONAttributesExtractor) process(msg *Message) {
+	content := bytes.TrimSpace(msg.Content)
+	if len(content) == 0 || content[0] != '{' {
+		return
+	}
+
+	var attributes map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// keep the numbers as they are written, e.g. to not format large identifiers in scientific notation
+	decoder.UseNumber()
+	if err := decoder.Decode(&attributes); err != nil {
+		return
+	}
+
+	if e.statusPath != nil {
+		if level, ok := lookupAttribute(attributes, e.statusPath).(string); ok {
+			if status, exists := levelStatusMapping[strings.ToLower(strings.TrimSpace(level))]; exists {
+				msg.Status = status
+			}
+		}
+	}
+
+	for i, path := range e.tagPaths {
+		switch value := lookupAttribute(attributes, path).(type) {
+		case string:
+			msg.Tags = append(msg.Tags, e.tagAttributes[i]+":"+value)
+		case json.Number, bool:
+			msg.Tags = append(msg.Tags, fmt.Sprintf("%s:%v", e.tagAttributes[i], value))
+		}
+	}
+}
+
+// lookupAttribute returns the value of the attribute at the given path of nested
+// attributes, or nil if there is none.
+func lookupAttribute(attributes map[string]interface{}, path []string) interface{} {
+	var value interface{} = attributes
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestJSONAttributesExtractor(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	e := NewJSONAttributesExtractor(inputChan, outputChan, &config.JSONAttributes{
+		Status: "level",
+		Tags:   []string{"logger", "trace_id", "http.status_code"},
+	})
+	e.Start()
+
+	tests := []struct {
+		content        string
+		expectedStatus string
+		expectedTags   []string
+	}{
+		{`{"level":"ERROR","logger":"com.example.Main","trace_id":1234567890123456789,"msg":"boom"}`, message.StatusError, []string{"logger:com.example.Main", "trace_id:1234567890123456789"}},
+		{`{"level":"warning","http":{"status_code":404}}`, message.StatusWarning, []string{"http.status_code:404"}},
+		{`{"level":"unknown","logger":{"name":"nested"}}`, message.StatusInfo, nil},
+		{`{"level":"debug"`, message.StatusInfo, nil},
+		{`not a json log`, message.StatusInfo, nil},
+	}
+
+	for _, test := range tests {
+		inputChan <- NewMessage([]byte(test.content), message.StatusInfo, len(test.content), "")
+		output := <-outputChan
+		assert.Equal(t, test.content, string(output.Content))
+		assert.Equal(t, test.expectedStatus, output.Status)
+		assert.Equal(t, test.expectedTags, output.Tags)
+	}
+
+	close(inputChan)
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestDecoderWithJSONAttributes(t *testing.T) {
+	source := config.NewLogSource("config", &config.LogsConfig{
+		JSONAttributes: &config.JSONAttributes{Status: "level", Tags: []string{"logger"}},
+	})
+	d := NewDecoderFromSource(source)
+	d.Start()
+	defer d.Stop()
+
+	input := []byte(`{"level":"error","logger":"main","msg":"boom"}` + "\n")
+	d.InputChan <- NewInput(input)
+
+	output := <-d.OutputChan
+	assert.Equal(t, message.StatusError, output.Status)
+	assert.Equal(t, []string{"logger:main"}, output.Tags)
+	assert.Equal(t, len(input), output.RawDataLen)
+}
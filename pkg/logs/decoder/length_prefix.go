@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// errMalformedLengthPrefix is returned when a length prefix can not be parsed.
+var errMalformedLengthPrefix = errors.New("malformed length prefix")
+
+// LengthPrefix parses the length prefix at the beginning of a buffer, and returns the
+// length of the record following it and the size of the prefix. The size is 0 when the
+// buffer does not hold a complete prefix yet. When the prefix is malformed, the size is
+// the number of bytes of the malformed prefix, skipped to parse the next one.
+type LengthPrefix func(buf []byte) (length uint64, prefixLen int, err error)
+
+// lengthPrefixForFraming returns the LengthPrefix of a framing, or nil if the records
+// are not length-prefixed.
+func lengthPrefixForFraming(framing string) LengthPrefix {
+	switch framing {
+	case config.LengthPrefixedVarint:
+		return varintLengthPrefix
+	case config.LengthPrefixedUint32BE:
+		return uint32BELengthPrefix
+	case config.LengthPrefixedUint32LE:
+		return uint32LELengthPrefix
+	default:
+		return nil
+	}
+}
+
+// varintLengthPrefix parses a length written as an unsigned varint, as done by the
+// delimited protobuf writers.
+func varintLengthPrefix(buf []byte) (uint64, int, error) {
+	length, n := binary.Uvarint(buf)
+	if n < 0 {
+		return 0, -n, errMalformedLengthPrefix
+	}
+	return length, n, nil
+}
+
+// uint32BELengthPrefix parses a length written as a big-endian 32-bit unsigned integer.
+func uint32BELengthPrefix(buf []byte) (uint64, int, error) {
+	if len(buf) < 4 {
+		return 0, 0, nil
+	}
+	return uint64(binary.BigEndian.Uint32(buf)), 4, nil
+}
+
+// uint32LELengthPrefix parses a length written as a little-endian 32-bit unsigned integer.
+func uint32LELengthPrefix(buf []byte) (uint64, int, error) {
+	if len(buf) < 4 {
+		return 0, 0, nil
+	}
+	return uint64(binary.LittleEndian.Uint32(buf)), 4, nil
+}
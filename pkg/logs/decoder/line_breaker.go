@@ -3,11 +3,16 @@ package decoder
 import (
 	"bytes"
 	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // LineBreaker implements an actor which reads chunks of bytes from an input
 // channel and uses and EndLineMatcher to break those into lines, passing the
 // results to a LineParser.
+// When the data is made of length-prefixed records, the LineBreaker uses their
+// LengthPrefix to break it into records instead.
 //
 // After Start(), the actor runs until its input channel is closed.
 // After all inputs are processed, the actor closes its output channel.
@@ -22,6 +27,22 @@ type LineBreaker struct {
 	lineBuffer      *bytes.Buffer
 	contentLenLimit int
 	rawDataLen      int
+
+	// carriageReturn defines how the carriage returns are handled, only when the lines are
+	// separated by single-byte line feeds.
+	carriageReturn string
+	// afterCarriageReturn is true when the last line was ended by a carriage return, so that
+	// the line feed of a CRLF sequence does not end an empty line.
+	afterCarriageReturn bool
+	// flushOnClose sends the last line when the input channel is closed, even if it is not
+	// terminated, e.g. for a file read to completion.
+	flushOnClose bool
+
+	lengthPrefix LengthPrefix
+	// pending is the truncated content of a record too long, sent once the remaining
+	// skip bytes of the record have been consumed.
+	pending []byte
+	skip    uint64
 }
 
 // NewLineBreaker initializes a LineBreaker
@@ -37,6 +58,17 @@ func NewLineBreaker(inputChan chan *Input, outputChan chan *DecodedInput, matche
 	}
 }
 
+// NewLengthPrefixedLineBreaker initializes a LineBreaker breaking length-prefixed records
+func NewLengthPrefixedLineBreaker(inputChan chan *Input, outputChan chan *DecodedInput, lengthPrefix LengthPrefix, contentLenLimit int) *LineBreaker {
+	return &LineBreaker{
+		inputChan:       inputChan,
+		outputChan:      outputChan,
+		lineBuffer:      &bytes.Buffer{},
+		contentLenLimit: contentLenLimit,
+		lengthPrefix:    lengthPrefix,
+	}
+}
+
 // Start starts the LineBreaker
 func (lb *LineBreaker) Start() {
 	go lb.run()
@@ -45,30 +77,45 @@ func (lb *LineBreaker) Start() {
 // run lets the LineBreaker handle data coming from InputChan
 func (lb *LineBreaker) run() {
 	for data := range lb.inputChan {
-		lb.breakIncomingData(data.content)
+		if lb.lengthPrefix != nil {
+			lb.breakIncomingRecords(data.content)
+		} else {
+			lb.breakIncomingData(data.content)
+		}
+	}
+	if lb.flushOnClose && lb.lengthPrefix == nil && lb.lineBuffer.Len() > 0 {
+		content := make([]byte, lb.lineBuffer.Len())
+		copy(content, lb.lineBuffer.Bytes())
+		lb.lineBuffer.Reset()
+		lb.outputChan <- NewDecodedInput(content, lb.rawDataLen)
+		lb.rawDataLen = 0
+		atomic.AddInt64(&lb.linesDecoded, 1)
 	}
 	close(lb.outputChan)
 }
 
 // breakIncomingData splits raw data based on '\n', creates and processes new lines
 func (lb *LineBreaker) breakIncomingData(inBuf []byte) {
+	if _, ok := lb.matcher.(*NewLineMatcher); ok {
+		lb.breakIncomingLines(inBuf)
+		return
+	}
+
 	i, j := 0, 0
 	n := len(inBuf)
 	maxj := lb.contentLenLimit - lb.lineBuffer.Len()
 
 	for ; j < n; j++ {
 		if j == maxj {
-			// send line because it is too long
-			lb.lineBuffer.Write(inBuf[i:j])
-			lb.rawDataLen += (j - i)
-			lb.sendLine()
+			lb.breakAtLimit(inBuf[i:j])
 			i = j
 			maxj = i + lb.contentLenLimit
+		} else if lb.carriageReturn == config.CarriageReturnTerminator && inBuf[j] == '\r' {
+			lb.breakAtCarriageReturn(inBuf[i:j])
+			i = j + 1
+			maxj = i + lb.contentLenLimit
 		} else if lb.matcher.Match(lb.lineBuffer.Bytes(), inBuf, i, j) {
-			lb.lineBuffer.Write(inBuf[i:j])
-			lb.rawDataLen += (j - i)
-			lb.rawDataLen++ // account for the matching byte
-			lb.sendLine()
+			lb.breakAtMatch(inBuf[i:j])
 			i = j + 1 // skip the last bytes of the matched sequence
 			maxj = i + lb.contentLenLimit
 		}
@@ -77,6 +124,89 @@ func (lb *LineBreaker) breakIncomingData(inBuf []byte) {
 	lb.rawDataLen += (j - i)
 }
 
+// breakIncomingLines splits raw data based on single-byte '\n' like breakIncomingData, it
+// looks for the line feeds with vectorized searches rather than matching every byte, as
+// the line breaker is on the path of every byte ingested.
+func (lb *LineBreaker) breakIncomingLines(inBuf []byte) {
+	i, j := 0, 0
+	n := len(inBuf)
+	maxj := lb.contentLenLimit - lb.lineBuffer.Len()
+
+	for j < n {
+		end := n
+		if maxj >= j && maxj < end {
+			end = maxj
+		}
+		k := lb.indexLineEnd(inBuf[j:end])
+		if k == -1 {
+			j = end
+			if j == maxj && j < n {
+				lb.breakAtLimit(inBuf[i:j])
+				i = j
+				maxj = i + lb.contentLenLimit
+				// the byte at the limit starts the next line, it does not end it
+				j++
+			}
+			continue
+		}
+		j += k
+		if inBuf[j] == '\r' {
+			lb.breakAtCarriageReturn(inBuf[i:j])
+		} else {
+			lb.breakAtMatch(inBuf[i:j])
+		}
+		i = j + 1
+		maxj = i + lb.contentLenLimit
+		j++
+	}
+	lb.lineBuffer.Write(inBuf[i:])
+	lb.rawDataLen += (n - i)
+}
+
+// indexLineEnd returns the index of the first byte ending a line in data, or -1.
+func (lb *LineBreaker) indexLineEnd(data []byte) int {
+	if lb.carriageReturn == config.CarriageReturnTerminator {
+		return bytes.IndexAny(data, "\r\n")
+	}
+	return bytes.IndexByte(data, '\n')
+}
+
+// breakAtLimit sends the content as a line because it is too long.
+func (lb *LineBreaker) breakAtLimit(content []byte) {
+	lb.lineBuffer.Write(content)
+	lb.rawDataLen += len(content)
+	lb.sendLine()
+	lb.afterCarriageReturn = false
+}
+
+// breakAtCarriageReturn sends the content ended by a carriage return as a line.
+func (lb *LineBreaker) breakAtCarriageReturn(content []byte) {
+	lb.lineBuffer.Write(content)
+	lb.rawDataLen += len(content)
+	lb.rawDataLen++ // account for the carriage return
+	lb.sendLine()
+	lb.afterCarriageReturn = true
+}
+
+// breakAtMatch sends the content ended by the last byte of the line separator as a line.
+func (lb *LineBreaker) breakAtMatch(content []byte) {
+	if lb.afterCarriageReturn && len(content) == 0 && lb.lineBuffer.Len() == 0 {
+		// the line feed of a CRLF sequence, whose line has been sent at the carriage
+		// return, it is accounted for with the next line
+		lb.rawDataLen++
+		lb.afterCarriageReturn = false
+		return
+	}
+	lb.lineBuffer.Write(content)
+	if lb.carriageReturn == config.CarriageReturnStrip && bytes.HasSuffix(lb.lineBuffer.Bytes(), []byte{'\r'}) {
+		lb.lineBuffer.Truncate(lb.lineBuffer.Len() - 1)
+	}
+	lb.rawDataLen += len(content)
+	lb.rawDataLen++ // account for the matching byte
+	lb.sendLine()
+	lb.afterCarriageReturn = false
+}
+
 // sendLine copies content from lineBuffer which is passed to lineHandler
 func (lb *LineBreaker) sendLine() {
 	// Account for longer-than-1-byte line separator
@@ -87,3 +217,79 @@ func (lb *LineBreaker) sendLine() {
 	lb.rawDataLen = 0
 	atomic.AddInt64(&lb.linesDecoded, 1)
 }
+
+// breakIncomingRecords splits raw data into length-prefixed records, the records longer
+// than the content limit are truncated.
+func (lb *LineBreaker) breakIncomingRecords(inBuf []byte) {
+	if lb.skip > 0 {
+		// consume the remaining bytes of a truncated record
+		n := uint64(len(inBuf))
+		if n > lb.skip {
+			n = lb.skip
+		}
+		lb.rawDataLen += int(n)
+		lb.skip -= n
+		inBuf = inBuf[n:]
+		if lb.skip > 0 {
+			return
+		}
+		lb.sendRecord(lb.pending)
+		lb.pending = nil
+	}
+
+	lb.lineBuffer.Write(inBuf)
+	for {
+		buf := lb.lineBuffer.Bytes()
+		length, prefixLen, err := lb.lengthPrefix(buf)
+		if err != nil {
+			// only the malformed prefix is dropped, the next bytes are parsed as the next record
+			log.Warnf("Could not break data into records: %v, dropping %d bytes", err, prefixLen)
+			lb.rawDataLen += prefixLen
+			lb.lineBuffer.Next(prefixLen)
+			lb.sendRecord(nil)
+			continue
+		}
+		if prefixLen == 0 {
+			// the prefix is not complete yet
+			return
+		}
+
+		available := uint64(len(buf) - prefixLen)
+		if length > uint64(lb.contentLenLimit) {
+			if available < uint64(lb.contentLenLimit) {
+				return
+			}
+			content := make([]byte, lb.contentLenLimit)
+			copy(content, buf[prefixLen:])
+			if available < length {
+				// wait for the remaining bytes of the record
+				lb.rawDataLen += len(buf)
+				lb.lineBuffer.Reset()
+				lb.pending = content
+				lb.skip = length - available
+				return
+			}
+			lb.rawDataLen += prefixLen + int(length)
+			lb.lineBuffer.Next(prefixLen + int(length))
+			lb.sendRecord(content)
+			continue
+		}
+
+		if available < length {
+			// the record is not complete yet
+			return
+		}
+		content := make([]byte, length)
+		copy(content, buf[prefixLen:])
+		lb.rawDataLen += prefixLen + int(length)
+		lb.lineBuffer.Next(prefixLen + int(length))
+		lb.sendRecord(content)
+	}
+}
+
+// sendRecord passes the content of a record to the lineHandler
+func (lb *LineBreaker) sendRecord(content []byte) {
+	lb.outputChan <- NewDecodedInput(content, lb.rawDataLen)
+	lb.rawDataLen = 0
+	atomic.AddInt64(&lb.linesDecoded, 1)
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func benchmarkLineBreaker(b *testing.B, matcher EndLineMatcher, carriageReturn string, lineLen int) {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < 4096; i++ {
+		line := fmt.Sprintf("This is a log test line to benchmark the logs agent %d ", i)
+		buf.WriteString(line)
+		buf.Write(bytes.Repeat([]byte{'x'}, lineLen-len(line)))
+		buf.WriteByte('\n')
+	}
+	data := buf.Bytes()[:4096]
+
+	inputChan, outputChan := make(chan *Input), make(chan *DecodedInput, 1000)
+	lb := NewLineBreaker(inputChan, outputChan, matcher, defaultContentLenLimit)
+	lb.carriageReturn = carriageReturn
+	go func() {
+		for range outputChan {
+		}
+	}()
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		lb.breakIncomingData(data)
+	}
+	close(outputChan)
+}
+
+func BenchmarkLineBreakerNewLineShortLines(b *testing.B) {
+	benchmarkLineBreaker(b, &NewLineMatcher{}, config.CarriageReturnPreserve, 100)
+}
+
+func BenchmarkLineBreakerNewLineLongLines(b *testing.B) {
+	benchmarkLineBreaker(b, &NewLineMatcher{}, config.CarriageReturnPreserve, 1000)
+}
+
+func BenchmarkLineBreakerNewLineCarriageReturnTerminator(b *testing.B) {
+	benchmarkLineBreaker(b, &NewLineMatcher{}, config.CarriageReturnTerminator, 1000)
+}
+
+func BenchmarkLineBreakerSingleByteSequence(b *testing.B) {
+	benchmarkLineBreaker(b, NewBytesSequenceMatcher([]byte{'\n'}, 1), config.CarriageReturnPreserve, 1000)
+}
+
+func BenchmarkLineBreakerUTF16Sequence(b *testing.B) {
+	benchmarkLineBreaker(b, NewBytesSequenceMatcher(Utf16leEOL, 2), config.CarriageReturnPreserve, 1000)
+}
@@ -1,11 +1,14 @@
 package decoder
 
 import (
+	"math/rand"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 )
 
 const contentLenLimit = 100
@@ -137,6 +140,71 @@ func TestLineBreakIncomingData(t *testing.T) {
 	assert.Equal(t, 0, lb.rawDataLen)
 }
 
+func TestLineBreakIncomingDataWithCarriageReturns(t *testing.T) {
+	test := func(carriageReturn string, chunks []string, expected []string) func(*testing.T) {
+		return func(t *testing.T) {
+			inputChan, outputChan := lineBreakerChans()
+			lb := NewLineBreaker(inputChan, outputChan, &NewLineMatcher{}, contentLenLimit)
+			lb.carriageReturn = carriageReturn
+			rawDataLen := 0
+			for _, chunk := range chunks {
+				lb.breakIncomingData([]byte(chunk))
+				rawDataLen += len(chunk)
+			}
+			for _, content := range expected {
+				line := <-outputChan
+				assert.Equal(t, content, string(line.content))
+				rawDataLen -= line.rawDataLen
+			}
+			assert.Len(t, outputChan, 0)
+			// the raw data not accounted for yet is still in the buffer of the line breaker
+			assert.Equal(t, rawDataLen, lb.rawDataLen)
+		}
+	}
+
+	t.Run("preserved by default", test("", []string{"line1\r\nline2\r\n"}, []string{"line1\r", "line2\r"}))
+	t.Run("preserved", test(config.CarriageReturnPreserve, []string{"line1\r\nline2\n"}, []string{"line1\r", "line2"}))
+	t.Run("stripped", test(config.CarriageReturnStrip, []string{"line1\r\nli\rne2\r", "\n"}, []string{"line1", "li\rne2"}))
+	t.Run("terminator", test(config.CarriageReturnTerminator, []string{"line1\rline2\r\nline3\nline4\r"}, []string{"line1", "line2", "line3", "line4"}))
+	t.Run("terminator across chunks", test(config.CarriageReturnTerminator, []string{"line1\r", "\nline2\r", "\r\n"}, []string{"line1", "line2", ""}))
+}
+
+func TestLineBreakIncomingLinesMatchesByteByByteBreaking(t *testing.T) {
+	// breaking lines with a single-byte sequence matches every byte, it must produce the same
+	// lines as the vectorized search of the new line matcher
+	breakLines := func(matcher EndLineMatcher, carriageReturn string, chunks [][]byte) []DecodedInput {
+		inputChan, outputChan := make(chan *Input), make(chan *DecodedInput, 1000)
+		lb := NewLineBreaker(inputChan, outputChan, matcher, 8)
+		lb.carriageReturn = carriageReturn
+		for _, chunk := range chunks {
+			lb.breakIncomingData(chunk)
+		}
+		close(outputChan)
+		var lines []DecodedInput
+		for line := range outputChan {
+			lines = append(lines, *line)
+		}
+		return lines
+	}
+
+	random := rand.New(rand.NewSource(42))
+	alphabet := []byte("ab\r\n")
+	for i := 0; i < 200; i++ {
+		var chunks [][]byte
+		for c := random.Intn(5) + 1; c > 0; c-- {
+			chunk := make([]byte, random.Intn(20))
+			for k := range chunk {
+				chunk[k] = alphabet[random.Intn(len(alphabet))]
+			}
+			chunks = append(chunks, chunk)
+		}
+		for _, carriageReturn := range []string{config.CarriageReturnPreserve, config.CarriageReturnStrip, config.CarriageReturnTerminator} {
+			expected := breakLines(NewBytesSequenceMatcher([]byte{'\n'}, 1), carriageReturn, chunks)
+			assert.Equal(t, expected, breakLines(&NewLineMatcher{}, carriageReturn, chunks), "chunks %q with %s carriage returns", chunks, carriageReturn)
+		}
+	}
+}
+
 func TestLineBreakIncomingDataWithCustomSequence(t *testing.T) {
 	inputChan, outputChan := lineBreakerChans()
 	lb := NewLineBreaker(inputChan, outputChan, NewBytesSequenceMatcher([]byte("SEPARATOR"), 1), contentLenLimit)
@@ -242,3 +310,84 @@ func TestLinBreakerInputNotDockerHeader(t *testing.T) {
 	assert.Equal(t, expected2, output.content)
 	assert.Equal(t, len(expected2)+1, output.rawDataLen)
 }
+
+func TestLengthPrefixedLineBreakActor(t *testing.T) {
+	var data []byte
+	for _, record := range []string{"rec\n1", "", "record 3"} {
+		data = append(data, byte(len(record)))
+		data = append(data, record...)
+	}
+
+	test := func(chunks [][]byte) func(*testing.T) {
+		return func(t *testing.T) {
+			inputChan, outputChan := lineBreakerChans()
+			go func() {
+				for _, chunk := range chunks {
+					inputChan <- &Input{content: chunk}
+				}
+			}()
+			lb := NewLengthPrefixedLineBreaker(inputChan, outputChan, varintLengthPrefix, contentLenLimit)
+			lb.Start()
+
+			record := <-outputChan
+			require.Equal(t, "rec\n1", string(record.content))
+			require.Equal(t, 6, record.rawDataLen)
+			record = <-outputChan
+			require.Equal(t, "", string(record.content))
+			require.Equal(t, 1, record.rawDataLen)
+			record = <-outputChan
+			require.Equal(t, "record 3", string(record.content))
+			require.Equal(t, 9, record.rawDataLen)
+
+			close(inputChan)
+			_, ok := <-outputChan
+			require.Equal(t, false, ok)
+		}
+	}
+
+	t.Run("with one chunk", test([][]byte{data}))
+
+	var bytes [][]byte
+	for _, b := range data {
+		bytes = append(bytes, []byte{b})
+	}
+	t.Run("with chunk per byte", test(bytes))
+}
+
+func TestLengthPrefixedLineBreakTruncatesLongRecords(t *testing.T) {
+	inputChan, outputChan := lineBreakerChans()
+	lb := NewLengthPrefixedLineBreaker(inputChan, outputChan, uint32BELengthPrefix, contentLenLimit)
+	lb.Start()
+
+	long := strings.Repeat("a", contentLenLimit+50)
+	inputChan <- &Input{content: append([]byte{0, 0, 0, byte(len(long))}, long[:contentLenLimit+10]...)}
+	inputChan <- &Input{content: append([]byte(long[contentLenLimit+10:]), 0, 0, 0, 2, 'o', 'k')}
+
+	record := <-outputChan
+	require.Equal(t, long[:contentLenLimit], string(record.content))
+	// the truncated record accounts for all its bytes
+	require.Equal(t, 4+len(long), record.rawDataLen)
+	record = <-outputChan
+	require.Equal(t, "ok", string(record.content))
+	require.Equal(t, 6, record.rawDataLen)
+	close(inputChan)
+}
+
+func TestLengthPrefixedLineBreakDropsMalformedData(t *testing.T) {
+	inputChan, outputChan := lineBreakerChans()
+	lb := NewLengthPrefixedLineBreaker(inputChan, outputChan, varintLengthPrefix, contentLenLimit)
+	lb.Start()
+
+	// a varint overflowing 64 bits, followed by a valid record
+	malformed := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}
+	inputChan <- &Input{content: append(malformed, 2, 'o', 'k')}
+
+	// only the malformed prefix is dropped
+	record := <-outputChan
+	require.Equal(t, "", string(record.content))
+	require.Equal(t, len(malformed), record.rawDataLen)
+	record = <-outputChan
+	require.Equal(t, "ok", string(record.content))
+	require.Equal(t, 3, record.rawDataLen)
+	close(inputChan)
+}
@@ -47,7 +47,7 @@ func benchmarkAutoMultiLineHandler(b *testing.B, logs int, line string) {
 
 	inputChan, outputChan := lineHandlerChans()
 	source := config.NewLogSource("config", &config.LogsConfig{})
-	h := NewAutoMultilineHandler(inputChan, outputChan, defaultContentLenLimit, 1000, 0.9, 30*time.Second, 1000*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{})
+	h := NewAutoMultilineHandler(inputChan, outputChan, defaultContentLenLimit, 1000, 0.9, 30*time.Second, 1000*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{}, 0)
 	h.Start()
 
 	go readToClose(outputChan)
@@ -6,6 +6,7 @@
 package decoder
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"testing"
@@ -244,7 +245,7 @@ func TestAutoMultiLineHandlerStaysSingleLineMode(t *testing.T) {
 	inputChan, outputChan := lineHandlerChans()
 	source := config.NewLogSource("config", &config.LogsConfig{})
 	detectedPattern := &DetectedPattern{}
-	h := NewAutoMultilineHandler(inputChan, outputChan, 100, 5, 1.0, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, detectedPattern)
+	h := NewAutoMultilineHandler(inputChan, outputChan, 100, 5, 1.0, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, detectedPattern, 0)
 	h.Start()
 	defer close(inputChan)
 
@@ -261,7 +262,7 @@ func TestAutoMultiLineHandlerSwitchesToMultiLineMode(t *testing.T) {
 	inputChan, outputChan := lineHandlerChans()
 	source := config.NewLogSource("config", &config.LogsConfig{})
 	detectedPattern := &DetectedPattern{}
-	h := NewAutoMultilineHandler(inputChan, outputChan, 100, 5, 1.0, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, detectedPattern)
+	h := NewAutoMultilineHandler(inputChan, outputChan, 100, 5, 1.0, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, detectedPattern, 0)
 	h.Start()
 	defer close(inputChan)
 
@@ -277,7 +278,7 @@ func TestAutoMultiLineHandlerSwitchesToMultiLineMode(t *testing.T) {
 func TestAutoMultiLineHandlerHandelsMessage(t *testing.T) {
 	inputChan, outputChan := lineHandlerChans()
 	source := config.NewLogSource("config", &config.LogsConfig{})
-	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 1, 1.0, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{})
+	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 1, 1.0, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{}, 0)
 	h.Start()
 	defer close(inputChan)
 
@@ -297,7 +298,7 @@ func TestAutoMultiLineHandlerHandelsMessage(t *testing.T) {
 func TestAutoMultiLineHandlerHandelsMessageConflictingPatterns(t *testing.T) {
 	inputChan, outputChan := lineHandlerChans()
 	source := config.NewLogSource("config", &config.LogsConfig{})
-	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 4, 0.75, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{})
+	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 4, 0.75, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{}, 0)
 	h.Start()
 	defer close(inputChan)
 
@@ -324,7 +325,7 @@ func TestAutoMultiLineHandlerHandelsMessageConflictingPatterns(t *testing.T) {
 func TestAutoMultiLineHandlerHandelsMessageConflictingPatternsNoWinner(t *testing.T) {
 	inputChan, outputChan := lineHandlerChans()
 	source := config.NewLogSource("config", &config.LogsConfig{})
-	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 4, 0.75, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{})
+	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 4, 0.75, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, &DetectedPattern{}, 0)
 	h.Start()
 	defer close(inputChan)
 
@@ -345,3 +346,57 @@ func TestAutoMultiLineHandlerHandelsMessageConflictingPatternsNoWinner(t *testin
 
 	assert.Equal(t, "Jul 12, 2021 12:55:15 PM test message 2", string(output.Content))
 }
+
+func TestAutoMultiLineHandlerScoresAllMatchingPatterns(t *testing.T) {
+	inputChan, outputChan := lineHandlerChans()
+	source := config.NewLogSource("config", &config.LogsConfig{})
+	detectedPattern := &DetectedPattern{}
+	// the first user pattern matches only half of the lines, a more specific pattern matches them all
+	additionalPatterns := []*regexp.Regexp{regexp.MustCompile(`^Jul 12, 2021 12:55:15 PM test message [0-1]`)}
+	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 4, 0.75, 10*time.Millisecond, 10*time.Millisecond, source, additionalPatterns, detectedPattern, 0)
+	h.Start()
+	defer close(inputChan)
+
+	for i := 0; i < 4; i++ {
+		inputChan <- getDummyMessageWithLF(fmt.Sprintf("Jul 12, 2021 12:55:15 PM test message %d", i))
+		<-outputChan
+	}
+
+	assert.Equal(t, formatsToTry[len(formatsToTry)-1], detectedPattern.Get())
+}
+
+func TestAutoMultiLineHandlerRedetectsPattern(t *testing.T) {
+	inputChan, outputChan := lineHandlerChans()
+	source := config.NewLogSource("config", &config.LogsConfig{})
+	detectedPattern := &DetectedPattern{}
+	h := NewAutoMultilineHandler(inputChan, outputChan, 500, 2, 1.0, 10*time.Second, 10*time.Second, source, []*regexp.Regexp{}, detectedPattern, 3)
+	h.Start()
+	defer close(inputChan)
+
+	inputChan <- getDummyMessageWithLF("Jul 12, 2021 12:55:15 PM test message 1")
+	<-outputChan
+	inputChan <- getDummyMessageWithLF("Jul 12, 2021 12:55:15 PM test message 2")
+	<-outputChan
+	assert.Equal(t, formatsToTry[len(formatsToTry)-1], detectedPattern.Get())
+
+	// the format changes, the previous pattern doesn't match anymore
+	inputChan <- getDummyMessageWithLF("2021-07-08 05:08:19,214 test message 3")
+	inputChan <- getDummyMessageWithLF("2021-07-08 05:08:19,214 test message 4")
+	inputChan <- getDummyMessageWithLF("2021-07-08 05:08:19,214 test message 5")
+	output := <-outputChan
+	assert.Equal(t, "2021-07-08 05:08:19,214 test message 3\\n2021-07-08 05:08:19,214 test message 4\\n2021-07-08 05:08:19,214 test message 5", string(output.Content))
+	assert.Nil(t, detectedPattern.Get())
+
+	// the new pattern is detected
+	inputChan <- getDummyMessageWithLF("2021-07-08 05:08:19,214 test message 6")
+	<-outputChan
+	inputChan <- getDummyMessageWithLF("2021-07-08 05:08:19,214 test message 7")
+	<-outputChan
+	assert.Equal(t, formatsToTry[len(formatsToTry)-2], detectedPattern.Get())
+
+	inputChan <- getDummyMessageWithLF("2021-07-08 05:08:19,214 test message 8")
+	inputChan <- getDummyMessageWithLF("java.lang.Exception: boom")
+	inputChan <- getDummyMessageWithLF("2021-07-08 05:08:19,214 test message 9")
+	output = <-outputChan
+	assert.Equal(t, "2021-07-08 05:08:19,214 test message 8\\njava.lang.Exception: boom", string(output.Content))
+}
@@ -146,6 +146,12 @@ func (p *MultiLineParser) process(input *DecodedInput) {
 	if err != nil {
 		log.Debug(err)
 	}
+	// partial lines can only be completed by lines coming from the same stream, which
+	// is reflected by their status: when streams are interleaved, the pending content
+	// is sent as is rather than being merged with a line from another stream.
+	if p.buffer.Len() > 0 && msg.Status != p.status {
+		p.sendLine()
+	}
 	// track the raw data length and the timestamp so that the agent tails
 	// from the right place at restart
 	p.rawDataLen += input.rawDataLen
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// Masker implements an actor which applies the masking rules of a source to the
+// messages, before they are parsed or sampled. The placeholders of the rules can
+// refer to the capture groups of their patterns, to keep part of the matched text.
+//
+// After Start(), the actor runs until its input channel is closed.
+// After all inputs are processed, the actor closes its output channel.
+type Masker struct {
+	inputChan  chan *Message
+	outputChan chan *Message
+	rules      []*config.ProcessingRule
+}
+
+// NewMasker returns a new Masker applying the given masking rules.
+func NewMasker(inputChan chan *Message, outputChan chan *Message, rules []*config.ProcessingRule) *Masker {
+	return &Masker{
+		inputChan:  inputChan,
+		outputChan: outputChan,
+		rules:      rules,
+	}
+}
+
+// maskingRules returns the masking rules among the processing rules.
+func maskingRules(rules []*config.ProcessingRule) []*config.ProcessingRule {
+	var masking []*config.ProcessingRule
+	for _, rule := range rules {
+		if rule.Type == config.MaskSequences {
+			masking = append(masking, rule)
+		}
+	}
+	return masking
+}
+
+// Start starts the masker.
+func (m *Masker) Start() {
+	go m.run()
+}
+
+// run consumes new messages, masks them and forwards them.
+func (m *Masker) run() {
+	for msg := range m.inputChan {
+		if len(msg.Content) > 0 {
+			msg.Content = mask(m.rules, msg.Content)
+		}
+		msg.Masked = true
+		m.outputChan <- msg
+	}
+	close(m.outputChan)
+}
+
+// mask replaces the sequences matching the rules by their placeholders.
+func mask(rules []*config.ProcessingRule, content []byte) []byte {
+	for _, rule := range rules {
+		if rule.Regex != nil {
+			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		}
+	}
+	return content
+}
@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestMaskerKeepsCaptureGroups(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	rules := []*config.ProcessingRule{
+		{Type: config.MaskSequences, Regex: regexp.MustCompile("(?P<key>password|token)=\\S+"), Placeholder: []byte("${key}=[masked]")},
+		{Type: config.MaskSequences, Regex: regexp.MustCompile("(user=)\\w+"), Placeholder: []byte("${1}[masked]")},
+	}
+	m := NewMasker(inputChan, outputChan, rules)
+	m.Start()
+
+	for _, content := range []string{"login user=john with password=hunter2 and token=abcdef", "no secret", ""} {
+		inputChan <- NewMessage([]byte(content), message.StatusInfo, len(content)+1, "")
+	}
+	close(inputChan)
+
+	var contents []string
+	for output := range outputChan {
+		assert.True(t, output.Masked)
+		contents = append(contents, string(output.Content))
+	}
+	assert.Equal(t, []string{"login user=[masked] with password=[masked] and token=[masked]", "no secret", ""}, contents)
+}
+
+func TestDecoderMasksMessages(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.MaskSequences, Name: "mask", Pattern: "(password=)\\S+", ReplacePlaceholder: "${1}[masked]"}
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+	d := InitializeDecoder(source, noop.New())
+	d.Start()
+	defer d.Stop()
+
+	d.InputChan <- NewInput([]byte("login with password=hunter2\n"))
+	output := <-d.OutputChan
+	assert.Equal(t, "login with password=[masked]", string(output.Content))
+	assert.True(t, output.Masked)
+}
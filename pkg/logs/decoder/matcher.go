@@ -10,6 +10,10 @@ var (
 	Utf16leEOL = []byte{'\n', 0x00}
 	// Utf16beEOL is the bytes sequence for UTF-16 Big-Endian end-of-line char
 	Utf16beEOL = []byte{0x00, '\n'}
+	// Utf32leEOL is the bytes sequence for UTF-32 Little-Endian end-of-line char
+	Utf32leEOL = []byte{'\n', 0x00, 0x00, 0x00}
+	// Utf32beEOL is the bytes sequence for UTF-32 Big-Endian end-of-line char
+	Utf32beEOL = []byte{0x00, 0x00, 0x00, '\n'}
 )
 
 // EndLineMatcher defines the criterion to whether to end a line or not.
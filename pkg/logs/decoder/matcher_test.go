@@ -71,6 +71,18 @@ func TestBytesSequenceMatcher_Match_Misaligned(t *testing.T) {
 	testMatchAt(t, NewBytesSequenceMatcher([]byte{0x0a, 0x00}, 2), input, 18)
 }
 
+func TestBytesSequenceMatcher_Match_UTF32(t *testing.T) {
+	input := []byte{
+		0x42, 0x00, 0x00, 0x00, // B
+		0x00, 0x0a, 0x00, 0x00, // U+0A00  // {0x0a, 0x00, 0x00, 0x00} starts here, at a misaligned offset
+		0x00, 0x00, 0x00, 0x00, // \0
+		0x44, 0x00, 0x00, 0x00, // D
+		0x0a, 0x00, 0x00, 0x00, // \n
+		0x41, 0x00, 0x00, 0x00, // A
+	}
+	testMatchAt(t, NewBytesSequenceMatcher(Utf32leEOL, 4), input, 20)
+}
+
 func TestBytesSequenceMatcher_SeparatorLen(t *testing.T) {
 	nlm := NewBytesSequenceMatcher([]byte{0x0a, 0x00}, 2)
 	require.Equal(t, 2, nlm.SeparatorLen())
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"regexp"
 	"time"
+	"unicode"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 )
@@ -27,6 +28,8 @@ type MultiLineHandler struct {
 	status         string
 	timestamp      string
 	countInfo      *config.CountInfo
+	// chunks tags the chunks of the messages too long rather than truncating them, when not nil
+	chunks *chunker
 }
 
 // NewMultiLineHandler returns a new MultiLineHandler.
@@ -108,9 +111,9 @@ func (h *MultiLineHandler) process(message *Message) {
 	h.timestamp = message.Timestamp
 	h.status = message.Status
 
-	if h.buffer.Len() > 0 {
-		// the buffer already contains some data which means that
-		// the current line is not the first line of the message
+	if h.buffer.Len() > 0 || (h.chunks != nil && h.chunks.inProgress()) {
+		// the buffer already contains some data, or has been sent as a chunk,
+		// which means that the current line is not the first line of the message
 		h.buffer.Write(escapedLineFeed)
 	}
 
@@ -123,7 +126,10 @@ func (h *MultiLineHandler) process(message *Message) {
 
 	h.buffer.Write(message.Content)
 
-	if h.buffer.Len() >= h.lineLimit {
+	if h.buffer.Len() >= h.lineLimit && h.chunks != nil {
+		// the message is sent in chunks to be reassembled
+		h.sendChunk()
+	} else if h.buffer.Len() >= h.lineLimit {
 		// the multiline message is too long, it needs to be cut off and send,
 		// adding the truncated flag the end of the content
 		h.buffer.Write(truncatedFlag)
@@ -142,10 +148,36 @@ func (h *MultiLineHandler) sendBuffer() {
 	}()
 
 	data := bytes.TrimSpace(h.buffer.Bytes())
+	lastChunk := h.chunks != nil && h.chunks.inProgress()
+	if lastChunk {
+		// the last chunk of a message too long
+		data = bytes.TrimRightFunc(h.buffer.Bytes(), unicode.IsSpace)
+	}
 	content := make([]byte, len(data))
 	copy(content, data)
 
-	if len(content) > 0 || h.linesLen > 0 {
-		h.outputChan <- NewMessage(content, h.status, h.linesLen, h.timestamp)
+	if len(content) > 0 || h.linesLen > 0 || lastChunk {
+		msg := NewMessage(content, h.status, h.linesLen, h.timestamp)
+		if lastChunk {
+			h.chunks.next(msg, true)
+		}
+		h.outputChan <- msg
+	}
+}
+
+// sendChunk forwards the content stored in the buffer as a chunk of a message too long,
+// which is not its last chunk.
+func (h *MultiLineHandler) sendChunk() {
+	data := h.buffer.Bytes()
+	if !h.chunks.inProgress() {
+		data = bytes.TrimLeftFunc(data, unicode.IsSpace)
 	}
+	content := make([]byte, len(data))
+	copy(content, data)
+
+	msg := NewMessage(content, h.status, h.linesLen, h.timestamp)
+	h.chunks.next(msg, false)
+	h.outputChan <- msg
+	h.buffer.Reset()
+	h.linesLen = 0
 }
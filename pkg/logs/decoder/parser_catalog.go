@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+)
+
+// Parser parses lines of log data into messages, it can be implemented outside of the
+// logs package to support custom log formats.
+type Parser = parsers.Parser
+
+// ParsedMessage is a message parsed from a line of log data by a Parser.
+type ParsedMessage = parsers.Message
+
+// ParserFactory returns the parser of the lines of a source, given the parser the decoder
+// would use otherwise. The factory can either wrap this parser, e.g. to strip an envelope
+// or a custom header from the content it returns, or replace it.
+type ParserFactory func(source *config.LogSource, parser Parser) Parser
+
+var (
+	parserCatalogMutex sync.RWMutex
+	parserCatalog      = make(map[string]ParserFactory)
+)
+
+// RegisterParser adds to the catalog the parser factory used by the decoders of the sources
+// selecting it by name with their parser option, whatever their type, replacing the factory
+// previously registered with this name, if any. The sources selecting a name which is not
+// registered are invalid.
+func RegisterParser(name string, factory ParserFactory) {
+	parserCatalogMutex.Lock()
+	defer parserCatalogMutex.Unlock()
+	parserCatalog[name] = factory
+	config.RegisterCustomParser(name)
+}
+
+// UnregisterParser removes from the catalog the parser factory of the given name.
+func UnregisterParser(name string) {
+	parserCatalogMutex.Lock()
+	defer parserCatalogMutex.Unlock()
+	delete(parserCatalog, name)
+	config.UnregisterCustomParser(name)
+}
+
+// GetRegisteredParsers returns the names of the parser factories in the catalog.
+func GetRegisteredParsers() []string {
+	parserCatalogMutex.RLock()
+	defer parserCatalogMutex.RUnlock()
+	names := make([]string, 0, len(parserCatalog))
+	for name := range parserCatalog {
+		names = append(names, name)
+	}
+	return names
+}
+
+// catalogParser returns the parser built by the factory selected by the parser option of
+// the source, or the given parser if there is none.
+func catalogParser(source *config.LogSource, parser Parser) Parser {
+	if source.Config == nil || source.Config.Parser == "" {
+		return parser
+	}
+	parserCatalogMutex.RLock()
+	factory, exists := parserCatalog[source.Config.Parser]
+	parserCatalogMutex.RUnlock()
+	if !exists {
+		return parser
+	}
+	return factory(source, parser)
+}
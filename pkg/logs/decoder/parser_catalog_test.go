@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const envelopeParserName = "envelope"
+
+// envelopeParser strips envelopes of the form "status|content" from the content
+// returned by the parser it wraps.
+type envelopeParser struct {
+	parser Parser
+}
+
+func (p *envelopeParser) Parse(line []byte) (ParsedMessage, error) {
+	msg, err := p.parser.Parse(line)
+	if err != nil {
+		return msg, err
+	}
+	if i := bytes.IndexByte(msg.Content, '|'); i != -1 {
+		msg.Status = string(msg.Content[:i])
+		msg.Content = msg.Content[i+1:]
+	}
+	return msg, nil
+}
+
+func (p *envelopeParser) SupportsPartialLine() bool {
+	return p.parser.SupportsPartialLine()
+}
+
+func TestRegisterParser(t *testing.T) {
+	RegisterParser(envelopeParserName, func(source *config.LogSource, parser Parser) Parser {
+		return &envelopeParser{parser: parser}
+	})
+	defer UnregisterParser(envelopeParserName)
+	assert.Contains(t, GetRegisteredParsers(), envelopeParserName)
+
+	// the parser is selected by its name, whatever the type of the source
+	logsConfig := &config.LogsConfig{Type: config.TCPType, Port: 10514, Parser: envelopeParserName}
+	assert.Nil(t, logsConfig.Validate())
+	d := InitializeDecoder(config.NewLogSource("config", logsConfig), noop.New())
+	d.Start()
+	defer d.Stop()
+
+	d.InputChan <- NewInput([]byte("error|something went wrong\n"))
+	output := <-d.OutputChan
+	assert.Equal(t, "something went wrong", string(output.Content))
+	assert.Equal(t, message.StatusError, output.Status)
+}
+
+func TestRegisterParserOnlyAppliesToTheSourcesSelectingIt(t *testing.T) {
+	RegisterParser(envelopeParserName, func(source *config.LogSource, parser Parser) Parser {
+		return &envelopeParser{parser: parser}
+	})
+	defer UnregisterParser(envelopeParserName)
+
+	d := InitializeDecoder(config.NewLogSource("config", &config.LogsConfig{}), noop.New())
+	d.Start()
+	defer d.Stop()
+
+	d.InputChan <- NewInput([]byte("error|something went wrong\n"))
+	assert.Equal(t, "error|something went wrong", string((<-d.OutputChan).Content))
+}
+
+func TestUnregisterParser(t *testing.T) {
+	RegisterParser(envelopeParserName, func(source *config.LogSource, parser Parser) Parser {
+		return &envelopeParser{parser: parser}
+	})
+	UnregisterParser(envelopeParserName)
+	assert.NotContains(t, GetRegisteredParsers(), envelopeParserName)
+
+	// the sources selecting an unknown parser are invalid
+	logsConfig := &config.LogsConfig{Type: config.TCPType, Port: 10514, Parser: envelopeParserName}
+	assert.NotNil(t, logsConfig.Validate())
+	parser := noop.New()
+	assert.Equal(t, parser, catalogParser(config.NewLogSource("config", logsConfig), parser))
+}
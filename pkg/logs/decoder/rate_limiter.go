@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// RateLimiter implements an actor which limits the number of lines and bytes per second
+// of the messages going through it. Depending on the policy, the messages exceeding the
+// limits either wait until they are allowed, holding back the tailer, or are dropped.
+//
+// Dropped messages are still sent with an empty content, so that the tailers keep track
+// of the offsets of the data they consumed.
+//
+// After Start(), the actor runs until its input channel is closed.
+// After all inputs are processed, the actor closes its output channel.
+type RateLimiter struct {
+	inputChan    chan *Message
+	outputChan   chan *Message
+	linesLimiter *rate.Limiter
+	bytesLimiter *rate.Limiter
+	drop         bool
+	sourceName   string
+}
+
+// NewRateLimiter returns a new RateLimiter enforcing the rate limit of the source.
+func NewRateLimiter(inputChan chan *Message, outputChan chan *Message, sourceName string, rateLimit *config.RateLimit) *RateLimiter {
+	l := &RateLimiter{
+		inputChan:  inputChan,
+		outputChan: outputChan,
+		drop:       rateLimit.Policy == config.RateLimitDrop,
+		sourceName: sourceName,
+	}
+	// the bursts allow up to one second worth of messages at once
+	if rateLimit.LinesPerSecond > 0 {
+		l.linesLimiter = rate.NewLimiter(rate.Limit(rateLimit.LinesPerSecond), rateLimit.LinesPerSecond)
+	}
+	if rateLimit.BytesPerSecond > 0 {
+		l.bytesLimiter = rate.NewLimiter(rate.Limit(rateLimit.BytesPerSecond), rateLimit.BytesPerSecond)
+	}
+	return l
+}
+
+// Start starts the rate limiter.
+func (l *RateLimiter) Start() {
+	go l.run()
+}
+
+// run consumes new messages and forwards them within the limits.
+func (l *RateLimiter) run() {
+	for msg := range l.inputChan {
+		if l.drop {
+			if !l.allow(len(msg.Content)) {
+				metrics.LogsRateLimited.Add(1)
+				metrics.TlmLogsRateLimited.Inc(l.sourceName)
+				msg.Content = nil
+			}
+		} else {
+			l.wait(len(msg.Content))
+		}
+		l.outputChan <- msg
+	}
+	close(l.outputChan)
+}
+
+// allow returns true if a message of the given size is within the limits.
+// The line is only consumed from the lines limit when the message is within the bytes limit.
+func (l *RateLimiter) allow(size int) bool {
+	if l.bytesLimiter != nil && !l.bytesLimiter.AllowN(time.Now(), l.bytes(size)) {
+		return false
+	}
+	return l.linesLimiter == nil || l.linesLimiter.Allow()
+}
+
+// wait blocks until a message of the given size is within the limits.
+func (l *RateLimiter) wait(size int) {
+	// the errors can be ignored as the context is never cancelled and the sizes never exceed the bursts
+	if l.linesLimiter != nil {
+		_ = l.linesLimiter.Wait(context.Background())
+	}
+	if l.bytesLimiter != nil {
+		_ = l.bytesLimiter.WaitN(context.Background(), l.bytes(size))
+	}
+}
+
+// bytes returns the number of bytes consumed from the bytes limit by a message of the given
+// size, messages larger than the burst consume the whole burst.
+func (l *RateLimiter) bytes(size int) int {
+	if size > l.bytesLimiter.Burst() {
+		return l.bytesLimiter.Burst()
+	}
+	return size
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestRateLimiterDropsMessagesOverTheLinesLimit(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	l := NewRateLimiter(inputChan, outputChan, "test", &config.RateLimit{LinesPerSecond: 2, Policy: config.RateLimitDrop})
+	l.Start()
+
+	for i := 0; i < 5; i++ {
+		inputChan <- NewMessage([]byte("message"), message.StatusInfo, len("message")+1, "")
+	}
+	close(inputChan)
+
+	var contents []string
+	for output := range outputChan {
+		// dropped messages keep their raw length for the offsets
+		assert.Equal(t, len("message")+1, output.RawDataLen)
+		contents = append(contents, string(output.Content))
+	}
+	assert.Equal(t, []string{"message", "message", "", "", ""}, contents)
+}
+
+func TestRateLimiterDropsMessagesOverTheBytesLimit(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	l := NewRateLimiter(inputChan, outputChan, "test", &config.RateLimit{BytesPerSecond: 10, Policy: config.RateLimitDrop})
+	l.Start()
+
+	inputChan <- NewMessage([]byte("12345"), message.StatusInfo, 6, "")
+	inputChan <- NewMessage([]byte("1234567890"), message.StatusInfo, 11, "")
+	inputChan <- NewMessage([]byte("12345"), message.StatusInfo, 6, "")
+	close(inputChan)
+
+	assert.Equal(t, "12345", string((<-outputChan).Content))
+	assert.Equal(t, "", string((<-outputChan).Content))
+	assert.Equal(t, "12345", string((<-outputChan).Content))
+}
+
+func TestRateLimiterBlocksMessagesOverTheLinesLimit(t *testing.T) {
+	inputChan := make(chan *Message, 30)
+	outputChan := make(chan *Message, 30)
+	l := NewRateLimiter(inputChan, outputChan, "test", &config.RateLimit{LinesPerSecond: 20})
+	l.Start()
+
+	start := time.Now()
+	for i := 0; i < 25; i++ {
+		inputChan <- NewMessage([]byte("message"), message.StatusInfo, len("message")+1, "")
+	}
+	close(inputChan)
+
+	count := 0
+	for output := range outputChan {
+		assert.Equal(t, "message", string(output.Content))
+		count++
+	}
+	assert.Equal(t, 25, count)
+	// the 5 messages over the burst wait for 5/20th of a second
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+}
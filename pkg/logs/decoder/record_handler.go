@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"encoding/base64"
+)
+
+// RecordHandler forwards the records of length-prefixed data encoded in base64: unlike
+// lines, their content is neither trimmed nor aggregated, and it is already truncated by
+// the LineBreaker when it is too long. Their binary content is encoded so that it is not
+// altered by the UTF-8 sanitization of the encoders of the pipeline.
+type RecordHandler struct {
+	inputChan  chan *Message
+	outputChan chan *Message
+}
+
+// NewRecordHandler returns a new RecordHandler.
+func NewRecordHandler(inputChan chan *Message, outputChan chan *Message) *RecordHandler {
+	return &RecordHandler{
+		inputChan:  inputChan,
+		outputChan: outputChan,
+	}
+}
+
+// Start starts the handler.
+func (h *RecordHandler) Start() {
+	go h.run()
+}
+
+// run forwards the records.
+func (h *RecordHandler) run() {
+	for record := range h.inputChan {
+		if len(record.Content) > 0 {
+			record.Content = []byte(base64.StdEncoding.EncodeToString(record.Content))
+		}
+		h.outputChan <- record
+	}
+	close(h.outputChan)
+}
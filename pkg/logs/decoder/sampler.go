@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"math/rand"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// Sampler implements an actor which keeps only a part of the messages matching the
+// sampling rules of a source, either one message out of N, or a random percentage
+// of them. A message is sampled by the first rule it matches.
+//
+// Sampled out messages are still sent with an empty content, so that the tailers keep
+// track of the offsets of the data they consumed.
+//
+// After Start(), the actor runs until its input channel is closed.
+// After all inputs are processed, the actor closes its output channel.
+type Sampler struct {
+	inputChan  chan *Message
+	outputChan chan *Message
+	rules      []*config.ProcessingRule
+	// counts holds, for each rule keeping one message out of N, the number of messages
+	// it matched since the last one kept
+	counts     []int
+	sourceName string
+}
+
+// NewSampler returns a new Sampler applying the given sampling rules.
+func NewSampler(inputChan chan *Message, outputChan chan *Message, sourceName string, rules []*config.ProcessingRule) *Sampler {
+	return &Sampler{
+		inputChan:  inputChan,
+		outputChan: outputChan,
+		rules:      rules,
+		counts:     make([]int, len(rules)),
+		sourceName: sourceName,
+	}
+}
+
+// samplingRules returns the sampling rules among the processing rules.
+func samplingRules(rules []*config.ProcessingRule) []*config.ProcessingRule {
+	var sampling []*config.ProcessingRule
+	for _, rule := range rules {
+		if rule.Type == config.Sample {
+			sampling = append(sampling, rule)
+		}
+	}
+	return sampling
+}
+
+// Start starts the sampler.
+func (s *Sampler) Start() {
+	go s.run()
+}
+
+// run consumes new messages and forwards the ones which are kept.
+func (s *Sampler) run() {
+	for msg := range s.inputChan {
+		if len(msg.Content) > 0 && !s.keep(msg) {
+			metrics.LogsSampledOut.Add(1)
+			metrics.TlmLogsSampledOut.Inc(s.sourceName)
+			msg.Content = nil
+		}
+		s.outputChan <- msg
+	}
+	close(s.outputChan)
+}
+
+// keep returns true if the message must be kept.
+func (s *Sampler) keep(msg *Message) bool {
+	for i, rule := range s.rules {
+		if rule.Regex != nil && !rule.Regex.Match(msg.Content) {
+			continue
+		}
+		if rule.KeepOneIn > 0 {
+			// the first message matched is kept
+			keep := s.counts[i] == 0
+			s.counts[i] = (s.counts[i] + 1) % rule.KeepOneIn
+			return keep
+		}
+		return rand.Float64()*100 < rule.KeepPercentage
+	}
+	return true
+}
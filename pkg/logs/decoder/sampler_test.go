@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestSamplerKeepsOneMessageOutOfN(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	rules := []*config.ProcessingRule{{Type: config.Sample, KeepOneIn: 3}}
+	s := NewSampler(inputChan, outputChan, "test", rules)
+	s.Start()
+
+	for i := 0; i < 7; i++ {
+		inputChan <- NewMessage([]byte("message"), message.StatusInfo, len("message")+1, "")
+	}
+	close(inputChan)
+
+	var contents []string
+	for output := range outputChan {
+		// sampled out messages keep their raw length for the offsets
+		assert.Equal(t, len("message")+1, output.RawDataLen)
+		contents = append(contents, string(output.Content))
+	}
+	assert.Equal(t, []string{"message", "", "", "message", "", "", "message"}, contents)
+}
+
+func TestSamplerOnlySamplesMatchingMessages(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	rules := []*config.ProcessingRule{{Type: config.Sample, KeepOneIn: 2, Regex: regexp.MustCompile("DEBUG")}}
+	s := NewSampler(inputChan, outputChan, "test", rules)
+	s.Start()
+
+	for _, content := range []string{"DEBUG 1", "INFO 1", "DEBUG 2", "INFO 2", "DEBUG 3"} {
+		inputChan <- NewMessage([]byte(content), message.StatusInfo, len(content)+1, "")
+	}
+	close(inputChan)
+
+	var contents []string
+	for output := range outputChan {
+		contents = append(contents, string(output.Content))
+	}
+	assert.Equal(t, []string{"DEBUG 1", "INFO 1", "", "INFO 2", "DEBUG 3"}, contents)
+}
+
+func TestSamplerKeepsAPercentageOfMessages(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	rules := []*config.ProcessingRule{
+		{Type: config.Sample, KeepPercentage: 100, Regex: regexp.MustCompile("INFO")},
+		{Type: config.Sample, KeepPercentage: 0.000001},
+	}
+	s := NewSampler(inputChan, outputChan, "test", rules)
+	s.Start()
+
+	inputChan <- NewMessage([]byte("INFO"), message.StatusInfo, 5, "")
+	inputChan <- NewMessage([]byte("DEBUG"), message.StatusInfo, 6, "")
+	close(inputChan)
+
+	assert.Equal(t, "INFO", string((<-outputChan).Content))
+	assert.Equal(t, "", string((<-outputChan).Content))
+}
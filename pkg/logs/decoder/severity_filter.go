@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// SeverityFilter implements an actor which drops the messages whose status is less
+// severe than a minimum severity. Messages without a status are considered as info.
+//
+// Dropped messages are still sent with an empty content, so that the tailers keep track
+// of the offsets of the data they consumed.
+//
+// After Start(), the actor runs until its input channel is closed.
+// After all inputs are processed, the actor closes its output channel.
+type SeverityFilter struct {
+	inputChan  chan *Message
+	outputChan chan *Message
+	maxLevel   int
+	sourceName string
+}
+
+// NewSeverityFilter returns a new SeverityFilter dropping the messages less severe than minSeverity.
+func NewSeverityFilter(inputChan chan *Message, outputChan chan *Message, sourceName string, minSeverity string) *SeverityFilter {
+	return &SeverityFilter{
+		inputChan:  inputChan,
+		outputChan: outputChan,
+		maxLevel:   message.StatusLevel(minSeverity),
+		sourceName: sourceName,
+	}
+}
+
+// Start starts the filter.
+func (f *SeverityFilter) Start() {
+	go f.run()
+}
+
+// run consumes new messages and forwards the ones severe enough.
+func (f *SeverityFilter) run() {
+	for msg := range f.inputChan {
+		if len(msg.Content) > 0 && message.StatusLevel(msg.Status) > f.maxLevel {
+			metrics.LogsFilteredBySeverity.Add(1)
+			metrics.TlmLogsFilteredBySeverity.Inc(f.sourceName)
+			msg.Content = nil
+		}
+		f.outputChan <- msg
+	}
+	close(f.outputChan)
+}
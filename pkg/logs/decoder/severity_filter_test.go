@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestSeverityFilterDropsLessSevereMessages(t *testing.T) {
+	inputChan := make(chan *Message, 10)
+	outputChan := make(chan *Message, 10)
+	f := NewSeverityFilter(inputChan, outputChan, "test", message.StatusWarning)
+	f.Start()
+
+	for _, status := range []string{message.StatusDebug, message.StatusInfo, "", message.StatusWarning, message.StatusError} {
+		inputChan <- NewMessage([]byte(status), status, len(status)+1, "")
+	}
+	close(inputChan)
+
+	var contents []string
+	for output := range outputChan {
+		// dropped messages keep their raw length for the offsets
+		assert.Equal(t, len(output.Status)+1, output.RawDataLen)
+		contents = append(contents, string(output.Content))
+	}
+	assert.Equal(t, []string{"", "", "", message.StatusWarning, message.StatusError}, contents)
+}
+
+func TestDecoderWithMinSeverityAndJSONAttributes(t *testing.T) {
+	source := config.NewLogSource("config", &config.LogsConfig{
+		JSONAttributes: &config.JSONAttributes{Status: "level"},
+		MinSeverity:    message.StatusInfo,
+	})
+	d := NewDecoderFromSource(source)
+	d.Start()
+	defer d.Stop()
+
+	// the status is filtered once extracted from the JSON attributes
+	d.InputChan <- NewInput([]byte(`{"level":"debug","msg":"details"}` + "\n"))
+	d.InputChan <- NewInput([]byte(`{"level":"info","msg":"started"}` + "\n"))
+
+	assert.Equal(t, "", string((<-d.OutputChan).Content))
+	assert.Equal(t, `{"level":"info","msg":"started"}`, string((<-d.OutputChan).Content))
+}
@@ -7,6 +7,7 @@ package decoder
 
 import (
 	"bytes"
+	"unicode"
 )
 
 // SingleLineHandler takes care of tracking the line length
@@ -16,6 +17,8 @@ type SingleLineHandler struct {
 	outputChan     chan *Message
 	shouldTruncate bool
 	lineLimit      int
+	// chunks tags the chunks of the lines too long rather than truncating them, when not nil
+	chunks *chunker
 }
 
 // NewSingleLineHandler returns a new SingleLineHandler.
@@ -45,6 +48,11 @@ func (h *SingleLineHandler) run() {
 // the limit and that the length of the line is properly tracked
 // so that the agent restarts tailing from the right place.
 func (h *SingleLineHandler) process(message *Message) {
+	if h.chunks != nil {
+		h.processChunk(message)
+		return
+	}
+
 	isTruncated := h.shouldTruncate
 	h.shouldTruncate = false
 
@@ -68,3 +76,24 @@ func (h *SingleLineHandler) process(message *Message) {
 		h.shouldTruncate = true
 	}
 }
+
+// processChunk sends the lines too long as chunks marked to be reassembled, the lines are
+// broken into lines no longer than the limit, so a line shorter than the limit is either a
+// whole line or the last chunk of a line.
+func (h *SingleLineHandler) processChunk(message *Message) {
+	last := len(message.Content) < h.lineLimit
+	if last && !h.chunks.inProgress() {
+		message.Content = bytes.TrimSpace(message.Content)
+		h.outputChan <- message
+		return
+	}
+	// only the whitespace at the edges of the line is trimmed
+	if !h.chunks.inProgress() {
+		message.Content = bytes.TrimLeftFunc(message.Content, unicode.IsSpace)
+	}
+	if last {
+		message.Content = bytes.TrimRightFunc(message.Content, unicode.IsSpace)
+	}
+	h.chunks.next(message, last)
+	h.outputChan <- message
+}
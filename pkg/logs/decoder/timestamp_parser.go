@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// futureTolerance is how far in the future a timestamp parsed without year can be before it
+// is considered to be from the previous year, to allow for clock skews between hosts.
+const futureTolerance = 24 * time.Hour
+
+// TimestampParser implements an actor which parses the timestamp of the messages from their
+// content, and sets it as their event timestamp, so that the logs collected late, e.g. from
+// backfilled files, keep the time of their events.
+//
+// The messages whose timestamp can not be parsed are forwarded unchanged.
+//
+// After Start(), the actor runs until its input channel is closed.
+// After all inputs are processed, the actor closes its output channel.
+type TimestampParser struct {
+	inputChan  chan *Message
+	outputChan chan *Message
+	// regex matches the timestamp in the content, the timestamp starts the content when nil
+	regex    *regexp.Regexp
+	layouts  []string
+	location *time.Location
+	// now returns the current time, used to complete the timestamps parsed without year
+	now func() time.Time
+}
+
+// NewTimestampParser returns a new TimestampParser, the timestamp parsing is expected to be valid.
+func NewTimestampParser(inputChan chan *Message, outputChan chan *Message, timestamp *config.TimestampParsing) *TimestampParser {
+	p := &TimestampParser{
+		inputChan:  inputChan,
+		outputChan: outputChan,
+		layouts:    timestamp.Layouts,
+		location:   time.UTC,
+		now:        time.Now,
+	}
+	if timestamp.Pattern != "" {
+		p.regex = regexp.MustCompile(timestamp.Pattern)
+	}
+	if location, err := time.LoadLocation(timestamp.Timezone); err == nil {
+		p.location = location
+	}
+	return p
+}
+
+// Start starts the timestamp parser.
+func (p *TimestampParser) Start() {
+	go p.run()
+}
+
+// run consumes new messages and parses their timestamp.
+func (p *TimestampParser) run() {
+	for msg := range p.inputChan {
+		if len(msg.Content) > 0 {
+			if timestamp, ok := p.parse(msg.Content); ok {
+				msg.EventTimestamp = timestamp.UTC()
+			}
+		}
+		p.outputChan <- msg
+	}
+	close(p.outputChan)
+}
+
+// parse returns the timestamp parsed from the content with the first layout matching it.
+func (p *TimestampParser) parse(content []byte) (time.Time, bool) {
+	var value string
+	if p.regex != nil {
+		match := p.regex.FindSubmatch(content)
+		if match == nil {
+			return time.Time{}, false
+		}
+		value = string(match[0])
+		if len(match) > 1 {
+			value = string(match[1])
+		}
+	}
+	for _, layout := range p.layouts {
+		candidate := value
+		if p.regex == nil {
+			// the timestamp spans as many fields at the start of the content as the layout
+			candidate = leadingFields(content, strings.Count(layout, " ")+1)
+		}
+		if timestamp, err := time.ParseInLocation(layout, candidate, p.location); err == nil {
+			if timestamp.Year() == 0 {
+				// the layout has no year
+				timestamp = p.withCurrentYear(timestamp)
+			}
+			return timestamp, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// withCurrentYear returns the timestamp in the current year, or in the previous one when it
+// would be in the future, e.g. for the logs of December read in January.
+func (p *TimestampParser) withCurrentYear(timestamp time.Time) time.Time {
+	now := p.now().In(p.location)
+	withYear := time.Date(now.Year(), timestamp.Month(), timestamp.Day(), timestamp.Hour(), timestamp.Minute(), timestamp.Second(), timestamp.Nanosecond(), p.location)
+	if withYear.After(now.Add(futureTolerance)) {
+		withYear = time.Date(now.Year()-1, timestamp.Month(), timestamp.Day(), timestamp.Hour(), timestamp.Minute(), timestamp.Second(), timestamp.Nanosecond(), p.location)
+	}
+	return withYear
+}
+
+// leadingFields returns the n first space-separated fields of the content, separated by a single space.
+func leadingFields(content []byte, n int) string {
+	fields := make([]string, 0, n)
+	start := -1
+	for i := 0; i <= len(content) && len(fields) < n; i++ {
+		if i < len(content) && content[i] != ' ' && content[i] != '\t' {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			fields = append(fields, string(content[start:i]))
+			start = -1
+		}
+	}
+	return strings.Join(fields, " ")
+}
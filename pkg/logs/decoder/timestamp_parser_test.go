@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestTimestampParserAtStartOfContent(t *testing.T) {
+	p := NewTimestampParser(nil, nil, &config.TimestampParsing{
+		Layouts: []string{"2006-01-02 15:04:05.000", "Jan _2 15:04:05", time.RFC3339},
+	})
+	p.now = func() time.Time { return time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC) }
+
+	timestamp, ok := p.parse([]byte("2021-03-04 05:06:07.890 INFO started"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, time.March, 4, 5, 6, 7, 890000000, time.UTC), timestamp)
+
+	// the padding of the layout is not required, and the current year is used without year
+	timestamp, ok = p.parse([]byte("Mar  4 05:06:07 host app: started"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC), timestamp)
+
+	timestamp, ok = p.parse([]byte("2021-03-04T05:06:07+02:00 started"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, time.March, 4, 3, 6, 7, 0, time.UTC), timestamp.UTC())
+
+	_, ok = p.parse([]byte("started at 2021-03-04 05:06:07.890"))
+	assert.False(t, ok)
+}
+
+func TestTimestampParserWithoutYear(t *testing.T) {
+	p := NewTimestampParser(nil, nil, &config.TimestampParsing{
+		Layouts:  []string{"Jan _2 15:04:05"},
+		Timezone: "Asia/Tokyo",
+	})
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.Nil(t, err)
+	p.now = func() time.Time { return time.Date(2022, time.January, 1, 10, 0, 0, 0, tokyo) }
+
+	timestamp, ok := p.parse([]byte("Jan  1 09:00:00 started"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2022, time.January, 1, 9, 0, 0, 0, tokyo), timestamp)
+
+	// the logs of December read in January are from the previous year
+	timestamp, ok = p.parse([]byte("Dec 31 23:00:00 started"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, time.December, 31, 23, 0, 0, 0, tokyo), timestamp)
+
+	// a timestamp slightly in the future is kept in the current year
+	timestamp, ok = p.parse([]byte("Jan  1 12:00:00 started"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2022, time.January, 1, 12, 0, 0, 0, tokyo), timestamp)
+}
+
+func TestTimestampParserWithPattern(t *testing.T) {
+	p := NewTimestampParser(nil, nil, &config.TimestampParsing{
+		Pattern: `time="([^"]+)"`,
+		Layouts: []string{"02/01/2006 15:04:05"},
+	})
+
+	timestamp, ok := p.parse([]byte(`level=info time="04/03/2021 05:06:07" msg="started"`))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC), timestamp)
+
+	_, ok = p.parse([]byte(`level=info msg="started"`))
+	assert.False(t, ok)
+
+	// the whole match is the timestamp without capture group
+	p = NewTimestampParser(nil, nil, &config.TimestampParsing{
+		Pattern: `\d{2}/\d{2}/\d{4} \d{2}:\d{2}:\d{2}`,
+		Layouts: []string{"02/01/2006 15:04:05"},
+	})
+	timestamp, ok = p.parse([]byte(`[app] 04/03/2021 05:06:07 started`))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC), timestamp)
+}
+
+func TestTimestampParserWithTimezone(t *testing.T) {
+	p := NewTimestampParser(nil, nil, &config.TimestampParsing{
+		Layouts:  []string{"2006-01-02 15:04:05"},
+		Timezone: "Asia/Tokyo",
+	})
+
+	timestamp, ok := p.parse([]byte("2021-03-04 05:06:07 started"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, time.March, 3, 20, 6, 7, 0, time.UTC), timestamp.UTC())
+}
+
+func TestDecoderWithTimestampParsing(t *testing.T) {
+	source := config.NewLogSource("config", &config.LogsConfig{
+		Timestamp: &config.TimestampParsing{Layouts: []string{"2006-01-02 15:04:05"}},
+	})
+	d := NewDecoderFromSource(source)
+	d.Start()
+	defer d.Stop()
+
+	d.InputChan <- NewInput([]byte("2021-03-04 05:06:07 started\n"))
+	d.InputChan <- NewInput([]byte("no timestamp\n"))
+
+	output := <-d.OutputChan
+	assert.Equal(t, "2021-03-04 05:06:07 started", string(output.Content))
+	assert.Equal(t, time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC), output.EventTimestamp)
+
+	output = <-d.OutputChan
+	assert.True(t, output.EventTimestamp.IsZero())
+}
+
+func TestLeadingFields(t *testing.T) {
+	assert.Equal(t, "a", leadingFields([]byte("a b c"), 1))
+	assert.Equal(t, "a b", leadingFields([]byte("  a \t b c"), 2))
+	assert.Equal(t, "a b", leadingFields([]byte("a b"), 3))
+	assert.Equal(t, "", leadingFields([]byte(""), 1))
+}
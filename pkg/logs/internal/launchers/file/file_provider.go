@@ -7,10 +7,12 @@ package file
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	tailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/file"
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -22,50 +24,93 @@ import (
 // files are tailed
 const openFilesLimitWarningType = "open_files_limit_warning"
 
+// Wildcard selection modes, defining which files matching wildcard paths are tailed first
+// when the limit on the number of open files is reached
+const (
+	// WildcardSelectionByName selects the files in reverse lexicographical order, source by source
+	WildcardSelectionByName = "by_name"
+	// WildcardSelectionByModificationTime selects the most recently modified files first
+	WildcardSelectionByModificationTime = "by_modification_time"
+	// WildcardSelectionBySize selects the largest files first
+	WildcardSelectionBySize = "by_size"
+)
+
 // fileProvider implements the logic to retrieve at most filesLimit Files defined in sources
 type fileProvider struct {
 	filesLimit      int
+	selectionMode   string
 	shouldLogErrors bool
+	// recursiveMaxDepth is the maximum number of directories matched by a recursive wildcard
+	recursiveMaxDepth int
 }
 
 // newFileProvider returns a new Provider
-func newFileProvider(filesLimit int) *fileProvider {
+func newFileProvider(filesLimit int, selectionMode string) *fileProvider {
+	switch selectionMode {
+	case WildcardSelectionByName, WildcardSelectionByModificationTime, WildcardSelectionBySize:
+	default:
+		log.Warnf("Unknown wildcard selection mode %s, using %s", selectionMode, WildcardSelectionByName)
+		selectionMode = WildcardSelectionByName
+	}
 	return &fileProvider{
-		filesLimit:      filesLimit,
-		shouldLogErrors: true,
+		filesLimit:        filesLimit,
+		selectionMode:     selectionMode,
+		shouldLogErrors:   true,
+		recursiveMaxDepth: coreConfig.Datadog.GetInt("logs_config.recursive_wildcard_max_depth"),
 	}
 }
 
 // filesToTail returns all the Files matching paths in sources,
 // it cannot return more than filesLimit Files.
-// For now, there is no way to prioritize specific Files over others,
-// they are just returned in reverse lexicographical order, see `searchFiles`
+// With the by_name selection mode, the files are returned source by source, in reverse
+// lexicographical order for wildcard paths, see `searchFiles`. With the other modes,
+// the files of all the sources are ordered by modification time or by size.
+// As filesToTail is called at each scan, the selection follows the changes of the files.
 func (p *fileProvider) filesToTail(sources []*config.LogSource) []*tailer.File {
-	var filesToTail []*tailer.File
+	var candidates []*tailer.File
+	var collectedSources []*config.LogSource
+	matchingFiles := make(map[*config.LogSource]int)
 	shouldLogErrors := p.shouldLogErrors
 	p.shouldLogErrors = false // Let's log errors on first run only
 
 	for i := 0; i < len(sources); i++ {
 		source := sources[i]
-		tailedFileCounter := 0
 		files, err := p.collectFiles(source)
-		isWildcardPath := config.ContainsWildcard(source.Config.Path)
 		if err != nil {
 			source.Status.Error(err)
-			if isWildcardPath {
-				source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", tailedFileCounter, len(files)))
+			if config.ContainsWildcard(source.Config.Path) {
+				source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", 0, len(files)))
 			}
 			if shouldLogErrors {
 				log.Warnf("Could not collect files: %v", err)
 			}
 			continue
 		}
-		for j := 0; j < len(files) && len(filesToTail) < p.filesLimit; j++ {
-			file := files[j]
-			filesToTail = append(filesToTail, file)
-			tailedFileCounter++
+		candidates = append(candidates, files...)
+		collectedSources = append(collectedSources, source)
+		matchingFiles[source] = len(files)
+	}
+
+	if len(candidates) > p.filesLimit {
+		p.sortFiles(candidates)
+	}
+
+	filesToTail := candidates
+	if len(filesToTail) > p.filesLimit {
+		filesToTail = filesToTail[:p.filesLimit]
+	}
+	tailedFiles := make(map[*config.LogSource]int)
+	for _, file := range filesToTail {
+		tailedFiles[file.Source]++
+	}
+
+	for _, source := range collectedSources {
+		if config.ContainsWildcard(source.Config.Path) {
+			source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", tailedFiles[source], matchingFiles[source]))
 		}
+	}
 
+	if len(collectedSources) > 0 {
 		if len(filesToTail) >= p.filesLimit {
 			status.AddGlobalWarning(
 				openFilesLimitWarningType,
@@ -77,20 +122,40 @@ func (p *fileProvider) filesToTail(sources []*config.LogSource) []*tailer.File {
 		} else {
 			status.RemoveGlobalWarning(openFilesLimitWarningType)
 		}
-
-		if isWildcardPath {
-			source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", tailedFileCounter, len(files)))
-		}
 	}
 
 	if len(filesToTail) == p.filesLimit {
 		log.Warn("Reached the limit on the maximum number of files in use: ", p.filesLimit)
-		return filesToTail
 	}
 
 	return filesToTail
 }
 
+// sortFiles orders the files according to the selection mode, the files which
+// can not be stat'ed are moved to the end.
+func (p *fileProvider) sortFiles(files []*tailer.File) {
+	var key func(os.FileInfo) int64
+	switch p.selectionMode {
+	case WildcardSelectionByModificationTime:
+		key = func(info os.FileInfo) int64 { return info.ModTime().UnixNano() }
+	case WildcardSelectionBySize:
+		key = func(info os.FileInfo) int64 { return info.Size() }
+	default:
+		// keep the order of the sources and of the files within each source
+		return
+	}
+	keys := make(map[*tailer.File]int64, len(files))
+	for _, file := range files {
+		keys[file] = math.MinInt64
+		if info, err := os.Stat(file.Path); err == nil {
+			keys[file] = key(info)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return keys[files[i]] > keys[files[j]]
+	})
+}
+
 // collectFiles returns all the files matching the source path.
 func (p *fileProvider) collectFiles(source *config.LogSource) ([]*tailer.File, error) {
 	path := source.Config.Path
@@ -110,7 +175,7 @@ func (p *fileProvider) collectFiles(source *config.LogSource) ([]*tailer.File, e
 
 // searchFiles returns all the files matching the source path pattern.
 func (p *fileProvider) searchFiles(pattern string, source *config.LogSource) ([]*tailer.File, error) {
-	paths, err := filepath.Glob(pattern)
+	paths, err := glob(pattern, p.recursiveMaxDepth)
 	if err != nil {
 		return nil, fmt.Errorf("malformed pattern, could not find any file: %s", pattern)
 	}
@@ -135,26 +200,41 @@ func (p *fileProvider) searchFiles(pattern string, source *config.LogSource) ([]
 		return filepath.Base(paths[i]) > filepath.Base(paths[j])
 	})
 
-	// Resolve excluded path(s)
-	excludedPaths := make(map[string]int)
-	for _, excludePattern := range source.Config.ExcludePaths {
-		excludedGlob, err := filepath.Glob(excludePattern)
-		if err != nil {
-			return nil, fmt.Errorf("malformed exclusion pattern: %s, %s", excludePattern, err)
-		}
-		for _, excludedPath := range excludedGlob {
-			log.Debugf("Adding excluded path: %s", excludedPath)
-			excludedPaths[excludedPath]++
-			if excludedPaths[excludedPath] > 1 {
-				log.Debugf("Overlapping excluded path: %s", excludedPath)
-			}
+	// the sidecar tags files of the matching files are not log files
+	sidecars := make(map[string]bool)
+	for _, path := range paths {
+		if sidecar := tailer.NewFile(path, source, true).SidecarTagsPath(); sidecar != "" {
+			sidecars[sidecar] = true
 		}
 	}
 
 	for _, path := range paths {
-		if excludedPaths[path] == 0 {
+		if sidecars[path] {
+			continue
+		}
+		excluded, err := isExcluded(path, source.Config.ExcludePaths)
+		if err != nil {
+			return nil, err
+		}
+		if !excluded {
 			files = append(files, tailer.NewFile(path, source, true))
 		}
 	}
 	return files, nil
 }
+
+// isExcluded returns true if the path matches one of the exclusion patterns,
+// which may contain recursive wildcards.
+func isExcluded(path string, excludePatterns []string) (bool, error) {
+	for _, excludePattern := range excludePatterns {
+		excluded, err := matchPath(excludePattern, path)
+		if err != nil {
+			return false, fmt.Errorf("malformed exclusion pattern: %s, %s", excludePattern, err)
+		}
+		if excluded {
+			log.Debugf("Excluded path: %s", path)
+			return true, nil
+		}
+	}
+	return false, nil
+}
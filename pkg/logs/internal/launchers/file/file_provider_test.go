@@ -13,9 +13,11 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
 )
@@ -76,7 +78,7 @@ func (suite *ProviderTestSuite) TearDownTest() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFile() {
 	path := fmt.Sprintf("%s/1/1.log", suite.testDir)
-	fileProvider := newFileProvider(suite.filesLimit)
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByName)
 	logSources := suite.newLogSources(path)
 	config.CreateSources(logSources)
 	files := fileProvider.filesToTail(logSources)
@@ -89,7 +91,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFile() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromDirectory() {
 	path := fmt.Sprintf("%s/1/*.log", suite.testDir)
-	fileProvider := newFileProvider(suite.filesLimit)
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByName)
 	logSources := suite.newLogSources(path)
 	status.InitStatus(config.CreateSources(logSources))
 	files := fileProvider.filesToTail(logSources)
@@ -114,7 +116,7 @@ func (suite *ProviderTestSuite) TestCollectFilesWildcardFlag() {
 	// with wildcard
 
 	path := fmt.Sprintf("%s/1/*.log", suite.testDir)
-	fileProvider := newFileProvider(suite.filesLimit)
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByName)
 	logSources := suite.newLogSources(path)
 	files, err := fileProvider.collectFiles(logSources[0])
 	suite.NoError(err, "searching for files in this directory shouldn't fail")
@@ -125,7 +127,7 @@ func (suite *ProviderTestSuite) TestCollectFilesWildcardFlag() {
 	// without wildcard
 
 	path = fmt.Sprintf("%s/1/1.log", suite.testDir)
-	fileProvider = newFileProvider(suite.filesLimit)
+	fileProvider = newFileProvider(suite.filesLimit, WildcardSelectionByName)
 	logSources = suite.newLogSources(path)
 	files, err = fileProvider.collectFiles(logSources[0])
 	suite.NoError(err, "searching for files in this directory shouldn't fail")
@@ -136,7 +138,7 @@ func (suite *ProviderTestSuite) TestCollectFilesWildcardFlag() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromAnyDirectoryWithRightPermissions() {
 	path := fmt.Sprintf("%s/*/*1.log", suite.testDir)
-	fileProvider := newFileProvider(suite.filesLimit)
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByName)
 	logSources := suite.newLogSources(path)
 	config.CreateSources(logSources)
 	files := fileProvider.filesToTail(logSources)
@@ -151,7 +153,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromAnyDirectoryWi
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFileWithWildcard() {
 	path := fmt.Sprintf("%s/1/?.log", suite.testDir)
-	fileProvider := newFileProvider(suite.filesLimit)
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByName)
 	logSources := suite.newLogSources(path)
 	status.InitStatus(config.CreateSources(logSources))
 	files := fileProvider.filesToTail(logSources)
@@ -175,7 +177,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFileWithWildcard()
 func (suite *ProviderTestSuite) TestWildcardPathsAreSorted() {
 	filesLimit := 6
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
-	fileProvider := newFileProvider(filesLimit)
+	fileProvider := newFileProvider(filesLimit, WildcardSelectionByName)
 	logSources := suite.newLogSources(path)
 	files := fileProvider.filesToTail(logSources)
 	suite.Equal(5, len(files))
@@ -191,7 +193,7 @@ func (suite *ProviderTestSuite) TestWildcardPathsAreSorted() {
 
 func (suite *ProviderTestSuite) TestNumberOfFilesToTailDoesNotExceedLimit() {
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
-	fileProvider := newFileProvider(suite.filesLimit)
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByName)
 	logSources := suite.newLogSources(path)
 	status.InitStatus(config.CreateSources(logSources))
 	files := fileProvider.filesToTail(logSources)
@@ -205,9 +207,58 @@ func (suite *ProviderTestSuite) TestNumberOfFilesToTailDoesNotExceedLimit() {
 	)
 }
 
+func (suite *ProviderTestSuite) TestWildcardSelectionByModificationTime() {
+	now := time.Now()
+	for i, name := range []string{"1/3.log", "2/2.log", "1/2.log", "2/1.log", "1/1.log"} {
+		// the files are all the more recent as they come late in the lexicographical order
+		modTime := now.Add(-time.Duration(i) * time.Minute)
+		suite.Nil(os.Chtimes(fmt.Sprintf("%s/%s", suite.testDir, name), modTime, modTime))
+	}
+	modTime := now.Add(time.Minute)
+	suite.Nil(os.Chtimes(fmt.Sprintf("%s/1/1.log", suite.testDir), modTime, modTime))
+
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByModificationTime)
+	logSources := []*config.LogSource{
+		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/1/*.log", suite.testDir)}),
+		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/2/*.log", suite.testDir)}),
+	}
+	status.InitStatus(config.CreateSources(logSources))
+	files := fileProvider.filesToTail(logSources)
+	suite.Equal(3, len(files))
+	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[0].Path)
+	suite.Equal(fmt.Sprintf("%s/1/3.log", suite.testDir), files[1].Path)
+	suite.Equal(fmt.Sprintf("%s/2/2.log", suite.testDir), files[2].Path)
+	suite.Equal([]string{"2 files tailed out of 3 files matching"}, logSources[0].Messages.GetMessages())
+	suite.Equal([]string{"1 files tailed out of 2 files matching"}, logSources[1].Messages.GetMessages())
+
+	// the selection follows the modification of the files
+	modTime = now.Add(2 * time.Minute)
+	suite.Nil(os.Chtimes(fmt.Sprintf("%s/2/1.log", suite.testDir), modTime, modTime))
+	files = fileProvider.filesToTail(logSources)
+	suite.Equal(3, len(files))
+	suite.Equal(fmt.Sprintf("%s/2/1.log", suite.testDir), files[0].Path)
+	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[1].Path)
+	suite.Equal(fmt.Sprintf("%s/1/3.log", suite.testDir), files[2].Path)
+}
+
+func (suite *ProviderTestSuite) TestWildcardSelectionBySize() {
+	for i, name := range []string{"1/1.log", "2/2.log", "1/2.log"} {
+		suite.Nil(ioutil.WriteFile(fmt.Sprintf("%s/%s", suite.testDir, name), make([]byte, 100-i), 0644))
+	}
+
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionBySize)
+	logSources := suite.newLogSources(fmt.Sprintf("%s/*/*.log", suite.testDir))
+	status.InitStatus(config.CreateSources(logSources))
+	files := fileProvider.filesToTail(logSources)
+	suite.Equal(3, len(files))
+	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[0].Path)
+	suite.Equal(fmt.Sprintf("%s/2/2.log", suite.testDir), files[1].Path)
+	suite.Equal(fmt.Sprintf("%s/1/2.log", suite.testDir), files[2].Path)
+}
+
 func (suite *ProviderTestSuite) TestAllWildcardPathsAreUpdated() {
 	filesLimit := 2
-	fileProvider := newFileProvider(filesLimit)
+	fileProvider := newFileProvider(filesLimit, WildcardSelectionByName)
 	logSources := []*config.LogSource{
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/1/*.log", suite.testDir)}),
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/2/*.log", suite.testDir)}),
@@ -258,7 +309,7 @@ func (suite *ProviderTestSuite) TestExcludePath() {
 	filesLimit := 6
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
 	excludePaths := []string{fmt.Sprintf("%s/2/*.log", suite.testDir)}
-	fileProvider := newFileProvider(filesLimit)
+	fileProvider := newFileProvider(filesLimit, WildcardSelectionByName)
 	logSources := []*config.LogSource{
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, ExcludePaths: excludePaths}),
 	}
@@ -273,6 +324,74 @@ func (suite *ProviderTestSuite) TestExcludePath() {
 	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[2].Path)
 }
 
+func (suite *ProviderTestSuite) TestSidecarTagsFilesAreNotTailed() {
+	coreConfig.Datadog.Set("logs_config.sidecar_tags_pattern", "{path}.ddtags")
+	defer coreConfig.Datadog.Set("logs_config.sidecar_tags_pattern", "")
+	_, err := os.Create(fmt.Sprintf("%s/1/1.log.ddtags", suite.testDir))
+	suite.Nil(err)
+	_, err = os.Create(fmt.Sprintf("%s/1/4.ddtags", suite.testDir))
+	suite.Nil(err)
+
+	path := fmt.Sprintf("%s/1/*", suite.testDir)
+	fileProvider := newFileProvider(6, WildcardSelectionByName)
+	files := fileProvider.filesToTail(suite.newLogSources(path))
+
+	// only the sidecar files of the matching files are ignored
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	suite.Equal([]string{
+		fmt.Sprintf("%s/1/4.ddtags", suite.testDir),
+		fmt.Sprintf("%s/1/3.log", suite.testDir),
+		fmt.Sprintf("%s/1/2.log", suite.testDir),
+		fmt.Sprintf("%s/1/1.log", suite.testDir),
+	}, paths)
+}
+
+func (suite *ProviderTestSuite) TestRecursiveWildcardPath() {
+	// Create a nested directory tree:
+	path := fmt.Sprintf("%s/1/nested/deeper", suite.testDir)
+	suite.Nil(os.MkdirAll(path, os.ModePerm))
+	for _, name := range []string{"1/nested/4.log", "1/nested/deeper/5.log", "1/nested/deeper/5.log.gz", "1/nested/deeper/6.log"} {
+		_, err := os.Create(fmt.Sprintf("%s/%s", suite.testDir, name))
+		suite.Nil(err)
+	}
+
+	filesLimit := 10
+	path = fmt.Sprintf("%s/1/**/*.log", suite.testDir)
+	excludePaths := []string{fmt.Sprintf("%s/**/deeper/6.log", suite.testDir)}
+	fileProvider := newFileProvider(filesLimit, WildcardSelectionByName)
+	logSources := []*config.LogSource{
+		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, ExcludePaths: excludePaths}),
+	}
+
+	files := fileProvider.filesToTail(logSources)
+	var paths []string
+	for _, file := range files {
+		suite.True(file.IsWildcardPath)
+		paths = append(paths, file.Path)
+	}
+	suite.Equal([]string{
+		fmt.Sprintf("%s/1/nested/deeper/5.log", suite.testDir),
+		fmt.Sprintf("%s/1/nested/4.log", suite.testDir),
+		fmt.Sprintf("%s/1/3.log", suite.testDir),
+		fmt.Sprintf("%s/1/2.log", suite.testDir),
+		fmt.Sprintf("%s/1/1.log", suite.testDir),
+	}, paths)
+}
+
+func (suite *ProviderTestSuite) TestMalformedExclusionPattern() {
+	path := fmt.Sprintf("%s/**/*.log", suite.testDir)
+	excludePaths := []string{fmt.Sprintf("%s/**/[.log", suite.testDir)}
+	fileProvider := newFileProvider(suite.filesLimit, WildcardSelectionByName)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, ExcludePaths: excludePaths})
+
+	files, err := fileProvider.collectFiles(source)
+	suite.NotNil(err)
+	suite.Nil(files)
+}
+
 func TestProviderTestSuite(t *testing.T) {
 	suite.Run(t, new(ProviderTestSuite))
 }
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// recursiveWildcard is the path element matching any number of directories, e.g. in /var/log/**/*.log
+const recursiveWildcard = "**"
+
+// glob returns the names of all the files matching the pattern, like filepath.Glob,
+// with the support of the recursive wildcard "**" as a whole path element, matching
+// zero or more directories, at most maxDepth directories below the directories matching
+// the part of the pattern before it, when maxDepth is positive. Only regular files, or
+// symlinks to regular files, are returned for recursive patterns, and the pseudo
+// filesystems such as /proc or /sys are not walked.
+func glob(pattern string, maxDepth int) ([]string, error) {
+	elements := splitPath(pattern)
+	recursiveIndex := -1
+	for i, element := range elements {
+		if element == recursiveWildcard {
+			recursiveIndex = i
+			break
+		}
+	}
+	if recursiveIndex == -1 {
+		return filepath.Glob(pattern)
+	}
+
+	// validate the whole pattern as filepath.Glob does
+	if err := validateElements(elements); err != nil {
+		return nil, err
+	}
+
+	// walk the directories matching the part of the pattern before the recursive wildcard
+	var rootPattern string
+	switch {
+	case recursiveIndex == 0:
+		rootPattern = "."
+	case recursiveIndex == 1 && elements[0] == "":
+		rootPattern = string(filepath.Separator)
+	default:
+		rootPattern = strings.Join(elements[:recursiveIndex], string(filepath.Separator))
+	}
+	roots, err := filepath.Glob(rootPattern)
+	if err != nil {
+		return nil, err
+	}
+	subPattern := elements[recursiveIndex:]
+
+	var matches []string
+	for _, root := range roots {
+		_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// skip the directories which can not be read
+				return nil
+			}
+			relativePath, err := filepath.Rel(root, path)
+			if err != nil {
+				return nil
+			}
+			if entry.IsDir() {
+				if path != root && (maxDepth > 0 && len(splitPath(relativePath)) > maxDepth || isPseudoFilesystem(path)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !isRegularFile(path, entry) {
+				return nil
+			}
+			if matchElements(subPattern, splitPath(relativePath)) {
+				matches = append(matches, path)
+			}
+			return nil
+		})
+	}
+	return matches, nil
+}
+
+// isRegularFile returns true if the entry is a regular file, or a symlink to a regular file,
+// such as the container log files in /var/log/containers.
+func isRegularFile(path string, entry fs.DirEntry) bool {
+	if entry.Type().IsRegular() {
+		return true
+	}
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// matchPath returns true if the path matches the pattern, which may contain recursive wildcards.
+func matchPath(pattern string, path string) (bool, error) {
+	elements := splitPath(pattern)
+	if err := validateElements(elements); err != nil {
+		return false, err
+	}
+	return matchElements(elements, splitPath(path)), nil
+}
+
+// validateElements returns filepath.ErrBadPattern if a pattern element is malformed.
+func validateElements(elements []string) error {
+	for _, element := range elements {
+		// filepath.Match checks the whole pattern when it does not match
+		if _, err := filepath.Match(element, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchElements returns true if the path elements match the pattern elements,
+// a recursive wildcard matching zero or more path elements.
+func matchElements(pattern []string, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == recursiveWildcard {
+			for i := 0; i <= len(path); i++ {
+				if matchElements(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// splitPath returns the elements of a path, the first one being empty for an absolute path.
+func splitPath(path string) []string {
+	return strings.Split(filepath.Clean(path), string(filepath.Separator))
+}
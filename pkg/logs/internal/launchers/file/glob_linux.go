@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// pseudoFilesystems are the types of the filesystems without log files, not walked by the
+// recursive wildcards.
+var pseudoFilesystems = map[int64]bool{
+	unix.PROC_SUPER_MAGIC:    true,
+	unix.SYSFS_MAGIC:         true,
+	unix.CGROUP_SUPER_MAGIC:  true,
+	unix.CGROUP2_SUPER_MAGIC: true,
+	unix.DEBUGFS_MAGIC:       true,
+	unix.TRACEFS_MAGIC:       true,
+	unix.SECURITYFS_MAGIC:    true,
+	unix.BPF_FS_MAGIC:        true,
+	unix.DEVPTS_SUPER_MAGIC:  true,
+}
+
+// isPseudoFilesystem returns true if the directory is in a pseudo filesystem such as /proc or /sys.
+func isPseudoFilesystem(path string) bool {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return false
+	}
+	return pseudoFilesystems[int64(stat.Type)]
+}
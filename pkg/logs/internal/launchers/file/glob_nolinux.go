@@ -0,0 +1,14 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !linux
+// +build !linux
+
+package file
+
+// isPseudoFilesystem returns false, the pseudo filesystems are only detected on Linux.
+func isPseudoFilesystem(path string) bool {
+	return false
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/var/log/*.log", "/var/log/app.log", true},
+		{"/var/log/*.log", "/var/log/app/app.log", false},
+		{"/var/log/**/*.log", "/var/log/app.log", true},
+		{"/var/log/**/*.log", "/var/log/app/app.log", true},
+		{"/var/log/**/*.log", "/var/log/app/2022/01/app.log", true},
+		{"/var/log/**/*.log", "/var/log/app/app.log.1", false},
+		{"/var/log/**/archive/*", "/var/log/app/archive/app.log", true},
+		{"/var/log/**/archive/*", "/var/log/archive/app.log", true},
+		{"/var/log/**/archive/*", "/var/log/app/app.log", false},
+		{"/var/log/**", "/var/log/app/app.log", true},
+		{"/**/*.gz", "/var/log/app/app.log.gz", true},
+		{"/var/log/*/**/*.log", "/var/log/app.log", false},
+	}
+	for _, test := range tests {
+		match, err := matchPath(test.pattern, test.path)
+		assert.Nil(t, err)
+		assert.Equal(t, test.match, match, "%s %s", test.pattern, test.path)
+	}
+
+	_, err := matchPath("/var/log/**/[.log", "/var/log/app.log")
+	assert.NotNil(t, err)
+}
+
+func TestGlobRecursive(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-glob-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	// the container log files are symlinks to the pod log files
+	for _, dir := range []string{"pods/app", "containers", "a/b/c/d"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(testDir, dir), 0755))
+	}
+	for _, file := range []string{"pods/app/0.log", "a/1.log", "a/b/c/d/4.log"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(testDir, file), []byte("hello\n"), 0644))
+	}
+	require.NoError(t, os.Symlink(filepath.Join(testDir, "pods/app/0.log"), filepath.Join(testDir, "containers/app.log")))
+	require.NoError(t, os.Symlink(filepath.Join(testDir, "pods/app"), filepath.Join(testDir, "containers/dir.log")))
+
+	matches, err := glob(filepath.Join(testDir, "containers/**/*.log"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(testDir, "containers/app.log")}, matches)
+
+	// the directories deeper than the maximum depth are not walked
+	matches, err = glob(filepath.Join(testDir, "a/**/*.log"), 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(testDir, "a/1.log"), filepath.Join(testDir, "a/b/c/d/4.log")}, matches)
+	matches, err = glob(filepath.Join(testDir, "a/**/*.log"), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(testDir, "a/1.log")}, matches)
+	matches, err = glob(filepath.Join(testDir, "a/**/*.log"), 4)
+	require.NoError(t, err)
+	assert.Len(t, matches, 2)
+}
+
+func TestIsPseudoFilesystem(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the pseudo filesystems are only detected on Linux")
+	}
+	assert.True(t, isPseudoFilesystem("/proc"))
+	assert.False(t, isPseudoFilesystem(os.TempDir()))
+}
@@ -6,9 +6,12 @@
 package file
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	tailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/file"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
 )
@@ -37,16 +41,27 @@ var ContainersLogsDir = "/var/log/containers"
 // DefaultSleepDuration represents the amount of time the tailer waits before reading new data when no data is received
 const DefaultSleepDuration = 1 * time.Second
 
+const (
+	// minReopenBackoff and maxReopenBackoff bound the time waited before trying again to open
+	// a file whose permissions did not allow to open it, e.g. until it is chmod'd or labelled.
+	// The backoff does not exceed the scan period either, after which the file would have been
+	// opened again without backoff.
+	minReopenBackoff = time.Second
+	maxReopenBackoff = time.Minute
+)
+
 // Launcher checks all files provided by fileProvider and create new tailers
 // or update the old ones if needed
 type Launcher struct {
-	pipelineProvider    pipeline.Provider
-	addedSources        chan *config.LogSource
-	removedSources      chan *config.LogSource
-	activeSources       []*config.LogSource
-	tailingLimit        int
-	fileProvider        *fileProvider
-	tailers             map[string]*tailer.Tailer
+	pipelineProvider pipeline.Provider
+	addedSources     chan *config.LogSource
+	removedSources   chan *config.LogSource
+	activeSources    []*config.LogSource
+	tailingLimit     int
+	fileProvider     *fileProvider
+	tailers          map[string]*tailer.Tailer
+	// deletedTailers are the tailers reading their deleted file during its grace period
+	deletedTailers      map[*tailer.Tailer]struct{}
 	registry            auditor.Registry
 	tailerSleepDuration time.Duration
 	stop                chan struct{}
@@ -55,26 +70,88 @@ type Launcher struct {
 	// Feature flag defaulting to false, use `logs_config.validate_pod_container_id`.
 	validatePodContainerID bool
 	scanPeriod             time.Duration
+	// pausedFiles contains the paths of the files whose tailers are paused,
+	// so that the tailers of these files are paused as well after a rotation.
+	pausedFiles   map[string]bool
+	pauseRequests chan pauseRequest
+	// scheduler makes the tailers take turns to read their files, it is nil when
+	// the tailers read their files independently.
+	scheduler *tailer.Scheduler
+	// outputs are the additional consumers of the messages of all the tailers.
+	outputs []tailer.Output
+	// stuckTailerTimeout is the time after which a tailer not making progress is reported as
+	// stuck, the tailers are not watched when it is 0.
+	stuckTailerTimeout  time.Duration
+	restartStuckTailers bool
+	// stuckTailers is the number of tailers found stuck by the last check.
+	stuckTailers int
+	// reopenRetries contains the files which could not be opened because of their permissions,
+	// per scan key, they are tried again with an exponential backoff rather than at each scan.
+	reopenRetries map[string]*reopenRetry
+	// completedFiles contains the identities of the files read to completion in one-shot mode,
+	// per scan key, which are not tailed again until a new file replaces them.
+	completedFiles map[string]string
+}
+
+// reopenRetry tracks the attempts to open a file whose permissions did not allow to open it.
+type reopenRetry struct {
+	file     *tailer.File
+	mode     config.TailingMode
+	attempts int
+	next     time.Time
+}
+
+// pauseRequest asks the launcher to pause or resume the tailers of a file
+type pauseRequest struct {
+	path  string
+	pause bool
+	err   chan error
 }
 
 // NewLauncher returns a new launcher.
 func NewLauncher(sources *config.LogSources, tailingLimit int, pipelineProvider pipeline.Provider, registry auditor.Registry,
-	tailerSleepDuration time.Duration, validatePodContainerID bool, scanPeriod time.Duration) *Launcher {
+	tailerSleepDuration time.Duration, validatePodContainerID bool, scanPeriod time.Duration, wildcardSelectionMode string, readBudget int) *Launcher {
+	var scheduler *tailer.Scheduler
+	if readBudget > 0 {
+		scheduler = tailer.NewScheduler(readBudget)
+	}
 	return &Launcher{
 		pipelineProvider:       pipelineProvider,
 		tailingLimit:           tailingLimit,
 		addedSources:           sources.GetAddedForType(config.FileType),
 		removedSources:         sources.GetRemovedForType(config.FileType),
-		fileProvider:           newFileProvider(tailingLimit),
+		fileProvider:           newFileProvider(tailingLimit, wildcardSelectionMode),
 		tailers:                make(map[string]*tailer.Tailer),
+		deletedTailers:         make(map[*tailer.Tailer]struct{}),
 		registry:               registry,
 		tailerSleepDuration:    tailerSleepDuration,
 		stop:                   make(chan struct{}),
 		validatePodContainerID: validatePodContainerID,
 		scanPeriod:             scanPeriod,
+		pausedFiles:            make(map[string]bool),
+		pauseRequests:          make(chan pauseRequest),
+		scheduler:              scheduler,
+		reopenRetries:          make(map[string]*reopenRetry),
+		completedFiles:         make(map[string]string),
 	}
 }
 
+// AddOutput makes all the tailers send their messages to an additional output, next to
+// their pipeline. It must be called before the launcher is started.
+func (s *Launcher) AddOutput(output tailer.Output) {
+	s.outputs = append(s.outputs, output)
+}
+
+// WatchStuckTailers makes the launcher check, after each scan, whether its tailers have been
+// stuck for longer than the timeout, i.e. have not read their growing files or have been blocked
+// on their outputs, and report them on the status page and in the telemetry.
+// The stuck tailers are replaced by new ones when restart is true.
+// It must be called before the launcher is started.
+func (s *Launcher) WatchStuckTailers(timeout time.Duration, restart bool) {
+	s.stuckTailerTimeout = timeout
+	s.restartStuckTailers = restart
+}
+
 // Start starts the Scanner
 func (s *Launcher) Start() {
 	go s.run()
@@ -91,15 +168,24 @@ func (s *Launcher) Stop() {
 func (s *Launcher) run() {
 	scanTicker := time.NewTicker(s.scanPeriod)
 	defer scanTicker.Stop()
+	reopenTicker := time.NewTicker(minReopenBackoff)
+	defer reopenTicker.Stop()
 	for {
 		select {
 		case source := <-s.addedSources:
 			s.addSource(source)
 		case source := <-s.removedSources:
 			s.removeSource(source)
+		case request := <-s.pauseRequests:
+			request.err <- s.setPaused(request.path, request.pause)
+		case <-reopenTicker.C:
+			s.retryReopens()
 		case <-scanTicker.C:
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
+			if s.stuckTailerTimeout > 0 {
+				s.checkStuckTailers()
+			}
 		case <-s.stop:
 			// no more file should be tailed
 			return
@@ -107,6 +193,50 @@ func (s *Launcher) run() {
 	}
 }
 
+// Pause stops reading the file with the given path until Resume is called, the tailers
+// of the file keep their offsets. It returns an error if the file is not tailed.
+// It must be called while the launcher is running.
+func (s *Launcher) Pause(path string) error {
+	request := pauseRequest{path: path, pause: true, err: make(chan error, 1)}
+	s.pauseRequests <- request
+	return <-request.err
+}
+
+// Resume resumes reading the file with the given path after a call to Pause.
+// It must be called while the launcher is running.
+func (s *Launcher) Resume(path string) error {
+	request := pauseRequest{path: path, pause: false, err: make(chan error, 1)}
+	s.pauseRequests <- request
+	return <-request.err
+}
+
+// setPaused pauses or resumes the tailers of the file with the given path.
+func (s *Launcher) setPaused(path string, pause bool) error {
+	found := false
+	for _, tailer := range s.tailers {
+		if tailer.File.Path != path {
+			continue
+		}
+		found = true
+		if pause {
+			tailer.Pause()
+		} else {
+			tailer.Resume()
+		}
+	}
+	if !found {
+		return fmt.Errorf("no tailer for file %s", path)
+	}
+	if pause {
+		log.Infof("Paused the tailing of %s", path)
+		s.pausedFiles[path] = true
+	} else {
+		log.Infof("Resumed the tailing of %s", path)
+		delete(s.pausedFiles, path)
+	}
+	return nil
+}
+
 // cleanup all tailers
 func (s *Launcher) cleanup() {
 	stopper := restart.NewParallelStopper()
@@ -114,6 +244,11 @@ func (s *Launcher) cleanup() {
 		stopper.Add(tailer)
 		delete(s.tailers, tailer.File.GetScanKey())
 	}
+	// the tailers of the deleted files do not outlive the launcher
+	for tailer := range s.deletedTailers {
+		stopper.Add(tailer)
+		delete(s.deletedTailers, tailer)
+	}
 	stopper.Stop()
 }
 
@@ -122,8 +257,10 @@ func (s *Launcher) cleanup() {
 // For instance, when a file is logrotated, its tailer will keep tailing the rotated file.
 // The Scanner needs to stop that previous tailer, and start a new one for the new file.
 func (s *Launcher) scan() {
+	s.forgetDeletedTailers()
 	files := s.fileProvider.filesToTail(s.activeSources)
 	filesTailed := make(map[string]bool)
+	filesFound := make(map[string]bool)
 	tailersLen := len(s.tailers)
 
 	for _, file := range files {
@@ -137,11 +274,31 @@ func (s *Launcher) scan() {
 		// when a tailer for a dead container is still tailing the file, and another
 		// tailer is tailing the file for the new container).
 		tailerKey := file.GetScanKey()
+		filesFound[tailerKey] = true
 		tailer, isTailed := s.tailers[tailerKey]
 		if isTailed && tailer.IsFinished() {
+			if tailer.IsCompleted() {
+				// the file has been read to completion in one-shot mode
+				s.completedFiles[tailerKey] = fileIdentity(file.Path)
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
+		if _, isRetried := s.reopenRetries[tailerKey]; isRetried && !isTailed {
+			// the file is opened again after its backoff
+			continue
+		}
+		var mode config.TailingMode = config.Beginning
+		if identity, isCompleted := s.completedFiles[tailerKey]; isCompleted && !isTailed {
+			if identity == fileIdentity(file.Path) {
+				continue
+			}
+			// a new file has been created at the same path, e.g. by the next run of a batch job,
+			// the offset of the previous one does not apply to it
+			log.Infof("%s has been replaced since it was read to completion", file.Path)
+			delete(s.completedFiles, tailerKey)
+			mode = config.ForceBeginning
+		}
 		if !isTailed && tailersLen >= s.tailingLimit {
 			// can't create new tailer because tailingLimit is reached
 			continue
@@ -149,7 +306,7 @@ func (s *Launcher) scan() {
 
 		if !isTailed && tailersLen < s.tailingLimit {
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
-			succeeded := s.startNewTailer(file, config.Beginning)
+			succeeded := s.startNewTailer(file, mode)
 			if !succeeded {
 				// the setup failed, let's try to tail this file in the next scan
 				continue
@@ -175,13 +332,76 @@ func (s *Launcher) scan() {
 		filesTailed[tailerKey] = true
 	}
 
+	// forget the files not to tail anymore
+	for key := range s.reopenRetries {
+		if !filesFound[key] {
+			delete(s.reopenRetries, key)
+		}
+	}
+	for key := range s.completedFiles {
+		if !filesFound[key] {
+			delete(s.completedFiles, key)
+		}
+	}
+
 	for _, tailer := range s.tailers {
 		// stop all tailers which have not been selected
 		_, shouldTail := filesTailed[tailer.File.GetScanKey()]
 		if !shouldTail {
-			s.stopTailer(tailer)
+			if _, err := os.Stat(tailer.File.Path); os.IsNotExist(err) {
+				s.stopTailerAfterFileDeletion(tailer)
+			} else {
+				s.stopTailer(tailer)
+			}
+		}
+	}
+}
+
+// checkStuckTailers counts the tailers stuck for longer than the timeout, and restarts them
+// when enabled.
+func (s *Launcher) checkStuckTailers() {
+	s.stuckTailers = 0
+	for _, tailer := range s.tailers {
+		reason := tailer.CheckStuck(s.stuckTailerTimeout)
+		if reason == "" {
+			continue
+		}
+		if !s.restartStuckTailers {
+			log.Warnf("Tailer of %s is stuck: %s", tailer.File.Path, reason)
+			s.stuckTailers++
+			continue
+		}
+		log.Warnf("Restarting the tailer of %s, it is stuck: %s", tailer.File.Path, reason)
+		if !s.restartStuckTailer(tailer) {
+			s.stuckTailers++
 		}
 	}
+	metrics.TlmStuckTailers.Set(float64(s.stuckTailers))
+}
+
+// restartStuckTailer replaces a stuck tailer with a new one, reading its file from the last
+// offset acknowledged by the intake so that the messages held by the stuck tailer are sent again.
+// It returns true if the new tailer is up and running.
+func (s *Launcher) restartStuckTailer(stuck *tailer.Tailer) bool {
+	offset := stuck.GetDecodedOffset()
+	if acknowledged, err := strconv.ParseInt(s.registry.GetOffset(stuck.Identifier()), 10, 64); err == nil && acknowledged < offset {
+		offset = acknowledged
+	}
+	stuck.StopNow()
+	delete(s.tailers, stuck.File.GetScanKey())
+	metrics.TlmStuckTailersRestarted.Inc(stuck.File.Source.Name)
+
+	tailer := s.createTailer(stuck.File, s.pipelineProvider.NextPipelineChan())
+	if s.pausedFiles[stuck.File.Path] {
+		tailer.Pause()
+	}
+	if err := tailer.Start(offset, io.SeekStart); err != nil {
+		// the next scan starts a new tailer for the file
+		log.Warn(err)
+		return false
+	}
+	s.tailers[tailer.File.GetScanKey()] = tailer
+	return true
 }
 
 // addSource keeps track of the new source and launch new tailers for this source.
@@ -249,9 +469,7 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 	// See these links for more info:
 	//   - https://github.com/kubernetes/kubernetes/issues/58638
 	//   - https://github.com/fabric8io/fluent-plugin-kubernetes_metadata_filter/issues/105
-	if s.validatePodContainerID && file.Source != nil &&
-		(file.Source.GetSourceType() == config.KubernetesSourceType || file.Source.GetSourceType() == config.DockerSourceType) &&
-		s.shouldIgnore(file) {
+	if s.isIgnored(file) {
 		return false
 	}
 
@@ -259,24 +477,147 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 
 	var offset int64
 	var whence int
-	mode := s.handleTailingModeChange(tailer.Identifier(), m)
+	identifier := s.registryIdentifier(tailer)
+	if file.Source.Config.OneShot && m != config.ForceBeginning && s.isCompletedInRegistry(file.Path) {
+		log.Debugf("Not tailing %s, it has been read to completion", file.Path)
+		s.completedFiles[file.GetScanKey()] = fileIdentity(file.Path)
+		return false
+	}
+	mode := s.handleTailingModeChange(identifier, m)
 
-	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
+	offset, whence, err := Position(s.registry, identifier, mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
 
+	if s.pausedFiles[file.Path] {
+		tailer.Pause()
+	}
+
 	log.Infof("Starting a new tailer for: %s (offset: %d, whence: %d) for tailer key %s", file.Path, offset, whence, file.GetScanKey())
 	err = tailer.Start(offset, whence)
 	if err != nil {
-		log.Warn(err)
+		if os.IsPermission(err) {
+			// the permissions of a new file may be fixed shortly after its creation
+			s.scheduleReopen(file, m, err)
+		} else {
+			delete(s.reopenRetries, file.GetScanKey())
+			log.Warn(err)
+		}
 		return false
 	}
 
+	delete(s.reopenRetries, file.GetScanKey())
 	s.tailers[tailer.File.GetScanKey()] = tailer
 	return true
 }
 
+// scheduleReopen makes the launcher try again to open a file which could not be opened because
+// of its permissions, after a backoff doubling at each attempt.
+func (s *Launcher) scheduleReopen(file *tailer.File, m config.TailingMode, err error) {
+	retry, exists := s.reopenRetries[file.GetScanKey()]
+	if !exists {
+		retry = &reopenRetry{file: file, mode: m}
+		s.reopenRetries[file.GetScanKey()] = retry
+	}
+	maxBackoff := maxReopenBackoff
+	if s.scanPeriod < maxBackoff {
+		maxBackoff = s.scanPeriod
+	}
+	backoff := minReopenBackoff
+	for i := 0; i < retry.attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	retry.attempts++
+	retry.next = time.Now().Add(backoff)
+	log.Infof("Could not open %s, retrying in %v: %v", file.Path, backoff, err)
+	file.Source.Status.Retrying(err, backoff)
+}
+
+// retryReopens tries again to open the files whose backoff has expired.
+func (s *Launcher) retryReopens() {
+	now := time.Now()
+	for key, retry := range s.reopenRetries {
+		if _, isTailed := s.tailers[key]; isTailed {
+			delete(s.reopenRetries, key)
+			continue
+		}
+		if now.Before(retry.next) || len(s.tailers) >= s.tailingLimit {
+			continue
+		}
+		if s.isIgnored(retry.file) {
+			// the file belongs to another container, it is not opened again
+			delete(s.reopenRetries, key)
+			continue
+		}
+		s.startNewTailer(retry.file, retry.mode)
+	}
+}
+
+// isIgnored returns true if the file is a container log file which does not belong to the container
+// of its source, when `logs_config.validate_pod_container_id` is enabled.
+func (s *Launcher) isIgnored(file *tailer.File) bool {
+	return s.validatePodContainerID && file.Source != nil &&
+		(file.Source.GetSourceType() == config.KubernetesSourceType || file.Source.GetSourceType() == config.DockerSourceType) &&
+		s.shouldIgnore(file)
+}
+
+// isCompletedInRegistry returns true if the registry records that the file at the path has been
+// read to completion in one-shot mode. The completion is recorded against the inode of the file,
+// a new file reusing the inode is read as long as its size or its beginning differ.
+func (s *Launcher) isCompletedInRegistry(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	inodeIdentifier := auditor.InodeIdentifier(info)
+	if inodeIdentifier == "" || !s.registry.IsCompleted(inodeIdentifier) {
+		return false
+	}
+	if s.registry.GetOffset(inodeIdentifier) != strconv.FormatInt(info.Size(), 10) {
+		return false
+	}
+	fingerprint := s.registry.GetFingerprint(inodeIdentifier)
+	return fingerprint == "" || fingerprint == auditor.Fingerprint(path, inodeIdentifier)
+}
+
+// fileIdentity returns what identifies the file at the path, to detect that it has been replaced
+// by a new file, which may reuse its inode.
+func fileIdentity(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", auditor.InodeIdentifier(info), info.Size(), info.ModTime().UnixNano())
+}
+
+// registryIdentifier returns the identifier of the registry entry of the file of the tailer,
+// preferring the one built from its device and inode numbers, so that its offset is kept when
+// it is accessed from another path, over the one built from its path.
+func (s *Launcher) registryIdentifier(tailer *tailer.Tailer) string {
+	info, err := os.Stat(tailer.File.Path)
+	if err != nil {
+		return tailer.Identifier()
+	}
+	inodeIdentifier := auditor.InodeIdentifier(info)
+	if inodeIdentifier == "" {
+		return tailer.Identifier()
+	}
+	offset, err := strconv.ParseInt(s.registry.GetOffset(inodeIdentifier), 10, 64)
+	if err != nil || offset > info.Size() {
+		// the inode may have been reused by a new file since the offset was registered
+		return tailer.Identifier()
+	}
+	if fingerprint := s.registry.GetFingerprint(inodeIdentifier); fingerprint != "" && fingerprint != auditor.Fingerprint(tailer.File.Path, inodeIdentifier) {
+		// the inode has been reused by a new file whose beginning differs
+		return tailer.Identifier()
+	}
+	return inodeIdentifier
+}
+
 // shouldIgnore resolves symlinks in /var/log/containers in order to use that redirection
 // to validate that we will be reading a file for the correct container.
 func (s *Launcher) shouldIgnore(file *tailer.File) bool {
@@ -368,12 +709,32 @@ func (s *Launcher) stopTailer(tailer *tailer.Tailer) {
 	delete(s.tailers, tailer.File.GetScanKey())
 }
 
+// stopTailerAfterFileDeletion lets the tailer finish reading its deleted file before stopping it
+func (s *Launcher) stopTailerAfterFileDeletion(tailer *tailer.Tailer) {
+	tailer.StopAfterFileDeletion()
+	delete(s.tailers, tailer.File.GetScanKey())
+	s.deletedTailers[tailer] = struct{}{}
+}
+
+// forgetDeletedTailers forgets the tailers of the deleted files stopped at the end of their
+// grace period.
+func (s *Launcher) forgetDeletedTailers() {
+	for tailer := range s.deletedTailers {
+		if tailer.IsFinished() {
+			delete(s.deletedTailers, tailer)
+		}
+	}
+}
+
 // restartTailer safely stops tailer and starts a new one
 // returns true if the new tailer is up and running, false if an error occurred
 func (s *Launcher) restartTailerAfterFileRotation(tailer *tailer.Tailer, file *tailer.File) bool {
 	log.Info("Log rotation happened to ", file.Path)
 	tailer.StopAfterFileRotation()
 	tailer = s.createRotatedTailer(file, tailer.OutputChan, tailer.GetDetectedPattern())
+	if s.pausedFiles[file.Path] {
+		tailer.Pause()
+	}
 	// force reading file from beginning since it has been log-rotated
 	err := tailer.StartFromBeginning()
 	if err != nil {
@@ -386,9 +747,41 @@ func (s *Launcher) restartTailerAfterFileRotation(tailer *tailer.Tailer, file *t
 
 // createTailer returns a new initialized tailer
 func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Message) *tailer.Tailer {
-	return tailer.NewTailer(outputChan, file, s.tailerSleepDuration, decoder.NewDecoderFromSource(file.Source))
+	// keep using the multiline pattern detected for the file before a restart
+	pattern := s.recoverMultiLinePattern(file)
+	return s.newTailer(file, outputChan, pattern)
+}
+
+// recoverMultiLinePattern returns the multiline pattern detected for the file before
+// a restart of the agent, if any.
+func (s *Launcher) recoverMultiLinePattern(file *tailer.File) *regexp.Regexp {
+	pattern := s.registry.GetMultiLinePattern(fmt.Sprintf("file:%s", file.Path))
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Warnf("Could not recover the multiline pattern detected for file with path %v: %v", file.Path, err)
+		return nil
+	}
+	return re
 }
 
 func (s *Launcher) createRotatedTailer(file *tailer.File, outputChan chan *message.Message, pattern *regexp.Regexp) *tailer.Tailer {
-	return tailer.NewTailer(outputChan, file, s.tailerSleepDuration, decoder.NewDecoderFromSourceWithPattern(file.Source, pattern))
+	return s.newTailer(file, outputChan, pattern)
+}
+
+// newTailer returns a new tailer decoding the file with its encoding, which is detected once
+// data is written to the file when it is still empty.
+func (s *Launcher) newTailer(file *tailer.File, outputChan chan *message.Message, pattern *regexp.Regexp) *tailer.Tailer {
+	t := tailer.NewTailer(outputChan, file, s.tailerSleepDuration, decoder.NewDecoderFromSourceWithEncoding(file.Source, file.Encoding(), pattern))
+	t.SetDecoderFactory(func(encoding string) *decoder.Decoder {
+		return decoder.NewDecoderFromSourceWithEncoding(file.Source, encoding, pattern)
+	})
+	t.SetRegistry(s.registry)
+	t.SetScheduler(s.scheduler)
+	for _, output := range s.outputs {
+		t.AddOutput(output)
+	}
+	return t
 }
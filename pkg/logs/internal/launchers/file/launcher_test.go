@@ -9,15 +9,19 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	logsAuditor "github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	filetailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/file"
@@ -64,7 +68,7 @@ func (suite *LauncherTestSuite) SetupTest() {
 	suite.openFilesLimit = 100
 	suite.source = config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Identifier: suite.configID, Path: suite.testPath})
 	sleepDuration := 20 * time.Millisecond
-	suite.s = NewLauncher(config.NewLogSources(), suite.openFilesLimit, suite.pipelineProvider, auditor.NewRegistry(), sleepDuration, false, 10*time.Second)
+	suite.s = NewLauncher(config.NewLogSources(), suite.openFilesLimit, suite.pipelineProvider, auditor.NewRegistry(), sleepDuration, false, 10*time.Second, WildcardSelectionByName, 0)
 	suite.s.activeSources = append(suite.s.activeSources, suite.source)
 	status.InitStatus(config.CreateSources([]*config.LogSource{suite.source}))
 	suite.s.scan()
@@ -162,6 +166,7 @@ func (suite *LauncherTestSuite) TestLauncherScanWithLogRotationCopyTruncate() {
 
 	msg = <-suite.outputChan
 	suite.Equal("third", string(msg.Content))
+	suite.Equal("6", msg.Origin.Offset)
 }
 
 func (suite *LauncherTestSuite) TestLauncherScanWithFileRemovedAndCreated() {
@@ -183,6 +188,73 @@ func (suite *LauncherTestSuite) TestLauncherScanWithFileRemovedAndCreated() {
 	suite.Equal(tailerLen, len(s.tailers))
 }
 
+func (suite *LauncherTestSuite) TestLauncherScanWithFileDeleted() {
+	s := suite.s
+	tailerLen := len(s.tailers)
+
+	coreConfig.Datadog.Set("logs_config.deleted_file_grace_period", 1)
+	defer coreConfig.Datadog.Set("logs_config.deleted_file_grace_period", 0)
+	s.cleanup()
+	s.scan()
+	tailer := s.tailers[getScanKey(suite.testPath, suite.source)]
+
+	err := os.Remove(suite.testPath)
+	suite.Nil(err)
+	s.scan()
+	suite.Equal(tailerLen-1, len(s.tailers))
+
+	// the deleted file is still read while it is open
+	_, err = suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+
+	// the tailer stops after the grace period
+	suite.Eventually(tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+}
+
+func (suite *LauncherTestSuite) TestLauncherStopsTheTailersOfDeletedFiles() {
+	s := suite.s
+
+	coreConfig.Datadog.Set("logs_config.deleted_file_grace_period", 3600)
+	defer coreConfig.Datadog.Set("logs_config.deleted_file_grace_period", 0)
+	s.cleanup()
+	s.scan()
+	tailer := s.tailers[getScanKey(suite.testPath, suite.source)]
+
+	suite.Nil(os.Remove(suite.testPath))
+	s.scan()
+	suite.Contains(s.deletedTailers, tailer)
+	suite.Empty(suite.source.GetInputs())
+
+	// the tailer does not wait for the end of its grace period when the launcher stops
+	s.cleanup()
+	suite.True(tailer.IsFinished())
+	suite.Empty(s.deletedTailers)
+}
+
+func (suite *LauncherTestSuite) TestLauncherPauseAndResume() {
+	s := suite.s
+	s.Start()
+	defer s.Stop()
+
+	suite.NotNil(s.Pause(suite.testRotatedPath))
+	suite.Nil(s.Pause(suite.testPath))
+	suite.True(s.tailers[getScanKey(suite.testPath, suite.source)].IsPaused())
+
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	select {
+	case <-suite.outputChan:
+		suite.Fail("a paused file should not be tailed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	suite.Nil(s.Resume(suite.testPath))
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+}
+
 func (suite *LauncherTestSuite) TestLifeCycle() {
 	s := suite.s
 	suite.Equal(1, len(s.tailers))
@@ -220,7 +292,7 @@ func TestLauncherScanStartNewTailer(t *testing.T) {
 		openFilesLimit := 2
 		sleepDuration := 20 * time.Millisecond
 		registry := auditor.NewRegistry()
-		launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration, false, 10*time.Second)
+		launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration, false, 10*time.Second, WildcardSelectionByName, 0)
 		source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Identifier: configID, Path: path})
 		launcher.activeSources = append(launcher.activeSources, source)
 		status.Clear()
@@ -258,7 +330,7 @@ func TestLauncherWithConcurrentContainerTailer(t *testing.T) {
 	openFilesLimit := 3
 	sleepDuration := 20 * time.Millisecond
 	registry := auditor.NewRegistry()
-	launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration, false, 10*time.Second)
+	launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration, false, 10*time.Second, WildcardSelectionByName, 0)
 	firstSource := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.log", testDir), TailingMode: "beginning", Identifier: "123456789"})
 	secondSource := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.log", testDir), TailingMode: "beginning", Identifier: "987654321"})
 
@@ -305,7 +377,7 @@ func TestLauncherTailFromTheBeginning(t *testing.T) {
 	openFilesLimit := 3
 	sleepDuration := 20 * time.Millisecond
 	registry := auditor.NewRegistry()
-	launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration, false, 10*time.Second)
+	launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration, false, 10*time.Second, WildcardSelectionByName, 0)
 	sources := []*config.LogSource{
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/test.log", testDir), TailingMode: "beginning"}),
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/container.log", testDir), TailingMode: "beginning", Identifier: "123456789"}),
@@ -346,6 +418,244 @@ func TestLauncherTailFromTheBeginning(t *testing.T) {
 	}
 }
 
+func TestLauncherRecoversMultiLinePattern(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	registry := auditor.NewRegistry()
+	registry.SetMultiLinePattern(`^\d{4}-\d{2}-\d{2}`)
+	launcher := NewLauncher(config.NewLogSources(), 3, mock.NewMockProvider(), registry, 20*time.Millisecond, false, 10*time.Second, WildcardSelectionByName, 0)
+
+	autoMultiLine := true
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, AutoMultiLine: &autoMultiLine})
+	tailer := launcher.createTailer(filetailer.NewFile(path, source, false), make(chan *message.Message))
+	assert.Equal(t, `^\d{4}-\d{2}-\d{2}`, tailer.GetDetectedPattern().String())
+
+	// an invalid pattern is ignored
+	registry.SetMultiLinePattern(`(`)
+	tailer = launcher.createTailer(filetailer.NewFile(path, source, false), make(chan *message.Message))
+	assert.Nil(t, tailer.GetDetectedPattern())
+}
+
+func TestLauncherPrefersInodeIdentifier(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	registry := auditor.NewRegistry()
+	launcher := NewLauncher(config.NewLogSources(), 3, mock.NewMockProvider(), registry, 20*time.Millisecond, false, 10*time.Second, WildcardSelectionByName, 0)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	tailer := launcher.createTailer(filetailer.NewFile(path, source, false), make(chan *message.Message))
+
+	// no offset is registered for the inode
+	assert.Equal(t, "file:"+path, launcher.registryIdentifier(tailer))
+
+	registry.SetOffset("6")
+	assert.Equal(t, logsAuditor.InodeIdentifier(info), launcher.registryIdentifier(tailer))
+
+	// the offset is beyond the end of the file, the inode was reused by another file
+	registry.SetOffset("42")
+	assert.Equal(t, "file:"+path, launcher.registryIdentifier(tailer))
+}
+
+func TestLauncherComparesInodeFingerprint(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, ioutil.WriteFile(path, bytes.Repeat([]byte("hello\n"), 1000), 0644))
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	inodeIdentifier := logsAuditor.InodeIdentifier(info)
+
+	registry := auditor.NewRegistry()
+	registry.SetOffset("6")
+	launcher := NewLauncher(config.NewLogSources(), 3, mock.NewMockProvider(), registry, 20*time.Millisecond, false, 10*time.Second, WildcardSelectionByName, 0)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	tailer := launcher.createTailer(filetailer.NewFile(path, source, false), make(chan *message.Message))
+
+	registry.SetFingerprint(logsAuditor.Fingerprint(path, inodeIdentifier))
+	assert.Equal(t, inodeIdentifier, launcher.registryIdentifier(tailer))
+
+	// the offset is within the file, but its beginning differs: the inode was reused by another file
+	registry.SetFingerprint("crc64:0000000000000000")
+	assert.Equal(t, "file:"+path, launcher.registryIdentifier(tailer))
+}
+
+func TestLauncherWatchesStuckTailers(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("hello\nworld\n"), 0644))
+
+	// the output of the mock provider is never read, its tailers are blocked on it
+	provider := mock.NewMockProvider()
+	registry := auditor.NewRegistry()
+	launcher := NewLauncher(config.NewLogSources(), 3, provider, registry, 20*time.Millisecond, false, 10*time.Second, WildcardSelectionByName, 0)
+	launcher.WatchStuckTailers(10*time.Millisecond, false)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	assert.True(t, launcher.startNewTailer(filetailer.NewFile(path, source, false), config.Beginning))
+	stuck := launcher.tailers[path]
+
+	assert.Eventually(t, func() bool {
+		launcher.checkStuckTailers()
+		return launcher.stuckTailers == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Same(t, stuck, launcher.tailers[path])
+
+	// the stuck tailer is replaced by a new one reading from the acknowledged offset
+	registry.SetOffset("6")
+	launcher.restartStuckTailers = true
+	launcher.checkStuckTailers()
+	assert.Equal(t, 0, launcher.stuckTailers)
+	assert.NotSame(t, stuck, launcher.tailers[path])
+	// the stuck tailer drops its pending message
+	assert.Eventually(t, stuck.IsFinished, 5*time.Second, 10*time.Millisecond)
+	msg := <-provider.NextPipelineChan()
+	assert.Equal(t, "world", string(msg.Content))
+
+	launcher.cleanup()
+}
+
+func TestLauncherRetriesReopenWithBackoff(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+
+	launcher := NewLauncher(config.NewLogSources(), 3, mock.NewMockProvider(), auditor.NewRegistry(), 20*time.Millisecond, false, 10*time.Second, WildcardSelectionByName, 0)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	file := filetailer.NewFile(path, source, false)
+
+	// the backoff doubles at each attempt, up to the scan period
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		launcher.scheduleReopen(file, config.End, os.ErrPermission)
+		assert.WithinDuration(t, time.Now().Add(backoff), launcher.reopenRetries[path].next, 500*time.Millisecond)
+		assert.True(t, source.Status.IsRetrying())
+		assert.Equal(t, fmt.Sprintf("Retrying in %v: permission denied", backoff), source.Status.GetError())
+	}
+	launcher.reopenRetries[path].attempts = 10
+	launcher.scheduleReopen(file, config.End, os.ErrPermission)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), launcher.reopenRetries[path].next, 500*time.Millisecond)
+
+	// the file is not opened before the end of its backoff, even by a scan
+	launcher.activeSources = []*config.LogSource{source}
+	launcher.scan()
+	launcher.retryReopens()
+	assert.Len(t, launcher.tailers, 0)
+
+	launcher.reopenRetries[path].next = time.Now()
+	launcher.retryReopens()
+	assert.Len(t, launcher.tailers, 1)
+	assert.Len(t, launcher.reopenRetries, 0)
+	assert.True(t, source.Status.IsSuccess())
+
+	// the retries of the files not to tail anymore are forgotten
+	launcher.cleanup()
+	launcher.scheduleReopen(file, config.End, os.ErrPermission)
+	launcher.activeSources = nil
+	launcher.scan()
+	assert.Len(t, launcher.reopenRetries, 0)
+}
+
+func TestLauncherDoesNotReopenIgnoredFiles(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+	containersLogsDir := ContainersLogsDir
+	ContainersLogsDir = filepath.Join(testDir, "containers")
+	defer func() { ContainersLogsDir = containersLogsDir }()
+
+	path := filepath.Join(testDir, "file-uuid-foo-bar.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+	assert.Nil(t, os.Mkdir(ContainersLogsDir, 0755))
+	// the file belongs to another container than the one of the source
+	assert.Nil(t, os.Symlink(path, filepath.Join(ContainersLogsDir, "myapp_my-namespace_myapp-1234123412341234123412341234123412341234123412341234123412341234.log")))
+
+	launcher := NewLauncher(config.NewLogSources(), 3, mock.NewMockProvider(), auditor.NewRegistry(), 20*time.Millisecond, true, 10*time.Second, WildcardSelectionByName, 0)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, Identifier: "abcdefabcdefabcdabcdefabcdefabcdabcdefabcdefabcdabcdefabcdefabcd"})
+	source.SetSourceType(config.DockerSourceType)
+	file := filetailer.NewFile(path, source, false)
+	launcher.scheduleReopen(file, config.End, os.ErrPermission)
+
+	launcher.reopenRetries[file.GetScanKey()].next = time.Now()
+	launcher.retryReopens()
+	assert.Len(t, launcher.tailers, 0)
+	assert.Len(t, launcher.reopenRetries, 0)
+}
+
+func TestLauncherDoesNotTailCompletedFiles(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+
+	registry := auditor.NewRegistry()
+	launcher := NewLauncher(config.NewLogSources(), 3, mock.NewMockProvider(), registry, 20*time.Millisecond, false, 10*time.Second, WildcardSelectionByName, 0)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, OneShot: true})
+	launcher.activeSources = []*config.LogSource{source}
+
+	// the file has been read to completion before a restart
+	registry.SetCompleted(true)
+	registry.SetOffset("6")
+	launcher.scan()
+	assert.Len(t, launcher.tailers, 0)
+	assert.Equal(t, fileIdentity(path), launcher.completedFiles[path])
+
+	// the completed files are forgotten once they are not to tail anymore
+	launcher.activeSources = nil
+	launcher.scan()
+	assert.Len(t, launcher.completedFiles, 0)
+}
+
+func TestLauncherTailsRecreatedCompletedFiles(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-launcher-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+
+	registry := auditor.NewRegistry()
+	provider := mock.NewMockProvider()
+	launcher := NewLauncher(config.NewLogSources(), 3, provider, registry, 20*time.Millisecond, false, 10*time.Second, WildcardSelectionByName, 0)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, OneShot: true})
+	launcher.activeSources = []*config.LogSource{source}
+
+	registry.SetCompleted(true)
+	registry.SetOffset("6")
+	launcher.scan()
+	assert.Len(t, launcher.tailers, 0)
+	assert.Contains(t, launcher.completedFiles, path)
+
+	// the next run of the batch job deletes the file and creates it again at the same path,
+	// the registry still records the completion of the previous file
+	assert.Nil(t, os.Remove(path))
+	assert.Nil(t, ioutil.WriteFile(path, []byte("hello again\n"), 0644))
+	launcher.scan()
+	assert.Len(t, launcher.tailers, 1)
+	assert.NotContains(t, launcher.completedFiles, path)
+
+	// the new file is read from its beginning, regardless of the offset of the previous one
+	msg := <-provider.NextPipelineChan()
+	assert.Equal(t, "hello again", string(msg.Content))
+	launcher.tailers[path].Stop()
+}
+
 func TestLauncherScanWithTooManyFiles(t *testing.T) {
 	var err error
 	var path string
@@ -370,7 +680,7 @@ func TestLauncherScanWithTooManyFiles(t *testing.T) {
 	path = fmt.Sprintf("%s/*.log", testDir)
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration, false, 10*time.Second)
+	launcher := NewLauncher(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration, false, 10*time.Second, WildcardSelectionByName, 0)
 	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
 	launcher.activeSources = append(launcher.activeSources, source)
 	status.Clear()
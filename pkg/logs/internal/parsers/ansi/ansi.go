@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package ansi implements a parser that strips ANSI escape sequences (colors,
+// cursor movements, terminal titles, ...) from the content returned by another
+// parser.
+package ansi
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+)
+
+// escapeSequence matches CSI sequences (e.g. "\x1b[1;31m"), OSC sequences terminated
+// by BEL or ST (e.g. "\x1b]0;title\x07") and the remaining two-bytes escape sequences.
+var escapeSequence = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// NewStripper wraps the given parser to remove ANSI escape sequences from the
+// content of the messages it returns.
+func NewStripper(parser parsers.Parser) parsers.Parser {
+	return &stripper{parser: parser}
+}
+
+type stripper struct {
+	parser parsers.Parser
+}
+
+// Parse implements Parser#Parse
+func (p *stripper) Parse(msg []byte) (parsers.Message, error) {
+	parsed, err := p.parser.Parse(msg)
+	parsed.Content = Strip(parsed.Content)
+	return parsed, err
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *stripper) SupportsPartialLine() bool {
+	return p.parser.SupportsPartialLine()
+}
+
+// Strip returns the content without its ANSI escape sequences.  The content is
+// returned unchanged when it does not contain any escape character.
+func Strip(content []byte) []byte {
+	if bytes.IndexByte(content, 0x1b) == -1 {
+		return content
+	}
+	return escapeSequence.ReplaceAll(content, nil)
+}
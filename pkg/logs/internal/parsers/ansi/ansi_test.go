@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package ansi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+)
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"hello world", "hello world"},
+		{"\x1b[31mERROR\x1b[0m something failed", "ERROR something failed"},
+		{"\x1b[1;32mINFO\x1b[m ready", "INFO ready"},
+		{"\x1b[2K\x1b[1Gprogress 50%", "progress 50%"},
+		{"\x1b]0;my title\x07hello", "hello"},
+		{"\x1b]8;;http://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"\x1bMreverse line feed", "reverse line feed"},
+		{"日本\x1b[33m語\x1b[0m", "日本語"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, string(Strip([]byte(test.input))))
+	}
+}
+
+func TestStripperHandleMessages(t *testing.T) {
+	parser := NewStripper(noop.New())
+	msg, err := parser.Parse([]byte("\x1b[31mERROR\x1b[0m something failed"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR something failed", string(msg.Content))
+	assert.False(t, parser.SupportsPartialLine())
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
 	"golang.org/x/text/transform"
 )
 
@@ -24,6 +26,14 @@ const (
 	UTF16BE
 	// SHIFTJIS Shift JIS (Japanese)
 	SHIFTJIS
+	// EUCJP EUC-JP (Japanese)
+	EUCJP
+	// GB18030 GB18030 (Chinese)
+	GB18030
+	// UTF32LE UTF32 little endian
+	UTF32LE
+	// UTF32BE UTF32 big endian
+	UTF32BE
 )
 
 type encodedText struct {
@@ -54,6 +64,14 @@ func New(e Encoding) parsers.Parser {
 		enc = unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
 	case SHIFTJIS:
 		enc = japanese.ShiftJIS
+	case EUCJP:
+		enc = japanese.EUCJP
+	case GB18030:
+		enc = simplifiedchinese.GB18030
+	case UTF32LE:
+		enc = utf32.UTF32(utf32.LittleEndian, utf32.UseBOM)
+	case UTF32BE:
+		enc = utf32.UTF32(utf32.BigEndian, utf32.UseBOM)
 	}
 	p.decoder = enc.NewDecoder()
 	return p
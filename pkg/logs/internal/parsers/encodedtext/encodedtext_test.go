@@ -58,3 +58,53 @@ func TestSHIFTJISParserHandleMessages(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "日本", string(msg.Content))
 }
+
+func TestEUCJPParserHandleMessages(t *testing.T) {
+	parser := New(EUCJP)
+	testMsg := []byte{0xc6, 0xfc, 0xcb, 0xdc}
+	msg, err := parser.Parse(testMsg)
+	assert.Nil(t, err)
+	assert.Equal(t, "日本", string(msg.Content))
+}
+
+func TestGB18030ParserHandleMessages(t *testing.T) {
+	parser := New(GB18030)
+	testMsg := []byte{0xd6, 0xd0, 0xce, 0xc4}
+	msg, err := parser.Parse(testMsg)
+	assert.Nil(t, err)
+	assert.Equal(t, "中文", string(msg.Content))
+
+	// four-byte sequences
+	testMsg = []byte{0x81, 0x30, 0x81, 0x30}
+	msg, err = parser.Parse(testMsg)
+	assert.Nil(t, err)
+	assert.Equal(t, "\u0080", string(msg.Content))
+}
+
+func TestUTF32LEParserHandleMessages(t *testing.T) {
+	parser := New(UTF32LE)
+	testMsg := []byte{'F', 0x0, 0x0, 0x0, 'o', 0x0, 0x0, 0x0, 'o', 0x0, 0x0, 0x0}
+	msg, err := parser.Parse(testMsg)
+	assert.Nil(t, err)
+	assert.Equal(t, "Foo", string(msg.Content))
+
+	// We should support BOM
+	testMsg = []byte{0xFF, 0xFE, 0x0, 0x0, 'F', 0x0, 0x0, 0x0, 'o', 0x0, 0x0, 0x0, 'o', 0x0, 0x0, 0x0}
+	msg, err = parser.Parse(testMsg)
+	assert.Nil(t, err)
+	assert.Equal(t, "Foo", string(msg.Content))
+}
+
+func TestUTF32BEParserHandleMessages(t *testing.T) {
+	parser := New(UTF32BE)
+	testMsg := []byte{0x0, 0x0, 0x0, 'F', 0x0, 0x0, 0x0, 'o', 0x0, 0x0, 0x0, 'o'}
+	msg, err := parser.Parse(testMsg)
+	assert.Nil(t, err)
+	assert.Equal(t, "Foo", string(msg.Content))
+
+	// We should support BOM
+	testMsg = []byte{0x0, 0x0, 0xFE, 0xFF, 0x0, 0x0, 0x0, 'F', 0x0, 0x0, 0x0, 'o', 0x0, 0x0, 0x0, 'o'}
+	msg, err = parser.Parse(testMsg)
+	assert.Nil(t, err)
+	assert.Equal(t, "Foo", string(msg.Content))
+}
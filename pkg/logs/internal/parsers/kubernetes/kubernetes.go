@@ -8,6 +8,7 @@ package kubernetes
 import (
 	"bytes"
 	"errors"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -20,7 +21,7 @@ var (
 
 // New creates a new parser that parses Kubernetes-formatted log lines.
 //
-// Kubernetes log lines follow the pattern '<timestamp> <stream> <flag> <content>'; see
+// Kubernetes log lines follow the pattern '<timestamp> <stream> <tags> <content>'; see
 // https://github.com/kubernetes/kubernetes/blob/master/pkg/kubelet/kuberuntime/logs/logs.go.
 //
 // For example: `2018-09-20T11:54:11.753589172Z stdout F This is my message`
@@ -69,8 +70,13 @@ func parseKubernetes(msg []byte) (parsers.Message, error) {
 	}, nil
 }
 
-func isPartial(flag string) bool {
-	return flag == "P"
+// isPartial returns true if the line is a partial line, the tags field of a CRI log line
+// is a ':'-separated list of tags where the first one is either "P" (partial) or "F" (full).
+func isPartial(tags string) bool {
+	if i := strings.IndexByte(tags, ':'); i >= 0 {
+		tags = tags[:i]
+	}
+	return tags == "P"
 }
 
 // getStatus returns the status of the message based on
@@ -38,6 +38,18 @@ func TestKubernetesParserShouldSucceedWithPartialFlag(t *testing.T) {
 	assert.Equal(t, []byte("anything"), msg.Content)
 }
 
+func TestKubernetesParserShouldSucceedWithTags(t *testing.T) {
+	msg, err := New().Parse([]byte("2018-09-20T11:54:11.753589172Z stdout P:foo:bar anything"))
+	assert.Nil(t, err)
+	assert.True(t, msg.IsPartial)
+	assert.Equal(t, []byte("anything"), msg.Content)
+
+	msg, err = New().Parse([]byte("2018-09-20T11:54:11.753589172Z stdout F:foo anything"))
+	assert.Nil(t, err)
+	assert.False(t, msg.IsPartial)
+	assert.Equal(t, []byte("anything"), msg.Content)
+}
+
 func TestKubernetesParserShouldHandleEmptyMessage(t *testing.T) {
 	msg, err := New().Parse([]byte(containerdHeaderOut))
 	assert.Nil(t, err)
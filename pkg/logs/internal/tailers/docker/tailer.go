@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 
 	"github.com/docker/docker/api/types"
@@ -318,8 +319,14 @@ func (t *Tailer) forwardMessages() {
 			origin.Offset = output.Timestamp
 			t.setLastSince(output.Timestamp)
 			origin.Identifier = t.Identifier()
-			origin.SetTags(t.tagProvider.GetTags())
-			t.outputChan <- message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+			origin.SetTags(append(output.Tags, t.tagProvider.GetTags()...))
+			msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+			msg.Timestamp = output.EventTimestamp
+			msg.Masked = output.Masked
+			if !output.EventTimestamp.IsZero() {
+				metrics.ReportE2ELatency(t.Source.Name, output.EventTimestamp, output.IngestionTimestamp)
+			}
+			t.outputChan <- msg
 		}
 	}
 }
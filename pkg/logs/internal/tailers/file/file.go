@@ -7,8 +7,15 @@ package file
 
 import (
 	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 )
 
 // File represents a file to tail
@@ -38,3 +45,59 @@ func (t *File) GetScanKey() string {
 	}
 	return t.Path
 }
+
+// Encoding returns the encoding to use to decode the file: the one configured on its
+// source if any, otherwise the one detected from the beginning of the file when
+// `logs_config.auto_encoding_detection` is enabled.
+func (t *File) Encoding() string {
+	if t.Source.Config.Encoding != "" {
+		return t.Source.Config.Encoding
+	}
+	// container log files have their own format and are always written in UTF-8
+	sourceType := t.Source.GetSourceType()
+	if sourceType == config.KubernetesSourceType || sourceType == config.DockerSourceType {
+		return ""
+	}
+	// length-prefixed records are binary data
+	if t.Source.Config.Framing != "" {
+		return ""
+	}
+	if !coreConfig.Datadog.GetBool("logs_config.auto_encoding_detection") {
+		return ""
+	}
+
+	f, err := openFile(t.Path)
+	if err != nil {
+		// the tailer reports the error when it fails to open the file
+		return ""
+	}
+	defer f.Close()
+
+	sample := make([]byte, decoder.EncodingDetectionSampleSize)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		log.Debugf("Could not read %s to detect its encoding: %v", t.Path, err)
+		return ""
+	}
+
+	encoding := decoder.DetectEncoding(sample[:n])
+	if encoding != "" {
+		log.Infof("Detected encoding %s for file %s", encoding, t.Path)
+	}
+	return encoding
+}
+
+// SidecarTagsPath returns the path of the sidecar file listing tags to add to the logs of
+// the file, built from the `logs_config.sidecar_tags_pattern`, or an empty string when
+// the sidecar files are disabled.
+func (t *File) SidecarTagsPath() string {
+	pattern := coreConfig.Datadog.GetString("logs_config.sidecar_tags_pattern")
+	if pattern == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{path}", t.Path,
+		"{dir}", filepath.Dir(t.Path),
+		"{filename}", filepath.Base(t.Path),
+	).Replace(pattern)
+}
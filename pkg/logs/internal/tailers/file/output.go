@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"context"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// Output is an additional consumer of the messages of a tailer, next to its pipeline,
+// e.g. a local analysis of the logs. Each output receives its own copy of the messages.
+type Output struct {
+	Channel chan *message.Message
+	// DropWhenFull makes the tailer drop the messages of the output when its channel is full,
+	// instead of waiting for the consumer and holding back the tailer.
+	DropWhenFull bool
+}
+
+// forward sends the message to the output, depending on its backpressure policy.
+func (o Output) forward(ctx context.Context, msg *message.Message, sourceName string) {
+	if o.DropWhenFull {
+		select {
+		case o.Channel <- msg:
+		default:
+			metrics.TlmOutputDropped.Inc(sourceName)
+		}
+		return
+	}
+	select {
+	case o.Channel <- msg:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// starvationThreshold is the time waited by a tailer for its turn above which it is considered starved.
+const starvationThreshold = time.Second
+
+// Scheduler makes the tailers sharing it take turns to read their files, each reading
+// up to a budget of bytes per turn, so that the busiest files can not starve the others
+// on the pipeline. The tailers waiting for a turn are served in the order they arrived.
+type Scheduler struct {
+	// turn holds the token owned by the tailer reading its file, the channel serves
+	// the receivers blocked on it in FIFO order.
+	turn    chan struct{}
+	budget  int
+	waiting int64
+}
+
+// NewScheduler returns a new Scheduler giving turns of budget bytes to the tailers.
+func NewScheduler(budget int) *Scheduler {
+	s := &Scheduler{
+		turn:   make(chan struct{}, 1),
+		budget: budget,
+	}
+	s.turn <- struct{}{}
+	return s
+}
+
+// acquire blocks until it is the turn of the tailer, and returns false if the tailer
+// was stopped in the meantime.
+func (s *Scheduler) acquire(sourceName string, stop chan struct{}) bool {
+	start := time.Now()
+	metrics.TlmTailersWaitingTurn.Set(float64(atomic.AddInt64(&s.waiting, 1)))
+	defer func() {
+		metrics.TlmTailersWaitingTurn.Set(float64(atomic.AddInt64(&s.waiting, -1)))
+	}()
+
+	select {
+	case <-s.turn:
+		if time.Since(start) > starvationThreshold {
+			metrics.TlmTailersStarved.Inc(sourceName)
+		}
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// release ends the turn of the tailer, letting the next tailer waiting read its file.
+func (s *Scheduler) release() {
+	s.turn <- struct{}{}
+}
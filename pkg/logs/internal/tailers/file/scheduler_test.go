@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerTurns(t *testing.T) {
+	scheduler := NewScheduler(4096)
+	stop := make(chan struct{}, 1)
+
+	assert.True(t, scheduler.acquire("", stop))
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- scheduler.acquire("", stop)
+	}()
+
+	select {
+	case <-acquired:
+		assert.Fail(t, "a tailer should wait for the end of the turn of the other tailer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	scheduler.release()
+	assert.True(t, <-acquired)
+	scheduler.release()
+}
+
+func TestSchedulerTurnsInArrivalOrder(t *testing.T) {
+	scheduler := NewScheduler(4096)
+	stop := make(chan struct{}, 1)
+
+	assert.True(t, scheduler.acquire("", stop))
+
+	turns := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			scheduler.acquire("", stop)
+			turns <- i
+			scheduler.release()
+		}(i)
+		// let the tailer wait for its turn before the next one arrives
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	scheduler.release()
+	assert.Equal(t, 0, <-turns)
+	assert.Equal(t, 1, <-turns)
+	assert.Equal(t, 2, <-turns)
+}
+
+func TestSchedulerStopWhileWaiting(t *testing.T) {
+	scheduler := NewScheduler(4096)
+	stop := make(chan struct{}, 1)
+
+	assert.True(t, scheduler.acquire("", stop))
+
+	stop <- struct{}{}
+	assert.False(t, scheduler.acquire("", stop))
+}
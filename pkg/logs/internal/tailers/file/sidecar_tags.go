@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// sidecarTagsRefreshPeriod is the minimum time between two checks of the modification of a sidecar tags file.
+const sidecarTagsRefreshPeriod = 10 * time.Second
+
+// sidecarTags provides the tags listed in the sidecar file of a log file, so that the
+// programs writing the log file can label their own logs. The sidecar file is optional,
+// it is read again when it is created, modified or removed.
+//
+// The tags are separated by commas or new lines, empty lines and lines starting with
+// '#' are ignored. sidecarTags is not thread safe.
+type sidecarTags struct {
+	path      string
+	lastCheck time.Time
+	modTime   time.Time
+	tags      []string
+}
+
+// newSidecarTags returns a new sidecarTags reading the sidecar file at path.
+func newSidecarTags(path string) *sidecarTags {
+	return &sidecarTags{
+		path: path,
+	}
+}
+
+// GetTags returns the tags listed in the sidecar file.
+func (s *sidecarTags) GetTags() []string {
+	now := time.Now()
+	if !s.lastCheck.IsZero() && now.Sub(s.lastCheck) < sidecarTagsRefreshPeriod {
+		return s.tags
+	}
+	s.lastCheck = now
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		s.modTime = time.Time{}
+		s.tags = nil
+		return s.tags
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return s.tags
+	}
+	s.modTime = info.ModTime()
+
+	content, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		log.Warnf("Could not read the sidecar tags file %s: %v", s.path, err)
+		s.tags = nil
+		return s.tags
+	}
+	s.tags = parseSidecarTags(string(content))
+	return s.tags
+}
+
+// parseSidecarTags returns the tags listed in the content of a sidecar tags file.
+func parseSidecarTags(content string) []string {
+	var tags []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, tag := range strings.Split(line, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestParseSidecarTags(t *testing.T) {
+	assert.Nil(t, parseSidecarTags(""))
+	assert.Equal(t, []string{"a:1", "b:2", "c"}, parseSidecarTags(" a:1 ,b:2\n# comment, ignored\n\n c \n,"))
+}
+
+func TestSidecarTagsReadAgainWhenModified(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-tailer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.ddtags")
+	sidecar := newSidecarTags(path)
+
+	// the sidecar file is optional
+	assert.Nil(t, sidecar.GetTags())
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("job:backup"), 0644))
+	sidecar.lastCheck = time.Time{}
+	assert.Equal(t, []string{"job:backup"}, sidecar.GetTags())
+
+	// the file is not checked again before the end of the refresh period
+	assert.Nil(t, ioutil.WriteFile(path, []byte("job:restore"), 0644))
+	assert.Nil(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	assert.Equal(t, []string{"job:backup"}, sidecar.GetTags())
+
+	sidecar.lastCheck = time.Time{}
+	assert.Equal(t, []string{"job:restore"}, sidecar.GetTags())
+
+	assert.Nil(t, os.Remove(path))
+	sidecar.lastCheck = time.Time{}
+	assert.Nil(t, sidecar.GetTags())
+}
+
+func TestSidecarTagsPath(t *testing.T) {
+	defer coreConfig.Datadog.Set("logs_config.sidecar_tags_pattern", "")
+	file := NewFile(filepath.Join("var", "log", "app.log"), config.NewLogSource("", &config.LogsConfig{}), false)
+
+	// the sidecar files are disabled by default
+	assert.Equal(t, "", file.SidecarTagsPath())
+
+	coreConfig.Datadog.Set("logs_config.sidecar_tags_pattern", "{path}.ddtags")
+	assert.Equal(t, filepath.Join("var", "log", "app.log")+".ddtags", file.SidecarTagsPath())
+
+	coreConfig.Datadog.Set("logs_config.sidecar_tags_pattern", "{dir}/tags/{filename}")
+	assert.Equal(t, filepath.Join("var", "log")+"/tags/app.log", file.SidecarTagsPath())
+
+}
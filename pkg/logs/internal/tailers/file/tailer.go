@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,11 +21,23 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 )
 
+// lagUpdatePeriod is the minimum duration between two updates of the bytes lag of a tailer.
+const lagUpdatePeriod = time.Second
+
+// completedTag is the tag of the event sent once a file read in one-shot mode has been read to completion.
+const completedTag = "file_read:completed"
+
+// modTimeRefreshPeriod is the minimum duration between two checks of the modification time of the
+// file of a tailer, used as the time of the events of the logs without parsed timestamp.
+const modTimeRefreshPeriod = time.Second
+
 // Tailer tails one file and sends messages to an output channel
 type Tailer struct {
 	readOffset    int64
@@ -37,15 +50,69 @@ type Tailer struct {
 
 	fullpath string
 	osFile   *os.File
-	tags     []string
-
-	OutputChan  chan *message.Message
+	// uring reads osFile when io_uring reads are enabled and supported, nil otherwise.
+	uring *uring
+	tags  []string
+	// symlinkTarget is the file read by the tailer when its path is a symbolic link
+	symlinkTarget string
+	// fileInfo identifies the file read by the tailer on windows, where the
+	// file is not kept open between reads.
+	fileInfo os.FileInfo
+	// inodeIdentifier holds the string identifying the file read by the tailer in the registry,
+	// next to its path. It changes when the file is replaced on windows.
+	inodeIdentifier atomic.Value
+
+	OutputChan chan *message.Message
+	// outputs are the additional consumers of the messages, next to OutputChan.
+	outputs     []Output
 	decoder     *decoder.Decoder
 	tagProvider tag.Provider
-
-	sleepDuration time.Duration
+	// decoderFactory, when set, builds a decoder for the encoding detected once data is written
+	// to a file which was empty when the tailer started, and whose encoding could not be detected.
+	// decoderLock guards the replacement of the decoder, which is done by the reading goroutine
+	// before the decoder is started.
+	decoderFactory func(encoding string) *decoder.Decoder
+	decoderLock    sync.Mutex
+	// detectEncoding and decodingStarted are only used by the reading goroutine once the tailer
+	// has started.
+	detectEncoding  bool
+	decodingStarted bool
+	// sidecarTags provides the tags of the sidecar file of the file, if enabled
+	sidecarTags *sidecarTags
+	// modTime is the modification time of the file checked at modTimeCheck, they are only
+	// used by forwardMessages
+	modTime      time.Time
+	modTimeCheck time.Time
+	// fileFingerprint is the fingerprint of the file identified by fingerprintedInode, they are
+	// only used by forwardMessages
+	fileFingerprint    string
+	fingerprintedInode string
+
+	// sleepDuration is the time waited before reading again a file which had no new data,
+	// it doubles while the file stays idle, up to maxSleepDuration.
+	sleepDuration    time.Duration
+	maxSleepDuration time.Duration
+	// idleSleepDuration is the next time to wait, only used by the reading goroutine.
+	idleSleepDuration time.Duration
 
 	closeTimeout time.Duration
+	// deletionGracePeriod is the time a tailer keeps reading its file after it has been deleted.
+	deletionGracePeriod time.Duration
+	// deletionTimer stops the tailer at the end of the grace period of its deleted file.
+	deletionTimer *time.Timer
+
+	// registry holds the offsets acknowledged by the intake: the auditor only
+	// commits the offset of a message once it has been delivered.
+	registry auditor.Registry
+
+	// scheduler, when set, makes the tailer take turns with the other tailers to read its file.
+	scheduler *Scheduler
+	// inTurn is true while the tailer owns the turn of its scheduler, the chunks read are then
+	// held in pendingInputs until the turn is released. Both are only used by the reading goroutine.
+	inTurn        bool
+	pendingInputs []*decoder.Input
+
+	lastLagUpdate time.Time
 
 	// isFinished is an atomic value, set to 1 when the tailer has closed its input
 	// and flushed all messages.
@@ -54,6 +121,46 @@ type Tailer struct {
 	// didFileRotate is an atomic value, used to determine hasFileRotated.
 	didFileRotate int32
 
+	// didFileGetDeleted is an atomic value, used to determine hasFileBeenDeleted.
+	didFileGetDeleted int32
+
+	// didFileTruncate is an atomic value, set to 1 once the tailer has found its file truncated
+	// in place, it then stops reading the file until the launcher restarts it from the beginning.
+	didFileTruncate int32
+
+	// isPaused is an atomic value, set to 1 while the tailer must not read its file.
+	isPaused int32
+
+	// oneShot makes the tailer stop once it has read its file to completion, rather than
+	// waiting for new data, for files which are not written anymore.
+	oneShot bool
+
+	// didComplete is an atomic value, set to 1 once the tailer has read its file to completion
+	// in one-shot mode.
+	didComplete int32
+
+	// completionWindow is how long a tailer in one-shot mode must read no new data at the end of
+	// its file before the file is read to completion, as its writer may not have closed it yet.
+	// eofSince is when the tailer last reached the end of its file after reading data, only used
+	// by the reading goroutine.
+	completionWindow time.Duration
+	eofSince         time.Time
+
+	// lastProgress is an atomic value, the unix time in nanoseconds of the last time the
+	// tailer read data from its file, or was not expected to.
+	lastProgress int64
+
+	// blockedSince is an atomic value, the unix time in nanoseconds since which the tailer
+	// waits for its outputs to accept a message, 0 while it does not.
+	blockedSince int64
+
+	// decoderInputBlocked is an atomic value, set to 1 while the tailer waits for the full
+	// input of its decoder to accept a chunk.
+	decoderInputBlocked int32
+
+	// stuckReason holds why the tailer was found stuck by its last check, empty if it was not.
+	stuckReason atomic.Value
+
 	stop chan struct{}
 	done chan struct{}
 
@@ -71,21 +178,47 @@ func NewTailer(outputChan chan *message.Message, file *File, sleepDuration time.
 		tagProvider = tag.NewLocalProvider([]string{})
 	}
 
+	var sidecar *sidecarTags
+	if path := file.SidecarTagsPath(); path != "" {
+		sidecar = newSidecarTags(path)
+	}
+
 	forwardContext, stopForward := context.WithCancel(context.Background())
 	closeTimeout := coreConfig.Datadog.GetDuration("logs_config.close_timeout") * time.Second
+	if file.Source.Config.CloseTimeout > 0 {
+		closeTimeout = time.Duration(file.Source.Config.CloseTimeout) * time.Second
+	}
+	deletionGracePeriod := coreConfig.Datadog.GetDuration("logs_config.deleted_file_grace_period") * time.Second
+	completionWindow := time.Duration(coreConfig.Datadog.GetFloat64("logs_config.one_shot_completion_window") * float64(time.Second))
+	maxSleepDuration := time.Duration(coreConfig.Datadog.GetFloat64("logs_config.file_tailer_max_sleep_duration") * float64(time.Second))
+	if maxSleepDuration < sleepDuration {
+		maxSleepDuration = sleepDuration
+	}
+
+	var uring *uring
+	if coreConfig.Datadog.GetBool("logs_config.file_io_uring") {
+		uring = sharedURing()
+	}
 
 	return &Tailer{
-		File:           file,
-		OutputChan:     outputChan,
-		decoder:        decoder,
-		tagProvider:    tagProvider,
-		readOffset:     0,
-		sleepDuration:  sleepDuration,
-		closeTimeout:   closeTimeout,
-		stop:           make(chan struct{}, 1),
-		done:           make(chan struct{}, 1),
-		forwardContext: forwardContext,
-		stopForward:    stopForward,
+		File:                file,
+		OutputChan:          outputChan,
+		decoder:             decoder,
+		tagProvider:         tagProvider,
+		sidecarTags:         sidecar,
+		readOffset:          0,
+		sleepDuration:       sleepDuration,
+		maxSleepDuration:    maxSleepDuration,
+		idleSleepDuration:   sleepDuration,
+		closeTimeout:        closeTimeout,
+		deletionGracePeriod: deletionGracePeriod,
+		oneShot:             file.Source.Config.OneShot,
+		completionWindow:    completionWindow,
+		uring:               uring,
+		stop:                make(chan struct{}, 1),
+		done:                make(chan struct{}, 1),
+		forwardContext:      forwardContext,
+		stopForward:         stopForward,
 	}
 }
 
@@ -108,14 +241,43 @@ func (t *Tailer) Start(offset int64, whence int) error {
 	}
 	t.File.Source.Status.Success()
 	t.File.Source.AddInput(t.File.Path)
+	t.File.Source.RegisterInfo(t)
+	t.recordProgress()
 
-	go t.forwardMessages()
-	t.decoder.Start()
+	t.detectEncoding = t.decoderFactory != nil && t.File.Source.Config.Encoding == "" && t.isFileEmpty()
+	if !t.detectEncoding {
+		t.startDecoding()
+	}
 	go t.readForever()
 
 	return nil
 }
 
+// isFileEmpty returns true if the file of the tailer has no data yet.
+func (t *Tailer) isFileEmpty() bool {
+	info, err := os.Stat(t.fullpath)
+	return err == nil && info.Size() == 0
+}
+
+// startDecoding starts the decoder of the tailer and the forwarding of its messages. When the
+// file was empty when the tailer started, the encoding of the file is detected first from the
+// data written since, and the decoder is replaced if it was built for another encoding.
+func (t *Tailer) startDecoding() {
+	if t.decodingStarted {
+		return
+	}
+	t.decodingStarted = true
+	if t.detectEncoding {
+		if encoding := t.File.Encoding(); encoding != "" {
+			t.decoderLock.Lock()
+			t.decoder = t.decoderFactory(encoding)
+			t.decoderLock.Unlock()
+		}
+	}
+	go t.forwardMessages()
+	t.decoder.Start()
+}
+
 // DidRotate returns true if the tailer's file has been log-rotated.
 // When a log rotation occurs, the file can be either:
 // - renamed and recreated
@@ -124,22 +286,91 @@ func (t *Tailer) Start(offset int64, whence int) error {
 // readForever lets the tailer tail the content of a file
 // until it is closed or the tailer is stopped.
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.didSymlinkTargetChange() || t.hasFileTruncated() {
+		return true, nil
+	}
 	return DidRotate(t.osFile, t.GetReadOffset())
 }
 
+// resolveSymlinkTarget records the target of the path of the tailer when it is a symbolic link.
+func (t *Tailer) resolveSymlinkTarget() {
+	info, err := os.Lstat(t.fullpath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return
+	}
+	target, err := filepath.EvalSymlinks(t.fullpath)
+	if err != nil {
+		log.Debugf("Could not resolve symlink %s: %v", t.fullpath, err)
+		return
+	}
+	t.symlinkTarget = target
+}
+
+// didSymlinkTargetChange returns true if the path of the tailer is a symbolic link
+// which now points to another file than the one read by the tailer.
+func (t *Tailer) didSymlinkTargetChange() bool {
+	if t.symlinkTarget == "" {
+		return false
+	}
+	target, err := filepath.EvalSymlinks(t.fullpath)
+	if err != nil || target == t.symlinkTarget {
+		// a missing link is handled like a missing file
+		return false
+	}
+	log.Infof("Target of symlink %s changed from %s to %s", t.File.Path, t.symlinkTarget, target)
+	return true
+}
+
 func (t *Tailer) readForever() {
 	defer func() {
 		t.osFile.Close()
+		// the messages are flushed and the tailer is done once its decoder is stopped
+		t.startDecoding()
 		t.decoder.Stop()
-		log.Info("Closed", t.File.Path, "for tailer key", t.File.GetScanKey(), "read", t.bytesRead, "bytes and", t.decoder.GetLineCount(), "lines")
+		metrics.TlmTailerBytesLag.Delete(t.File.Source.Name, t.File.Path)
+		t.File.Source.UnregisterInfo(t)
+		log.Info("Closed", t.File.Path, "for tailer key", t.File.GetScanKey(), "read", atomic.LoadInt64(&t.bytesRead), "bytes and", t.decoder.GetLineCount(), "lines")
 	}()
 
 	for {
-		n, err := t.read()
+		if t.IsPaused() {
+			select {
+			case <-t.stop:
+				return
+			default:
+				// a paused tailer is not expected to read its file
+				t.recordProgress()
+				t.wait()
+				continue
+			}
+		}
+
+		if t.scheduler != nil && !t.scheduler.acquire(t.File.Source.Name, t.stop) {
+			return
+		}
+		t.inTurn = t.scheduler != nil
+		n, err := t.readTurn()
+		if t.scheduler != nil {
+			t.inTurn = false
+			t.scheduler.release()
+			// the turn is not held while the pipeline of the tailer applies backpressure
+			t.flushInputs()
+		}
 		if err != nil {
 			return
 		}
-		t.recordBytes(int64(n))
+		if n == 0 && t.oneShot && !t.hasFileTruncated() && t.isStableAtEOF() {
+			log.Infof("Finished reading %s (%d bytes)", t.File.Path, t.GetReadOffset())
+			metrics.TlmFilesCompleted.Inc(t.File.Source.Name)
+			atomic.StoreInt32(&t.didComplete, 1)
+			return
+		}
+		if n != 0 {
+			t.eofSince = time.Time{}
+			// poll the file quickly again as soon as it has new data
+			t.idleSleepDuration = t.sleepDuration
+			t.recordProgress()
+		}
 
 		select {
 		case <-t.stop:
@@ -157,8 +388,73 @@ func (t *Tailer) readForever() {
 	}
 }
 
-// buildTailerTags groups the file tag, directory (if wildcard path) and user tags
+// isStableAtEOF returns true once the tailer has read no new data at the end of its file for
+// the completion window.
+func (t *Tailer) isStableAtEOF() bool {
+	if t.eofSince.IsZero() {
+		t.eofSince = time.Now()
+	}
+	return time.Since(t.eofSince) >= t.completionWindow
+}
+
+// readTurn reads the file once, or when the tailer is scheduled, until the file has
+// no new data or the read budget of the turn is exhausted. It returns the number of bytes read.
+func (t *Tailer) readTurn() (int, error) {
+	total := 0
+	for {
+		n, err := t.read()
+		if err != nil {
+			return total, err
+		}
+		t.recordBytes(int64(n))
+		t.recordLag()
+		total += n
+		if n == 0 || t.scheduler == nil || total >= t.scheduler.budget {
+			return total, nil
+		}
+	}
+}
+
+// sendInput sends a chunk read from the file to the decoder. During a turn of the scheduler,
+// the chunk is held until the turn is released, so that a tailer blocked by its pipeline
+// does not prevent the other tailers from reading their files.
+func (t *Tailer) sendInput(input *decoder.Input) {
+	t.startDecoding()
+	if t.inTurn {
+		t.pendingInputs = append(t.pendingInputs, input)
+		return
+	}
+	t.sendToDecoder(input)
+}
+
+// flushInputs sends the chunks read during the last turn to the decoder.
+func (t *Tailer) flushInputs() {
+	for _, input := range t.pendingInputs {
+		t.sendToDecoder(input)
+	}
+	t.pendingInputs = nil
+}
+
+// sendToDecoder sends a chunk to the decoder, recording while its input is full so that the
+// tailer is not reported as stuck when it is only slowed down by its pipeline.
+func (t *Tailer) sendToDecoder(input *decoder.Input) {
+	select {
+	case t.decoder.InputChan <- input:
+		return
+	default:
+	}
+	atomic.StoreInt32(&t.decoderInputBlocked, 1)
+	t.decoder.InputChan <- input
+	atomic.StoreInt32(&t.decoderInputBlocked, 0)
+	t.recordProgress()
+}
+
+// buildTailerTags groups the file tag, directory (if wildcard path) and user tags,
+// or the tags extracted from the path when the source has a path tags pattern.
 func (t *Tailer) buildTailerTags() []string {
+	if tags := t.buildPathTags(); len(tags) > 0 {
+		return tags
+	}
 	tags := []string{fmt.Sprintf("filename:%s", filepath.Base(t.File.Path))}
 	if t.File.IsWildcardPath {
 		tags = append(tags, fmt.Sprintf("dirname:%s", filepath.Dir(t.File.Path)))
@@ -166,6 +462,28 @@ func (t *Tailer) buildTailerTags() []string {
 	return tags
 }
 
+// buildPathTags returns a tag for each named capture group of the source path tags pattern,
+// compiled when the source is validated, matched by the file path, or nil if there is no
+// pattern or if it does not match.
+func (t *Tailer) buildPathTags() []string {
+	re := t.File.Source.Config.PathTagsRegex
+	if re == nil {
+		return nil
+	}
+	matches := re.FindStringSubmatch(t.File.Path)
+	if matches == nil {
+		log.Debugf("Path tags pattern %s does not match %s", re, t.File.Path)
+		return nil
+	}
+	var tags []string
+	for i, name := range re.SubexpNames() {
+		if name != "" && matches[i] != "" {
+			tags = append(tags, fmt.Sprintf("%s:%s", name, matches[i]))
+		}
+	}
+	return tags
+}
+
 // StartFromBeginning lets the tailer start tailing its file
 // from the beginning
 func (t *Tailer) StartFromBeginning() error {
@@ -174,16 +492,33 @@ func (t *Tailer) StartFromBeginning() error {
 
 // Stop stops the tailer and returns only when the decoder is flushed
 func (t *Tailer) Stop() {
-	t.stop <- struct{}{}
-	t.File.Source.RemoveInput(t.File.Path)
+	if t.deletionTimer == nil {
+		t.stop <- struct{}{}
+		t.File.Source.RemoveInput(t.File.Path)
+	} else if t.deletionTimer.Stop() {
+		// the grace period of the deleted file is cut short, the input has already been removed
+		t.stop <- struct{}{}
+	}
 	// wait for the decoder to be flushed
 	<-t.done
 }
 
+// StopNow stops the tailer without waiting for its pending messages to be forwarded, they are
+// dropped, e.g. when its outputs are blocked. It returns without waiting for the tailer to stop.
+func (t *Tailer) StopNow() {
+	t.stopForward()
+	t.stop <- struct{}{}
+	t.File.Source.RemoveInput(t.File.Path)
+}
+
 // StopAfterFileRotation prepares the tailer to stop after a timeout
 // to finish reading its file that has been log-rotated
 func (t *Tailer) StopAfterFileRotation() {
+	// the info key of the tailer changes once its file has rotated,
+	// so that it does not conflict with the tailer of the new file
+	t.File.Source.UnregisterInfo(t)
 	t.fileHasRotated()
+	t.File.Source.RegisterInfo(t)
 	go func() {
 		time.Sleep(t.closeTimeout)
 		t.stopForward()
@@ -192,6 +527,43 @@ func (t *Tailer) StopAfterFileRotation() {
 	t.File.Source.RemoveInput(t.File.Path)
 }
 
+// StopAfterFileDeletion prepares the tailer to stop after the grace period of the deleted
+// files, to finish reading its file which remains readable while it is open.
+func (t *Tailer) StopAfterFileDeletion() {
+	log.Infof("File %s has been deleted, reading it for %v before closing it", t.File.Path, t.deletionGracePeriod)
+	metrics.TlmDeletedFiles.Inc(t.File.Source.Name)
+	// the info key of the tailer changes once its file has been deleted,
+	// so that it does not conflict with the tailer of a new file with the same path
+	t.File.Source.UnregisterInfo(t)
+	t.fileHasBeenDeleted()
+	t.File.Source.RegisterInfo(t)
+	t.deletionTimer = time.AfterFunc(t.deletionGracePeriod, func() {
+		t.stop <- struct{}{}
+	})
+	t.File.Source.RemoveInput(t.File.Path)
+}
+
+// Pause stops reading the file until Resume is called, the messages already read
+// are still forwarded and the offsets are kept.
+func (t *Tailer) Pause() {
+	atomic.StoreInt32(&t.isPaused, 1)
+}
+
+// Resume resumes reading the file after a call to Pause.
+func (t *Tailer) Resume() {
+	atomic.StoreInt32(&t.isPaused, 0)
+}
+
+// IsPaused returns true if the tailer has been paused.
+func (t *Tailer) IsPaused() bool {
+	return atomic.LoadInt32(&t.isPaused) != 0
+}
+
+// IsCompleted returns true if the tailer has read its file to completion in one-shot mode.
+func (t *Tailer) IsCompleted() bool {
+	return atomic.LoadInt32(&t.didComplete) != 0
+}
+
 // IsFinished returns true if the tailer is in the process of stopping.  Specifically,
 // this may be true if the tailer has completed handling all messages, but has not
 // yet had its Stop method called.
@@ -207,30 +579,159 @@ func (t *Tailer) forwardMessages() {
 		close(t.done)
 	}()
 	for output := range t.decoder.OutputChan {
-		offset := t.decodedOffset + int64(output.RawDataLen)
+		offset := t.GetDecodedOffset() + int64(output.RawDataLen)
 		identifier := t.Identifier()
-		if t.hasFileRotated() {
+		if t.hasFileRotated() || t.hasFileBeenDeleted() {
+			// the offsets of the file must not be restored for a new file with the same path
 			offset = 0
 			identifier = ""
 		}
-		t.decodedOffset = offset
+		t.SetDecodedOffset(offset)
 		origin := message.NewOrigin(t.File.Source)
 		origin.Identifier = identifier
+		if identifier != "" {
+			origin.InodeIdentifier = t.getInodeIdentifier()
+			origin.Fingerprint = t.fingerprint(origin.InodeIdentifier, offset)
+		}
 		origin.Offset = strconv.FormatInt(offset, 10)
-		origin.SetTags(append(t.tags, t.tagProvider.GetTags()...))
+		if pattern := t.decoder.GetDetectedPattern(); pattern != nil {
+			// persisted in the registry to keep using the pattern after a restart
+			origin.MultiLinePattern = pattern.String()
+		}
+		tags := append(append(output.Tags, t.tags...), t.tagProvider.GetTags()...)
+		if t.sidecarTags != nil {
+			tags = append(tags, t.sidecarTags.GetTags()...)
+		}
+		origin.SetTags(tags)
 		// Ignore empty lines once the registry offset is updated
 		if len(output.Content) == 0 {
 			continue
 		}
+		if eventTime := t.eventTime(output); !eventTime.IsZero() {
+			metrics.ReportE2ELatency(t.File.Source.Name, eventTime, output.IngestionTimestamp)
+		}
 		// Make the write to the output chan cancellable to be able to stop the tailer
 		// after a file rotation when it is stuck on it.
 		// We don't return directly to keep the same shutdown sequence that in the
 		// normal case.
-		select {
-		case t.OutputChan <- message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp):
-		case <-t.forwardContext.Done():
+		// the messages are processed concurrently, each output needs its own content and
+		// origin, copied before the pipeline gets the original ones
+		outputMsgs := make([]*message.Message, len(t.outputs))
+		for i := range t.outputs {
+			content := append([]byte(nil), output.Content...)
+			outputOrigin := *origin
+			outputOrigin.SetTags(append([]string(nil), tags...))
+			outputMsgs[i] = message.NewMessage(content, &outputOrigin, output.Status, output.IngestionTimestamp)
+			outputMsgs[i].Timestamp = output.EventTimestamp
+			outputMsgs[i].Attributes = output.Attributes
+			outputMsgs[i].Masked = output.Masked
+		}
+		msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+		msg.Timestamp = output.EventTimestamp
+		msg.Attributes = output.Attributes
+		msg.Masked = output.Masked
+		t.forward(msg, outputMsgs)
+	}
+	if t.IsCompleted() {
+		t.forwardCompletion()
+	}
+}
+
+// forwardCompletion sends the event telling that the file has been read to completion, once,
+// after all its messages. The completion is recorded in the registry against the inode of the
+// file once the event is delivered, so that a new file created at the same path is read.
+func (t *Tailer) forwardCompletion() {
+	origin := message.NewOrigin(t.File.Source)
+	if !t.hasFileRotated() && !t.hasFileBeenDeleted() {
+		origin.Identifier = t.Identifier()
+		origin.InodeIdentifier = t.getInodeIdentifier()
+		origin.Fingerprint = t.fingerprint(origin.InodeIdentifier, t.GetDecodedOffset())
+		origin.Completed = origin.InodeIdentifier != ""
+	}
+	origin.Offset = strconv.FormatInt(t.GetDecodedOffset(), 10)
+	origin.SetTags(append(append([]string{completedTag}, t.tags...), t.tagProvider.GetTags()...))
+	content := []byte(fmt.Sprintf("Finished reading %s (%d bytes)", t.File.Path, t.GetDecodedOffset()))
+	msg := message.NewMessage(content, origin, message.StatusInfo, time.Now().UnixNano())
+	atomic.StoreInt64(&t.blockedSince, time.Now().UnixNano())
+	select {
+	case t.OutputChan <- msg:
+	case <-t.forwardContext.Done():
+	}
+	atomic.StoreInt64(&t.blockedSince, 0)
+}
+
+// forward sends a message to the output channel of the tailer, and its copies to the additional outputs.
+func (t *Tailer) forward(msg *message.Message, outputMsgs []*message.Message) {
+	atomic.StoreInt64(&t.blockedSince, time.Now().UnixNano())
+	select {
+	case t.OutputChan <- msg:
+	case <-t.forwardContext.Done():
+	}
+	for i, o := range t.outputs {
+		o.forward(t.forwardContext, outputMsgs[i], t.File.Source.Name)
+	}
+	atomic.StoreInt64(&t.blockedSince, 0)
+}
+
+// CheckStuck returns why the tailer is stuck, and shows it on the status page until the next
+// check: when it has waited for longer than the timeout for its outputs to accept a message, or
+// when it has not read its file for longer than the timeout although the file grew. It returns
+// an empty string when the tailer is not stuck, which includes while it waits for the full input
+// of its decoder, as its pipeline is then only slower than its file.
+func (t *Tailer) CheckStuck(timeout time.Duration) string {
+	reason := ""
+	now := time.Now()
+	if atomic.LoadInt32(&t.decoderInputBlocked) == 1 {
+		// back pressure of the pipeline, a new tailer would be blocked as well
+	} else if blockedSince := atomic.LoadInt64(&t.blockedSince); blockedSince != 0 && now.Sub(time.Unix(0, blockedSince)) > timeout {
+		reason = fmt.Sprintf("blocked on its output for %v", now.Sub(time.Unix(0, blockedSince)).Round(time.Second))
+	} else if idle := now.Sub(time.Unix(0, atomic.LoadInt64(&t.lastProgress))); idle > timeout && !t.IsPaused() {
+		if lag, err := t.lag(); err == nil && lag > 0 {
+			reason = fmt.Sprintf("no data read for %v with %d bytes not read", idle.Round(time.Second), lag)
+		}
+	}
+	t.stuckReason.Store(reason)
+	return reason
+}
+
+// getInodeIdentifier returns the identifier of the file read by the tailer in the registry, next
+// to its path, empty if it is unknown.
+func (t *Tailer) getInodeIdentifier() string {
+	identifier, _ := t.inodeIdentifier.Load().(string)
+	return identifier
+}
+
+// fingerprint returns the fingerprint of the file identified by inodeIdentifier, computed once
+// the tailer has read enough of the file to fingerprint it.
+func (t *Tailer) fingerprint(inodeIdentifier string, offset int64) string {
+	if t.fingerprintedInode != inodeIdentifier {
+		t.fileFingerprint = ""
+		t.fingerprintedInode = inodeIdentifier
+	}
+	if t.fileFingerprint == "" && inodeIdentifier != "" && offset >= auditor.FingerprintSize {
+		t.fileFingerprint = auditor.Fingerprint(t.File.Path, inodeIdentifier)
+	}
+	return t.fileFingerprint
+}
+
+// recordProgress records that the tailer read its file or was not expected to.
+func (t *Tailer) recordProgress() {
+	atomic.StoreInt64(&t.lastProgress, time.Now().UnixNano())
+}
+
+// eventTime returns the time of the event of a message, parsed from its content, or the last
+// modification time of the file otherwise.
+func (t *Tailer) eventTime(output *decoder.Message) time.Time {
+	if !output.EventTimestamp.IsZero() {
+		return output.EventTimestamp
+	}
+	if now := time.Now(); now.Sub(t.modTimeCheck) >= modTimeRefreshPeriod {
+		t.modTimeCheck = now
+		if stat, err := os.Stat(t.fullpath); err == nil {
+			t.modTime = stat.ModTime()
 		}
 	}
+	return t.modTime
 }
 
 func (t *Tailer) incrementReadOffset(n int) {
@@ -254,11 +755,104 @@ func (t *Tailer) SetDecodedOffset(off int64) {
 	atomic.StoreInt64(&t.decodedOffset, off)
 }
 
+// GetDecodedOffset returns the position of the last byte decoded in the file
+func (t *Tailer) GetDecodedOffset() int64 {
+	return atomic.LoadInt64(&t.decodedOffset)
+}
+
+// InfoKey returns the key of the tailer info on the status page
+func (t *Tailer) InfoKey() string {
+	if t.hasFileRotated() {
+		return fmt.Sprintf("Tailer %s (rotated)", t.File.Path)
+	}
+	if t.hasFileBeenDeleted() {
+		return fmt.Sprintf("Tailer %s (deleted)", t.File.Path)
+	}
+	return fmt.Sprintf("Tailer %s", t.File.Path)
+}
+
+// Info returns the position of the tailer in its file, for the status page
+func (t *Tailer) Info() []string {
+	info := []string{
+		fmt.Sprintf("Read offset: %d", t.GetReadOffset()),
+		fmt.Sprintf("Decoded offset: %d", t.GetDecodedOffset()),
+		fmt.Sprintf("Bytes read: %d", atomic.LoadInt64(&t.bytesRead)),
+		fmt.Sprintf("Rotated: %t", t.hasFileRotated()),
+		fmt.Sprintf("Paused: %t", t.IsPaused()),
+	}
+	if t.oneShot {
+		info = append(info, fmt.Sprintf("Completed: %t", t.IsCompleted()))
+	}
+	if t.symlinkTarget != "" {
+		info = append(info, fmt.Sprintf("Symlink target: %s", t.symlinkTarget))
+	}
+	if t.hasFileBeenDeleted() {
+		info = append(info, "Deleted: true")
+	}
+	if pattern := t.GetDetectedPattern(); pattern != nil {
+		info = append(info, fmt.Sprintf("Multiline pattern: %s", pattern.String()))
+	}
+	if reason, _ := t.stuckReason.Load().(string); reason != "" {
+		info = append(info, fmt.Sprintf("Stuck: %s", reason))
+	}
+	if !t.hasFileRotated() && !t.hasFileBeenDeleted() {
+		if lag, err := t.lag(); err == nil {
+			info = append(info, fmt.Sprintf("Bytes lag: %d", lag))
+		}
+		// the messages of a rotated tailer share their identifier with the new tailer
+		if t.registry != nil {
+			if offset := t.registry.GetOffset(t.Identifier()); offset != "" {
+				info = append(info, fmt.Sprintf("Acknowledged offset: %s", offset))
+			}
+		}
+	}
+	return info
+}
+
+// SetScheduler makes the tailer take turns with the other tailers of the scheduler to read its file.
+func (t *Tailer) SetScheduler(scheduler *Scheduler) {
+	t.scheduler = scheduler
+}
+
+// AddOutput makes the tailer send its messages to an additional output,
+// it must be called before the tailer is started.
+func (t *Tailer) AddOutput(output Output) {
+	t.outputs = append(t.outputs, output)
+}
+
+// SetRegistry sets the registry the tailer reports its acknowledged offset from.
+func (t *Tailer) SetRegistry(registry auditor.Registry) {
+	t.registry = registry
+}
+
 // GetDetectedPattern returns a regexp if a pattern was detected
 func (t *Tailer) GetDetectedPattern() *regexp.Regexp {
+	t.decoderLock.Lock()
+	defer t.decoderLock.Unlock()
 	return t.decoder.GetDetectedPattern()
 }
 
+// SetDecoderFactory sets the function building the decoder of the file for the encoding detected
+// once data is written to it, when the file is empty when the tailer starts.
+func (t *Tailer) SetDecoderFactory(factory func(encoding string) *decoder.Decoder) {
+	t.decoderFactory = factory
+}
+
+// fileHasTruncated causes subsequent calls to hasFileTruncated to return true. The messages
+// still decoded from the content of the file before its truncation are handled like the ones
+// of a rotated file, their offsets are not committed.
+func (t *Tailer) fileHasTruncated() {
+	atomic.StoreInt32(&t.didFileTruncate, 1)
+	t.File.Source.UnregisterInfo(t)
+	t.fileHasRotated()
+	t.File.Source.RegisterInfo(t)
+}
+
+// hasFileTruncated returns true if the file has been found truncated in place.
+func (t *Tailer) hasFileTruncated() bool {
+	return atomic.LoadInt32(&t.didFileTruncate) != 0
+}
+
 // fileHasRotated causes subsequent calls to hasFileRotated to return true.
 func (t *Tailer) fileHasRotated() {
 	atomic.StoreInt32(&t.didFileRotate, 1)
@@ -270,15 +864,58 @@ func (t *Tailer) hasFileRotated() bool {
 	return atomic.LoadInt32(&t.didFileRotate) != 0
 }
 
-// wait lets the tailer sleep for a bit
+// fileHasBeenDeleted causes subsequent calls to hasFileBeenDeleted to return true.
+func (t *Tailer) fileHasBeenDeleted() {
+	atomic.StoreInt32(&t.didFileGetDeleted, 1)
+}
+
+// hasFileBeenDeleted returns true if the file has been deleted while the tailer was reading it.
+func (t *Tailer) hasFileBeenDeleted() bool {
+	return atomic.LoadInt32(&t.didFileGetDeleted) != 0
+}
+
+// wait lets the tailer sleep for a bit, a bit longer each time while the file is idle
 func (t *Tailer) wait() {
-	time.Sleep(t.sleepDuration)
+	time.Sleep(t.idleSleepDuration)
+	t.idleSleepDuration *= 2
+	if t.idleSleepDuration > t.maxSleepDuration {
+		t.idleSleepDuration = t.maxSleepDuration
+	}
 }
 
 func (t *Tailer) recordBytes(n int64) {
-	t.bytesRead += n
+	atomic.AddInt64(&t.bytesRead, n)
 	t.File.Source.BytesRead.Add(n)
 	if t.File.Source.ParentSource != nil {
 		t.File.Source.ParentSource.BytesRead.Add(n)
 	}
 }
+
+// recordLag updates the bytes lag metric of the tailer, at most once per lagUpdatePeriod.
+func (t *Tailer) recordLag() {
+	if time.Since(t.lastLagUpdate) < lagUpdatePeriod || t.hasFileRotated() || t.hasFileBeenDeleted() {
+		// the path of a rotated or deleted file does not refer to the file read by this tailer
+		return
+	}
+	t.lastLagUpdate = time.Now()
+	lag, err := t.lag()
+	if err != nil {
+		log.Debugf("Could not compute the bytes lag of %s: %v", t.File.Path, err)
+		return
+	}
+	metrics.TlmTailerBytesLag.Set(float64(lag), t.File.Source.Name, t.File.Path)
+}
+
+// lag returns the number of bytes of the file not read yet.
+func (t *Tailer) lag() (int64, error) {
+	stat, err := os.Stat(t.fullpath)
+	if err != nil {
+		return 0, err
+	}
+	lag := stat.Size() - t.GetReadOffset()
+	if lag < 0 {
+		// the file has been truncated
+		return 0, nil
+	}
+	return lag, nil
+}
@@ -12,7 +12,9 @@ import (
 	"io"
 	"path/filepath"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -23,6 +25,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 		return err
 	}
 	t.fullpath = fullpath
+	t.resolveSymlinkTarget()
 
 	// adds metadata to enable users to filter logs by filename
 	t.tags = t.buildTailerTags()
@@ -34,6 +37,9 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	}
 
 	t.osFile = f
+	if info, err := f.Stat(); err == nil {
+		t.inodeIdentifier.Store(auditor.InodeIdentifier(info))
+	}
 	ret, _ := f.Seek(offset, whence)
 	t.readOffset = ret
 	t.decodedOffset = ret
@@ -44,18 +50,62 @@ func (t *Tailer) setup(offset int64, whence int) error {
 // read lets the tailer tail the content of a file
 // until it is closed or the tailer is stopped.
 func (t *Tailer) read() (int, error) {
+	if t.hasFileTruncated() {
+		// the content read from the stale offset would not be the next lines of the file
+		return 0, nil
+	}
 	// keep reading data from file
 	inBuf := make([]byte, 4096)
-	n, err := t.osFile.Read(inBuf)
+	n, err := t.readFile(inBuf)
 	if err != nil && err != io.EOF {
 		// an unexpected error occurred, stop the tailor
 		t.File.Source.Status.Error(err)
 		return 0, log.Error("Unexpected error occurred while reading file: ", err)
 	}
 	if n == 0 {
-		return 0, nil
+		return 0, t.checkTruncation()
 	}
-	t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+	t.sendInput(decoder.NewInput(inBuf[:n]))
 	t.incrementReadOffset(n)
 	return n, nil
 }
+
+// readFile reads the file from the read offset, with io_uring when enabled. It falls back to
+// read system calls when the reads of the ring fail, e.g. on files not supporting them.
+func (t *Tailer) readFile(buf []byte) (int, error) {
+	if t.uring == nil {
+		return t.osFile.Read(buf)
+	}
+	n, err := t.uring.read(t.osFile, buf, t.GetReadOffset())
+	if err == nil {
+		if n == 0 {
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+	log.Infof("Could not read %s with io_uring, falling back to read system calls: %v", t.File.Path, err)
+	t.uring = nil
+	// the ring reads at explicit offsets, so the position of the file has not moved since setup,
+	// the files which can not be seeked (e.g. pipes) are read from their current position
+	_, _ = t.osFile.Seek(t.GetReadOffset(), io.SeekStart)
+	return t.osFile.Read(buf)
+}
+
+// checkTruncation stops reading the file when it is smaller than the read offset, which happens
+// when it is truncated in place (e.g. by a copytruncate rotation), so that the next writes are
+// not read from a stale offset. The launcher then replaces the tailer with one reading the file
+// from its beginning, as for a rotation, while the decoder flushes the previous content.
+func (t *Tailer) checkTruncation() error {
+	stat, err := t.osFile.Stat()
+	if err != nil {
+		// the file will be handled by the launcher
+		return nil
+	}
+	if stat.Size() >= t.GetReadOffset() {
+		return nil
+	}
+	log.Infof("File %s has been truncated, it will be read again from the beginning", t.File.Path)
+	t.fileHasTruncated()
+	metrics.TlmTruncatedFiles.Inc(t.File.Source.Name)
+	return nil
+}
@@ -15,14 +15,19 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	"path/filepath"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	logsAuditor "github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -108,6 +113,385 @@ func (suite *TailerTestSuite) TestTialerTimeDurationConfig() {
 	tailer.Stop()
 }
 
+func (suite *TailerTestSuite) TestSourceCloseTimeout() {
+	// To satisfy the suite level tailer
+	suite.tailer.StartFromBeginning()
+
+	coreConfig.Datadog.Set("logs_config.close_timeout", 42)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: suite.testPath, CloseTimeout: 300})
+	tailer := NewTailer(suite.outputChan, NewFile(suite.testPath, source, false), 10*time.Millisecond, decoder.NewDecoderFromSource(source))
+
+	suite.Equal(300*time.Second, tailer.closeTimeout)
+}
+
+func (suite *TailerTestSuite) TestLag() {
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+
+	err = suite.tailer.setup(0, io.SeekStart)
+	suite.Nil(err)
+	lag, err := suite.tailer.lag()
+	suite.Nil(err)
+	suite.Equal(int64(len("hello world\n")), lag)
+
+	suite.tailer.SetReadOffset(int64(len("hello ")))
+	lag, err = suite.tailer.lag()
+	suite.Nil(err)
+	suite.Equal(int64(len("world\n")), lag)
+
+	// the lag is never negative, e.g. when the file has been truncated
+	suite.tailer.SetReadOffset(100)
+	lag, err = suite.tailer.lag()
+	suite.Nil(err)
+	suite.Equal(int64(0), lag)
+
+	// To satisfy the suite level tailer
+	suite.tailer.osFile.Close()
+	suite.tailer.StartFromBeginning()
+}
+
+func (suite *TailerTestSuite) TestInfo() {
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+
+	suite.Nil(suite.tailer.StartFromBeginning())
+	<-suite.outputChan
+
+	expected := []string{
+		"Read offset: 12",
+		"Decoded offset: 12",
+		"Bytes read: 12",
+		"Rotated: false",
+		"Paused: false",
+		"Bytes lag: 0",
+	}
+	// the bytes read are recorded right after the data is sent to the decoder
+	suite.Eventually(func() bool {
+		return assert.ObjectsAreEqual(expected, suite.source.GetInfoStatus()["Tailer "+suite.testPath])
+	}, time.Second, 10*time.Millisecond)
+
+	suite.tailer.StopAfterFileRotation()
+	info := suite.source.GetInfoStatus()
+	suite.NotContains(info, "Tailer "+suite.testPath)
+	suite.Contains(info, "Tailer "+suite.testPath+" (rotated)")
+}
+
+func (suite *TailerTestSuite) TestInfoAcknowledgedOffset() {
+	registry := auditor.NewRegistry()
+	suite.tailer.SetRegistry(registry)
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	// nothing has been delivered yet
+	suite.NotContains(suite.tailer.Info(), "Acknowledged offset: 12")
+
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	<-suite.outputChan
+
+	// the offset is acknowledged once the auditor has committed the delivered message
+	registry.SetOffset("12")
+	suite.Contains(suite.tailer.Info(), "Acknowledged offset: 12")
+}
+
+func (suite *TailerTestSuite) TestCheckStuckWhenNotReading() {
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	suite.Nil(suite.tailer.setup(0, io.SeekStart))
+
+	// the tailer has never read its file although it has data
+	suite.True(strings.HasPrefix(suite.tailer.CheckStuck(time.Minute), "no data read for"))
+	suite.Contains(strings.Join(suite.tailer.Info(), "\n"), "Stuck: no data read for")
+
+	suite.tailer.recordProgress()
+	suite.Equal("", suite.tailer.CheckStuck(time.Minute))
+	suite.NotContains(strings.Join(suite.tailer.Info(), "\n"), "Stuck")
+
+	// a tailer waiting for its full decoder is slowed down by its pipeline
+	atomic.StoreInt64(&suite.tailer.lastProgress, 0)
+	atomic.StoreInt32(&suite.tailer.decoderInputBlocked, 1)
+	suite.Equal("", suite.tailer.CheckStuck(time.Minute))
+	atomic.StoreInt32(&suite.tailer.decoderInputBlocked, 0)
+
+	// a paused tailer is not expected to read its file
+	suite.tailer.Pause()
+	suite.Equal("", suite.tailer.CheckStuck(time.Minute))
+
+	// To satisfy the suite level tailer
+	suite.tailer.Resume()
+	suite.tailer.osFile.Close()
+	suite.tailer.StartFromBeginning()
+}
+
+func (suite *TailerTestSuite) TestCheckStuckWhenBlockedOnOutput() {
+	// write more messages than the output channel capacity
+	for i := 0; i < chanSize+2; i++ {
+		_, err := suite.testFile.WriteString(fmt.Sprintf("line %d\n", i))
+		suite.Nil(err)
+	}
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	suite.Eventually(func() bool {
+		return strings.HasPrefix(suite.tailer.CheckStuck(10*time.Millisecond), "blocked on its output")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the tailer stops without waiting for its output
+	suite.tailer.StopNow()
+	select {
+	case <-suite.tailer.done:
+	case <-time.After(5 * time.Second):
+		suite.Fail("timeout")
+	}
+}
+
+func (suite *TailerTestSuite) TestOneShot() {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: suite.testPath, OneShot: true})
+	tailer := NewTailer(suite.outputChan, NewFile(suite.testPath, source, false), 10*time.Millisecond, decoder.NewDecoderFromSource(source))
+	tailer.completionWindow = time.Second
+	_, err := suite.testFile.WriteString("hello\n")
+	suite.Nil(err)
+	suite.Nil(tailer.StartFromBeginning())
+
+	// the file is not completed while it is still written within the completion window,
+	// and its last line is not terminated
+	time.Sleep(100 * time.Millisecond)
+	_, err = suite.testFile.WriteString("world\n\n")
+	suite.Nil(err)
+
+	msg := <-suite.outputChan
+	suite.Equal("hello", string(msg.Content))
+	suite.False(msg.Origin.Completed)
+	msg = <-suite.outputChan
+	suite.Equal("world", string(msg.Content))
+	suite.False(msg.Origin.Completed)
+
+	// a completion event is sent once, after all the messages of the file, and records the
+	// completion against the inode of the file
+	msg = <-suite.outputChan
+	suite.Equal(fmt.Sprintf("Finished reading %s (13 bytes)", suite.testPath), string(msg.Content))
+	suite.Contains(msg.Origin.Tags(), completedTag)
+	suite.True(msg.Origin.Completed)
+	suite.NotEmpty(msg.Origin.InodeIdentifier)
+	suite.Equal("13", msg.Origin.Offset)
+
+	// the tailer stops by itself
+	select {
+	case <-tailer.done:
+	case <-time.After(5 * time.Second):
+		suite.Fail("timeout")
+	}
+	suite.Len(suite.outputChan, 0)
+	suite.True(tailer.IsCompleted())
+	suite.Contains(tailer.Info(), "Completed: true")
+
+	// To satisfy the suite level tailer
+	suite.tailer.StartFromBeginning()
+}
+
+func (suite *TailerTestSuite) TestScheduledTailer() {
+	scheduler := NewScheduler(16)
+	suite.tailer.SetScheduler(scheduler)
+
+	// the file is larger than the read budget of a turn
+	line := strings.Repeat("a", 4095) + "\n"
+	for i := 0; i < 3; i++ {
+		_, err := suite.testFile.WriteString(line)
+		suite.Nil(err)
+	}
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	for i := 0; i < 3; i++ {
+		msg := <-suite.outputChan
+		suite.Equal(4095, len(msg.Content))
+	}
+
+	// the tailer releases its turn between its reads
+	stop := make(chan struct{}, 1)
+	suite.True(scheduler.acquire("", stop))
+	scheduler.release()
+}
+
+func (suite *TailerTestSuite) TestScheduledTailerBlockedByItsPipeline() {
+	scheduler := NewScheduler(16)
+	suite.tailer.SetScheduler(scheduler)
+
+	// write more messages than the pipeline can hold, nothing reads the output channel
+	for i := 0; i < 100*chanSize; i++ {
+		_, err := suite.testFile.WriteString(fmt.Sprintf("line %d\n", i))
+		suite.Nil(err)
+	}
+	suite.Nil(suite.tailer.StartFromBeginning())
+	suite.Eventually(func() bool {
+		return len(suite.outputChan) == chanSize
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the tailer blocked by its pipeline does not hold the turn of the other tailers
+	acquired := make(chan bool)
+	go func() {
+		acquired <- scheduler.acquire("", make(chan struct{}))
+	}()
+	select {
+	case ok := <-acquired:
+		suite.True(ok)
+		scheduler.release()
+	case <-time.After(5 * time.Second):
+		suite.Fail("the turn is held by the blocked tailer")
+	}
+
+	suite.tailer.StopNow()
+	select {
+	case <-suite.tailer.done:
+	case <-time.After(5 * time.Second):
+		suite.Fail("timeout")
+	}
+}
+
+func (suite *TailerTestSuite) TestStopAfterFileDeletion() {
+	suite.tailer.deletionGracePeriod = 100 * time.Millisecond
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	<-suite.outputChan
+
+	suite.Nil(os.Remove(suite.testPath))
+	suite.tailer.StopAfterFileDeletion()
+	suite.Contains(suite.source.GetInfoStatus(), "Tailer "+suite.testPath+" (deleted)")
+
+	// the file is read until the end of the grace period
+	_, err = suite.testFile.WriteString("hello again\n")
+	suite.Nil(err)
+	msg := <-suite.outputChan
+	suite.Equal("hello again", string(msg.Content))
+	// the offsets of a deleted file are not committed
+	suite.Equal("", msg.Origin.Identifier)
+
+	suite.Eventually(suite.tailer.IsFinished, time.Second, 10*time.Millisecond)
+}
+
+func (suite *TailerTestSuite) TestOutputs() {
+	analysisChan := make(chan *message.Message, chanSize)
+	suite.tailer.AddOutput(Output{Channel: analysisChan})
+	// nothing reads this output, its messages are dropped without holding back the tailer
+	suite.tailer.AddOutput(Output{Channel: make(chan *message.Message), DropWhenFull: true})
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	_, err := suite.testFile.WriteString("hello world\nhello again\n")
+	suite.Nil(err)
+
+	for _, content := range []string{"hello world", "hello again"} {
+		msg := <-suite.outputChan
+		analysisMsg := <-analysisChan
+		suite.Equal(content, string(msg.Content))
+		suite.Equal(content, string(analysisMsg.Content))
+		// each output owns its content and origin
+		msg.Content[0] = 'H'
+		suite.Equal(content, string(analysisMsg.Content))
+		suite.NotSame(msg.Origin, analysisMsg.Origin)
+		msg.Origin.SetService("changed")
+		suite.Equal("", analysisMsg.Origin.Service())
+		suite.Equal(msg.Origin.Tags(), analysisMsg.Origin.Tags())
+	}
+}
+
+func (suite *TailerTestSuite) TestPauseAndResume() {
+	suite.tailer.Pause()
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+
+	select {
+	case <-suite.outputChan:
+		suite.Fail("a paused tailer should not read its file")
+	case <-time.After(100 * time.Millisecond):
+	}
+	suite.Equal(int64(0), suite.tailer.GetReadOffset())
+
+	suite.tailer.Resume()
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+}
+
+func (suite *TailerTestSuite) TestTruncation() {
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+
+	suite.Nil(suite.tailer.StartFromBeginning())
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+
+	// truncate the file in place like a copytruncate rotation
+	suite.Nil(suite.testFile.Truncate(0))
+	_, err = suite.testFile.Seek(0, io.SeekStart)
+	suite.Nil(err)
+	_, err = suite.testFile.WriteString("bye\n")
+	suite.Nil(err)
+
+	// the tailer stops reading the file until it is replaced by the launcher
+	suite.Eventually(func() bool {
+		didRotate, err := suite.tailer.DidRotate()
+		return err == nil && didRotate
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	suite.Len(suite.outputChan, 0)
+	suite.Equal(int64(12), suite.tailer.GetReadOffset())
+	suite.Contains(suite.tailer.Info(), "Rotated: true")
+}
+
+func (suite *TailerTestSuite) TestIdleSleepDurationBackoff() {
+	// To satisfy the suite level tailer
+	suite.tailer.StartFromBeginning()
+
+	coreConfig.Datadog.Set("logs_config.file_tailer_max_sleep_duration", 0.004)
+	defer coreConfig.Datadog.Set("logs_config.file_tailer_max_sleep_duration", 5.0)
+	tailer := NewTailer(suite.outputChan, NewFile(suite.testPath, suite.source, false), time.Millisecond, decoder.NewDecoderFromSource(suite.source))
+
+	suite.Equal(time.Millisecond, tailer.idleSleepDuration)
+	tailer.wait()
+	suite.Equal(2*time.Millisecond, tailer.idleSleepDuration)
+	tailer.wait()
+	suite.Equal(4*time.Millisecond, tailer.idleSleepDuration)
+	tailer.wait()
+	suite.Equal(4*time.Millisecond, tailer.idleSleepDuration)
+
+	// the max sleep duration can not be lower than the sleep duration
+	coreConfig.Datadog.Set("logs_config.file_tailer_max_sleep_duration", 0)
+	tailer = NewTailer(suite.outputChan, NewFile(suite.testPath, suite.source, false), time.Millisecond, decoder.NewDecoderFromSource(suite.source))
+	tailer.wait()
+	suite.Equal(time.Millisecond, tailer.idleSleepDuration)
+}
+
+func (suite *TailerTestSuite) TestSymlinkTargetChange() {
+	// To satisfy the suite level tailer
+	suite.tailer.StartFromBeginning()
+
+	linkPath := fmt.Sprintf("%s/current.log", suite.testDir)
+	newTargetPath := fmt.Sprintf("%s/next.log", suite.testDir)
+	suite.Nil(os.Symlink(suite.testPath, linkPath))
+	defer os.Remove(linkPath)
+	suite.Nil(ioutil.WriteFile(newTargetPath, []byte("hello world\n"), 0644))
+	defer os.Remove(newTargetPath)
+
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: linkPath})
+	tailer := NewTailer(suite.outputChan, NewFile(linkPath, source, false), 10*time.Millisecond, decoder.NewDecoderFromSource(source))
+	suite.Nil(tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	testPath, err := filepath.EvalSymlinks(suite.testPath)
+	suite.Nil(err)
+	suite.Equal(testPath, tailer.symlinkTarget)
+	didRotate, err := tailer.DidRotate()
+	suite.Nil(err)
+	suite.False(didRotate)
+
+	// point the link to another file, bigger than the current one
+	suite.Nil(os.Remove(linkPath))
+	suite.Nil(os.Symlink(newTargetPath, linkPath))
+	didRotate, err = tailer.DidRotate()
+	suite.Nil(err)
+	suite.True(didRotate)
+}
+
 func (suite *TailerTestSuite) TestTailFromBeginning() {
 	lines := []string{"hello world\n", "hello again\n", "good bye\n"}
 
@@ -139,6 +523,11 @@ func (suite *TailerTestSuite) TestTailFromBeginning() {
 	suite.Equal(len(lines[0])+len(lines[1])+len(lines[2]), toInt(msg.Origin.Offset))
 
 	suite.Equal(len(lines[0])+len(lines[1])+len(lines[2]), int(suite.tailer.decodedOffset))
+
+	// the offsets are also registered under the device and inode numbers of the file
+	info, err := os.Stat(suite.testPath)
+	suite.Nil(err)
+	suite.Equal(logsAuditor.InodeIdentifier(info), msg.Origin.InodeIdentifier)
 }
 
 func (suite *TailerTestSuite) TestTailFromEnd() {
@@ -297,6 +686,56 @@ func (suite *TailerTestSuite) TestBuildTagsFileDir() {
 	suite.Equal("dirname:"+filepath.Dir(suite.testFile.Name()), tags[1])
 }
 
+func (suite *TailerTestSuite) TestBuildTagsFromPath() {
+	pathTaggedSource := config.NewLogSource("", &config.LogsConfig{
+		Type:            config.FileType,
+		Path:            suite.testPath,
+		PathTagsPattern: `/(?P<app>log-tailer-test-[^/]+)/(?P<name>[^/]+)\.log$`,
+	})
+	suite.Nil(pathTaggedSource.Config.Validate())
+	sleepDuration := 10 * time.Millisecond
+	suite.tailer = NewTailer(suite.outputChan, NewFile(suite.testPath, pathTaggedSource, true), sleepDuration, decoder.NewDecoderFromSource(suite.source))
+	suite.tailer.StartFromBeginning()
+
+	tags := suite.tailer.buildTailerTags()
+	suite.Equal([]string{"app:" + filepath.Base(suite.testDir), "name:tailer"}, tags)
+
+	// fallback to the default tags when the pattern does not match
+	pathTaggedSource.Config.PathTagsPattern = `/var/log/(?P<app>[^/]+)/.*\.log$`
+	suite.Nil(pathTaggedSource.Config.Validate())
+	tags = suite.tailer.buildTailerTags()
+	suite.Equal(2, len(tags))
+	suite.Equal("filename:"+filepath.Base(suite.testFile.Name()), tags[0])
+	suite.Equal("dirname:"+filepath.Dir(suite.testFile.Name()), tags[1])
+}
+
+func (suite *TailerTestSuite) TestSidecarTags() {
+	coreConfig.Datadog.Set("logs_config.sidecar_tags_pattern", "{path}.ddtags")
+	defer coreConfig.Datadog.Set("logs_config.sidecar_tags_pattern", "")
+	suite.Nil(ioutil.WriteFile(suite.testPath+".ddtags", []byte("# labels of the job\njob:backup, team:storage\n\nrun:42\n"), 0644))
+
+	suite.tailer = NewTailer(suite.outputChan, NewFile(suite.testPath, suite.source, false), 10*time.Millisecond, decoder.NewDecoderFromSource(suite.source))
+	suite.tailer.StartFromBeginning()
+
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+
+	msg := <-suite.outputChan
+	suite.Subset(msg.Origin.Tags(), []string{"job:backup", "team:storage", "run:42"})
+}
+
+func (suite *TailerTestSuite) TestEventTime() {
+	suite.tailer.StartFromBeginning()
+
+	parsed := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+	suite.Equal(parsed, suite.tailer.eventTime(&decoder.Message{EventTimestamp: parsed}))
+
+	// the modification time of the file is used when no timestamp was parsed
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	suite.Nil(os.Chtimes(suite.testPath, modTime, modTime))
+	suite.True(modTime.Equal(suite.tailer.eventTime(&decoder.Message{})))
+}
+
 func (suite *TailerTestSuite) TestMutliLineAutoDetect() {
 	lines := "Jul 12, 2021 12:55:15 PM test message 1\n"
 	lines += "Jul 12, 2021 12:55:15 PM test message 2\n"
@@ -325,6 +764,53 @@ func (suite *TailerTestSuite) TestMutliLineAutoDetect() {
 
 	expectedRegex := regexp.MustCompile(`^[A-Za-z_]+ \d+, \d+ \d+:\d+:\d+ (AM|PM)`)
 	suite.Equal(suite.tailer.GetDetectedPattern(), expectedRegex)
+	suite.Contains(suite.tailer.Info(), "Multiline pattern: "+expectedRegex.String())
+
+	// the detected pattern is sent to the registry along with the offsets
+	_, err = suite.testFile.WriteString(lines)
+	suite.Nil(err)
+
+	msg := <-suite.outputChan
+	suite.Equal(expectedRegex.String(), msg.Origin.MultiLinePattern)
+}
+
+func (suite *TailerTestSuite) TestDetectedEncoding() {
+	// To satisfy the suite level tailer
+	suite.tailer.StartFromBeginning()
+
+	file := NewFile(suite.testPath, suite.source, false)
+	suite.Equal("", file.Encoding())
+
+	_, err := suite.testFile.Write([]byte{0xFF, 0xFE, 'h', 0x0, 'i', 0x0, '\n', 0x0})
+	suite.Nil(err)
+	suite.Equal(config.UTF16LE, file.Encoding())
+
+	coreConfig.Datadog.Set("logs_config.auto_encoding_detection", false)
+	defer coreConfig.Datadog.Set("logs_config.auto_encoding_detection", true)
+	suite.Equal("", file.Encoding())
+
+	suite.source.Config.Encoding = config.SHIFTJIS
+	suite.Equal(config.SHIFTJIS, file.Encoding())
+}
+
+func (suite *TailerTestSuite) TestEncodingDetectedOnceFileIsWritten() {
+	tailer := NewTailer(suite.outputChan, NewFile(suite.testPath, suite.source, false), 10*time.Millisecond, decoder.NewDecoderFromSource(suite.source))
+	tailer.SetDecoderFactory(func(encoding string) *decoder.Decoder {
+		return decoder.NewDecoderFromSourceWithEncoding(suite.source, encoding, nil)
+	})
+	suite.Nil(tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	// the file is empty when the tailer starts, its encoding is detected from its first data
+	time.Sleep(50 * time.Millisecond)
+	_, err := suite.testFile.Write([]byte{0xFF, 0xFE, 'h', 0x0, 'i', 0x0, '\n', 0x0})
+	suite.Nil(err)
+
+	msg := <-suite.outputChan
+	suite.Equal("hi", string(msg.Content))
+
+	// To satisfy the suite level tailer
+	suite.tailer.StartFromBeginning()
 }
 
 func toInt(str string) int {
@@ -9,11 +9,16 @@
 package file
 
 import (
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 
+	"golang.org/x/sys/windows"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -24,6 +29,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 		return err
 	}
 	t.fullpath = fullpath
+	t.resolveSymlinkTarget()
 
 	// adds metadata to enable users to filter logs by filename
 	t.tags = t.buildTailerTags()
@@ -34,6 +40,8 @@ func (t *Tailer) setup(offset int64, whence int) error {
 		return err
 	}
 	filePos, _ := f.Seek(offset, whence)
+	t.fileInfo, _ = f.Stat()
+	t.inodeIdentifier.Store(auditor.InodeIdentifier(t.fileInfo))
 	f.Close()
 
 	t.readOffset = filePos
@@ -57,7 +65,16 @@ func (t *Tailer) readAvailable() (int, error) {
 
 	sz := st.Size()
 	offset := t.GetReadOffset()
-	if sz == 0 {
+	if t.fileInfo != nil && !os.SameFile(t.fileInfo, st) {
+		// the file has been replaced, e.g. renamed and recreated or with ReplaceFile,
+		// the old file can not be read anymore as it is not kept open.
+		log.Infof("File %s has been replaced, reading the new file from the beginning", t.File.Path)
+		t.SetReadOffset(0)
+		t.SetDecodedOffset(0)
+		// the new file is only reported replaced once
+		t.fileInfo = st
+		t.inodeIdentifier.Store(auditor.InodeIdentifier(st))
+	} else if sz == 0 {
 		log.Debug("File size now zero, resetting offset")
 		t.SetReadOffset(0)
 		t.SetDecodedOffset(0)
@@ -65,6 +82,7 @@ func (t *Tailer) readAvailable() (int, error) {
 		log.Debug("Offset off end of file, resetting")
 		t.SetReadOffset(0)
 		t.SetDecodedOffset(0)
+		metrics.TlmTruncatedFiles.Inc(t.File.Source.Name)
 	}
 	f.Seek(t.GetReadOffset(), io.SeekStart)
 	bytes := 0
@@ -76,8 +94,12 @@ func (t *Tailer) readAvailable() (int, error) {
 		if n == 0 || err != nil {
 			return bytes, err
 		}
-		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+		t.sendInput(decoder.NewInput(inBuf[:n]))
 		t.incrementReadOffset(n)
+		if t.scheduler != nil && bytes >= t.scheduler.budget {
+			// the rest of the file is read during the next turns
+			return bytes, nil
+		}
 	}
 }
 
@@ -88,9 +110,23 @@ func (t *Tailer) read() (int, error) {
 	n, err := t.readAvailable()
 	if err == io.EOF || os.IsNotExist(err) {
 		return n, nil
+	} else if isTransientOpenError(err) {
+		// the file will be read again after the tailer has waited for new data
+		log.Debugf("Could not open %s, retrying later: %v", t.File.Path, err)
+		return n, nil
 	} else if err != nil {
 		t.File.Source.Status.Error(err)
 		return n, log.Error("Err: ", err)
 	}
 	return n, nil
 }
+
+// isTransientOpenError returns true if the file could not be opened because it is locked
+// by its writer, or because it is being deleted (e.g. during a rotation) while other
+// handles are still open on it. Access denied errors are not transient, as they are
+// also returned for permanent permission failures.
+func isTransientOpenError(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) ||
+		errors.Is(err, windows.ERROR_LOCK_VIOLATION) ||
+		errors.Is(err, windows.ERROR_DELETE_PENDING)
+}
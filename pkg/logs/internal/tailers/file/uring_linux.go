@@ -0,0 +1,343 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// uringEntries is the number of reads the ring can batch in a single submission
+	uringEntries = 64
+	// uringBufferSize is the size of the buffer of each entry of the ring, a read returns at most
+	// this number of bytes
+	uringBufferSize = 4096
+
+	uringOpRead            = 22 // IORING_OP_READ, available since linux 5.6
+	uringEnterGetEvents    = 1  // IORING_ENTER_GETEVENTS
+	uringOffSQRing         = 0
+	uringOffCQRing         = 0x8000000
+	uringOffSQEs           = 0x10000000
+	uringSQESize           = 64
+	uringCQESize           = 16
+	uringSubmissionIndexes = 4
+)
+
+// uringSQRingOffsets mirrors struct io_sqring_offsets.
+type uringSQRingOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	resv2                                                           uint64
+}
+
+// uringCQRingOffsets mirrors struct io_cqring_offsets.
+type uringCQRingOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	resv2                                                           uint64
+}
+
+// uringParams mirrors struct io_uring_params.
+type uringParams struct {
+	sqEntries, cqEntries, flags, sqThreadCPU, sqThreadIdle, features, wqFd uint32
+	resv                                                                   [3]uint32
+	sqOff                                                                  uringSQRingOffsets
+	cqOff                                                                  uringCQRingOffsets
+}
+
+// uringSQE mirrors struct io_uring_sqe for a read.
+type uringSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFdIn  int32
+	pad         [2]uint64
+}
+
+// uringCQE mirrors struct io_uring_cqe.
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// uringRead is a read submitted to the ring.
+type uringRead struct {
+	fd     int
+	buf    []byte
+	offset int64
+	result chan uringResult
+}
+
+// uringResult is the outcome of a read.
+type uringResult struct {
+	n   int
+	err error
+}
+
+// uring reads the files of the tailers with io_uring, batching the reads of all the tailers
+// in a single system call to cut the syscall overhead on hosts tailing many busy files.
+// The kernel writes the data into buffers mapped outside of the Go heap, one per entry, which
+// are copied into the buffers of the tailers once the reads complete, so that a read completed
+// late by the kernel can never write into memory reused by the Go runtime.
+type uring struct {
+	fd    int
+	reads chan *uringRead
+
+	sqRing  []byte
+	cqRing  []byte
+	sqes    []byte
+	buffers []byte
+
+	// generation identifies the reads of the current batch in the user data of the entries,
+	// the completions of the reads of previous batches are ignored
+	generation uint32
+	// err is set when the ring can not be used anymore, the reads then fail with it
+	err error
+
+	sqHead, sqTail, sqMask, sqArray unsafe.Pointer
+	cqHead, cqTail, cqMask, cqes    unsafe.Pointer
+	entries                         uint32
+}
+
+var (
+	sharedURingOnce sync.Once
+	sharedURingInst *uring
+)
+
+// sharedURing returns the ring shared by all the tailers, or nil when io_uring reads are not
+// supported by the kernel, in which case the tailers read their files with read system calls.
+func sharedURing() *uring {
+	sharedURingOnce.Do(func() {
+		r, err := newURing(uringEntries)
+		if err != nil {
+			log.Infof("Could not set up io_uring to read the files, falling back to read system calls: %v", err)
+			return
+		}
+		sharedURingInst = r
+		go r.run()
+	})
+	return sharedURingInst
+}
+
+// newURing sets up a ring, it returns an error when the kernel does not support io_uring reads.
+func newURing(entries uint32) (*uring, error) {
+	var params uringParams
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&params)), 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	r := &uring{fd: int(fd), reads: make(chan *uringRead, entries), entries: params.sqEntries}
+
+	var err error
+	sqRingSize := int(params.sqOff.array + params.sqEntries*uringSubmissionIndexes)
+	if r.sqRing, err = unix.Mmap(r.fd, uringOffSQRing, sqRingSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		r.close()
+		return nil, err
+	}
+	cqRingSize := int(params.cqOff.cqes + params.cqEntries*uringCQESize)
+	if r.cqRing, err = unix.Mmap(r.fd, uringOffCQRing, cqRingSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.sqes, err = unix.Mmap(r.fd, uringOffSQEs, int(params.sqEntries*uringSQESize), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.buffers, err = unix.Mmap(-1, 0, int(params.sqEntries*uringBufferSize), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS); err != nil {
+		r.close()
+		return nil, err
+	}
+	r.sqHead = unsafe.Pointer(&r.sqRing[params.sqOff.head])
+	r.sqTail = unsafe.Pointer(&r.sqRing[params.sqOff.tail])
+	r.sqMask = unsafe.Pointer(&r.sqRing[params.sqOff.ringMask])
+	r.sqArray = unsafe.Pointer(&r.sqRing[params.sqOff.array])
+	r.cqHead = unsafe.Pointer(&r.cqRing[params.cqOff.head])
+	r.cqTail = unsafe.Pointer(&r.cqRing[params.cqOff.tail])
+	r.cqMask = unsafe.Pointer(&r.cqRing[params.cqOff.ringMask])
+	r.cqes = unsafe.Pointer(&r.cqRing[params.cqOff.cqes])
+
+	// the read operation is only supported since linux 5.6
+	if err := r.probe(); err != nil {
+		r.close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// probe checks that the kernel supports the read operation.
+func (r *uring) probe() error {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	results := r.submit([]*uringRead{{fd: int(f.Fd()), buf: make([]byte, 1)}})
+	if results[0].err != nil {
+		return fmt.Errorf("io_uring reads are not supported: %v", results[0].err)
+	}
+	return nil
+}
+
+// read reads the file from the offset into the buffer, it returns 0 at the end of the file.
+func (r *uring) read(f *os.File, buf []byte, offset int64) (int, error) {
+	read := &uringRead{fd: int(f.Fd()), buf: buf, offset: offset, result: make(chan uringResult, 1)}
+	r.reads <- read
+	result := <-read.result
+	return result.n, result.err
+}
+
+// run submits the reads of the tailers in batches, the reads requested while a batch is
+// being processed are submitted together in the next one.
+func (r *uring) run() {
+	batch := make([]*uringRead, 0, r.entries)
+	for read := range r.reads {
+		batch = append(batch[:0], read)
+	collect:
+		for len(batch) < int(r.entries) {
+			select {
+			case read := <-r.reads:
+				batch = append(batch, read)
+			default:
+				break collect
+			}
+		}
+		for i, result := range r.submit(batch) {
+			batch[i].result <- result
+		}
+	}
+}
+
+// submit submits the reads, at most one per entry of the ring, and waits for their completions.
+func (r *uring) submit(reads []*uringRead) []uringResult {
+	results := make([]uringResult, len(reads))
+	if r.err != nil {
+		for i := range results {
+			results[i].err = r.err
+		}
+		return results
+	}
+	r.generation++
+	mask := atomic.LoadUint32((*uint32)(r.sqMask))
+	tail := atomic.LoadUint32((*uint32)(r.sqTail))
+	for i, read := range reads {
+		size := len(read.buf)
+		if size > uringBufferSize {
+			size = uringBufferSize
+		}
+		index := (tail + uint32(i)) & mask
+		sqe := (*uringSQE)(unsafe.Pointer(&r.sqes[index*uringSQESize]))
+		*sqe = uringSQE{
+			opcode:   uringOpRead,
+			fd:       int32(read.fd),
+			off:      uint64(read.offset),
+			addr:     uint64(uintptr(unsafe.Pointer(&r.buffers[i*uringBufferSize]))),
+			len:      uint32(size),
+			userData: uint64(r.generation)<<32 | uint64(i),
+		}
+		*(*uint32)(unsafe.Add(r.sqArray, index*uringSubmissionIndexes)) = index
+	}
+	atomic.StoreUint32((*uint32)(r.sqTail), tail+uint32(len(reads)))
+
+	toSubmit := len(reads)
+	completed := 0
+	for completed < len(reads) {
+		_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(toSubmit), uintptr(len(reads)-completed), uringEnterGetEvents, 0, 0)
+		if errno != 0 && errno != syscall.EINTR {
+			// the reads the kernel did not consume are withdrawn from the ring, the ones it
+			// consumed may still complete and are waited for before the buffers are reused
+			submitted := int(atomic.LoadUint32((*uint32)(r.sqHead)) - tail)
+			atomic.StoreUint32((*uint32)(r.sqTail), tail+uint32(submitted))
+			for i := submitted; i < len(reads); i++ {
+				results[i].err = errno
+			}
+			completed += r.reap(results)
+			r.drain(results, submitted-completed)
+			break
+		}
+		if errno == 0 {
+			toSubmit = 0
+		}
+		completed += r.reap(results)
+	}
+	for i, read := range reads {
+		if results[i].err == nil {
+			copy(read.buf, r.buffers[i*uringBufferSize:i*uringBufferSize+results[i].n])
+		}
+	}
+	return results
+}
+
+// drain waits for the completions of the reads still in flight after a failed submission. The
+// ring is not used anymore when they can not be waited for, as the kernel may still write into
+// the buffers.
+func (r *uring) drain(results []uringResult, inFlight int) {
+	for inFlight > 0 {
+		_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), 0, uintptr(inFlight), uringEnterGetEvents, 0, 0)
+		if errno != 0 && errno != syscall.EINTR {
+			r.err = fmt.Errorf("could not wait for the reads of the ring: %v", errno)
+			for i := range results {
+				if results[i].err == nil && results[i].n == 0 {
+					results[i].err = r.err
+				}
+			}
+			return
+		}
+		inFlight -= r.reap(results)
+	}
+}
+
+// reap records the results of the completed reads of the current batch, it returns their number.
+func (r *uring) reap(results []uringResult) int {
+	head := atomic.LoadUint32((*uint32)(r.cqHead))
+	tail := atomic.LoadUint32((*uint32)(r.cqTail))
+	mask := atomic.LoadUint32((*uint32)(r.cqMask))
+	reaped := 0
+	for ; head != tail; head++ {
+		cqe := (*uringCQE)(unsafe.Add(r.cqes, (head&mask)*uringCQESize))
+		if uint32(cqe.userData>>32) != r.generation {
+			// completed after its batch gave up waiting for it
+			continue
+		}
+		i := uint32(cqe.userData)
+		if cqe.res < 0 {
+			results[i].err = syscall.Errno(-cqe.res)
+		} else {
+			results[i].n = int(cqe.res)
+		}
+		reaped++
+	}
+	atomic.StoreUint32((*uint32)(r.cqHead), head)
+	return reaped
+}
+
+// close releases the ring.
+func (r *uring) close() {
+	for _, mapping := range [][]byte{r.buffers, r.sqes, r.cqRing, r.sqRing} {
+		if mapping != nil {
+			_ = unix.Munmap(mapping)
+		}
+	}
+	_ = unix.Close(r.fd)
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// openBenchmarkFiles opens files of 64KB, read concurrently as busy tailed files.
+func openBenchmarkFiles(b *testing.B, count int) []*os.File {
+	dir := b.TempDir()
+	content := make([]byte, 64*1024)
+	for i := range content {
+		content[i] = byte('a' + i%26)
+	}
+	files := make([]*os.File, count)
+	for i := range files {
+		path := filepath.Join(dir, fmt.Sprintf("%d.log", i))
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			b.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { f.Close() })
+		files[i] = f
+	}
+	return files
+}
+
+func benchmarkRead(b *testing.B, read func(f *os.File, buf []byte, offset int64) (int, error)) {
+	files := openBenchmarkFiles(b, 64)
+	var next int64
+	b.ReportAllocs()
+	b.SetBytes(4096)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		buf := make([]byte, 4096)
+		f := files[atomic.AddInt64(&next, 1)%int64(len(files))]
+		offset := int64(0)
+		for pb.Next() {
+			n, err := read(f, buf, offset)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			offset = (offset + int64(n)) % (64 * 1024)
+		}
+	})
+}
+
+func BenchmarkReadSyscalls(b *testing.B) {
+	benchmarkRead(b, func(f *os.File, buf []byte, offset int64) (int, error) {
+		return f.ReadAt(buf, offset)
+	})
+}
+
+func BenchmarkReadURing(b *testing.B) {
+	r, err := newURing(uringEntries)
+	if err != nil {
+		b.Skipf("io_uring reads are not supported: %v", err)
+	}
+	go r.run()
+	benchmarkRead(b, r.read)
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newTestURing(t *testing.T) *uring {
+	r, err := newURing(4)
+	if err != nil {
+		t.Skipf("io_uring reads are not supported: %v", err)
+	}
+	go r.run()
+	return r
+}
+
+func TestURingBatchesReads(t *testing.T) {
+	r := newTestURing(t)
+	dir := t.TempDir()
+
+	// more concurrent reads than entries in the ring
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.log", i))
+		content := fmt.Sprintf("content of file %d\n", i)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 64)
+			n, err := r.read(f, buf, 8)
+			assert.NoError(t, err)
+			assert.Equal(t, content[8:], string(buf[:n]))
+
+			n, err = r.read(f, buf, int64(len(content)))
+			assert.NoError(t, err)
+			assert.Equal(t, 0, n)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTailerReadsWithURing(t *testing.T) {
+	r := newTestURing(t)
+	path := filepath.Join(t.TempDir(), "tailer.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\nsecond\n"), 0644))
+
+	outputChan := make(chan *message.Message, 10)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	tailer := NewTailer(outputChan, NewFile(path, source, false), 10*time.Millisecond, decoder.NewDecoderFromSource(source))
+	tailer.uring = r
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	assert.Equal(t, "first", string((<-outputChan).Content))
+	assert.Equal(t, "second", string((<-outputChan).Content))
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString("third\n")
+	require.NoError(t, err)
+	assert.Equal(t, "third", string((<-outputChan).Content))
+	assert.Equal(t, int64(len("first\nsecond\nthird\n")), tailer.GetReadOffset())
+}
+
+func TestURingReadsAtMostItsBufferSize(t *testing.T) {
+	r := newTestURing(t)
+	path := filepath.Join(t.TempDir(), "large.log")
+	content := make([]byte, 2*uringBufferSize)
+	for i := range content {
+		content[i] = byte('a' + i%26)
+	}
+	require.NoError(t, ioutil.WriteFile(path, content, 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	buf := make([]byte, len(content))
+	n, err := r.read(f, buf, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uringBufferSize, n)
+	assert.Equal(t, content[1:1+n], buf[:n])
+}
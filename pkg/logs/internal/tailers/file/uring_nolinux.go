@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !linux
+// +build !linux
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+// uring is not available on this platform, the tailers read their files with read system calls.
+type uring struct{}
+
+// sharedURing returns nil as io_uring is only available on linux.
+func sharedURing() *uring {
+	return nil
+}
+
+func (r *uring) read(f *os.File, buf []byte, offset int64) (int, error) {
+	return 0, errors.New("io_uring is only available on linux")
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 )
 
 // Tailer reads data from a net.Conn.  It uses a `read` callback to be generic
@@ -64,7 +65,15 @@ func (t *Tailer) forwardMessages() {
 	}()
 	for output := range t.decoder.OutputChan {
 		if len(output.Content) > 0 {
-			t.outputChan <- message.NewMessageWithSource(output.Content, message.StatusInfo, t.source, output.IngestionTimestamp)
+			origin := message.NewOrigin(t.source)
+			origin.SetTags(output.Tags)
+			msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+			msg.Timestamp = output.EventTimestamp
+			msg.Masked = output.Masked
+			if !output.EventTimestamp.IsZero() {
+				metrics.ReportE2ELatency(t.source.Name, output.EventTimestamp, output.IngestionTimestamp)
+			}
+			t.outputChan <- msg
 		}
 	}
 }
@@ -15,20 +15,34 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+const (
+	// spoolTimeout is the time after which the pipeline is considered unavailable and the
+	// traps are spooled
+	spoolTimeout = time.Second
+	// the spooled traps are replayed periodically by batches, so that the traps which are
+	// received in the meantime are still spooled
+	spoolReplayInterval  = time.Second
+	spoolReplayTimeout   = 100 * time.Millisecond
+	spoolReplayBatchSize = 1000
+)
+
 // Tailer consumes and processes a stream of trap packets, and sends them to a stream of log messages.
 type Tailer struct {
 	source     *config.LogSource
 	inputChan  traps.PacketsChannel
 	outputChan chan *message.Message
-	done       chan interface{}
+	// spool buffers the messages while the pipeline is unavailable, it is nil when it is disabled
+	spool *traps.Spool
+	done  chan interface{}
 }
 
 // NewTailer returns a new Tailer
-func NewTailer(source *config.LogSource, inputChan traps.PacketsChannel, outputChan chan *message.Message) *Tailer {
+func NewTailer(source *config.LogSource, inputChan traps.PacketsChannel, spool *traps.Spool, outputChan chan *message.Message) *Tailer {
 	return &Tailer{
 		source:     source,
 		inputChan:  inputChan,
 		outputChan: outputChan,
+		spool:      spool,
 		done:       make(chan interface{}, 1),
 	}
 }
@@ -48,21 +62,92 @@ func (t *Tailer) run() {
 		t.done <- true
 	}()
 
-	// Loop terminates when the channel is closed.
-	for packet := range t.inputChan {
-		data, err := traps.FormatPacketToJSON(packet)
-		if err != nil {
-			log.Errorf("failed to format packet: %s", err)
-			continue
+	var replay <-chan time.Time
+	if t.spool != nil {
+		ticker := time.NewTicker(spoolReplayInterval)
+		defer ticker.Stop()
+		replay = ticker.C
+	}
+
+	for {
+		select {
+		case packet, ok := <-t.inputChan:
+			// Loop terminates when the channel is closed, the spooled traps are replayed
+			// when the Agent is restarted.
+			if !ok {
+				return
+			}
+			t.forward(packet)
+		case <-replay:
+			t.replay()
+		}
+	}
+}
+
+// forward formats a packet and sends it to the pipeline, or to the spool while the pipeline
+// is unavailable.
+func (t *Tailer) forward(packet *traps.SnmpPacket) {
+	payload, err := traps.FormatPacketToJSON(packet)
+	if err != nil {
+		log.Errorf("failed to format packet: %s", err)
+		return
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("failed to serialize packet data to JSON: %s", err)
+		return
+	}
+	t.source.BytesRead.Add(int64(len(content)))
+	tags := traps.GetTags(packet)
+	msg := t.newMessage(content, tags)
+	if t.spool == nil {
+		t.outputChan <- msg
+		return
+	}
+
+	// the traps are spooled while there are spooled traps, so that they are sent in order
+	if t.spool.Len() == 0 {
+		select {
+		case t.outputChan <- msg:
+			return
+		case <-time.After(spoolTimeout):
+		}
+	}
+	trap := traps.SpooledTrap{Content: content, Tags: tags, Timestamp: time.Now()}
+	if err := t.spool.Push(trap); err != nil {
+		log.Warnf("Could not spool the trap, waiting for the pipeline: %v", err)
+		t.outputChan <- msg
+	}
+}
+
+// replay sends a batch of spooled traps to the pipeline, until it is unavailable.
+func (t *Tailer) replay() {
+	if t.spool.Len() == 0 {
+		return
+	}
+	sent := 0
+	t.spool.Replay(func(trap traps.SpooledTrap) bool {
+		if sent == spoolReplayBatchSize {
+			return false
 		}
-		t.source.BytesRead.Add(int64(len(data)))
-		content, err := json.Marshal(data)
-		if err != nil {
-			log.Errorf("failed to serialize packet data to JSON: %s", err)
-			continue
+		msg := t.newMessage(trap.Content, trap.Tags)
+		// the replayed traps are timestamped with the time they were received
+		msg.Timestamp = trap.Timestamp
+		select {
+		case t.outputChan <- msg:
+			sent++
+			return true
+		case <-time.After(spoolReplayTimeout):
+			return false
 		}
-		origin := message.NewOrigin(t.source)
-		origin.SetTags(traps.GetTags(packet))
-		t.outputChan <- message.NewMessage(content, origin, message.StatusInfo, time.Now().UnixNano())
+	})
+	if sent > 0 {
+		log.Debugf("Replayed %d spooled traps", sent)
 	}
 }
+
+func (t *Tailer) newMessage(content []byte, tags []string) *message.Message {
+	origin := message.NewOrigin(t.source)
+	origin.SetTags(tags)
+	return message.NewMessage(content, origin, message.StatusInfo, time.Now().UnixNano())
+}
@@ -23,7 +23,7 @@ import (
 func TestTrapsShouldReceiveMessages(t *testing.T) {
 	inputChan := make(traps.PacketsChannel, 1)
 	outputChan := make(chan *message.Message)
-	tailer := NewTailer(config.NewLogSource("test", &config.LogsConfig{}), inputChan, outputChan)
+	tailer := NewTailer(config.NewLogSource("test", &config.LogsConfig{}), inputChan, nil, outputChan)
 	tailer.Start()
 
 	p := &traps.SnmpPacket{
@@ -55,9 +55,48 @@ func TestTrapsShouldReceiveMessages(t *testing.T) {
 }
 
 func format(t *testing.T, p *traps.SnmpPacket) []byte {
-	data, err := traps.FormatPacketToJSON(p)
+	payload, err := traps.FormatPacketToJSON(p)
 	assert.NoError(t, err)
-	content, err := json.Marshal(data)
+	content, err := json.Marshal(payload)
 	assert.NoError(t, err)
 	return content
 }
+
+func TestTrapsShouldBeSpooledWhilePipelineIsBlocked(t *testing.T) {
+	spool, err := traps.NewSpool(t.TempDir(), 100000)
+	assert.NoError(t, err)
+	inputChan := make(traps.PacketsChannel, 2)
+	outputChan := make(chan *message.Message)
+	tailer := NewTailer(config.NewLogSource("test", &config.LogsConfig{}), inputChan, spool, outputChan)
+	tailer.Start()
+
+	p := &traps.SnmpPacket{
+		Content: &gosnmp.SnmpPacket{
+			Version:   gosnmp.Version2c,
+			Community: "public",
+			Variables: traps.NetSNMPExampleHeartbeatNotification.Variables,
+		},
+		Addr: &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1620},
+	}
+
+	// the output channel is not read, the traps are spooled
+	inputChan <- p
+	inputChan <- p
+	assert.Eventually(t, func() bool { return spool.Len() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// the spooled traps are replayed once the output channel is read
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-outputChan:
+			assert.Equal(t, format(t, p), msg.Content)
+			assert.Equal(t, traps.GetTags(p), msg.Origin.Tags())
+			assert.False(t, msg.Timestamp.IsZero())
+		case <-time.After(5 * time.Second):
+			t.Fatal("Message not replayed")
+		}
+	}
+	assert.Equal(t, 0, spool.Len())
+
+	close(inputChan)
+	tailer.WaitFlush()
+}
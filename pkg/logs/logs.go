@@ -210,6 +210,23 @@ func GetStatus() status.Status {
 	return status.Get()
 }
 
+// PauseFile stops reading the file with the given path until ResumeFile is called, its
+// tailers keep their offsets. It returns an error if the file is not tailed.
+func PauseFile(path string) error {
+	if agent == nil || agent.fileLauncher == nil || !IsAgentRunning() {
+		return errors.New("the logs agent is not running")
+	}
+	return agent.fileLauncher.Pause(path)
+}
+
+// ResumeFile resumes reading the file with the given path after a call to PauseFile.
+func ResumeFile(path string) error {
+	if agent == nil || agent.fileLauncher == nil || !IsAgentRunning() {
+		return errors.New("the logs agent is not running")
+	}
+	return agent.fileLauncher.Resume(path)
+}
+
 // GetMessageReceiver returns the diagnostic message receiver
 func GetMessageReceiver() *diagnostic.BufferedMessageReceiver {
 	if agent == nil {
@@ -23,3 +23,8 @@ func TestBuildEndpoints(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "agent-intake.logs.datadoghq.com", endpoints.Main.Host)
 }
+
+func TestPauseFileWithoutAgent(t *testing.T) {
+	assert.NotNil(t, PauseFile("/var/log/app.log"))
+	assert.NotNil(t, ResumeFile("/var/log/app.log"))
+}
@@ -7,6 +7,7 @@ package message
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
@@ -32,11 +33,17 @@ type Message struct {
 	status             string
 	IngestionTimestamp int64
 	// Optional. Must be UTC. If not provided, time.Now().UTC() will be used
-	// Used in the Serverless Agent
+	// Used in the Serverless Agent, and for the timestamps parsed from the logs content
 	Timestamp time.Time
 	// Optional.
 	// Used in the Serverless Agent
 	Lambda *Lambda
+	// Optional.
+	// Attributes are sent next to the content, e.g. the correlation ID of the chunks of a long line
+	Attributes map[string]string
+	// Optional.
+	// Masked is true when the masking rules of the source were already applied by its decoder
+	Masked bool
 }
 
 // Lambda is a struct storing information about the Lambda function and function execution.
@@ -84,6 +91,20 @@ func (m *Message) GetStatus() string {
 	return m.status
 }
 
+// AttributesAsTags returns the attributes of the message as sorted tags, for the formats
+// which can not hold attributes.
+func (m *Message) AttributesAsTags() []string {
+	if len(m.Attributes) == 0 {
+		return nil
+	}
+	tags := make([]string, 0, len(m.Attributes))
+	for key, value := range m.Attributes {
+		tags = append(tags, key+":"+value)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
 // GetLatency returns the latency delta from ingestion time until now
 func (m *Message) GetLatency() int64 {
 	return time.Now().UnixNano() - m.IngestionTimestamp
@@ -16,9 +16,19 @@ type Origin struct {
 	Identifier string
 	LogSource  *config.LogSource
 	Offset     string
-	service    string
-	source     string
-	tags       []string
+	// InodeIdentifier identifies the file of the origin by its device and inode numbers,
+	// the offset is registered under both identifiers.
+	InodeIdentifier string
+	// Fingerprint is the checksum of the first bytes of the file of the origin, registered
+	// with its inode identifier to detect the reuse of the inode by a new file.
+	Fingerprint string
+	// MultiLinePattern is the multiline pattern auto-detected for the content of the origin
+	MultiLinePattern string
+	// Completed is true for the event sent once the file of the origin has been read to completion
+	Completed bool
+	service   string
+	source    string
+	tags      []string
 }
 
 // NewOrigin returns a new Origin
@@ -35,6 +45,11 @@ func (o *Origin) Tags() []string {
 
 // TagsPayload returns the raw tag payload of the origin.
 func (o *Origin) TagsPayload() []byte {
+	return o.TagsPayloadWith(nil)
+}
+
+// TagsPayloadWith returns the raw tag payload of the origin, with additional tags.
+func (o *Origin) TagsPayloadWith(extraTags []string) []byte {
 	var tagsPayload []byte
 
 	source := o.Source()
@@ -49,6 +64,7 @@ func (o *Origin) TagsPayload() []byte {
 	var tags []string
 	tags = append(tags, o.LogSource.Config.Tags...)
 	tags = append(tags, o.tags...)
+	tags = append(tags, extraTags...)
 
 	if len(tags) > 0 {
 		tagsPayload = append(tagsPayload, []byte("[dd ddtags=\""+strings.Join(tags, ",")+"\"]")...)
@@ -48,3 +48,24 @@ func StatusToSeverity(status string) []byte {
 	}
 	return SevInfo
 }
+
+// statusLevelMapping maps the statuses to their syslog levels, from the most to the least severe.
+var statusLevelMapping = map[string]int{
+	StatusEmergency: 0,
+	StatusAlert:     1,
+	StatusCritical:  2,
+	StatusError:     3,
+	StatusWarning:   4,
+	StatusNotice:    5,
+	StatusInfo:      6,
+	StatusDebug:     7,
+}
+
+// StatusLevel returns the syslog level of a status, from 0 for the most severe status
+// to 7 for the least severe one. Unknown statuses are at the info level.
+func StatusLevel(status string) int {
+	if level, exists := statusLevelMapping[status]; exists {
+		return level
+	}
+	return statusLevelMapping[StatusInfo]
+}
@@ -33,3 +33,14 @@ func TestStatusToSeverity(t *testing.T) {
 	// default value should be "info"
 	assert.Equal(t, 0, bytes.Compare(SevInfo, StatusToSeverity("foo")))
 }
+
+func TestStatusLevel(t *testing.T) {
+	assert.Equal(t, 0, StatusLevel(StatusEmergency))
+	assert.Equal(t, 3, StatusLevel(StatusError))
+	assert.Equal(t, 7, StatusLevel(StatusDebug))
+	assert.True(t, StatusLevel(StatusWarning) < StatusLevel(StatusInfo))
+
+	// default value should be "info"
+	assert.Equal(t, StatusLevel(StatusInfo), StatusLevel(""))
+	assert.Equal(t, StatusLevel(StatusInfo), StatusLevel("foo"))
+}
@@ -7,6 +7,7 @@ package metrics
 
 import (
 	"expvar"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 )
@@ -58,6 +59,54 @@ var (
 		nil, "Histogram of http sender latency in ms", []float64{10, 25, 50, 75, 100, 250, 500, 1000, 10000})
 	// DestinationExpVars a map of sender utilization metrics for each http destination
 	DestinationExpVars = expvar.Map{}
+	// LogsRateLimited is the total number of logs dropped by the rate limits of the sources
+	LogsRateLimited = expvar.Int{}
+	// TlmLogsRateLimited is the total number of logs dropped by the rate limits of the sources
+	TlmLogsRateLimited = telemetry.NewCounter("logs", "rate_limited",
+		[]string{"source"}, "Total number of logs dropped by the rate limits of the sources")
+	// LogsSampledOut is the total number of logs dropped by the sampling rules of the sources
+	LogsSampledOut = expvar.Int{}
+	// TlmLogsSampledOut is the total number of logs dropped by the sampling rules of the sources
+	TlmLogsSampledOut = telemetry.NewCounter("logs", "sampled_out",
+		[]string{"source"}, "Total number of logs dropped by the sampling rules of the sources")
+	// LogsFilteredBySeverity is the total number of logs dropped by the minimum severities of the sources
+	LogsFilteredBySeverity = expvar.Int{}
+	// TlmLogsFilteredBySeverity is the total number of logs dropped by the minimum severities of the sources
+	TlmLogsFilteredBySeverity = telemetry.NewCounter("logs", "filtered_by_severity",
+		[]string{"source"}, "Total number of logs dropped by the minimum severities of the sources")
+	// TlmTailerBytesLag is the number of bytes of the tailed files not read yet, per tailed file
+	TlmTailerBytesLag = telemetry.NewGauge("logs", "tailer_bytes_lag",
+		[]string{"source", "path"}, "Number of bytes of the tailed files not read yet")
+	// TlmTruncatedFiles is the total number of truncations of the tailed files detected by their tailers
+	TlmTruncatedFiles = telemetry.NewCounter("logs", "truncated_files",
+		[]string{"source"}, "Total number of truncations of the tailed files, e.g. by a copytruncate rotation")
+	// TlmDeletedFiles is the total number of tailed files deleted while their tailers were reading them
+	TlmDeletedFiles = telemetry.NewCounter("logs", "deleted_files",
+		[]string{"source"}, "Total number of tailed files deleted while their tailers were reading them")
+	// TlmFilesCompleted is the total number of files read to completion by the tailers in one-shot mode
+	TlmFilesCompleted = telemetry.NewCounter("logs", "files_completed",
+		[]string{"source"}, "Total number of files read to completion by the file tailers in one-shot mode")
+	// TlmTailersWaitingTurn is the number of file tailers waiting for their turn to read their files
+	TlmTailersWaitingTurn = telemetry.NewGauge("logs", "tailers_waiting_turn",
+		nil, "Number of file tailers waiting for their turn to read their files")
+	// TlmTailersStarved is the total number of turns the file tailers waited for longer than a second
+	TlmTailersStarved = telemetry.NewCounter("logs", "tailers_starved",
+		[]string{"source"}, "Total number of turns the file tailers waited for longer than a second")
+	// TlmOutputDropped is the total number of messages dropped by the additional outputs of the tailers
+	TlmOutputDropped = telemetry.NewCounter("logs", "output_dropped",
+		[]string{"source"}, "Total number of messages dropped by the additional outputs of the tailers when they were full")
+	// TlmStuckTailers is the number of file tailers found stuck by the last check of the file launcher
+	TlmStuckTailers = telemetry.NewGauge("logs", "stuck_tailers",
+		nil, "Number of file tailers not making progress although their files grew or blocked on their outputs")
+	// TlmStuckTailersRestarted is the total number of stuck file tailers restarted by the file launcher
+	TlmStuckTailersRestarted = telemetry.NewCounter("logs", "stuck_tailers_restarted",
+		[]string{"source"}, "Total number of stuck file tailers restarted")
+	// E2ELatency is the last reported time (ms) between the events of the logs and their collection, per source
+	E2ELatency = expvar.Map{}
+	// TlmE2ELatency a histogram of the time (ms) between the events of the logs and their collection, per source
+	TlmE2ELatency = telemetry.NewHistogram("logs", "e2e_latency",
+		[]string{"source"}, "Histogram of the time in ms between the events of the logs, parsed from their content or the modification time of their file, and their collection",
+		[]float64{100, 500, 1000, 5000, 10000, 60000, 300000, 900000, 3600000})
 	// TODO: Add LogsCollected for the total number of collected logs.
 
 )
@@ -73,4 +122,26 @@ func init() {
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
 	LogsExpvars.Set("HttpDestinationStats", &DestinationExpVars)
+	LogsExpvars.Set("LogsRateLimited", &LogsRateLimited)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
+	LogsExpvars.Set("LogsFilteredBySeverity", &LogsFilteredBySeverity)
+	LogsExpvars.Set("E2ELatency", &E2ELatency)
+}
+
+// ReportE2ELatency reports the time between the event of a log and its collection, given as the
+// ingestion timestamp of the log in nanoseconds, separately from the time it takes to send it.
+func ReportE2ELatency(sourceName string, eventTime time.Time, ingestionTimestamp int64) {
+	latency := (ingestionTimestamp - eventTime.UnixNano()) / int64(time.Millisecond)
+	if latency < 0 {
+		// the clocks of the event and the agent are not synchronized
+		latency = 0
+	}
+	TlmE2ELatency.Observe(float64(latency), sourceName)
+	if v, ok := E2ELatency.Get(sourceName).(*expvar.Int); ok {
+		v.Set(latency)
+		return
+	}
+	v := &expvar.Int{}
+	v.Set(latency)
+	E2ELatency.Set(sourceName, v)
 }
@@ -7,10 +7,23 @@ package metrics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMetrics(t *testing.T) {
-	assert.Equal(t, LogsExpvars.String(), `{"BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "HttpDestinationStats": {}, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "SenderLatency": 0}`)
+	assert.Equal(t, LogsExpvars.String(), `{"BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "E2ELatency": {}, "EncodedBytesSent": 0, "HttpDestinationStats": {}, "LogsDecoded": 0, "LogsFilteredBySeverity": 0, "LogsProcessed": 0, "LogsRateLimited": 0, "LogsSampledOut": 0, "LogsSent": 0, "SenderLatency": 0}`)
+}
+
+func TestReportE2ELatency(t *testing.T) {
+	defer E2ELatency.Init()
+	eventTime := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+	ReportE2ELatency("source", eventTime, eventTime.Add(1500*time.Millisecond).UnixNano())
+	assert.Equal(t, `{"source": 1500}`, E2ELatency.String())
+
+	ReportE2ELatency("source", eventTime, eventTime.Add(200*time.Millisecond).UnixNano())
+	ReportE2ELatency("other", eventTime, eventTime.Add(-time.Second).UnixNano())
+	assert.Equal(t, `{"other": 0, "source": 200}`, E2ELatency.String())
 }
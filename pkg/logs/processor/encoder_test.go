@@ -221,6 +221,54 @@ func TestJsonEncoder(t *testing.T) {
 	assert.NotEmpty(t, log.Timestamp)
 }
 
+func TestEncodersSendAttributes(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Tags: []string{"foo:bar"}})
+	msg := newMessage([]byte("message"), source, message.StatusInfo)
+	msg.Attributes = map[string]string{"chunk_id": "0123456789abcdef", "ddtags": "ignored"}
+
+	// the JSON payloads hold the attributes next to the content
+	encoded, err := JSONEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	var payload map[string]interface{}
+	assert.Nil(t, json.Unmarshal(encoded, &payload))
+	assert.Equal(t, "0123456789abcdef", payload["chunk_id"])
+	assert.Equal(t, "redacted", payload["message"])
+	assert.Equal(t, "foo:bar", payload["ddtags"])
+
+	// the other formats send them as tags
+	encoded, err = ProtoEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	log := &pb.Log{}
+	assert.Nil(t, log.Unmarshal(encoded))
+	assert.Equal(t, []string{"foo:bar", "chunk_id:0123456789abcdef", "ddtags:ignored"}, log.Tags)
+
+	encoded, err = RawEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(encoded), `[dd ddtags="foo:bar,chunk_id:0123456789abcdef,ddtags:ignored"]`)
+}
+
+func TestEncodersUseMessageTimestamp(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, "")
+	msg.Timestamp = time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+	encoded, err := JSONEncoder.Encode(msg, msg.Content)
+	assert.Nil(t, err)
+	jsonLog := &jsonPayload{}
+	assert.Nil(t, json.Unmarshal(encoded, jsonLog))
+	assert.Equal(t, msg.Timestamp.UnixNano()/nanoToMillis, jsonLog.Timestamp)
+
+	encoded, err = ProtoEncoder.Encode(msg, msg.Content)
+	assert.Nil(t, err)
+	protoLog := &pb.Log{}
+	assert.Nil(t, protoLog.Unmarshal(encoded))
+	assert.Equal(t, msg.Timestamp.UnixNano(), protoLog.Timestamp)
+
+	encoded, err = RawEncoder.Encode(msg, msg.Content)
+	assert.Nil(t, err)
+	assert.Contains(t, string(encoded), "2021-03-04T05:06:07.000000000Z")
+}
+
 func TestEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a�z", toValidUtf8([]byte("a\xfez")))
 	assert.Equal(t, "a��z", toValidUtf8([]byte("a\xc0\xafz")))
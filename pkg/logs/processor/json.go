@@ -31,13 +31,24 @@ type jsonPayload struct {
 	Tags      string `json:"ddtags"`
 }
 
+// reservedJSONAttributes are the names of the fields of the JSON payload.
+var reservedJSONAttributes = map[string]bool{
+	"message":   true,
+	"status":    true,
+	"timestamp": true,
+	"hostname":  true,
+	"service":   true,
+	"ddsource":  true,
+	"ddtags":    true,
+}
+
 // Encode encodes a message into a JSON byte array.
 func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
 	ts := time.Now().UTC()
 	if !msg.Timestamp.IsZero() {
 		ts = msg.Timestamp
 	}
-	return json.Marshal(jsonPayload{
+	encoded, err := json.Marshal(jsonPayload{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
 		Timestamp: ts.UnixNano() / nanoToMillis,
@@ -46,4 +57,29 @@ func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 		Source:    msg.Origin.Source(),
 		Tags:      msg.Origin.TagsToString(),
 	})
+	if err != nil || len(msg.Attributes) == 0 {
+		return encoded, err
+	}
+	return withAttributes(encoded, msg.Attributes)
+}
+
+// withAttributes adds the attributes of a message to its encoded JSON payload, as top-level
+// attributes next to its content. The attributes named like the fields of the payload are ignored.
+func withAttributes(encoded []byte, attributes map[string]string) ([]byte, error) {
+	extra := make(map[string]string, len(attributes))
+	for key, value := range attributes {
+		if !reservedJSONAttributes[key] {
+			extra[key] = value
+		}
+	}
+	if len(extra) == 0 {
+		return encoded, nil
+	}
+	encodedAttributes, err := json.Marshal(extra)
+	if err != nil {
+		return nil, err
+	}
+	// merge the two JSON objects
+	merged := append(encoded[:len(encoded)-1], ',')
+	return append(merged, encodedAttributes[1:]...), nil
 }
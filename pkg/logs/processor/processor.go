@@ -108,7 +108,7 @@ func (p *Processor) processMessage(msg *message.Message) {
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 	content := msg.Content
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
-	for _, rule := range rules {
+	for i, rule := range rules {
 		switch rule.Type {
 		case config.ExcludeAtMatch:
 			if rule.Regex.Match(content) {
@@ -119,6 +119,10 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 				return false, nil
 			}
 		case config.MaskSequences:
+			// the masking rules of the source are applied by its decoder, if any
+			if msg.Masked && i >= len(p.processingRules) {
+				continue
+			}
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
 		}
 	}
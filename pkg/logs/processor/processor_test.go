@@ -126,6 +126,20 @@ func TestMask(t *testing.T) {
 	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte("New data added to data_values= on prod"), &source, ""))
 	assert.Equal(t, true, shouldProcess)
 	assert.Equal(t, []byte("New data added to data_values= on prod"), redactedMessage)
+
+	source = newSource("mask_sequences", "${key}=[masked_${key}]", "(?P<key>password|token)=\\S+")
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte("login with password=hunter2 and token=abcdef"), &source, ""))
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("login with password=[masked_password] and token=[masked_token]"), redactedMessage)
+
+	// the messages already masked by the decoder of the source are only masked by the global rules
+	p = &Processor{processingRules: []*config.ProcessingRule{newProcessingRule("mask_sequences", "[masked_token]", "token=\\S+")}}
+	source = newSource("mask_sequences", "[masked_password]", "password=\\S+")
+	msg := newMessage([]byte("login with password=[masked] and token=abcdef"), &source, "")
+	msg.Masked = true
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("login with password=[masked] and [masked_token]"), redactedMessage)
 }
 
 func TestTruncate(t *testing.T) {
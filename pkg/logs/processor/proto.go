@@ -20,13 +20,18 @@ type protoEncoder struct{}
 
 // Encode encodes a message into a protobuf byte array.
 func (p *protoEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	ts := time.Now().UTC()
+	if !msg.Timestamp.IsZero() {
+		ts = msg.Timestamp
+	}
 	return (&pb.Log{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
-		Timestamp: time.Now().UTC().UnixNano(),
+		Timestamp: ts.UnixNano(),
 		Hostname:  msg.GetHostname(),
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
-		Tags:      msg.Origin.Tags(),
+		// the protobuf payload can not hold attributes, they are sent as tags
+		Tags: append(msg.Origin.Tags(), msg.AttributesAsTags()...),
 	}).Marshal()
 }
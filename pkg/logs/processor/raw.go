@@ -35,7 +35,11 @@ func (r *rawEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, e
 		extraContent = append(extraContent, ' ')
 
 		// Timestamp
-		extraContent = time.Now().UTC().AppendFormat(extraContent, config.DateFormat)
+		ts := time.Now().UTC()
+		if !msg.Timestamp.IsZero() {
+			ts = msg.Timestamp
+		}
+		extraContent = ts.AppendFormat(extraContent, config.DateFormat)
 		extraContent = append(extraContent, ' ')
 
 		extraContent = append(extraContent, []byte(msg.GetHostname())...)
@@ -53,7 +57,8 @@ func (r *rawEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, e
 		extraContent = append(extraContent, []byte(" - - ")...)
 
 		// Tags
-		tagsPayload := msg.Origin.TagsPayload()
+		// the raw format can not hold attributes, they are sent as tags
+		tagsPayload := msg.Origin.TagsPayloadWith(msg.AttributesAsTags())
 		if len(tagsPayload) > 0 {
 			extraContent = append(extraContent, tagsPayload...)
 		} else {
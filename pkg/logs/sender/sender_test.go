@@ -82,6 +82,40 @@ func TestSenderSingleDestination(t *testing.T) {
 	sender.Stop()
 }
 
+func TestSenderOnlyAcknowledgesDeliveredPayloads(t *testing.T) {
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 1)
+
+	respondChan := make(chan int)
+	server := http.NewTestServerWithOptions(500, 0, true, respondChan)
+
+	destinations := client.NewDestinations([]client.Destination{server.Destination}, nil)
+
+	sender := NewSender(input, output, destinations, 10)
+	sender.Start()
+
+	input <- &message.Payload{}
+
+	// the payload is not forwarded to the auditor while the intake rejects it
+	assert.Equal(t, 500, <-respondChan)
+	select {
+	case <-output:
+		assert.Fail(t, "an undelivered payload should not be acknowledged")
+	default:
+	}
+
+	server.ChangeStatus(200)
+	for code := range respondChan {
+		if code == 200 {
+			break
+		}
+	}
+	<-output
+
+	server.Stop()
+	sender.Stop()
+}
+
 func TestSenderDualReliableDestination(t *testing.T) {
 	input := make(chan *message.Payload, 1)
 	output := make(chan *message.Payload, 1)
@@ -127,7 +127,7 @@ func (b *Builder) toString(status *config.LogStatus) string {
 		value = "Pending"
 	} else if status.IsSuccess() {
 		value = "OK"
-	} else if status.IsError() {
+	} else if status.IsError() || status.IsRetrying() {
 		value = status.GetError()
 	}
 	return value
@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package devices keeps the devices monitored by the SNMP integration, so that the other SNMP
+// components, e.g. the traps server, can tag their data like the metrics of the devices.
+package devices
+
+import (
+	"sync"
+)
+
+// Device is a device monitored by the SNMP integration.
+type Device struct {
+	Namespace string
+	IPAddress string
+	Profile   string
+	Vendor    string
+	// Tags are the tags of the metrics of the device
+	Tags []string
+}
+
+type deviceKey struct {
+	namespace string
+	ipAddress string
+}
+
+var (
+	devicesMu sync.RWMutex
+	devices   = make(map[deviceKey]Device)
+)
+
+// Register registers a device monitored by the SNMP integration, or updates it when it is
+// already registered.
+func Register(device Device) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	devices[deviceKey{namespace: device.Namespace, ipAddress: device.IPAddress}] = device
+}
+
+// Unregister unregisters a device which is no longer monitored.
+func Unregister(namespace string, ipAddress string) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	delete(devices, deviceKey{namespace: namespace, ipAddress: ipAddress})
+}
+
+// Get returns the device of a namespace with an IP address, if it is monitored.
+func Get(namespace string, ipAddress string) (Device, bool) {
+	devicesMu.RLock()
+	defer devicesMu.RUnlock()
+	device, ok := devices[deviceKey{namespace: namespace, ipAddress: ipAddress}]
+	return device, ok
+}
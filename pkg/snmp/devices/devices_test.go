@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package devices
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevices(t *testing.T) {
+	device := Device{Namespace: "default", IPAddress: "10.0.0.1", Profile: "cisco-nexus", Vendor: "cisco", Tags: []string{"snmp_profile:cisco-nexus"}}
+	Register(device)
+	defer Unregister("default", "10.0.0.1")
+
+	registered, ok := Get("default", "10.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, device, registered)
+
+	// the devices are identified by their namespace and their IP address
+	_, ok = Get("other", "10.0.0.1")
+	assert.False(t, ok)
+	_, ok = Get("default", "10.0.0.2")
+	assert.False(t, ok)
+
+	device.Tags = []string{"snmp_profile:cisco-nexus", "snmp_host:switch"}
+	Register(device)
+	registered, _ = Get("default", "10.0.0.1")
+	assert.Equal(t, device.Tags, registered.Tags)
+
+	Unregister("default", "10.0.0.1")
+	_, ok = Get("default", "10.0.0.1")
+	assert.False(t, ok)
+}
@@ -9,9 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"hash/fnv"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/common"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/gosnmplib"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/gosnmp/gosnmp"
 )
@@ -21,6 +26,26 @@ func IsEnabled() bool {
 	return config.Datadog.GetBool("snmp_traps_enabled")
 }
 
+// IsLogsOutputEnabled returns whether the traps are forwarded as logs, which requires the
+// logs Agent.
+func IsLogsOutputEnabled() bool {
+	if serverInstance != nil {
+		return serverInstance.getConfig().hasOutput(logsOutput)
+	}
+	var c Config
+	if err := config.Datadog.UnmarshalKey("snmp_traps_config", &c); err != nil {
+		return true
+	}
+	return c.hasOutput(logsOutput)
+}
+
+// The security levels of the SNMPv3 users, compared case-insensitively.
+const (
+	noAuthNoPrivLevel = "noauthnopriv"
+	authNoPrivLevel   = "authnopriv"
+	authPrivLevel     = "authpriv"
+)
+
 // UserV3 contains the definition of one SNMPv3 user with its username and its auth
 // parameters.
 type UserV3 struct {
@@ -29,18 +54,156 @@ type UserV3 struct {
 	AuthProtocol string `mapstructure:"authProtocol" yaml:"authProtocol"`
 	PrivKey      string `mapstructure:"privKey" yaml:"privKey"`
 	PrivProtocol string `mapstructure:"privProtocol" yaml:"privProtocol"`
+	// SecurityLevel is the security level of the traps of the user, the traps with another
+	// security level are rejected when it is set
+	SecurityLevel string `mapstructure:"securityLevel" yaml:"securityLevel"`
+}
+
+// msgFlags returns the security level of the user, the one given by its keys when it is not set.
+func (u UserV3) msgFlags() (gosnmp.SnmpV3MsgFlags, error) {
+	switch strings.ToLower(u.SecurityLevel) {
+	case "":
+		if u.PrivKey != "" {
+			return gosnmp.AuthPriv, nil
+		} else if u.AuthKey != "" {
+			return gosnmp.AuthNoPriv, nil
+		}
+		return gosnmp.NoAuthNoPriv, nil
+	case noAuthNoPrivLevel:
+		return gosnmp.NoAuthNoPriv, nil
+	case authNoPrivLevel:
+		if u.AuthKey == "" {
+			return 0, fmt.Errorf("security level %s requires an authKey", u.SecurityLevel)
+		}
+		return gosnmp.AuthNoPriv, nil
+	case authPrivLevel:
+		if u.AuthKey == "" || u.PrivKey == "" {
+			return 0, fmt.Errorf("security level %s requires an authKey and a privKey", u.SecurityLevel)
+		}
+		return gosnmp.AuthPriv, nil
+	default:
+		return 0, fmt.Errorf("unknown security level %q, expected noAuthNoPriv, authNoPriv or authPriv", u.SecurityLevel)
+	}
+}
+
+// formatSecurityLevel returns the name of the security level of v3 message flags.
+func formatSecurityLevel(flags gosnmp.SnmpV3MsgFlags) string {
+	switch flags & gosnmp.AuthPriv {
+	case gosnmp.AuthPriv:
+		return "authPriv"
+	case gosnmp.AuthNoPriv:
+		return "authNoPriv"
+	default:
+		return "noAuthNoPriv"
+	}
+}
+
+// ContextConfig contains the configuration of the traps sent from an SNMPv3 context, e.g.
+// a virtual routing instance of a device.
+type ContextConfig struct {
+	Name      string   `mapstructure:"name" yaml:"name"`
+	Namespace string   `mapstructure:"namespace" yaml:"namespace"`
+	Tags      []string `mapstructure:"tags" yaml:"tags"`
+}
+
+// ListenerConfig contains the configuration of an additional trap listener, its unset
+// options default to the ones of the main listener.
+type ListenerConfig struct {
+	Port             uint16   `mapstructure:"port" yaml:"port"`
+	BindHost         string   `mapstructure:"bind_host" yaml:"bind_host"`
+	Users            []UserV3 `mapstructure:"users" yaml:"users"`
+	CommunityStrings []string `mapstructure:"community_strings" yaml:"community_strings"`
+	Namespace        string   `mapstructure:"namespace" yaml:"namespace"`
+	Tags             []string `mapstructure:"tags" yaml:"tags"`
+}
+
+// BundleConfig contains the configuration of a traps DB bundle, a traps DB file fetched over
+// HTTPS and refreshed periodically.
+type BundleConfig struct {
+	URL string `mapstructure:"url" yaml:"url"`
+	// SHA256 is the checksum of the bundle, ChecksumURL the URL where it is published
+	SHA256          string `mapstructure:"sha256" yaml:"sha256"`
+	ChecksumURL     string `mapstructure:"checksum_url" yaml:"checksum_url"`
+	RefreshInterval int    `mapstructure:"refresh_interval" yaml:"refresh_interval"`
+}
+
+// TrapsDBSetConfig contains the configuration of the traps DB files of a device namespace,
+// e.g. the MIBs of a tenant, which override the other traps DB files for the traps received
+// in the namespace.
+type TrapsDBSetConfig struct {
+	Namespace string `mapstructure:"namespace" yaml:"namespace"`
+	// Dir is the directory of the files, relative to the traps_db directory unless it is absolute
+	Dir string `mapstructure:"dir" yaml:"dir"`
+}
+
+// RelayConfig contains the configuration of a downstream receiver the traps are relayed to.
+type RelayConfig struct {
+	Host string `mapstructure:"host" yaml:"host"`
+	Port uint16 `mapstructure:"port" yaml:"port"`
+	// Community rewrites the community of the v1 and v2c traps
+	Community string `mapstructure:"community" yaml:"community"`
+}
+
+// Addr returns the host:port address of the receiver.
+func (c RelayConfig) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port)))
+}
+
+// CorrelationConfig contains the configuration of a pair of traps raising and clearing the same
+// condition, e.g. linkDown and linkUp.
+type CorrelationConfig struct {
+	RaiseOID string `mapstructure:"raise_oid" yaml:"raise_oid"`
+	ClearOID string `mapstructure:"clear_oid" yaml:"clear_oid"`
+	// MatchVariables are the OIDs of the variables identifying the object of the condition, e.g.
+	// ifIndex, whose values must be the same in both traps
+	MatchVariables []string `mapstructure:"match_variables" yaml:"match_variables"`
+}
+
+// ValueTransformConfig contains the configuration of a transformation of the values of a
+// variable, to fix the values of the devices which do not format them as their MIB defines.
+// The regex is applied first, then the hexadecimal values are parsed, then the values are scaled.
+type ValueTransformConfig struct {
+	// OID is the OID of the variable, or of the table column of its instances
+	OID string `mapstructure:"oid" yaml:"oid"`
+	// Regex extracts the first group of its matches from the string values, or the whole match
+	// when it has no group
+	Regex string `mapstructure:"regex" yaml:"regex"`
+	// HexToInt parses the hexadecimal string values as integers, e.g. "0x1f"
+	HexToInt bool `mapstructure:"hex_to_int" yaml:"hex_to_int"`
+	// Scale multiplies the numeric values, e.g. 0.1 for the values in tenths of their unit
+	Scale  float64 `mapstructure:"scale" yaml:"scale"`
+	regexp *regexp.Regexp
 }
 
 // Config contains configuration for SNMP trap listeners.
 // YAML field tags provided for test marshalling purposes.
 type Config struct {
-	Port                  uint16   `mapstructure:"port" yaml:"port"`
-	Users                 []UserV3 `mapstructure:"users" yaml:"users"`
-	CommunityStrings      []string `mapstructure:"community_strings" yaml:"community_strings"`
-	BindHost              string   `mapstructure:"bind_host" yaml:"bind_host"`
-	StopTimeout           int      `mapstructure:"stop_timeout" yaml:"stop_timeout"`
-	Namespace             string   `mapstructure:"namespace" yaml:"namespace"`
-	authoritativeEngineID string   `mapstructure:"-" yaml:"-"`
+	Port                   uint16                 `mapstructure:"port" yaml:"port"`
+	Users                  []UserV3               `mapstructure:"users" yaml:"users"`
+	CommunityStrings       []string               `mapstructure:"community_strings" yaml:"community_strings"`
+	BindHost               string                 `mapstructure:"bind_host" yaml:"bind_host"`
+	StopTimeout            int                    `mapstructure:"stop_timeout" yaml:"stop_timeout"`
+	Namespace              string                 `mapstructure:"namespace" yaml:"namespace"`
+	Contexts               []ContextConfig        `mapstructure:"contexts" yaml:"contexts"`
+	Tags                   []string               `mapstructure:"tags" yaml:"tags"`
+	Listeners              []ListenerConfig       `mapstructure:"listeners" yaml:"listeners"`
+	MIBsDir                string                 `mapstructure:"mibs_dir" yaml:"mibs_dir"`
+	TrapsDBReloadInterval  int                    `mapstructure:"traps_db_reload_interval" yaml:"traps_db_reload_interval"`
+	TrapsDBBundles         []BundleConfig         `mapstructure:"traps_db_bundles" yaml:"traps_db_bundles"`
+	TrapsDBCacheSize       int                    `mapstructure:"traps_db_cache_size" yaml:"traps_db_cache_size"`
+	TrapsDBSets            []TrapsDBSetConfig     `mapstructure:"traps_db_sets" yaml:"traps_db_sets"`
+	Outputs                []string               `mapstructure:"outputs" yaml:"outputs"`
+	Relays                 []RelayConfig          `mapstructure:"relays" yaml:"relays"`
+	SpoolMaxSize           int64                  `mapstructure:"spool_max_size_in_bytes" yaml:"spool_max_size_in_bytes"`
+	SpoolPath              string                 `mapstructure:"spool_path" yaml:"spool_path"`
+	PayloadSchemaVersion   int                    `mapstructure:"payload_schema_version" yaml:"payload_schema_version"`
+	IncludeRawPDU          bool                   `mapstructure:"include_raw_pdu" yaml:"include_raw_pdu"`
+	LargeIntegersAsStrings bool                   `mapstructure:"large_integers_as_strings" yaml:"large_integers_as_strings"`
+	StormThreshold         int                    `mapstructure:"storm_threshold" yaml:"storm_threshold"`
+	StormSummaryInterval   int                    `mapstructure:"storm_summary_interval" yaml:"storm_summary_interval"`
+	Correlations           []CorrelationConfig    `mapstructure:"correlations" yaml:"correlations"`
+	ValueTransforms        []ValueTransformConfig `mapstructure:"value_transforms" yaml:"value_transforms"`
+	authoritativeEngineID  string                 `mapstructure:"-" yaml:"-"`
 }
 
 // ReadConfig builds and returns configuration from Agent configuration.
@@ -51,9 +214,8 @@ func ReadConfig(agentHostname string) (*Config, error) {
 		return nil, err
 	}
 
-	// gosnmp only supports one v3 user at the moment.
-	if len(c.Users) > 1 {
-		return nil, errors.New("only one user is currently supported in snmp_traps_config")
+	if err := validateUsers(c.Users); err != nil {
+		return nil, err
 	}
 
 	// Set defaults.
@@ -67,6 +229,82 @@ func ReadConfig(agentHostname string) (*Config, error) {
 	if c.StopTimeout == 0 {
 		c.StopTimeout = defaultStopTimeout
 	}
+	if c.TrapsDBReloadInterval == 0 {
+		c.TrapsDBReloadInterval = defaultTrapsDBReloadInterval
+	}
+	for i := range c.TrapsDBBundles {
+		bundle := &c.TrapsDBBundles[i]
+		if err := bundle.validate(); err != nil {
+			return nil, fmt.Errorf("invalid snmp_traps_config: %w", err)
+		}
+		if bundle.RefreshInterval == 0 {
+			bundle.RefreshInterval = defaultBundleRefreshInterval
+		}
+	}
+	for _, output := range c.Outputs {
+		if output != logsOutput && output != eventsOutput {
+			return nil, fmt.Errorf("invalid snmp_traps_config: unknown output %q, expected %q or %q", output, logsOutput, eventsOutput)
+		}
+	}
+	if len(c.Outputs) == 0 {
+		c.Outputs = []string{logsOutput}
+	}
+	for i := range c.Relays {
+		relay := &c.Relays[i]
+		if relay.Host == "" {
+			return nil, errors.New("invalid snmp_traps_config: relays must have a host")
+		}
+		if relay.Port == 0 {
+			relay.Port = defaultPort
+		}
+	}
+	if c.StormThreshold < 0 || c.StormSummaryInterval < 0 {
+		return nil, errors.New("invalid snmp_traps_config: storm_threshold and storm_summary_interval must be positive")
+	}
+	if c.StormSummaryInterval == 0 {
+		c.StormSummaryInterval = defaultStormSummaryInterval
+	}
+	if c.SpoolMaxSize < 0 {
+		return nil, errors.New("invalid snmp_traps_config: spool_max_size_in_bytes must be positive")
+	}
+	if c.SpoolPath == "" {
+		c.SpoolPath = spoolDir()
+	}
+	for i := range c.Correlations {
+		correlation := &c.Correlations[i]
+		if correlation.RaiseOID == "" || correlation.ClearOID == "" {
+			return nil, errors.New("invalid snmp_traps_config: correlations must have a raise_oid and a clear_oid")
+		}
+		correlation.RaiseOID = normalizeOID(correlation.RaiseOID)
+		correlation.ClearOID = normalizeOID(correlation.ClearOID)
+		for j, oid := range correlation.MatchVariables {
+			correlation.MatchVariables[j] = normalizeOID(oid)
+		}
+	}
+	for i := range c.ValueTransforms {
+		transform := &c.ValueTransforms[i]
+		if transform.OID == "" {
+			return nil, errors.New("invalid snmp_traps_config: value_transforms must have an oid")
+		}
+		if transform.Regex == "" && !transform.HexToInt && transform.Scale == 0 {
+			return nil, fmt.Errorf("invalid snmp_traps_config: value transform of %s must have a regex, hex_to_int or a scale", transform.OID)
+		}
+		transform.OID = normalizeOID(transform.OID)
+		if transform.Regex != "" {
+			if transform.regexp, err = regexp.Compile(transform.Regex); err != nil {
+				return nil, fmt.Errorf("invalid snmp_traps_config: value transform of %s: %w", transform.OID, err)
+			}
+		}
+	}
+	if c.PayloadSchemaVersion == 0 {
+		c.PayloadSchemaVersion = payloadSchemaV1
+	}
+	if c.PayloadSchemaVersion != payloadSchemaV1 && c.PayloadSchemaVersion != payloadSchemaV2 {
+		return nil, fmt.Errorf("invalid snmp_traps_config: unknown payload_schema_version %d, expected %d or %d", c.PayloadSchemaVersion, payloadSchemaV1, payloadSchemaV2)
+	}
+	if c.MIBsDir == "" {
+		c.MIBsDir = filepath.Join(config.Datadog.GetString("confd_path"), "snmp.d", "mibs")
+	}
 
 	if agentHostname == "" {
 		// Make sure to have at least some unique bytes for the authoritative engineID.
@@ -89,15 +327,136 @@ func ReadConfig(agentHostname string) (*Config, error) {
 		return nil, fmt.Errorf("invalid snmp_traps_config: %w", err)
 	}
 
+	for i := range c.Listeners {
+		listener := &c.Listeners[i]
+		if listener.Port == 0 {
+			return nil, errors.New("invalid snmp_traps_config: listeners must have a port")
+		}
+		if err := validateUsers(listener.Users); err != nil {
+			return nil, err
+		}
+		if listener.Namespace != "" {
+			listener.Namespace, err = common.NormalizeNamespace(listener.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snmp_traps_config: listener on port %d: %w", listener.Port, err)
+			}
+		}
+	}
+
+	setNamespaces := make(map[string]bool, len(c.TrapsDBSets))
+	for i := range c.TrapsDBSets {
+		set := &c.TrapsDBSets[i]
+		if set.Namespace == "" || set.Dir == "" {
+			return nil, errors.New("invalid snmp_traps_config: traps_db_sets must have a namespace and a dir")
+		}
+		set.Namespace, err = common.NormalizeNamespace(set.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snmp_traps_config: traps DB set %q: %w", set.Dir, err)
+		}
+		if setNamespaces[set.Namespace] {
+			return nil, fmt.Errorf("invalid snmp_traps_config: duplicate traps DB set for namespace %q", set.Namespace)
+		}
+		setNamespaces[set.Namespace] = true
+		if !filepath.IsAbs(set.Dir) {
+			set.Dir = filepath.Join(trapsDBDir(), set.Dir)
+		}
+	}
+
+	contextNames := make(map[string]bool, len(c.Contexts))
+	for i := range c.Contexts {
+		context := &c.Contexts[i]
+		if context.Name == "" {
+			return nil, errors.New("invalid snmp_traps_config: contexts must have a name")
+		}
+		if contextNames[context.Name] {
+			return nil, fmt.Errorf("invalid snmp_traps_config: duplicate context %q", context.Name)
+		}
+		contextNames[context.Name] = true
+		if context.Namespace != "" {
+			context.Namespace, err = common.NormalizeNamespace(context.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snmp_traps_config: context %q: %w", context.Name, err)
+			}
+		}
+	}
+
 	return &c, nil
 }
 
+// validateUsers checks that the v3 users can be told apart, as the v3 traps are authenticated
+// with the parameters of the user who sent them.
+func validateUsers(users []UserV3) error {
+	usernames := make(map[string]bool, len(users))
+	for _, user := range users {
+		if usernames[user.Username] {
+			return fmt.Errorf("duplicate user %q in snmp_traps_config", user.Username)
+		}
+		usernames[user.Username] = true
+		if _, err := user.msgFlags(); err != nil {
+			return fmt.Errorf("invalid user %q in snmp_traps_config: %w", user.Username, err)
+		}
+	}
+	return nil
+}
+
+// listenerConfigs returns the configuration of each listener, starting with the main one.
+func (c *Config) listenerConfigs() []*Config {
+	configs := []*Config{c}
+	for _, listener := range c.Listeners {
+		lc := *c
+		lc.Listeners = nil
+		lc.Port = listener.Port
+		if listener.BindHost != "" {
+			lc.BindHost = listener.BindHost
+		}
+		if len(listener.Users) > 0 {
+			lc.Users = listener.Users
+		}
+		if len(listener.CommunityStrings) > 0 {
+			lc.CommunityStrings = listener.CommunityStrings
+		}
+		if listener.Namespace != "" {
+			lc.Namespace = listener.Namespace
+		}
+		if len(listener.Tags) > 0 {
+			lc.Tags = listener.Tags
+		}
+		configs = append(configs, &lc)
+	}
+	return configs
+}
+
+// hasOutput returns whether the traps are forwarded to an output, they are forwarded as logs
+// when no output is configured.
+func (c *Config) hasOutput(output string) bool {
+	if len(c.Outputs) == 0 {
+		return output == logsOutput
+	}
+	for _, o := range c.Outputs {
+		if o == output {
+			return true
+		}
+	}
+	return false
+}
+
+// getContext returns the configuration of the context, or nil if it is not configured.
+func (c *Config) getContext(name string) *ContextConfig {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i]
+		}
+	}
+	return nil
+}
+
 // Addr returns the host:port address to listen on.
 func (c *Config) Addr() string {
 	return fmt.Sprintf("%s:%d", c.BindHost, c.Port)
 }
 
-// BuildSNMPParams returns a valid GoSNMP params structure from configuration.
+// BuildSNMPParams returns a valid GoSNMP params structure from configuration, with the
+// security parameters of the first user when there are v3 users.
 func (c *Config) BuildSNMPParams() (*gosnmp.GoSNMP, error) {
 	if len(c.Users) == 0 {
 		return &gosnmp.GoSNMP{
@@ -107,44 +466,46 @@ func (c *Config) BuildSNMPParams() (*gosnmp.GoSNMP, error) {
 			Logger:    gosnmp.NewLogger(&trapLogger{}),
 		}, nil
 	}
-	user := c.Users[0]
-	var authProtocol gosnmp.SnmpV3AuthProtocol
-	switch lowerAuthProtocol := strings.ToLower(user.AuthProtocol); lowerAuthProtocol {
-	case "":
-		authProtocol = gosnmp.NoAuth
-	case "md5":
-		authProtocol = gosnmp.MD5
-	case "sha":
-		authProtocol = gosnmp.SHA
-	default:
-		return nil, fmt.Errorf("unsupported authentication protocol: %s", user.AuthProtocol)
+	return c.buildUserSNMPParams(c.Users[0])
+}
+
+// BuildUsersSNMPParams returns a valid GoSNMP params structure for each v3 user, in the order
+// of the configuration.
+func (c *Config) BuildUsersSNMPParams() ([]*gosnmp.GoSNMP, error) {
+	params := make([]*gosnmp.GoSNMP, 0, len(c.Users))
+	for _, user := range c.Users {
+		userParams, err := c.buildUserSNMPParams(user)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user %q: %w", user.Username, err)
+		}
+		params = append(params, userParams)
 	}
+	return params, nil
+}
 
-	var privProtocol gosnmp.SnmpV3PrivProtocol
-	switch lowerPrivProtocol := strings.ToLower(user.PrivProtocol); lowerPrivProtocol {
-	case "":
-		privProtocol = gosnmp.NoPriv
-	case "des":
-		privProtocol = gosnmp.DES
-	case "aes":
-		privProtocol = gosnmp.AES
-	case "aes192":
-		privProtocol = gosnmp.AES192
-	case "aes192c":
-		privProtocol = gosnmp.AES192C
-	case "aes256":
-		privProtocol = gosnmp.AES256
-	case "aes256c":
-		privProtocol = gosnmp.AES256C
-	default:
-		return nil, fmt.Errorf("unsupported privacy protocol: %s", user.PrivProtocol)
+func (c *Config) buildUserSNMPParams(user UserV3) (*gosnmp.GoSNMP, error) {
+	// The protocols default to MD5 and DES when only the keys are set.
+	authProtocolName := user.AuthProtocol
+	if authProtocolName == "" && user.AuthKey != "" {
+		authProtocolName = "md5"
+	}
+	authProtocol, err := gosnmplib.GetAuthProtocol(authProtocolName)
+	if err != nil {
+		return nil, err
+	}
+
+	privProtocolName := user.PrivProtocol
+	if privProtocolName == "" && user.PrivKey != "" {
+		privProtocolName = "des"
+	}
+	privProtocol, err := gosnmplib.GetPrivProtocol(privProtocolName)
+	if err != nil {
+		return nil, err
 	}
 
-	msgFlags := gosnmp.NoAuthNoPriv
-	if user.PrivKey != "" {
-		msgFlags = gosnmp.AuthPriv
-	} else if user.AuthKey != "" {
-		msgFlags = gosnmp.AuthNoPriv
+	msgFlags, err := user.msgFlags()
+	if err != nil {
+		return nil, err
 	}
 
 	return &gosnmp.GoSNMP{
@@ -6,6 +6,7 @@
 package traps
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -104,6 +105,234 @@ func TestDefaultUsers(t *testing.T) {
 	assert.Equal(t, 11, config.StopTimeout)
 }
 
+func TestMultipleUsers(t *testing.T) {
+	Configure(t, Config{
+		Users: []UserV3{
+			{Username: "user", AuthKey: "password", AuthProtocol: "MD5"},
+			{Username: "other", AuthKey: "password", AuthProtocol: "SHA", PrivKey: "password", PrivProtocol: "AES"},
+		},
+	})
+	config, err := ReadConfig(mockedHostname)
+	assert.NoError(t, err)
+
+	params, err := config.BuildUsersSNMPParams()
+	assert.NoError(t, err)
+	assert.Len(t, params, 2)
+	assert.Equal(t, gosnmp.AuthNoPriv, params[0].MsgFlags)
+	assert.Equal(t, "user", params[0].SecurityParameters.(*gosnmp.UsmSecurityParameters).UserName)
+	assert.Equal(t, gosnmp.AuthPriv, params[1].MsgFlags)
+	assert.Equal(t, &gosnmp.UsmSecurityParameters{
+		UserName:                 "other",
+		AuthoritativeEngineID:    expectedEngineID,
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "password",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "password",
+	}, params[1].SecurityParameters)
+}
+
+func TestUserProtocols(t *testing.T) {
+	for _, tc := range []struct {
+		user         UserV3
+		authProtocol gosnmp.SnmpV3AuthProtocol
+		privProtocol gosnmp.SnmpV3PrivProtocol
+		msgFlags     gosnmp.SnmpV3MsgFlags
+	}{
+		{UserV3{Username: "user"}, gosnmp.NoAuth, gosnmp.NoPriv, gosnmp.NoAuthNoPriv},
+		{UserV3{Username: "user", AuthKey: "password"}, gosnmp.MD5, gosnmp.NoPriv, gosnmp.AuthNoPriv},
+		{UserV3{Username: "user", AuthKey: "password", PrivKey: "password"}, gosnmp.MD5, gosnmp.DES, gosnmp.AuthPriv},
+		{UserV3{Username: "user", AuthKey: "password", AuthProtocol: "SHA224"}, gosnmp.SHA224, gosnmp.NoPriv, gosnmp.AuthNoPriv},
+		{UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha256", PrivKey: "password", PrivProtocol: "aes192"}, gosnmp.SHA256, gosnmp.AES192, gosnmp.AuthPriv},
+		{UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha384", PrivKey: "password", PrivProtocol: "aes192c"}, gosnmp.SHA384, gosnmp.AES192C, gosnmp.AuthPriv},
+		{UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha512", PrivKey: "password", PrivProtocol: "AES256"}, gosnmp.SHA512, gosnmp.AES256, gosnmp.AuthPriv},
+		{UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha512", PrivKey: "password", PrivProtocol: "aes256c"}, gosnmp.SHA512, gosnmp.AES256C, gosnmp.AuthPriv},
+	} {
+		config := Config{Users: []UserV3{tc.user}}
+		params, err := config.BuildSNMPParams()
+		assert.NoError(t, err)
+		securityParams := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		assert.Equal(t, tc.authProtocol, securityParams.AuthenticationProtocol, "%+v", tc.user)
+		assert.Equal(t, tc.privProtocol, securityParams.PrivacyProtocol, "%+v", tc.user)
+		assert.Equal(t, tc.msgFlags, params.MsgFlags, "%+v", tc.user)
+	}
+}
+
+func TestDuplicateUsers(t *testing.T) {
+	Configure(t, Config{
+		Users: []UserV3{
+			{Username: "user", AuthKey: "password", AuthProtocol: "MD5"},
+			{Username: "user", AuthKey: "otherpassword", AuthProtocol: "SHA"},
+		},
+	})
+	_, err := ReadConfig("")
+	assert.Error(t, err)
+}
+
+func TestInvalidUser(t *testing.T) {
+	config := Config{Users: []UserV3{{Username: "user", AuthKey: "password", AuthProtocol: "unknown"}}}
+	_, err := config.BuildUsersSNMPParams()
+	assert.Error(t, err)
+
+	config = Config{Users: []UserV3{{Username: "user", AuthKey: "password", PrivKey: "password", PrivProtocol: "aes512"}}}
+	_, err = config.BuildUsersSNMPParams()
+	assert.Error(t, err)
+}
+
+func TestUserSecurityLevel(t *testing.T) {
+	Configure(t, Config{Users: []UserV3{
+		{Username: "user1", AuthKey: "password", PrivKey: "password", SecurityLevel: "AUTHPRIV"},
+		{Username: "user2", AuthKey: "password", PrivKey: "password", SecurityLevel: "authNoPriv"},
+	}})
+	config, err := ReadConfig("")
+	assert.NoError(t, err)
+	params, err := config.BuildUsersSNMPParams()
+	assert.NoError(t, err)
+	assert.Equal(t, gosnmp.AuthPriv, params[0].MsgFlags)
+	assert.Equal(t, gosnmp.AuthNoPriv, params[1].MsgFlags)
+
+	for _, user := range []UserV3{
+		{Username: "user", SecurityLevel: "authNoPriv"},
+		{Username: "user", AuthKey: "password", SecurityLevel: "authPriv"},
+		{Username: "user", SecurityLevel: "unknown"},
+	} {
+		Configure(t, Config{Users: []UserV3{user}})
+		_, err = ReadConfig("")
+		assert.Error(t, err, "%+v", user)
+	}
+}
+
+func TestContexts(t *testing.T) {
+	Configure(t, Config{
+		Namespace: "foo",
+		Contexts: []ContextConfig{
+			{Name: "vrf-blue", Namespace: "blue<net", Tags: []string{"vrf:blue"}},
+			{Name: "vrf-red"},
+		},
+	})
+	config, err := ReadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, &ContextConfig{Name: "vrf-blue", Namespace: "blue-net", Tags: []string{"vrf:blue"}}, config.getContext("vrf-blue"))
+	assert.Equal(t, "", config.getContext("vrf-red").Namespace)
+	assert.Nil(t, config.getContext("vrf-green"))
+}
+
+func TestOutputs(t *testing.T) {
+	// the traps are forwarded as logs by default
+	Configure(t, Config{})
+	config, err := ReadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logs"}, config.Outputs)
+	assert.True(t, IsLogsOutputEnabled())
+
+	Configure(t, Config{Outputs: []string{"events"}})
+	config, err = ReadConfig("")
+	assert.NoError(t, err)
+	assert.True(t, config.hasOutput(eventsOutput))
+	assert.False(t, config.hasOutput(logsOutput))
+	assert.False(t, IsLogsOutputEnabled())
+
+	Configure(t, Config{Outputs: []string{"events", "metrics"}})
+	_, err = ReadConfig("")
+	assert.Error(t, err)
+}
+
+func TestInvalidContexts(t *testing.T) {
+	for _, contexts := range [][]ContextConfig{
+		{{Namespace: "foo"}},
+		{{Name: "vrf-blue"}, {Name: "vrf-blue"}},
+		{{Name: "vrf-blue", Namespace: strings.Repeat("x", 101)}},
+	} {
+		Configure(t, Config{Contexts: contexts})
+		_, err := ReadConfig("")
+		assert.Error(t, err, "%+v", contexts)
+	}
+}
+
+func TestPayloadSchemaVersion(t *testing.T) {
+	Configure(t, Config{})
+	config, err := ReadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, payloadSchemaV1, config.PayloadSchemaVersion)
+
+	Configure(t, Config{PayloadSchemaVersion: 2})
+	config, err = ReadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, payloadSchemaV2, config.PayloadSchemaVersion)
+
+	Configure(t, Config{PayloadSchemaVersion: 3})
+	_, err = ReadConfig("")
+	assert.Error(t, err)
+}
+
+func TestTrapsDBSets(t *testing.T) {
+	Configure(t, Config{TrapsDBSets: []TrapsDBSetConfig{
+		{Namespace: "tenant<a", Dir: "tenant_a"},
+		{Namespace: "tenant-b", Dir: "/etc/tenant_b"},
+	}})
+	config, err := ReadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, []TrapsDBSetConfig{
+		{Namespace: "tenant-a", Dir: filepath.Join(trapsDBDir(), "tenant_a")},
+		{Namespace: "tenant-b", Dir: "/etc/tenant_b"},
+	}, config.TrapsDBSets)
+
+	for _, sets := range [][]TrapsDBSetConfig{
+		{{Namespace: "tenant-a"}},
+		{{Dir: "tenant_a"}},
+		{{Namespace: "tenant-a", Dir: "tenant_a"}, {Namespace: "tenant-a", Dir: "other"}},
+	} {
+		Configure(t, Config{TrapsDBSets: sets})
+		_, err := ReadConfig("")
+		assert.Error(t, err, "%+v", sets)
+	}
+}
+
+func TestListeners(t *testing.T) {
+	Configure(t, Config{
+		Port:             1162,
+		BindHost:         "127.0.0.1",
+		CommunityStrings: []string{"public"},
+		Namespace:        "foo",
+		Tags:             []string{"network:main"},
+		Listeners: []ListenerConfig{
+			{Port: 1163, CommunityStrings: []string{"private"}, Namespace: "bar", Tags: []string{"network:dmz"}},
+			{Port: 1164, BindHost: "0.0.0.0", Users: []UserV3{{Username: "user", AuthKey: "password"}}},
+		},
+	})
+	config, err := ReadConfig("")
+	assert.NoError(t, err)
+
+	configs := config.listenerConfigs()
+	assert.Len(t, configs, 3)
+	assert.Equal(t, config, configs[0])
+
+	assert.Equal(t, "127.0.0.1:1163", configs[1].Addr())
+	assert.Equal(t, []string{"private"}, configs[1].CommunityStrings)
+	assert.Equal(t, "bar", configs[1].Namespace)
+	assert.Equal(t, []string{"network:dmz"}, configs[1].Tags)
+
+	// the unset options default to the ones of the main listener
+	assert.Equal(t, "0.0.0.0:1164", configs[2].Addr())
+	assert.Equal(t, []string{"public"}, configs[2].CommunityStrings)
+	assert.Equal(t, []UserV3{{Username: "user", AuthKey: "password"}}, configs[2].Users)
+	assert.Equal(t, "foo", configs[2].Namespace)
+	assert.Equal(t, []string{"network:main"}, configs[2].Tags)
+	assert.Equal(t, config.StopTimeout, configs[2].StopTimeout)
+	assert.Equal(t, config.authoritativeEngineID, configs[2].authoritativeEngineID)
+}
+
+func TestInvalidListeners(t *testing.T) {
+	for _, listeners := range [][]ListenerConfig{
+		{{CommunityStrings: []string{"public"}}},
+		{{Port: 1163, Users: []UserV3{{Username: "user"}, {Username: "user"}}}},
+		{{Port: 1163, Namespace: strings.Repeat("x", 101)}},
+	} {
+		Configure(t, Config{Listeners: listeners})
+		_, err := ReadConfig("")
+		assert.Error(t, err, "%+v", listeners)
+	}
+}
+
 func TestBuildAuthoritativeEngineID(t *testing.T) {
 	Configure(t, Config{})
 	for hostname, engineID := range expectedEngineIDs {
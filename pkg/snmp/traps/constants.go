@@ -6,9 +6,12 @@
 package traps
 
 const (
-	defaultPort        = uint16(162) // Standard UDP port for traps.
-	defaultStopTimeout = 5
-	defaultNamespace   = "default"
-	packetsChanSize    = 100
-	genericTrapOid     = "1.3.6.1.6.3.1.1.5"
+	defaultPort                  = uint16(162) // Standard UDP port for traps.
+	defaultStopTimeout           = 5
+	defaultNamespace             = "default"
+	packetsChanSize              = 100
+	genericTrapOid               = "1.3.6.1.6.3.1.1.5"
+	defaultTrapsDBReloadInterval = 5    // Seconds between the checks of the changes of the traps DB files.
+	defaultBundleRefreshInterval = 3600 // Seconds between the fetches of the traps DB bundles.
+	defaultStormSummaryInterval  = 10   // Seconds between the summaries of the traps received during a storm.
 )
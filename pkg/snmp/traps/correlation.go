@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// maxPendingRaises is the number of raise traps waiting for their clear trap which are kept,
+// the oldest ones are forgotten when it is reached.
+const maxPendingRaises = 10000
+
+// correlation is the pairing of a trap with the raise trap of its pair.
+type correlation struct {
+	id string
+	// duration is the time elapsed since the raise trap, for a clear trap
+	duration time.Duration
+	cleared  bool
+}
+
+// pendingRaise is a raise trap waiting for its clear trap.
+type pendingRaise struct {
+	id       string
+	received time.Time
+}
+
+// trapCorrelator pairs the raise and clear traps of the configured pairs, e.g. linkDown and
+// linkUp, which are sent by the same device for the same object: the values of the matching
+// variables of the traps are the same.
+type trapCorrelator struct {
+	pairs []CorrelationConfig
+	// mu serializes the correlation of the traps received by the listeners
+	mu      sync.Mutex
+	pending *lru.Cache
+	now     func() time.Time
+}
+
+// newTrapCorrelator returns a correlator of the configured pairs, it returns nil when no pair
+// is configured.
+func newTrapCorrelator(pairs []CorrelationConfig) (*trapCorrelator, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	pending, err := lru.New(maxPendingRaises)
+	if err != nil {
+		return nil, err
+	}
+	return &trapCorrelator{pairs: pairs, pending: pending, now: time.Now}, nil
+}
+
+// correlate records a raise trap, or pairs a clear trap with its raise trap. It returns nil
+// when the trap is not part of a pair, or when a clear trap has no pending raise trap.
+func (c *trapCorrelator) correlate(packet *SnmpPacket, namespace string) *correlation {
+	trapOID, err := getTrapOID(packet.Content)
+	if err != nil {
+		return nil
+	}
+	for i, pair := range c.pairs {
+		if trapOID != pair.RaiseOID && trapOID != pair.ClearOID {
+			continue
+		}
+		key, ok := correlationKey(packet, pair.MatchVariables)
+		if !ok {
+			log.Debugf("The trap %s from %s does not have the variables to match, it is not correlated", trapOID, packet.Addr.IP.String())
+			return nil
+		}
+		key = fmt.Sprintf("%d|%s|%s|%s", i, namespace, packet.Addr.IP.String(), key)
+		if trapOID == pair.RaiseOID {
+			return c.raise(key)
+		}
+		return c.clear(key)
+	}
+	return nil
+}
+
+// raise records a raise trap, the raise traps which are repeated before their clear trap are
+// correlated with the first one.
+func (c *trapCorrelator) raise(key string) *correlation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if raise, ok := c.pending.Get(key); ok {
+		return &correlation{id: raise.(pendingRaise).id}
+	}
+	raise := pendingRaise{id: newCorrelationID(), received: c.now()}
+	c.pending.Add(key, raise)
+	return &correlation{id: raise.id}
+}
+
+// clear pairs a clear trap with its raise trap.
+func (c *trapCorrelator) clear(key string) *correlation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.pending.Get(key)
+	if !ok {
+		return nil
+	}
+	c.pending.Remove(key)
+	raise := value.(pendingRaise)
+	return &correlation{id: raise.id, duration: c.now().Sub(raise.received), cleared: true}
+}
+
+// correlationKey returns the names and the values of the matching variables of a trap, the
+// matching variables are identified by their OIDs or by the OIDs of the objects they are
+// instances of. It returns false when a matching variable is missing.
+func correlationKey(packet *SnmpPacket, matchVariables []string) (string, bool) {
+	parts := make([]string, 0, len(matchVariables))
+	for _, oid := range matchVariables {
+		found := false
+		for _, variable := range packet.Content.Variables {
+			name := normalizeOID(variable.Name)
+			if name == oid || strings.HasPrefix(name, oid+".") {
+				parts = append(parts, fmt.Sprintf("%s=%v", name, variable.Value))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return strings.Join(parts, "|"), true
+}
+
+// newCorrelationID returns a random ID shared by the traps of a pair.
+func newCorrelationID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
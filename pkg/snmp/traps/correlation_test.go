@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var linkCorrelation = CorrelationConfig{
+	RaiseOID:       "1.3.6.1.6.3.1.1.5.3",
+	ClearOID:       "1.3.6.1.6.3.1.1.5.4",
+	MatchVariables: []string{"1.3.6.1.2.1.2.2.1.1"},
+}
+
+// createLinkPacket returns a v1 linkDown or linkUp trap of an interface.
+func createLinkPacket(genericTrap int, ifIndex int, ip string) *SnmpPacket {
+	content := &gosnmp.SnmpPacket{Version: gosnmp.Version1, SnmpTrap: gosnmp.SnmpTrap{
+		Enterprise:  ".1.3.6.1.6.3.1.1.5",
+		GenericTrap: genericTrap,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.2.2.1.1", Type: gosnmp.Integer, Value: ifIndex},
+		},
+	}}
+	content.Variables = content.SnmpTrap.Variables
+	return &SnmpPacket{Content: content, Addr: &net.UDPAddr{IP: net.ParseIP(ip), Port: 13156}}
+}
+
+func TestTrapCorrelator(t *testing.T) {
+	correlator, err := newTrapCorrelator([]CorrelationConfig{linkCorrelation})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	correlator.now = func() time.Time { return now }
+
+	raise := correlator.correlate(createLinkPacket(2, 1, "10.0.0.1"), "default")
+	require.NotNil(t, raise)
+	assert.False(t, raise.cleared)
+	// the repeated raise traps are correlated with the first one
+	now = now.Add(time.Minute)
+	assert.Equal(t, raise, correlator.correlate(createLinkPacket(2, 1, "10.0.0.1"), "default"))
+	// the traps of other objects, devices or namespaces are not correlated together
+	other := correlator.correlate(createLinkPacket(2, 2, "10.0.0.1"), "default")
+	require.NotNil(t, other)
+	assert.NotEqual(t, raise.id, other.id)
+	assert.Nil(t, correlator.correlate(createLinkPacket(3, 1, "10.0.0.2"), "default"))
+	assert.Nil(t, correlator.correlate(createLinkPacket(3, 1, "10.0.0.1"), "other"))
+
+	// the clear trap is annotated with the duration since the first raise trap
+	now = now.Add(time.Minute)
+	assert.Equal(t, &correlation{id: raise.id, duration: 2 * time.Minute, cleared: true}, correlator.correlate(createLinkPacket(3, 1, "10.0.0.1"), "default"))
+	assert.Nil(t, correlator.correlate(createLinkPacket(3, 1, "10.0.0.1"), "default"))
+
+	// the traps which are not paired or miss the matching variables are not correlated
+	assert.Nil(t, correlator.correlate(createLinkPacket(0, 1, "10.0.0.1"), "default"))
+	packet := createLinkPacket(2, 1, "10.0.0.1")
+	packet.Content.Variables = nil
+	assert.Nil(t, correlator.correlate(packet, "default"))
+}
+
+func TestFormatCorrelatedPacket(t *testing.T) {
+	packet := createLinkPacket(3, 1, "10.0.0.1")
+	packet.correlation = &correlation{id: "abcd", duration: 1500 * time.Millisecond, cleared: true}
+	data := mustFormat(t, packet)
+	assert.Equal(t, "abcd", data.CorrelationID)
+	require.NotNil(t, data.CorrelationDuration)
+	assert.Equal(t, 1.5, *data.CorrelationDuration)
+
+	packet.correlation = &correlation{id: "abcd"}
+	data = mustFormat(t, packet)
+	assert.Equal(t, "abcd", data.CorrelationID)
+	assert.Nil(t, data.CorrelationDuration)
+}
+
+func TestServerCorrelation(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, Correlations: []CorrelationConfig{linkCorrelation}}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	// the linkDown trap is cleared by the linkUp trap of the same interface
+	sendTestV1GenericTrap(t, config, "public")
+	raise := receivePacket(t)
+	require.NotNil(t, raise)
+	require.NotNil(t, raise.correlation)
+	params, err := config.BuildSNMPParams()
+	require.NoError(t, err)
+	params.Community = "public"
+	params.Timeout = time.Second
+	params.Retries = 1
+	params.Version = gosnmp.Version1
+	require.NoError(t, params.Connect())
+	defer params.Conn.Close()
+	linkUp := LinkDownv1GenericTrap
+	linkUp.GenericTrap = 3
+	_, err = params.SendTrap(linkUp)
+	require.NoError(t, err)
+	cleared := receivePacket(t)
+	require.NotNil(t, cleared)
+	require.NotNil(t, cleared.correlation)
+	assert.Equal(t, raise.correlation.id, cleared.correlation.id)
+	assert.True(t, cleared.correlation.cleared)
+}
+
+func TestCorrelationsConfig(t *testing.T) {
+	Configure(t, Config{Correlations: []CorrelationConfig{{RaiseOID: ".1.3.6.1.6.3.1.1.5.3", ClearOID: ".1.3.6.1.6.3.1.1.5.4", MatchVariables: []string{".1.3.6.1.2.1.2.2.1.1"}}}})
+	config, err := ReadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, []CorrelationConfig{linkCorrelation}, config.Correlations)
+
+	Configure(t, Config{Correlations: []CorrelationConfig{{RaiseOID: "1.3.6.1.6.3.1.1.5.3"}}})
+	_, err = ReadConfig("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The outputs the traps are forwarded to.
+const (
+	logsOutput   = "logs"
+	eventsOutput = "events"
+)
+
+const (
+	trapEventSourceType = "snmp"
+	trapEventType       = "snmp_trap"
+)
+
+// EventSender sends the traps forwarded as events, it is implemented by the senders of the
+// aggregator.
+type EventSender interface {
+	Event(e metrics.Event)
+	Commit()
+}
+
+var eventSender EventSender
+
+// SetEventSender sets the sender of the traps forwarded as events, it must be called before
+// the server is started.
+func SetEventSender(sender EventSender) {
+	eventSender = sender
+}
+
+// severityAlertTypes are the alert types of the events of the traps by the labels of their
+// severity variables.
+var severityAlertTypes = map[string]metrics.EventAlertType{
+	"emergency":     metrics.EventAlertTypeError,
+	"alert":         metrics.EventAlertTypeError,
+	"critical":      metrics.EventAlertTypeError,
+	"major":         metrics.EventAlertTypeError,
+	"error":         metrics.EventAlertTypeError,
+	"minor":         metrics.EventAlertTypeWarning,
+	"warning":       metrics.EventAlertTypeWarning,
+	"warn":          metrics.EventAlertTypeWarning,
+	"cleared":       metrics.EventAlertTypeSuccess,
+	"clear":         metrics.EventAlertTypeSuccess,
+	"normal":        metrics.EventAlertTypeSuccess,
+	"ok":            metrics.EventAlertTypeSuccess,
+	"informational": metrics.EventAlertTypeInfo,
+	"info":          metrics.EventAlertTypeInfo,
+	"indeterminate": metrics.EventAlertTypeInfo,
+}
+
+// forwardEvent sends a trap as an event.
+func forwardEvent(sender EventSender, packet *SnmpPacket) {
+	payload, err := FormatPacketToJSON(packet)
+	if err != nil {
+		log.Errorf("failed to format packet: %s", err)
+		return
+	}
+	sender.Event(formatEvent(payload, GetTags(packet), packet.Addr.IP.String()))
+	sender.Commit()
+}
+
+// formatEvent returns the event of a formatted trap, titled with its name and whose alert type
+// is the one of its severity variables.
+func formatEvent(payload *TrapPayload, tags []string, device string) metrics.Event {
+	if payload.StormSummary != nil {
+		return formatStormEvent(payload.StormSummary, tags, device)
+	}
+	title := payload.TrapName
+	if title == "" {
+		title = payload.OID
+	}
+
+	var text strings.Builder
+	for _, variable := range payload.Variables {
+		fmt.Fprintf(&text, "%s: %v\n", variableLabel(variable), variableValue(variable))
+	}
+
+	return metrics.Event{
+		Title:          fmt.Sprintf("SNMP trap %s from %s", title, device),
+		Text:           text.String(),
+		Ts:             time.Now().Unix(),
+		Priority:       metrics.EventPriorityNormal,
+		Tags:           tags,
+		AlertType:      alertType(payload.Variables),
+		AggregationKey: fmt.Sprintf("%s:%s", device, payload.OID),
+		SourceTypeName: trapEventSourceType,
+		EventType:      trapEventType,
+	}
+}
+
+// formatStormEvent returns the event of the summary of the traps received from a device during
+// a storm.
+func formatStormEvent(summary *StormSummary, tags []string, device string) metrics.Event {
+	oids := make([]string, 0, len(summary.TrapOIDs))
+	for oid := range summary.TrapOIDs {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d traps received in %.0f seconds\n", summary.Count, summary.Interval)
+	for _, oid := range oids {
+		fmt.Fprintf(&text, "%s: %d\n", oid, summary.TrapOIDs[oid])
+	}
+
+	return metrics.Event{
+		Title:          fmt.Sprintf("SNMP trap storm from %s", device),
+		Text:           text.String(),
+		Ts:             time.Now().Unix(),
+		Priority:       metrics.EventPriorityNormal,
+		Tags:           tags,
+		AlertType:      metrics.EventAlertTypeWarning,
+		AggregationKey: fmt.Sprintf("%s:storm", device),
+		SourceTypeName: trapEventSourceType,
+		EventType:      trapEventType,
+	}
+}
+
+// alertType returns the alert type of the first severity variable whose label is known, the
+// severity variables are the ones whose name contains "severity".
+func alertType(variables []TrapVariable) metrics.EventAlertType {
+	for _, variable := range variables {
+		if !strings.Contains(strings.ToLower(variable.Name), "severity") {
+			continue
+		}
+		label := strings.ToLower(fmt.Sprintf("%v", variableValue(variable)))
+		if alertType, ok := severityAlertTypes[label]; ok {
+			return alertType
+		}
+	}
+	return metrics.EventAlertTypeInfo
+}
+
+// variableLabel returns the name of a formatted variable, or its OID when it is not resolved.
+func variableLabel(variable TrapVariable) string {
+	if variable.Name != "" {
+		return variable.Name
+	}
+	return variable.OID
+}
+
+// variableValue returns the resolved value of a formatted variable, or its raw value when it
+// is not resolved.
+func variableValue(variable TrapVariable) interface{} {
+	if variable.ResolvedValue != nil {
+		return variable.ResolvedValue
+	}
+	return variable.Value
+}
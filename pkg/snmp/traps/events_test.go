@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// testEventSender sends the events to a channel.
+type testEventSender struct {
+	events chan metrics.Event
+}
+
+func newTestEventSender() *testEventSender {
+	return &testEventSender{events: make(chan metrics.Event, 10)}
+}
+
+func (s *testEventSender) Event(e metrics.Event) {
+	s.events <- e
+}
+
+func (s *testEventSender) Commit() {}
+
+func (s *testEventSender) receiveEvent(t *testing.T) metrics.Event {
+	select {
+	case event := <-s.events:
+		return event
+	case <-time.After(3 * time.Second):
+		t.Error("Event not received")
+		return metrics.Event{}
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	data := &TrapPayload{
+		OID:      "1.3.6.1.4.1.99999.0.1",
+		TrapName: "fanFailure",
+		Variables: []TrapVariable{
+			{OID: "1.3.6.1.4.1.99999.1.1", Value: 2, Name: "fanSeverity", ResolvedValue: "Major"},
+			{OID: "1.3.6.1.4.1.99999.1.2", Value: "fan 1"},
+		},
+	}
+	event := formatEvent(data, []string{"snmp_device:10.0.0.1"}, "10.0.0.1")
+	assert.Equal(t, "SNMP trap fanFailure from 10.0.0.1", event.Title)
+	assert.Equal(t, "fanSeverity: Major\n1.3.6.1.4.1.99999.1.2: fan 1\n", event.Text)
+	assert.Equal(t, metrics.EventAlertTypeError, event.AlertType)
+	assert.Equal(t, []string{"snmp_device:10.0.0.1"}, event.Tags)
+	assert.Equal(t, "10.0.0.1:1.3.6.1.4.1.99999.0.1", event.AggregationKey)
+	assert.Equal(t, "snmp", event.SourceTypeName)
+	assert.Equal(t, "snmp_trap", event.EventType)
+
+	// the unresolved traps are titled with their OID
+	data.TrapName = ""
+	assert.Equal(t, "SNMP trap 1.3.6.1.4.1.99999.0.1 from 10.0.0.1", formatEvent(data, nil, "10.0.0.1").Title)
+}
+
+func TestAlertType(t *testing.T) {
+	for label, expected := range map[string]metrics.EventAlertType{
+		"critical": metrics.EventAlertTypeError,
+		"minor":    metrics.EventAlertTypeWarning,
+		"Cleared":  metrics.EventAlertTypeSuccess,
+		"unknown":  metrics.EventAlertTypeInfo,
+	} {
+		variables := []TrapVariable{{Name: "alarmSeverity", Value: label}}
+		assert.Equal(t, expected, alertType(variables), label)
+	}
+	// only the severity variables are taken into account
+	assert.Equal(t, metrics.EventAlertTypeInfo, alertType([]TrapVariable{{Name: "alarmState", Value: "critical"}}))
+	assert.Equal(t, metrics.EventAlertTypeInfo, alertType(nil))
+}
+
+func TestServerEventsOutput(t *testing.T) {
+	sender := newTestEventSender()
+	SetEventSender(sender)
+	defer SetEventSender(nil)
+
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, Outputs: []string{"events", "logs"}}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	sendTestV2Trap(t, config, "public")
+	event := sender.receiveEvent(t)
+	assert.Equal(t, "SNMP trap 1.3.6.1.4.1.8072.2.3.0.1 from 127.0.0.1", event.Title)
+	assert.Contains(t, event.Tags, "snmp_version:2")
+	// the traps are still forwarded as logs
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	assertVariables(t, packet)
+}
+
+func TestServerEventsOnlyOutput(t *testing.T) {
+	sender := newTestEventSender()
+	SetEventSender(sender)
+	defer SetEventSender(nil)
+
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, Outputs: []string{"events"}}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+	assert.False(t, IsLogsOutputEnabled())
+
+	sendTestV2Trap(t, config, "public")
+	sender.receiveEvent(t)
+	assertNoPacketReceived(t)
+}
+
+func TestServerEventsOutputWithoutSender(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, Outputs: []string{"events"}}
+	Configure(t, config)
+	assert.Error(t, StartServer("dummy_hostname"))
+	StopServer()
+}
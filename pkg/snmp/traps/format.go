@@ -6,9 +6,16 @@
 package traps
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"net"
+	"strconv"
 	"strings"
 
+	"github.com/DataDog/datadog-agent/pkg/snmp/devices"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/gosnmp/gosnmp"
 )
 
@@ -17,21 +24,232 @@ const (
 	snmpTrapOID          = "1.3.6.1.6.3.1.1.4.1.0"
 )
 
-// FormatPacketToJSON converts an SNMP trap packet to a JSON-serializable object.
-func FormatPacketToJSON(packet *SnmpPacket) (map[string]interface{}, error) {
+// The versions of the schema of the formatted traps. The version 2 adds the descriptions of
+// the trap and of its variables defined in the traps DB, so that the traps can be read without
+// the MIBs.
+const (
+	payloadSchemaV1 = 1
+	payloadSchemaV2 = 2
+)
+
+// maxRawPDUSize is the maximum number of bytes of the raw PDUs included in the formatted traps.
+const maxRawPDUSize = 1024
+
+// maxSafeInteger is the largest integer which is exactly represented by the JSON parsers storing
+// the numbers as double-precision floats.
+const maxSafeInteger = 1<<53 - 1
+
+// uint64ValueType annotates the unsigned values formatted as strings.
+const uint64ValueType = "uint64"
+
+// formatOptions are the options a trap is formatted with, the ones of the listener which
+// received it.
+type formatOptions struct {
+	schemaVersion int
+	// largeIntegersAsStrings formats the unsigned values larger than maxSafeInteger as strings
+	largeIntegersAsStrings bool
+	// valueTransforms fix the formatted values of the variables
+	valueTransforms []ValueTransformConfig
+}
+
+// FormatPacketToJSON converts an SNMP trap packet to a JSON-serializable payload.
+func FormatPacketToJSON(packet *SnmpPacket) (*TrapPayload, error) {
+	if packet.storm != nil {
+		return &TrapPayload{StormSummary: packet.storm}, nil
+	}
+	options := getFormatOptions(packet)
+	var payload *TrapPayload
 	if packet.Content.Version == gosnmp.Version1 {
-		return formatV1Trap(packet), nil
+		payload = formatV1Trap(packet, options)
+	} else {
+		var err error
+		if payload, err = formatTrap(packet, options); err != nil {
+			if packet.raw != nil {
+				raw, _ := formatRawPDU(packet.raw)
+				return nil, fmt.Errorf("%w, raw PDU: %s", err, raw)
+			}
+			return nil, err
+		}
+	}
+	if options.schemaVersion >= payloadSchemaV2 {
+		payload.UptimeHumanized, payload.UptimeISO8601 = formatTimeTicks(payload.Uptime)
+	}
+	if packet.correlation != nil {
+		payload.CorrelationID = packet.correlation.id
+		if packet.correlation.cleared {
+			duration := packet.correlation.duration.Seconds()
+			payload.CorrelationDuration = &duration
+		}
+	}
+	if packet.raw != nil {
+		// the raw PDU helps analyze the traps which are not decoded as expected
+		payload.RawPDU, payload.RawPDUTruncated = formatRawPDU(packet.raw)
+	}
+	if packet.Content.Version == gosnmp.Version3 {
+		payload.ContextFields = &ContextFields{
+			ContextName:     packet.Content.ContextName,
+			ContextEngineID: hex.EncodeToString([]byte(packet.Content.ContextEngineID)),
+		}
+	}
+	if options.schemaVersion != payloadSchemaV1 {
+		payload.SchemaVersion = options.schemaVersion
+	}
+	return payload, nil
+}
+
+// redactCommunity returns a copy of a message whose community string, which authenticates the
+// v1 and v2c packets, is masked with asterisks of the same length, so that the message can still
+// be decoded. It returns nil when the header of the message can not be parsed, as its community
+// could not be found.
+func redactCommunity(msg []byte) []byte {
+	redacted := append([]byte(nil), msg...)
+	// the message is a sequence of the version, the community and the PDU for v1 and v2c
+	offset, _, ok := parseBERHeader(redacted, 0, 0x30)
+	if !ok {
+		return nil
+	}
+	offset, versionLength, ok := parseBERHeader(redacted, offset, 0x02)
+	if !ok || offset+versionLength > len(redacted) {
+		return nil
+	}
+	if versionLength == 1 && redacted[offset] == byte(gosnmp.Version3) {
+		// the v3 messages have no community
+		return redacted
+	}
+	offset, communityLength, ok := parseBERHeader(redacted, offset+versionLength, 0x04)
+	if !ok {
+		return nil
+	}
+	for i := offset; i < offset+communityLength && i < len(redacted); i++ {
+		redacted[i] = '*'
+	}
+	return redacted
+}
+
+// parseBERHeader parses the tag and the length of a BER encoded value at an offset of a message,
+// it returns the offset of the value and its length, and false if the header could not be parsed
+// or its tag is not the expected one.
+func parseBERHeader(msg []byte, offset int, tag byte) (int, int, bool) {
+	if offset+2 > len(msg) || msg[offset] != tag {
+		return 0, 0, false
+	}
+	length := int(msg[offset+1])
+	offset += 2
+	if length < 0x80 {
+		return offset, length, true
+	}
+	// long form, the length is encoded in the next bytes
+	size := length & 0x7f
+	if size == 0 || size > 4 || offset+size > len(msg) {
+		return 0, 0, false
+	}
+	length = 0
+	for _, b := range msg[offset : offset+size] {
+		length = length<<8 | int(b)
 	}
-	return formatTrap(packet)
+	return offset + size, length, true
+}
+
+// formatRawPDU returns the hex dump of a message, truncated to maxRawPDUSize bytes, and whether
+// it is truncated.
+func formatRawPDU(msg []byte) (string, bool) {
+	if len(msg) > maxRawPDUSize {
+		return hex.EncodeToString(msg[:maxRawPDUSize]), true
+	}
+	return hex.EncodeToString(msg), false
+}
+
+// getFormatOptions returns the options the trap is formatted with.
+func getFormatOptions(packet *SnmpPacket) formatOptions {
+	options := formatOptions{schemaVersion: payloadSchemaV1}
+	if config := getListenerConfig(packet); config != nil {
+		if config.PayloadSchemaVersion != 0 {
+			options.schemaVersion = config.PayloadSchemaVersion
+		}
+		options.largeIntegersAsStrings = config.LargeIntegersAsStrings
+		options.valueTransforms = config.ValueTransforms
+	}
+	return options
 }
 
 // GetTags returns a list of tags associated to an SNMP trap packet.
 func GetTags(packet *SnmpPacket) []string {
-	return []string{
+	namespace := getNamespace(packet)
+	var listenerTags, contextTags []string
+	config := getListenerConfig(packet)
+	if config != nil {
+		listenerTags = config.Tags
+	}
+
+	contextName := getContextName(packet)
+	if contextName != "" && config != nil {
+		if context := config.getContext(contextName); context != nil {
+			contextTags = context.Tags
+		}
+	}
+
+	tags := []string{
 		fmt.Sprintf("snmp_version:%s", formatVersion(packet)),
-		fmt.Sprintf("device_namespace:%s", GetNamespace()),
+		fmt.Sprintf("device_namespace:%s", namespace),
 		fmt.Sprintf("snmp_device:%s", packet.Addr.IP.String()),
 	}
+	if packet.IsInform() {
+		tags = append(tags, "snmp_inform:true")
+	}
+	if packet.storm != nil {
+		tags = append(tags, "snmp_trap_storm:true")
+	}
+	if packet.injected {
+		tags = append(tags, "snmp_trap_injected:true")
+	}
+	if contextName != "" {
+		tags = append(tags, fmt.Sprintf("snmp_context:%s", contextName))
+	}
+	tags = append(tags, listenerTags...)
+	tags = append(tags, contextTags...)
+	// the traps of the devices monitored by the SNMP integration are tagged like their metrics
+	if device, ok := devices.Get(namespace, packet.Addr.IP.String()); ok {
+		tags = appendMissingTags(tags, device.Tags)
+	}
+	return tags
+}
+
+// getNamespace returns the device namespace of a packet, the one of the listener which received
+// it unless it is overridden by its v3 context.
+func getNamespace(packet *SnmpPacket) string {
+	config := getListenerConfig(packet)
+	if config == nil {
+		return defaultNamespace
+	}
+	if contextName := getContextName(packet); contextName != "" {
+		if context := config.getContext(contextName); context != nil && context.Namespace != "" {
+			return context.Namespace
+		}
+	}
+	return config.Namespace
+}
+
+// getContextName returns the context of a v3 packet, or an empty string.
+func getContextName(packet *SnmpPacket) string {
+	if packet.Content.Version == gosnmp.Version3 {
+		return packet.Content.ContextName
+	}
+	return ""
+}
+
+// appendMissingTags appends the tags which are not already in a list of tags.
+func appendMissingTags(tags []string, extraTags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		seen[tag] = true
+	}
+	for _, tag := range extraTags {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
 }
 
 func formatVersion(packet *SnmpPacket) string {
@@ -47,30 +265,49 @@ func formatVersion(packet *SnmpPacket) string {
 	}
 }
 
-func formatV1Trap(packet *SnmpPacket) map[string]interface{} {
-	data := make(map[string]interface{})
-	data["uptime"] = uint32(packet.Content.Timestamp)
-	enterpriseOid := normalizeOID(packet.Content.Enterprise)
-	genericTrap := packet.Content.GenericTrap
-	specificTrap := packet.Content.SpecificTrap
-	var trapOID string
-	if genericTrap == 6 {
+func formatV1Trap(packet *SnmpPacket, options formatOptions) *TrapPayload {
+	trapOID := getV1TrapOID(packet.Content)
+	payload := &TrapPayload{
+		Uptime: uint32(packet.Content.Timestamp),
+		OID:    trapOID,
+		V1TrapFields: &V1TrapFields{
+			EnterpriseOID: normalizeOID(packet.Content.Enterprise),
+			GenericTrap:   packet.Content.GenericTrap,
+			SpecificTrap:  packet.Content.SpecificTrap,
+		},
+	}
+	// the v1 traps are resolved with the OIDs of their v2 equivalents
+	// See: https://tools.ietf.org/html/rfc3584#section-3.1
+	resolver := getOIDResolver(getNamespace(packet))
+	resolveTrap(payload, resolver, trapOID, options)
+	payload.Variables = parseVariables(resolver, trapOID, packet.Content.Variables, options)
+
+	return payload
+}
+
+// getV1TrapOID returns the OID of the v2 equivalent of a v1 trap.
+// See: https://tools.ietf.org/html/rfc3584#section-3.1
+func getV1TrapOID(content *gosnmp.SnmpPacket) string {
+	if content.GenericTrap == 6 {
 		// Vendor-specific trap
-		trapOID = fmt.Sprintf("%s.0.%d", enterpriseOid, specificTrap)
-	} else {
-		// Generic trap
-		trapOID = fmt.Sprintf("%s.%d", genericTrapOid, genericTrap+1)
+		return fmt.Sprintf("%s.0.%d", normalizeOID(content.Enterprise), content.SpecificTrap)
 	}
-	data["oid"] = trapOID
-	data["enterprise_oid"] = enterpriseOid
-	data["generic_trap"] = genericTrap
-	data["specific_trap"] = specificTrap
-	data["variables"] = parseVariables(packet.Content.Variables)
+	// Generic trap
+	return fmt.Sprintf("%s.%d", genericTrapOid, content.GenericTrap+1)
+}
 
-	return data
+// getTrapOID returns the OID of a trap.
+func getTrapOID(content *gosnmp.SnmpPacket) (string, error) {
+	if content.Version == gosnmp.Version1 {
+		return getV1TrapOID(content), nil
+	}
+	if len(content.Variables) < 2 {
+		return "", fmt.Errorf("expected at least 2 variables, got %d", len(content.Variables))
+	}
+	return parseSnmpTrapOID(content.Variables[1])
 }
 
-func formatTrap(packet *SnmpPacket) (map[string]interface{}, error) {
+func formatTrap(packet *SnmpPacket, options formatOptions) (*TrapPayload, error) {
 	/*
 		An SNMP v2 or v3 trap packet consists in the following variables (PDUs):
 		{sysUpTime.0, snmpTrapOID.0, additionalDataVariables...}
@@ -81,23 +318,40 @@ func formatTrap(packet *SnmpPacket) (map[string]interface{}, error) {
 		return nil, fmt.Errorf("expected at least 2 variables, got %d", len(variables))
 	}
 
-	data := make(map[string]interface{})
-
 	uptime, err := parseSysUpTime(variables[0])
 	if err != nil {
 		return nil, err
 	}
-	data["uptime"] = uptime
 
 	trapOID, err := parseSnmpTrapOID(variables[1])
 	if err != nil {
 		return nil, err
 	}
-	data["oid"] = trapOID
+	payload := &TrapPayload{Uptime: uptime, OID: trapOID}
+	resolver := getOIDResolver(getNamespace(packet))
+	resolveTrap(payload, resolver, trapOID, options)
+	payload.Variables = parseVariables(resolver, trapOID, variables[2:], options)
 
-	data["variables"] = parseVariables(variables[2:])
+	return payload, nil
+}
 
-	return data, nil
+// resolveTrap adds the name and the MIB of a trap defined by the traps DB, and the file which
+// defines it.
+func resolveTrap(payload *TrapPayload, resolver OIDResolver, trapOID string, options formatOptions) {
+	if resolver == nil {
+		return
+	}
+	trapMetadata, err := resolver.GetTrapMetadata(trapOID)
+	if err != nil {
+		log.Debugf("Could not resolve trap: %v", err)
+		return
+	}
+	payload.TrapName = trapMetadata.Name
+	payload.TrapMIB = trapMetadata.MIBName
+	payload.TrapSourceFile = trapMetadata.SourceFile
+	if options.schemaVersion >= payloadSchemaV2 {
+		payload.TrapDescription = trapMetadata.Description
+	}
 }
 
 func normalizeOID(value string) string {
@@ -139,20 +393,239 @@ func parseSnmpTrapOID(variable gosnmp.SnmpPDU) (string, error) {
 	return normalizeOID(value), nil
 }
 
-func parseVariables(variables []gosnmp.SnmpPDU) []map[string]interface{} {
-	var parsedVariables []map[string]interface{}
+// parseVariables formats the variables of a trap, with their names and resolved values when
+// they are defined for the trap in the traps DB.
+func parseVariables(resolver OIDResolver, trapOID string, variables []gosnmp.SnmpPDU, options formatOptions) []TrapVariable {
+	parsedVariables := make([]TrapVariable, 0, len(variables))
 
 	for _, variable := range variables {
-		parsedVariable := make(map[string]interface{})
-		parsedVariable["oid"] = normalizeOID(variable.Name)
-		parsedVariable["type"] = formatType(variable)
-		parsedVariable["value"] = formatValue(variable)
+		var metadata VariableMetadata
+		var index []int
+		hasMetadata := false
+		if resolver != nil && trapOID != "" {
+			var err error
+			metadata, index, err = resolver.GetInstanceMetadata(trapOID, variable.Name)
+			hasMetadata = err == nil
+		}
+
+		parsedVariable := TrapVariable{
+			OID:   normalizeOID(variable.Name),
+			Type:  formatType(variable),
+			Value: formatValue(variable, metadata),
+		}
+		if ticks, ok := variable.Value.(uint32); ok && variable.Type == gosnmp.TimeTicks && options.schemaVersion >= payloadSchemaV2 {
+			parsedVariable.ValueHumanized, parsedVariable.ValueISO8601 = formatTimeTicks(ticks)
+		}
+		if transform := findValueTransform(options.valueTransforms, parsedVariable.OID); transform != nil {
+			// the values are resolved from the values of the trap, the transformed values only
+			// replace the formatted ones
+			if value, err := transform.apply(parsedVariable.Value); err == nil {
+				parsedVariable.Value = value
+			} else {
+				log.Debugf("Could not transform the value of variable %s: %v", parsedVariable.OID, err)
+			}
+		}
+		if options.largeIntegersAsStrings {
+			if value, ok := unsignedValue(parsedVariable.Value); ok && value > maxSafeInteger {
+				// the value would be rounded by the parsers storing the numbers as floats
+				parsedVariable.Value = strconv.FormatUint(value, 10)
+				parsedVariable.ValueType = uint64ValueType
+			}
+		}
+		if hasMetadata {
+			parsedVariable.Name = metadata.Name
+			parsedVariable.MIB = metadata.MIBName
+			parsedVariable.SourceFile = metadata.SourceFile
+			if options.schemaVersion >= payloadSchemaV2 {
+				parsedVariable.Description = metadata.Description
+			}
+			if resolved, ok := resolveValue(variable, metadata); ok {
+				parsedVariable.ResolvedValue = resolved
+			}
+			if len(index) > 0 {
+				parsedVariable.Index = formatMIBOID(index)
+				if components, ok := decodeIndex(index, metadata.Index); ok {
+					parsedVariable.IndexComponents = components
+				}
+			}
+		}
 		parsedVariables = append(parsedVariables, parsedVariable)
 	}
 
 	return parsedVariables
 }
 
+// resolveValue returns the value of a variable translated with its metadata, e.g. the label
+// of an enumerated integer.
+func resolveValue(variable gosnmp.SnmpPDU, metadata VariableMetadata) (interface{}, bool) {
+	if bits, ok := variable.Value.([]byte); ok && len(metadata.Bits) > 0 {
+		return resolveBits(bits, metadata.Bits), true
+	}
+	if len(metadata.Enumeration) == 0 {
+		return nil, false
+	}
+	value, ok := integerValue(variable.Value)
+	if !ok {
+		return nil, false
+	}
+	label, ok := metadata.Enumeration[value]
+	return label, ok
+}
+
+// decodeIndex decodes the components of the index of an instance of a table column, it returns
+// false when the index is not described or does not match its description.
+// See: https://tools.ietf.org/html/rfc2578#section-7.7
+func decodeIndex(index []int, components []IndexComponent) (map[string]interface{}, bool) {
+	if len(components) == 0 {
+		return nil, false
+	}
+	decoded := make(map[string]interface{}, len(components))
+	for i, component := range components {
+		var length int
+		switch component.Type {
+		case "integer":
+			length = 1
+		case "ip_address":
+			length = 4
+		case "fixed_string":
+			length = component.Length
+		case "string", "oid":
+			if component.Implied && i == len(components)-1 {
+				length = len(index)
+			} else {
+				if len(index) == 0 {
+					return nil, false
+				}
+				length, index = index[0], index[1:]
+			}
+		default:
+			return nil, false
+		}
+		if length > len(index) {
+			return nil, false
+		}
+		ids := index[:length]
+		index = index[length:]
+
+		switch component.Type {
+		case "integer":
+			decoded[component.Name] = ids[0]
+		case "ip_address", "oid":
+			decoded[component.Name] = formatMIBOID(ids)
+		default:
+			value := make([]byte, length)
+			for j, id := range ids {
+				if id > 255 {
+					return nil, false
+				}
+				value[j] = byte(id)
+			}
+			if formatted, ok := formatOctetString(value, component.Format); ok {
+				decoded[component.Name] = formatted
+			} else {
+				decoded[component.Name] = string(value)
+			}
+		}
+	}
+	if len(index) > 0 {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// resolveBits returns the names of the bits set in the bitmap of a BITS variable, where the
+// first bit is the most significant bit of the first byte. The bits without names are
+// returned as their positions.
+// See: https://tools.ietf.org/html/rfc2578#section-7.1.4
+func resolveBits(bitmap []byte, names map[int]string) []string {
+	setBits := []string{}
+	for i, b := range bitmap {
+		for j := 0; j < 8; j++ {
+			if b&(0x80>>j) == 0 {
+				continue
+			}
+			position := i*8 + j
+			if name, ok := names[position]; ok {
+				setBits = append(setBits, name)
+			} else {
+				setBits = append(setBits, strconv.Itoa(position))
+			}
+		}
+	}
+	return setBits
+}
+
+// integerValue returns the value of an integer variable.
+func integerValue(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case uint:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// formatTimeTicks returns the humanized form, e.g. "1d 2h 3m 4.56s", and the ISO 8601 form,
+// e.g. "P1DT2H3M4.56S", of a duration in hundredths of seconds.
+// See: https://tools.ietf.org/html/rfc2578#section-7.1.8
+func formatTimeTicks(ticks uint32) (string, string) {
+	days := ticks / (100 * 60 * 60 * 24)
+	hours := ticks / (100 * 60 * 60) % 24
+	minutes := ticks / (100 * 60) % 60
+	seconds := strconv.Itoa(int(ticks / 100 % 60))
+	if hundredths := ticks % 100; hundredths != 0 {
+		seconds += "." + strings.TrimRight(fmt.Sprintf("%02d", hundredths), "0")
+	}
+
+	var humanized []string
+	iso8601 := "P"
+	if days > 0 {
+		humanized = append(humanized, fmt.Sprintf("%dd", days))
+		iso8601 += fmt.Sprintf("%dD", days)
+	}
+	if ticks%(100*60*60*24) == 0 && days > 0 {
+		return strings.Join(humanized, " "), iso8601
+	}
+	iso8601 += "T"
+	if hours > 0 {
+		humanized = append(humanized, fmt.Sprintf("%dh", hours))
+		iso8601 += fmt.Sprintf("%dH", hours)
+	}
+	if minutes > 0 {
+		humanized = append(humanized, fmt.Sprintf("%dm", minutes))
+		iso8601 += fmt.Sprintf("%dM", minutes)
+	}
+	if seconds != "0" || len(humanized) == 0 {
+		humanized = append(humanized, seconds+"s")
+		iso8601 += seconds + "S"
+	}
+	return strings.Join(humanized, " "), iso8601
+}
+
+// unsignedValue returns the value of an unsigned integer variable.
+func unsignedValue(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case uint:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
 func formatType(variable gosnmp.SnmpPDU) string {
 	switch variable.Type {
 	case gosnmp.Integer, gosnmp.Uinteger32:
@@ -167,16 +640,99 @@ func formatType(variable gosnmp.SnmpPDU) string {
 		return "counter64"
 	case gosnmp.Gauge32:
 		return "gauge32"
+	case gosnmp.OpaqueFloat:
+		return "float"
+	case gosnmp.OpaqueDouble:
+		return "double"
 	default:
 		return "other"
 	}
 }
 
-func formatValue(variable gosnmp.SnmpPDU) interface{} {
-	switch variable.Value.(type) {
+// formatValue returns the value of a variable, the octet strings are formatted with the
+// format of the variable when it is known.
+func formatValue(variable gosnmp.SnmpPDU, metadata VariableMetadata) interface{} {
+	switch value := variable.Value.(type) {
 	case []byte:
-		return string(variable.Value.([]byte))
+		if len(metadata.Bits) > 0 {
+			// the raw value of a BITS variable is a bitmap rather than a string
+			return hex.EncodeToString(value)
+		}
+		if formatted, ok := formatOctetString(value, metadata.Format); ok {
+			return formatted
+		}
+		return string(value)
+	case float32:
+		return formatFloat(float64(value), 32)
+	case float64:
+		return formatFloat(value, 64)
 	default:
 		return variable.Value
 	}
 }
+
+// formatFloat returns the value of a float variable with the shortest decimal representation
+// of its precision, e.g. 21.1 rather than 21.100000381469727 for a float32. The values which
+// are not numbers or are infinite are returned as strings since JSON does not support them.
+func formatFloat(value float64, bitSize int) interface{} {
+	formatted := strconv.FormatFloat(value, 'g', -1, bitSize)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return formatted
+	}
+	parsed, _ := strconv.ParseFloat(formatted, 64)
+	return parsed
+}
+
+// formatOctetString returns the human-readable form of an octet string of a known format, it
+// returns false when the format is unknown or the octet string does not match it.
+func formatOctetString(value []byte, format string) (string, bool) {
+	switch format {
+	case macAddressFormat:
+		if len(value) == 0 {
+			return "", false
+		}
+		parts := make([]string, len(value))
+		for i, b := range value {
+			parts[i] = fmt.Sprintf("%02x", b)
+		}
+		return strings.Join(parts, ":"), true
+	case dateAndTimeFormat:
+		return formatDateAndTime(value)
+	case inetAddressFormat:
+		return formatInetAddress(value)
+	default:
+		return "", false
+	}
+}
+
+// formatDateAndTime formats a DateAndTime octet string, with its timezone when it is set.
+// See: https://tools.ietf.org/html/rfc2579
+func formatDateAndTime(value []byte) (string, bool) {
+	if len(value) != 8 && len(value) != 11 {
+		return "", false
+	}
+	year := int(value[0])<<8 | int(value[1])
+	formatted := fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02d.%d", year, value[2], value[3], value[4], value[5], value[6], value[7])
+	if len(value) == 11 {
+		if value[8] != '+' && value[8] != '-' {
+			return "", false
+		}
+		formatted += fmt.Sprintf("%c%02d:%02d", value[8], value[9], value[10])
+	}
+	return formatted, true
+}
+
+// formatInetAddress formats an InetAddress octet string, whose type is given by its length:
+// IPv4 or IPv6, optionally followed by a zone index.
+// See: https://tools.ietf.org/html/rfc4001
+func formatInetAddress(value []byte) (string, bool) {
+	switch len(value) {
+	case net.IPv4len, net.IPv6len:
+		return net.IP(value).String(), true
+	case net.IPv4len + 4, net.IPv6len + 4:
+		ipLen := len(value) - 4
+		return fmt.Sprintf("%s%%%d", net.IP(value[:ipLen]), binary.BigEndian.Uint32(value[ipLen:])), true
+	default:
+		return "", false
+	}
+}
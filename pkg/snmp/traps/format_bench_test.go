@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// used to store the result and avoid optimizations
+var (
+	benchPayload *TrapPayload
+	benchContent []byte
+)
+
+// createBenchPacket returns a linkDown trap with a number of instances of ifOperStatus.
+func createBenchPacket(variableCount int) *SnmpPacket {
+	packet := createTestPacket()
+	packet.Content.Variables = []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1000)},
+		{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+	}
+	for i := 0; i < variableCount; i++ {
+		packet.Content.Variables = append(packet.Content.Variables,
+			gosnmp.SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.8.%d", i), Type: gosnmp.Integer, Value: 2})
+	}
+	return packet
+}
+
+// withBenchTrapsDB runs a benchmark with or without the traps DB resolving the bench packets.
+func withBenchTrapsDB(b *testing.B, resolved bool, run func(b *testing.B)) {
+	if !resolved {
+		run(b)
+		return
+	}
+	dir := b.TempDir()
+	writeTrapsDB(b, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	serverInstance = &TrapServer{config: &Config{Namespace: "default"}, oidResolver: resolver}
+	defer func() { serverInstance = nil }()
+	run(b)
+}
+
+func runFormatBenchmark(b *testing.B, marshal bool) {
+	for _, resolved := range []bool{false, true} {
+		for _, variableCount := range []int{1, 10, 100} {
+			b.Run(fmt.Sprintf("resolved-%t/%d-variables", resolved, variableCount), func(b *testing.B) {
+				withBenchTrapsDB(b, resolved, func(b *testing.B) {
+					packet := createBenchPacket(variableCount)
+					b.ReportAllocs()
+					b.ResetTimer()
+					for n := 0; n < b.N; n++ {
+						payload, err := FormatPacketToJSON(packet)
+						if err != nil {
+							b.Fatal(err)
+						}
+						benchPayload = payload
+						if marshal {
+							if benchContent, err = json.Marshal(payload); err != nil {
+								b.Fatal(err)
+							}
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+func BenchmarkFormatPacketToJSON(b *testing.B) {
+	runFormatBenchmark(b, false)
+}
+
+func BenchmarkFormatAndMarshalPacket(b *testing.B) {
+	runFormatBenchmark(b, true)
+}
+
+func BenchmarkFormatV1Packet(b *testing.B) {
+	packet := createTestV1GenericPacket()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		benchPayload = formatV1Trap(packet, formatOptions{schemaVersion: payloadSchemaV1})
+	}
+}
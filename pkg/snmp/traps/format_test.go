@@ -6,12 +6,18 @@
 package traps
 
 import (
+	"encoding/json"
+	"math"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gosnmp/gosnmp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/snmp/devices"
 )
 
 func createTestV1GenericPacket() *SnmpPacket {
@@ -49,89 +55,423 @@ func TestFormatPacketV1Generic(t *testing.T) {
 	data, err := FormatPacketToJSON(packet)
 	require.NoError(t, err)
 
-	assert.Equal(t, "1.3.6.1.6.3.1.1.5.3", data["oid"])
-	assert.NotNil(t, data["uptime"])
-	assert.NotNil(t, data["enterprise_oid"])
-	assert.NotNil(t, data["generic_trap"])
-	assert.NotNil(t, data["specific_trap"])
-
-	variables, ok := data["variables"].([]map[string]interface{})
-	assert.True(t, ok)
-	assert.Equal(t, len(variables), 3)
+	assert.Equal(t, "1.3.6.1.6.3.1.1.5.3", data.OID)
+	assert.Equal(t, &V1TrapFields{EnterpriseOID: "1.3.6.1.6.3.1.1.5", GenericTrap: 2, SpecificTrap: 0}, data.V1TrapFields)
 
-	assert.Equal(t, "1.3.6.1.6.3.1.1.5", data["enterprise_oid"])
-	assert.Equal(t, 2, data["generic_trap"])
-	assert.Equal(t, 0, data["specific_trap"])
+	assert.Equal(t, []TrapVariable{
+		{OID: "1.3.6.1.2.1.2.2.1.1", Type: "integer", Value: 2},
+		{OID: "1.3.6.1.2.1.2.2.1.7", Type: "integer", Value: 1},
+		{OID: "1.3.6.1.2.1.2.2.1.8", Type: "integer", Value: 2},
+	}, data.Variables)
+}
 
-	ifIndex := variables[0]
-	assert.Equal(t, ifIndex["oid"], "1.3.6.1.2.1.2.2.1.1")
-	assert.Equal(t, ifIndex["type"], "integer")
-	assert.Equal(t, ifIndex["value"], 2)
+func TestFormatPacketV1Specific(t *testing.T) {
+	packet := createTestV1SpecificPacket()
+	data, err := FormatPacketToJSON(packet)
+	require.NoError(t, err)
 
-	ifAdminStatus := variables[1]
-	assert.Equal(t, ifAdminStatus["oid"], "1.3.6.1.2.1.2.2.1.7")
-	assert.Equal(t, ifAdminStatus["type"], "integer")
-	assert.Equal(t, ifAdminStatus["value"], 1)
+	assert.Equal(t, "1.3.6.1.2.1.118.0.2", data.OID)
+	assert.Equal(t, &V1TrapFields{EnterpriseOID: "1.3.6.1.2.1.118", GenericTrap: 6, SpecificTrap: 2}, data.V1TrapFields)
 
-	ifOperStatus := variables[2]
-	assert.Equal(t, ifOperStatus["oid"], "1.3.6.1.2.1.2.2.1.8")
-	assert.Equal(t, ifOperStatus["type"], "integer")
-	assert.Equal(t, ifOperStatus["value"], 2)
+	assert.Equal(t, []TrapVariable{
+		{OID: "1.3.6.1.2.1.118.1.2.2.1.13", Type: "string", Value: "foo"},
+		{OID: "1.3.6.1.2.1.118.1.2.2.1.10", Type: "string", Value: "bar"},
+	}, data.Variables)
 }
 
-func TestFormatPacketV1Specific(t *testing.T) {
-	packet := createTestV1SpecificPacket()
+func TestFormatPacketToJSON(t *testing.T) {
+	packet := createTestPacket()
+
 	data, err := FormatPacketToJSON(packet)
 	require.NoError(t, err)
 
-	assert.Equal(t, "1.3.6.1.2.1.118.0.2", data["oid"])
-	assert.NotNil(t, data["uptime"])
-	assert.NotNil(t, data["enterprise_oid"])
-	assert.NotNil(t, data["generic_trap"])
-	assert.NotNil(t, data["specific_trap"])
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data.OID)
+	assert.Equal(t, uint32(1000), data.Uptime)
+	assert.Nil(t, data.V1TrapFields)
 
-	variables, ok := data["variables"].([]map[string]interface{})
-	assert.True(t, ok)
-	assert.Equal(t, len(variables), 2)
+	assert.Equal(t, []TrapVariable{
+		{OID: "1.3.6.1.4.1.8072.2.3.2.1", Type: "integer", Value: 1024},
+		{OID: "1.3.6.1.4.1.8072.2.3.2.2", Type: "string", Value: "test"},
+	}, data.Variables)
+}
 
-	assert.Equal(t, "1.3.6.1.2.1.118", data["enterprise_oid"])
-	assert.Equal(t, 6, data["generic_trap"])
-	assert.Equal(t, 2, data["specific_trap"])
+func TestTrapPayloadJSON(t *testing.T) {
+	content, err := json.Marshal(mustFormat(t, createTestV1GenericPacket()))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"uptime": 1000,
+		"oid": "1.3.6.1.6.3.1.1.5.3",
+		"enterprise_oid": "1.3.6.1.6.3.1.1.5",
+		"generic_trap": 2,
+		"specific_trap": 0,
+		"variables": [
+			{"oid": "1.3.6.1.2.1.2.2.1.1", "type": "integer", "value": 2},
+			{"oid": "1.3.6.1.2.1.2.2.1.7", "type": "integer", "value": 1},
+			{"oid": "1.3.6.1.2.1.2.2.1.8", "type": "integer", "value": 2}
+		]
+	}`, string(content))
 
-	alarmActiveModelPointer := variables[0]
-	assert.Equal(t, alarmActiveModelPointer["oid"], "1.3.6.1.2.1.118.1.2.2.1.13")
-	assert.Equal(t, alarmActiveModelPointer["type"], "string")
-	assert.Equal(t, alarmActiveModelPointer["value"], "foo")
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.Version3
+	packet.Content.ContextName = "vrf-blue"
+	content, err = json.Marshal(mustFormat(t, packet))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"uptime": 1000,
+		"oid": "1.3.6.1.4.1.8072.2.3.0.1",
+		"variables": [
+			{"oid": "1.3.6.1.4.1.8072.2.3.2.1", "type": "integer", "value": 1024},
+			{"oid": "1.3.6.1.4.1.8072.2.3.2.2", "type": "string", "value": "test"}
+		],
+		"context_name": "vrf-blue",
+		"context_engine_id": ""
+	}`, string(content))
+}
 
-	alarmActiveResourceID := variables[1]
-	assert.Equal(t, alarmActiveResourceID["oid"], "1.3.6.1.2.1.118.1.2.2.1.10")
-	assert.Equal(t, alarmActiveResourceID["type"], "string")
-	assert.Equal(t, alarmActiveResourceID["value"], "bar")
+func TestFormatPacketToJSONWithLargeIntegers(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Variables = append(packet.Content.Variables,
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: gosnmp.Counter64, Value: uint64(math.MaxUint64)},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.31.1.1.1.6.2", Type: gosnmp.Counter64, Value: uint64(maxSafeInteger)},
+	)
+	// the large values are emitted as numbers by default
+	variables := mustFormat(t, packet).Variables
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.31.1.1.1.6.1", Type: "counter64", Value: uint64(math.MaxUint64)}, variables[2])
+
+	serverInstance = &TrapServer{config: &Config{Namespace: "default", LargeIntegersAsStrings: true}}
+	defer func() { serverInstance = nil }()
+	variables = mustFormat(t, packet).Variables
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.31.1.1.1.6.1", Type: "counter64", Value: "18446744073709551615", ValueType: "uint64"}, variables[2])
+	// the values exactly represented as floats are still emitted as numbers
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.31.1.1.1.6.2", Type: "counter64", Value: uint64(maxSafeInteger)}, variables[3])
+	assert.Equal(t, 1024, variables[0].Value)
+}
 
+func TestFormatPacketToJSONWithFloats(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Variables = append(packet.Content.Variables,
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.8072.2.3.2.3", Type: gosnmp.OpaqueFloat, Value: float32(21.1)},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.8072.2.3.2.4", Type: gosnmp.OpaqueDouble, Value: float64(-0.125)},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.8072.2.3.2.5", Type: gosnmp.OpaqueFloat, Value: float32(math.NaN())},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.8072.2.3.2.6", Type: gosnmp.OpaqueDouble, Value: math.Inf(-1)},
+	)
+	data := mustFormat(t, packet)
+
+	variables := data.Variables
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.4.1.8072.2.3.2.3", Type: "float", Value: 21.1}, variables[2])
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.4.1.8072.2.3.2.4", Type: "double", Value: -0.125}, variables[3])
+	// the values not supported by JSON are emitted as strings
+	assert.Equal(t, "NaN", variables[4].Value)
+	assert.Equal(t, "-Inf", variables[5].Value)
+
+	_, err := json.Marshal(data)
+	assert.NoError(t, err)
 }
 
-func TestFormatPacketToJSON(t *testing.T) {
+func TestFormatPacketToJSONWithContext(t *testing.T) {
 	packet := createTestPacket()
+	assert.Nil(t, mustFormat(t, packet).ContextFields)
+
+	packet.Content.Version = gosnmp.Version3
+	packet.Content.ContextName = "vrf-blue"
+	packet.Content.ContextEngineID = "\x80\x00\x1f\x88\x04"
+	data := mustFormat(t, packet)
+	assert.Equal(t, &ContextFields{ContextName: "vrf-blue", ContextEngineID: "80001f8804"}, data.ContextFields)
+}
 
+func mustFormat(t *testing.T, packet *SnmpPacket) *TrapPayload {
 	data, err := FormatPacketToJSON(packet)
 	require.NoError(t, err)
+	return data
+}
+
+func TestFormatPacketToJSONWithTrapsDBSets(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	tenantDir := filepath.Join(dir, "tenant_a")
+	require.NoError(t, os.Mkdir(tenantDir, 0755))
+	writeTrapsDB(t, tenantDir, "tenant.yaml", userTrapsDB)
+	tenantResolver := newMultiFilesOIDResolver(dir)
+	tenantResolver.namespaceDir = tenantDir
+	require.NoError(t, tenantResolver.reload())
+	serverInstance = &TrapServer{
+		config:             &Config{Namespace: "default", Contexts: []ContextConfig{{Name: "tenant-a", Namespace: "tenant-a"}}},
+		oidResolver:        resolver,
+		namespaceResolvers: map[string]OIDResolver{"tenant-a": tenantResolver},
+	}
+	defer func() { serverInstance = nil }()
+
+	packet := createTestPacket()
+	packet.Content.Variables = []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1000)},
+		{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.4"},
+	}
+	// the files of the tenant are not used for the traps of the other namespaces
+	data := mustFormat(t, packet)
+	assert.Equal(t, "linkUp", data.TrapName)
+	assert.Equal(t, "IF-MIB", data.TrapMIB)
+
+	packet.Content.Version = gosnmp.Version3
+	packet.Content.ContextName = "tenant-a"
+	data = mustFormat(t, packet)
+	assert.Equal(t, "vendorLinkUp", data.TrapName)
+	assert.Equal(t, "VENDOR-MIB", data.TrapMIB)
+
+	// the traps which are not defined by the tenant are resolved with the other files
+	packet.Content.Variables[1].Value = ".1.3.6.1.6.3.1.1.5.3"
+	data = mustFormat(t, packet)
+	assert.Equal(t, "linkDown", data.TrapName)
+}
+
+func TestFormatPacketToJSONWithTrapsDB(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	serverInstance = &TrapServer{config: &Config{Namespace: "default"}, oidResolver: resolver}
+	defer func() { serverInstance = nil }()
+
+	packet := createTestPacket()
+	packet.Content.Variables = []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1000)},
+		{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+		{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.2.2.1.8", Type: gosnmp.Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.2.2.1.1", Type: gosnmp.Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.2.2.1.8", Type: gosnmp.Integer, Value: 42},
+		{Name: ".1.3.6.1.2.1.2.2.1.99", Type: gosnmp.OctetString, Value: []byte{0xc0, 0x40}},
+		{Name: ".1.3.6.1.2.1.2.2.1.6", Type: gosnmp.OctetString, Value: []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}},
+		{Name: ".1.3.6.1.2.1.2.2.1.8.3", Type: gosnmp.Integer, Value: 1},
+		{Name: ".1.3.6.1.2.1.2.2.1.8.3.4", Type: gosnmp.Integer, Value: 1},
+		{Name: ".1.3.6.1.2.1.2.2.2.1", Type: gosnmp.Integer, Value: 1},
+	}
+	data := mustFormat(t, packet)
+	assert.Equal(t, "linkDown", data.TrapName)
+	assert.Equal(t, "IF-MIB", data.TrapMIB)
+	// the file defining the trap and its variables is emitted to debug the conflicting definitions
+	source := filepath.Join(dir, "dd_traps_db.json")
+	assert.Equal(t, source, data.TrapSourceFile)
+
+	variables := data.Variables
+	// the instances of the objects are resolved with their index
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.1.2", Type: "integer", Value: 2, Name: "ifIndex", SourceFile: source, Index: "2"}, variables[0])
+	// both the raw and the resolved values of the enumerations are emitted
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.8", Type: "integer", Value: 2, Name: "ifOperStatus", SourceFile: source, ResolvedValue: "down"}, variables[1])
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.1", Type: "integer", Value: 2, Name: "ifIndex", SourceFile: source}, variables[2])
+	// the values missing from the enumerations are not resolved
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.8", Type: "integer", Value: 42, Name: "ifOperStatus", SourceFile: source}, variables[3])
+	// the BITS are decoded into the names of the bits which are set
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.99", Type: "string", Value: "c040", Name: "ifCapabilities", SourceFile: source, ResolvedValue: []string{"fullDuplex", "autoNegotiation", "poe"}}, variables[4])
+	// the octet strings are formatted with their format
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.6", Type: "string", Value: "00:1a:2b:3c:4d:5e", Name: "ifPhysAddress", SourceFile: source}, variables[5])
+	// the components of the index of a table column are decoded with its description
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.8.3", Type: "integer", Value: 1, Name: "ifOperStatus", SourceFile: source, ResolvedValue: "up", Index: "3", IndexComponents: map[string]interface{}{"ifIndex": 3}}, variables[6])
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.8.3.4", Type: "integer", Value: 1, Name: "ifOperStatus", SourceFile: source, ResolvedValue: "up", Index: "3.4"}, variables[7])
+	// the variables which are not defined for the trap are not resolved
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.2.1", Type: "integer", Value: 1}, variables[8])
+
+	// the unknown traps are not enriched
+	packet.Content.Variables[1].Value = ".1.3.6.1.6.3.1.1.5.5"
+	data = mustFormat(t, packet)
+	assert.Empty(t, data.TrapName)
+	assert.Nil(t, data.Variables[1].ResolvedValue)
+}
+
+func TestFormatPacketToJSONWithPayloadSchemaV2(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	serverInstance = &TrapServer{config: &Config{Namespace: "default", PayloadSchemaVersion: payloadSchemaV2}, oidResolver: resolver}
+	defer func() { serverInstance = nil }()
+
+	packet := createTestPacket()
+	packet.Content.Variables = []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1000)},
+		{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+		{Name: ".1.3.6.1.2.1.2.2.1.8.3", Type: gosnmp.Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.2.2.2.1", Type: gosnmp.Integer, Value: 1},
+	}
+	data := mustFormat(t, packet)
+	assert.Equal(t, payloadSchemaV2, data.SchemaVersion)
+	assert.Equal(t, "linkDown", data.TrapName)
+	assert.Equal(t, "A linkDown trap", data.TrapDescription)
+
+	// the descriptions of the variables are emitted inline with their names and their labels
+	source := filepath.Join(dir, "dd_traps_db.json")
+	assert.Equal(t, []TrapVariable{
+		{OID: "1.3.6.1.2.1.2.2.1.8.3", Type: "integer", Value: 2, Name: "ifOperStatus", Description: "The operational state", SourceFile: source, ResolvedValue: "down", Index: "3", IndexComponents: map[string]interface{}{"ifIndex": 3}},
+		{OID: "1.3.6.1.2.1.2.2.2.1", Type: "integer", Value: 1},
+	}, data.Variables)
+
+	// the schema is unchanged by default
+	serverInstance.config.PayloadSchemaVersion = 0
+	data = mustFormat(t, packet)
+	assert.Zero(t, data.SchemaVersion)
+	assert.Empty(t, data.TrapDescription)
+	assert.Empty(t, data.Variables[0].Description)
+}
+
+func TestRedactCommunity(t *testing.T) {
+	// v2c message with the "public" community
+	msg := []byte{0x30, 0x0d, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', 0xa7, 0x00}
+	assert.Equal(t, []byte{0x30, 0x0d, 0x02, 0x01, 0x01, 0x04, 0x06, '*', '*', '*', '*', '*', '*', 0xa7, 0x00}, redactCommunity(msg))
+	assert.Equal(t, byte('p'), msg[7])
+
+	// long form lengths
+	msg = []byte{0x30, 0x81, 0x0b, 0x02, 0x01, 0x00, 0x04, 0x81, 0x03, 'a', 'b', 'c', 0xa4, 0x00}
+	assert.Equal(t, []byte{0x30, 0x81, 0x0b, 0x02, 0x01, 0x00, 0x04, 0x81, 0x03, '*', '*', '*', 0xa4, 0x00}, redactCommunity(msg))
+
+	// the truncated communities are masked up to the end of the message
+	assert.Equal(t, []byte{0x30, 0x0d, 0x02, 0x01, 0x01, 0x04, 0x06, '*', '*'}, redactCommunity([]byte{0x30, 0x0d, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u'}))
+
+	// the v3 messages have no community
+	msg = []byte{0x30, 0x05, 0x02, 0x01, 0x03, 0x30, 0x00}
+	assert.Equal(t, msg, redactCommunity(msg))
+
+	// the messages whose community can not be found are not kept
+	assert.Nil(t, redactCommunity([]byte{0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c'}))
+	assert.Nil(t, redactCommunity([]byte{0x30, 0x0d, 0x02, 0x01}))
+}
+
+func TestFormatPacketToJSONWithRawPDU(t *testing.T) {
+	packet := createTestPacket()
+	packet.raw = []byte{0x30, 0x2a, 0x02, 0x01}
+	data := mustFormat(t, packet)
+	assert.Equal(t, "302a0201", data.RawPDU)
+	assert.False(t, data.RawPDUTruncated)
+
+	// the raw PDUs are capped
+	packet.raw = make([]byte, maxRawPDUSize+1)
+	data = mustFormat(t, packet)
+	assert.Len(t, data.RawPDU, 2*maxRawPDUSize)
+	assert.True(t, data.RawPDUTruncated)
+
+	// the raw PDUs of the traps which cannot be formatted are reported with the error
+	packet.raw = []byte{0x30, 0x2a}
+	packet.Content.Variables = packet.Content.Variables[:1]
+	_, err := FormatPacketToJSON(packet)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "raw PDU: 302a")
+}
+
+func TestFormatV1PacketToJSONWithTrapsDB(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	writeTrapsDB(t, dir, "alarm.yaml", `
+traps:
+  1.3.6.1.2.1.118.0.2:
+    name: alarmClearState
+    mib: ALARM-MIB
+vars:
+  1.3.6.1.2.1.118.1.2.2.1.10:
+    name: alarmActiveResourceId
+`)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	serverInstance = &TrapServer{config: &Config{Namespace: "default"}, oidResolver: resolver}
+	defer func() { serverInstance = nil }()
+
+	// the generic traps are resolved with the OIDs of the standard traps
+	data := mustFormat(t, createTestV1GenericPacket())
+	assert.Equal(t, "linkDown", data.TrapName)
+	assert.Equal(t, "IF-MIB", data.TrapMIB)
+	variables := data.Variables
+	assert.Equal(t, "ifIndex", variables[0].Name)
+	assert.Empty(t, variables[1].Name)
+	assert.Equal(t, "ifOperStatus", variables[2].Name)
+	assert.Equal(t, "down", variables[2].ResolvedValue)
+
+	// the enterprise-specific traps are resolved with their enterprise and their specific trap
+	data = mustFormat(t, createTestV1SpecificPacket())
+	assert.Equal(t, "alarmClearState", data.TrapName)
+	assert.Equal(t, "ALARM-MIB", data.TrapMIB)
+	variables = data.Variables
+	assert.Empty(t, variables[0].Name)
+	assert.Equal(t, "alarmActiveResourceId", variables[1].Name)
+}
 
-	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
-	assert.NotNil(t, data["uptime"])
+func TestFormatTimeTicks(t *testing.T) {
+	for ticks, expected := range map[uint32][2]string{
+		0:              {"0s", "PT0S"},
+		456:            {"4.56s", "PT4.56S"},
+		450:            {"4.5s", "PT4.5S"},
+		6000:           {"1m", "PT1M"},
+		9378456:        {"1d 2h 3m 4.56s", "P1DT2H3M4.56S"},
+		8640000:        {"1d", "P1D"},
+		8640100:        {"1d 1s", "P1DT1S"},
+		360000:         {"1h", "PT1H"},
+		math.MaxUint32: {"497d 2h 27m 52.95s", "P497DT2H27M52.95S"},
+	} {
+		humanized, iso8601 := formatTimeTicks(ticks)
+		assert.Equal(t, expected, [2]string{humanized, iso8601}, ticks)
+	}
 
-	variables, ok := data["variables"].([]map[string]interface{})
-	assert.True(t, ok)
-	assert.Equal(t, len(variables), 2)
+	// the TimeTicks variables and the uptime are formatted as durations too with the payload schema v2
+	packet := createTestPacket()
+	packet.config = &Config{PayloadSchemaVersion: payloadSchemaV2}
+	packet.Content.Variables = append(packet.Content.Variables,
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.9.1", Type: gosnmp.TimeTicks, Value: uint32(9378456)})
+	data := mustFormat(t, packet)
+	assert.Equal(t, uint32(1000), data.Uptime)
+	assert.Equal(t, "10s", data.UptimeHumanized)
+	assert.Equal(t, "PT10S", data.UptimeISO8601)
+	assert.Equal(t, TrapVariable{OID: "1.3.6.1.2.1.2.2.1.9.1", Type: "other", Value: uint32(9378456), ValueHumanized: "1d 2h 3m 4.56s", ValueISO8601: "P1DT2H3M4.56S"}, data.Variables[2])
+	assert.Empty(t, data.Variables[0].ValueHumanized)
+
+	// the payload schema v1 is unchanged
+	packet.config = &Config{PayloadSchemaVersion: payloadSchemaV1}
+	data = mustFormat(t, packet)
+	assert.Empty(t, data.UptimeHumanized)
+	assert.Empty(t, data.UptimeISO8601)
+	assert.Empty(t, data.Variables[2].ValueHumanized)
+	assert.Empty(t, data.Variables[2].ValueISO8601)
+}
 
-	heartBeatRate := variables[0]
-	assert.Equal(t, heartBeatRate["oid"], "1.3.6.1.4.1.8072.2.3.2.1")
-	assert.Equal(t, heartBeatRate["type"], "integer")
-	assert.Equal(t, heartBeatRate["value"], 1024)
+func TestResolveBits(t *testing.T) {
+	names := map[int]string{0: "a", 7: "b", 8: "c", 15: "d"}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, resolveBits([]byte{0x81, 0x81}, names))
+	assert.Equal(t, []string{"b"}, resolveBits([]byte{0x01}, names))
+	// the bits without names are emitted as their positions
+	assert.Equal(t, []string{"a", "1", "17"}, resolveBits([]byte{0xc0, 0x00, 0x40}, names))
+	assert.Equal(t, []string{}, resolveBits([]byte{0x00}, names))
+	assert.Equal(t, []string{}, resolveBits(nil, names))
+}
+
+func TestFormatOctetString(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    []byte
+		format   string
+		expected string
+		ok       bool
+	}{
+		{"mac address", []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}, macAddressFormat, "00:1a:2b:3c:4d:5e", true},
+		{"empty mac address", []byte{}, macAddressFormat, "", false},
+		{"date and time", []byte{0x07, 0xe5, 3, 4, 5, 6, 7, 8}, dateAndTimeFormat, "2021-03-04T05:06:07.8", true},
+		{"date and time with timezone", []byte{0x07, 0xe5, 3, 4, 5, 6, 7, 8, '-', 5, 30}, dateAndTimeFormat, "2021-03-04T05:06:07.8-05:30", true},
+		{"date and time with invalid timezone", []byte{0x07, 0xe5, 3, 4, 5, 6, 7, 8, 'x', 5, 30}, dateAndTimeFormat, "", false},
+		{"truncated date and time", []byte{0x07, 0xe5, 3, 4}, dateAndTimeFormat, "", false},
+		{"ipv4 address", []byte{10, 0, 0, 1}, inetAddressFormat, "10.0.0.1", true},
+		{"ipv4 address with zone", []byte{10, 0, 0, 1, 0, 0, 0, 3}, inetAddressFormat, "10.0.0.1%3", true},
+		{"ipv6 address", []byte{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, inetAddressFormat, "fe80::1", true},
+		{"invalid inet address", []byte{10, 0, 1}, inetAddressFormat, "", false},
+		{"unknown format", []byte("abc"), "unknown", "", false},
+		{"no format", []byte("abc"), "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, ok := formatOctetString(tt.value, tt.format)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, formatted)
+		})
+	}
 
-	heartBeatName := variables[1]
-	assert.Equal(t, heartBeatName["oid"], "1.3.6.1.4.1.8072.2.3.2.2")
-	assert.Equal(t, heartBeatName["type"], "string")
-	assert.Equal(t, heartBeatName["value"], "test")
+	// the octet strings which do not match their format are emitted as strings
+	variable := gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("abc")}
+	assert.Equal(t, "abc", formatValue(variable, VariableMetadata{Format: inetAddressFormat}))
 }
 
 func TestFormatPacketToJSONShouldFailIfNotEnoughVariables(t *testing.T) {
@@ -172,6 +512,89 @@ func TestGetTags(t *testing.T) {
 	})
 }
 
+func TestGetTagsForInform(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.PDUType = gosnmp.InformRequest
+	tags := GetTags(packet)
+	assert.Equal(t, tags, []string{
+		"snmp_version:2",
+		"device_namespace:default",
+		"snmp_device:127.0.0.1",
+		"snmp_inform:true",
+	})
+}
+
+func TestGetTagsForContext(t *testing.T) {
+	serverInstance = &TrapServer{config: &Config{
+		Namespace: "default",
+		Contexts: []ContextConfig{
+			{Name: "vrf-blue", Namespace: "blue", Tags: []string{"vrf:blue"}},
+			{Name: "vrf-red", Tags: []string{"vrf:red"}},
+		},
+	}}
+	defer func() { serverInstance = nil }()
+
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.Version3
+	packet.Content.ContextName = "vrf-blue"
+	assert.Equal(t, []string{
+		"snmp_version:3",
+		"device_namespace:blue",
+		"snmp_device:127.0.0.1",
+		"snmp_context:vrf-blue",
+		"vrf:blue",
+	}, GetTags(packet))
+
+	packet.Content.ContextName = "vrf-red"
+	assert.Equal(t, []string{
+		"snmp_version:3",
+		"device_namespace:default",
+		"snmp_device:127.0.0.1",
+		"snmp_context:vrf-red",
+		"vrf:red",
+	}, GetTags(packet))
+
+	// the traps sent from contexts which are not configured are only tagged with their context
+	packet.Content.ContextName = "vrf-green"
+	assert.Equal(t, []string{
+		"snmp_version:3",
+		"device_namespace:default",
+		"snmp_device:127.0.0.1",
+		"snmp_context:vrf-green",
+	}, GetTags(packet))
+}
+
+func TestGetTagsForMonitoredDevice(t *testing.T) {
+	devices.Register(devices.Device{
+		Namespace: "default",
+		IPAddress: "127.0.0.1",
+		Profile:   "cisco-nexus",
+		Vendor:    "cisco",
+		Tags:      []string{"device_namespace:default", "snmp_device:127.0.0.1", "snmp_profile:cisco-nexus", "device_vendor:cisco", "snmp_host:switch"},
+	})
+	defer devices.Unregister("default", "127.0.0.1")
+
+	// the traps are tagged with the tags of the metrics of their device, without duplicates
+	packet := createTestPacket()
+	assert.Equal(t, []string{
+		"snmp_version:2",
+		"device_namespace:default",
+		"snmp_device:127.0.0.1",
+		"snmp_profile:cisco-nexus",
+		"device_vendor:cisco",
+		"snmp_host:switch",
+	}, GetTags(packet))
+
+	// the devices are matched in the namespace of the traps
+	serverInstance = &TrapServer{config: &Config{Namespace: "other"}}
+	defer func() { serverInstance = nil }()
+	assert.Equal(t, []string{
+		"snmp_version:2",
+		"device_namespace:other",
+		"snmp_device:127.0.0.1",
+	}, GetTags(packet))
+}
+
 func TestGetTagsForUnsupportedVersionShouldStillSucceed(t *testing.T) {
 	packet := createTestPacket()
 	packet.Content.Version = 12
@@ -182,3 +605,32 @@ func TestGetTagsForUnsupportedVersionShouldStillSucceed(t *testing.T) {
 		"snmp_device:127.0.0.1",
 	})
 }
+
+func TestDecodeIndex(t *testing.T) {
+	tests := []struct {
+		name       string
+		index      []int
+		components []IndexComponent
+		expected   map[string]interface{}
+		ok         bool
+	}{
+		{"integer", []int{7}, []IndexComponent{{Name: "ifIndex", Type: "integer"}}, map[string]interface{}{"ifIndex": 7}, true},
+		{"ip address and integer", []int{10, 0, 0, 1, 161}, []IndexComponent{{Name: "addr", Type: "ip_address"}, {Name: "port", Type: "integer"}}, map[string]interface{}{"addr": "10.0.0.1", "port": 161}, true},
+		{"string", []int{3, 'e', 't', 'h', 2}, []IndexComponent{{Name: "name", Type: "string"}, {Name: "unit", Type: "integer"}}, map[string]interface{}{"name": "eth", "unit": 2}, true},
+		{"implied string", []int{'e', 't', 'h'}, []IndexComponent{{Name: "name", Type: "string", Implied: true}}, map[string]interface{}{"name": "eth"}, true},
+		{"fixed string with format", []int{0, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}, []IndexComponent{{Name: "mac", Type: "fixed_string", Length: 6, Format: macAddressFormat}}, map[string]interface{}{"mac": "00:1a:2b:3c:4d:5e"}, true},
+		{"oid", []int{3, 1, 3, 6, 9}, []IndexComponent{{Name: "oid", Type: "oid"}, {Name: "n", Type: "integer"}}, map[string]interface{}{"oid": "1.3.6", "n": 9}, true},
+		{"not described", []int{7}, nil, nil, false},
+		{"too short", []int{10, 0, 0}, []IndexComponent{{Name: "addr", Type: "ip_address"}}, nil, false},
+		{"too long", []int{7, 8}, []IndexComponent{{Name: "ifIndex", Type: "integer"}}, nil, false},
+		{"invalid string", []int{1, 300}, []IndexComponent{{Name: "name", Type: "string"}}, nil, false},
+		{"unknown type", []int{7}, []IndexComponent{{Name: "x", Type: "float"}}, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, ok := decodeIndex(test.index, test.components)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, decoded)
+		})
+	}
+}
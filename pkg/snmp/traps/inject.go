@@ -0,0 +1,176 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/common"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/gosnmp/gosnmp"
+)
+
+// InjectedTrap is a synthetic trap injected into the running server, to validate the traps DB
+// files and the logs pipelines without sending traps from the devices.
+type InjectedTrap struct {
+	TrapOID string `json:"trap_oid"`
+	// Source is the IP address of the device sending the trap, 127.0.0.1 by default
+	Source string `json:"source"`
+	// Namespace is the device namespace of the trap, the one of the server by default
+	Namespace string `json:"namespace"`
+	// Uptime is the uptime of the device in hundredths of seconds
+	Uptime    uint32             `json:"uptime"`
+	Variables []InjectedVariable `json:"variables"`
+}
+
+// InjectedVariable is a variable of an injected trap, its value is parsed according to its type:
+// integer, string, oid, ip_address, counter32, gauge32, timeticks or counter64.
+type InjectedVariable struct {
+	OID   string `json:"oid"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// InjectedTrapResult is the trap forwarded by the server for an injected trap.
+type InjectedTrapResult struct {
+	Payload *TrapPayload `json:"payload"`
+	Tags    []string     `json:"tags"`
+}
+
+// InjectTrap forwards a synthetic trap through the resolution, the formatting and the outputs of
+// the running server, as if it was received by its main listener, and returns the forwarded
+// trap. The injected traps are tagged with snmp_trap_injected:true, and are neither relayed nor
+// counted in the storm detection.
+func InjectTrap(trap InjectedTrap) (*InjectedTrapResult, error) {
+	if serverInstance == nil {
+		return nil, errors.New("the SNMP traps server is not running")
+	}
+	return serverInstance.inject(trap)
+}
+
+func (s *TrapServer) inject(trap InjectedTrap) (*InjectedTrapResult, error) {
+	packet, err := s.buildInjectedPacket(trap)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := FormatPacketToJSON(packet)
+	if err != nil {
+		return nil, err
+	}
+	result := &InjectedTrapResult{Payload: payload, Tags: GetTags(packet)}
+
+	// the packets channel is closed once the server is stopped, the send is tracked so that the
+	// channel is not closed while the trap is sent, but the server is not blocked while it waits
+	s.reloadMu.Lock()
+	if s.stopped {
+		s.reloadMu.Unlock()
+		return nil, errors.New("the SNMP traps server is stopped")
+	}
+	s.injecting.Add(1)
+	s.reloadMu.Unlock()
+	defer s.injecting.Done()
+
+	if s.correlator != nil {
+		packet.correlation = s.correlator.correlate(packet, getNamespace(packet))
+	}
+	select {
+	case s.received <- packet:
+	case <-s.stopInject:
+		return nil, errors.New("the SNMP traps server is stopped")
+	}
+	log.Infof("Injected trap %s from %s", trap.TrapOID, packet.Addr.IP)
+	trapsInjected.Add(1)
+	return result, nil
+}
+
+// buildInjectedPacket returns the v2c packet of an injected trap.
+func (s *TrapServer) buildInjectedPacket(trap InjectedTrap) (*SnmpPacket, error) {
+	if trap.TrapOID == "" {
+		return nil, errors.New("the trap OID is required")
+	}
+	source := trap.Source
+	if source == "" {
+		source = "127.0.0.1"
+	}
+	ip := net.ParseIP(source)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source IP address %q", source)
+	}
+
+	variables := []gosnmp.SnmpPDU{
+		{Name: "." + sysUpTimeInstanceOID, Type: gosnmp.TimeTicks, Value: trap.Uptime},
+		{Name: "." + snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: "." + normalizeOID(trap.TrapOID)},
+	}
+	for _, variable := range trap.Variables {
+		pdu, err := variable.pdu()
+		if err != nil {
+			return nil, fmt.Errorf("invalid variable %s: %v", variable.OID, err)
+		}
+		variables = append(variables, pdu)
+	}
+
+	config := s.getConfig()
+	if trap.Namespace != "" {
+		namespace, err := common.NormalizeNamespace(trap.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		namespaced := *config
+		namespaced.Namespace = namespace
+		config = &namespaced
+	}
+	content := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, PDUType: gosnmp.SNMPv2Trap, Variables: variables}
+	return &SnmpPacket{Content: content, Addr: &net.UDPAddr{IP: ip}, config: config, injected: true}, nil
+}
+
+// pdu returns the variable as decoded by gosnmp, with the Go type of the values of its type.
+func (v InjectedVariable) pdu() (gosnmp.SnmpPDU, error) {
+	if v.OID == "" {
+		return gosnmp.SnmpPDU{}, errors.New("the OID is required")
+	}
+	pdu := gosnmp.SnmpPDU{Name: "." + normalizeOID(v.OID)}
+	var err error
+	switch v.Type {
+	case "integer":
+		pdu.Type = gosnmp.Integer
+		pdu.Value, err = strconv.Atoi(v.Value)
+	case "string":
+		pdu.Type = gosnmp.OctetString
+		pdu.Value = []byte(v.Value)
+	case "oid":
+		pdu.Type = gosnmp.ObjectIdentifier
+		pdu.Value = "." + normalizeOID(v.Value)
+	case "ip_address":
+		pdu.Type = gosnmp.IPAddress
+		if ip := net.ParseIP(v.Value).To4(); ip != nil {
+			pdu.Value = ip.String()
+		} else {
+			err = fmt.Errorf("invalid IPv4 address %q", v.Value)
+		}
+	case "counter32", "gauge32":
+		pdu.Type = gosnmp.Counter32
+		if v.Type == "gauge32" {
+			pdu.Type = gosnmp.Gauge32
+		}
+		var value uint64
+		value, err = strconv.ParseUint(v.Value, 10, 32)
+		pdu.Value = uint(value)
+	case "timeticks":
+		pdu.Type = gosnmp.TimeTicks
+		var value uint64
+		value, err = strconv.ParseUint(v.Value, 10, 32)
+		pdu.Value = uint32(value)
+	case "counter64":
+		pdu.Type = gosnmp.Counter64
+		pdu.Value, err = strconv.ParseUint(v.Value, 10, 64)
+	default:
+		return gosnmp.SnmpPDU{}, fmt.Errorf("unsupported type %q", v.Type)
+	}
+	return pdu, err
+}
@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectTrap(t *testing.T) {
+	_, err := InjectTrap(InjectedTrap{TrapOID: "1.3.6.1.6.3.1.1.5.3"})
+	assert.Error(t, err)
+
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, Namespace: "default"}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	serverInstance.oidResolver = resolver
+
+	injected := InjectedTrap{
+		TrapOID:   ".1.3.6.1.6.3.1.1.5.3",
+		Source:    "10.0.0.1",
+		Namespace: "lab",
+		Uptime:    1000,
+		Variables: []InjectedVariable{
+			{OID: "1.3.6.1.2.1.2.2.1.1", Type: "integer", Value: "2"},
+			{OID: "1.3.6.1.2.1.2.2.1.8.2", Type: "integer", Value: "2"},
+		},
+	}
+	result, err := InjectTrap(injected)
+	require.NoError(t, err)
+	assert.Equal(t, "linkDown", result.Payload.TrapName)
+	require.Len(t, result.Payload.Variables, 2)
+	assert.Equal(t, "ifOperStatus", result.Payload.Variables[1].Name)
+	assert.Equal(t, "down", result.Payload.Variables[1].ResolvedValue)
+	assert.Contains(t, result.Tags, "snmp_device:10.0.0.1")
+	assert.Contains(t, result.Tags, "device_namespace:lab")
+	assert.Contains(t, result.Tags, "snmp_trap_injected:true")
+
+	// the injected trap is forwarded as the traps received by the listeners
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	assert.Equal(t, "10.0.0.1", packet.Addr.IP.String())
+	assert.Equal(t, result.Payload, mustFormat(t, packet))
+
+	_, err = InjectTrap(InjectedTrap{})
+	assert.Error(t, err)
+	_, err = InjectTrap(InjectedTrap{TrapOID: "1.3.6.1.6.3.1.1.5.3", Source: "device"})
+	assert.Error(t, err)
+	_, err = InjectTrap(InjectedTrap{TrapOID: "1.3.6.1.6.3.1.1.5.3", Variables: []InjectedVariable{{OID: "1.3.6.1.2.1.2.2.1.1", Type: "integer", Value: "up"}}})
+	assert.Error(t, err)
+}
+
+func TestInjectTrapStop(t *testing.T) {
+	s := &TrapServer{config: &Config{Namespace: "default", StopTimeout: 5}, received: make(PacketsChannel), stopInject: make(chan struct{})}
+	injected := make(chan error)
+	go func() {
+		_, err := s.inject(InjectedTrap{TrapOID: "1.3.6.1.6.3.1.1.5.3"})
+		injected <- err
+	}()
+
+	// the injected trap which is not consumed does not block the stop of the server
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		require.Fail(t, "the server did not stop")
+	}
+	assert.Error(t, <-injected)
+}
+
+func TestInjectedVariable(t *testing.T) {
+	for _, tt := range []struct {
+		variable InjectedVariable
+		expected gosnmp.SnmpPDU
+	}{
+		{InjectedVariable{OID: "1.3.6.1.2.1.2.2.1.2", Type: "string", Value: "eth0"}, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.OctetString, Value: []byte("eth0")}},
+		{InjectedVariable{OID: "1.3.6.1.2.1.2.2.1.2", Type: "oid", Value: "1.3.6.1.4.1"}, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1"}},
+		{InjectedVariable{OID: "1.3.6.1.2.1.2.2.1.2", Type: "ip_address", Value: "10.0.0.1"}, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.IPAddress, Value: "10.0.0.1"}},
+		{InjectedVariable{OID: "1.3.6.1.2.1.2.2.1.2", Type: "counter32", Value: "42"}, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.Counter32, Value: uint(42)}},
+		{InjectedVariable{OID: "1.3.6.1.2.1.2.2.1.2", Type: "gauge32", Value: "42"}, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.Gauge32, Value: uint(42)}},
+		{InjectedVariable{OID: "1.3.6.1.2.1.2.2.1.2", Type: "timeticks", Value: "42"}, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.TimeTicks, Value: uint32(42)}},
+		{InjectedVariable{OID: "1.3.6.1.2.1.2.2.1.2", Type: "counter64", Value: "18446744073709551615"}, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.Counter64, Value: uint64(18446744073709551615)}},
+	} {
+		pdu, err := tt.variable.pdu()
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, pdu)
+	}
+
+	for _, variable := range []InjectedVariable{
+		{Type: "integer", Value: "1"},
+		{OID: "1.3.6.1.2.1.2.2.1.2", Type: "bits", Value: "1"},
+		{OID: "1.3.6.1.2.1.2.2.1.2", Type: "ip_address", Value: "::1"},
+		{OID: "1.3.6.1.2.1.2.2.1.2", Type: "counter32", Value: "4294967296"},
+	} {
+		_, err := variable.pdu()
+		assert.Error(t, err, variable)
+	}
+}
@@ -0,0 +1,440 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/gosnmp/gosnmp"
+)
+
+// maxPacketSize is the maximum size of the UDP datagrams carrying the traps.
+const maxPacketSize = 65535
+
+// The reasons why the v3 packets cannot be decoded.
+const (
+	v3AuthFailure = "authentication"
+	v3PrivFailure = "privacy"
+	v3UnknownUser = "unknown_user"
+	// v3SecurityLevelMismatch is the reason of the packets which are decoded but whose security
+	// level is not the one of their user
+	v3SecurityLevelMismatch = "security_level"
+)
+
+// trapListener receives trap packets on a UDP socket and forwards the valid ones.
+// It is used rather than a gosnmp.TrapListener, which only supports a single v3 user, so that
+// the v3 packets are authenticated with the parameters of the user who sent them.
+type trapListener struct {
+	config  *Config
+	conn    *net.UDPConn
+	packets PacketsChannel
+	// params decode the packets, they are tried in order until the packet is authenticated
+	params []*gosnmp.GoSNMP
+	// drops is the number of packets dropped by the socket since it was opened
+	drops uint32
+	// relay re-emits the valid packets, it is nil when the traps are not relayed
+	relay *trapRelay
+	// correlator pairs the raise and clear traps, it is nil when they are not correlated
+	correlator *trapCorrelator
+	// storm summarizes the packets during the trap storms, it is nil when they are not detected
+	storm *stormDetector
+	// forwardingSince is the time in nanoseconds since which the packet being forwarded is
+	// blocked, it is 0 while no packet is forwarded
+	forwardingSince int64
+	stopped         chan struct{}
+}
+
+// startTrapListener starts listening for traps, it returns an error if the listener could not be started.
+func startTrapListener(c *Config, packets PacketsChannel, relay *trapRelay, correlator *trapCorrelator, storm *stormDetector) (*trapListener, error) {
+	l, err := newTrapListener(c, packets, relay, correlator, storm)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := listenUDP(c)
+	if err != nil {
+		return nil, err
+	}
+	l.start(conn)
+	return l, nil
+}
+
+// newTrapListener returns a listener which is not started yet, it returns an error if the
+// configuration of the listener is invalid.
+func newTrapListener(c *Config, packets PacketsChannel, relay *trapRelay, correlator *trapCorrelator, storm *stormDetector) (*trapListener, error) {
+	params, err := c.BuildUsersSNMPParams()
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		defaultParams, err := c.BuildSNMPParams()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, defaultParams)
+	}
+
+	return &trapListener{
+		config:     c,
+		packets:    packets,
+		params:     params,
+		relay:      relay,
+		correlator: correlator,
+		storm:      storm,
+		stopped:    make(chan struct{}),
+	}, nil
+}
+
+// listenUDP opens the socket of a listener.
+func listenUDP(c *Config) (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", c.Addr())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := enableSocketDrops(conn); err != nil {
+		log.Debugf("The packets dropped by the socket of listener %s will not be counted: %v", c.Addr(), err)
+	}
+	return conn, nil
+}
+
+// start receives the packets of a socket in the background.
+func (l *trapListener) start(conn *net.UDPConn) {
+	l.conn = conn
+	log.Infof("Start listening for traps on %s", l.config.Addr())
+	go l.run()
+}
+
+// run receives packets until the listener is closed or its socket is handed over.
+func (l *trapListener) run() {
+	defer close(l.stopped)
+	buf := make([]byte, maxPacketSize)
+	oob := make([]byte, getDropsAncillarySize())
+	for {
+		n, oobn, _, addr, err := l.conn.ReadMsgUDP(buf, oob)
+		if err != nil {
+			// the read deadline is only set when the socket is handed over
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrDeadlineExceeded) {
+				return
+			}
+			log.Debugf("Could not read packet on listener %s: %v", l.config.Addr(), err)
+			continue
+		}
+		l.countDrops(oob[:oobn])
+		l.handlePacket(buf[:n], addr)
+	}
+}
+
+// countDrops counts the packets dropped by the socket since the last packet was received.
+func (l *trapListener) countDrops(ancillary []byte) {
+	drops, ok := parseSocketDrops(ancillary)
+	if !ok || drops == l.drops {
+		return
+	}
+	dropped := drops - l.drops
+	l.drops = drops
+	log.Debugf("%d packets were dropped by the socket of listener %s", dropped, l.config.Addr())
+	trapsSocketDrops.Add(int64(dropped))
+	tlmSocketDrops.Add(float64(dropped), l.config.Addr())
+}
+
+// handlePacket decodes and validates a packet, acknowledges it if it is an inform, and forwards it.
+func (l *trapListener) handlePacket(msg []byte, addr *net.UDPAddr) {
+	var original []byte
+	if l.relay != nil || l.config.IncludeRawPDU {
+		// the message is copied since gosnmp modifies it while decoding it
+		original = append([]byte(nil), msg...)
+	}
+	p := l.unmarshal(msg)
+	if p == nil {
+		l.countDecodeError(msg, addr)
+		if raw := redactCommunity(original); l.config.IncludeRawPDU && raw != nil {
+			dump, _ := formatRawPDU(raw)
+			log.Debugf("Raw PDU of the packet from %s which could not be decoded: %s", addr.String(), dump)
+		}
+		return
+	}
+	countVersion(p.Version)
+	if err := validateSecurityLevel(p, l.config); err != nil {
+		user := p.SecurityParameters.(*gosnmp.UsmSecurityParameters).UserName
+		log.Warnf("Invalid security level of user %q from %s on listener %s (%v), dropping packet", user, addr.String(), l.config.Addr(), err)
+		trapsV3SecurityLevelErrors.Add(1)
+		tlmV3SecurityErrors.Inc(user, v3SecurityLevelMismatch)
+		return
+	}
+	if err := validatePacket(p, l.config); err != nil {
+		log.Warnf("Invalid credentials from %s on listener %s, dropping packet", addr.String(), l.config.Addr())
+		trapsPacketsAuthErrors.Add(1)
+		return
+	}
+	log.Debugf("Packet received from %s on listener %s", addr.String(), l.config.Addr())
+	trapsPackets.Add(1)
+	if p.PDUType == gosnmp.InformRequest {
+		trapsInforms.Add(1)
+		l.acknowledge(p, addr)
+	}
+	if l.relay != nil {
+		l.relay.relay(p, original, addr)
+	}
+	packet := &SnmpPacket{Content: p, Addr: addr, config: l.config}
+	if l.storm != nil && !l.storm.observe(packet) {
+		return
+	}
+	if l.config.IncludeRawPDU {
+		packet.raw = redactCommunity(original)
+	}
+	if l.correlator != nil {
+		packet.correlation = l.correlator.correlate(packet, l.config.Namespace)
+	}
+	atomic.StoreInt64(&l.forwardingSince, time.Now().UnixNano())
+	l.packets <- packet
+	atomic.StoreInt64(&l.forwardingSince, 0)
+}
+
+// isAlive returns whether the loop of the listener receives the packets: it is running and it
+// is not blocked forwarding a packet for longer than the timeout.
+func (l *trapListener) isAlive(timeout time.Duration) bool {
+	select {
+	case <-l.stopped:
+		return false
+	default:
+	}
+	since := atomic.LoadInt64(&l.forwardingSince)
+	return since == 0 || time.Since(time.Unix(0, since)) < timeout
+}
+
+// unmarshal decodes a packet, the v3 packets with the parameters of the user who sent them.
+// It returns nil if the packet could not be decoded or authenticated.
+func (l *trapListener) unmarshal(msg []byte) *gosnmp.SnmpPacket {
+	for i, params := range l.params {
+		data := msg
+		if i < len(l.params)-1 {
+			// gosnmp blanks the authentication parameters of the message while decoding it
+			data = append([]byte(nil), msg...)
+		}
+		p := unmarshalTrap(params, data)
+		if p == nil {
+			continue
+		}
+		if p.Version != gosnmp.Version3 {
+			return p
+		}
+		// gosnmp does not check the user of the packets which are not authenticated
+		user, ok := p.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		expected, _ := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if ok && expected != nil && user.UserName == expected.UserName {
+			return p
+		}
+	}
+	return nil
+}
+
+// unmarshalTrap decodes a packet with params, it returns nil if the packet could not be decoded.
+func unmarshalTrap(params *gosnmp.GoSNMP, msg []byte) (p *gosnmp.SnmpPacket) {
+	defer func() {
+		// gosnmp panics while decoding some truncated packets
+		if r := recover(); r != nil {
+			p = nil
+		}
+	}()
+	return params.UnmarshalTrap(msg, false)
+}
+
+// countDecodeError counts a packet which could not be decoded, the v3 packets by the reason why
+// they could not be authenticated or decrypted.
+func (l *trapListener) countDecodeError(msg []byte, addr *net.UDPAddr) {
+	header := decodeHeader(msg)
+	if header == nil {
+		l.countMalformed(addr)
+		return
+	}
+	countVersion(header.Version)
+	user, ok := header.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if header.Version != gosnmp.Version3 || !ok {
+		l.countMalformed(addr)
+		return
+	}
+
+	reason := v3UnknownUser
+	for _, params := range l.params {
+		expected, _ := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if expected == nil || expected.UserName != user.UserName {
+			continue
+		}
+		if reason = v3DecodeFailure(expected, header.MsgFlags, msg); reason == "" {
+			l.countMalformed(addr)
+			return
+		}
+		break
+	}
+
+	log.Debugf("Could not decode the v3 packet of user %q from %s on listener %s (%s), dropping packet", user.UserName, addr.String(), l.config.Addr(), reason)
+	switch reason {
+	case v3AuthFailure:
+		trapsV3AuthErrors.Add(1)
+	case v3PrivFailure:
+		trapsV3PrivErrors.Add(1)
+	case v3UnknownUser:
+		trapsV3UnknownUsers.Add(1)
+	}
+	// the users which are not configured are not tagged, as they are not trusted
+	tagUser := user.UserName
+	if reason == v3UnknownUser {
+		tagUser = "unknown"
+	}
+	tlmV3SecurityErrors.Inc(tagUser, reason)
+}
+
+// countMalformed counts a packet which could not be decoded.
+func (l *trapListener) countMalformed(addr *net.UDPAddr) {
+	log.Debugf("Could not decode packet from %s on listener %s, dropping packet", addr.String(), l.config.Addr())
+	trapsPacketsMalformed.Add(1)
+	tlmMalformedPackets.Inc()
+}
+
+// v3DecodeFailure returns why a v3 packet of a configured user could not be decoded with the
+// security parameters of the user, or an empty string when the packet is malformed. gosnmp does
+// not return why it could not decode a trap: it authenticates the packets before decrypting and
+// decoding them, so a packet which can be decrypted and decoded without being authenticated
+// failed its authentication, and the encrypted packets which can not be decrypted and decoded
+// are counted as decryption failures, including the ones which could not be authenticated either.
+func v3DecodeFailure(user *gosnmp.UsmSecurityParameters, flags gosnmp.SnmpV3MsgFlags, msg []byte) string {
+	params := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           flags,
+		SecurityParameters: user.Copy(),
+		Logger:             gosnmp.NewLogger(&trapLogger{}),
+	}
+	if _, err := decodePacket(params, msg); err == nil {
+		return v3AuthFailure
+	}
+	if flags&gosnmp.AuthPriv > gosnmp.AuthNoPriv {
+		return v3PrivFailure
+	}
+	return ""
+}
+
+// decodePacket decodes a packet without authenticating it.
+func decodePacket(params *gosnmp.GoSNMP, msg []byte) (p *gosnmp.SnmpPacket, err error) {
+	defer func() {
+		// gosnmp panics while decoding some truncated packets
+		if r := recover(); r != nil {
+			p, err = nil, fmt.Errorf("could not decode packet: %v", r)
+		}
+	}()
+	return params.SnmpDecodePacket(append([]byte(nil), msg...))
+}
+
+// headerUserName is the user of the params decoding the headers, which can not be the user
+// of a packet since it is not printable, so that it tells whether the header of the v3
+// packets has been decoded.
+const headerUserName = "\x00header"
+
+// decodeHeader decodes the header of a packet, with its version and the user of the v3
+// packets, it returns nil if the header could not be decoded.
+func decodeHeader(msg []byte) *gosnmp.SnmpPacket {
+	// the protocols are only set so that the header of the authenticated and encrypted packets
+	// is decoded, the packets are neither authenticated nor decrypted
+	params := &gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 headerUserName,
+			AuthenticationProtocol:   gosnmp.MD5,
+			AuthenticationPassphrase: "header",
+			PrivacyProtocol:          gosnmp.DES,
+			PrivacyPassphrase:        "header",
+		},
+		Logger: gosnmp.NewLogger(&trapLogger{}),
+	}
+	p, err := decodePacket(params, msg)
+	if err == nil {
+		return p
+	}
+	if p == nil {
+		return nil
+	}
+	// the header is decoded before the rest of the packet, which can not be decrypted
+	switch p.Version {
+	case gosnmp.Version3:
+		if user, ok := p.SecurityParameters.(*gosnmp.UsmSecurityParameters); !ok || user.UserName == headerUserName {
+			return nil
+		}
+	case gosnmp.Version1, gosnmp.Version2c:
+		if p.Community == "" {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return p
+}
+
+// countVersion counts a received packet by its SNMP version.
+func countVersion(version gosnmp.SnmpVersion) {
+	switch version {
+	case gosnmp.Version1:
+		trapsPacketsV1.Add(1)
+	case gosnmp.Version2c:
+		trapsPacketsV2c.Add(1)
+	case gosnmp.Version3:
+		trapsPacketsV3.Add(1)
+	}
+	tlmPackets.Inc(formatVersion(&SnmpPacket{Content: &gosnmp.SnmpPacket{Version: version}}))
+}
+
+// acknowledge sends the response to an inform back to its sender, with the same variables.
+// See: https://tools.ietf.org/html/rfc3416#section-4.2.7
+func (l *trapListener) acknowledge(p *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	response := *p
+	if p.SecurityParameters != nil {
+		response.SecurityParameters = p.SecurityParameters.Copy()
+	}
+	response.PDUType = gosnmp.GetResponse
+	response.Error = gosnmp.NoError
+	response.ErrorIndex = 0
+
+	out, err := response.MarshalMsg()
+	if err != nil {
+		log.Warnf("Could not build the response to the inform from %s: %v", addr.String(), err)
+		return
+	}
+	if _, err := l.conn.WriteToUDP(out, addr); err != nil {
+		log.Warnf("Could not send the response to the inform from %s: %v", addr.String(), err)
+	}
+}
+
+// close stops the listener, and waits for the packet being handled to be forwarded.
+func (l *trapListener) close() {
+	l.conn.Close()
+	<-l.stopped
+}
+
+// handOver stops the listener once the packet being handled is forwarded, and hands its socket
+// over to the listener replacing it. The packets received in the meantime are queued by the
+// socket, so that none of them is dropped.
+func (l *trapListener) handOver(next *trapListener) error {
+	if err := l.conn.SetReadDeadline(time.Now()); err != nil {
+		return err
+	}
+	<-l.stopped
+	if err := l.conn.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+	next.drops = l.drops
+	next.start(l.conn)
+	return nil
+}
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// getDropsAncillarySize returns the size of the ancillary data carrying the number of packets
+// dropped by the socket.
+func getDropsAncillarySize() int {
+	return unix.CmsgSpace(4)
+}
+
+// enableSocketDrops asks the kernel to send the number of packets dropped by the socket, as
+// its receive buffer was full, with the ancillary data of the packets.
+func enableSocketDrops(conn *net.UDPConn) error {
+	rawconn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rawconn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RXQ_OVFL, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// parseSocketDrops returns the number of packets dropped by the socket since it was opened,
+// from the ancillary data of a packet.
+func parseSocketDrops(ancillary []byte) (uint32, bool) {
+	messages, err := unix.ParseSocketControlMessage(ancillary)
+	if err != nil {
+		return 0, false
+	}
+	for _, message := range messages {
+		if message.Header.Level == unix.SOL_SOCKET && message.Header.Type == unix.SO_RXQ_OVFL && len(message.Data) >= 4 {
+			// the counter is in the native byte order
+			return *(*uint32)(unsafe.Pointer(&message.Data[0])), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+//go:build !linux
+// +build !linux
+
+package traps
+
+import (
+	"errors"
+	"net"
+)
+
+// getDropsAncillarySize returns 0 on non-linux hosts
+func getDropsAncillarySize() int {
+	return 0
+}
+
+// enableSocketDrops returns a "not implemented" error on non-linux hosts
+func enableSocketDrops(conn *net.UDPConn) error {
+	return errors.New("only implemented on Linux hosts")
+}
+
+// parseSocketDrops never finds the number of dropped packets on non-linux hosts
+func parseSocketDrops(ancillary []byte) (uint32, bool) {
+	return 0, false
+}
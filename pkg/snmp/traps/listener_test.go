@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"encoding/hex"
+	"expvar"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertCounted asserts that an expvar counter is eventually incremented.
+func assertCounted(t *testing.T, counter *expvar.Int, before int64) {
+	assert.Eventually(t, func() bool { return counter.Value() == before+1 }, 3*time.Second, 10*time.Millisecond)
+}
+
+// v3SecurityParams returns the security parameters of the traps sent by a v3 user.
+func v3SecurityParams(user string, authKey string, privKey string) *gosnmp.UsmSecurityParameters {
+	return &gosnmp.UsmSecurityParameters{
+		UserName:                 user,
+		AuthoritativeEngineID:    "foo",
+		AuthenticationPassphrase: authKey,
+		AuthenticationProtocol:   gosnmp.SHA,
+		PrivacyPassphrase:        privKey,
+		PrivacyProtocol:          gosnmp.AES,
+	}
+}
+
+func TestListenerTelemetry(t *testing.T) {
+	userV3 := UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes"}
+	config := Config{Port: GetPort(t), Users: []UserV3{userV3}}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	packetsV3 := trapsPacketsV3.Value()
+	sendTestV3Trap(t, config, v3SecurityParams("user", "password", "password"))
+	require.NotNil(t, receivePacket(t))
+	assert.Equal(t, packetsV3+1, trapsPacketsV3.Value())
+
+	authErrors := trapsV3AuthErrors.Value()
+	sendTestV3Trap(t, config, v3SecurityParams("user", "wrong_password", "password"))
+	assertCounted(t, &trapsV3AuthErrors, authErrors)
+
+	privErrors := trapsV3PrivErrors.Value()
+	sendTestV3Trap(t, config, v3SecurityParams("user", "password", "wrong_password"))
+	assertCounted(t, &trapsV3PrivErrors, privErrors)
+
+	unknownUsers := trapsV3UnknownUsers.Value()
+	sendTestV3Trap(t, config, v3SecurityParams("unknown", "password", "password"))
+	assertCounted(t, &trapsV3UnknownUsers, unknownUsers)
+	assert.Equal(t, packetsV3+4, trapsPacketsV3.Value())
+
+	malformed := trapsPacketsMalformed.Value()
+	conn, err := net.Dial("udp", config.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("not a trap"))
+	require.NoError(t, err)
+	assertCounted(t, &trapsPacketsMalformed, malformed)
+	// gosnmp panics while decoding this truncated v3 packet
+	_, err = conn.Write([]byte{0x30, 0x03, 0x02, 0x01, 0x03})
+	require.NoError(t, err)
+	assertCounted(t, &trapsPacketsMalformed, malformed+1)
+	assertNoPacketReceived(t)
+
+	// the listener still receives the traps
+	sendTestV3Trap(t, config, v3SecurityParams("user", "password", "password"))
+	require.NotNil(t, receivePacket(t))
+}
+
+func TestListenerSecurityLevel(t *testing.T) {
+	config := Config{Port: GetPort(t), Users: []UserV3{
+		{Username: "strict", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes", SecurityLevel: "authPriv"},
+		{Username: "lenient", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes"},
+	}}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	sendAuthNoPrivTrap := func(user string) {
+		params, err := config.BuildSNMPParams()
+		require.NoError(t, err)
+		params.MsgFlags = gosnmp.AuthNoPriv
+		securityParams := v3SecurityParams(user, "password", "")
+		securityParams.PrivacyProtocol = gosnmp.NoPriv
+		params.SecurityParameters = securityParams
+		params.Timeout = time.Second
+		params.Retries = 1
+		require.NoError(t, params.Connect())
+		defer params.Conn.Close()
+		_, err = params.SendTrap(NetSNMPExampleHeartbeatNotification)
+		require.NoError(t, err)
+	}
+
+	// the traps of the users whose security level is set must have this security level
+	levelErrors := trapsV3SecurityLevelErrors.Value()
+	sendAuthNoPrivTrap("strict")
+	assertCounted(t, &trapsV3SecurityLevelErrors, levelErrors)
+	assertNoPacketReceived(t)
+	sendTestV3Trap(t, config, v3SecurityParams("strict", "password", "password"))
+	require.NotNil(t, receivePacket(t))
+
+	// the security level of the other users is not checked
+	sendAuthNoPrivTrap("lenient")
+	require.NotNil(t, receivePacket(t))
+	assert.Equal(t, levelErrors+1, trapsV3SecurityLevelErrors.Value())
+}
+
+func TestListenerRawPDU(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, IncludeRawPDU: true}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	sendTestV2Trap(t, config, "public")
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	// the raw PDU is the message which was received, without its community string
+	assert.NotContains(t, string(packet.raw), "public")
+	params := &gosnmp.GoSNMP{Logger: gosnmp.NewLogger(&trapLogger{})}
+	decoded := params.UnmarshalTrap(append([]byte(nil), packet.raw...), false)
+	require.NotNil(t, decoded)
+	assert.Equal(t, "******", decoded.Community)
+	assert.Equal(t, packet.Content.Variables, decoded.Variables)
+	data, err := FormatPacketToJSON(packet)
+	require.NoError(t, err)
+	assert.NotContains(t, data.RawPDU, hex.EncodeToString([]byte("public")))
+}
+
+func TestListenerVersionTelemetry(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	packetsV1 := trapsPacketsV1.Value()
+	sendTestV1GenericTrap(t, config, "public")
+	require.NotNil(t, receivePacket(t))
+	assert.Equal(t, packetsV1+1, trapsPacketsV1.Value())
+
+	packetsV2c := trapsPacketsV2c.Value()
+	sendTestV2Trap(t, config, "public")
+	require.NotNil(t, receivePacket(t))
+	assert.Equal(t, packetsV2c+1, trapsPacketsV2c.Value())
+}
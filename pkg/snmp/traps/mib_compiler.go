@@ -0,0 +1,985 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The kinds of the nodes of the MIBs which are compiled into the traps DB.
+const (
+	mibNodeOther = iota
+	mibNodeObjectType
+	mibNodeNotificationType
+	mibNodeTrapType
+)
+
+// maxMIBTypeDepth bounds the chains of textual conventions followed to resolve a syntax.
+const maxMIBTypeDepth = 16
+
+// mibRoots are the well-known OIDs defined by the SMI modules, so that the MIBs can be
+// compiled without them.
+var mibRoots = map[string][]int{
+	"ccitt":           {0},
+	"zeroDotZero":     {0, 0},
+	"iso":             {1},
+	"org":             {1, 3},
+	"dod":             {1, 3, 6},
+	"internet":        {1, 3, 6, 1},
+	"directory":       {1, 3, 6, 1, 1},
+	"mgmt":            {1, 3, 6, 1, 2},
+	"mib-2":           {1, 3, 6, 1, 2, 1},
+	"system":          {1, 3, 6, 1, 2, 1, 1},
+	"interfaces":      {1, 3, 6, 1, 2, 1, 2},
+	"transmission":    {1, 3, 6, 1, 2, 1, 10},
+	"snmp":            {1, 3, 6, 1, 2, 1, 11},
+	"experimental":    {1, 3, 6, 1, 3},
+	"private":         {1, 3, 6, 1, 4},
+	"enterprises":     {1, 3, 6, 1, 4, 1},
+	"security":        {1, 3, 6, 1, 5},
+	"snmpV2":          {1, 3, 6, 1, 6},
+	"snmpDomains":     {1, 3, 6, 1, 6, 1},
+	"snmpProxys":      {1, 3, 6, 1, 6, 2},
+	"snmpModules":     {1, 3, 6, 1, 6, 3},
+	"snmpTraps":       {1, 3, 6, 1, 6, 3, 1, 1, 5},
+	"joint-iso-ccitt": {2},
+}
+
+// mibTypeFormats are the formats of the textual conventions of the octet strings which are
+// displayed in a human-readable form.
+var mibTypeFormats = map[string]string{
+	"MacAddress":  macAddressFormat,
+	"PhysAddress": macAddressFormat,
+	"DateAndTime": dateAndTimeFormat,
+	"InetAddress": inetAddressFormat,
+}
+
+// mibDisplayHintFormats are the formats of the display hints of the textual conventions.
+var mibDisplayHintFormats = map[string]string{
+	"1x:":                          macAddressFormat,
+	"2d-1d-1d,1d:1d:1d.1d,1a1d:1d": dateAndTimeFormat,
+}
+
+// mibBuiltinTypes are the textual conventions defined by the SMI modules, so that the MIBs can
+// be compiled without them.
+var mibBuiltinTypes = map[string]*mibType{
+	"TruthValue":      {syntax: &mibSyntax{typeName: "INTEGER", namedNumbers: map[int]string{1: "true", 2: "false"}}},
+	"MacAddress":      {syntax: &mibSyntax{typeName: "OCTET STRING", size: 6}},
+	"PhysAddress":     {syntax: &mibSyntax{typeName: "OCTET STRING"}},
+	"DisplayString":   {syntax: &mibSyntax{typeName: "OCTET STRING"}},
+	"SnmpAdminString": {syntax: &mibSyntax{typeName: "OCTET STRING"}},
+	"DateAndTime":     {syntax: &mibSyntax{typeName: "OCTET STRING"}},
+	"InetAddress":     {syntax: &mibSyntax{typeName: "OCTET STRING"}},
+}
+
+// mibIndexTypes are the encodings in the OIDs of the instances of the index components of
+// the base types.
+var mibIndexTypes = map[string]string{
+	"INTEGER":           "integer",
+	"Integer32":         "integer",
+	"Unsigned32":        "integer",
+	"Gauge32":           "integer",
+	"Counter32":         "integer",
+	"TimeTicks":         "integer",
+	"IpAddress":         "ip_address",
+	"OCTET STRING":      "string",
+	"BITS":              "string",
+	"OBJECT IDENTIFIER": "oid",
+}
+
+// mibToken is a token of a MIB file.
+type mibToken struct {
+	value string
+	// quoted is true for the strings, whose value is unquoted
+	quoted bool
+	line   int
+}
+
+// mibSyntax is the syntax of an object or of a textual convention.
+type mibSyntax struct {
+	typeName     string
+	namedNumbers map[int]string
+	sequence     bool
+	// size is the size of a fixed-size octet string, 0 when the size varies
+	size int
+}
+
+// mibType is a textual convention or a type assignment.
+type mibType struct {
+	syntax      *mibSyntax
+	displayHint string
+}
+
+// mibOIDValue is the value of an OID assignment, relative to its parent, or absolute when the
+// parent is empty.
+type mibOIDValue struct {
+	parent string
+	ids    []int
+}
+
+// mibNode is a node of the OID tree defined by a MIB.
+type mibNode struct {
+	name        string
+	module      string
+	kind        int
+	value       mibOIDValue
+	description string
+	syntax      *mibSyntax
+	// enterprise and trapNumber identify the SNMPv1 traps
+	enterprise string
+	trapNumber int
+	// index and augments define the index of the rows of a table
+	index    []mibIndexName
+	augments string
+}
+
+// mibIndexName is an object of the INDEX clause of a row.
+type mibIndexName struct {
+	name    string
+	implied bool
+}
+
+// mibModule is a module of a MIB file.
+type mibModule struct {
+	name  string
+	nodes []*mibNode
+	types map[string]*mibType
+	// file is the MIB file defining the module
+	file string
+}
+
+// mibCompiler compiles the traps and the objects of MIB modules into the content of a traps DB
+// file.
+type mibCompiler struct {
+	modules []*mibModule
+	// nodes indexes the nodes by module and by name
+	nodes map[string]map[string]*mibNode
+	types map[string]*mibType
+	oids  map[*mibNode][]int
+}
+
+// compileMIBs compiles the traps, notifications and objects of the MIB files of a directory.
+// The files which cannot be parsed are skipped.
+func compileMIBs(dir string) (trapDBFileContent, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return trapDBFileContent{}, err
+	}
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	compiler := newMIBCompiler()
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			log.Warnf("Could not read MIB file %s: %v", name, err)
+			continue
+		}
+		modules, err := parseMIB(data)
+		if err != nil {
+			log.Warnf("Could not parse MIB file %s: %v", name, err)
+			continue
+		}
+		for _, module := range modules {
+			module.file = filepath.Join(dir, name)
+		}
+		compiler.add(modules...)
+	}
+	return compiler.compile(), nil
+}
+
+func newMIBCompiler() *mibCompiler {
+	return &mibCompiler{
+		nodes: make(map[string]map[string]*mibNode),
+		types: make(map[string]*mibType),
+		oids:  make(map[*mibNode][]int),
+	}
+}
+
+// add adds parsed modules to the compiler, a module defined by several files is the one of
+// the last file.
+func (c *mibCompiler) add(modules ...*mibModule) {
+	for _, module := range modules {
+		if _, ok := c.nodes[module.name]; ok {
+			log.Debugf("MIB module %s is redefined", module.name)
+			for i, m := range c.modules {
+				if m.name == module.name {
+					c.modules = append(c.modules[:i], c.modules[i+1:]...)
+					break
+				}
+			}
+		}
+		c.modules = append(c.modules, module)
+		nodes := make(map[string]*mibNode, len(module.nodes))
+		for _, node := range module.nodes {
+			nodes[node.name] = node
+		}
+		c.nodes[module.name] = nodes
+		for name, t := range module.types {
+			c.types[name] = t
+		}
+	}
+}
+
+// compile resolves the OIDs and the syntaxes of the nodes of the modules.
+func (c *mibCompiler) compile() trapDBFileContent {
+	content := trapDBFileContent{Traps: make(trapSpec), Variables: make(variableSpec)}
+	for _, module := range c.modules {
+		for _, node := range module.nodes {
+			var oid []int
+			switch node.kind {
+			case mibNodeTrapType:
+				enterprise, ok := c.resolveName(node.enterprise, node.module, nil)
+				if !ok {
+					log.Debugf("Could not resolve the enterprise %s of trap %s::%s", node.enterprise, node.module, node.name)
+					continue
+				}
+				// the SNMPv1 traps are identified by the enterprise, 0 and the specific trap number
+				// See: https://tools.ietf.org/html/rfc3584#section-3.1
+				oid = append(append(append([]int(nil), enterprise...), 0), node.trapNumber)
+			case mibNodeNotificationType, mibNodeObjectType:
+				var ok bool
+				if oid, ok = c.resolveOID(node, nil); !ok {
+					log.Debugf("Could not resolve the OID of %s::%s", node.module, node.name)
+					continue
+				}
+			default:
+				continue
+			}
+
+			if node.kind == mibNodeObjectType {
+				if node.syntax == nil || c.isSequence(node.syntax) {
+					// tables and rows are not variables
+					continue
+				}
+				metadata := c.variableMetadata(node)
+				metadata.Index = c.columnIndex(node)
+				metadata.SourceFile = module.file
+				content.Variables[formatMIBOID(oid)] = metadata
+			} else {
+				content.Traps[formatMIBOID(oid)] = TrapMetadata{Name: node.name, MIBName: node.module, Description: node.description, SourceFile: module.file}
+			}
+		}
+	}
+	return content
+}
+
+// resolveOID returns the OID of a node.
+func (c *mibCompiler) resolveOID(node *mibNode, visiting map[*mibNode]bool) ([]int, bool) {
+	if oid, ok := c.oids[node]; ok {
+		return oid, true
+	}
+	if visiting[node] {
+		return nil, false
+	}
+	if visiting == nil {
+		visiting = make(map[*mibNode]bool)
+	}
+	visiting[node] = true
+
+	var oid []int
+	if node.value.parent != "" {
+		parent, ok := c.resolveName(node.value.parent, node.module, visiting)
+		if !ok {
+			return nil, false
+		}
+		oid = append(oid, parent...)
+	}
+	oid = append(oid, node.value.ids...)
+	c.oids[node] = oid
+	return oid, true
+}
+
+// resolveName returns the OID of a name, defined by a module, preferably the module which
+// references it, or by the SMI modules.
+func (c *mibCompiler) resolveName(name string, module string, visiting map[*mibNode]bool) ([]int, bool) {
+	if node, ok := c.lookupNode(name, module); ok {
+		return c.resolveOID(node, visiting)
+	}
+	oid, ok := mibRoots[name]
+	return oid, ok
+}
+
+// lookupNode returns the node of a name, preferably the one of the module which references it.
+func (c *mibCompiler) lookupNode(name string, module string) (*mibNode, bool) {
+	if node, ok := c.nodes[module][name]; ok {
+		return node, true
+	}
+	for _, m := range c.modules {
+		if node, ok := c.nodes[m.name][name]; ok {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// columnIndex returns the components of the index of the rows of the table of a column, or
+// nil when the object is not a column or its index cannot be resolved.
+func (c *mibCompiler) columnIndex(node *mibNode) []IndexComponent {
+	if len(node.value.ids) != 1 || node.value.parent == "" {
+		return nil
+	}
+	row, ok := c.lookupNode(node.value.parent, node.module)
+	if ok && row.augments != "" {
+		// an augmenting row has the index of the row it augments
+		row, ok = c.lookupNode(row.augments, row.module)
+	}
+	if !ok || len(row.index) == 0 {
+		return nil
+	}
+
+	components := make([]IndexComponent, 0, len(row.index))
+	for _, indexName := range row.index {
+		object, ok := c.lookupNode(indexName.name, row.module)
+		if !ok {
+			log.Debugf("Could not resolve the index %s of %s::%s", indexName.name, row.module, row.name)
+			return nil
+		}
+		component, ok := c.indexComponent(object)
+		if !ok {
+			log.Debugf("Could not resolve the type of the index %s of %s::%s", indexName.name, row.module, row.name)
+			return nil
+		}
+		component.Implied = indexName.implied
+		components = append(components, component)
+	}
+	return components
+}
+
+// indexComponent returns the encoding of an object used as an index component, from its base
+// type and the size of its octet strings.
+func (c *mibCompiler) indexComponent(node *mibNode) (IndexComponent, bool) {
+	component := IndexComponent{Name: node.name, Format: c.variableMetadata(node).Format}
+	size := 0
+	syntax := node.syntax
+	for depth := 0; syntax != nil && depth < maxMIBTypeDepth; depth++ {
+		if size == 0 {
+			size = syntax.size
+		}
+		if indexType, ok := mibIndexTypes[syntax.typeName]; ok {
+			component.Type = indexType
+			if indexType == "string" && size > 0 {
+				component.Type = "fixed_string"
+				component.Length = size
+			}
+			return component, true
+		}
+		t, ok := c.lookupType(syntax.typeName)
+		if !ok {
+			break
+		}
+		syntax = t.syntax
+	}
+	return IndexComponent{}, false
+}
+
+// variableMetadata returns the metadata of an object, its enumeration, bits and format are
+// resolved through its textual conventions.
+func (c *mibCompiler) variableMetadata(node *mibNode) VariableMetadata {
+	metadata := VariableMetadata{Name: node.name, MIBName: node.module, Description: node.description}
+	syntax := node.syntax
+	for depth := 0; syntax != nil && depth < maxMIBTypeDepth; depth++ {
+		if metadata.Enumeration == nil && metadata.Bits == nil && len(syntax.namedNumbers) > 0 {
+			if syntax.typeName == "BITS" {
+				metadata.Bits = syntax.namedNumbers
+			} else {
+				metadata.Enumeration = syntax.namedNumbers
+			}
+		}
+		if metadata.Format == "" {
+			metadata.Format = mibTypeFormats[syntax.typeName]
+		}
+		t, ok := c.lookupType(syntax.typeName)
+		if !ok {
+			break
+		}
+		if metadata.Format == "" {
+			metadata.Format = mibDisplayHintFormats[t.displayHint]
+		}
+		syntax = t.syntax
+	}
+	return metadata
+}
+
+// isSequence returns whether a syntax is the one of a table or of a row.
+func (c *mibCompiler) isSequence(syntax *mibSyntax) bool {
+	for depth := 0; syntax != nil && depth < maxMIBTypeDepth; depth++ {
+		if syntax.sequence {
+			return true
+		}
+		t, ok := c.lookupType(syntax.typeName)
+		if !ok {
+			return false
+		}
+		syntax = t.syntax
+	}
+	return false
+}
+
+// lookupType returns a textual convention or a type defined by the modules or by the SMI.
+func (c *mibCompiler) lookupType(name string) (*mibType, bool) {
+	if t, ok := c.types[name]; ok {
+		return t, true
+	}
+	t, ok := mibBuiltinTypes[name]
+	return t, ok
+}
+
+func formatMIBOID(oid []int) string {
+	ids := make([]string, len(oid))
+	for i, id := range oid {
+		ids[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ids, ".")
+}
+
+// parseMIB parses the modules of a MIB file.
+func parseMIB(data []byte) ([]*mibModule, error) {
+	tokens, err := tokenizeMIB(data)
+	if err != nil {
+		return nil, err
+	}
+	p := &mibParser{tokens: tokens}
+	var modules []*mibModule
+	for !p.done() {
+		module, err := p.parseModule()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, module)
+	}
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("no MIB module defined")
+	}
+	return modules, nil
+}
+
+// tokenizeMIB splits a MIB file into tokens, without the comments.
+func tokenizeMIB(data []byte) ([]mibToken, error) {
+	var tokens []mibToken
+	line := 1
+	for i := 0; i < len(data); {
+		switch ch := data[i]; {
+		case ch == '\n':
+			line++
+			i++
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\f':
+			i++
+		case ch == '-' && i+1 < len(data) && data[i+1] == '-':
+			// the comments end at the end of the line or at the next "--"
+			i += 2
+			for i < len(data) && data[i] != '\n' {
+				if data[i] == '-' && i+1 < len(data) && data[i+1] == '-' {
+					i += 2
+					break
+				}
+				i++
+			}
+		case ch == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				end++
+			}
+			if end == len(data) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			value := string(data[i+1 : end])
+			tokens = append(tokens, mibToken{value: value, quoted: true, line: line})
+			line += strings.Count(value, "\n")
+			i = end + 1
+		case ch == ':' && i+2 < len(data) && data[i+1] == ':' && data[i+2] == '=':
+			tokens = append(tokens, mibToken{value: "::=", line: line})
+			i += 3
+		case ch == '.' && i+1 < len(data) && data[i+1] == '.':
+			tokens = append(tokens, mibToken{value: "..", line: line})
+			i += 2
+		case isMIBSymbol(ch):
+			tokens = append(tokens, mibToken{value: string(ch), line: line})
+			i++
+		default:
+			end := i
+			for end < len(data) && !isMIBSeparator(data[end]) && !(data[end] == '-' && end+1 < len(data) && data[end+1] == '-') {
+				end++
+			}
+			tokens = append(tokens, mibToken{value: string(data[i:end]), line: line})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func isMIBSymbol(ch byte) bool {
+	return strings.IndexByte("{}()[],;|.:", ch) >= 0
+}
+
+func isMIBSeparator(ch byte) bool {
+	return isMIBSymbol(ch) || ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' || ch == '\f' || ch == '"'
+}
+
+// isMIBMacro returns whether a word is the name of a macro, e.g. OBJECT-TYPE.
+func isMIBMacro(word string) bool {
+	if word == "" || word != strings.ToUpper(word) || word[0] < 'A' || word[0] > 'Z' {
+		return false
+	}
+	switch word {
+	case "INTEGER", "BITS", "SEQUENCE", "CHOICE", "OCTET", "OBJECT", "MACRO", "END", "BEGIN":
+		return false
+	}
+	return true
+}
+
+// mibParser parses the tokens of a MIB file.
+type mibParser struct {
+	tokens []mibToken
+	pos    int
+	module *mibModule
+}
+
+func (p *mibParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *mibParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos].value
+}
+
+func (p *mibParser) next() (mibToken, error) {
+	if p.done() {
+		return mibToken{}, fmt.Errorf("unexpected end of file")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, nil
+}
+
+func (p *mibParser) expect(value string) error {
+	token, err := p.next()
+	if err != nil {
+		return err
+	}
+	if token.quoted || token.value != value {
+		return fmt.Errorf("line %d: expected %q, got %q", token.line, value, token.value)
+	}
+	return nil
+}
+
+// skipUntil skips the tokens up to and including a value.
+func (p *mibParser) skipUntil(value string) error {
+	for {
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+		if !token.quoted && token.value == value {
+			return nil
+		}
+	}
+}
+
+// skipBlock skips a block between balanced delimiters, starting at the opening one.
+func (p *mibParser) skipBlock(open string, closing string) error {
+	depth := 0
+	for {
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+		if token.quoted {
+			continue
+		}
+		switch token.value {
+		case open:
+			depth++
+		case closing:
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// parseModule parses a module: <name> DEFINITIONS ::= BEGIN <assignments> END
+func (p *mibParser) parseModule() (*mibModule, error) {
+	name, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	if err := p.skipUntil("::="); err != nil {
+		return nil, err
+	}
+	if err := p.expect("BEGIN"); err != nil {
+		return nil, err
+	}
+	p.module = &mibModule{name: name.value, types: make(map[string]*mibType)}
+
+	for {
+		token, err := p.next()
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", p.module.name, err)
+		}
+		switch token.value {
+		case "END":
+			return p.module, nil
+		case "IMPORTS", "EXPORTS":
+			err = p.skipUntil(";")
+		default:
+			err = p.parseAssignment(token)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", p.module.name, err)
+		}
+	}
+}
+
+// parseAssignment parses the assignment of a name, whose first token has been consumed.
+func (p *mibParser) parseAssignment(name mibToken) error {
+	switch keyword := p.peek(); {
+	case keyword == "MACRO":
+		return p.skipUntil("END")
+	case keyword == "::=":
+		p.pos++
+		return p.parseTypeAssignment(name.value)
+	case keyword == "OBJECT":
+		p.pos++
+		if err := p.expect("IDENTIFIER"); err != nil {
+			return err
+		}
+		if err := p.expect("::="); err != nil {
+			return err
+		}
+		value, err := p.parseOIDValue()
+		if err != nil {
+			return err
+		}
+		p.module.nodes = append(p.module.nodes, &mibNode{name: name.value, module: p.module.name, value: value})
+		return nil
+	case isMIBMacro(keyword):
+		p.pos++
+		return p.parseMacro(name.value, keyword)
+	default:
+		// other value assignments are skipped
+		if err := p.skipUntil("::="); err != nil {
+			return err
+		}
+		if p.peek() == "{" {
+			return p.skipBlock("{", "}")
+		}
+		_, err := p.next()
+		return err
+	}
+}
+
+// parseTypeAssignment parses a textual convention or a type assignment.
+func (p *mibParser) parseTypeAssignment(name string) error {
+	t := &mibType{}
+	if p.peek() == "TEXTUAL-CONVENTION" {
+		p.pos++
+		for {
+			token, err := p.next()
+			if err != nil {
+				return err
+			}
+			if token.quoted {
+				continue
+			}
+			if token.value == "DISPLAY-HINT" {
+				hint, err := p.next()
+				if err != nil {
+					return err
+				}
+				t.displayHint = hint.value
+			}
+			if token.value == "SYNTAX" {
+				break
+			}
+		}
+	}
+	syntax, err := p.parseSyntax()
+	if err != nil {
+		return err
+	}
+	t.syntax = syntax
+	p.module.types[name] = t
+	return nil
+}
+
+// parseMacro parses the clauses of a macro invocation, e.g. OBJECT-TYPE, and its value.
+func (p *mibParser) parseMacro(name string, macro string) error {
+	node := &mibNode{name: name, module: p.module.name, kind: mibNodeOther}
+	switch macro {
+	case "OBJECT-TYPE":
+		node.kind = mibNodeObjectType
+	case "NOTIFICATION-TYPE":
+		node.kind = mibNodeNotificationType
+	case "TRAP-TYPE":
+		node.kind = mibNodeTrapType
+	}
+
+	depth := 0
+	for {
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+		if token.quoted {
+			continue
+		}
+		switch token.value {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		case "SYNTAX":
+			syntax, err := p.parseSyntax()
+			if err != nil {
+				return err
+			}
+			if node.syntax == nil {
+				node.syntax = syntax
+			}
+		case "DESCRIPTION":
+			description, err := p.next()
+			if err != nil {
+				return err
+			}
+			if node.description == "" {
+				node.description = strings.Join(strings.Fields(description.value), " ")
+			}
+		case "ENTERPRISE":
+			enterprise, err := p.next()
+			if err != nil {
+				return err
+			}
+			node.enterprise = enterprise.value
+		case "INDEX":
+			if node.index, err = p.parseIndex(); err != nil {
+				return err
+			}
+		case "AUGMENTS":
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			augments, err := p.next()
+			if err != nil {
+				return err
+			}
+			node.augments = augments.value
+			if err := p.expect("}"); err != nil {
+				return err
+			}
+		}
+		if token.value == "::=" && depth == 0 {
+			break
+		}
+	}
+
+	if node.kind == mibNodeTrapType {
+		number, err := p.next()
+		if err != nil {
+			return err
+		}
+		if node.trapNumber, err = strconv.Atoi(number.value); err != nil {
+			return fmt.Errorf("line %d: invalid trap number %q", number.line, number.value)
+		}
+	} else {
+		value, err := p.parseOIDValue()
+		if err != nil {
+			return err
+		}
+		node.value = value
+	}
+	p.module.nodes = append(p.module.nodes, node)
+	return nil
+}
+
+// parseSyntax parses a syntax, e.g. INTEGER { up(1), down(2) } or OCTET STRING (SIZE (0..255)).
+func (p *mibParser) parseSyntax() (*mibSyntax, error) {
+	syntax := &mibSyntax{}
+	for {
+		if p.peek() == "[" {
+			if err := p.skipBlock("[", "]"); err != nil {
+				return nil, err
+			}
+		} else if p.peek() == "IMPLICIT" {
+			p.pos++
+		} else {
+			break
+		}
+	}
+
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch token.value {
+	case "OCTET":
+		syntax.typeName = "OCTET STRING"
+		err = p.expect("STRING")
+	case "OBJECT":
+		syntax.typeName = "OBJECT IDENTIFIER"
+		err = p.expect("IDENTIFIER")
+	case "SEQUENCE", "CHOICE":
+		syntax.sequence = true
+		if p.peek() == "OF" {
+			p.pos++
+			_, err = p.next()
+		} else {
+			err = p.skipBlock("{", "}")
+		}
+	default:
+		syntax.typeName = token.value
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() == "{" {
+		if syntax.namedNumbers, err = p.parseNamedNumbers(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek() == "(" {
+		start := p.pos
+		if err := p.skipBlock("(", ")"); err != nil {
+			return nil, err
+		}
+		syntax.size = fixedMIBSize(p.tokens[start:p.pos])
+	}
+	return syntax, nil
+}
+
+// fixedMIBSize returns the size of a constraint of a fixed size: (SIZE (6))
+func fixedMIBSize(constraint []mibToken) int {
+	if len(constraint) != 6 || constraint[1].value != "SIZE" || constraint[2].value != "(" {
+		return 0
+	}
+	size, err := strconv.Atoi(constraint[3].value)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// parseIndex parses the objects of an INDEX clause: { ifIndex, IMPLIED name }
+func (p *mibParser) parseIndex() ([]mibIndexName, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var index []mibIndexName
+	implied := false
+	for {
+		token, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch token.value {
+		case "}":
+			return index, nil
+		case ",":
+		case "IMPLIED":
+			implied = true
+		default:
+			index = append(index, mibIndexName{name: token.value, implied: implied})
+			implied = false
+		}
+	}
+}
+
+// parseNamedNumbers parses the named numbers of an enumeration or of bits: { up(1), down(2) }
+func (p *mibParser) parseNamedNumbers() (map[int]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	namedNumbers := make(map[int]string)
+	for {
+		name, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if name.value == "}" {
+			return namedNumbers, nil
+		}
+		if name.value == "," {
+			continue
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		number, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.Atoi(number.value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid number %q", number.line, number.value)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		namedNumbers[value] = name.value
+	}
+}
+
+// parseOIDValue parses an OID value: { parent 1 2 }, { iso(1) org(3) 6 } or { 1 3 6 }
+func (p *mibParser) parseOIDValue() (mibOIDValue, error) {
+	var value mibOIDValue
+	if err := p.expect("{"); err != nil {
+		return value, err
+	}
+	for first := true; ; first = false {
+		token, err := p.next()
+		if err != nil {
+			return value, err
+		}
+		if token.value == "}" {
+			return value, nil
+		}
+		if id, err := strconv.Atoi(token.value); err == nil {
+			value.ids = append(value.ids, id)
+			continue
+		}
+		if p.peek() == "(" {
+			// named number, e.g. org(3)
+			p.pos++
+			number, err := p.next()
+			if err != nil {
+				return value, err
+			}
+			id, err := strconv.Atoi(number.value)
+			if err != nil {
+				return value, fmt.Errorf("line %d: invalid number %q", number.line, number.value)
+			}
+			if err := p.expect(")"); err != nil {
+				return value, err
+			}
+			value.ids = append(value.ids, id)
+			continue
+		}
+		if !first {
+			return value, fmt.Errorf("line %d: unexpected %q in OID value", token.line, token.value)
+		}
+		value.parent = token.value
+	}
+}
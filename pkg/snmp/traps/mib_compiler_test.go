@@ -0,0 +1,277 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vendorMIB = `
+VENDOR-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    MODULE-IDENTITY, OBJECT-TYPE, NOTIFICATION-TYPE, enterprises, Integer32
+        FROM SNMPv2-SMI
+    TEXTUAL-CONVENTION, MacAddress, TruthValue, DisplayString
+        FROM SNMPv2-TC;
+
+vendorMIB MODULE-IDENTITY
+    LAST-UPDATED "202101010000Z"
+    ORGANIZATION "Vendor"
+    CONTACT-INFO "support@vendor.example"
+    DESCRIPTION  "The MIB of the vendor."
+    ::= { enterprises 99999 }
+
+-- the state of a fan
+FanState ::= TEXTUAL-CONVENTION
+    STATUS      current
+    DESCRIPTION "The state of a fan."
+    SYNTAX      INTEGER { ok(1), failed(2), absent(-1) }
+
+VendorAddress ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1x:"
+    STATUS       current
+    DESCRIPTION  "A hardware address."
+    SYNTAX       OCTET STRING (SIZE (6))
+
+vendorObjects       OBJECT IDENTIFIER -- the objects -- ::= { vendorMIB 1 }
+vendorNotifications OBJECT IDENTIFIER ::= { vendorMIB 0 }
+
+fanTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF FanEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The fans."
+    ::= { vendorObjects 1 }
+
+fanEntry OBJECT-TYPE
+    SYNTAX      FanEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A fan."
+    INDEX       { fanIndex }
+    ::= { fanTable 1 }
+
+FanEntry ::= SEQUENCE {
+    fanIndex    Integer32,
+    fanState    FanState
+}
+
+fanIndex OBJECT-TYPE
+    SYNTAX      Integer32 (1..64)
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The index of the fan."
+    ::= { fanEntry 1 }
+
+fanState OBJECT-TYPE
+    SYNTAX      FanState
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION
+        "The state of
+         the fan."
+    DEFVAL      { ok }
+    ::= { fanEntry 2 }
+
+fanFeatures OBJECT-TYPE
+    SYNTAX      BITS { variableSpeed(0), redundant(1) }
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The features of the fan."
+    ::= { vendorObjects 2 }
+
+chassisAddress OBJECT-TYPE
+    SYNTAX      MacAddress
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The address of the chassis."
+    ::= { vendorObjects 3 }
+
+moduleAddress OBJECT-TYPE
+    SYNTAX      VendorAddress
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The address of the module."
+    ::= { vendorObjects 4 }
+
+fanRedundant OBJECT-TYPE
+    SYNTAX      TruthValue
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Whether the fans are redundant."
+    ::= { vendorObjects 5 }
+
+portTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF PortEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The ports of the modules."
+    ::= { vendorObjects 6 }
+
+portEntry OBJECT-TYPE
+    SYNTAX      PortEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A port."
+    INDEX       { moduleAddress, IMPLIED portName }
+    ::= { portTable 1 }
+
+PortEntry ::= SEQUENCE {
+    portName    DisplayString
+}
+
+portName OBJECT-TYPE
+    SYNTAX      DisplayString (SIZE (1..32))
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The name of the port."
+    ::= { portEntry 1 }
+
+portStatsEntry OBJECT-TYPE
+    SYNTAX      PortStatsEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The statistics of a port."
+    AUGMENTS    { portEntry }
+    ::= { vendorObjects 7 }
+
+PortStatsEntry ::= SEQUENCE {
+    portErrors  Counter32
+}
+
+portErrors OBJECT-TYPE
+    SYNTAX      Counter32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The errors of the port."
+    ::= { portStatsEntry 1 }
+
+fanFailure NOTIFICATION-TYPE
+    OBJECTS     { fanState, fanFeatures }
+    STATUS      current
+    DESCRIPTION "A fan failed."
+    ::= { vendorNotifications 1 }
+
+END
+`
+
+const vendorV1MIB = `
+VENDOR-V1-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    TRAP-TYPE FROM RFC-1215
+    vendorMIB FROM VENDOR-MIB;
+
+TRAP-TYPE MACRO ::=
+BEGIN
+    TYPE NOTATION ::= "ENTERPRISE" value (enterprise OBJECT IDENTIFIER)
+    VALUE NOTATION ::= value (VALUE INTEGER)
+END
+
+powerFailure TRAP-TYPE
+    ENTERPRISE  vendorMIB
+    VARIABLES   { fanState }
+    DESCRIPTION "The power failed."
+    ::= 3
+
+END
+`
+
+func TestCompileMIBs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "VENDOR-MIB.txt"), []byte(vendorMIB), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "VENDOR-V1-MIB.my"), []byte(vendorV1MIB), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.mib"), []byte("INVALID DEFINITIONS ::= BEGIN foo OBJECT IDENTIFIER ::= {"), 0644))
+
+	content, err := compileMIBs(dir)
+	require.NoError(t, err)
+	vendorFile, vendorV1File := filepath.Join(dir, "VENDOR-MIB.txt"), filepath.Join(dir, "VENDOR-V1-MIB.my")
+
+	assert.Equal(t, trapSpec{
+		"1.3.6.1.4.1.99999.0.1": {Name: "fanFailure", MIBName: "VENDOR-MIB", Description: "A fan failed.", SourceFile: vendorFile},
+		// the SNMPv1 traps are identified by their enterprise and their specific trap number
+		"1.3.6.1.4.1.99999.0.3": {Name: "powerFailure", MIBName: "VENDOR-V1-MIB", Description: "The power failed.", SourceFile: vendorV1File},
+	}, content.Traps)
+	fanIndex := []IndexComponent{{Name: "fanIndex", Type: "integer"}}
+	// the fixed-size octet strings are not prefixed by their length, unlike the last one whose
+	// length is implied
+	portIndex := []IndexComponent{
+		{Name: "moduleAddress", Type: "fixed_string", Length: 6, Format: macAddressFormat},
+		{Name: "portName", Type: "string", Implied: true},
+	}
+	assert.Equal(t, variableSpec{
+		"1.3.6.1.4.1.99999.1.1.1.1": {Name: "fanIndex", MIBName: "VENDOR-MIB", Description: "The index of the fan.", Index: fanIndex, SourceFile: vendorFile},
+		"1.3.6.1.4.1.99999.1.1.1.2": {Name: "fanState", MIBName: "VENDOR-MIB", Description: "The state of the fan.", Enumeration: map[int]string{1: "ok", 2: "failed", -1: "absent"}, Index: fanIndex, SourceFile: vendorFile},
+		"1.3.6.1.4.1.99999.1.2":     {Name: "fanFeatures", MIBName: "VENDOR-MIB", Description: "The features of the fan.", Bits: map[int]string{0: "variableSpeed", 1: "redundant"}, SourceFile: vendorFile},
+		"1.3.6.1.4.1.99999.1.3":     {Name: "chassisAddress", MIBName: "VENDOR-MIB", Description: "The address of the chassis.", Format: macAddressFormat, SourceFile: vendorFile},
+		"1.3.6.1.4.1.99999.1.4":     {Name: "moduleAddress", MIBName: "VENDOR-MIB", Description: "The address of the module.", Format: macAddressFormat, SourceFile: vendorFile},
+		"1.3.6.1.4.1.99999.1.5":     {Name: "fanRedundant", MIBName: "VENDOR-MIB", Description: "Whether the fans are redundant.", Enumeration: map[int]string{1: "true", 2: "false"}, SourceFile: vendorFile},
+		"1.3.6.1.4.1.99999.1.6.1.1": {Name: "portName", MIBName: "VENDOR-MIB", Description: "The name of the port.", Index: portIndex, SourceFile: vendorFile},
+		// the augmenting rows have the index of the rows they augment
+		"1.3.6.1.4.1.99999.1.7.1": {Name: "portErrors", MIBName: "VENDOR-MIB", Description: "The errors of the port.", Index: portIndex, SourceFile: vendorFile},
+	}, content.Variables)
+}
+
+func TestCompileMIBsWithAbsoluteOIDs(t *testing.T) {
+	modules, err := parseMIB([]byte(`
+ROOT-MIB DEFINITIONS ::= BEGIN
+rootObject OBJECT IDENTIFIER ::= { iso(1) org(3) dod(6) internet(1) private(4) enterprises(1) 42 }
+rootTrap NOTIFICATION-TYPE
+    STATUS current
+    DESCRIPTION "A trap."
+    ::= { rootObject 7 }
+END`))
+	require.NoError(t, err)
+	compiler := newMIBCompiler()
+	compiler.add(modules...)
+	assert.Equal(t, trapSpec{"1.3.6.1.4.1.42.7": {Name: "rootTrap", MIBName: "ROOT-MIB", Description: "A trap."}}, compiler.compile().Traps)
+}
+
+func TestParseMIBErrors(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`NOT-A-MIB`,
+		`UNTERMINATED DEFINITIONS ::= BEGIN foo OBJECT-TYPE DESCRIPTION "unterminated`,
+		`NO-END DEFINITIONS ::= BEGIN foo OBJECT IDENTIFIER ::= { iso 3 }`,
+		`BAD-ENUM DEFINITIONS ::= BEGIN Foo ::= INTEGER { a(b) } END`,
+	} {
+		_, err := parseMIB([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestMultiFilesOIDResolverWithMIBs(t *testing.T) {
+	dbDir := t.TempDir()
+	writeTrapsDB(t, dbDir, "vendor.yaml", `
+traps:
+  1.3.6.1.4.1.99999.0.1:
+    name: fanDown
+    mib: VENDOR-DB
+`)
+	mibsDir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mibsDir, "VENDOR-MIB"), []byte(vendorMIB), 0644))
+
+	resolver, err := NewMultiFilesOIDResolverFromDir(dbDir)
+	require.NoError(t, err)
+	require.NoError(t, resolver.updateFromMIBs(mibsDir))
+
+	// the traps of the MIBs override the ones of the traps DB files
+	trapMetadata, err := resolver.GetTrapMetadata("1.3.6.1.4.1.99999.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "fanFailure", trapMetadata.Name)
+
+	variableMetadata, err := resolver.GetVariableMetadata("1.3.6.1.4.1.99999.0.1", "1.3.6.1.4.1.99999.1.1.1.2")
+	require.NoError(t, err)
+	assert.Equal(t, "fanState", variableMetadata.Name)
+
+	assert.Error(t, resolver.updateFromMIBs(filepath.Join(mibsDir, "missing")))
+}
@@ -0,0 +1,776 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	lru "github.com/hashicorp/golang-lru"
+	"gopkg.in/yaml.v2"
+)
+
+// The formats of the octet string variables which are displayed in a human-readable form.
+const (
+	macAddressFormat  = "mac_address"
+	dateAndTimeFormat = "date_and_time"
+	inetAddressFormat = "inet_address"
+)
+
+// ddTrapsDBFilePrefix is the prefix of the traps DB files shipped with the Agent, which have
+// the lowest priority.
+const ddTrapsDBFilePrefix = "dd_traps_db"
+
+// OIDResolver gets the metadata of traps and of their variables from their OIDs.
+type OIDResolver interface {
+	GetTrapMetadata(trapOID string) (TrapMetadata, error)
+	GetVariableMetadata(trapOID string, varOID string) (VariableMetadata, error)
+	// GetInstanceMetadata returns the metadata of the object a variable is an instance of, and
+	// the index of the instance, whose OID is the one of the object followed by the index,
+	// e.g. the row of a table column. The index is empty when the OID is the one of the object.
+	GetInstanceMetadata(trapOID string, varOID string) (VariableMetadata, []int, error)
+}
+
+// TrapMetadata is the metadata of a trap.
+type TrapMetadata struct {
+	Name        string `yaml:"name" json:"name"`
+	MIBName     string `yaml:"mib" json:"mib"`
+	Description string `yaml:"descr" json:"descr"`
+	// SourceFile is the traps DB file or the MIB file defining the trap, set by the resolver
+	SourceFile string `yaml:"-" json:"-"`
+}
+
+// VariableMetadata is the metadata of a trap variable.
+type VariableMetadata struct {
+	Name        string `yaml:"name" json:"name"`
+	MIBName     string `yaml:"mib" json:"mib"`
+	Description string `yaml:"descr" json:"descr"`
+	// Enumeration maps the integer values of the variable to their labels
+	Enumeration map[int]string `yaml:"enum" json:"enum"`
+	// Bits maps the positions of the bits of a BITS variable to their names
+	Bits map[int]string `yaml:"bits" json:"bits"`
+	// Format is the format of an octet string variable: mac_address, date_and_time or inet_address
+	Format string `yaml:"format" json:"format"`
+	// Index are the components of the index of the rows of a table column
+	Index []IndexComponent `yaml:"index" json:"index"`
+	// SourceFile is the traps DB file or the MIB file defining the variable, set by the resolver
+	SourceFile string `yaml:"-" json:"-"`
+}
+
+// IndexComponent is a component of the index of the rows of a table, encoded in the OIDs of
+// the instances of its columns.
+// See: https://tools.ietf.org/html/rfc2578#section-7.7
+type IndexComponent struct {
+	Name string `yaml:"name" json:"name"`
+	// Type is the encoding of the component: integer, ip_address, string, fixed_string or oid
+	Type string `yaml:"type" json:"type"`
+	// Length is the length of a fixed_string component
+	Length int `yaml:"length" json:"length,omitempty"`
+	// Implied is true for the last string or oid component of an index when its length is
+	// implied rather than encoded
+	Implied bool `yaml:"implied" json:"implied,omitempty"`
+	// Format is the format of a string component, as the one of the variables
+	Format string `yaml:"format" json:"format,omitempty"`
+}
+
+type trapSpec map[string]TrapMetadata
+type variableSpec map[string]VariableMetadata
+
+// trapDBFileContent is the content of a traps DB file.
+type trapDBFileContent struct {
+	Traps     trapSpec     `yaml:"traps" json:"traps"`
+	Variables variableSpec `yaml:"vars" json:"vars"`
+}
+
+// trapDBEntry is a trap with the variables of the file which defines it.
+type trapDBEntry struct {
+	trapMetadata TrapMetadata
+	variables    variableSpec
+	// path is the traps DB file, or the MIBs directory, defining the trap
+	path string
+	// index is the index of the file defining the trap when it is loaded lazily, in which case
+	// the metadata of the trap are read from the file when they are needed
+	index     *trapDBFileIndex
+	trapRange trapDBValueRange
+}
+
+// MultiFilesOIDResolver is an OIDResolver loading the traps DB files of a directory, in json
+// or yaml, optionally gzipped, and the tar.gz bundles of such files. The json files can be loaded lazily to bound the memory used
+// by large traps DBs: only the offsets of their entries are loaded, and the entries which are
+// read are kept in an LRU cache.
+// The conflicts between trap OIDs are resolved with the alphabetical order of the files, the
+// last file wins, except for the files shipped with the Agent which have the lowest priority.
+// The variables of a trap are resolved with the file which defines the trap.
+type MultiFilesOIDResolver struct {
+	dir string
+	// bundlesDir is the directory of the cached traps DB bundles, if any
+	bundlesDir string
+	// mibsDir is the directory of the MIB files compiled into the resolver, if any
+	mibsDir string
+	// namespaceDir is the directory of the traps DB files of a device namespace, if any, which
+	// override the other files
+	namespaceDir string
+	// base is the resolver of the other files when the resolver is the one of a device namespace,
+	// so that they are loaded only once for all the namespaces, nil otherwise
+	base *MultiFilesOIDResolver
+	// namespaces are the resolvers of the device namespaces based on the resolver, which are
+	// reloaded with it
+	namespaces []*MultiFilesOIDResolver
+	// reloadMu serializes the reloads of the files
+	reloadMu sync.Mutex
+	// cacheSize is the number of entries of the files loaded lazily which are kept in memory, the
+	// files are fully loaded when it is 0
+	cacheSize int
+	// traps, cache and conflicts are swapped when the files are reloaded
+	mu    sync.RWMutex
+	traps map[string]trapDBEntry
+	cache *lru.Cache
+	// conflicts are the files defining the OIDs defined more than once, in the order they are
+	// loaded
+	conflicts map[oidDefinition][]string
+	// variableFiles is the last file defining each variable, to detect the conflicts while the
+	// files are loaded, and while the files of the namespaces are loaded on top of them
+	variableFiles map[string]string
+	// fileCount is the number of traps DB files loaded, including the files of the tar.gz bundles
+	fileCount int
+}
+
+// The kinds of the OIDs defined in the traps DB.
+const (
+	trapKind     = "trap"
+	variableKind = "variable"
+)
+
+// oidDefinition is an OID defined in the traps DB.
+type oidDefinition struct {
+	kind string
+	oid  string
+}
+
+// OIDConflict is an OID defined by several traps DB files or MIB files.
+type OIDConflict struct {
+	// Namespace is the device namespace whose traps DB defines the OID, empty for the traps DB
+	// of the devices of the namespaces without their own traps DB files
+	Namespace string `json:"namespace,omitempty"`
+	OID       string `json:"oid"`
+	// Kind is trap or variable
+	Kind string `json:"kind"`
+	// Files are the files defining the OID, in the order they are loaded
+	Files []string `json:"files"`
+	// Winners are the files whose definitions are used. A trap is resolved with the last file
+	// defining it, and the variables of a trap are resolved with the file defining the trap, so
+	// the definitions of a variable used are the ones of the files whose traps are used.
+	Winners []string `json:"winners"`
+}
+
+// NewMultiFilesOIDResolver returns a resolver loading the traps DB files of snmp.d/traps_db in
+// the configuration directory.
+func NewMultiFilesOIDResolver() (*MultiFilesOIDResolver, error) {
+	return NewMultiFilesOIDResolverFromDir(trapsDBDir())
+}
+
+// NewMultiFilesOIDResolverFromDir returns a resolver loading the traps DB files of a directory.
+func NewMultiFilesOIDResolverFromDir(dir string) (*MultiFilesOIDResolver, error) {
+	resolver := newMultiFilesOIDResolver(dir)
+	if err := resolver.updateFromDirs(dir); err != nil {
+		return nil, err
+	}
+	return resolver, nil
+}
+
+func newMultiFilesOIDResolver(dir string) *MultiFilesOIDResolver {
+	return &MultiFilesOIDResolver{
+		dir:           dir,
+		traps:         make(map[string]trapDBEntry),
+		conflicts:     make(map[oidDefinition][]string),
+		variableFiles: make(map[string]string),
+	}
+}
+
+// trapsDBDir returns the directory of the traps DB files.
+func trapsDBDir() string {
+	return filepath.Join(config.Datadog.GetString("confd_path"), "snmp.d", "traps_db")
+}
+
+// GetTrapMetadata returns the metadata of a trap.
+func (r *MultiFilesOIDResolver) GetTrapMetadata(trapOID string) (TrapMetadata, error) {
+	r.mu.RLock()
+	entry, ok := r.traps[normalizeOID(trapOID)]
+	cache := r.cache
+	r.mu.RUnlock()
+	if !ok {
+		return TrapMetadata{}, fmt.Errorf("trap OID %s is not defined", trapOID)
+	}
+	metadata := entry.trapMetadata
+	if entry.index != nil {
+		var err error
+		if metadata, err = entry.index.readTrap(entry.trapRange, cache); err != nil {
+			return TrapMetadata{}, err
+		}
+	}
+	metadata.SourceFile = sourceFile(metadata.SourceFile, entry.path)
+	return metadata, nil
+}
+
+// GetVariableMetadata returns the metadata of a variable of a trap.
+func (r *MultiFilesOIDResolver) GetVariableMetadata(trapOID string, varOID string) (VariableMetadata, error) {
+	r.mu.RLock()
+	entry, ok := r.traps[normalizeOID(trapOID)]
+	cache := r.cache
+	r.mu.RUnlock()
+	if !ok {
+		return VariableMetadata{}, fmt.Errorf("trap OID %s is not defined", trapOID)
+	}
+	metadata, ok, err := entry.variable(normalizeOID(varOID), cache)
+	if err != nil {
+		return VariableMetadata{}, err
+	}
+	if !ok {
+		return VariableMetadata{}, fmt.Errorf("variable OID %s is not defined for trap %s", varOID, trapOID)
+	}
+	return metadata, nil
+}
+
+// GetInstanceMetadata implements OIDResolver#GetInstanceMetadata, the object is the variable
+// whose OID is the longest prefix of the OID of the instance.
+func (r *MultiFilesOIDResolver) GetInstanceMetadata(trapOID string, varOID string) (VariableMetadata, []int, error) {
+	r.mu.RLock()
+	entry, ok := r.traps[normalizeOID(trapOID)]
+	cache := r.cache
+	r.mu.RUnlock()
+	if !ok {
+		return VariableMetadata{}, nil, fmt.Errorf("trap OID %s is not defined", trapOID)
+	}
+	oid := normalizeOID(varOID)
+	for end := len(oid); end > 0; end = strings.LastIndexByte(oid[:end], '.') {
+		metadata, ok, err := entry.variable(oid[:end], cache)
+		if err != nil {
+			return VariableMetadata{}, nil, err
+		}
+		if !ok {
+			continue
+		}
+		index, err := parseOIDSuffix(oid[end:])
+		if err != nil {
+			return VariableMetadata{}, nil, fmt.Errorf("invalid index of variable OID %s: %w", varOID, err)
+		}
+		return metadata, index, nil
+	}
+	return VariableMetadata{}, nil, fmt.Errorf("variable OID %s is not defined for trap %s", varOID, trapOID)
+}
+
+// variable returns the metadata of a variable of a trap, and whether it is defined.
+func (e trapDBEntry) variable(oid string, cache *lru.Cache) (VariableMetadata, bool, error) {
+	var metadata VariableMetadata
+	if e.index != nil {
+		variableRange, ok := e.index.variables[oid]
+		if !ok {
+			return VariableMetadata{}, false, nil
+		}
+		var err error
+		if metadata, err = e.index.readVariable(variableRange, cache); err != nil {
+			return VariableMetadata{}, false, err
+		}
+	} else {
+		var ok bool
+		if metadata, ok = e.variables[oid]; !ok {
+			return VariableMetadata{}, false, nil
+		}
+	}
+	metadata.SourceFile = sourceFile(metadata.SourceFile, e.path)
+	return metadata, true, nil
+}
+
+// parseOIDSuffix parses the sub-identifiers following the OID of an object, e.g. ".1.2".
+func parseOIDSuffix(suffix string) ([]int, error) {
+	if suffix == "" {
+		return nil, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(suffix, "."), ".")
+	index := make([]int, len(parts))
+	for i, part := range parts {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		index[i] = int(id)
+	}
+	return index, nil
+}
+
+// updateFromDirs loads the traps DB files of directories, the files of a directory override
+// the ones of the previous directories, except the files shipped with the Agent which have the
+// lowest priority.
+func (r *MultiFilesOIDResolver) updateFromDirs(dirs ...string) error {
+	type trapsDBFile struct {
+		dirIndex int
+		name     string
+	}
+	var files []trapsDBFile
+	for i, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			// the hidden files are skipped, e.g. the bundles being downloaded
+			if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				files = append(files, trapsDBFile{dirIndex: i, name: entry.Name()})
+			}
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		// the files shipped with the Agent are loaded first so that they are overridden
+		iDatadog, jDatadog := strings.HasPrefix(files[i].name, ddTrapsDBFilePrefix), strings.HasPrefix(files[j].name, ddTrapsDBFilePrefix)
+		if iDatadog != jDatadog {
+			return iDatadog
+		}
+		if files[i].dirIndex != files[j].dirIndex {
+			return files[i].dirIndex < files[j].dirIndex
+		}
+		return files[i].name < files[j].name
+	})
+
+	for _, file := range files {
+		if err := r.updateFromFile(filepath.Join(dirs[file.dirIndex], file.name)); err != nil {
+			log.Warnf("Could not load traps DB file %s: %v", file.name, err)
+		}
+	}
+	return nil
+}
+
+// reload reloads the traps DB files, the bundles, the MIB files and the files of the namespace,
+// the traps are swapped at once so that a trap is resolved either with the previous files or
+// with the new ones.
+func (r *MultiFilesOIDResolver) reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	reloaded := newMultiFilesOIDResolver(r.dir)
+	reloaded.cacheSize = r.cacheSize
+	if r.base != nil {
+		r.base.copyInto(reloaded)
+	} else if err := reloaded.loadBaseFiles(r.bundlesDir, r.mibsDir); err != nil {
+		return err
+	}
+	if r.namespaceDir != "" {
+		if err := reloaded.updateFromDirs(r.namespaceDir); os.IsNotExist(err) {
+			log.Debugf("No traps DB files in %s: %v", r.namespaceDir, err)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	var cache *lru.Cache
+	if r.cacheSize > 0 {
+		// the offsets of the cached entries are the ones of the previous files
+		var err error
+		if cache, err = lru.New(r.cacheSize); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.traps = reloaded.traps
+	r.cache = cache
+	r.conflicts = reloaded.conflicts
+	r.variableFiles = reloaded.variableFiles
+	r.fileCount = reloaded.fileCount
+	r.mu.Unlock()
+
+	for _, namespace := range r.namespaces {
+		if err := namespace.reload(); err != nil {
+			log.Warnf("Could not reload the traps DB of namespace directory %s: %v", namespace.namespaceDir, err)
+		}
+	}
+	return nil
+}
+
+// loadBaseFiles loads the cached bundles, the traps DB files of the directory of the resolver
+// and the MIB files.
+func (r *MultiFilesOIDResolver) loadBaseFiles(bundlesDir string, mibsDir string) error {
+	var dirs []string
+	// the traps DB files of the host override the ones of the bundles
+	for _, dir := range []string{bundlesDir, r.dir} {
+		if dir == "" {
+			continue
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			log.Debugf("No traps DB files in %s: %v", dir, err)
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	if err := r.updateFromDirs(dirs...); err != nil {
+		return err
+	}
+	if mibsDir != "" {
+		if err := r.updateFromMIBs(mibsDir); os.IsNotExist(err) {
+			log.Debugf("No MIBs to compile: %v", err)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newNamespaceOIDResolver returns a resolver of the traps DB files of a device namespace, on
+// top of the files loaded by a base resolver, which reloads it when its files are reloaded.
+// The resolver is not loaded.
+func newNamespaceOIDResolver(base *MultiFilesOIDResolver, namespaceDir string) *MultiFilesOIDResolver {
+	resolver := newMultiFilesOIDResolver(base.dir)
+	resolver.namespaceDir = namespaceDir
+	resolver.cacheSize = base.cacheSize
+	resolver.base = base
+	base.reloadMu.Lock()
+	base.namespaces = append(base.namespaces, resolver)
+	base.reloadMu.Unlock()
+	return resolver
+}
+
+// copyInto copies the traps loaded by the resolver into a resolver being reloaded, the entries
+// are shared as they are not modified once loaded.
+func (r *MultiFilesOIDResolver) copyInto(reloaded *MultiFilesOIDResolver) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for oid, entry := range r.traps {
+		reloaded.traps[oid] = entry
+	}
+	for definition, files := range r.conflicts {
+		reloaded.conflicts[definition] = append([]string(nil), files...)
+	}
+	for oid, file := range r.variableFiles {
+		reloaded.variableFiles[oid] = file
+	}
+	reloaded.fileCount = r.fileCount
+}
+
+// Stats returns the number of traps DB files loaded by the resolver and the number of traps
+// they define.
+func (r *MultiFilesOIDResolver) Stats() (fileCount int, trapCount int) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fileCount, len(r.traps)
+}
+
+// Conflicts returns the OIDs defined by several files, sorted by kind and OID, so that the
+// users can check which definitions are overridden.
+func (r *MultiFilesOIDResolver) Conflicts() []OIDConflict {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	usedVariableFiles := r.usedVariableFiles()
+	conflicts := make([]OIDConflict, 0, len(r.conflicts))
+	for definition, files := range r.conflicts {
+		conflict := OIDConflict{
+			OID:   definition.oid,
+			Kind:  definition.kind,
+			Files: append([]string(nil), files...),
+		}
+		switch definition.kind {
+		case trapKind:
+			conflict.Winners = []string{r.traps[definition.oid].file()}
+		case variableKind:
+			for _, file := range files {
+				if _, ok := usedVariableFiles[definition.oid][file]; ok {
+					conflict.Winners = append(conflict.Winners, file)
+				}
+			}
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+		return conflicts[i].OID < conflicts[j].OID
+	})
+	return conflicts
+}
+
+// usedVariableFiles returns the files whose definitions of the conflicting variables are used
+// to resolve the traps, by variable. It must be called with the lock held.
+func (r *MultiFilesOIDResolver) usedVariableFiles() map[string]map[string]struct{} {
+	used := make(map[string]map[string]struct{})
+	for definition := range r.conflicts {
+		if definition.kind == variableKind {
+			used[definition.oid] = make(map[string]struct{})
+		}
+	}
+	// the traps of a file share its variables
+	visited := make(map[string]struct{})
+	for _, entry := range r.traps {
+		if _, ok := visited[entry.path]; ok {
+			continue
+		}
+		visited[entry.path] = struct{}{}
+		for oid, files := range used {
+			if entry.index != nil {
+				if _, ok := entry.index.variables[oid]; ok {
+					files[entry.path] = struct{}{}
+				}
+			} else if metadata, ok := entry.variables[oid]; ok {
+				files[sourceFile(metadata.SourceFile, entry.path)] = struct{}{}
+			}
+		}
+	}
+	return used
+}
+
+// watch reloads the files in the background when they change, it checks them at every
+// interval until stop is closed.
+func (r *MultiFilesOIDResolver) watch(interval time.Duration, stop <-chan struct{}) {
+	state := r.filesState()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			newState := r.filesState()
+			if newState == state {
+				continue
+			}
+			state = newState
+			log.Infof("The traps DB files changed, reloading them")
+			if err := r.reload(); err != nil {
+				log.Warnf("Could not reload the traps DB: %v", err)
+			}
+		}
+	}()
+}
+
+// filesState returns the names, sizes and modification times of the files of the resolver,
+// which change when the files are added, removed or modified. The bundles are not watched
+// since they are reloaded when they are fetched, nor the files loaded by the base resolver of
+// a namespace.
+func (r *MultiFilesOIDResolver) filesState() string {
+	var state strings.Builder
+	dirs := []string{r.dir, r.mibsDir, r.namespaceDir}
+	if r.base != nil {
+		// the other files are watched by the base resolver
+		dirs = []string{r.namespaceDir}
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			fmt.Fprintf(&state, "%s:%v\n", dir, err)
+			continue
+		}
+		for _, file := range files {
+			fmt.Fprintf(&state, "%s:%d:%d\n", filepath.Join(dir, file.Name()), file.Size(), file.ModTime().UnixNano())
+		}
+	}
+	return state.String()
+}
+
+// updateFromFile loads a traps DB file, its traps override the ones already loaded.
+func (r *MultiFilesOIDResolver) updateFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isTarball(path) {
+		return r.updateFromTarball(f, path)
+	}
+
+	var reader io.Reader = f
+	name := path
+	if strings.HasSuffix(name, ".gz") {
+		gzipReader, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	if r.cacheSize > 0 && filepath.Ext(name) == ".json" {
+		if err := r.updateFromIndex(reader, path, name != path); err != nil {
+			return err
+		}
+		r.fileCount++
+		return nil
+	}
+	content, err := decodeTrapsDB(reader, name)
+	if err != nil {
+		return err
+	}
+	r.updateResolverWithData(content, path)
+	r.fileCount++
+	return nil
+}
+
+// isTarball returns whether a traps DB file is a tar.gz bundle of traps DB files.
+func isTarball(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// updateFromTarball loads the traps DB files of a tar.gz bundle, as vendors often distribute
+// them, in the alphabetical order of their names: as the files of a directory, the traps of a
+// file override the ones already loaded. The files are identified by the path of the bundle
+// followed by their name in the bundle. The files are decoded while the bundle is read, so only
+// their decoded content is held until they are loaded.
+func (r *MultiFilesOIDResolver) updateFromTarball(f io.Reader, bundlePath string) error {
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	members := make(map[string]trapDBFileContent)
+	var names []string
+	// the bundles are bounded once expanded as well, as when they are downloaded
+	var remaining int64 = maxBundleSize
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// the hidden files are skipped, e.g. the metadata added by some archivers
+		if header.Typeflag != tar.TypeReg || strings.HasPrefix(path.Base(header.Name), ".") {
+			continue
+		}
+		reader := &io.LimitedReader{R: tarReader, N: remaining + 1}
+		content, err := decodeTarballMember(reader, header.Name)
+		if remaining = reader.N - 1; remaining < 0 {
+			return fmt.Errorf("the expanded bundle is larger than %d bytes", maxBundleSize)
+		}
+		if err != nil {
+			log.Warnf("Could not load traps DB file %s: %v", filepath.Join(bundlePath, filepath.FromSlash(header.Name)), err)
+			continue
+		}
+		if _, ok := members[header.Name]; !ok {
+			names = append(names, header.Name)
+		}
+		members[header.Name] = content
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		r.updateResolverWithData(members[name], filepath.Join(bundlePath, filepath.FromSlash(name)))
+		r.fileCount++
+	}
+	return nil
+}
+
+// decodeTarballMember decodes a traps DB file of a tar.gz bundle, optionally gzipped. The
+// expanded size of the gzipped files is bounded by the reader of the bundle.
+func decodeTarballMember(reader *io.LimitedReader, name string) (trapDBFileContent, error) {
+	if !strings.HasSuffix(name, ".gz") {
+		return decodeTrapsDB(reader, name)
+	}
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return trapDBFileContent{}, err
+	}
+	defer gzipReader.Close()
+	limited := &io.LimitedReader{R: gzipReader, N: reader.N}
+	content, err := decodeTrapsDB(limited, strings.TrimSuffix(name, ".gz"))
+	reader.N = limited.N
+	return content, err
+}
+
+// decodeTrapsDB decodes a traps DB file in json or yaml, depending on the extension of its name.
+func decodeTrapsDB(reader io.Reader, name string) (trapDBFileContent, error) {
+	var content trapDBFileContent
+	var err error
+	switch filepath.Ext(name) {
+	case ".json":
+		err = json.NewDecoder(reader).Decode(&content)
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(reader).Decode(&content)
+	default:
+		return trapDBFileContent{}, fmt.Errorf("unsupported file format, expected json or yaml")
+	}
+	return content, err
+}
+
+// updateFromMIBs compiles the MIB files of a directory, their traps override the ones already
+// loaded.
+func (r *MultiFilesOIDResolver) updateFromMIBs(dir string) error {
+	content, err := compileMIBs(dir)
+	if err != nil {
+		return err
+	}
+	r.updateResolverWithData(content, dir)
+	return nil
+}
+
+// updateResolverWithData adds the traps of a file to the resolver.
+func (r *MultiFilesOIDResolver) updateResolverWithData(content trapDBFileContent, path string) {
+	variables := make(variableSpec, len(content.Variables))
+	for oid, metadata := range content.Variables {
+		oid = normalizeOID(oid)
+		variables[oid] = metadata
+		r.addVariableDefinition(oid, sourceFile(metadata.SourceFile, path))
+	}
+	for oid, metadata := range content.Traps {
+		oid = normalizeOID(oid)
+		entry := trapDBEntry{trapMetadata: metadata, variables: variables, path: path}
+		if existing, ok := r.traps[oid]; ok {
+			log.Debugf("Trap OID %s (%s) is redefined as %s by %s", oid, existing.trapMetadata.Name, metadata.Name, path)
+			r.addConflict(oidDefinition{kind: trapKind, oid: oid}, existing.file(), entry.file())
+		}
+		r.traps[oid] = entry
+	}
+}
+
+// addVariableDefinition records the file defining a variable to detect the conflicts.
+func (r *MultiFilesOIDResolver) addVariableDefinition(oid string, file string) {
+	if previous, ok := r.variableFiles[oid]; ok && previous != file {
+		r.addConflict(oidDefinition{kind: variableKind, oid: oid}, previous, file)
+	}
+	r.variableFiles[oid] = file
+}
+
+// addConflict records that an OID defined by a file is redefined by another one.
+func (r *MultiFilesOIDResolver) addConflict(definition oidDefinition, previous string, file string) {
+	files, ok := r.conflicts[definition]
+	if !ok {
+		files = []string{previous}
+	}
+	r.conflicts[definition] = append(files, file)
+}
+
+// file returns the file defining the trap.
+func (e trapDBEntry) file() string {
+	return sourceFile(e.trapMetadata.SourceFile, e.path)
+}
+
+// sourceFile returns the file defining an entry, which is set for the entries compiled from the
+// MIB files, or else the traps DB file.
+func sourceFile(entryFile string, path string) string {
+	if entryFile != "" {
+		return entryFile
+	}
+	return path
+}
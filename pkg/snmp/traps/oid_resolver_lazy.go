@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// trapDBValueRange is the position of the metadata of a trap or of a variable in a json traps
+// DB file.
+type trapDBValueRange struct {
+	offset int64
+	length int
+}
+
+// trapDBFileIndex is the index of a json traps DB file loaded lazily. The offsets of the
+// gzipped files are the ones of their decompressed content.
+type trapDBFileIndex struct {
+	path       string
+	compressed bool
+	variables  map[string]trapDBValueRange
+}
+
+// trapDBCacheKey is the key of the entries of a file loaded lazily in the LRU cache.
+type trapDBCacheKey struct {
+	path   string
+	offset int64
+}
+
+// updateFromIndex indexes a json traps DB file, read decompressed when it is gzipped, its traps
+// override the ones already loaded.
+func (r *MultiFilesOIDResolver) updateFromIndex(f io.Reader, path string, compressed bool) error {
+	index := &trapDBFileIndex{path: path, compressed: compressed, variables: make(map[string]trapDBValueRange)}
+	traps := make(map[string]trapDBValueRange)
+
+	decoder := json.NewDecoder(f)
+	if err := expectJSONDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var ranges map[string]trapDBValueRange
+		switch key {
+		case "traps":
+			ranges = traps
+		case "vars":
+			ranges = index.variables
+		default:
+			var ignored json.RawMessage
+			if err := decoder.Decode(&ignored); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := indexJSONObject(decoder, ranges); err != nil {
+			return err
+		}
+	}
+	if err := expectJSONDelim(decoder, '}'); err != nil {
+		return err
+	}
+
+	for oid := range index.variables {
+		r.addVariableDefinition(oid, path)
+	}
+	for oid, trapRange := range traps {
+		if existing, ok := r.traps[oid]; ok {
+			log.Debugf("Trap OID %s is redefined by %s", oid, path)
+			r.addConflict(oidDefinition{kind: trapKind, oid: oid}, existing.file(), path)
+		}
+		r.traps[oid] = trapDBEntry{index: index, trapRange: trapRange, path: path}
+	}
+	return nil
+}
+
+// indexJSONObject records the positions of the values of a json object by normalized OID.
+func indexJSONObject(decoder *json.Decoder, ranges map[string]trapDBValueRange) error {
+	if err := expectJSONDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		oid, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected an OID, got %v", token)
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		// the decoder is at the end of the value, which is decoded without its surrounding spaces
+		ranges[normalizeOID(oid)] = trapDBValueRange{offset: decoder.InputOffset() - int64(len(value)), length: len(value)}
+	}
+	return expectJSONDelim(decoder, '}')
+}
+
+// readCompressed reads a value of a gzipped file, which can not be seeked: its content is
+// decompressed up to the value, so the entries of the gzipped files are slower to read on a
+// cache miss than the ones of the uncompressed files.
+func readCompressed(f io.Reader, valueRange trapDBValueRange, data []byte) error {
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+	if _, err := io.CopyN(ioutil.Discard, gzipReader, valueRange.offset); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(gzipReader, data)
+	return err
+}
+
+func expectJSONDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// readTrap reads the metadata of a trap from the file, or from the cache when it has already
+// been read.
+func (i *trapDBFileIndex) readTrap(valueRange trapDBValueRange, cache *lru.Cache) (TrapMetadata, error) {
+	key := trapDBCacheKey{path: i.path, offset: valueRange.offset}
+	if cache != nil {
+		if metadata, ok := cache.Get(key); ok {
+			return metadata.(TrapMetadata), nil
+		}
+	}
+	var metadata TrapMetadata
+	if err := i.read(valueRange, &metadata); err != nil {
+		return TrapMetadata{}, err
+	}
+	if cache != nil {
+		cache.Add(key, metadata)
+	}
+	return metadata, nil
+}
+
+// readVariable reads the metadata of a variable from the file, or from the cache when it has
+// already been read.
+func (i *trapDBFileIndex) readVariable(valueRange trapDBValueRange, cache *lru.Cache) (VariableMetadata, error) {
+	key := trapDBCacheKey{path: i.path, offset: valueRange.offset}
+	if cache != nil {
+		if metadata, ok := cache.Get(key); ok {
+			return metadata.(VariableMetadata), nil
+		}
+	}
+	var metadata VariableMetadata
+	if err := i.read(valueRange, &metadata); err != nil {
+		return VariableMetadata{}, err
+	}
+	if cache != nil {
+		cache.Add(key, metadata)
+	}
+	return metadata, nil
+}
+
+func (i *trapDBFileIndex) read(valueRange trapDBValueRange, metadata interface{}) error {
+	f, err := os.Open(i.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data := make([]byte, valueRange.length)
+	if i.compressed {
+		err = readCompressed(f, valueRange, data)
+	} else {
+		_, err = io.ReadFull(io.NewSectionReader(f, valueRange.offset, int64(valueRange.length)), data)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read traps DB file %s: %w", i.path, err)
+	}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return fmt.Errorf("could not read traps DB file %s: %w", i.path, err)
+	}
+	return nil
+}
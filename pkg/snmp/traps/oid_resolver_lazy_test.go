@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiFilesOIDResolverLazyLoading(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	writeTrapsDB(t, dir, "a_vendor.yaml", userTrapsDB)
+	writeTrapsDB(t, dir, "b_vendor.json", `{"mibs": ["VENDOR-MIB"], "traps": {}, "vars": {"1.3.6.1.4.1.1": {"name": "unused"}}}`)
+	writeTrapsDB(t, dir, "invalid.json", `{"traps": {"1.3.6.1.4.1.1.0.1": {"name": "truncated"}`)
+
+	resolver := newMultiFilesOIDResolver(dir)
+	resolver.cacheSize = 2
+	require.NoError(t, resolver.reload())
+
+	// the json files are indexed, the other ones are fully loaded
+	assert.NotNil(t, resolver.traps["1.3.6.1.6.3.1.1.5.3"].index)
+	assert.Nil(t, resolver.traps["1.3.6.1.6.3.1.1.5.4"].index)
+	// the traps of the invalid files are not loaded
+	assert.NotContains(t, resolver.traps, "1.3.6.1.4.1.1.0.1")
+
+	for i := 0; i < 2; i++ {
+		trap, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.3")
+		require.NoError(t, err)
+		assert.Equal(t, TrapMetadata{Name: "linkDown", MIBName: "IF-MIB", Description: "A linkDown trap", SourceFile: filepath.Join(dir, "dd_traps_db.json")}, trap)
+
+		variable, err := resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.8")
+		require.NoError(t, err)
+		assert.Equal(t, VariableMetadata{Name: "ifOperStatus", Description: "The operational state", Enumeration: map[int]string{1: "up", 2: "down", 3: "testing"}, Index: []IndexComponent{{Name: "ifIndex", Type: "integer"}}, SourceFile: filepath.Join(dir, "dd_traps_db.json")}, variable)
+
+		variable, err = resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.99")
+		require.NoError(t, err)
+		assert.Equal(t, "ifCapabilities", variable.Name)
+	}
+	// the cache is bounded
+	assert.Equal(t, 2, resolver.cache.Len())
+
+	_, err := resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.3", "1.3.6.1.2.1.2.2.1.2")
+	assert.Error(t, err)
+
+	trap, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+	require.NoError(t, err)
+	assert.Equal(t, "vendorLinkUp", trap.Name)
+}
+
+func TestMultiFilesOIDResolverLazyLoadingGzipped(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json.gz", ddTrapsDB)
+
+	resolver := newMultiFilesOIDResolver(dir)
+	resolver.cacheSize = 2
+	require.NoError(t, resolver.reload())
+
+	// the gzipped json files are indexed as well
+	assert.NotNil(t, resolver.traps["1.3.6.1.6.3.1.1.5.3"].index)
+
+	trap, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.3")
+	require.NoError(t, err)
+	assert.Equal(t, TrapMetadata{Name: "linkDown", MIBName: "IF-MIB", Description: "A linkDown trap", SourceFile: filepath.Join(dir, "dd_traps_db.json.gz")}, trap)
+
+	variable, err := resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.8")
+	require.NoError(t, err)
+	assert.Equal(t, "ifOperStatus", variable.Name)
+	assert.Equal(t, map[int]string{1: "up", 2: "down", 3: "testing"}, variable.Enumeration)
+}
@@ -0,0 +1,328 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ddTrapsDB = `{
+  "traps": {
+    "1.3.6.1.6.3.1.1.5.3": {"name": "linkDown", "mib": "IF-MIB", "descr": "A linkDown trap"},
+    "1.3.6.1.6.3.1.1.5.4": {"name": "linkUp", "mib": "IF-MIB", "descr": "A linkUp trap"}
+  },
+  "vars": {
+    "1.3.6.1.2.1.2.2.1.1": {"name": "ifIndex", "descr": "A unique value for each interface"},
+    "1.3.6.1.2.1.2.2.1.8": {"name": "ifOperStatus", "descr": "The operational state", "enum": {"1": "up", "2": "down", "3": "testing"}, "index": [{"name": "ifIndex", "type": "integer"}]},
+    "1.3.6.1.2.1.2.2.1.99": {"name": "ifCapabilities", "descr": "The capabilities of the interface", "bits": {"0": "fullDuplex", "1": "autoNegotiation", "9": "poe"}},
+    "1.3.6.1.2.1.2.2.1.6": {"name": "ifPhysAddress", "descr": "The address of the interface", "format": "mac_address"}
+  }
+}`
+
+const userTrapsDB = `
+traps:
+  .1.3.6.1.6.3.1.1.5.4:
+    name: vendorLinkUp
+    mib: VENDOR-MIB
+vars:
+  .1.3.6.1.2.1.2.2.1.8:
+    name: vendorOperStatus
+    enum:
+      1: operational
+`
+
+func writeTrapsDB(t testing.TB, dir string, name string, content string) {
+	data := []byte(content)
+	if filepath.Ext(name) == ".gz" {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		data = buf.Bytes()
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0644))
+}
+
+func TestMultiFilesOIDResolver(t *testing.T) {
+	dir := t.TempDir()
+	// the files shipped with the Agent have the lowest priority whatever their name
+	writeTrapsDB(t, dir, "a_vendor.yaml", userTrapsDB)
+	writeTrapsDB(t, dir, "dd_traps_db.json.gz", ddTrapsDB)
+	writeTrapsDB(t, dir, "invalid.json", "{")
+	writeTrapsDB(t, dir, "README.txt", "not a traps DB")
+
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+
+	trap, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.3")
+	require.NoError(t, err)
+	assert.Equal(t, TrapMetadata{Name: "linkDown", MIBName: "IF-MIB", Description: "A linkDown trap", SourceFile: filepath.Join(dir, "dd_traps_db.json.gz")}, trap)
+
+	trap, err = resolver.GetTrapMetadata(".1.3.6.1.6.3.1.1.5.4")
+	require.NoError(t, err)
+	assert.Equal(t, TrapMetadata{Name: "vendorLinkUp", MIBName: "VENDOR-MIB", SourceFile: filepath.Join(dir, "a_vendor.yaml")}, trap)
+
+	_, err = resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.5")
+	assert.Error(t, err)
+
+	// the variables are resolved with the file defining the trap
+	variable, err := resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.8")
+	require.NoError(t, err)
+	assert.Equal(t, "ifOperStatus", variable.Name)
+	assert.Equal(t, map[int]string{1: "up", 2: "down", 3: "testing"}, variable.Enumeration)
+
+	variable, err = resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.4", "1.3.6.1.2.1.2.2.1.8")
+	require.NoError(t, err)
+	assert.Equal(t, "vendorOperStatus", variable.Name)
+	assert.Equal(t, map[int]string{1: "operational"}, variable.Enumeration)
+
+	_, err = resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.4", "1.3.6.1.2.1.2.2.1.1")
+	assert.Error(t, err)
+}
+
+func TestMultiFilesOIDResolverInstances(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	for _, cacheSize := range []int{0, 10} {
+		resolver := newMultiFilesOIDResolver(dir)
+		resolver.cacheSize = cacheSize
+		require.NoError(t, resolver.reload())
+
+		// the instances are resolved with the longest OID prefix defined
+		variable, index, err := resolver.GetInstanceMetadata("1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.8.12")
+		require.NoError(t, err)
+		assert.Equal(t, "ifOperStatus", variable.Name)
+		assert.Equal(t, []int{12}, index)
+
+		variable, index, err = resolver.GetInstanceMetadata("1.3.6.1.6.3.1.1.5.3", "1.3.6.1.2.1.2.2.1.99")
+		require.NoError(t, err)
+		assert.Equal(t, "ifCapabilities", variable.Name)
+		assert.Empty(t, index)
+
+		_, _, err = resolver.GetInstanceMetadata("1.3.6.1.6.3.1.1.5.3", "1.3.6.1.2.1.2.2.1.2.1")
+		assert.Error(t, err)
+		_, _, err = resolver.GetInstanceMetadata("1.3.6.1.6.3.1.1.5.3", "1.3.6.1.2.1.2.2.1.8.x")
+		assert.Error(t, err)
+		_, _, err = resolver.GetInstanceMetadata("1.3.6.1.6.3.1.1.5.5", "1.3.6.1.2.1.2.2.1.8.12")
+		assert.Error(t, err)
+	}
+}
+
+func TestMultiFilesOIDResolverWithoutDirectory(t *testing.T) {
+	_, err := NewMultiFilesOIDResolverFromDir(filepath.Join(t.TempDir(), "traps_db"))
+	assert.Error(t, err)
+}
+
+func TestMultiFilesOIDResolverWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	resolver.watch(10*time.Millisecond, stop)
+
+	// the traps of the files added while the resolver is running are resolved
+	_, err = resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+	require.NoError(t, err)
+	writeTrapsDB(t, dir, "a_vendor.yaml", userTrapsDB)
+	assert.Eventually(t, func() bool {
+		trapMetadata, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+		return err == nil && trapMetadata.Name == "vendorLinkUp"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the traps of the removed files are not resolved anymore
+	require.NoError(t, os.Remove(filepath.Join(dir, "dd_traps_db.json")))
+	assert.Eventually(t, func() bool {
+		_, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.3")
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	trapMetadata, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+	require.NoError(t, err)
+	assert.Equal(t, "vendorLinkUp", trapMetadata.Name)
+}
+
+func TestMultiFilesOIDResolverReloadWithoutDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+
+	// the traps DB is empty once its directory is removed
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, resolver.reload())
+	_, err = resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.3")
+	assert.Error(t, err)
+}
+
+// writeTrapsDBTarball writes a tar.gz bundle of traps DB files, in the order of the members.
+// The members whose name ends with .gz are gzipped.
+func writeTrapsDBTarball(t *testing.T, dir string, name string, members ...[2]string) {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, member := range members {
+		data := []byte(member[1])
+		if filepath.Ext(member[0]) == ".gz" {
+			var memberBuf bytes.Buffer
+			w := gzip.NewWriter(&memberBuf)
+			_, err := w.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			data = memberBuf.Bytes()
+		}
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: member[0], Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tarWriter.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644))
+}
+
+func TestMultiFilesOIDResolverTarball(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	// the members are loaded in the order of their names, not in the order of the bundle
+	writeTrapsDBTarball(t, dir, "vendor.tar.gz",
+		[2]string{"vendor/b_vendor.json.gz", `{"traps": {"1.3.6.1.6.3.1.1.5.4": {"name": "otherLinkUp"}}}`},
+		[2]string{"vendor/a_vendor.yaml", userTrapsDB},
+		[2]string{"vendor/._a_vendor.yaml", "hidden"},
+		[2]string{"vendor/invalid.json", "{"},
+		[2]string{"vendor/invalid.json.gz", "{"},
+		[2]string{"README.txt", "not a traps DB"},
+	)
+	for _, cacheSize := range []int{0, 10} {
+		resolver := newMultiFilesOIDResolver(dir)
+		resolver.cacheSize = cacheSize
+		require.NoError(t, resolver.reload())
+
+		fileCount, trapCount := resolver.Stats()
+		assert.Equal(t, 3, fileCount)
+		assert.Equal(t, 2, trapCount)
+
+		aFile, bFile := filepath.Join(dir, "vendor.tar.gz", "vendor", "a_vendor.yaml"), filepath.Join(dir, "vendor.tar.gz", "vendor", "b_vendor.json.gz")
+		trap, err := resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+		require.NoError(t, err)
+		assert.Equal(t, TrapMetadata{Name: "otherLinkUp", SourceFile: bFile}, trap)
+		variable, err := resolver.GetVariableMetadata("1.3.6.1.6.3.1.1.5.3", "1.3.6.1.2.1.2.2.1.8")
+		require.NoError(t, err)
+		assert.Equal(t, "ifOperStatus", variable.Name)
+
+		ddFile := filepath.Join(dir, "dd_traps_db.json")
+		assert.Equal(t, []OIDConflict{
+			{OID: "1.3.6.1.6.3.1.1.5.4", Kind: "trap", Files: []string{ddFile, aFile, bFile}, Winners: []string{bFile}},
+			// the only trap of a_vendor.yaml is overridden, its variable is not used
+			{OID: "1.3.6.1.2.1.2.2.1.8", Kind: "variable", Files: []string{ddFile, aFile}, Winners: []string{ddFile}},
+		}, resolver.Conflicts())
+	}
+
+	writeTrapsDB(t, dir, "invalid.tgz", "not a tarball")
+	_, err := NewMultiFilesOIDResolverFromDir(dir)
+	assert.NoError(t, err)
+}
+
+func TestMultiFilesOIDResolverConflicts(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "a_vendor.yaml", userTrapsDB)
+	writeTrapsDB(t, dir, "b_vendor.json", `{"traps": {"1.3.6.1.6.3.1.1.5.4": {"name": "otherLinkUp"}}}`)
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	for _, cacheSize := range []int{0, 10} {
+		resolver := newMultiFilesOIDResolver(dir)
+		resolver.cacheSize = cacheSize
+		require.NoError(t, resolver.reload())
+
+		ddFile, aFile, bFile := filepath.Join(dir, "dd_traps_db.json"), filepath.Join(dir, "a_vendor.yaml"), filepath.Join(dir, "b_vendor.json")
+		assert.Equal(t, []OIDConflict{
+			{OID: "1.3.6.1.6.3.1.1.5.4", Kind: "trap", Files: []string{ddFile, aFile, bFile}, Winners: []string{bFile}},
+			// the only trap of a_vendor.yaml is overridden, its variable is not used
+			{OID: "1.3.6.1.2.1.2.2.1.8", Kind: "variable", Files: []string{ddFile, aFile}, Winners: []string{ddFile}},
+		}, resolver.Conflicts())
+	}
+
+	// the conflicts are updated when the files are reloaded
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, "a_vendor.yaml")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "b_vendor.json")))
+	require.NoError(t, resolver.reload())
+	assert.Empty(t, resolver.Conflicts())
+}
+
+func TestNamespaceOIDResolver(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	tenantDir := filepath.Join(t.TempDir(), "tenant_a")
+	require.NoError(t, os.Mkdir(tenantDir, 0755))
+	writeTrapsDB(t, tenantDir, "tenant.yaml", userTrapsDB)
+
+	base := newMultiFilesOIDResolver(dir)
+	require.NoError(t, base.reload())
+	tenantResolver := newNamespaceOIDResolver(base, tenantDir)
+	require.NoError(t, tenantResolver.reload())
+
+	trap, err := tenantResolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+	require.NoError(t, err)
+	assert.Equal(t, "vendorLinkUp", trap.Name)
+	trap, err = base.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+	require.NoError(t, err)
+	assert.Equal(t, "linkUp", trap.Name)
+	fileCount, _ := tenantResolver.Stats()
+	assert.Equal(t, 2, fileCount)
+
+	// the resolvers of the namespaces are reloaded with their base, without loading its files again
+	writeTrapsDB(t, dir, "b_vendor.json", `{"traps": {"1.3.6.1.4.1.1.0.1": {"name": "newTrap"}}}`)
+	require.NoError(t, base.reload())
+	trap, err = tenantResolver.GetTrapMetadata("1.3.6.1.4.1.1.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "newTrap", trap.Name)
+	trap, err = tenantResolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+	require.NoError(t, err)
+	assert.Equal(t, "vendorLinkUp", trap.Name)
+
+	// the files of the namespace are watched without the ones of the base
+	assert.NotContains(t, tenantResolver.filesState(), dir+string(filepath.Separator))
+	assert.Contains(t, tenantResolver.filesState(), "tenant.yaml")
+}
+
+func TestGetOIDConflictsWithNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	tenantDir := filepath.Join(dir, "tenant_a")
+	require.NoError(t, os.Mkdir(tenantDir, 0755))
+	writeTrapsDB(t, tenantDir, "tenant.yaml", userTrapsDB)
+	tenantResolver := newMultiFilesOIDResolver(dir)
+	tenantResolver.namespaceDir = tenantDir
+	require.NoError(t, tenantResolver.reload())
+	serverInstance = &TrapServer{
+		oidResolver:        resolver,
+		namespaceResolvers: map[string]OIDResolver{"tenant-a": tenantResolver},
+	}
+	defer func() { serverInstance = nil }()
+
+	conflicts, err := GetOIDConflicts()
+	require.NoError(t, err)
+	ddFile, tenantFile := filepath.Join(dir, "dd_traps_db.json"), filepath.Join(tenantDir, "tenant.yaml")
+	assert.Equal(t, []OIDConflict{
+		{Namespace: "tenant-a", OID: "1.3.6.1.6.3.1.1.5.4", Kind: "trap", Files: []string{ddFile, tenantFile}, Winners: []string{tenantFile}},
+		// the variable is resolved with both files, depending on the trap
+		{Namespace: "tenant-a", OID: "1.3.6.1.2.1.2.2.1.8", Kind: "variable", Files: []string{ddFile, tenantFile}, Winners: []string{ddFile, tenantFile}},
+	}, conflicts)
+}
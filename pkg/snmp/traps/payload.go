@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+// TrapPayload is a formatted trap, serialized to JSON as the content of its log. The optional
+// fields are omitted when they are not set.
+type TrapPayload struct {
+	// Uptime is the uptime of the device in hundredths of seconds, also formatted as a humanized
+	// duration and as an ISO 8601 duration, which are only set from payload_schema_version 2 and
+	// are always omitted by the storm summaries
+	Uptime          uint32 `json:"uptime"`
+	UptimeHumanized string `json:"uptime_humanized,omitempty"`
+	UptimeISO8601   string `json:"uptime_iso8601,omitempty"`
+	OID             string `json:"oid"`
+	// V1TrapFields is only set for the v1 traps
+	*V1TrapFields
+	TrapName        string         `json:"trap_name,omitempty"`
+	TrapMIB         string         `json:"trap_mib,omitempty"`
+	TrapSourceFile  string         `json:"trap_source_file,omitempty"`
+	TrapDescription string         `json:"trap_description,omitempty"`
+	Variables       []TrapVariable `json:"variables"`
+	CorrelationID   string         `json:"correlation_id,omitempty"`
+	// CorrelationDuration is the number of seconds since the raise trap, for a clear trap
+	CorrelationDuration *float64 `json:"correlation_duration,omitempty"`
+	RawPDU              string   `json:"raw_pdu,omitempty"`
+	RawPDUTruncated     bool     `json:"raw_pdu_truncated,omitempty"`
+	// ContextFields is only set for the v3 traps
+	*ContextFields
+	// StormSummary is only set for the summaries of the traps received during a storm
+	StormSummary  *StormSummary `json:"storm_summary,omitempty"`
+	SchemaVersion int           `json:"schema_version,omitempty"`
+}
+
+// StormSummary aggregates the traps received from a device during a trap storm, which are not
+// forwarded individually.
+type StormSummary struct {
+	Count int `json:"count"`
+	// Interval is the number of seconds the traps were received in
+	Interval float64 `json:"interval"`
+	// TrapOIDs are the numbers of traps by OID, for the first OIDs of the traps
+	TrapOIDs map[string]int `json:"trap_oids"`
+}
+
+// V1TrapFields contains the fields specific to the v1 traps.
+type V1TrapFields struct {
+	EnterpriseOID string `json:"enterprise_oid"`
+	GenericTrap   int    `json:"generic_trap"`
+	SpecificTrap  int    `json:"specific_trap"`
+}
+
+// ContextFields contains the context of a v3 trap.
+type ContextFields struct {
+	ContextName     string `json:"context_name"`
+	ContextEngineID string `json:"context_engine_id"`
+}
+
+// TrapVariable is a formatted variable of a trap, its name and the following fields are only set
+// when the variable is defined for the trap in the traps DB.
+type TrapVariable struct {
+	OID   string      `json:"oid"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+	// ValueType annotates the values which are not formatted as their JSON type, e.g. "uint64"
+	// for the large unsigned integers formatted as strings
+	ValueType string `json:"value_type,omitempty"`
+	// ValueHumanized and ValueISO8601 are the durations of the TimeTicks values
+	ValueHumanized  string                 `json:"value_humanized,omitempty"`
+	ValueISO8601    string                 `json:"value_iso8601,omitempty"`
+	Name            string                 `json:"name,omitempty"`
+	MIB             string                 `json:"mib,omitempty"`
+	SourceFile      string                 `json:"source_file,omitempty"`
+	Description     string                 `json:"description,omitempty"`
+	ResolvedValue   interface{}            `json:"resolved_value,omitempty"`
+	Index           string                 `json:"index,omitempty"`
+	IndexComponents map[string]interface{} `json:"index_components,omitempty"`
+}
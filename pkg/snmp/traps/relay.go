@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/gosnmp/gosnmp"
+)
+
+// relayDestination is a downstream receiver the traps are relayed to.
+type relayDestination struct {
+	addr      *net.UDPAddr
+	community string
+}
+
+// trapRelay re-emits the received traps to downstream receivers, e.g. a legacy NMS, so that
+// the devices do not need to send their traps twice.
+type trapRelay struct {
+	conn         *net.UDPConn
+	destinations []relayDestination
+}
+
+// newTrapRelay resolves the destinations of the relay and opens the socket the traps are
+// relayed from, it returns nil when no destination is configured.
+func newTrapRelay(configs []RelayConfig) (*trapRelay, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	destinations := make([]relayDestination, 0, len(configs))
+	for _, c := range configs {
+		addr, err := net.ResolveUDPAddr("udp", c.Addr())
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve the relay destination %s: %w", c.Addr(), err)
+		}
+		destinations = append(destinations, relayDestination{addr: addr, community: c.Community})
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &trapRelay{conn: conn, destinations: destinations}, nil
+}
+
+// relay sends a trap to the destinations. The original message is sent unchanged, except when
+// the community of a v1 or v2c trap is rewritten for a destination: the v3 traps are always
+// sent unchanged since their authentication would not match a rewritten message.
+func (r *trapRelay) relay(p *gosnmp.SnmpPacket, msg []byte, addr *net.UDPAddr) {
+	for _, destination := range r.destinations {
+		out := msg
+		if destination.community != "" && p.Version != gosnmp.Version3 && destination.community != p.Community {
+			rewritten := *p
+			rewritten.Community = destination.community
+			var err error
+			if out, err = rewritten.MarshalMsg(); err != nil {
+				log.Warnf("Could not rewrite the trap from %s to relay it to %s: %v", addr.String(), destination.addr.String(), err)
+				trapsRelayErrors.Add(1)
+				continue
+			}
+		}
+		if _, err := r.conn.WriteToUDP(out, destination.addr); err != nil {
+			log.Debugf("Could not relay the trap from %s to %s: %v", addr.String(), destination.addr.String(), err)
+			trapsRelayErrors.Add(1)
+			continue
+		}
+		trapsRelayed.Add(1)
+	}
+}
+
+// close closes the socket of the relay.
+func (r *trapRelay) close() {
+	r.conn.Close()
+}
@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenRelayDestination listens for the relayed traps on a random port.
+func listenRelayDestination(t *testing.T) (*net.UDPConn, uint16) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// receiveRelayedTrap waits for a relayed trap and decodes it.
+func receiveRelayedTrap(t *testing.T, conn *net.UDPConn) *gosnmp.SnmpPacket {
+	buf := make([]byte, maxPacketSize)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	n, _, err := conn.ReadFromUDP(buf)
+	require.NoError(t, err)
+	params := &gosnmp.GoSNMP{Logger: gosnmp.NewLogger(&trapLogger{})}
+	packet := params.UnmarshalTrap(buf[:n], false)
+	require.NotNil(t, packet)
+	return packet
+}
+
+func TestServerRelay(t *testing.T) {
+	unchanged, unchangedPort := listenRelayDestination(t)
+	rewritten, rewrittenPort := listenRelayDestination(t)
+
+	config := Config{
+		Port:             GetPort(t),
+		CommunityStrings: []string{"public"},
+		Relays: []RelayConfig{
+			{Host: "127.0.0.1", Port: unchangedPort},
+			{Host: "127.0.0.1", Port: rewrittenPort, Community: "nms"},
+		},
+	}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	sendTestV2Trap(t, config, "public")
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+
+	relayed := receiveRelayedTrap(t, unchanged)
+	assert.Equal(t, "public", relayed.Community)
+	assert.Equal(t, packet.Content.Variables, relayed.Variables)
+
+	relayed = receiveRelayedTrap(t, rewritten)
+	assert.Equal(t, "nms", relayed.Community)
+	assert.Equal(t, packet.Content.Variables, relayed.Variables)
+
+	// the traps with invalid credentials are not relayed
+	sendTestV2Trap(t, config, "wrong")
+	require.NoError(t, unchanged.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err := unchanged.ReadFromUDP(make([]byte, maxPacketSize))
+	assert.Error(t, err)
+}
+
+func TestServerRelayV1(t *testing.T) {
+	conn, port := listenRelayDestination(t)
+	config := Config{
+		Port:             GetPort(t),
+		CommunityStrings: []string{"public"},
+		Relays:           []RelayConfig{{Host: "127.0.0.1", Port: port, Community: "nms"}},
+	}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	sendTestV1SpecificTrap(t, config, "public")
+	require.NotNil(t, receivePacket(t))
+
+	relayed := receiveRelayedTrap(t, conn)
+	assert.Equal(t, gosnmp.Version1, relayed.Version)
+	assert.Equal(t, "nms", relayed.Community)
+	relayed.SnmpTrap.Variables = relayed.Variables
+	assert.Equal(t, AlarmActiveStatev1SpecificTrap, relayed.SnmpTrap)
+}
+
+func TestRelaysConfig(t *testing.T) {
+	Configure(t, Config{Relays: []RelayConfig{{Host: "nms.example"}}})
+	config, err := ReadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, []RelayConfig{{Host: "nms.example", Port: 162}}, config.Relays)
+	assert.Equal(t, "nms.example:162", config.Relays[0].Addr())
+
+	Configure(t, Config{Relays: []RelayConfig{{Port: 1162}}})
+	_, err = ReadConfig("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/common"
+)
+
+// ResolvedTrap is the metadata of a trap and of some of its variables in the traps DB of the
+// running server, with the files defining them, to check the traps DB without receiving traps.
+type ResolvedTrap struct {
+	OID         string `json:"oid"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name,omitempty"`
+	MIB         string `json:"mib,omitempty"`
+	Description string `json:"description,omitempty"`
+	SourceFile  string `json:"source_file,omitempty"`
+	// Error is set when the trap is not defined
+	Error     string             `json:"error,omitempty"`
+	Variables []ResolvedVariable `json:"variables"`
+}
+
+// ResolvedVariable is the metadata of a variable of a trap in the traps DB, the index fields are
+// set for the instances of table columns.
+type ResolvedVariable struct {
+	OID             string                 `json:"oid"`
+	Name            string                 `json:"name,omitempty"`
+	MIB             string                 `json:"mib,omitempty"`
+	Description     string                 `json:"description,omitempty"`
+	SourceFile      string                 `json:"source_file,omitempty"`
+	Enumeration     map[int]string         `json:"enum,omitempty"`
+	Bits            map[int]string         `json:"bits,omitempty"`
+	Format          string                 `json:"format,omitempty"`
+	Index           string                 `json:"index,omitempty"`
+	IndexComponents map[string]interface{} `json:"index_components,omitempty"`
+	// Error is set when the variable is not defined for the trap
+	Error string `json:"error,omitempty"`
+}
+
+// ResolveOIDs resolves a trap OID and the OIDs of variables of the trap with the traps DB of a
+// device namespace, the one of the server by default, as the traps received by the server.
+func ResolveOIDs(namespace string, trapOID string, variableOIDs []string) (*ResolvedTrap, error) {
+	if serverInstance == nil {
+		return nil, errors.New("the SNMP traps server is not running")
+	}
+	if trapOID == "" {
+		return nil, errors.New("the trap OID is required")
+	}
+	if namespace == "" {
+		namespace = serverInstance.getConfig().Namespace
+	} else {
+		var err error
+		if namespace, err = common.NormalizeNamespace(namespace); err != nil {
+			return nil, err
+		}
+	}
+	resolver := getOIDResolver(namespace)
+	if resolver == nil {
+		return nil, errors.New("the traps DB is not loaded")
+	}
+	return resolveOIDs(resolver, namespace, normalizeOID(trapOID), variableOIDs), nil
+}
+
+func resolveOIDs(resolver OIDResolver, namespace string, trapOID string, variableOIDs []string) *ResolvedTrap {
+	resolved := &ResolvedTrap{OID: trapOID, Namespace: namespace, Variables: make([]ResolvedVariable, 0, len(variableOIDs))}
+	trap, err := resolver.GetTrapMetadata(trapOID)
+	if err != nil {
+		resolved.Error = err.Error()
+	} else {
+		resolved.Name = trap.Name
+		resolved.MIB = trap.MIBName
+		resolved.Description = trap.Description
+		resolved.SourceFile = trap.SourceFile
+	}
+
+	for _, oid := range variableOIDs {
+		variable := ResolvedVariable{OID: normalizeOID(oid)}
+		metadata, index, err := resolver.GetInstanceMetadata(trapOID, variable.OID)
+		if err != nil {
+			variable.Error = err.Error()
+			resolved.Variables = append(resolved.Variables, variable)
+			continue
+		}
+		variable.Name = metadata.Name
+		variable.MIB = metadata.MIBName
+		variable.Description = metadata.Description
+		variable.SourceFile = metadata.SourceFile
+		variable.Enumeration = metadata.Enumeration
+		variable.Bits = metadata.Bits
+		variable.Format = metadata.Format
+		if len(index) > 0 {
+			variable.Index = formatMIBOID(index)
+			if components, ok := decodeIndex(index, metadata.Index); ok {
+				variable.IndexComponents = components
+			}
+		}
+		resolved.Variables = append(resolved.Variables, variable)
+	}
+	return resolved
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveOIDs(t *testing.T) {
+	_, err := ResolveOIDs("", "1.3.6.1.6.3.1.1.5.3", nil)
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	writeTrapsDB(t, dir, "dd_traps_db.json", ddTrapsDB)
+	resolver, err := NewMultiFilesOIDResolverFromDir(dir)
+	require.NoError(t, err)
+	serverInstance = &TrapServer{config: &Config{Namespace: "default"}, oidResolver: resolver}
+	defer func() { serverInstance = nil }()
+
+	ddFile := filepath.Join(dir, "dd_traps_db.json")
+	resolved, err := ResolveOIDs("", ".1.3.6.1.6.3.1.1.5.3", []string{".1.3.6.1.2.1.2.2.1.8.2", "1.3.6.1.2.1.2.2.1.1", "1.3.6.1.4.1.8072.2.3.2.1"})
+	require.NoError(t, err)
+	assert.Equal(t, &ResolvedTrap{
+		OID:         "1.3.6.1.6.3.1.1.5.3",
+		Namespace:   "default",
+		Name:        "linkDown",
+		MIB:         "IF-MIB",
+		Description: "A linkDown trap",
+		SourceFile:  ddFile,
+		Variables: []ResolvedVariable{
+			{
+				OID:             "1.3.6.1.2.1.2.2.1.8.2",
+				Name:            "ifOperStatus",
+				Description:     "The operational state",
+				SourceFile:      ddFile,
+				Enumeration:     map[int]string{1: "up", 2: "down", 3: "testing"},
+				Index:           "2",
+				IndexComponents: map[string]interface{}{"ifIndex": 2},
+			},
+			{OID: "1.3.6.1.2.1.2.2.1.1", Name: "ifIndex", Description: "A unique value for each interface", SourceFile: ddFile},
+			{OID: "1.3.6.1.4.1.8072.2.3.2.1", Error: "variable OID 1.3.6.1.4.1.8072.2.3.2.1 is not defined for trap 1.3.6.1.6.3.1.1.5.3"},
+		},
+	}, resolved)
+
+	// the traps which are not defined are reported with the error of the resolver
+	resolved, err = ResolveOIDs("other", "1.3.6.1.6.3.1.1.5.5", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "other", resolved.Namespace)
+	assert.NotEmpty(t, resolved.Error)
+	assert.Empty(t, resolved.Variables)
+
+	_, err = ResolveOIDs("", "", nil)
+	assert.Error(t, err)
+}
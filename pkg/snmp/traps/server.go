@@ -6,9 +6,16 @@
 package traps
 
 import (
+	"errors"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/gosnmp/gosnmp"
 )
@@ -17,19 +24,75 @@ import (
 type SnmpPacket struct {
 	Content *gosnmp.SnmpPacket
 	Addr    *net.UDPAddr
+	// config is the configuration of the listener which received the packet
+	config *Config
+	// raw is the original message of the packet, without its community string, it is only kept
+	// when the raw PDUs are included in the formatted traps
+	raw []byte
+	// correlation pairs the packet with the raise trap of its pair, if any
+	correlation *correlation
+	// storm is set for the summaries of the traps received from a device during a storm, whose
+	// content only has the version of the traps
+	storm *StormSummary
+	// injected is set for the synthetic traps injected into the running server
+	injected bool
+}
+
+// IsInform returns whether the packet is an inform, which has been acknowledged to its sender.
+func (p *SnmpPacket) IsInform() bool {
+	return p.Content.PDUType == gosnmp.InformRequest
 }
 
 // PacketsChannel is the type of channels of trap packets.
 type PacketsChannel = chan *SnmpPacket
 
-// TrapServer manages an SNMP trap listener.
+// TrapServer manages the SNMP trap listeners.
 type TrapServer struct {
-	Addr     string
-	config   *Config
-	listener *gosnmp.TrapListener
-	packets  PacketsChannel
+	Addr          string
+	agentHostname string
+	// reloadMu serializes the reloads of the listeners and the stop of the server
+	reloadMu sync.Mutex
+	// config and listeners are swapped when the listeners are reloaded
+	mu        sync.RWMutex
+	config    *Config
+	listeners []*trapListener
+	stopped   bool
+	packets   PacketsChannel
+	// received is the channel of the packets received by the listeners, which are dispatched to
+	// the outputs when they are not only forwarded as logs
+	received PacketsChannel
+	// oidResolver enriches the traps with the metadata of the traps DB
+	oidResolver OIDResolver
+	// namespaceResolvers enrich the traps of the namespaces which have their own traps DB files
+	namespaceResolvers map[string]OIDResolver
+	// stopOIDResolver stops the reload of the traps DB and the fetch of its bundles
+	stopOIDResolver chan struct{}
+	relay           *trapRelay
+	// correlator pairs the raise and clear traps, it is nil when no pair is configured
+	correlator *trapCorrelator
+	// storm summarizes the traps during the trap storms, it is nil when they are not detected
+	storm *stormDetector
+	// spool buffers the traps while the logs pipeline is blocked, it is nil when it is disabled
+	spool *Spool
+	// health reports the server healthy to the Agent until it is stopped
+	health     *health.Handle
+	stopHealth chan struct{}
+	// injecting tracks the injected traps being sent, the packets channel is closed once they
+	// are sent or stopInject is closed
+	injecting  sync.WaitGroup
+	stopInject chan struct{}
 }
 
+const (
+	// healthName is the name of the traps server in the health of the Agent.
+	healthName = "snmp-traps"
+	// healthCheckInterval is the interval at which the listeners are checked while the server
+	// is unhealthy.
+	healthCheckInterval = time.Second
+	// forwardTimeout is the time after which a listener blocked forwarding a packet is unhealthy.
+	forwardTimeout = 30 * time.Second
+)
+
 var (
 	serverInstance *TrapServer
 	startError     error
@@ -62,14 +125,85 @@ func GetPacketsChannel() PacketsChannel {
 	return serverInstance.packets
 }
 
+// GetSpool returns the spool of the traps forwarded as logs, or nil if it is disabled.
+func GetSpool() *Spool {
+	if serverInstance != nil {
+		return serverInstance.spool
+	}
+	return nil
+}
+
 // GetNamespace returns the device namespace for the traps listener.
 func GetNamespace() string {
 	if serverInstance != nil {
-		return serverInstance.config.Namespace
+		return serverInstance.getConfig().Namespace
 	}
 	return defaultNamespace
 }
 
+// getListenerConfig returns the configuration of the listener which received a packet, or nil
+// if it is not known.
+func getListenerConfig(packet *SnmpPacket) *Config {
+	if packet.config != nil {
+		return packet.config
+	}
+	if serverInstance != nil {
+		return serverInstance.getConfig()
+	}
+	return nil
+}
+
+// getOIDResolver returns the resolver of the traps DB of a device namespace, or nil if it is
+// not available.
+func getOIDResolver(namespace string) OIDResolver {
+	if serverInstance == nil {
+		return nil
+	}
+	if resolver, ok := serverInstance.namespaceResolvers[namespace]; ok {
+		return resolver
+	}
+	return serverInstance.oidResolver
+}
+
+// GetOIDConflicts returns the OIDs defined by several files of the traps DB of the running
+// server, and of the traps DBs of its device namespaces, sorted by namespace.
+func GetOIDConflicts() ([]OIDConflict, error) {
+	if serverInstance == nil {
+		return nil, errors.New("the SNMP traps server is not running")
+	}
+	resolver, ok := serverInstance.oidResolver.(*MultiFilesOIDResolver)
+	if !ok {
+		return nil, errors.New("the traps DB does not report its conflicts")
+	}
+	conflicts := resolver.Conflicts()
+	namespaces := make([]string, 0, len(serverInstance.namespaceResolvers))
+	for namespace := range serverInstance.namespaceResolvers {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		resolver, ok := serverInstance.namespaceResolvers[namespace].(*MultiFilesOIDResolver)
+		if !ok {
+			continue
+		}
+		for _, conflict := range resolver.Conflicts() {
+			conflict.Namespace = namespace
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts, nil
+}
+
+// ReloadServer reloads the configuration of the listeners of the running server: their ports,
+// credentials, namespaces and tags. The listeners are replaced without dropping the packets
+// being received.
+func ReloadServer() error {
+	if serverInstance == nil {
+		return errors.New("the SNMP traps server is not running")
+	}
+	return serverInstance.reload()
+}
+
 // NewTrapServer configures and returns a running SNMP traps server.
 func NewTrapServer(agentHostname string) (*TrapServer, error) {
 	config, err := ReadConfig(agentHostname)
@@ -78,82 +212,339 @@ func NewTrapServer(agentHostname string) (*TrapServer, error) {
 	}
 
 	packets := make(PacketsChannel, packetsChanSize)
+	received := packets
+	if config.hasOutput(eventsOutput) {
+		if eventSender == nil {
+			return nil, errors.New("the traps cannot be forwarded as events, the event sender is not set")
+		}
+		received = make(PacketsChannel, packetsChanSize)
+	}
 
-	listener, err := startSNMPTrapListener(config, packets)
+	relay, err := newTrapRelay(config.Relays)
 	if err != nil {
 		return nil, err
 	}
+	correlator, err := newTrapCorrelator(config.Correlations)
+	if err != nil {
+		if relay != nil {
+			relay.close()
+		}
+		return nil, err
+	}
+
+	var spool *Spool
+	if config.SpoolMaxSize > 0 && config.hasOutput(logsOutput) {
+		if spool, err = NewSpool(config.SpoolPath, config.SpoolMaxSize); err != nil {
+			if relay != nil {
+				relay.close()
+			}
+			return nil, err
+		}
+	}
 
 	server := &TrapServer{
-		listener: listener,
-		config:   config,
-		packets:  packets,
+		agentHostname: agentHostname,
+		config:        config,
+		packets:       packets,
+		received:      received,
+		relay:         relay,
+		correlator:    correlator,
+		spool:         spool,
+	}
+	server.storm = newStormDetector(config, received)
+	server.stopOIDResolver = make(chan struct{})
+	server.stopInject = make(chan struct{})
+	bundleFetchers := prepareBundleFetchers(config)
+	oidResolver := loadOIDResolver(config)
+	server.oidResolver = oidResolver
+	resolvers := []*MultiFilesOIDResolver{oidResolver}
+	if len(config.TrapsDBSets) > 0 {
+		server.namespaceResolvers = make(map[string]OIDResolver, len(config.TrapsDBSets))
+		for _, set := range config.TrapsDBSets {
+			// the other files are loaded once, by the resolver of the namespaces without their own files
+			resolver := newNamespaceOIDResolver(oidResolver, set.Dir)
+			if err := resolver.reload(); err != nil {
+				log.Warnf("Could not load the traps DB of namespace %s: %v", set.Namespace, err)
+			}
+			server.namespaceResolvers[set.Namespace] = resolver
+			resolvers = append(resolvers, resolver)
+		}
+	}
+	if config.TrapsDBReloadInterval > 0 {
+		// the traps DB files added while the Agent is running are taken into account
+		for _, resolver := range resolvers {
+			resolver.watch(time.Duration(config.TrapsDBReloadInterval)*time.Second, server.stopOIDResolver)
+		}
+	}
+	for _, fetcher := range bundleFetchers {
+		// the resolvers of the namespaces are reloaded with the resolver they are based on
+		go fetcher.run(func() {
+			if err := oidResolver.reload(); err != nil {
+				log.Warnf("Could not reload the traps DB: %v", err)
+			}
+		}, server.stopOIDResolver)
 	}
 
+	for _, listenerConfig := range config.listenerConfigs() {
+		listener, err := startTrapListener(listenerConfig, received, relay, correlator, server.storm)
+		if err != nil {
+			for _, started := range server.listeners {
+				started.close()
+			}
+			if server.storm != nil {
+				server.storm.close()
+			}
+			if relay != nil {
+				relay.close()
+			}
+			if spool != nil {
+				spool.close()
+			}
+			close(server.stopOIDResolver)
+			return nil, err
+		}
+		server.listeners = append(server.listeners, listener)
+	}
+	if received != packets {
+		go server.dispatch(eventSender)
+	}
+	server.health = health.RegisterLiveness(healthName)
+	server.stopHealth = make(chan struct{})
+	go server.reportHealth()
+
 	return server, nil
 }
 
-func startSNMPTrapListener(c *Config, packets PacketsChannel) (*gosnmp.TrapListener, error) {
-	var err error
-	listener := gosnmp.NewTrapListener()
-	listener.Params, err = c.BuildSNMPParams()
-	if err != nil {
-		return nil, err
+// reportHealth reads the health handle of the server while its listeners receive the packets,
+// until the server is stopped.
+func (s *TrapServer) reportHealth() {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		var healthC <-chan time.Time
+		if s.listenersAlive() {
+			healthC = s.health.C
+		}
+		select {
+		case <-healthC:
+		case <-ticker.C:
+		case <-s.stopHealth:
+			return
+		}
 	}
+}
 
-	listener.OnNewTrap = func(p *gosnmp.SnmpPacket, u *net.UDPAddr) {
-		if err := validatePacket(p, c); err != nil {
-			log.Warnf("Invalid credentials from %s on listener %s, dropping packet", u.String(), c.Addr())
-			trapsPacketsAuthErrors.Add(1)
-			return
+// listenersAlive returns whether the loops of all the listeners receive the packets.
+func (s *TrapServer) listenersAlive() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, listener := range s.listeners {
+		if !listener.isAlive(forwardTimeout) {
+			return false
 		}
-		log.Debugf("Packet received from %s on listener %s", u.String(), c.Addr())
-		trapsPackets.Add(1)
-		packets <- &SnmpPacket{Content: p, Addr: u}
 	}
+	return true
+}
 
-	errors := make(chan error, 1)
+// getConfig returns the configuration of the server.
+func (s *TrapServer) getConfig() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
 
-	// Start actually listening in the background.
-	go func() {
-		log.Infof("Start listening for traps on %s", c.Addr())
-		err := listener.Listen(c.Addr())
+// reload reads the configuration of the listeners again and replaces the listeners. The socket
+// of a listener whose address is unchanged is handed over to the listener replacing it, the
+// other listeners are stopped once the new ones are started. The listeners are left unchanged
+// when the new configuration is invalid or a new listener cannot be started.
+func (s *TrapServer) reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.stopped {
+		return errors.New("the SNMP traps server is stopped")
+	}
+
+	config, err := ReadConfig(s.agentHostname)
+	if err != nil {
+		return err
+	}
+	// the outputs, the relays, the correlations, the spool, the traps DB and the storm detection
+	// are only configured when the server starts
+	previous := s.getConfig()
+	config.StopTimeout = previous.StopTimeout
+	config.Outputs = previous.Outputs
+	config.Relays = previous.Relays
+	config.Correlations = previous.Correlations
+	config.SpoolMaxSize = previous.SpoolMaxSize
+	config.SpoolPath = previous.SpoolPath
+	config.MIBsDir = previous.MIBsDir
+	config.TrapsDBReloadInterval = previous.TrapsDBReloadInterval
+	config.TrapsDBBundles = previous.TrapsDBBundles
+	config.TrapsDBCacheSize = previous.TrapsDBCacheSize
+	config.TrapsDBSets = previous.TrapsDBSets
+	config.StormThreshold = previous.StormThreshold
+	config.StormSummaryInterval = previous.StormSummaryInterval
+
+	previousListeners := make(map[string]*trapListener, len(s.listeners))
+	for _, listener := range s.listeners {
+		previousListeners[listener.config.Addr()] = listener
+	}
+	var listeners, started []*trapListener
+	handOvers := make(map[*trapListener]*trapListener)
+	for _, listenerConfig := range config.listenerConfigs() {
+		listener, err := newTrapListener(listenerConfig, s.received, s.relay, s.correlator, s.storm)
+		if err == nil {
+			if previousListener, ok := previousListeners[listenerConfig.Addr()]; ok {
+				handOvers[previousListener] = listener
+			} else {
+				var conn *net.UDPConn
+				if conn, err = listenUDP(listenerConfig); err == nil {
+					listener.start(conn)
+					started = append(started, listener)
+				}
+			}
+		}
 		if err != nil {
-			errors <- err
+			for _, listener := range started {
+				listener.close()
+			}
+			return err
 		}
-	}()
+		listeners = append(listeners, listener)
+	}
 
-	select {
-	// Wait for listener to be started and listening to traps.
-	// See: https://godoc.org/github.com/gosnmp/gosnmp#TrapListener.Listening
-	case <-listener.Listening():
-		break
-	// If the listener failed to start (eg because it couldn't bind to a socket),
-	// we'll get an error here.
-	case err := <-errors:
-		close(errors)
-		return nil, err
+	for _, previousListener := range s.listeners {
+		next, ok := handOvers[previousListener]
+		if !ok {
+			log.Infof("Stop listening on %s", previousListener.config.Addr())
+			previousListener.close()
+			continue
+		}
+		if err := previousListener.handOver(next); err != nil {
+			log.Warnf("Could not hand over the socket of listener %s, reopening it: %v", previousListener.config.Addr(), err)
+			previousListener.close()
+			conn, err := listenUDP(next.config)
+			if err != nil {
+				log.Errorf("Could not restart listener %s: %v", next.config.Addr(), err)
+				continue
+			}
+			next.start(conn)
+		}
+	}
+	running := listeners[:0]
+	for _, listener := range listeners {
+		if listener.conn != nil {
+			running = append(running, listener)
+		}
 	}
 
-	return listener, nil
+	s.mu.Lock()
+	s.config = config
+	s.listeners = running
+	s.mu.Unlock()
+	log.Infof("Reloaded the configuration of the SNMP traps listeners")
+	return nil
+}
+
+// dispatch forwards the received packets as events, and as logs when it is enabled, until the
+// listeners are stopped.
+func (s *TrapServer) dispatch(sender EventSender) {
+	defer close(s.packets)
+	forwardLogs := s.getConfig().hasOutput(logsOutput)
+	for packet := range s.received {
+		forwardEvent(sender, packet)
+		if forwardLogs {
+			s.packets <- packet
+		}
+	}
+}
+
+// loadOIDResolver loads the traps DB files, the cached bundles and the MIB files, the resolver
+// is empty when none of them is available.
+func loadOIDResolver(c *Config) *MultiFilesOIDResolver {
+	oidResolver := newMultiFilesOIDResolver(trapsDBDir())
+	oidResolver.mibsDir = c.MIBsDir
+	oidResolver.cacheSize = c.TrapsDBCacheSize
+	if len(c.TrapsDBBundles) > 0 {
+		oidResolver.bundlesDir = trapsDBBundlesDir()
+	}
+	if err := oidResolver.reload(); err != nil {
+		log.Warnf("Could not load the traps DB: %v", err)
+	}
+	return oidResolver
+}
+
+// prepareBundleFetchers returns the fetchers of the traps DB bundles, the cached bundles which
+// are not configured anymore are removed so that they are not loaded.
+func prepareBundleFetchers(c *Config) []*bundleFetcher {
+	if len(c.TrapsDBBundles) == 0 {
+		return nil
+	}
+	dir := trapsDBBundlesDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Warnf("Could not create the cache directory of the traps DB bundles: %v", err)
+		return nil
+	}
+
+	var fetchers []*bundleFetcher
+	paths := make(map[string]bool, len(c.TrapsDBBundles))
+	for i, bundle := range c.TrapsDBBundles {
+		fetcher := newBundleFetcher(bundle, i, dir)
+		fetchers = append(fetchers, fetcher)
+		paths[fetcher.path] = true
+	}
+	if files, err := ioutil.ReadDir(dir); err == nil {
+		for _, file := range files {
+			if path := filepath.Join(dir, file.Name()); !paths[path] {
+				_ = os.Remove(path)
+			}
+		}
+	}
+	return fetchers
 }
 
 // Stop stops the TrapServer.
 func (s *TrapServer) Stop() {
+	if s.stopOIDResolver != nil {
+		close(s.stopOIDResolver)
+	}
+	if s.health != nil {
+		close(s.stopHealth)
+		s.health.Deregister() //nolint:errcheck
+	}
+	if s.stopInject != nil {
+		close(s.stopInject)
+	}
 	stopped := make(chan interface{})
 
 	go func() {
-		log.Infof("Stop listening on %s", s.config.Addr())
-		s.listener.Close()
+		s.reloadMu.Lock()
+		defer s.reloadMu.Unlock()
+		s.stopped = true
+		s.injecting.Wait()
+		for _, listener := range s.listeners {
+			log.Infof("Stop listening on %s", listener.config.Addr())
+			listener.close()
+		}
+		if s.relay != nil {
+			s.relay.close()
+		}
+		if s.storm != nil {
+			// the summaries of the storm in progress are sent before the packets channel is closed
+			s.storm.close()
+		}
 		close(stopped)
 	}()
 
 	select {
 	case <-stopped:
-	case <-time.After(time.Duration(s.config.StopTimeout) * time.Second):
-		log.Errorf("Stopping server. Timeout after %d seconds", s.config.StopTimeout)
+	case <-time.After(time.Duration(s.getConfig().StopTimeout) * time.Second):
+		log.Errorf("Stopping server. Timeout after %d seconds", s.getConfig().StopTimeout)
 	}
 
 	// Let consumers know that we will not be sending any more packets.
-	close(s.packets)
+	close(s.received)
+	if s.spool != nil {
+		s.spool.close()
+	}
 }
@@ -6,8 +6,12 @@
 package traps
 
 import (
+	"fmt"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/gosnmp/gosnmp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -71,6 +75,38 @@ func TestServerV2BadCredentials(t *testing.T) {
 	assertNoPacketReceived(t)
 }
 
+func TestServerV2Inform(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}}
+	Configure(t, config)
+
+	err := StartServer("dummy_hostname")
+	require.NoError(t, err)
+	defer StopServer()
+
+	// the inform is acknowledged
+	err = sendTestV2Inform(t, config, "public")
+	require.NoError(t, err)
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	assertIsValidV2Packet(t, packet, config)
+	assertVariables(t, packet)
+	assert.True(t, packet.IsInform())
+	assert.Contains(t, GetTags(packet), "snmp_inform:true")
+}
+
+func TestServerV2InformBadCredentials(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}}
+	Configure(t, config)
+
+	err := StartServer("dummy_hostname")
+	require.NoError(t, err)
+	defer StopServer()
+
+	err = sendTestV2Inform(t, config, "wrong-community")
+	assert.Error(t, err)
+	assertNoPacketReceived(t)
+}
+
 func TestServerV3(t *testing.T) {
 	userV3 := UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes"}
 	config := Config{Port: GetPort(t), Users: []UserV3{userV3}}
@@ -113,6 +149,120 @@ func TestServerV3BadCredentials(t *testing.T) {
 	assertNoPacketReceived(t)
 }
 
+func TestServerV3StrongProtocols(t *testing.T) {
+	for _, tc := range []struct {
+		authProtocol gosnmp.SnmpV3AuthProtocol
+		privProtocol gosnmp.SnmpV3PrivProtocol
+		user         UserV3
+	}{
+		{gosnmp.SHA256, gosnmp.AES192, UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha256", PrivKey: "password", PrivProtocol: "aes192"}},
+		{gosnmp.SHA512, gosnmp.AES256C, UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha512", PrivKey: "password", PrivProtocol: "aes256c"}},
+	} {
+		config := Config{Port: GetPort(t), Users: []UserV3{tc.user}}
+		Configure(t, config)
+
+		err := StartServer("dummy_hostname")
+		require.NoError(t, err)
+
+		sendTestV3Trap(t, config, &gosnmp.UsmSecurityParameters{
+			UserName:                 "user",
+			AuthoritativeEngineID:    "foo",
+			AuthenticationPassphrase: "password",
+			AuthenticationProtocol:   tc.authProtocol,
+			PrivacyPassphrase:        "password",
+			PrivacyProtocol:          tc.privProtocol,
+		})
+		packet := receivePacket(t)
+		StopServer()
+		require.NotNil(t, packet)
+		assertVariables(t, packet)
+	}
+}
+
+func TestServerV3MultipleUsers(t *testing.T) {
+	users := []UserV3{
+		{Username: "user", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes"},
+		{Username: "other", AuthKey: "otherpassword", AuthProtocol: "md5", PrivKey: "otherpassword", PrivProtocol: "des"},
+		{Username: "noauth"},
+	}
+	config := Config{Port: GetPort(t), Users: users}
+	Configure(t, config)
+
+	err := StartServer("dummy_hostname")
+	require.NoError(t, err)
+	defer StopServer()
+
+	sendTestV3Trap(t, config, &gosnmp.UsmSecurityParameters{
+		UserName:                 "other",
+		AuthoritativeEngineID:    "foo",
+		AuthenticationPassphrase: "otherpassword",
+		AuthenticationProtocol:   gosnmp.MD5,
+		PrivacyPassphrase:        "otherpassword",
+		PrivacyProtocol:          gosnmp.DES,
+	})
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	assertVariables(t, packet)
+	assert.Equal(t, "other", packet.Content.SecurityParameters.(*gosnmp.UsmSecurityParameters).UserName)
+
+	sendTestV3Trap(t, config, &gosnmp.UsmSecurityParameters{
+		UserName:                 "user",
+		AuthoritativeEngineID:    "foo",
+		AuthenticationPassphrase: "password",
+		AuthenticationProtocol:   gosnmp.SHA,
+		PrivacyPassphrase:        "password",
+		PrivacyProtocol:          gosnmp.AES,
+	})
+	packet = receivePacket(t)
+	require.NotNil(t, packet)
+	assertVariables(t, packet)
+	assert.Equal(t, "user", packet.Content.SecurityParameters.(*gosnmp.UsmSecurityParameters).UserName)
+
+	// the credentials of a user are not valid for another one
+	sendTestV3Trap(t, config, &gosnmp.UsmSecurityParameters{
+		UserName:                 "other",
+		AuthoritativeEngineID:    "foo",
+		AuthenticationPassphrase: "password",
+		AuthenticationProtocol:   gosnmp.SHA,
+		PrivacyPassphrase:        "password",
+		PrivacyProtocol:          gosnmp.AES,
+	})
+	assertNoPacketReceived(t)
+}
+
+func TestServerMultipleListeners(t *testing.T) {
+	config := Config{
+		Port:             GetPort(t),
+		CommunityStrings: []string{"public"},
+		Tags:             []string{"network:main"},
+		Listeners: []ListenerConfig{
+			{Port: GetPort(t), CommunityStrings: []string{"private"}, Namespace: "dmz", Tags: []string{"network:dmz"}},
+		},
+	}
+	Configure(t, config)
+
+	err := StartServer("dummy_hostname")
+	require.NoError(t, err)
+	defer StopServer()
+
+	sendTestV2Trap(t, config, "public")
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	assertVariables(t, packet)
+	assert.Equal(t, []string{"snmp_version:2", "device_namespace:default", "snmp_device:127.0.0.1", "network:main"}, GetTags(packet))
+
+	dmzConfig := Config{Port: config.Listeners[0].Port}
+	sendTestV2Trap(t, dmzConfig, "private")
+	packet = receivePacket(t)
+	require.NotNil(t, packet)
+	assertVariables(t, packet)
+	assert.Equal(t, []string{"snmp_version:2", "device_namespace:dmz", "snmp_device:127.0.0.1", "network:dmz"}, GetTags(packet))
+
+	// each listener only accepts its own credentials
+	sendTestV2Trap(t, dmzConfig, "public")
+	assertNoPacketReceived(t)
+}
+
 func TestStartFailure(t *testing.T) {
 	/*
 		Start two servers with the same config to trigger an "address already in use" error.
@@ -131,3 +281,117 @@ func TestStartFailure(t *testing.T) {
 	require.Nil(t, failedServer)
 	require.Error(t, err)
 }
+
+func TestServerHealth(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}}
+	Configure(t, config)
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: int(config.Port)})
+	require.NoError(t, err)
+
+	// the server which could not be started is not registered, its error is reported in the status
+	require.Error(t, StartServer("dummy_hostname"))
+	live := health.GetLive()
+	assert.NotContains(t, live.Healthy, "snmp-traps")
+	assert.NotContains(t, live.Unhealthy, "snmp-traps")
+	assert.Contains(t, GetStatus()["error"], "address already in use")
+	StopServer()
+
+	// the running server reads its health handle while its listeners receive the packets
+	conn.Close()
+	require.NoError(t, StartServer("dummy_hostname"))
+	assert.Contains(t, append(health.GetLive().Healthy, health.GetLive().Unhealthy...), "snmp-traps")
+	assert.Eventually(t, func() bool {
+		return len(serverInstance.health.C) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	StopServer()
+	live = health.GetLive()
+	assert.NotContains(t, append(live.Healthy, live.Unhealthy...), "snmp-traps")
+}
+
+func TestListenerIsAlive(t *testing.T) {
+	l := &trapListener{stopped: make(chan struct{})}
+	assert.True(t, l.isAlive(forwardTimeout))
+
+	// the listener blocked forwarding a packet is alive until the timeout
+	l.forwardingSince = time.Now().UnixNano()
+	assert.True(t, l.isAlive(forwardTimeout))
+	l.forwardingSince = time.Now().Add(-forwardTimeout).UnixNano()
+	assert.False(t, l.isAlive(forwardTimeout))
+
+	// the listener whose loop stopped is not alive
+	l.forwardingSince = 0
+	close(l.stopped)
+	assert.False(t, l.isAlive(forwardTimeout))
+}
+
+func TestServerStatus(t *testing.T) {
+	config := Config{
+		Port:             GetPort(t),
+		CommunityStrings: []string{"public"},
+		Users:            []UserV3{{Username: "user", AuthKey: "password", AuthProtocol: "sha"}},
+		Listeners:        []ListenerConfig{{Port: GetPort(t), Namespace: "dmz"}},
+	}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	status := GetStatus()
+	assert.Equal(t, []map[string]interface{}{
+		{"address": fmt.Sprintf("localhost:%d", config.Port), "namespace": "default", "community_strings": 1, "users": []string{"user"}},
+		{"address": fmt.Sprintf("localhost:%d", config.Listeners[0].Port), "namespace": "dmz", "community_strings": 1, "users": []string{"user"}},
+	}, status["listeners"])
+	assert.Equal(t, map[string]interface{}{"files": 0, "traps": 0}, status["traps_db"])
+}
+
+func TestServerReload(t *testing.T) {
+	config := Config{
+		Port:             GetPort(t),
+		CommunityStrings: []string{"public"},
+		Listeners:        []ListenerConfig{{Port: GetPort(t), CommunityStrings: []string{"private"}}},
+	}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	sendTestV2Trap(t, config, "public")
+	require.NotNil(t, receivePacket(t))
+
+	reloaded := Config{
+		Port:             config.Port,
+		CommunityStrings: []string{"public", "new"},
+		Namespace:        "reloaded",
+		Listeners:        []ListenerConfig{{Port: GetPort(t), CommunityStrings: []string{"other"}}},
+	}
+	Configure(t, reloaded)
+
+	// the packets received while the listeners are reloaded are not dropped
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for i := 0; i < 20; i++ {
+			sendTestV2Trap(t, config, "public")
+		}
+	}()
+	require.NoError(t, ReloadServer())
+	<-sent
+	for i := 0; i < 20; i++ {
+		require.NotNil(t, receivePacket(t))
+	}
+
+	sendTestV2Trap(t, config, "new")
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	assert.Contains(t, GetTags(packet), "device_namespace:reloaded")
+
+	// the listeners which are not configured anymore are stopped
+	sendTestV2Trap(t, Config{Port: config.Listeners[0].Port}, "private")
+	assertNoPacketReceived(t)
+	sendTestV2Trap(t, Config{Port: reloaded.Listeners[0].Port}, "other")
+	require.NotNil(t, receivePacket(t))
+
+	// the listeners are unchanged when the configuration is invalid
+	Configure(t, Config{Port: config.Port, Users: []UserV3{{Username: "user"}, {Username: "user"}}})
+	assert.Error(t, ReloadServer())
+	sendTestV2Trap(t, config, "new")
+	require.NotNil(t, receivePacket(t))
+}
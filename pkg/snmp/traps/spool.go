@@ -0,0 +1,276 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	spoolSegmentPrefix = "spool-"
+	spoolSegmentSuffix = ".jsonl"
+	// spoolSegments is the number of segments the spool is split into, the oldest segment is
+	// dropped when the spool is full
+	spoolSegments = 10
+)
+
+// SpooledTrap is a formatted trap buffered in the spool.
+type SpooledTrap struct {
+	Content   json.RawMessage `json:"content"`
+	Tags      []string        `json:"tags"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// spoolSegment is a file of the spool, which contains a spooled trap per line.
+type spoolSegment struct {
+	path  string
+	size  int64
+	count int
+}
+
+// Spool buffers the formatted traps on disk while their output is unavailable, so that they
+// are not lost during the outages of the intake and are replayed once it has recovered.
+// The spool is bounded, its oldest traps are dropped when it is full. It is kept across the
+// restarts of the Agent, the traps of a segment being replayed when the Agent stops may be
+// replayed twice.
+type Spool struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	mu       sync.Mutex
+	segments []*spoolSegment
+	size     int64
+	count    int
+	nextID   uint64
+	// writer appends to the last segment, it is nil when a new segment must be created
+	writer *os.File
+	// pending are the traps of the first segment left to replay, loaded when it is replayed
+	pending []SpooledTrap
+	loaded  bool
+}
+
+// spoolDir returns the default directory of the spool.
+func spoolDir() string {
+	return filepath.Join(config.Datadog.GetString("run_path"), "snmp_traps_spool")
+}
+
+// NewSpool opens the spool stored in a directory, with the traps spooled before the Agent
+// was restarted.
+func NewSpool(dir string, maxSize int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("could not create the directory of the spool: %w", err)
+	}
+	s := &Spool{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: maxSize / spoolSegments,
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the directory of the spool: %w", err)
+	}
+	var ids []uint64
+	for _, file := range files {
+		if id, ok := parseSegmentName(file.Name()); ok && !file.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		segment := &spoolSegment{path: s.segmentPath(id)}
+		content, err := ioutil.ReadFile(segment.path)
+		if err != nil {
+			log.Warnf("Could not read the spool segment %s, dropping it: %v", segment.path, err)
+			_ = os.Remove(segment.path)
+			continue
+		}
+		segment.size = int64(len(content))
+		segment.count = bytes.Count(content, []byte{'\n'})
+		s.segments = append(s.segments, segment)
+		s.size += segment.size
+		s.count += segment.count
+		s.nextID = id + 1
+	}
+	s.dropOverflow()
+	trapsSpooled.Set(int64(s.count))
+	if s.count > 0 {
+		log.Infof("Found %d traps in the spool, they will be replayed", s.count)
+	}
+	return s, nil
+}
+
+// parseSegmentName returns the ID of a segment from the name of its file.
+func parseSegmentName(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, spoolSegmentPrefix) || !strings.HasSuffix(name, spoolSegmentSuffix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, spoolSegmentPrefix), spoolSegmentSuffix), 10, 64)
+	return id, err == nil
+}
+
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", spoolSegmentPrefix, id, spoolSegmentSuffix))
+}
+
+// Len returns the number of spooled traps.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Push appends a trap to the spool, the oldest traps are dropped if the spool is full.
+func (s *Spool) Push(trap SpooledTrap) error {
+	line, err := json.Marshal(trap)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if int64(len(line)) > s.maxSize {
+		return fmt.Errorf("the trap is larger than the spool (%d bytes)", s.maxSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer == nil || s.segments[len(s.segments)-1].size+int64(len(line)) > s.segmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.writer.Write(line); err != nil {
+		return err
+	}
+	segment := s.segments[len(s.segments)-1]
+	segment.size += int64(len(line))
+	segment.count++
+	s.size += int64(len(line))
+	s.count++
+	s.dropOverflow()
+	trapsSpooled.Set(int64(s.count))
+	return nil
+}
+
+// rotate creates a new segment the traps are appended to.
+func (s *Spool) rotate() error {
+	s.closeWriter()
+	segment := &spoolSegment{path: s.segmentPath(s.nextID)}
+	writer, err := os.OpenFile(segment.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create the spool segment: %w", err)
+	}
+	s.nextID++
+	s.writer = writer
+	s.segments = append(s.segments, segment)
+	return nil
+}
+
+// dropOverflow drops the oldest segments until the spool is not larger than its maximum size.
+func (s *Spool) dropOverflow() {
+	for s.size > s.maxSize && len(s.segments) > 1 {
+		dropped := s.segments[0].count
+		if s.loaded {
+			dropped = len(s.pending)
+		}
+		log.Warnf("The traps spool is full, dropping its %d oldest traps", dropped)
+		trapsSpoolDropped.Add(int64(dropped))
+		s.count -= dropped
+		s.removeFirst()
+	}
+}
+
+// removeFirst removes the first segment of the spool.
+func (s *Spool) removeFirst() {
+	segment := s.segments[0]
+	if len(s.segments) == 1 {
+		s.closeWriter()
+	}
+	if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove the spool segment %s: %v", segment.path, err)
+	}
+	s.segments = s.segments[1:]
+	s.size -= segment.size
+	s.pending = nil
+	s.loaded = false
+}
+
+// Replay sends the spooled traps in order until send returns false, the traps which are sent
+// are removed from the spool.
+func (s *Spool) Replay(send func(SpooledTrap) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer func() { trapsSpooled.Set(int64(s.count)) }()
+	for len(s.segments) > 0 {
+		if !s.loaded {
+			s.load()
+		}
+		for len(s.pending) > 0 {
+			if !send(s.pending[0]) {
+				return
+			}
+			s.pending = s.pending[1:]
+			s.count--
+			trapsSpoolReplayed.Add(1)
+		}
+		s.removeFirst()
+	}
+}
+
+// load reads the traps of the first segment, the traps which cannot be decoded are dropped.
+func (s *Spool) load() {
+	segment := s.segments[0]
+	if len(s.segments) == 1 {
+		// the traps spooled while the segment is replayed are appended to a new segment
+		s.closeWriter()
+	}
+	s.loaded = true
+	s.pending = nil
+
+	content, err := ioutil.ReadFile(segment.path)
+	if err != nil {
+		log.Warnf("Could not read the spool segment %s, dropping it: %v", segment.path, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		var trap SpooledTrap
+		if err := json.Unmarshal(scanner.Bytes(), &trap); err != nil {
+			log.Debugf("Could not decode a trap of the spool segment %s, dropping it: %v", segment.path, err)
+			continue
+		}
+		s.pending = append(s.pending, trap)
+	}
+	s.count += len(s.pending) - segment.count
+	segment.count = len(s.pending)
+}
+
+func (s *Spool) closeWriter() {
+	if s.writer != nil {
+		s.writer.Close()
+		s.writer = nil
+	}
+}
+
+// close closes the segment the traps are appended to, the spooled traps are kept on disk.
+func (s *Spool) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeWriter()
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spooledTrap(i int) SpooledTrap {
+	return SpooledTrap{
+		Content:   json.RawMessage(fmt.Sprintf(`{"oid":"1.3.6.1.4.1.99999.0.%03d"}`, i)),
+		Tags:      []string{"snmp_version:2"},
+		Timestamp: time.Unix(int64(i), 0).UTC(),
+	}
+}
+
+// replayAll replays the spooled traps, at most limit of them.
+func replayAll(s *Spool, limit int) []SpooledTrap {
+	var replayed []SpooledTrap
+	s.Replay(func(trap SpooledTrap) bool {
+		if len(replayed) == limit {
+			return false
+		}
+		trap.Timestamp = trap.Timestamp.UTC()
+		replayed = append(replayed, trap)
+		return true
+	})
+	return replayed
+}
+
+func TestSpoolReplay(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), 10000)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Push(spooledTrap(i)))
+	}
+	assert.Equal(t, 5, s.Len())
+
+	// the traps are replayed in order, until they cannot be sent
+	assert.Equal(t, []SpooledTrap{spooledTrap(0), spooledTrap(1)}, replayAll(s, 2))
+	assert.Equal(t, 3, s.Len())
+
+	// the traps spooled while the spool is replayed are replayed after the others
+	require.NoError(t, s.Push(spooledTrap(5)))
+	assert.Equal(t, []SpooledTrap{spooledTrap(2), spooledTrap(3), spooledTrap(4), spooledTrap(5)}, replayAll(s, 10))
+	assert.Equal(t, 0, s.Len())
+	assert.Empty(t, replayAll(s, 10))
+}
+
+func TestSpoolReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSpool(dir, 10000)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Push(spooledTrap(i)))
+	}
+	s.close()
+
+	// the traps spooled before the Agent restarts are replayed
+	s, err = NewSpool(dir, 10000)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.Len())
+	require.NoError(t, s.Push(spooledTrap(3)))
+	assert.Equal(t, []SpooledTrap{spooledTrap(0), spooledTrap(1), spooledTrap(2), spooledTrap(3)}, replayAll(s, 10))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestSpoolFull(t *testing.T) {
+	line, err := json.Marshal(spooledTrap(0))
+	require.NoError(t, err)
+	// the spool holds 2 traps per segment
+	s, err := NewSpool(t.TempDir(), int64(len(line)+1)*2*spoolSegments)
+	require.NoError(t, err)
+
+	for i := 0; i < 2*spoolSegments+3; i++ {
+		require.NoError(t, s.Push(spooledTrap(i)))
+	}
+	// the oldest segments are dropped
+	assert.Equal(t, 2*spoolSegments-1, s.Len())
+	replayed := replayAll(s, 100)
+	assert.Equal(t, spooledTrap(4), replayed[0])
+	assert.Equal(t, spooledTrap(2*spoolSegments+2), replayed[len(replayed)-1])
+
+	// the traps larger than the spool are not spooled
+	s, err = NewSpool(t.TempDir(), 10)
+	require.NoError(t, err)
+	assert.Error(t, s.Push(spooledTrap(0)))
+}
+
+func TestSpoolConfig(t *testing.T) {
+	Configure(t, Config{Port: GetPort(t), SpoolMaxSize: 1000, SpoolPath: t.TempDir()})
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+	require.NotNil(t, GetSpool())
+
+	Configure(t, Config{SpoolMaxSize: -1})
+	_, err := ReadConfig("")
+	assert.Error(t, err)
+}
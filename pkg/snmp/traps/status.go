@@ -8,17 +8,64 @@ package traps
 import (
 	"encoding/json"
 	"expvar"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
 )
 
 var (
-	trapsExpvars           = expvar.NewMap("snmp_traps")
-	trapsPackets           = expvar.Int{}
-	trapsPacketsAuthErrors = expvar.Int{}
+	trapsExpvars               = expvar.NewMap("snmp_traps")
+	trapsPackets               = expvar.Int{}
+	trapsPacketsAuthErrors     = expvar.Int{}
+	trapsInforms               = expvar.Int{}
+	trapsRelayed               = expvar.Int{}
+	trapsRelayErrors           = expvar.Int{}
+	trapsSpooled               = expvar.Int{}
+	trapsSpoolReplayed         = expvar.Int{}
+	trapsSpoolDropped          = expvar.Int{}
+	trapsPacketsV1             = expvar.Int{}
+	trapsPacketsV2c            = expvar.Int{}
+	trapsPacketsV3             = expvar.Int{}
+	trapsPacketsMalformed      = expvar.Int{}
+	trapsV3AuthErrors          = expvar.Int{}
+	trapsV3PrivErrors          = expvar.Int{}
+	trapsV3UnknownUsers        = expvar.Int{}
+	trapsV3SecurityLevelErrors = expvar.Int{}
+	trapsSocketDrops           = expvar.Int{}
+	trapsStorms                = expvar.Int{}
+	trapsStormSummarized       = expvar.Int{}
+	trapsInjected              = expvar.Int{}
+
+	tlmPackets = telemetry.NewCounter("snmp_traps", "packets",
+		[]string{"version"}, "Count of the trap packets received, by SNMP version")
+	tlmMalformedPackets = telemetry.NewCounter("snmp_traps", "malformed_packets",
+		nil, "Count of the trap packets which could not be decoded")
+	tlmV3SecurityErrors = telemetry.NewCounter("snmp_traps", "v3_security_errors",
+		[]string{"user", "reason"}, "Count of the SNMPv3 trap packets which could not be authenticated or decrypted, or whose security level is not the one of their user, by user")
+	tlmSocketDrops = telemetry.NewCounter("snmp_traps", "socket_drops",
+		[]string{"listener"}, "Count of the trap packets dropped by the sockets of the listeners as their receive buffer was full")
 )
 
 func init() {
 	trapsExpvars.Set("Packets", &trapsPackets)
 	trapsExpvars.Set("PacketsAuthErrors", &trapsPacketsAuthErrors)
+	trapsExpvars.Set("Informs", &trapsInforms)
+	trapsExpvars.Set("PacketsRelayed", &trapsRelayed)
+	trapsExpvars.Set("RelayErrors", &trapsRelayErrors)
+	trapsExpvars.Set("Spooled", &trapsSpooled)
+	trapsExpvars.Set("SpoolReplayed", &trapsSpoolReplayed)
+	trapsExpvars.Set("SpoolDropped", &trapsSpoolDropped)
+	trapsExpvars.Set("PacketsV1", &trapsPacketsV1)
+	trapsExpvars.Set("PacketsV2c", &trapsPacketsV2c)
+	trapsExpvars.Set("PacketsV3", &trapsPacketsV3)
+	trapsExpvars.Set("PacketsMalformed", &trapsPacketsMalformed)
+	trapsExpvars.Set("V3AuthErrors", &trapsV3AuthErrors)
+	trapsExpvars.Set("V3PrivErrors", &trapsV3PrivErrors)
+	trapsExpvars.Set("V3UnknownUsers", &trapsV3UnknownUsers)
+	trapsExpvars.Set("V3SecurityLevelErrors", &trapsV3SecurityLevelErrors)
+	trapsExpvars.Set("SocketDrops", &trapsSocketDrops)
+	trapsExpvars.Set("Storms", &trapsStorms)
+	trapsExpvars.Set("StormSummarized", &trapsStormSummarized)
+	trapsExpvars.Set("Injected", &trapsInjected)
 }
 
 // GetStatus returns key-value data for use in status reporting of the traps server.
@@ -33,6 +80,34 @@ func GetStatus() map[string]interface{} {
 	if startError != nil {
 		status["error"] = startError.Error()
 	}
+	if server := serverInstance; server != nil {
+		status["listeners"] = server.listenersStatus()
+		if resolver, ok := server.oidResolver.(*MultiFilesOIDResolver); ok {
+			fileCount, trapCount := resolver.Stats()
+			status["traps_db"] = map[string]interface{}{"files": fileCount, "traps": trapCount}
+		}
+	}
 
 	return status
 }
+
+// listenersStatus returns the address, the namespace and the credentials of each listener,
+// without the secrets of the credentials.
+func (s *TrapServer) listenersStatus() []map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	listeners := make([]map[string]interface{}, 0, len(s.listeners))
+	for _, listener := range s.listeners {
+		users := make([]string, 0, len(listener.config.Users))
+		for _, user := range listener.config.Users {
+			users = append(users, user.Username)
+		}
+		listeners = append(listeners, map[string]interface{}{
+			"address":           listener.config.Addr(),
+			"namespace":         listener.config.Namespace,
+			"community_strings": len(listener.config.CommunityStrings),
+			"users":             users,
+		})
+	}
+	return listeners
+}
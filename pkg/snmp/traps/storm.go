@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/gosnmp/gosnmp"
+)
+
+// maxStormSummaryOIDs is the number of trap OIDs counted by a storm summary, the traps of the
+// other OIDs are only counted in its total.
+const maxStormSummaryOIDs = 20
+
+// stormSource is a device sending traps during a storm.
+type stormSource struct {
+	namespace string
+	ip        string
+}
+
+// stormSourceSummary aggregates the traps received from a device during a storm.
+type stormSourceSummary struct {
+	// packet is the last trap of the device, the summary is tagged like it
+	packet *SnmpPacket
+	count  int
+	oids   map[string]int
+}
+
+// stormDetector protects the Agent and the intake from the trap storms, e.g. during network
+// meltdowns: when the traps received by all the listeners exceed a rate, they are no longer
+// forwarded individually but summarized, with a summary per device per interval, until their
+// average rate over an interval falls below the threshold again.
+type stormDetector struct {
+	// threshold is the number of packets per second above which a storm starts
+	threshold int
+	interval  time.Duration
+	packets   PacketsChannel
+	now       func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+	storming    bool
+	// intervalStart and intervalCount measure the rate of the packets over the current interval
+	intervalStart time.Time
+	intervalCount int
+	summaries     map[stormSource]*stormSourceSummary
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newStormDetector returns a storm detector sending its summaries to a channel, it returns nil
+// when the storm detection is disabled.
+func newStormDetector(c *Config, packets PacketsChannel) *stormDetector {
+	if c.StormThreshold <= 0 {
+		return nil
+	}
+	d := &stormDetector{
+		threshold: c.StormThreshold,
+		interval:  time.Duration(c.StormSummaryInterval) * time.Second,
+		packets:   packets,
+		now:       time.Now,
+		summaries: make(map[stormSource]*stormSourceSummary),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	d.intervalStart = d.now()
+	go d.run()
+	return d
+}
+
+// observe counts a packet and returns whether it must be forwarded, the packets received during
+// a storm are summarized instead.
+func (d *stormDetector) observe(packet *SnmpPacket) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	if now.Sub(d.windowStart) >= time.Second {
+		d.windowStart = now
+		d.windowCount = 0
+	}
+	d.windowCount++
+	d.intervalCount++
+	if !d.storming && d.windowCount > d.threshold {
+		log.Warnf("More than %d SNMP traps per second were received, summarizing the traps until the storm subsides", d.threshold)
+		d.storming = true
+		d.intervalStart = now
+		d.intervalCount = 1
+		trapsStorms.Add(1)
+	}
+	if !d.storming {
+		return true
+	}
+
+	source := stormSource{namespace: getNamespace(packet), ip: packet.Addr.IP.String()}
+	summary, ok := d.summaries[source]
+	if !ok {
+		summary = &stormSourceSummary{oids: make(map[string]int)}
+		d.summaries[source] = summary
+	}
+	summary.packet = packet
+	summary.count++
+	if trapOID, err := getTrapOID(packet.Content); err == nil {
+		if _, ok := summary.oids[trapOID]; ok || len(summary.oids) < maxStormSummaryOIDs {
+			summary.oids[trapOID]++
+		}
+	}
+	trapsStormSummarized.Add(1)
+	return false
+}
+
+// run sends the summaries of each interval until the detector is closed.
+func (d *stormDetector) run() {
+	defer close(d.stopped)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.flush(false)
+		case <-d.stop:
+			d.flush(true)
+			return
+		}
+	}
+}
+
+// flush sends the summaries of the interval, and ends the storm when the average rate of the
+// packets over the interval is below the threshold.
+func (d *stormDetector) flush(stopping bool) {
+	d.mu.Lock()
+	now := d.now()
+	elapsed := now.Sub(d.intervalStart)
+	summaries := d.summaries
+	d.summaries = make(map[stormSource]*stormSourceSummary)
+	if d.storming && (stopping || float64(d.intervalCount) <= float64(d.threshold)*elapsed.Seconds()) {
+		log.Infof("The SNMP traps storm subsided, forwarding the traps again")
+		d.storming = false
+	}
+	d.intervalStart = now
+	d.intervalCount = 0
+	d.mu.Unlock()
+
+	for _, summary := range summaries {
+		packet := &SnmpPacket{
+			// the context name gives the namespace of the v3 traps
+			Content: &gosnmp.SnmpPacket{Version: summary.packet.Content.Version, ContextName: summary.packet.Content.ContextName},
+			Addr:    summary.packet.Addr,
+			config:  summary.packet.config,
+			storm: &StormSummary{
+				Count:    summary.count,
+				Interval: elapsed.Seconds(),
+				TrapOIDs: summary.oids,
+			},
+		}
+		if !d.send(packet, stopping) {
+			stopping = true
+		}
+	}
+}
+
+// send sends a summary, it returns false once the detector is stopped. The summaries are dropped rather than blocking the detector when it is stopped,
+// as the packets may not be consumed anymore.
+func (d *stormDetector) send(packet *SnmpPacket, stopping bool) bool {
+	if !stopping {
+		select {
+		case d.packets <- packet:
+			return true
+		case <-d.stop:
+		}
+	}
+	select {
+	case d.packets <- packet:
+	default:
+		log.Debugf("Dropping the SNMP traps storm summary of %s, the traps are not consumed anymore", packet.Addr.IP.String())
+	}
+	return false
+}
+
+// close stops the detector once the summaries of the storm in progress are sent, the summaries
+// which cannot be sent without blocking are dropped.
+func (d *stormDetector) close() {
+	close(d.stop)
+	<-d.stopped
+}
@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStormDetector(t *testing.T) {
+	now := time.Unix(1000, 0)
+	d := &stormDetector{
+		threshold: 2,
+		interval:  10 * time.Second,
+		packets:   make(PacketsChannel, 10),
+		now:       func() time.Time { return now },
+		summaries: make(map[stormSource]*stormSourceSummary),
+	}
+	d.intervalStart = now
+
+	// the packets are forwarded until their rate exceeds the threshold
+	assert.True(t, d.observe(createLinkPacket(2, 1, "10.0.0.1")))
+	assert.True(t, d.observe(createLinkPacket(2, 1, "10.0.0.1")))
+	assert.False(t, d.observe(createLinkPacket(3, 1, "10.0.0.1")))
+	assert.False(t, d.observe(createLinkPacket(2, 1, "10.0.0.1")))
+	assert.False(t, d.observe(createLinkPacket(2, 1, "10.0.0.2")))
+	// the storm goes on while the average rate over the interval exceeds the threshold
+	for i := 0; i < 20; i++ {
+		now = now.Add(400 * time.Millisecond)
+		assert.False(t, d.observe(createLinkPacket(2, 1, "10.0.0.2")))
+	}
+
+	// a summary is sent per device
+	d.flush(false)
+	require.Len(t, d.packets, 2)
+	summaries := make(map[string]*StormSummary)
+	for i := 0; i < 2; i++ {
+		packet := <-d.packets
+		summaries[packet.Addr.IP.String()] = packet.storm
+		assert.Contains(t, GetTags(packet), "snmp_trap_storm:true")
+	}
+	assert.Equal(t, &StormSummary{Count: 2, Interval: 8, TrapOIDs: map[string]int{"1.3.6.1.6.3.1.1.5.3": 1, "1.3.6.1.6.3.1.1.5.4": 1}}, summaries["10.0.0.1"])
+	assert.Equal(t, &StormSummary{Count: 21, Interval: 8, TrapOIDs: map[string]int{"1.3.6.1.6.3.1.1.5.3": 21}}, summaries["10.0.0.2"])
+	assert.True(t, d.storming)
+
+	// the storm ends once the average rate over an interval falls below the threshold
+	now = now.Add(time.Second)
+	assert.False(t, d.observe(createLinkPacket(2, 1, "10.0.0.1")))
+	now = now.Add(10 * time.Second)
+	d.flush(false)
+	assert.False(t, d.storming)
+	require.Len(t, d.packets, 1)
+	<-d.packets
+	assert.True(t, d.observe(createLinkPacket(2, 1, "10.0.0.1")))
+	d.flush(false)
+	assert.Empty(t, d.packets)
+}
+
+func TestStormDetectorContext(t *testing.T) {
+	serverInstance = &TrapServer{config: &Config{
+		Namespace: "default",
+		Contexts:  []ContextConfig{{Name: "vrf-blue", Namespace: "blue"}},
+	}}
+	defer func() { serverInstance = nil }()
+	now := time.Unix(1000, 0)
+	d := &stormDetector{
+		threshold: 1,
+		interval:  10 * time.Second,
+		packets:   make(PacketsChannel, 10),
+		now:       func() time.Time { return now },
+		summaries: make(map[stormSource]*stormSourceSummary),
+	}
+	d.intervalStart = now
+
+	for i := 0; i < 3; i++ {
+		packet := createLinkPacket(2, 1, "10.0.0.1")
+		packet.Content.Version = gosnmp.Version3
+		packet.Content.ContextName = "vrf-blue"
+		d.observe(packet)
+	}
+	// the summary is sent to the namespace of the context of the traps
+	d.flush(false)
+	require.Len(t, d.packets, 1)
+	summary := <-d.packets
+	assert.Equal(t, "vrf-blue", summary.Content.ContextName)
+	assert.Equal(t, "blue", getNamespace(summary))
+}
+
+func TestStormDetectorStop(t *testing.T) {
+	now := time.Unix(1000, 0)
+	d := &stormDetector{
+		threshold: 1,
+		interval:  10 * time.Second,
+		packets:   make(PacketsChannel),
+		now:       func() time.Time { return now },
+		summaries: make(map[stormSource]*stormSourceSummary),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	d.intervalStart = now
+	go d.run()
+
+	for i := 0; i < 3; i++ {
+		d.observe(createLinkPacket(2, 1, "10.0.0.1"))
+	}
+	// the summaries which are not consumed do not block the detector when it stops
+	done := make(chan struct{})
+	go func() {
+		d.close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the storm detector did not stop")
+	}
+}
+
+func TestFormatStormSummary(t *testing.T) {
+	packet := createLinkPacket(2, 1, "10.0.0.1")
+	packet.storm = &StormSummary{Count: 3, Interval: 10, TrapOIDs: map[string]int{"1.3.6.1.6.3.1.1.5.3": 3}}
+	assert.Equal(t, &TrapPayload{StormSummary: packet.storm}, mustFormat(t, packet))
+
+	event := formatEvent(mustFormat(t, packet), nil, "10.0.0.1")
+	assert.Equal(t, "SNMP trap storm from 10.0.0.1", event.Title)
+	assert.Equal(t, "3 traps received in 10 seconds\n1.3.6.1.6.3.1.1.5.3: 3\n", event.Text)
+}
+
+func TestServerStorm(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, StormThreshold: 1, StormSummaryInterval: 1}
+	Configure(t, config)
+	require.NoError(t, StartServer("dummy_hostname"))
+	defer StopServer()
+
+	storms := trapsStorms.Value()
+	for i := 0; i < 3; i++ {
+		sendTestV2Trap(t, config, "public")
+	}
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	assert.Nil(t, packet.storm)
+	// the traps received during the storm are summarized
+	summary := receivePacket(t)
+	require.NotNil(t, summary)
+	require.NotNil(t, summary.storm)
+	assert.Equal(t, 2, summary.storm.Count)
+	assert.Equal(t, storms+1, trapsStorms.Value())
+}
+
+func TestStormConfig(t *testing.T) {
+	Configure(t, Config{StormThreshold: 1000})
+	config, err := ReadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, defaultStormSummaryInterval, config.StormSummaryInterval)
+
+	Configure(t, Config{StormThreshold: -1})
+	_, err = ReadConfig("")
+	assert.Error(t, err)
+}
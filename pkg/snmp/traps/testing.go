@@ -164,6 +164,24 @@ func sendTestV2Trap(t *testing.T, trapConfig Config, community string) *gosnmp.G
 	return params
 }
 
+// sendTestV2Inform sends an inform and returns the error of its acknowledgement.
+func sendTestV2Inform(t *testing.T, trapConfig Config, community string) error {
+	params, err := trapConfig.BuildSNMPParams()
+	require.NoError(t, err)
+	params.Community = community
+	params.Timeout = 1 * time.Second // Must be non-zero when sending informs.
+	params.Retries = 1               // Must be non-zero when sending informs.
+
+	err = params.Connect()
+	require.NoError(t, err)
+	defer params.Conn.Close()
+
+	inform := NetSNMPExampleHeartbeatNotification
+	inform.IsInform = true
+	_, err = params.SendTrap(inform)
+	return err
+}
+
 func sendTestV3Trap(t *testing.T, trapConfig Config, securityParams *gosnmp.UsmSecurityParameters) *gosnmp.GoSNMP {
 	params, err := trapConfig.BuildSNMPParams()
 	require.NoError(t, err)
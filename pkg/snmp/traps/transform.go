@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// findValueTransform returns the transformation of the values of a variable, the one of the
+// variable itself or else the one of the longest column OID of which it is an instance, or nil
+// if its values are not transformed.
+func findValueTransform(transforms []ValueTransformConfig, oid string) *ValueTransformConfig {
+	var found *ValueTransformConfig
+	for i := range transforms {
+		transform := &transforms[i]
+		if transform.OID == oid {
+			return transform
+		}
+		if strings.HasPrefix(oid, transform.OID+".") && (found == nil || len(transform.OID) > len(found.OID)) {
+			found = transform
+		}
+	}
+	return found
+}
+
+// apply transforms a formatted value, it returns an error when the value cannot be transformed,
+// e.g. when it does not match the regex.
+func (t *ValueTransformConfig) apply(value interface{}) (interface{}, error) {
+	if t.regexp != nil {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("the regex only applies to the string values, got %v of type %T", value, value)
+		}
+		match := t.regexp.FindStringSubmatch(s)
+		if match == nil {
+			return nil, fmt.Errorf("%q does not match %s", s, t.Regex)
+		}
+		value = match[0]
+		if len(match) > 1 {
+			value = match[1]
+		}
+	}
+	if t.HexToInt {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("only the string values are hexadecimal, got %v of type %T", value, value)
+		}
+		s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+		parsed, err := strconv.ParseUint(s, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		value = parsed
+	}
+	if t.Scale != 0 {
+		number, err := numericValue(value)
+		if err != nil {
+			return nil, err
+		}
+		// the values are rounded to 15 significant digits so that e.g. 42 * 0.1 is 4.2, rather
+		// than 4.200000000000001
+		value, _ = strconv.ParseFloat(strconv.FormatFloat(number*t.Scale, 'g', 15, 64), 64)
+	}
+	return value, nil
+}
+
+// numericValue returns a value as a float, the string values are parsed.
+func numericValue(value interface{}) (float64, error) {
+	if s, ok := value.(string); ok {
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	if v, ok := unsignedValue(value); ok {
+		return float64(v), nil
+	}
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("%v of type %T is not a number", value, value)
+	}
+}
@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueTransform(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		transform ValueTransformConfig
+		value     interface{}
+		expected  interface{}
+	}{
+		{"regex group", ValueTransformConfig{Regex: `temp=(\d+)C`}, "sensor 2 temp=42C", "42"},
+		{"regex match", ValueTransformConfig{Regex: `\d+`}, "42 C", "42"},
+		{"hex", ValueTransformConfig{HexToInt: true}, "0x1F", uint64(31)},
+		{"hex without prefix", ValueTransformConfig{HexToInt: true}, "ff", uint64(255)},
+		{"scale", ValueTransformConfig{Scale: 0.1}, 42, 4.2},
+		{"scale unsigned", ValueTransformConfig{Scale: 1024}, uint(2), 2048.0},
+		{"regex and scale", ValueTransformConfig{Regex: `(\d+) dBm`, Scale: 0.01}, "-1 / 250 dBm", 2.5},
+		{"regex, hex and scale", ValueTransformConfig{Regex: `code=(\w+)`, HexToInt: true, Scale: 2}, "code=0a", 20.0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.transform.Regex != "" {
+				tt.transform.regexp = regexp.MustCompile(tt.transform.Regex)
+			}
+			value, err := tt.transform.apply(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+
+	for _, tt := range []struct {
+		transform ValueTransformConfig
+		value     interface{}
+	}{
+		{ValueTransformConfig{Regex: `\d+`}, 42},
+		{ValueTransformConfig{Regex: `\d+`}, "none"},
+		{ValueTransformConfig{HexToInt: true}, "0xzz"},
+		{ValueTransformConfig{HexToInt: true}, 42},
+		{ValueTransformConfig{Scale: 2}, "forty-two"},
+		{ValueTransformConfig{Scale: 2}, []string{"up"}},
+	} {
+		if tt.transform.Regex != "" {
+			tt.transform.regexp = regexp.MustCompile(tt.transform.Regex)
+		}
+		_, err := tt.transform.apply(tt.value)
+		assert.Error(t, err, tt.value)
+	}
+}
+
+func TestFindValueTransform(t *testing.T) {
+	transforms := []ValueTransformConfig{
+		{OID: "1.3.6.1.4.1.9.9.13.1.3.1", Scale: 1},
+		{OID: "1.3.6.1.4.1.9.9.13.1.3.1.3", Scale: 2},
+	}
+	assert.Equal(t, &transforms[1], findValueTransform(transforms, "1.3.6.1.4.1.9.9.13.1.3.1.3"))
+	assert.Equal(t, &transforms[1], findValueTransform(transforms, "1.3.6.1.4.1.9.9.13.1.3.1.3.1005"))
+	assert.Equal(t, &transforms[0], findValueTransform(transforms, "1.3.6.1.4.1.9.9.13.1.3.1.2.1005"))
+	assert.Nil(t, findValueTransform(transforms, "1.3.6.1.4.1.9.9.13.1.3.10"))
+	assert.Nil(t, findValueTransform(nil, "1.3.6.1.4.1.9.9.13.1.3.1.3"))
+}
+
+func TestFormatPacketToJSONWithValueTransforms(t *testing.T) {
+	Configure(t, Config{ValueTransforms: []ValueTransformConfig{
+		{OID: ".1.3.6.1.4.1.9.9.13.1.3.1.3", Regex: `(\d+) C`, Scale: 0.1},
+		{OID: "1.3.6.1.2.1.2.2.1.8", HexToInt: true},
+	}})
+	config, err := ReadConfig("")
+	require.NoError(t, err)
+
+	packet := createTestPacket()
+	packet.config = config
+	packet.Content.Variables = append(packet.Content.Variables,
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.9.9.13.1.3.1.3.1005", Type: gosnmp.OctetString, Value: []byte("temperature: 421 C")},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.9.9.13.1.3.1.3.1006", Type: gosnmp.OctetString, Value: []byte("unknown")},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.8.1", Type: gosnmp.Integer, Value: 2},
+	)
+	variables := mustFormat(t, packet).Variables
+	assert.Equal(t, 42.1, variables[2].Value)
+	// the values which cannot be transformed are unchanged, e.g. the integers parsed as hexadecimal
+	assert.Equal(t, "unknown", variables[3].Value)
+	assert.Equal(t, 2, variables[4].Value)
+}
+
+func TestValueTransformsConfig(t *testing.T) {
+	Configure(t, Config{ValueTransforms: []ValueTransformConfig{{OID: ".1.3.6.1.4.1.9.9.13.1.3.1.3", Regex: `(\d+)`}}})
+	config, err := ReadConfig("")
+	require.NoError(t, err)
+	require.Len(t, config.ValueTransforms, 1)
+	assert.Equal(t, "1.3.6.1.4.1.9.9.13.1.3.1.3", config.ValueTransforms[0].OID)
+	assert.NotNil(t, config.ValueTransforms[0].regexp)
+
+	for _, transform := range []ValueTransformConfig{
+		{Regex: `(\d+)`},
+		{OID: "1.3.6.1.4.1.9.9.13.1.3.1.3"},
+		{OID: "1.3.6.1.4.1.9.9.13.1.3.1.3", Regex: `(\d+`},
+	} {
+		Configure(t, Config{ValueTransforms: []ValueTransformConfig{transform}})
+		_, err = ReadConfig("")
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,240 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// maxBundleSize bounds the size of the traps DB bundles which are downloaded
+	maxBundleSize = 512 * 1024 * 1024
+	// bundleFetchTimeout is the timeout of the download of a bundle
+	bundleFetchTimeout = 5 * time.Minute
+)
+
+// trapsDBBundlesDir returns the directory where the traps DB bundles are cached.
+func trapsDBBundlesDir() string {
+	return filepath.Join(config.Datadog.GetString("run_path"), "snmp_traps_db_bundles")
+}
+
+// bundleFetcher fetches a traps DB bundle and caches it on disk, so that it is available
+// when the Agent starts even if it cannot be fetched.
+type bundleFetcher struct {
+	config BundleConfig
+	client *http.Client
+	// path is the path of the cached bundle
+	path string
+	// etag is the entity tag of the cached bundle, to only download the bundle when it changes
+	etag string
+}
+
+// newBundleFetcher returns a fetcher caching a bundle in a directory, the bundles are cached
+// with their index so that the last bundles override the first ones.
+func newBundleFetcher(c BundleConfig, index int, dir string) *bundleFetcher {
+	return &bundleFetcher{
+		config: c,
+		client: &http.Client{Transport: httputils.CreateHTTPTransport(), Timeout: bundleFetchTimeout},
+		path:   filepath.Join(dir, fmt.Sprintf("%02d_%s", index, c.fileName())),
+	}
+}
+
+// run fetches the bundle at every refresh interval until stop is closed, it calls onUpdate
+// when the cached bundle changes.
+func (f *bundleFetcher) run(onUpdate func(), stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(f.config.RefreshInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		updated, err := f.fetch()
+		if err != nil {
+			log.Warnf("Could not fetch the traps DB bundle %s: %v", f.config.safeURL(), err)
+		} else if updated {
+			log.Infof("The traps DB bundle %s changed, reloading the traps DB", f.config.safeURL())
+			onUpdate()
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetch downloads the bundle, verifies its checksum and caches it, it returns whether the
+// cached bundle changed.
+func (f *bundleFetcher) fetch() (bool, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, f.config.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	// the bundle is written to a hidden temporary file while it is hashed, and renamed once
+	// verified, so that neither the whole bundle is held in memory nor a partially written
+	// bundle is ever loaded
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), ".download-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(resp.Body, maxBundleSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if size > maxBundleSize {
+		return false, fmt.Errorf("the bundle is larger than %d bytes", maxBundleSize)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if err := f.verifyChecksum(checksum); err != nil {
+		return false, err
+	}
+	etag := resp.Header.Get("ETag")
+
+	if cached, err := fileChecksum(f.path); err == nil && cached == checksum {
+		f.etag = etag
+		return false, nil
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return false, err
+	}
+	f.etag = etag
+	return true, nil
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum of a file.
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyChecksum checks the SHA-256 checksum of the bundle against the configured one or the
+// one published at the checksum URL.
+func (f *bundleFetcher) verifyChecksum(checksum string) error {
+	expected := f.config.SHA256
+	if expected == "" && f.config.ChecksumURL != "" {
+		var err error
+		if expected, err = f.fetchChecksum(); err != nil {
+			return fmt.Errorf("could not fetch the checksum: %w", err)
+		}
+	}
+	if expected != "" && !strings.EqualFold(expected, checksum) {
+		return fmt.Errorf("checksum mismatch, expected %s, got %s", expected, checksum)
+	}
+	return nil
+}
+
+// fetchChecksum downloads the checksum of the bundle, in the format of sha256sum.
+func (f *bundleFetcher) fetchChecksum() (string, error) {
+	resp, err := f.client.Get(f.config.ChecksumURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum")
+	}
+	return fields[0], nil
+}
+
+// fileName returns the name of the file of the bundle, whose extension gives its format.
+func (c BundleConfig) fileName() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return ""
+	}
+	return path.Base(u.Path)
+}
+
+// safeURL returns the URL of the bundle without its credentials and its query, which may
+// contain tokens.
+func (c BundleConfig) safeURL() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "<invalid URL>"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
+// validate checks that the bundle is fetched over HTTPS, that its format is supported and
+// that its refresh interval is valid.
+func (c BundleConfig) validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		// the error is not wrapped since it contains the URL, which may contain credentials
+		return fmt.Errorf("invalid traps DB bundle URL")
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("traps DB bundle %s must be fetched over https", c.safeURL())
+	}
+	if !isTarball(c.fileName()) {
+		switch filepath.Ext(strings.TrimSuffix(c.fileName(), ".gz")) {
+		case ".json", ".yaml", ".yml":
+		default:
+			return fmt.Errorf("traps DB bundle %s must be a json or yaml file, optionally gzipped, or a tar.gz bundle of such files", c.safeURL())
+		}
+	}
+	if c.RefreshInterval < 0 {
+		return fmt.Errorf("the refresh interval of traps DB bundle %s must be positive", c.safeURL())
+	}
+	if c.ChecksumURL != "" {
+		if u, err := url.Parse(c.ChecksumURL); err != nil || u.Scheme != "https" {
+			return fmt.Errorf("the checksum of traps DB bundle %s must be fetched over https", c.safeURL())
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ package traps
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/gosnmp/gosnmp"
 )
@@ -26,3 +27,25 @@ func validatePacket(p *gosnmp.SnmpPacket, c *Config) error {
 
 	return errors.New("unknown community string")
 }
+
+// validateSecurityLevel checks that a v3 packet has the security level of the user who sent it,
+// when the security level of the user is set, so that the users cannot be downgraded.
+func validateSecurityLevel(p *gosnmp.SnmpPacket, c *Config) error {
+	sender, ok := p.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if p.Version != gosnmp.Version3 || !ok {
+		return nil
+	}
+	for _, user := range c.Users {
+		if user.Username != sender.UserName || user.SecurityLevel == "" {
+			continue
+		}
+		expected, err := user.msgFlags()
+		if err != nil {
+			return err
+		}
+		if p.MsgFlags&gosnmp.AuthPriv != expected {
+			return fmt.Errorf("expected security level %s, got %s", formatSecurityLevel(expected), formatSecurityLevel(p.MsgFlags))
+		}
+	}
+	return nil
+}
@@ -20,6 +20,8 @@ type CLCChecks struct {
 type CLCStats struct {
 	AverageExecutionTime int  `json:"AverageExecutionTime"`
 	MetricSamples        int  `json:"MetricSamples"`
+	TotalRuns            int  `json:"TotalRuns"`
+	TotalErrors          int  `json:"TotalErrors"`
 	LastExecFailed       bool `json:"LastExecFailed"`
 }
 
@@ -31,6 +33,8 @@ func (d *CLCStats) UnmarshalJSON(data []byte) error {
 	}
 	d.AverageExecutionTime = int(stats.AverageExecutionTime)
 	d.MetricSamples = int(stats.MetricSamples)
+	d.TotalRuns = int(stats.TotalRuns)
+	d.TotalErrors = int(stats.TotalErrors)
 	if stats.LastError != "" {
 		d.LastExecFailed = true
 	} else {
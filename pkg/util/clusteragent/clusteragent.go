@@ -56,7 +56,7 @@ type DCAClientInterface interface {
 	GetCFAppsMetadataForNode(nodename string) (map[string][]string, error)
 
 	PostClusterCheckStatus(ctx context.Context, nodeName string, status types.NodeStatus) (types.StatusResponse, error)
-	GetClusterCheckConfigs(ctx context.Context, nodeName string) (types.ConfigResponse, error)
+	GetClusterCheckConfigs(ctx context.Context, nodeName, cluster string) (types.ConfigResponse, error)
 	GetEndpointsCheckConfigs(ctx context.Context, nodeName string) (types.ConfigResponse, error)
 	GetKubernetesClusterID() (string, error)
 }
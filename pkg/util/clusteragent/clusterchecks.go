@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -71,26 +72,30 @@ func (c *DCAClient) doPostClusterCheckStatus(ctx context.Context, identifier str
 	return response, err
 }
 
-// GetClusterCheckConfigs is called by the clustercheck config provider
-func (c *DCAClient) GetClusterCheckConfigs(ctx context.Context, identifier string) (types.ConfigResponse, error) {
+// GetClusterCheckConfigs is called by the clustercheck config provider. The
+// cluster of the runner, if any, identifies it with a federated cluster-agent.
+func (c *DCAClient) GetClusterCheckConfigs(ctx context.Context, identifier, cluster string) (types.ConfigResponse, error) {
 	// Retry on the main URL if the leader fails
 	willRetry := c.leaderClient.hasLeader()
 
-	result, err := c.doGetClusterCheckConfigs(ctx, identifier)
+	result, err := c.doGetClusterCheckConfigs(ctx, identifier, cluster)
 	if err != nil && willRetry {
 		log.Debugf("Got error on leader, retrying via the service: %s", err)
 		c.leaderClient.resetURL()
-		return c.doGetClusterCheckConfigs(ctx, identifier)
+		return c.doGetClusterCheckConfigs(ctx, identifier, cluster)
 	}
 	return result, err
 }
 
-func (c *DCAClient) doGetClusterCheckConfigs(ctx context.Context, identifier string) (types.ConfigResponse, error) {
+func (c *DCAClient) doGetClusterCheckConfigs(ctx context.Context, identifier, cluster string) (types.ConfigResponse, error) {
 	var configs types.ConfigResponse
 	var err error
 
-	// https://host:port/api/v1/clusterchecks/configs/{identifier}
+	// https://host:port/api/v1/clusterchecks/configs/{identifier}?cluster={cluster}
 	rawURL := c.leaderClient.buildURL(dcaClusterChecksConfigsPath, identifier)
+	if cluster != "" {
+		rawURL += "?" + url.Values{"cluster": []string{cluster}}.Encode()
+	}
 	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
 	if err != nil {
 		return configs, err
@@ -49,12 +49,21 @@ func (suite *clusterAgentSuite) TestClusterChecksNominal() {
 	require.NoError(suite.T(), err)
 	assert.True(suite.T(), response.IsUpToDate)
 
-	configs, err := ca.GetClusterCheckConfigs(ctx, "mynode")
+	configs, err := ca.GetClusterCheckConfigs(ctx, "mynode", "")
 	require.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(42), configs.LastChange)
 	require.Len(suite.T(), configs.Configs, 2)
 	assert.Equal(suite.T(), "one", configs.Configs[0].Name)
 	assert.Equal(suite.T(), "two", configs.Configs[1].Name)
+
+	// The runners of a federated cluster report their cluster
+	for dca.PopRequest() != nil {
+	}
+	_, err = ca.GetClusterCheckConfigs(ctx, "mynode", "satellite")
+	require.NoError(suite.T(), err)
+	request := dca.PopRequest()
+	require.NotNil(suite.T(), request)
+	assert.Equal(suite.T(), "satellite", request.URL.Query().Get("cluster"))
 }
 
 func (suite *clusterAgentSuite) TestClusterChecksRedirect() {
@@ -99,7 +108,7 @@ func (suite *clusterAgentSuite) TestClusterChecksRedirect() {
 	assert.NotNil(suite.T(), leader.PopRequest(), "request did not reach leader")
 
 	// Subsequent requests will bypass the follower
-	configs, err := ca.GetClusterCheckConfigs(ctx, "mynode")
+	configs, err := ca.GetClusterCheckConfigs(ctx, "mynode", "")
 	require.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(42), configs.LastChange)
 	require.Len(suite.T(), configs.Configs, 2)
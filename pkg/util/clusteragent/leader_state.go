@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package clusteragent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/api/security"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+/*
+Client used by the follower cluster-agents to replicate the cluster checks
+dispatching state of the leader, and to serve it read-only.
+*/
+
+const (
+	leaderDispatchingStatePath = "api/v1/clusterchecks/dispatching"
+	leaderStatePath            = "api/v1/clusterchecks/state"
+)
+
+var globalLeaderStateClient *LeaderStateClient
+
+// LeaderStateClientInterface is required to query the dispatching state of the leader
+type LeaderStateClientInterface interface {
+	GetDispatchingState(leaderAddr string) (types.DispatchingState, error)
+	GetState(leaderAddr string) (types.StateResponse, error)
+}
+
+// LeaderStateClient is required to query the dispatching state of the leader
+type LeaderStateClient struct {
+	sync.Once
+	initErr        error
+	requestHeaders http.Header
+	client         *http.Client
+}
+
+// GetLeaderStateClient returns or init the LeaderStateClient
+func GetLeaderStateClient() (LeaderStateClientInterface, error) {
+	globalLeaderStateClient.Do(globalLeaderStateClient.init)
+	return globalLeaderStateClient, globalLeaderStateClient.initErr
+}
+
+func (c *LeaderStateClient) init() {
+	c.initErr = nil
+
+	authToken, err := security.GetClusterAgentAuthToken()
+	if err != nil {
+		c.initErr = err
+		return
+	}
+
+	// Set headers
+	c.requestHeaders = http.Header{}
+	c.requestHeaders.Set(authorizationHeaderKey, fmt.Sprintf("Bearer %s", authToken))
+
+	// Set http client
+	// TODO remove insecure
+	c.client = util.GetClient(false)
+	c.client.Timeout = 2 * time.Second
+}
+
+// GetDispatchingState fetches the dispatching state of the leader, given as host:port
+func (c *LeaderStateClient) GetDispatchingState(leaderAddr string) (types.DispatchingState, error) {
+	var state types.DispatchingState
+	err := c.get(leaderAddr, leaderDispatchingStatePath, &state)
+	return state, err
+}
+
+// GetState fetches the state of the dispatching of the leader, as shown by the
+// clusterchecks command
+func (c *LeaderStateClient) GetState(leaderAddr string) (types.StateResponse, error) {
+	var state types.StateResponse
+	err := c.get(leaderAddr, leaderStatePath, &state)
+	return state, err
+}
+
+// get queries a path of the API of the leader and unmarshals the response
+func (c *LeaderStateClient) get(leaderAddr, path string, out interface{}) error {
+	rawURL := fmt.Sprintf("https://%s/%s", leaderAddr, path)
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = c.requestHeaders
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code from the leader cluster-agent: %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// init globalLeaderStateClient
+func init() {
+	globalLeaderStateClient = &LeaderStateClient{}
+}
@@ -36,6 +36,7 @@ const (
 	defaultLeaderLeaseDuration = 60 * time.Second
 	defaultLeaseName           = "datadog-leader-election"
 	getLeaderTimeout           = 10 * time.Second
+	releaseLeadershipTimeout   = 5 * time.Second
 )
 
 var (
@@ -61,6 +62,13 @@ type LeaderEngine struct {
 	leaderIdentityMutex sync.RWMutex
 	leaderElector       *leaderelection.LeaderElector
 
+	// stopRun stops the leader election, runDone is closed once it is stopped
+	stopRun context.CancelFunc
+	runDone chan struct{}
+
+	// leaderChangeSubscribers are notified of every leader change
+	leaderChangeSubscribers []chan struct{}
+
 	// leaderIdentity is the HolderIdentity of the current leader.
 	leaderIdentity string
 
@@ -161,11 +169,33 @@ func (le *LeaderEngine) init() error {
 func (le *LeaderEngine) StartLeaderElectionRun() {
 	le.once.Do(
 		func() {
-			go le.runLeaderElection()
+			var ctx context.Context
+			ctx, le.stopRun = context.WithCancel(context.Background())
+			le.runDone = make(chan struct{})
+			go le.runLeaderElection(ctx)
 		},
 	)
 }
 
+// ReleaseLeadership stops the leader election and, if this instance is the leader, releases
+// the leadership so that another instance takes over without waiting for the lease to expire.
+// It is meant to be called when shutting down: the leader election is not started again.
+func (le *LeaderEngine) ReleaseLeadership() {
+	// Prevent the leader election from being started afterwards
+	le.once.Do(func() {})
+	if le.stopRun == nil {
+		return
+	}
+
+	le.stopRun()
+	select {
+	case <-le.runDone:
+		log.Infof("Leader election stopped for %q", le.HolderIdentity)
+	case <-time.After(releaseLeadershipTimeout):
+		log.Warnf("Leader election still running for %q after %s, the leadership might not be released", le.HolderIdentity, releaseLeadershipTimeout)
+	}
+}
+
 // EnsureLeaderElectionRuns start the Leader election process if not already running,
 // return nil if the process is effectively running
 func (le *LeaderEngine) EnsureLeaderElectionRuns() error {
@@ -198,10 +228,15 @@ func (le *LeaderEngine) EnsureLeaderElectionRuns() error {
 	}
 }
 
-func (le *LeaderEngine) runLeaderElection() {
+func (le *LeaderEngine) runLeaderElection(ctx context.Context) {
+	defer close(le.runDone)
+
 	for {
 		log.Infof("Starting leader election process for %q...", le.HolderIdentity)
-		le.leaderElector.Run(context.Background())
+		le.leaderElector.Run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
 		log.Info("Leader election lost")
 	}
 }
@@ -253,6 +288,19 @@ func (le *LeaderEngine) Subscribe() <-chan struct{} {
 	return c
 }
 
+// SubscribeLeaderChanges allows any component to receive a notification when
+// the leader changes, including when the current process becomes or stops
+// being leader. Notifications are coalesced if the subscriber is busy.
+func (le *LeaderEngine) SubscribeLeaderChanges() <-chan struct{} {
+	c := make(chan struct{}, 1)
+
+	le.m.Lock()
+	le.leaderChangeSubscribers = append(le.leaderChangeSubscribers, c)
+	le.m.Unlock()
+
+	return c
+}
+
 // GetLeaderElectionRecord is used in for the Flare and for the Status commands.
 func GetLeaderElectionRecord() (leaderDetails rl.LeaderElectionRecord, err error) {
 	var led rl.LeaderElectionRecord
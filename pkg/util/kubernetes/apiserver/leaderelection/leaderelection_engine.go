@@ -77,12 +77,14 @@ func (le *LeaderEngine) newElection() (*ld.LeaderElector, error) {
 		OnNewLeader: func(identity string) {
 			le.updateLeaderIdentity(identity)
 			le.reportLeaderMetric(false)
+			le.notifyLeaderChange()
 			log.Infof("New leader %q", identity)
 		},
 		OnStartedLeading: func(ctx context.Context) {
 			le.updateLeaderIdentity(le.HolderIdentity)
 			le.reportLeaderMetric(true)
 			le.notify()
+			le.notifyLeaderChange()
 			log.Infof("Started leading as %q...", le.HolderIdentity)
 		},
 		// OnStoppedLeading shouldn't be called unless the election is lost. This could happen if
@@ -90,6 +92,7 @@ func (le *LeaderEngine) newElection() (*ld.LeaderElector, error) {
 		OnStoppedLeading: func() {
 			le.updateLeaderIdentity("")
 			le.reportLeaderMetric(false)
+			le.notifyLeaderChange()
 			log.Infof("Stopped leading %q", le.HolderIdentity)
 		},
 	}
@@ -123,6 +126,8 @@ func (le *LeaderEngine) newElection() (*ld.LeaderElector, error) {
 		RenewDeadline: le.LeaseDuration / 2,
 		RetryPeriod:   le.LeaseDuration / 4,
 		Callbacks:     callbacks,
+		// Release the leadership when the leader election is stopped with ReleaseLeadership
+		ReleaseOnCancel: true,
 	}
 	return ld.NewLeaderElector(electionConfig)
 }
@@ -155,3 +160,17 @@ func (le *LeaderEngine) notify() {
 		s <- struct{}{}
 	}
 }
+
+// notifyLeaderChange sends a notification to the leader change subscribers
+// without blocking: a pending notification is enough for them to refresh.
+func (le *LeaderEngine) notifyLeaderChange() {
+	le.m.Lock()
+	defer le.m.Unlock()
+
+	for _, s := range le.leaderChangeSubscribers {
+		select {
+		case s <- struct{}{}:
+		default:
+		}
+	}
+}
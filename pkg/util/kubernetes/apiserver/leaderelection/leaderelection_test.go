@@ -100,6 +100,37 @@ func TestNewLeaseAcquiring(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestReleaseLeadership(t *testing.T) {
+	const leaseName = "datadog-leader-election"
+
+	client := fake.NewSimpleClientset()
+	le := &LeaderEngine{
+		HolderIdentity:  "foo",
+		LeaseName:       leaseName,
+		LeaderNamespace: "default",
+		LeaseDuration:   1 * time.Second,
+		coreClient:      client.CoreV1(),
+		leaderMetric:    &dummyGauge{},
+	}
+
+	var err error
+	le.leaderElector, err = le.newElection()
+	require.NoError(t, err)
+
+	le.EnsureLeaderElectionRuns()
+	require.True(t, le.IsLeader())
+
+	le.ReleaseLeadership()
+	assert.False(t, le.IsLeader())
+	leader, _, err := le.getCurrentLeader()
+	require.NoError(t, err)
+	assert.Equal(t, "", leader)
+
+	// The leader election is not started again
+	le.StartLeaderElectionRun()
+	assert.False(t, le.IsLeader())
+}
+
 func TestSubscribe(t *testing.T) {
 	const leaseName = "datadog-leader-election"
 
@@ -154,6 +185,41 @@ func TestSubscribe(t *testing.T) {
 	}
 }
 
+func TestSubscribeLeaderChanges(t *testing.T) {
+	const leaseName = "datadog-leader-election"
+
+	client := fake.NewSimpleClientset()
+	le := &LeaderEngine{
+		HolderIdentity:  "foo",
+		LeaseName:       leaseName,
+		LeaderNamespace: "default",
+		LeaseDuration:   1 * time.Second,
+		coreClient:      client.CoreV1(),
+		leaderMetric:    &dummyGauge{},
+	}
+
+	notif := le.SubscribeLeaderChanges()
+	require.Len(t, le.leaderChangeSubscribers, 1)
+
+	var err error
+	le.leaderElector, err = le.newElection()
+	require.NoError(t, err)
+
+	le.EnsureLeaderElectionRuns()
+	require.True(t, le.IsLeader())
+
+	select {
+	case <-notif:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Waiting on leader change notification timed out")
+	}
+
+	// Notifications are coalesced instead of blocking the leader election
+	le.notifyLeaderChange()
+	le.notifyLeaderChange()
+	assert.Len(t, notif, 1)
+}
+
 func TestGetLeaderIPFollower(t *testing.T) {
 	const leaseName = "datadog-leader-election"
 	const endpointsName = "datadog-cluster-agent"